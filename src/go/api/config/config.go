@@ -25,6 +25,10 @@ var AllKinds = []string{"Topology", "Scenario", "Experiment", "Image", "User", "
 
 var NameRegex = regexp.MustCompile(`^[a-zA-Z0-9_@.-]*$`)
 
+// Prefix of the annotations users' permissions to an experiment are saved as.
+// They're only changed via the experiment's access, not by updating its config.
+const ExperimentAccessAnnotationPrefix = "access/"
+
 // ConfigHook is a function to be called during the different lifecycle stages
 // of a config. The passed config can be updated by the hook functions as
 // necessary, and an error can be returned if the lifecycle stage should be
@@ -411,6 +415,10 @@ func Update(name string, c *store.Config) error {
 
 	c.Metadata.Created = old.Metadata.Created
 
+	if c.Kind == "Experiment" {
+		keepExperimentAccess(old, c)
+	}
+
 	if err := types.ValidateConfigSpec(*c); err != nil {
 		return fmt.Errorf("validating config: %w", err)
 	}
@@ -501,3 +509,27 @@ func Delete(name string) error {
 func IsConfigNotModified(err error) bool {
 	return errors.Is(err, editor.ErrNoChange)
 }
+
+// keepExperimentAccess replaces any experiment access annotations in the given
+// updated config with those of the given stored config, so users can't grant
+// themselves access to an experiment (or remove everyone else's) by updating
+// its config.
+func keepExperimentAccess(old, c *store.Config) {
+	for k := range c.Metadata.Annotations {
+		if strings.HasPrefix(k, ExperimentAccessAnnotationPrefix) {
+			delete(c.Metadata.Annotations, k)
+		}
+	}
+
+	for k, v := range old.Metadata.Annotations {
+		if !strings.HasPrefix(k, ExperimentAccessAnnotationPrefix) {
+			continue
+		}
+
+		if c.Metadata.Annotations == nil {
+			c.Metadata.Annotations = make(store.Annotations)
+		}
+
+		c.Metadata.Annotations[k] = v
+	}
+}
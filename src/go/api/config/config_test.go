@@ -1,6 +1,7 @@
 package config
 
 import (
+	"reflect"
 	"testing"
 
 	"phenix/store"
@@ -71,3 +72,25 @@ func TestCreateScoped(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestKeepExperimentAccess(t *testing.T) {
+	old := &store.Config{
+		Metadata: store.ConfigMetadata{
+			Annotations: store.Annotations{"access/alice": "owner", "access/bob": "viewer", "note": "old"},
+		},
+	}
+
+	c := &store.Config{
+		Metadata: store.ConfigMetadata{
+			Annotations: store.Annotations{"access/mallory": "owner", "access/bob": "owner", "note": "new"},
+		},
+	}
+
+	keepExperimentAccess(old, c)
+
+	expected := store.Annotations{"access/alice": "owner", "access/bob": "viewer", "note": "new"}
+
+	if !reflect.DeepEqual(c.Metadata.Annotations, expected) {
+		t.Fatalf("expected annotations %v, got %v", expected, c.Metadata.Annotations)
+	}
+}
@@ -0,0 +1,153 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"phenix/api/config"
+	"phenix/store"
+)
+
+var (
+	ErrPermissionDenied  = errors.New("experiment permission denied")
+	ErrInvalidPermission = errors.New("invalid experiment permission")
+)
+
+// Permission is the level of access a user has to a specific experiment, on top
+// of whatever their role allows. Each level includes the ones below it: viewers
+// can see the experiment, editors can also start and stop it, and owners can
+// also change who has access to it.
+type Permission string
+
+const (
+	PermissionViewer Permission = "viewer"
+	PermissionEditor Permission = "editor"
+	PermissionOwner  Permission = "owner"
+)
+
+// Users' permissions are saved as annotations of the form `access/<user>:
+// <permission>` on the experiment's config.
+const accessAnnotationPrefix = config.ExperimentAccessAnnotationPrefix
+
+func (this Permission) level() int {
+	switch this {
+	case PermissionViewer:
+		return 1
+	case PermissionEditor:
+		return 2
+	case PermissionOwner:
+		return 3
+	}
+
+	return 0
+}
+
+// Valid returns true if the permission is one of the known levels.
+func (this Permission) Valid() bool {
+	return this.level() > 0
+}
+
+// Includes returns true if the permission grants at least the given one.
+func (this Permission) Includes(required Permission) bool {
+	return this.Valid() && this.level() >= required.level()
+}
+
+// Permissions returns the users granted access to the experiment with the given
+// name, along with their permissions. Experiments without any (e.g. those
+// created before permissions existed, or from the command line) aren't
+// restricted beyond users' roles.
+func Permissions(name string) (map[string]Permission, error) {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	return permissionsFromAnnotations(c.Metadata.Annotations), nil
+}
+
+// SetPermissions grants the given permissions to the given users for the
+// experiment with the given name, leaving other users' permissions as they are.
+// An empty permission revokes the user's access. It returns an error wrapping
+// ErrInvalidPermission if any of the permissions are unknown or the change would
+// leave a restricted experiment without an owner.
+func SetPermissions(name string, perms map[string]Permission) error {
+	if len(perms) == 0 {
+		return nil
+	}
+
+	current, err := Permissions(name)
+	if err != nil {
+		return err
+	}
+
+	annotations := make(map[string]string)
+
+	for user, perm := range perms {
+		if user == "" {
+			return fmt.Errorf("%w: missing user", ErrInvalidPermission)
+		}
+
+		if perm != "" && !perm.Valid() {
+			return fmt.Errorf("%w: unknown permission %q for user %s", ErrInvalidPermission, perm, user)
+		}
+
+		if perm == "" {
+			delete(current, user)
+		} else {
+			current[user] = perm
+		}
+
+		annotations[accessAnnotationPrefix+user] = string(perm)
+	}
+
+	if len(current) > 0 && !hasOwner(current) {
+		return fmt.Errorf("%w: experiment %s would be left without an owner", ErrInvalidPermission, name)
+	}
+
+	return Annotate(name, annotations)
+}
+
+// CheckPermission returns an error wrapping ErrPermissionDenied if the given
+// user hasn't been granted at least the given permission for the experiment
+// with the given name. Any user is allowed for experiments that haven't been
+// restricted to specific users.
+func CheckPermission(name, user string, required Permission) error {
+	perms, err := Permissions(name)
+	if err != nil {
+		return err
+	}
+
+	if len(perms) == 0 {
+		return nil
+	}
+
+	if perm := perms[user]; !perm.Includes(required) {
+		return fmt.Errorf("%w: %s permission for experiment %s required for user %s", ErrPermissionDenied, required, name, user)
+	}
+
+	return nil
+}
+
+func permissionsFromAnnotations(annotations map[string]string) map[string]Permission {
+	perms := make(map[string]Permission)
+
+	for k, v := range annotations {
+		if user, ok := strings.CutPrefix(k, accessAnnotationPrefix); ok && user != "" {
+			perms[user] = Permission(v)
+		}
+	}
+
+	return perms
+}
+
+func hasOwner(perms map[string]Permission) bool {
+	for _, perm := range perms {
+		if perm == PermissionOwner {
+			return true
+		}
+	}
+
+	return false
+}
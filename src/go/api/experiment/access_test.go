@@ -0,0 +1,51 @@
+package experiment
+
+import "testing"
+
+func TestPermissionIncludes(t *testing.T) {
+	cases := []struct {
+		perm, required Permission
+		expected       bool
+	}{
+		{PermissionOwner, PermissionEditor, true},
+		{PermissionEditor, PermissionEditor, true},
+		{PermissionViewer, PermissionEditor, false},
+		{PermissionViewer, PermissionViewer, true},
+		{"", PermissionViewer, false},
+		{"admin", PermissionViewer, false},
+	}
+
+	for _, c := range cases {
+		if included := c.perm.Includes(c.required); included != c.expected {
+			t.Errorf("%q includes %q: expected %t, got %t", c.perm, c.required, c.expected, included)
+		}
+	}
+}
+
+func TestPermissionsFromAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		"topology":      "foo",
+		"access/alice":  "owner",
+		"access/bob":    "viewer",
+		"access/":       "owner",
+		"note/vm-1":     "access/carol",
+		"template":      "bar",
+		"snapshot/base": "{}",
+	}
+
+	perms := permissionsFromAnnotations(annotations)
+
+	if len(perms) != 2 || perms["alice"] != PermissionOwner || perms["bob"] != PermissionViewer {
+		t.Fatalf("unexpected permissions %v", perms)
+	}
+
+	if !hasOwner(perms) {
+		t.Fatal("expected permissions to have an owner")
+	}
+
+	delete(perms, "alice")
+
+	if hasOwner(perms) {
+		t.Fatal("expected permissions to have no owner")
+	}
+}
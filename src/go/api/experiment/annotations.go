@@ -0,0 +1,48 @@
+package experiment
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UpdateAnnotations merges the given annotations into the documentation
+// annotations in the spec of the experiment with the given name, removing any
+// annotations given with an empty value. Unlike tags, annotations are only for
+// people reading the experiment (e.g. its owner, ticket or purpose), so there
+// are no restrictions on their values and they can be changed while the
+// experiment is running. It returns the experiment's updated annotations.
+func UpdateAnnotations(name string, annotations map[string]string) (map[string]string, error) {
+	defer InvalidateCached(name)
+
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	updated := make(map[string]string)
+
+	for k, v := range exp.Spec.Annotations() {
+		updated[k] = v
+	}
+
+	for k, v := range annotations {
+		if strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid annotation key %q", k)
+		}
+
+		if v == "" {
+			delete(updated, k)
+			continue
+		}
+
+		updated[k] = v
+	}
+
+	exp.Spec.SetAnnotations(updated)
+
+	if err := exp.WriteToStore(false); err != nil {
+		return nil, fmt.Errorf("saving annotations for experiment %s: %w", name, err)
+	}
+
+	return updated, nil
+}
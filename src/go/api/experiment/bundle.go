@@ -0,0 +1,608 @@
+package experiment
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util"
+	"phenix/util/common"
+	"phenix/util/plog"
+
+	"github.com/activeshadow/structs"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidBundle = errors.New("invalid experiment bundle")
+
+// Version of the bundle format written by Export. Import rejects bundles with a
+// newer version.
+const BundleVersion = 1
+
+// Names of the entries in an experiment bundle. The manifest and configs are
+// always written before any images so they can be validated before any large
+// images are read.
+const (
+	bundleManifest   = "manifest.json"
+	bundleExperiment = "experiment.yml"
+	bundleTopology   = "topology.yml"
+	bundleScenario   = "scenario.yml"
+	bundleImagesDir  = "images/"
+)
+
+// Largest manifest or config accepted when importing a bundle.
+const maxBundleConfigSize = 64 << 20
+
+// BundleManifest describes the contents of an experiment bundle.
+type BundleManifest struct {
+	Version    int           `json:"version"`
+	Experiment string        `json:"experiment"`
+	Exported   string        `json:"exported"`
+	Images     []BundleImage `json:"images"`
+
+	// The experiment's documentation annotations (owner, ticket, etc.), copied
+	// from its spec so they can be read without unpacking its config.
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// BundleImage is a disk image referenced by the VMs in a bundled experiment.
+// Images with absolute paths are never included, since they can't be placed
+// anywhere sensible on another installation.
+type BundleImage struct {
+	Path     string `json:"path"`
+	Size     int64  `json:"size,omitempty"`
+	Included bool   `json:"included"`
+}
+
+// ImportResult describes the experiment registered from an imported bundle.
+type ImportResult struct {
+	Name   string        `json:"name"`
+	Config *store.Config `json:"config"`
+
+	// Images written to the minimega files directory, those skipped since an
+	// image with the same path was already there, and those referenced by the
+	// experiment that are neither in the bundle nor already present.
+	Images        []string `json:"images"`
+	SkippedImages []string `json:"skippedImages"`
+	MissingImages []string `json:"missingImages"`
+
+	// Topology and scenario configs not registered since configs with the same
+	// names already exist. The experiment's own spec is used either way.
+	SkippedConfigs []string `json:"skippedConfigs"`
+}
+
+// Export writes a gzipped tarball of the experiment with the given name to the
+// given writer, so it can be imported into another phenix installation. The
+// bundle contains the experiment's config (including its scenario's app
+// configs), its topology and scenario configs if they still exist, and
+// optionally the disk images referenced by its VMs.
+func Export(name string, w io.Writer, opts ...ExportOption) error {
+	o := newExportOptions(opts...)
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	configs := map[string]*store.Config{bundleExperiment: portableConfig(c)}
+
+	for entry, kind := range map[string]string{bundleTopology: "topology", bundleScenario: "scenario"} {
+		ref := c.Metadata.Annotations[kind]
+		if ref == "" {
+			continue
+		}
+
+		related, _ := store.NewConfig(kind + "/" + ref)
+
+		if err := store.Get(related); err != nil {
+			// The experiment's spec has its own copy, so it's still usable.
+			continue
+		}
+
+		configs[entry] = portableConfig(related)
+	}
+
+	manifest := BundleManifest{
+		Version:    BundleVersion,
+		Experiment: name,
+		Exported:   time.Now().UTC().Format(time.RFC3339),
+		Images:     bundleImages(exp, o.images),
+
+		Annotations: exp.Spec.Annotations(),
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	body, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling bundle manifest: %w", err)
+	}
+
+	if err := writeBundleEntry(tw, bundleManifest, body); err != nil {
+		return err
+	}
+
+	for _, entry := range []string{bundleExperiment, bundleTopology, bundleScenario} {
+		cfg, ok := configs[entry]
+		if !ok {
+			continue
+		}
+
+		body, err := yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("marshaling %s config %s: %w", cfg.Kind, cfg.Metadata.Name, err)
+		}
+
+		if err := writeBundleEntry(tw, entry, body); err != nil {
+			return err
+		}
+	}
+
+	for _, image := range manifest.Images {
+		if !image.Included {
+			continue
+		}
+
+		if err := writeBundleImage(tw, image); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("closing bundle: %w", err)
+	}
+
+	if err := gw.Close(); err != nil {
+		return fmt.Errorf("closing bundle: %w", err)
+	}
+
+	return nil
+}
+
+// Import registers the experiment from a bundle written by Export, writing any
+// images it includes to the minimega files directory if they aren't already
+// there. The experiment keeps its name unless a different one is given. If an
+// experiment with the name already exists, it returns an error wrapping
+// ErrExperimentExists unless importing with renaming enabled, in which case a
+// numeric suffix is added to the name. The new experiment is stopped and goes
+// through the same validation and create hooks as any other experiment.
+func Import(r io.Reader, opts ...ImportOption) (_ *ImportResult, err error) {
+	o := newImportOptions(opts...)
+
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+	}
+
+	defer gr.Close()
+
+	var (
+		tr       = tar.NewReader(gr)
+		manifest *BundleManifest
+		configs  = make(map[string]*store.Config)
+		result   *ImportResult
+	)
+
+	// Images are written before the experiment is created, so they're removed
+	// again if the import fails after all.
+	defer func() {
+		if err != nil && result != nil {
+			removeImportedImages(result)
+		}
+	}()
+
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: reading bundle: %v", ErrInvalidBundle, err)
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		switch name := hdr.Name; {
+		case name == bundleManifest:
+			manifest = new(BundleManifest)
+
+			if err := json.NewDecoder(io.LimitReader(tr, maxBundleConfigSize)).Decode(manifest); err != nil {
+				return nil, fmt.Errorf("%w: parsing manifest: %v", ErrInvalidBundle, err)
+			}
+		case name == bundleExperiment, name == bundleTopology, name == bundleScenario:
+			body, err := io.ReadAll(io.LimitReader(tr, maxBundleConfigSize))
+			if err != nil {
+				return nil, fmt.Errorf("%w: reading %s: %v", ErrInvalidBundle, name, err)
+			}
+
+			cfg := new(store.Config)
+
+			if err := yaml.Unmarshal(body, cfg); err != nil {
+				return nil, fmt.Errorf("%w: parsing %s: %v", ErrInvalidBundle, name, err)
+			}
+
+			configs[name] = cfg
+		case strings.HasPrefix(name, bundleImagesDir):
+			// The configs come first, so the experiment can be validated before
+			// reading any (potentially large) images.
+			if result == nil {
+				if result, err = prepareImport(manifest, configs, o); err != nil {
+					return nil, err
+				}
+			}
+
+			if err := importBundleImage(tr, strings.TrimPrefix(name, bundleImagesDir), manifest, result); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if result == nil {
+		if result, err = prepareImport(manifest, configs, o); err != nil {
+			return nil, err
+		}
+	}
+
+	return finishImport(result, manifest, configs)
+}
+
+// portableConfig returns a copy of the given config without anything specific
+// to this installation or the experiment's runtime state.
+func portableConfig(c *store.Config) *store.Config {
+	portable := &store.Config{
+		Version:  c.Version,
+		Kind:     c.Kind,
+		Metadata: store.ConfigMetadata{Name: c.Metadata.Name, Annotations: make(map[string]string)},
+		Spec:     c.Spec,
+	}
+
+	for k, v := range c.Metadata.Annotations {
+		// Disk snapshots are specific to this installation's files, and users
+		// specific to its accounts.
+		if strings.HasPrefix(k, "snapshot/") || strings.HasPrefix(k, accessAnnotationPrefix) {
+			continue
+		}
+
+		portable.Metadata.Annotations[k] = v
+	}
+
+	if c.Kind == "Experiment" {
+		spec := make(map[string]any)
+
+		for k, v := range c.Spec {
+			spec[k] = v
+		}
+
+		// Both are set again when the experiment is imported.
+		delete(spec, "experimentName")
+		delete(spec, "baseDir")
+
+		portable.Spec = spec
+	}
+
+	return portable
+}
+
+// bundleImages returns the disk images referenced by the given experiment's
+// VMs, marking those that will be included in the bundle.
+func bundleImages(exp *types.Experiment, include bool) []BundleImage {
+	var (
+		seen   = make(map[string]bool)
+		images []BundleImage
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		for _, drive := range node.Hardware().Drives() {
+			path := drive.Image()
+
+			if path == "" || seen[path] {
+				continue
+			}
+
+			seen[path] = true
+
+			image := BundleImage{Path: path}
+
+			if info, err := os.Stat(util.GetMMFullPath(path)); err == nil {
+				image.Size = info.Size()
+				image.Included = include && !filepath.IsAbs(path)
+			}
+
+			images = append(images, image)
+		}
+	}
+
+	sort.Slice(images, func(i, j int) bool { return images[i].Path < images[j].Path })
+
+	return images
+}
+
+func writeBundleEntry(tw *tar.Writer, name string, body []byte) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(body)),
+		ModTime: time.Now(),
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing bundle header for %s: %w", name, err)
+	}
+
+	if _, err := tw.Write(body); err != nil {
+		return fmt.Errorf("writing %s to bundle: %w", name, err)
+	}
+
+	return nil
+}
+
+func writeBundleImage(tw *tar.Writer, image BundleImage) error {
+	f, err := os.Open(util.GetMMFullPath(image.Path))
+	if err != nil {
+		return fmt.Errorf("opening image %s: %w", image.Path, err)
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("getting file stats for image %s: %w", image.Path, err)
+	}
+
+	hdr := &tar.Header{
+		Name:    bundleImagesDir + image.Path,
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+
+	if err := tw.WriteHeader(hdr); err != nil {
+		return fmt.Errorf("writing bundle header for image %s: %w", image.Path, err)
+	}
+
+	if _, err := io.Copy(tw, f); err != nil {
+		return fmt.Errorf("writing image %s to bundle: %w", image.Path, err)
+	}
+
+	return nil
+}
+
+// prepareImport works out the name to import the experiment from a bundle as
+// and validates its config, returning the result of the import so far with the
+// config to be created.
+func prepareImport(manifest *BundleManifest, configs map[string]*store.Config, o importOptions) (*ImportResult, error) {
+	if manifest == nil {
+		return nil, fmt.Errorf("%w: missing manifest", ErrInvalidBundle)
+	}
+
+	if manifest.Version < 1 || manifest.Version > BundleVersion {
+		return nil, fmt.Errorf("%w: unsupported bundle version %d", ErrInvalidBundle, manifest.Version)
+	}
+
+	c, ok := configs[bundleExperiment]
+	if !ok || c.Kind != "Experiment" {
+		return nil, fmt.Errorf("%w: missing experiment config", ErrInvalidBundle)
+	}
+
+	name := o.name
+	if name == "" {
+		name = c.Metadata.Name
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	if strings.ToLower(name) == "all" {
+		return nil, fmt.Errorf("cannot use 'all' for experiment name")
+	}
+
+	if experimentExists(name) {
+		if !o.rename {
+			return nil, fmt.Errorf("importing experiment %s: %w", name, ErrExperimentExists)
+		}
+
+		base := name
+
+		for i := 1; experimentExists(name); i++ {
+			name = fmt.Sprintf("%s-%d", base, i)
+		}
+	}
+
+	spec := make(map[string]any)
+
+	for k, v := range c.Spec {
+		spec[k] = v
+	}
+
+	spec["experimentName"] = name
+	spec["baseDir"] = common.PhenixBase + "/experiments/" + name
+
+	meta := store.ConfigMetadata{Name: name, Annotations: make(map[string]string)}
+
+	for k, v := range c.Metadata.Annotations {
+		if strings.HasPrefix(k, "snapshot/") || strings.HasPrefix(k, accessAnnotationPrefix) {
+			continue
+		}
+
+		meta.Annotations[k] = v
+	}
+
+	if o.owner != "" {
+		meta.Annotations[accessAnnotationPrefix+o.owner] = string(PermissionOwner)
+	}
+
+	exp := &store.Config{Version: c.Version, Kind: c.Kind, Metadata: meta, Spec: spec}
+
+	if err := types.ValidateConfigSpec(*exp); err != nil {
+		return nil, fmt.Errorf("validating imported experiment %s: %w", name, err)
+	}
+
+	decoded, err := types.DecodeExperimentFromConfig(*exp)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding imported experiment %s: %v", types.ErrValidationFailed, name, err)
+	}
+
+	exp.Spec = structs.MapDefaultCase(decoded.Spec, structs.CASESNAKE)
+
+	return &ImportResult{
+		Name:           name,
+		Config:         exp,
+		Images:         []string{},
+		SkippedImages:  []string{},
+		MissingImages:  []string{},
+		SkippedConfigs: []string{},
+	}, nil
+}
+
+// finishImport registers the experiment from a bundle, along with its topology
+// and scenario configs if they don't already exist.
+func finishImport(result *ImportResult, manifest *BundleManifest, configs map[string]*store.Config) (*ImportResult, error) {
+	defer InvalidateCached(result.Name)
+
+	for _, entry := range []string{bundleTopology, bundleScenario} {
+		cfg, ok := configs[entry]
+		if !ok {
+			continue
+		}
+
+		ref := strings.ToLower(cfg.Kind) + "/" + cfg.Metadata.Name
+
+		if existing, err := store.NewConfig(ref); err != nil || store.Get(existing) == nil {
+			result.SkippedConfigs = append(result.SkippedConfigs, ref)
+			continue
+		}
+
+		if _, err := config.Create(config.CreateFromConfig(cfg), config.CreateWithValidation()); err != nil {
+			return nil, fmt.Errorf("creating imported %s config %s: %w", cfg.Kind, cfg.Metadata.Name, err)
+		}
+	}
+
+	created, err := config.Create(config.CreateFromConfig(result.Config), config.CreateWithValidation())
+	if err != nil {
+		return nil, fmt.Errorf("creating experiment config: %w", err)
+	}
+
+	for _, hook := range hooks["create"] {
+		hook("create", result.Name)
+	}
+
+	for _, image := range manifest.Images {
+		if _, err := os.Stat(util.GetMMFullPath(image.Path)); err != nil {
+			result.MissingImages = append(result.MissingImages, image.Path)
+		}
+	}
+
+	result.Config = created
+
+	return result, nil
+}
+
+// importBundleImage writes the image with the given path from a bundle to the
+// minimega files directory, unless an image with the same path is already
+// there. Only images the given manifest includes are written, so bundles can't
+// be used to drop arbitrary files in the minimega files directory.
+func importBundleImage(r io.Reader, path string, manifest *BundleManifest, result *ImportResult) error {
+	clean := filepath.Clean(path)
+
+	if clean == "." || filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("%w: invalid image path %s", ErrInvalidBundle, path)
+	}
+
+	if !manifestIncludesImage(manifest, clean) {
+		return fmt.Errorf("%w: image %s isn't included in the bundle's manifest", ErrInvalidBundle, path)
+	}
+
+	dst := util.GetMMFullPath(clean)
+
+	if _, err := os.Stat(dst); err == nil {
+		result.SkippedImages = append(result.SkippedImages, clean)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("creating directory for image %s: %w", clean, err)
+	}
+
+	// Write to a temporary file first so a failed import doesn't leave behind a
+	// truncated image that would be skipped by the next import.
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".import-*")
+	if err != nil {
+		return fmt.Errorf("creating image %s: %w", clean, err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing image %s: %w", clean, err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing image %s: %w", clean, err)
+	}
+
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("saving image %s: %w", clean, err)
+	}
+
+	result.Images = append(result.Images, clean)
+
+	return nil
+}
+
+func manifestIncludesImage(manifest *BundleManifest, path string) bool {
+	if manifest == nil {
+		return false
+	}
+
+	for _, image := range manifest.Images {
+		if image.Included && filepath.Clean(image.Path) == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeImportedImages removes the images written to the minimega files
+// directory by a failed import. Images that were already there are left alone.
+func removeImportedImages(result *ImportResult) {
+	for _, image := range result.Images {
+		if err := os.Remove(util.GetMMFullPath(image)); err != nil {
+			plog.Warn("removing image from failed experiment import", "image", image, "err", err)
+		}
+	}
+
+	result.Images = []string{}
+}
+
+func experimentExists(name string) bool {
+	c, _ := store.NewConfig("experiment/" + name)
+	return store.Get(c) == nil
+}
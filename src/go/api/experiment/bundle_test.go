@@ -0,0 +1,52 @@
+package experiment
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPortableConfig(t *testing.T) {
+	c := testExperimentConfig(map[string]any{"baseDir": "/phenix/experiments/" + testExperimentName})
+
+	c.Metadata.Annotations = map[string]string{
+		"topology":                     "test-topo",
+		"snapshot/host-1":              "host-1_snapshot",
+		accessAnnotationPrefix + "bob": string(PermissionOwner),
+	}
+
+	portable := portableConfig(&c)
+
+	if len(portable.Metadata.Annotations) != 1 || portable.Metadata.Annotations["topology"] != "test-topo" {
+		t.Fatalf("expected only topology annotation, got %v", portable.Metadata.Annotations)
+	}
+
+	for _, key := range []string{"experimentName", "baseDir"} {
+		if _, ok := portable.Spec[key]; ok {
+			t.Fatalf("expected %s to be removed from spec", key)
+		}
+	}
+
+	if _, ok := c.Spec["experimentName"]; !ok {
+		t.Fatal("expected original config spec to be left alone")
+	}
+}
+
+func TestImportBundleImageInvalidPath(t *testing.T) {
+	for _, path := range []string{"", ".", "..", "../etc/passwd", "/etc/passwd", "foo/../../bar"} {
+		err := importBundleImage(strings.NewReader(""), path, new(BundleManifest), new(ImportResult))
+		if !errors.Is(err, ErrInvalidBundle) {
+			t.Fatalf("expected invalid bundle error for path %q, got %v", path, err)
+		}
+	}
+
+	// Images have to be included in the manifest to be imported.
+	manifest := &BundleManifest{Images: []BundleImage{{Path: "included.qc2", Included: true}, {Path: "excluded.qc2"}}}
+
+	for _, path := range []string{"excluded.qc2", "other.qc2"} {
+		err := importBundleImage(strings.NewReader(""), path, manifest, new(ImportResult))
+		if !errors.Is(err, ErrInvalidBundle) {
+			t.Fatalf("expected invalid bundle error for unlisted image %q, got %v", path, err)
+		}
+	}
+}
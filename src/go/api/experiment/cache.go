@@ -0,0 +1,67 @@
+package experiment
+
+import (
+	"sync"
+	"time"
+
+	"phenix/types"
+)
+
+// How long an experiment returned by GetCached is reused before being re-read
+// from the store.
+const cachedExperimentTTL = 2 * time.Second
+
+type cachedExperiment struct {
+	exp     *types.Experiment
+	expires time.Time
+}
+
+var (
+	expCache   = make(map[string]cachedExperiment)
+	expCacheMu sync.Mutex
+
+	// Incremented each time an experiment is invalidated so an experiment read
+	// from the store before it was modified doesn't get cached after.
+	expCacheGen = make(map[string]uint64)
+)
+
+// GetCached is like Get, but reuses the experiment read from the store for a
+// short period of time. It's meant for hot, read-only paths (like broadcasting
+// status updates) where re-reading and re-parsing the store each time is
+// wasteful. The returned experiment is shared between callers and must not be
+// modified. The cached experiment is invalidated by any of this package's
+// functions that modify it, and callers that modify an experiment some other
+// way should call InvalidateCached.
+func GetCached(name string) (*types.Experiment, error) {
+	expCacheMu.Lock()
+	cached, ok := expCache[name]
+	gen := expCacheGen[name]
+	expCacheMu.Unlock()
+
+	if ok && time.Now().Before(cached.expires) {
+		return cached.exp, nil
+	}
+
+	exp, err := Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	expCacheMu.Lock()
+	if expCacheGen[name] == gen {
+		expCache[name] = cachedExperiment{exp: exp, expires: time.Now().Add(cachedExperimentTTL)}
+	}
+	expCacheMu.Unlock()
+
+	return exp, nil
+}
+
+// InvalidateCached removes the given experiment from the cache used by
+// GetCached, forcing the next call to read it from the store.
+func InvalidateCached(name string) {
+	expCacheMu.Lock()
+	defer expCacheMu.Unlock()
+
+	delete(expCache, name)
+	expCacheGen[name]++
+}
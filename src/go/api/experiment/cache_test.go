@@ -0,0 +1,57 @@
+package experiment
+
+import (
+	"testing"
+
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestGetCached(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := store.NewMockStore(ctrl)
+
+	// The store should only be read once before the cache is invalidated, and
+	// once more after.
+	m.EXPECT().Get(gomock.Any()).Times(2).DoAndReturn(func(c *store.Config) error {
+		c.Version = "phenix.sandia.gov/v1"
+		c.Kind = "Experiment"
+
+		return nil
+	})
+
+	store.DefaultStore = m
+
+	first, err := GetCached("test-experiment")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	second, err := GetCached("test-experiment")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if first != second {
+		t.Log("expecting cached experiment to be reused")
+		t.FailNow()
+	}
+
+	InvalidateCached("test-experiment")
+
+	third, err := GetCached("test-experiment")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if third == first {
+		t.Log("expecting experiment to be read again after invalidation")
+		t.FailNow()
+	}
+}
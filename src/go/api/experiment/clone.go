@@ -0,0 +1,158 @@
+package experiment
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/common"
+
+	"github.com/activeshadow/structs"
+)
+
+var ErrExperimentExists = errors.New("experiment already exists")
+
+// Clone creates a new, stopped experiment with the given destination name from
+// the config of the experiment with the given source name, including its
+// topology and scenario. None of the source experiment's runtime state is
+// copied. It returns the config of the new experiment.
+func Clone(src, dst string, opts ...CloneOption) (*store.Config, error) {
+	o := newCloneOptions(opts...)
+
+	defer InvalidateCached(dst)
+
+	if dst == "" {
+		return nil, fmt.Errorf("no experiment name provided for clone")
+	}
+
+	if strings.ToLower(dst) == "all" {
+		return nil, fmt.Errorf("cannot use 'all' for experiment name")
+	}
+
+	if existing, _ := store.NewConfig("experiment/" + dst); store.Get(existing) == nil {
+		return nil, fmt.Errorf("cloning experiment %s to %s: %w", src, dst, ErrExperimentExists)
+	}
+
+	c, _ := store.NewConfig("experiment/" + src)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", src, err)
+	}
+
+	// Decoding the source config gives us a copy of its spec to modify.
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	exp.Spec.SetExperimentName(dst)
+
+	// Only move the base directory if it's the default one for the source
+	// experiment, otherwise both experiments would share it.
+	if exp.Spec.BaseDir() == common.PhenixBase+"/experiments/"+src {
+		exp.Spec.SetBaseDir(common.PhenixBase + "/experiments/" + dst)
+	}
+
+	if o.subnetOffset != 0 {
+		if err := offsetSubnets(exp, o.subnetOffset); err != nil {
+			return nil, fmt.Errorf("offsetting subnets for experiment %s: %w", dst, err)
+		}
+	}
+
+	meta := store.ConfigMetadata{
+		Name:        dst,
+		Annotations: make(map[string]string),
+	}
+
+	for k, v := range c.Metadata.Annotations {
+		// Disk snapshots are specific to the source experiment's VMs.
+		if strings.HasPrefix(k, "snapshot/") {
+			continue
+		}
+
+		// Access to the clone is up to whoever cloned it.
+		if strings.HasPrefix(k, accessAnnotationPrefix) {
+			continue
+		}
+
+		meta.Annotations[k] = v
+	}
+
+	if o.owner != "" {
+		meta.Annotations[accessAnnotationPrefix+o.owner] = string(PermissionOwner)
+	}
+
+	clone := &store.Config{
+		Version:  c.Version,
+		Kind:     c.Kind,
+		Metadata: meta,
+		Spec:     structs.MapDefaultCase(exp.Spec, structs.CASESNAKE),
+	}
+
+	created, err := config.Create(config.CreateFromConfig(clone), config.CreateWithValidation())
+	if err != nil {
+		return nil, fmt.Errorf("creating experiment config: %w", err)
+	}
+
+	for _, hook := range hooks["create"] {
+		hook("create", dst)
+	}
+
+	return created, nil
+}
+
+func offsetSubnets(exp *types.Experiment, offset int) error {
+	for _, node := range exp.Spec.Topology().Nodes() {
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Address() == "" || iface.Mask() <= 0 || iface.Mask() > 32 {
+				continue
+			}
+
+			addr, err := offsetIPv4(iface.Address(), iface.Mask(), offset)
+			if err != nil {
+				return fmt.Errorf("interface %s on VM %s: %w", iface.Name(), node.General().Hostname(), err)
+			}
+
+			iface.SetAddress(addr)
+
+			if iface.Gateway() != "" {
+				gw, err := offsetIPv4(iface.Gateway(), iface.Mask(), offset)
+				if err != nil {
+					return fmt.Errorf("gateway for interface %s on VM %s: %w", iface.Name(), node.General().Hostname(), err)
+				}
+
+				iface.SetGateway(gw)
+			}
+		}
+	}
+
+	return nil
+}
+
+// offsetIPv4 moves the given IPv4 address by offset subnets of the given mask
+// size, keeping its host portion the same.
+func offsetIPv4(addr string, mask, offset int) (string, error) {
+	ip := net.ParseIP(addr).To4()
+	if ip == nil {
+		return "", fmt.Errorf("invalid IPv4 address %s", addr)
+	}
+
+	var (
+		size = uint64(1) << (32 - mask)
+		val  = int64(binary.BigEndian.Uint32(ip)) + int64(offset)*int64(size)
+	)
+
+	if val < 0 || val > 0xFFFFFFFF {
+		return "", fmt.Errorf("offsetting %s by %d subnets overflows the IPv4 address space", addr, offset)
+	}
+
+	out := make(net.IP, 4)
+	binary.BigEndian.PutUint32(out, uint32(val))
+
+	return out.String(), nil
+}
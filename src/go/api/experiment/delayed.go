@@ -0,0 +1,100 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"phenix/util/mm"
+	"phenix/util/pubsub"
+)
+
+var (
+	ErrNotDelayedVM     = errors.New("VM is not a delayed VM")
+	ErrDelayedVMStarted = errors.New("delayed VM already started")
+)
+
+// How long to wait between attempts at starting a delayed VM that failed to
+// start. It's a variable so tests can shorten it.
+var delayedRetryBackoff = 5 * time.Second
+
+// DelayedRetry describes a retry of starting a delayed VM that failed to start
+// when starting an experiment.
+type DelayedRetry struct {
+	VM string
+
+	// Which retry this is, starting at 1, out of how many will be made.
+	Attempt int
+	Retries int
+
+	// The error from the previous attempt.
+	Err error
+}
+
+// startDelayedVM starts the given delayed VM, retrying it up to the given
+// number of times if it fails to start. The given function is called before
+// each retry. It returns a DelayedVMError if the VM still couldn't be started,
+// or the context's error if it's canceled while waiting to retry.
+func startDelayedVM(ctx context.Context, ns, host string, retries int, progress func(DelayedRetry)) error {
+	for attempt := 0; ; attempt++ {
+		err := mm.StartVM(mm.NS(ns), mm.VMName(host))
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= retries {
+			if retries > 0 {
+				return NewDelayedVMError(host, err, "starting VM %s after %d attempts", host, attempt+1)
+			}
+
+			return NewDelayedVMError(host, err, "starting VM %s", host)
+		}
+
+		progress(DelayedRetry{VM: host, Attempt: attempt + 1, Retries: retries, Err: err})
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delayedRetryBackoff):
+		}
+	}
+}
+
+// RetryDelayedVM makes another attempt at starting the given delayed VM in the
+// given running experiment, for when it failed to start along with the rest of
+// the experiment. It returns an error wrapping ErrNotDelayedVM if the VM isn't
+// a delayed VM in the experiment, ErrDelayedVMStarted if it's already been
+// started, or a DelayedVMError if it fails to start again.
+func RetryDelayedVM(expName, vmName string) error {
+	exp, err := Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return fmt.Errorf("retrying delayed VM %s: %w", vmName, ErrExperimentNotRunning)
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node == nil || node.Delayed() == "" {
+		return fmt.Errorf("%w: %s in experiment %s", ErrNotDelayedVM, vmName, expName)
+	}
+
+	vms := mm.GetVMInfo(mm.NS(expName), mm.VMName(vmName))
+	if len(vms) == 0 {
+		return fmt.Errorf("%w: %s has not been launched in experiment %s", ErrNotDelayedVM, vmName, expName)
+	}
+
+	if vms[0].Running {
+		return fmt.Errorf("%w: %s in experiment %s", ErrDelayedVMStarted, vmName, expName)
+	}
+
+	if err := mm.StartVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
+		return NewDelayedVMError(vmName, err, "retrying VM %s", vmName)
+	}
+
+	pubsub.Publish("delayed-start", fmt.Sprintf("%s/%s", expName, vmName))
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"phenix/util/mm"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestStartDelayedVMRetries(t *testing.T) {
+	defer func(orig mm.MM, backoff time.Duration) {
+		mm.DefaultMM = orig
+		delayedRetryBackoff = backoff
+	}(mm.DefaultMM, delayedRetryBackoff)
+
+	delayedRetryBackoff = time.Millisecond
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm.NewMockMM(ctrl)
+	mm.DefaultMM = m
+
+	failure := errors.New("vm start failed")
+
+	gomock.InOrder(
+		m.EXPECT().StartVM(gomock.Any(), gomock.Any()).Return(failure).Times(2),
+		m.EXPECT().StartVM(gomock.Any(), gomock.Any()).Return(nil),
+	)
+
+	var retries []DelayedRetry
+
+	if err := startDelayedVM(context.Background(), "foo", "vm-1", 2, func(r DelayedRetry) { retries = append(retries, r) }); err != nil {
+		t.Fatalf("expected VM to start after retries, got %v", err)
+	}
+
+	if len(retries) != 2 || retries[0].Attempt != 1 || retries[1].Attempt != 2 || retries[1].Retries != 2 {
+		t.Fatalf("unexpected retries %+v", retries)
+	}
+
+	m.EXPECT().StartVM(gomock.Any(), gomock.Any()).Return(failure).Times(2)
+
+	retries = nil
+
+	err := startDelayedVM(context.Background(), "foo", "vm-1", 1, func(r DelayedRetry) { retries = append(retries, r) })
+
+	var delayErr DelayedVMError
+
+	if !errors.As(err, &delayErr) || delayErr.VM != "vm-1" || !errors.Is(err, failure) {
+		t.Fatalf("expected delayed VM error for vm-1, got %v", err)
+	}
+
+	if len(retries) != 1 {
+		t.Fatalf("expected 1 retry, got %d", len(retries))
+	}
+}
@@ -0,0 +1,129 @@
+package experiment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+
+	"phenix/util/cache"
+	"phenix/util/shell"
+)
+
+type topologySVG struct {
+	hash [sha256.Size]byte
+	svg  []byte
+}
+
+// TopologyDOT generates a Graphviz DOT graph of the given experiment's topology.
+// Each VM is a node labeled with its hostname and IP addresses, and each VLAN is
+// a node every VM with an interface on the VLAN is connected to. Connecting VMs
+// through VLAN nodes rather than to each other keeps the number of edges linear
+// in the number of interfaces, even for VLANs with a lot of VMs.
+func TopologyDOT(name string) ([]byte, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	var (
+		buf   bytes.Buffer
+		vlans = make(map[string][]string)
+	)
+
+	fmt.Fprintf(&buf, "graph %q {\n", name)
+	buf.WriteString("\tnode [shape=box];\n")
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		var (
+			hostname = node.General().Hostname()
+			label    = []string{hostname}
+		)
+
+		for _, iface := range node.Network().Interfaces() {
+			if iface.Address() != "" {
+				label = append(label, iface.Address())
+			}
+
+			if iface.VLAN() != "" {
+				vlans[iface.VLAN()] = append(vlans[iface.VLAN()], hostname)
+			}
+		}
+
+		fmt.Fprintf(&buf, "\t%q [label=%q];\n", hostname, strings.Join(label, "\n"))
+	}
+
+	names := make([]string, 0, len(vlans))
+
+	for vlan := range vlans {
+		names = append(names, vlan)
+	}
+
+	sort.Strings(names)
+
+	for _, vlan := range names {
+		// Prefixed so VLAN nodes can't collide with VMs of the same name.
+		id := "vlan/" + vlan
+
+		fmt.Fprintf(&buf, "\t%q [label=%q, shape=ellipse];\n", id, vlan)
+
+		linked := make(map[string]struct{})
+
+		for _, host := range vlans[vlan] {
+			// VMs with multiple interfaces on the VLAN are only linked to it once.
+			if _, ok := linked[host]; ok {
+				continue
+			}
+
+			linked[host] = struct{}{}
+
+			fmt.Fprintf(&buf, "\t%q -- %q;\n", host, id)
+		}
+	}
+
+	buf.WriteString("}\n")
+
+	return buf.Bytes(), nil
+}
+
+// TopologySVG renders the DOT graph generated by TopologyDOT to SVG using the
+// Graphviz `dot` command. The rendered SVG is cached until the DOT graph for
+// the experiment changes.
+func TopologySVG(ctx context.Context, name string) ([]byte, error) {
+	dot, err := TopologyDOT(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		key  = fmt.Sprintf("experiment|%s|topology-svg", name)
+		hash = sha256.Sum256(dot)
+	)
+
+	if val, ok := cache.Get(key); ok {
+		if cached := val.(topologySVG); cached.hash == hash {
+			return cached.svg, nil
+		}
+	}
+
+	if !shell.CommandExists("dot") {
+		return nil, fmt.Errorf("graphviz dot command not found")
+	}
+
+	opts := []shell.Option{
+		shell.Command("dot"),
+		shell.Args("-Tsvg"),
+		shell.Stdin(dot),
+	}
+
+	svg, stderr, err := shell.ExecCommand(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("rendering topology SVG: %w (%s)", err, strings.TrimSpace(string(stderr)))
+	}
+
+	cache.Set(key, topologySVG{hash: hash, svg: svg})
+
+	return svg, nil
+}
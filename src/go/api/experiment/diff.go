@@ -0,0 +1,340 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+)
+
+// SpecDiff is the difference between an experiment's stored config and a
+// candidate config for it. VMs are keyed by hostname, VLANs by alias, and apps
+// by name. Changes that can't be applied while the experiment is running (the
+// same ones rejected by Patch) are marked as requiring a restart.
+type SpecDiff struct {
+	VMs   ItemsDiff `json:"vms"`
+	VLANs ItemsDiff `json:"vlans"`
+	Apps  ItemsDiff `json:"apps"`
+
+	// Changes to any other spec fields, by path relative to the spec.
+	Spec []Change `json:"spec"`
+
+	// Changes to the experiment's annotations, by key. These never require a
+	// restart.
+	Annotations []Change `json:"annotations"`
+
+	// Changes to the documentation annotations in the experiment's spec, by key.
+	// Like the annotations above, these never require a restart.
+	SpecAnnotations []Change `json:"specAnnotations"`
+
+	// Whether the experiment is running, and whether any of the changes require
+	// it to be restarted to take effect.
+	Running         bool `json:"running"`
+	RestartRequired bool `json:"restartRequired"`
+}
+
+// ItemsDiff is the difference between two sets of named items.
+type ItemsDiff struct {
+	Added   []string     `json:"added"`
+	Removed []string     `json:"removed"`
+	Changed []ItemChange `json:"changed"`
+
+	// Whether changes to the items require a restart.
+	Restart bool `json:"restart"`
+}
+
+// ItemChange is the changes to an item present in both configs.
+type ItemChange struct {
+	Name    string   `json:"name"`
+	Changes []Change `json:"changes"`
+}
+
+// Change is a changed value, by dot-separated path. From is omitted for added
+// values and To for removed ones.
+type Change struct {
+	Path    string `json:"path"`
+	From    any    `json:"from,omitempty"`
+	To      any    `json:"to,omitempty"`
+	Restart bool   `json:"restart"`
+}
+
+func (this ItemsDiff) empty() bool {
+	return len(this.Added) == 0 && len(this.Removed) == 0 && len(this.Changed) == 0
+}
+
+// Diff returns the difference between the stored config for the experiment with
+// the given name and the given candidate config. The candidate is validated
+// first. Its experiment name is ignored, since it can't be changed.
+func Diff(name string, candidate store.Config) (*SpecDiff, error) {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment %s: %w", name, err)
+	}
+
+	if candidate.Kind != "Experiment" {
+		return nil, fmt.Errorf("%w: candidate config is a %s, not an experiment", types.ErrValidationFailed, candidate.Kind)
+	}
+
+	if candidate.Spec == nil {
+		candidate.Spec = make(map[string]any)
+	}
+
+	candidate.Spec["experimentName"] = name
+
+	if err := types.ValidateConfigSpec(candidate); err != nil {
+		return nil, fmt.Errorf("validating candidate config for experiment %s: %w", name, err)
+	}
+
+	cand, err := types.DecodeExperimentFromConfig(candidate)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding candidate config for experiment %s: %v", types.ErrValidationFailed, name, err)
+	}
+
+	// Compare the decoded specs so defaults applied when decoding don't show up
+	// as changes, and values decoded differently (e.g. ints from YAML versus
+	// floats from JSON) compare equal.
+	var (
+		from = normalizeSpec(exp.Spec)
+		to   = normalizeSpec(cand.Spec)
+	)
+
+	diff := SpecDiff{
+		VMs:   diffItems(takeList(from, "topology", "nodes"), takeList(to, "topology", "nodes"), "general.hostname"),
+		VLANs: diffItems(takeAliases(from), takeAliases(to), "alias"),
+		Apps:  diffItems(takeList(from, "scenario", "apps"), takeList(to, "scenario", "apps"), "name"),
+
+		SpecAnnotations: diffValues("", takeMap(from, "annotations"), takeMap(to, "annotations")),
+
+		Running: exp.Running(),
+	}
+
+	diff.VMs.Restart = requiresRestart("topology")
+	diff.VLANs.Restart = requiresRestart("vlans")
+	diff.Apps.Restart = requiresRestart("scenario")
+
+	for _, items := range []*ItemsDiff{&diff.VMs, &diff.VLANs, &diff.Apps} {
+		for i := range items.Changed {
+			for j := range items.Changed[i].Changes {
+				items.Changed[i].Changes[j].Restart = items.Restart
+			}
+		}
+
+		if items.Restart && !items.empty() {
+			diff.RestartRequired = true
+		}
+	}
+
+	diff.Spec = diffValues("", from, to)
+
+	for i, change := range diff.Spec {
+		diff.Spec[i].Restart = requiresRestart(strings.Split(change.Path, ".")[0])
+
+		if diff.Spec[i].Restart {
+			diff.RestartRequired = true
+		}
+	}
+
+	diff.Annotations = diffValues("", stringMap(c.Metadata.Annotations), stringMap(candidate.Metadata.Annotations))
+
+	return &diff, nil
+}
+
+func requiresRestart(field string) bool {
+	_, ok := runningImmutableFields[field]
+	return ok
+}
+
+func normalizeSpec(spec any) map[string]any {
+	normalized := make(map[string]any)
+
+	body, _ := json.Marshal(spec)
+	json.Unmarshal(body, &normalized)
+
+	return normalized
+}
+
+// takeList removes the list at the given key of the given nested object from
+// the spec and returns it. The nested object is removed too if it's left empty.
+func takeList(spec map[string]any, obj, key string) []any {
+	m, _ := spec[obj].(map[string]any)
+	if m == nil {
+		return nil
+	}
+
+	list, _ := m[key].([]any)
+	delete(m, key)
+
+	if len(m) == 0 {
+		delete(spec, obj)
+	}
+
+	return list
+}
+
+// takeMap removes the object at the given key from the spec and returns it,
+// or an empty object if it's not set.
+func takeMap(spec map[string]any, key string) map[string]any {
+	m, _ := spec[key].(map[string]any)
+	delete(spec, key)
+
+	if m == nil {
+		m = make(map[string]any)
+	}
+
+	return m
+}
+
+// takeAliases removes the VLAN aliases from the spec and returns them as a list
+// of items keyed by alias.
+func takeAliases(spec map[string]any) []any {
+	m, _ := spec["vlans"].(map[string]any)
+	if m == nil {
+		return nil
+	}
+
+	aliases, _ := m["aliases"].(map[string]any)
+	delete(m, "aliases")
+
+	if len(m) == 0 {
+		delete(spec, "vlans")
+	}
+
+	var items []any
+
+	for alias, id := range aliases {
+		items = append(items, map[string]any{"alias": alias, "id": id})
+	}
+
+	return items
+}
+
+// diffItems compares two lists of objects, matching them by the value at the
+// given dot-separated path.
+func diffItems(from, to []any, path string) ItemsDiff {
+	var (
+		diff    = ItemsDiff{Added: []string{}, Removed: []string{}, Changed: []ItemChange{}}
+		fromMap = keyItems(from, path)
+		toMap   = keyItems(to, path)
+	)
+
+	for _, name := range sortedKeys(fromMap) {
+		if _, ok := toMap[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	for _, name := range sortedKeys(toMap) {
+		prev, ok := fromMap[name]
+		if !ok {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+
+		if changes := diffValues("", prev, toMap[name]); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, ItemChange{Name: name, Changes: changes})
+		}
+	}
+
+	return diff
+}
+
+func keyItems(items []any, path string) map[string]any {
+	keyed := make(map[string]any)
+
+	for _, item := range items {
+		var (
+			v  = item
+			ok = true
+		)
+
+		for _, token := range strings.Split(path, ".") {
+			var m map[string]any
+
+			if m, ok = v.(map[string]any); !ok {
+				break
+			}
+
+			v = m[token]
+		}
+
+		if key, _ := v.(string); ok && key != "" {
+			keyed[key] = item
+		}
+	}
+
+	return keyed
+}
+
+// diffValues returns the changes between two values, recursing into objects so
+// each change is reported at the deepest path that differs. Lists are compared
+// as a whole.
+func diffValues(prefix string, from, to any) []Change {
+	changes := []Change{}
+
+	var (
+		fromMap, fromOK = from.(map[string]any)
+		toMap, toOK     = to.(map[string]any)
+	)
+
+	if !fromOK || !toOK {
+		if !reflect.DeepEqual(from, to) {
+			changes = append(changes, Change{Path: prefix, From: from, To: to})
+		}
+
+		return changes
+	}
+
+	keys := make(map[string]any)
+
+	for k := range fromMap {
+		keys[k] = nil
+	}
+
+	for k := range toMap {
+		keys[k] = nil
+	}
+
+	for _, k := range sortedKeys(keys) {
+		path := k
+
+		if prefix != "" {
+			path = prefix + "." + k
+		}
+
+		changes = append(changes, diffValues(path, fromMap[k], toMap[k])...)
+	}
+
+	return changes
+}
+
+func stringMap(m map[string]string) map[string]any {
+	converted := make(map[string]any)
+
+	for k, v := range m {
+		converted[k] = v
+	}
+
+	return converted
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,47 @@
+package experiment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffItems(t *testing.T) {
+	node := func(hostname string, memory float64) map[string]any {
+		return testNode(hostname, map[string]any{"hardware.memory": memory, "hardware.os_type": "linux"})
+	}
+
+	var (
+		from = []any{node("host", 512), node("router", 1024)}
+		to   = []any{node("host", 2048), node("fw", 1024)}
+	)
+
+	diff := diffItems(from, to, "general.hostname")
+
+	expected := ItemsDiff{
+		Added:   []string{"fw"},
+		Removed: []string{"router"},
+		Changed: []ItemChange{
+			{Name: "host", Changes: []Change{{Path: "hardware.memory", From: 512.0, To: 2048.0}}},
+		},
+	}
+
+	if !reflect.DeepEqual(diff, expected) {
+		t.Fatalf("expected diff %+v, got %+v", expected, diff)
+	}
+}
+
+func TestDiffValues(t *testing.T) {
+	var (
+		from = map[string]any{"tags": map[string]any{"a": "1", "b": "2"}, "deployMode": "all"}
+		to   = map[string]any{"tags": map[string]any{"a": "1", "c": "3"}, "deployMode": "all"}
+	)
+
+	expected := []Change{
+		{Path: "tags.b", From: "2"},
+		{Path: "tags.c", To: "3"},
+	}
+
+	if changes := diffValues("", from, to); !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected changes %+v, got %+v", expected, changes)
+	}
+}
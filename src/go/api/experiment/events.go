@@ -0,0 +1,61 @@
+package experiment
+
+import (
+	"fmt"
+
+	"phenix/store"
+)
+
+// RecordEvent appends a lifecycle event for the given experiment to the store.
+// The action is the lifecycle transition (e.g. `starting` or `errorStopping`),
+// and the user, if not empty, is who initiated it. If err is not nil, it's
+// included in the event as the reason for the transition.
+func RecordEvent(name, action, user string, err error) error {
+	return RecordEventWithMetadata(name, action, user, nil, err)
+}
+
+// RecordEventWithMetadata is like RecordEvent, but also includes the given
+// metadata (e.g. the VM an action was taken against) in the event.
+func RecordEventWithMetadata(name, action, user string, md map[string]string, err error) error {
+	event := store.NewLifecycleEvent("experiment %s %s", name, action)
+
+	// Set first so it can't override the experiment, action or user.
+	for k, v := range md {
+		event.WithMetadata(k, v)
+	}
+
+	event.WithMetadata("experiment", name).WithMetadata("action", action)
+
+	if user != "" {
+		event.WithMetadata("user", user)
+	}
+
+	if err != nil {
+		event.Message = fmt.Sprintf("%s: %v", event.Message, err)
+		event.WithMetadata("error", err.Error())
+	}
+
+	if err := store.AddEvent(*event); err != nil {
+		return fmt.Errorf("recording %s event for experiment %s: %w", action, name, err)
+	}
+
+	return nil
+}
+
+// Events returns the lifecycle events recorded for the given experiment, oldest
+// first.
+func Events(name string) (store.Events, error) {
+	filter := store.Event{
+		Type:     store.EventTypeLifecycle,
+		Metadata: map[string]string{"experiment": name},
+	}
+
+	events, err := store.GetEventsBy(filter)
+	if err != nil {
+		return nil, fmt.Errorf("getting events for experiment %s: %w", name, err)
+	}
+
+	events.SortByTimestamp(true)
+
+	return events, nil
+}
@@ -2,11 +2,13 @@ package experiment
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -22,7 +24,6 @@ import (
 	"phenix/util/common"
 	"phenix/util/file"
 	"phenix/util/mm"
-	"phenix/util/mm/mmcli"
 	"phenix/util/notes"
 	"phenix/util/plog"
 	"phenix/util/pubsub"
@@ -35,6 +36,13 @@ import (
 var (
 	ErrExperimentNotFound   = errors.New("experiment not found")
 	ErrExperimentNotRunning = errors.New("experiment not running")
+	ErrExperimentRunning    = errors.New("experiment already running")
+	ErrScheduleInfeasible   = errors.New("schedule infeasible")
+	ErrHostUnreachable      = errors.New("host unreachable")
+
+	// ErrLaunchFailed is wrapped by errors from minimega while launching an
+	// experiment's VMs, which may succeed if the start is retried.
+	ErrLaunchFailed = errors.New("launch failed")
 )
 
 func init() {
@@ -248,6 +256,8 @@ func Get(name string) (*types.Experiment, error) {
 func Create(ctx context.Context, opts ...CreateOption) error {
 	o := newCreateOptions(opts...)
 
+	defer InvalidateCached(o.name)
+
 	if o.name == "" {
 		return fmt.Errorf("no experiment name provided")
 	}
@@ -337,6 +347,10 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		}
 	}
 
+	if o.owner != "" {
+		meta.Annotations[accessAnnotationPrefix+o.owner] = string(PermissionOwner)
+	}
+
 	c := &store.Config{
 		Version:  store.API_GROUP + "/" + apiVersion,
 		Kind:     kind,
@@ -373,6 +387,8 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 func Schedule(opts ...ScheduleOption) error {
 	o := newScheduleOptions(opts...)
 
+	defer InvalidateCached(o.name)
+
 	c, _ := store.NewConfig("experiment/" + o.name)
 
 	if err := store.Get(c); err != nil {
@@ -403,9 +419,50 @@ func Schedule(opts ...ScheduleOption) error {
 
 // Start starts the experiment with the given name. It returns any errors
 // encountered while starting the experiment.
-func Start(ctx context.Context, opts ...StartOption) error {
+func Start(ctx context.Context, opts ...StartOption) (err error) {
 	o := newStartOptions(opts...)
 
+	var trace *startTrace
+
+	if o.trace {
+		trace = newStartTrace(o.name)
+
+		// Starts that continue in the background once Start returns are finished
+		// tracing once they're done.
+		var proxied chan error
+
+		if o.errChan != nil {
+			proxied = trace.proxy(o.errChan)
+			o.errChan = proxied
+		}
+
+		defer func() {
+			if err != nil {
+				// Finished before the proxy is closed, since closing it finishes
+				// the trace without the error.
+				trace.finish(err)
+
+				if proxied != nil {
+					close(proxied)
+				}
+			} else if proxied == nil {
+				trace.finish(nil)
+			}
+		}()
+
+		trace.state("starting", "dry run: %t", o.dryrun)
+	}
+
+	if len(o.vars) > 0 {
+		ctx = app.SetContextVars(ctx, o.vars)
+	}
+
+	defer InvalidateCached(o.name)
+
+	// Starting commits resources on the cluster hosts, even if it fails part way
+	// through.
+	defer mm.InvalidateClusterHosts()
+
 	c, _ := store.NewConfig("experiment/" + o.name)
 
 	if err := store.Get(c); err != nil {
@@ -419,7 +476,15 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 	if exp.Running() {
 		if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
-			return fmt.Errorf("experiment already running (started at: %s)", exp.Status.StartTime())
+			if o.idempotent {
+				if o.errChan != nil {
+					close(o.errChan)
+				}
+
+				return nil
+			}
+
+			return fmt.Errorf("%w (started at: %s)", ErrExperimentRunning, exp.Status.StartTime())
 		}
 	}
 
@@ -431,19 +496,109 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		exp.Spec.VLANs().SetMax(o.vlanMax)
 	}
 
+	if len(o.schedule) > 0 {
+		schedule := exp.Spec.Schedules()
+
+		for vm, host := range o.schedule {
+			schedule[vm] = host
+		}
+
+		exp.Spec.SetSchedule(schedule)
+	}
+
+	var gpus map[string][]string
+
+	// GPU VMs are placed before running any scheduling algorithm so they end up
+	// on hosts with free GPUs. Dry runs don't use any cluster resources.
+	if !o.dryrun && len(requestedGPUs(exp)) > 0 {
+		cluster, _, err := mm.GetCachedClusterHosts(false)
+		if err != nil {
+			return fmt.Errorf("%w: getting cluster hosts: %w", ErrGPUUnavailable, err)
+		}
+
+		if gpus, err = scheduleGPUs(exp, cluster, true); err != nil {
+			return fmt.Errorf("scheduling GPUs: %w", err)
+		}
+
+		defer releaseGPUs(exp.Metadata.Name)
+
+		trace.state("gpus", "%s", gpuDeviceSummary(gpus))
+	}
+
+	if o.scheduler != "" {
+		if err := scheduler.Schedule(o.scheduler, exp.Spec); err != nil {
+			return fmt.Errorf("%w: running %s scheduler algorithm: %w", ErrScheduleInfeasible, o.scheduler, err)
+		}
+	}
+
+	trace.state("scheduled", "%v", exp.Spec.Schedules())
+
+	// Checked for dry runs too, so they catch VMs scheduled on dead hosts.
+	if err := checkScheduledHosts(exp); err != nil {
+		return fmt.Errorf("checking scheduled hosts: %w", err)
+	}
+
+	var cluster mm.Hosts
+
+	// Dry runs don't use any cluster resources.
+	if !o.dryrun {
+		if cluster, _, err = mm.GetCachedClusterHosts(false); err != nil {
+			notes.AddWarnings(ctx, false, fmt.Errorf("unable to check cluster capacity: %w", err))
+		}
+	}
+
+	if err := checkQuota(exp, cluster); err != nil {
+		return fmt.Errorf("checking experiment resources: %w", err)
+	}
+
+	trace.state("prestart", "applying pre-start apps")
+
 	if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPRESTART), app.DryRun(o.dryrun)); err != nil {
 		return fmt.Errorf("applying apps to experiment: %w", err)
 	}
 
+	// Checked after applying the pre-start apps since they generate many of the
+	// files injected into VMs.
+	injections, err := checkInjections(ctx, exp)
+	if err != nil {
+		return fmt.Errorf("checking VM file injections: %w", err)
+	}
+
 	var (
 		mmScript = fmt.Sprintf("%s/mm_files/%s.mm", exp.Spec.BaseDir(), exp.Spec.ExperimentName())
 		ccScript = fmt.Sprintf("%s/mm_files/%s-cc.mm", exp.Spec.BaseDir(), exp.Spec.ExperimentName())
 	)
 
-	if err := tmpl.CreateFileFromTemplate("minimega_script.tmpl", exp.Spec, mmScript); err != nil {
+	// Allocated VLAN IDs are only used in the minimega script, so the aliases in
+	// the experiment spec are left as they were for the next start.
+	aliases := exp.Spec.VLANs().Aliases()
+
+	if len(o.vlanAllocation) > 0 {
+		allocated := make(map[string]int)
+
+		for alias, id := range aliases {
+			allocated[alias] = id
+		}
+
+		for alias, id := range o.vlanAllocation {
+			allocated[alias] = id
+		}
+
+		exp.Spec.VLANs().SetAliases(allocated)
+	}
+
+	err = tmpl.CreateFileFromTemplate("minimega_script.tmpl", scriptSpec{ExperimentSpec: exp.Spec, gpus: gpus}, mmScript)
+
+	if len(o.vlanAllocation) > 0 {
+		exp.Spec.VLANs().SetAliases(aliases)
+	}
+
+	if err != nil {
 		return fmt.Errorf("generating minimega script: %w", err)
 	}
 
+	trace.state("script", "generated minimega script %s", mmScript)
+
 	if exp.Spec.Topology().HasCommands() {
 		if err := tmpl.CreateFileFromTemplate("minimega_cc_script.tmpl", exp.Spec.Topology().Nodes(), ccScript); err != nil {
 			return fmt.Errorf("generating minimega cc script: %w", err)
@@ -457,6 +612,21 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 	if o.dryrun {
 		exp.Status.SetVLANs(exp.Spec.VLANs().Aliases())
+
+		// Already ordered successfully when applying the apps above.
+		apps, _ := app.OrderApps(exp.Apps())
+
+		var order []string
+
+		for _, a := range apps {
+			if !a.Disabled() {
+				order = append(order, a.Name())
+			}
+		}
+
+		if len(order) > 0 {
+			notes.AddInfo(ctx, false, fmt.Sprintf("experiment apps will run in order: %s", strings.Join(order, ", ")))
+		}
 	} else {
 		// Delete any snapshot files created by this headnode for this experiment
 		// previously before starting the experiment. This way, cluster nodes that VMs
@@ -471,7 +641,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		if err := mm.ReadScriptFromFile(mmScript); err != nil {
 			if !o.mmErrAsWarn {
 				mm.ClearNamespace(exp.Spec.ExperimentName())
-				return fmt.Errorf("reading minimega script: %w", err)
+				return fmt.Errorf("%w: reading minimega script: %w", ErrLaunchFailed, err)
 			}
 
 			if merr, ok := err.(*multierror.Error); ok {
@@ -481,6 +651,13 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			}
 		}
 
+		// The minimega script creates each VM's disk snapshot but only queues the
+		// VMs to be launched, so files can still be injected into the snapshots.
+		if err := injectFiles(ctx, injections); err != nil {
+			mm.ClearNamespace(exp.Spec.ExperimentName())
+			return fmt.Errorf("injecting VM files: %w", err)
+		}
+
 		var (
 			bootable = exp.Spec.Topology().BootableNodes()
 			start    = make([]string, 0) // nil vs. slice makes a difference here
@@ -532,17 +709,42 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			start = append(start, hostname)
 		}
 
-		if len(start) == len(bootable) {
-			// Reset start slice so the call to mm.LaunchVMs results in `vm start all`
-			// being used (to reduce calls to minimega). A nil slice vs. an empty
-			// slice makes a difference here.
-			start = nil
+		// Determined before launching since start is reset below when every VM
+		// is launched at once.
+		probes, err := readinessProbes(exp, start, o.readinessProbe)
+		if err != nil {
+			mm.ClearNamespace(exp.Spec.ExperimentName())
+			return fmt.Errorf("determining VM readiness probes: %w", err)
+		}
+
+		// Starts given up on (e.g. timed out) before launching don't launch
+		// anything.
+		if ctx.Err() != nil {
+			mm.ClearNamespace(exp.Spec.ExperimentName())
+			return fmt.Errorf("starting experiment: %w", context.Cause(ctx))
+		}
+
+		trace.state("launching", "launching %d of %d bootable VMs (%d delayed)", len(start), len(bootable), len(delays)+len(c2s))
+
+		if groups := bootGroups(exp, start); groups != nil {
+			err = launchVMsInBootOrder(ctx, exp.Spec.ExperimentName(), groups, o.maxConcurrentLaunch, o.bootGroupTimeout, o.bootGroupProgress)
+		} else if o.maxConcurrentLaunch > 0 && len(start) > o.maxConcurrentLaunch {
+			err = launchVMsBatched(ctx, exp.Spec.ExperimentName(), start, o.maxConcurrentLaunch)
+		} else {
+			if len(start) == len(bootable) {
+				// Reset start slice so the call to mm.LaunchVMs results in `vm start all`
+				// being used (to reduce calls to minimega). A nil slice vs. an empty
+				// slice makes a difference here.
+				start = nil
+			}
+
+			err = mm.LaunchVMs(exp.Spec.ExperimentName(), start...)
 		}
 
-		if err := mm.LaunchVMs(exp.Spec.ExperimentName(), start...); err != nil {
+		if err != nil {
 			if !o.mmErrAsWarn {
 				mm.ClearNamespace(exp.Spec.ExperimentName())
-				return fmt.Errorf("launching experiment VMs: %w", err)
+				return fmt.Errorf("%w: launching experiment VMs: %w", ErrLaunchFailed, err)
 			}
 
 			if merr, ok := err.(*multierror.Error); ok {
@@ -561,7 +763,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 			if err := mm.CreateBridge(mm.NS(exp.Metadata.Name), mm.Bridge(exp.Spec.DefaultBridge())); err != nil {
 				if !o.mmErrAsWarn {
 					mm.ClearNamespace(exp.Spec.ExperimentName())
-					return fmt.Errorf("creating experiment bridge: %w", err)
+					return fmt.Errorf("%w: creating experiment bridge: %w", ErrLaunchFailed, err)
 				}
 
 				if merr, ok := err.(*multierror.Error); ok {
@@ -580,13 +782,56 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 		exp.Status.SetSchedule(schedule)
 
+		trace.state("launched", "VMs launched on %v", schedule)
+
 		vlans, err := mm.GetVLANs(mm.NS(exp.Spec.ExperimentName()))
 		if err != nil {
 			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("processing experiment VLANs: %w", err)
+			return fmt.Errorf("%w: processing experiment VLANs: %w", ErrLaunchFailed, err)
 		}
 
 		exp.Status.SetVLANs(vlans)
+		exp.Status.SetGPUs(gpus)
+
+		if o.verifyIsolation {
+			report, err := checkIsolation(exp)
+			if err != nil {
+				mm.ClearNamespace(exp.Spec.ExperimentName())
+				return fmt.Errorf("verifying experiment isolation: %w", err)
+			}
+
+			trace.state("isolation", "checked against %v, %d overlaps", report.Checked, len(report.Overlaps))
+
+			if !report.Isolated {
+				details := make([]string, len(report.Overlaps))
+
+				for i, overlap := range report.Overlaps {
+					details[i] = overlap.Detail
+				}
+
+				mm.ClearNamespace(exp.Spec.ExperimentName())
+				return fmt.Errorf("%w: %s", ErrIsolationViolated, strings.Join(details, "; "))
+			}
+		}
+
+		if len(probes) > 0 {
+			trace.state("booting", "waiting for %d VMs to be ready", len(probes))
+
+			if err := waitForReadiness(ctx, exp, probes, o.readinessTimeout, o.readinessProgress); err != nil {
+				mm.ClearNamespace(exp.Spec.ExperimentName())
+				return fmt.Errorf("waiting for experiment VMs to be ready: %w", err)
+			}
+
+			trace.state("ready", "%d VMs ready", len(probes))
+		}
+	}
+
+	// Starts given up on while launching aren't marked as running, since whoever
+	// gave up on them is cleaning up after them. Checked before anything is left
+	// running in the background below.
+	if ctx.Err() != nil {
+		mm.ClearNamespace(exp.Spec.ExperimentName())
+		return fmt.Errorf("starting experiment: %w", context.Cause(ctx))
 	}
 
 	start := time.Now().Format(time.RFC3339)
@@ -609,7 +854,8 @@ func Start(ctx context.Context, opts ...StartOption) error {
 				}
 			}
 
-			if err := handleDelayedVMs(ctx, exp.Spec.ExperimentName(), delays, c2s); err != nil {
+			failed, err := handleDelayedVMs(ctx, exp.Spec.ExperimentName(), delays, c2s, o.delayedRetries, o.delayedRetryProgress)
+			if err != nil {
 				errors := multierror.Append(nil, fmt.Errorf("handling delayed VMs: %w", err))
 
 				if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
@@ -618,8 +864,14 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 				return errors
 			}
+
+			for _, err := range failed {
+				notes.AddWarnings(ctx, false, err)
+			}
 		}
 
+		trace.state("poststart", "applying post-start apps")
+
 		if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
 			errors := multierror.Append(nil, fmt.Errorf("applying apps to experiment: %w", err))
 
@@ -650,7 +902,8 @@ func Start(ctx context.Context, opts ...StartOption) error {
 					}
 				}
 
-				if err := handleDelayedVMs(ctx, exp.Spec.ExperimentName(), delays, c2s); err != nil {
+				failed, err := handleDelayedVMs(ctx, exp.Spec.ExperimentName(), delays, c2s, o.delayedRetries, o.delayedRetryProgress)
+				if err != nil {
 					o.errChan <- fmt.Errorf("handling delayed VMs: %w", err)
 
 					if err := Stop(exp.Spec.ExperimentName()); err != nil {
@@ -659,8 +912,16 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 					return
 				}
+
+				// Each delayed VM that failed to start is reported once, after it's
+				// been retried, so it can be retried again individually.
+				for _, err := range failed {
+					o.errChan <- err
+				}
 			}
 
+			trace.state("poststart", "applying post-start apps")
+
 			if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
 				o.errChan <- fmt.Errorf("applying apps to experiment: %w", err)
 
@@ -673,6 +934,14 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 	exp.Status.SetStartTime(start)
 
+	// Every VM was just launched, so any marked as errored by an earlier
+	// reconcile no longer are.
+	for k := range c.Metadata.Annotations {
+		if strings.HasPrefix(k, VMErrorAnnotationPrefix) {
+			delete(c.Metadata.Annotations, k)
+		}
+	}
+
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
 
@@ -690,7 +959,16 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 // Stop stops the experiment with the given name. It returns any errors
 // encountered while stopping the experiment.
-func Stop(name string) error {
+func Stop(name string, opts ...StopOption) error {
+	o := newStopOptions(opts...)
+
+	if o.snapshotErr != nil {
+		return o.snapshotErr
+	}
+
+	defer InvalidateCached(name)
+	defer mm.InvalidateClusterHosts()
+
 	c, _ := store.NewConfig("experiment/" + name)
 
 	if err := store.Get(c); err != nil {
@@ -703,24 +981,60 @@ func Stop(name string) error {
 	}
 
 	if !exp.Running() {
-		return fmt.Errorf("experiment isn't running")
+		return fmt.Errorf("stopping experiment %s: %w", name, ErrExperimentNotRunning)
 	}
 
 	dryrun := strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN")
 
 	var errors error
 
-	if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(dryrun)); err != nil {
+	if o.snapshot != "" && !dryrun {
+		snapshots := snapshotVMs(exp.Spec.ExperimentName(), o)
+
+		body, _ := json.Marshal(snapshots)
+
+		if c.Metadata.Annotations == nil {
+			c.Metadata.Annotations = make(map[string]string)
+		}
+
+		c.Metadata.Annotations["snapshot/"+o.snapshot] = string(body)
+	}
+
+	if err := app.ApplyApps(o.ctx, exp, app.Stage(app.ACTIONCLEANUP), app.DryRun(dryrun)); err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("cleaning up app experiments: %w", err))
 	}
 
 	if !dryrun {
+		if o.graceful > 0 {
+			shutdownVMs(exp, o)
+		}
+
+		if !o.parallel {
+			if err := killVMsInBootOrder(exp, o); err != nil {
+				errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs in reverse boot order: %w", err))
+			}
+		}
+
 		if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
-			errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs: %w", err))
+			if o.force {
+				plog.Warn("unable to clear experiment namespace, forcibly killing VMs", "exp", name, "err", err)
+
+				err = forceClearNamespace(exp.Spec.ExperimentName(), o)
+			}
+
+			if err != nil {
+				errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs: %w", err))
+			}
 		}
 	}
 
 	exp.Status.SetStartTime("")
+	exp.Status.SetPaused(false)
+	exp.Status.SetGPUs(nil)
+
+	for vlan := range exp.Status.LinkImpairments() {
+		exp.Status.SetLinkImpairment(vlan, nil)
+	}
 
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
@@ -736,6 +1050,232 @@ func Stop(name string) error {
 	return errors
 }
 
+// killVMsInBootOrder kills the experiment's VMs in reverse boot order, so
+// services started later (and likely depending on earlier ones) are stopped
+// first. It's a no-op if none of the VMs have a boot order set.
+func killVMsInBootOrder(exp *types.Experiment, o stopOptions) error {
+	ns := exp.Spec.ExperimentName()
+
+	var names []string
+
+	for _, vm := range mm.GetVMInfo(mm.NS(ns)) {
+		// VMs already shut down can't be killed.
+		if vm.State != "QUIT" {
+			names = append(names, vm.Name)
+		}
+	}
+
+	groups := bootGroups(exp, names)
+	if groups == nil {
+		return nil
+	}
+
+	return killVMsInTeardownOrder(o.ctx, ns, teardownGroups(groups), o.teardownTimeout, o.teardownProgress)
+}
+
+// forceClearNamespace kills the processes backing any VMs minimega wasn't able
+// to kill before trying to clear the namespace again. The VMs are killed at
+// once, giving up on any not killed within the force kill timeout.
+func forceClearNamespace(ns string, o stopOptions) error {
+	ctx, cancel := context.WithTimeout(o.ctx, o.forceTimeout)
+	defer cancel()
+
+	var remaining []string
+
+	for _, vm := range mm.GetVMInfo(mm.NS(ns)) {
+		remaining = append(remaining, vm.Name)
+	}
+
+	if len(remaining) > 0 {
+		o.progress(remaining)
+	}
+
+	var (
+		killed = make(chan error, len(remaining))
+		errs   error
+	)
+
+	for _, vm := range remaining {
+		go func(vm string) {
+			killed <- mm.ForceKillVM(mm.NS(ns), mm.VMName(vm))
+		}(vm)
+	}
+
+	for pending := len(remaining); pending > 0; pending-- {
+		select {
+		case err := <-killed:
+			if err != nil {
+				errs = multierror.Append(errs, err)
+			}
+		case <-ctx.Done():
+			return multierror.Append(errs, fmt.Errorf("%d VMs in namespace %s not killed: %w", pending, ns, ctx.Err()))
+		}
+	}
+
+	if err := mm.ClearNamespace(ns); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return errs
+}
+
+// CleanupStart cleans up after a start of the experiment with the given name
+// that didn't finish, such as one that was canceled, by running the cleanup
+// stage of its apps and killing any VMs that were already launched. Running
+// experiments are left alone, since they should be stopped instead.
+func CleanupStart(ctx context.Context, name string) error {
+	defer InvalidateCached(name)
+
+	exp, err := Get(name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if exp.Running() {
+		return ErrExperimentRunning
+	}
+
+	var errs error
+
+	if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONCLEANUP)); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("cleaning up app experiments: %w", err))
+	}
+
+	if len(mm.GetVMInfo(mm.NS(exp.Spec.ExperimentName()))) > 0 {
+		if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("killing experiment VMs: %w", err))
+		}
+	}
+
+	return errs
+}
+
+// Pause pauses all the running VMs in the experiment with the given name,
+// leaving the experiment itself in the running state. It returns any errors
+// encountered while pausing the experiment.
+func Pause(name string) error {
+	defer InvalidateCached(name)
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if !exp.Running() {
+		return ErrExperimentNotRunning
+	}
+
+	if exp.Status.Paused() {
+		return fmt.Errorf("experiment is already paused")
+	}
+
+	var paused []string
+
+	if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
+		// Only VMs actually running are paused, so VMs that are still delayed or
+		// were stopped on purpose aren't started when the experiment is resumed.
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			if vm.Running {
+				paused = append(paused, vm.Name)
+			}
+		}
+
+		if err := mm.StopVM(mm.NS(name), mm.VMName("all")); err != nil {
+			return fmt.Errorf("pausing experiment VMs: %w", err)
+		}
+	}
+
+	exp.Status.SetPaused(true)
+
+	if c.Metadata.Annotations == nil {
+		c.Metadata.Annotations = make(store.Annotations)
+	}
+
+	c.Metadata.Annotations[pausedVMsAnnotation] = strings.Join(paused, ",")
+	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	for _, hook := range hooks["pause"] {
+		hook("pause", name)
+	}
+
+	return nil
+}
+
+// Names of the VMs paused when pausing an experiment, separated by commas,
+// are saved as this annotation on the experiment's config so only they are
+// started when it's resumed.
+const pausedVMsAnnotation = "pausedVMs"
+
+// Resume starts the VMs that were paused in the paused experiment with the
+// given name. It returns any errors encountered while resuming the experiment.
+func Resume(name string) error {
+	defer InvalidateCached(name)
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if !exp.Running() {
+		return ErrExperimentNotRunning
+	}
+
+	if !exp.Status.Paused() {
+		return fmt.Errorf("experiment isn't paused")
+	}
+
+	if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
+		paused, ok := c.Metadata.Annotations[pausedVMsAnnotation]
+
+		// Experiments paused before the paused VMs were recorded have all their
+		// VMs started.
+		if !ok {
+			paused = "all"
+		}
+
+		for _, vm := range strings.Split(paused, ",") {
+			if vm == "" {
+				continue
+			}
+
+			if err := mm.StartVM(mm.NS(name), mm.VMName(vm)); err != nil {
+				return fmt.Errorf("resuming experiment VMs: %w", err)
+			}
+		}
+	}
+
+	exp.Status.SetPaused(false)
+
+	delete(c.Metadata.Annotations, pausedVMsAnnotation)
+	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	for _, hook := range hooks["resume"] {
+		hook("resume", name)
+	}
+
+	return nil
+}
+
 func Status(name string) (*v1.ExperimentStatus, error) {
 	c, _ := store.NewConfig("experiment/" + name)
 
@@ -770,6 +1310,8 @@ func Running(name string) bool {
 func Save(opts ...SaveOption) error {
 	o := newSaveOptions(opts...)
 
+	defer InvalidateCached(o.name)
+
 	if o.name == "" {
 		return fmt.Errorf("experiment name required")
 	}
@@ -803,10 +1345,48 @@ func Save(opts ...SaveOption) error {
 	return nil
 }
 
+// VMErrorAnnotationPrefix prefixes the experiment annotations VMs missing from
+// minimega are marked as errored with, followed by the VM's name. They're
+// cleared when the experiment is started.
+const VMErrorAnnotationPrefix = "vmError/"
+
+// Annotate sets the given annotations on the config for the experiment with
+// the given name, removing any with an empty value. Annotations are metadata,
+// so they can be changed whether or not the experiment is running.
+func Annotate(name string, annotations map[string]string) error {
+	defer InvalidateCached(name)
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	if c.Metadata.Annotations == nil {
+		c.Metadata.Annotations = make(map[string]string)
+	}
+
+	for k, v := range annotations {
+		if v == "" {
+			delete(c.Metadata.Annotations, k)
+		} else {
+			c.Metadata.Annotations[k] = v
+		}
+	}
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("saving experiment config: %w", err)
+	}
+
+	return nil
+}
+
 // Reconfigure executes the 'configure' stage for all apps the given experiment
 // is configured to use. It returns any errors encountered while reconfiguring
 // the experiment.
 func Reconfigure(name string) error {
+	defer InvalidateCached(name)
+
 	c, _ := store.NewConfig("experiment/" + name)
 
 	if err := store.Get(c); err != nil {
@@ -846,6 +1426,8 @@ func Reconfigure(name string) error {
 // experiment. If no apps are passed, then all experiment apps will have their
 // 'running' stage triggered.
 func TriggerRunning(ctx context.Context, name string, apps ...string) error {
+	defer InvalidateCached(name)
+
 	c, _ := store.NewConfig("experiment/" + name)
 
 	if err := store.Get(c); err != nil {
@@ -869,6 +1451,8 @@ func TriggerRunning(ctx context.Context, name string, apps ...string) error {
 }
 
 func Delete(name string) error {
+	defer InvalidateCached(name)
+
 	if Running(name) {
 		return fmt.Errorf("cannot delete a running experiment")
 	}
@@ -899,6 +1483,10 @@ func Delete(name string) error {
 		errors = multierror.Append(errors, fmt.Errorf("deleting experiment base directory: %w", err))
 	}
 
+	if err := RemoveTraces(name); err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
 	for _, hook := range hooks["delete"] {
 		hook("delete", name)
 	}
@@ -985,18 +1573,55 @@ func deleteC2AndSnapshots(exp *types.Experiment) error {
 	return nil
 }
 
-func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Duration, c2s map[string]map[string]bool) error {
+// handleDelayedVMs starts the given delayed VMs once their timers have elapsed
+// or their C2 dependencies are active, retrying each up to the given number of
+// times if it fails to start. Delayed VMs that still couldn't be started are
+// returned separately from errors that should abort the start (e.g. the start
+// being canceled), since the rest of the experiment is left running without
+// them.
+func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Duration, c2s map[string]map[string]bool, retries int, progress func(DelayedRetry)) ([]DelayedVMError, error) {
 	if len(delays) == 0 && len(c2s) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	notes.AddInfo(ctx, true, "Waiting for delayed VMs to be started...")
 
 	var (
 		wg     sync.WaitGroup
-		errors error
+		mu     sync.Mutex
+		failed []DelayedVMError
+		errs   error
 	)
 
+	start := func(host, kind string) {
+		err := startDelayedVM(ctx, ns, host, retries, progress)
+
+		if err == nil {
+			notes.AddInfo(ctx, true, fmt.Sprintf("%s delayed VM %s started", kind, host))
+			pubsub.Publish("delayed-start", fmt.Sprintf("%s/%s", ns, host))
+
+			return
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		var derr DelayedVMError
+
+		if errors.As(err, &derr) {
+			failed = append(failed, derr)
+		} else {
+			errs = multierror.Append(errs, err)
+		}
+	}
+
+	canceled := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		errs = multierror.Append(errs, ctx.Err())
+	}
+
 	for host, delay := range delays {
 		wg.Add(1)
 
@@ -1005,19 +1630,9 @@ func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Dur
 
 			select {
 			case <-ctx.Done():
-				errors = multierror.Append(errors, ctx.Err())
-				return
+				canceled()
 			case <-time.After(delay):
-				cmd := mmcli.NewNamespacedCommand(ns)
-				cmd.Command = "vm start " + host
-
-				if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-					errors = multierror.Append(errors, NewDelayedVMError(host, err, "starting VM %s", host))
-					return
-				}
-
-				notes.AddInfo(ctx, true, fmt.Sprintf("Time delayed VM %s started", host))
-				pubsub.Publish("delayed-start", fmt.Sprintf("%s/%s", ns, host))
+				start(host, "Time")
 			}
 		}(host, delay)
 	}
@@ -1034,7 +1649,7 @@ func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Dur
 			for {
 				select {
 				case <-ctx.Done():
-					errors = multierror.Append(errors, ctx.Err())
+					canceled()
 					return
 				case <-ticker.C:
 					done := true
@@ -1053,17 +1668,7 @@ func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Dur
 					}
 
 					if done {
-						cmd := mmcli.NewNamespacedCommand(ns)
-						cmd.Command = "vm start " + host
-
-						if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-							errors = multierror.Append(errors, NewDelayedVMError(host, err, "starting VM %s", host))
-							return
-						}
-
-						notes.AddInfo(ctx, true, fmt.Sprintf("C2 delayed VM %s started", host))
-						pubsub.Publish("delayed-start", fmt.Sprintf("%s/%s", ns, host))
-
+						start(host, "C2")
 						return
 					}
 				}
@@ -1073,13 +1678,15 @@ func handleDelayedVMs(ctx context.Context, ns string, delays map[string]time.Dur
 
 	wg.Wait()
 
-	if errors != nil {
+	if errs != nil {
 		if err := mm.ClearNamespace(ns); err != nil {
-			errors = multierror.Append(errors, fmt.Errorf("killing experiment VMs: %w", err))
+			errs = multierror.Append(errs, fmt.Errorf("killing experiment VMs: %w", err))
 		}
 
-		return errors
+		return nil, errs
 	}
 
-	return nil
+	sort.Slice(failed, func(i, j int) bool { return failed[i].VM < failed[j].VM })
+
+	return failed, nil
 }
@@ -1,9 +1,11 @@
 package experiment
 
 import (
+	"strings"
 	"testing"
 
 	"phenix/store"
+	"phenix/types"
 
 	"github.com/golang/mock/gomock"
 )
@@ -40,3 +42,64 @@ func TestList(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+const testExperimentName = "test-experiment"
+
+// testNode returns a topology node with the given hostname and any other node
+// fields, keyed by their dotted path (e.g. "hardware.memory").
+func testNode(hostname string, fields map[string]any) map[string]any {
+	node := map[string]any{"general": map[string]any{"hostname": hostname}}
+
+	for path, value := range fields {
+		var (
+			keys   = strings.Split(path, ".")
+			parent = node
+		)
+
+		for _, key := range keys[:len(keys)-1] {
+			child, ok := parent[key].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				parent[key] = child
+			}
+
+			parent = child
+		}
+
+		parent[keys[len(keys)-1]] = value
+	}
+
+	return node
+}
+
+// testExperimentConfig returns the config for an experiment named
+// testExperimentName with the given topology nodes and other spec fields.
+func testExperimentConfig(spec map[string]any, nodes ...any) store.Config {
+	c := store.Config{
+		Version:  "phenix.sandia.gov/v1",
+		Kind:     "Experiment",
+		Metadata: store.ConfigMetadata{Name: testExperimentName},
+		Spec: map[string]any{
+			"experimentName": testExperimentName,
+			"topology":       map[string]any{"nodes": append([]any{}, nodes...)},
+		},
+	}
+
+	for k, v := range spec {
+		c.Spec[k] = v
+	}
+
+	return c
+}
+
+// testExperiment decodes the experiment for testExperimentConfig.
+func testExperiment(t *testing.T, spec map[string]any, nodes ...any) *types.Experiment {
+	t.Helper()
+
+	exp, err := types.DecodeExperimentFromConfig(testExperimentConfig(spec, nodes...))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exp
+}
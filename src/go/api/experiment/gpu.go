@@ -0,0 +1,238 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+)
+
+var ErrGPUUnavailable = errors.New("GPU unavailable")
+
+// GPUs allocated to experiments that are still starting, keyed by experiment
+// and then by VM. They're tracked until the start finishes (at which point
+// they're in the experiment's status if it succeeded) so concurrent starts
+// don't pass the same GPUs through to different VMs.
+var gpuReservations = struct {
+	sync.Mutex
+	starting map[string]map[string]gpuAllocation
+}{starting: make(map[string]map[string]gpuAllocation)}
+
+type gpuAllocation struct {
+	host  string
+	addrs []string
+}
+
+// scriptSpec is the experiment spec the minimega script is generated from,
+// along with the GPUs passed through to each VM.
+type scriptSpec struct {
+	ifaces.ExperimentSpec
+
+	gpus map[string][]string
+}
+
+// GPUDevices returns the PCI addresses of the host GPUs to pass through to the
+// given VM.
+func (this scriptSpec) GPUDevices(node string) []string {
+	return this.gpus[node]
+}
+
+// GPUsInUse returns the PCI addresses of the GPUs passed through to VMs in
+// running experiments (and experiments being started), keyed by cluster host.
+// GPUs allocated to the experiment with the given name, if any, aren't
+// included.
+func GPUsInUse(except string) (map[string][]string, error) {
+	running, err := types.Experiments(true)
+	if err != nil {
+		return nil, fmt.Errorf("getting running experiments: %w", err)
+	}
+
+	gpuReservations.Lock()
+	defer gpuReservations.Unlock()
+
+	return gpusInUse(running, except), nil
+}
+
+// gpusInUse does the work for GPUsInUse. The caller must hold the reservations
+// lock.
+func gpusInUse(running []*types.Experiment, except string) map[string][]string {
+	used := make(map[string][]string)
+
+	for _, exp := range running {
+		if exp.Metadata.Name == except || exp.DryRun() {
+			continue
+		}
+
+		// Reservations take precedence, since they're newer.
+		if _, ok := gpuReservations.starting[exp.Metadata.Name]; ok {
+			continue
+		}
+
+		schedule := exp.Status.Schedules()
+
+		for vm, addrs := range exp.Status.GPUs() {
+			if host := schedule[vm]; host != "" {
+				used[host] = append(used[host], addrs...)
+			}
+		}
+	}
+
+	for name, vms := range gpuReservations.starting {
+		if name == except {
+			continue
+		}
+
+		for _, alloc := range vms {
+			used[alloc.host] = append(used[alloc.host], alloc.addrs...)
+		}
+	}
+
+	return used
+}
+
+// requestedGPUs returns the VMs in the given experiment that would be booted
+// and that request GPUs, ordered by the number of GPUs requested (most first).
+func requestedGPUs(exp *types.Experiment) []ifaces.NodeSpec {
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() || node.Hardware().GPUs() < 1 {
+			continue
+		}
+
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Hardware().GPUs() > nodes[j].Hardware().GPUs()
+	})
+
+	return nodes
+}
+
+// scheduleGPUs allocates GPUs on the given cluster hosts to each VM in the
+// given experiment that requests them, returning the PCI addresses allocated
+// to each VM. VMs already scheduled on a host must fit on that host, and the
+// rest are scheduled on the host with the most free GPUs. It's run before any
+// scheduling algorithm so the algorithms leave GPU VMs where they were placed.
+// It returns an error wrapping ErrGPUUnavailable if not enough GPUs are free.
+// If reserve is true, the allocated GPUs are held for the experiment until
+// releaseGPUs is called.
+func scheduleGPUs(exp *types.Experiment, cluster mm.Hosts, reserve bool) (map[string][]string, error) {
+	nodes := requestedGPUs(exp)
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	running, err := types.Experiments(true)
+	if err != nil {
+		return nil, fmt.Errorf("getting running experiments: %w", err)
+	}
+
+	gpuReservations.Lock()
+	defer gpuReservations.Unlock()
+
+	var (
+		name  = exp.Metadata.Name
+		used  = make(map[string]bool)
+		free  = make(map[string][]string)
+		hosts []string
+	)
+
+	for host, addrs := range gpusInUse(running, name) {
+		for _, addr := range addrs {
+			used[host+"/"+addr] = true
+		}
+	}
+
+	for _, host := range cluster {
+		hosts = append(hosts, host.Name)
+
+		for _, addr := range host.GPUs {
+			if !used[host.Name+"/"+addr] {
+				free[host.Name] = append(free[host.Name], addr)
+			}
+		}
+	}
+
+	sort.Strings(hosts)
+
+	var (
+		schedule  = exp.Spec.Schedules()
+		allocated = make(map[string]gpuAllocation)
+	)
+
+	for _, node := range nodes {
+		var (
+			vm     = node.General().Hostname()
+			needed = node.Hardware().GPUs()
+			host   = schedule[vm]
+		)
+
+		if host != "" {
+			if len(free[host]) < needed {
+				return nil, fmt.Errorf("%w: VM %s requests %d GPUs, but its scheduled host %s only has %d free", ErrGPUUnavailable, vm, needed, host, len(free[host]))
+			}
+		} else {
+			for _, h := range hosts {
+				if len(free[h]) >= needed && (host == "" || len(free[h]) > len(free[host])) {
+					host = h
+				}
+			}
+
+			if host == "" {
+				return nil, fmt.Errorf("%w: VM %s requests %d GPUs, but no cluster host has that many free", ErrGPUUnavailable, vm, needed)
+			}
+
+			schedule[vm] = host
+		}
+
+		allocated[vm] = gpuAllocation{host: host, addrs: free[host][:needed:needed]}
+		free[host] = free[host][needed:]
+	}
+
+	exp.Spec.SetSchedule(schedule)
+
+	if reserve {
+		gpuReservations.starting[name] = allocated
+	}
+
+	devices := make(map[string][]string)
+
+	for vm, alloc := range allocated {
+		devices[vm] = alloc.addrs
+	}
+
+	return devices, nil
+}
+
+// releaseGPUs releases the GPUs reserved for the given experiment while it was
+// starting.
+func releaseGPUs(name string) {
+	gpuReservations.Lock()
+	defer gpuReservations.Unlock()
+
+	delete(gpuReservations.starting, name)
+}
+
+// gpuDeviceSummary describes the GPUs allocated to each VM, for traces.
+func gpuDeviceSummary(devices map[string][]string) string {
+	var summary []string
+
+	for vm, addrs := range devices {
+		summary = append(summary, fmt.Sprintf("%s=%s", vm, strings.Join(addrs, ",")))
+	}
+
+	sort.Strings(summary)
+
+	return strings.Join(summary, " ")
+}
@@ -0,0 +1,74 @@
+package experiment
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"phenix/store"
+	"phenix/util/mm"
+)
+
+func TestScheduleGPUs(t *testing.T) {
+	b := store.NewBoltDB()
+
+	if err := b.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(orig store.Store) { store.DefaultStore = orig }(store.DefaultStore)
+
+	store.DefaultStore = b
+
+	node := func(hostname string, gpus int) map[string]any {
+		return testNode(hostname, map[string]any{"hardware.vcpus": 1, "hardware.memory": 1024, "hardware.gpus": gpus})
+	}
+
+	exp := testExperiment(t, nil, node("trainer", 2), node("worker", 1), node("router", 0))
+
+	cluster := mm.Hosts{
+		{Name: "compute1", GPUs: []string{"0000:3b:00.0"}},
+		{Name: "compute2", GPUs: []string{"0000:af:00.0", "0000:d8:00.0"}},
+	}
+
+	devices, err := scheduleGPUs(exp, cluster, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"trainer": {"0000:af:00.0", "0000:d8:00.0"},
+		"worker":  {"0000:3b:00.0"},
+	}
+
+	if !reflect.DeepEqual(devices, expected) {
+		t.Fatalf("expected GPUs %v, got %v", expected, devices)
+	}
+
+	if host := exp.Spec.Schedules()["trainer"]; host != "compute2" {
+		t.Fatalf("expected trainer to be scheduled on compute2, got %s", host)
+	}
+
+	if _, ok := exp.Spec.Schedules()["router"]; ok {
+		t.Fatal("expected router to be left for the scheduler")
+	}
+
+	// The GPUs are reserved until the start finishes.
+	used, err := GPUsInUse("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(used["compute1"]) != 1 || len(used["compute2"]) != 2 {
+		t.Fatalf("expected reserved GPUs to be in use, got %v", used)
+	}
+
+	releaseGPUs(testExperimentName)
+
+	exp.Spec.Schedules()["trainer"] = "compute1"
+
+	if _, err := scheduleGPUs(exp, cluster, false); !errors.Is(err, ErrGPUUnavailable) {
+		t.Fatalf("expected ErrGPUUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package experiment
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"phenix/types"
+	"phenix/util/mm"
+)
+
+// HostUnreachableError is returned (wrapped) when cluster hosts VMs in an
+// experiment are scheduled on can't be reached over minimega's mesh, or aren't
+// running minimega. It wraps ErrHostUnreachable.
+type HostUnreachableError struct {
+	// Why each unreachable host couldn't be reached, keyed by host name.
+	Hosts map[string]string `json:"hosts"`
+}
+
+func (this HostUnreachableError) Error() string {
+	hosts := make([]string, 0, len(this.Hosts))
+
+	for host := range this.Hosts {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	return fmt.Sprintf("scheduled hosts unreachable: %s", strings.Join(hosts, ", "))
+}
+
+func (HostUnreachableError) Unwrap() error {
+	return ErrHostUnreachable
+}
+
+// checkScheduledHosts pings each cluster host the given experiment's schedule
+// assigns VMs to, returning a HostUnreachableError listing any that can't be
+// reached. Each host is only pinged once per start, no matter how many VMs are
+// scheduled on it, and hosts are pinged concurrently so a dead host's timeout
+// doesn't hold up checking the rest.
+func checkScheduledHosts(exp *types.Experiment) error {
+	var (
+		schedule = exp.Spec.Schedules()
+		hosts    = make(map[string]struct{})
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		if host := schedule[node.General().Hostname()]; host != "" {
+			hosts[host] = struct{}{}
+		}
+	}
+
+	var (
+		herr = HostUnreachableError{Hosts: make(map[string]string)}
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+	)
+
+	for host := range hosts {
+		wg.Add(1)
+
+		go func(host string) {
+			defer wg.Done()
+
+			if err := mm.PingHost(host); err != nil {
+				mu.Lock()
+				herr.Hosts[host] = err.Error()
+				mu.Unlock()
+			}
+		}(host)
+	}
+
+	wg.Wait()
+
+	if len(herr.Hosts) > 0 {
+		return herr
+	}
+
+	return nil
+}
@@ -0,0 +1,41 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+
+	"phenix/util/mm"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestCheckScheduledHosts(t *testing.T) {
+	defer func(orig mm.MM) { mm.DefaultMM = orig }(mm.DefaultMM)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm.NewMockMM(ctrl)
+	mm.DefaultMM = m
+
+	exp := testExperiment(t,
+		map[string]any{"schedules": map[string]any{"router": "compute1", "host-1": "compute1", "host-2": "compute2"}},
+		testNode("router", nil), testNode("host-1", nil), testNode("host-2", nil),
+	)
+
+	// Each host should only be pinged once, even with multiple VMs on it.
+	m.EXPECT().PingHost("compute1").Return(nil)
+	m.EXPECT().PingHost("compute2").Return(errors.New("no such client"))
+
+	err := checkScheduledHosts(exp)
+
+	var herr HostUnreachableError
+
+	if !errors.As(err, &herr) || !errors.Is(err, ErrHostUnreachable) {
+		t.Fatalf("expected host unreachable error, got %v", err)
+	}
+
+	if len(herr.Hosts) != 1 || herr.Hosts["compute2"] == "" {
+		t.Fatalf("expected only compute2 to be unreachable, got %v", herr.Hosts)
+	}
+}
@@ -0,0 +1,109 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util"
+)
+
+var ErrImageMissing = errors.New("VM disk image missing")
+
+// MissingImagesError is returned when VMs in an experiment reference disk
+// images that don't exist in the minimega files directory. It wraps
+// ErrImageMissing.
+type MissingImagesError struct {
+	// Map of each missing image, as referenced in the topology, to the VMs
+	// referencing it.
+	Images map[string][]string `json:"images"`
+}
+
+func (this MissingImagesError) Error() string {
+	images := make([]string, 0, len(this.Images))
+
+	for image := range this.Images {
+		images = append(images, image)
+	}
+
+	sort.Strings(images)
+
+	missing := make([]string, len(images))
+
+	for i, image := range images {
+		missing[i] = fmt.Sprintf("%s (used by %s)", image, strings.Join(this.Images[image], ", "))
+	}
+
+	return fmt.Sprintf("disk images not found: %s", strings.Join(missing, "; "))
+}
+
+func (MissingImagesError) Unwrap() error {
+	return ErrImageMissing
+}
+
+// CheckImages verifies that all the disk images referenced by VMs in the
+// experiment with the given name exist, returning a MissingImagesError if any
+// of them don't.
+func CheckImages(name string) error {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	return checkImages(exp, os.Stat)
+}
+
+// checkImages uses the given stat function to check that the disk images
+// referenced by VMs in the given experiment exist. Each image is only stat'ed
+// once no matter how many VMs reference it, which matters for large topologies
+// built from a handful of images. Images are only checked once per start, so
+// the results aren't kept beyond the call.
+func checkImages(exp *types.Experiment, stat func(string) (os.FileInfo, error)) error {
+	var (
+		exists  = make(map[string]bool)
+		missing = make(map[string][]string)
+	)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		for _, drive := range node.Hardware().Drives() {
+			image := drive.Image()
+
+			if image == "" {
+				continue
+			}
+
+			ok, checked := exists[image]
+
+			if !checked {
+				_, err := stat(util.GetMMFullPath(image))
+
+				ok = err == nil
+				exists[image] = ok
+			}
+
+			if !ok {
+				missing[image] = append(missing[image], node.General().Hostname())
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return MissingImagesError{Images: missing}
+	}
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package experiment
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCheckImages(t *testing.T) {
+	node := func(hostname, image string) map[string]any {
+		return testNode(hostname, map[string]any{"hardware.drives": []any{map[string]any{"image": image}}})
+	}
+
+	exp := testExperiment(t, nil,
+		node("router", "/images/vyos.qc2"),
+		node("host-1", "/images/ubuntu.qc2"),
+		node("host-2", "/images/ubuntu.qc2"),
+		node("host-3", "/images/ubuntu.qc2"),
+	)
+
+	stats := make(map[string]int)
+
+	stat := func(path string) (os.FileInfo, error) {
+		stats[path]++
+
+		if path == "/images/ubuntu.qc2" {
+			return nil, os.ErrNotExist
+		}
+
+		return nil, nil
+	}
+
+	err := checkImages(exp, stat)
+
+	var merr MissingImagesError
+
+	if !errors.As(err, &merr) || !errors.Is(err, ErrImageMissing) {
+		t.Fatalf("expected missing images error, got %v", err)
+	}
+
+	if len(merr.Images) != 1 || strings.Join(merr.Images["/images/ubuntu.qc2"], ",") != "host-1,host-2,host-3" {
+		t.Fatalf("unexpected missing images %v", merr.Images)
+	}
+
+	for path, count := range stats {
+		if count != 1 {
+			t.Errorf("expected image %s to be checked once, got %d", path, count)
+		}
+	}
+}
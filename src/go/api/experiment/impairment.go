@@ -0,0 +1,123 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/mm"
+
+	"github.com/activeshadow/structs"
+)
+
+var (
+	ErrVLANNotFound      = errors.New("VLAN not found")
+	ErrInvalidImpairment = errors.New("invalid link impairment")
+)
+
+// Limits for link impairments applied via SetLinkImpairment.
+const (
+	MaxImpairmentLatencyMs = 60000    // one minute
+	MaxImpairmentRateKbit  = 10000000 // 10 Gbit/s
+)
+
+// SetLinkImpairment applies the given latency (in milliseconds), packet loss
+// (percentage) and rate limit (in kbit/s) to the VLAN with the given alias in
+// the running experiment with the given name, replacing any impairment already
+// applied to it. A value of 0 disables that type of impairment. The applied
+// impairment is tracked in the experiment's status.
+func SetLinkImpairment(name, vlan string, latencyMs int, lossPct float64, rateKbit int) error {
+	if latencyMs < 0 || latencyMs > MaxImpairmentLatencyMs {
+		return fmt.Errorf("%w: latency must be between 0 and %d ms", ErrInvalidImpairment, MaxImpairmentLatencyMs)
+	}
+
+	if lossPct < 0 || lossPct > 100 {
+		return fmt.Errorf("%w: loss must be between 0 and 100 percent", ErrInvalidImpairment)
+	}
+
+	if rateKbit < 0 || rateKbit > MaxImpairmentRateKbit {
+		return fmt.Errorf("%w: rate must be between 0 and %d kbit/s", ErrInvalidImpairment, MaxImpairmentRateKbit)
+	}
+
+	if latencyMs == 0 && lossPct == 0 && rateKbit == 0 {
+		return fmt.Errorf("%w: at least one of latency, loss, or rate must be provided", ErrInvalidImpairment)
+	}
+
+	return updateLinkImpairment(name, vlan, func(exp *types.Experiment, alias string) error {
+		if err := mm.SetLinkImpairment(name, alias, latencyMs, lossPct, rateKbit); err != nil {
+			return fmt.Errorf("impairing VLAN %s: %w", alias, err)
+		}
+
+		exp.Status.SetLinkImpairment(alias, map[string]float64{
+			"latencyMs": float64(latencyMs),
+			"lossPct":   lossPct,
+			"rateKbit":  float64(rateKbit),
+		})
+
+		return nil
+	})
+}
+
+// ClearLinkImpairment removes any impairment applied to the VLAN with the given
+// alias in the running experiment with the given name.
+func ClearLinkImpairment(name, vlan string) error {
+	return updateLinkImpairment(name, vlan, func(exp *types.Experiment, alias string) error {
+		if err := mm.ClearLinkImpairment(name, alias); err != nil {
+			return fmt.Errorf("clearing impairment for VLAN %s: %w", alias, err)
+		}
+
+		exp.Status.SetLinkImpairment(alias, nil)
+
+		return nil
+	})
+}
+
+func updateLinkImpairment(name, vlan string, update func(*types.Experiment, string) error) error {
+	defer InvalidateCached(name)
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if !exp.Running() {
+		return ErrExperimentNotRunning
+	}
+
+	var alias string
+
+	for a := range exp.Spec.VLANs().Aliases() {
+		if strings.EqualFold(a, vlan) {
+			alias = a
+			break
+		}
+	}
+
+	if alias == "" {
+		return fmt.Errorf("%w: %s", ErrVLANNotFound, vlan)
+	}
+
+	if exp.DryRun() {
+		return fmt.Errorf("cannot impair VLANs in a dry-run experiment")
+	}
+
+	if err := update(exp, alias); err != nil {
+		return err
+	}
+
+	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/notes"
+)
+
+// Top-level guest directories files are expected to be injected into. Files
+// injected anywhere else are still injected, but a warning is added since the
+// path is likely a mistake. Windows paths are relative to the root of the
+// partition, so are matched case-insensitively.
+var injectionRoots = map[string]bool{
+	"boot":          true,
+	"etc":           true,
+	"home":          true,
+	"opt":           true,
+	"phenix":        true,
+	"program files": true,
+	"programdata":   true,
+	"root":          true,
+	"srv":           true,
+	"tmp":           true,
+	"usr":           true,
+	"users":         true,
+	"var":           true,
+	"windows":       true,
+}
+
+// vmInjections is the files to inject into the disk snapshot of a single VM
+// before it's launched.
+type vmInjections struct {
+	vm      string
+	disk    string
+	part    int
+	injects []string
+
+	// Whether any of the files are required to be injected for the VM to be
+	// launched.
+	required bool
+}
+
+// checkInjections validates the file injections for each bootable VM in the
+// given experiment that uses a disk snapshot, returning the files to inject
+// into each VM. Injections whose source file doesn't exist are skipped with a
+// warning, unless they're required, in which case an error is returned.
+// Injections with a suspicious destination path are warned about.
+func checkInjections(ctx context.Context, exp *types.Experiment) ([]vmInjections, error) {
+	var injections []vmInjections
+
+	for _, node := range exp.Spec.Topology().BootableNodes() {
+		if node.External() || len(node.Injections()) == 0 {
+			continue
+		}
+
+		// Files are only injected into disk snapshots, since injecting into a
+		// VM's base image would change it for every VM using the image.
+		if snap := node.General().Snapshot(); snap == nil || !*snap {
+			continue
+		}
+
+		drives := node.Hardware().Drives()
+		if len(drives) == 0 {
+			continue
+		}
+
+		var (
+			hostname = node.General().Hostname()
+			vi       = vmInjections{vm: hostname, disk: exp.Spec.SnapshotName(hostname), part: 1}
+		)
+
+		if part := drives[0].InjectPartition(); part != nil {
+			vi.part = *part
+		}
+
+		for _, inject := range node.Injections() {
+			src := inject.Src()
+
+			if !filepath.IsAbs(src) {
+				src = filepath.Join(exp.Spec.BaseDir(), src)
+			}
+
+			if _, err := os.Stat(src); err != nil {
+				if inject.Required() {
+					return nil, fmt.Errorf("checking required injection %s for VM %s: %w", src, hostname, err)
+				}
+
+				notes.AddWarnings(ctx, false, fmt.Errorf("skipping injection %s for VM %s: %w", src, hostname, err))
+				continue
+			}
+
+			if reason := suspiciousInjectionPath(inject.Dst()); reason != "" {
+				notes.AddWarnings(ctx, false, fmt.Errorf("injection destination %s for VM %s %s", inject.Dst(), hostname, reason))
+			}
+
+			if perms := inject.Permissions(); perms != "" && len(perms) <= 4 {
+				if mode, err := strconv.ParseInt(perms, 8, 64); err == nil {
+					// Update file permissions on local disk before it gets injected into
+					// the VM disk.
+					os.Chmod(src, os.FileMode(mode))
+				}
+			}
+
+			vi.injects = append(vi.injects, fmt.Sprintf(`"%s":"%s"`, src, inject.Dst()))
+			vi.required = vi.required || inject.Required()
+		}
+
+		if len(vi.injects) > 0 {
+			injections = append(injections, vi)
+		}
+	}
+
+	return injections, nil
+}
+
+// suspiciousInjectionPath returns why the given injection destination looks
+// like a mistake, or an empty string if it doesn't.
+func suspiciousInjectionPath(dst string) string {
+	for _, elem := range strings.Split(dst, "/") {
+		if elem == ".." {
+			return "contains a parent directory reference"
+		}
+	}
+
+	root := strings.SplitN(strings.TrimLeft(dst, "/"), "/", 2)[0]
+
+	if !injectionRoots[strings.ToLower(root)] {
+		return "is outside the expected guest directories"
+	}
+
+	return ""
+}
+
+// injectFiles injects files into the disk snapshot of each of the given VMs.
+// Failures are added as warnings for each VM, unless files required by the VM
+// fail to be injected, in which case an error is returned.
+func injectFiles(ctx context.Context, injections []vmInjections) error {
+	for _, vi := range injections {
+		if err := mm.InjectDiskFiles(vi.disk, vi.part, vi.injects...); err != nil {
+			if vi.required {
+				return fmt.Errorf("injecting required files into VM %s: %w", vi.vm, err)
+			}
+
+			notes.AddWarnings(ctx, false, fmt.Errorf("injecting files into VM %s: %w", vi.vm, err))
+		}
+	}
+
+	return nil
+}
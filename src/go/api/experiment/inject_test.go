@@ -0,0 +1,82 @@
+package experiment
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"phenix/types"
+	"phenix/util/notes"
+)
+
+func TestCheckInjections(t *testing.T) {
+	base := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(base, "foo.conf"), []byte("foo"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	node := func(hostname string, injections ...map[string]any) map[string]any {
+		return testNode(hostname, map[string]any{
+			"general.snapshot": true,
+			"hardware.drives":  []any{map[string]any{"image": "foo.qc2", "inject_partition": 2}},
+			"injections":       injections,
+		})
+	}
+
+	decode := func(nodes ...any) *types.Experiment {
+		return testExperiment(t, map[string]any{"baseDir": base}, nodes...)
+	}
+
+	ctx := notes.Context(nil, false)
+
+	exp := decode(node("host",
+		map[string]any{"src": "foo.conf", "dst": "/etc/foo.conf"},
+		map[string]any{"src": "missing.conf", "dst": "/etc/missing.conf"},
+		map[string]any{"src": "foo.conf", "dst": "/../foo.conf"},
+	))
+
+	injections, err := checkInjections(ctx, exp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(injections) != 1 {
+		t.Fatalf("expected injections for 1 VM, got %d", len(injections))
+	}
+
+	vi := injections[0]
+
+	if vi.vm != "host" || vi.part != 2 || vi.required {
+		t.Fatalf("unexpected injections for VM: %+v", vi)
+	}
+
+	if len(vi.injects) != 2 || vi.injects[0] != `"`+filepath.Join(base, "foo.conf")+`":"/etc/foo.conf"` {
+		t.Fatalf("unexpected injects for VM: %v", vi.injects)
+	}
+
+	if warnings := notes.Warnings(ctx, true); len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", warnings)
+	}
+
+	exp = decode(node("host", map[string]any{"src": "missing.conf", "dst": "/etc/missing.conf", "required": true}))
+
+	if _, err := checkInjections(ctx, exp); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Fatalf("expected missing required injection error, got %v", err)
+	}
+}
+
+func TestSuspiciousInjectionPath(t *testing.T) {
+	for _, dst := range []string{"/etc/foo.conf", "/phenix/startup/20-startup.ps1", "ProgramData/Microsoft/foo.cmd"} {
+		if reason := suspiciousInjectionPath(dst); reason != "" {
+			t.Errorf("expected %s to not be suspicious, got %s", dst, reason)
+		}
+	}
+
+	for _, dst := range []string{"/proc/foo", "/etc/../../foo", "foo.conf"} {
+		if reason := suspiciousInjectionPath(dst); reason == "" {
+			t.Errorf("expected %s to be suspicious", dst)
+		}
+	}
+}
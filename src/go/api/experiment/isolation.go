@@ -0,0 +1,246 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+)
+
+var ErrIsolationViolated = errors.New("experiment isolation violated")
+
+// Types of overlaps reported in an IsolationReport.
+const (
+	IsolationOverlapVLAN = "vlan"
+	IsolationOverlapTap  = "tap"
+)
+
+var vlanIDRegex = regexp.MustCompile(`^(?:.* )?\(?(\d+)\)?$`)
+
+// IsolationReport is the result of checking that a running experiment's VLANs
+// and host taps don't overlap with those of other running experiments.
+type IsolationReport struct {
+	Experiment string             `json:"experiment"`
+	Isolated   bool               `json:"isolated"`
+	Checked    []string           `json:"checked"`
+	Overlaps   []IsolationOverlap `json:"overlaps"`
+}
+
+// IsolationOverlap is a VLAN used by both the experiment being checked and
+// another running experiment on the same bridge, or a host tap in one of them
+// on a VLAN used by the other.
+type IsolationOverlap struct {
+	Type       string `json:"type"`
+	Value      string `json:"value"`
+	Experiment string `json:"experiment"`
+	Detail     string `json:"detail"`
+}
+
+// namespaceResources are the VLANs minimega has assigned to VMs in an
+// experiment's namespace, and the host taps created in it.
+type namespaceResources struct {
+	// VLAN IDs used on each bridge, mapped to the VLAN aliases (if known) using
+	// them.
+	vlans map[string]map[int]string
+
+	taps []hostTap
+}
+
+// hostTap is a tap created on a cluster host, rather than for a VM.
+type hostTap struct {
+	host   string
+	name   string
+	bridge string
+	vlan   int
+}
+
+// VerifyIsolation checks that the VLANs minimega has assigned to the running
+// experiment with the given name aren't also used on the same bridge by VMs in
+// other running experiments' namespaces, and that neither has host taps on the
+// other's VLANs, which would let traffic bleed between them (e.g. experiments
+// with pinned VLAN IDs sharing a bridge).
+func VerifyIsolation(name string) (*IsolationReport, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("verifying isolation of experiment %s: %w", name, ErrExperimentNotRunning)
+	}
+
+	return checkIsolation(exp)
+}
+
+// checkIsolation does the work for VerifyIsolation, without requiring the given
+// experiment to be marked as running so it can be called while starting it.
+func checkIsolation(exp *types.Experiment) (*IsolationReport, error) {
+	running, err := types.Experiments(true)
+	if err != nil {
+		return nil, fmt.Errorf("getting running experiments: %w", err)
+	}
+
+	var (
+		name   = exp.Metadata.Name
+		report = &IsolationReport{Experiment: name, Checked: []string{}, Overlaps: []IsolationOverlap{}}
+	)
+
+	ours, err := getNamespaceResources(exp)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, other := range running {
+		if other.Metadata.Name == name || other.DryRun() {
+			continue
+		}
+
+		report.Checked = append(report.Checked, other.Metadata.Name)
+
+		theirs, err := getNamespaceResources(other)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Overlaps = append(report.Overlaps, isolationOverlaps(other.Metadata.Name, ours, theirs)...)
+	}
+
+	sort.Strings(report.Checked)
+
+	sort.Slice(report.Overlaps, func(i, j int) bool {
+		a, b := report.Overlaps[i], report.Overlaps[j]
+
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+
+		if a.Value != b.Value {
+			return a.Value < b.Value
+		}
+
+		if a.Experiment != b.Experiment {
+			return a.Experiment < b.Experiment
+		}
+
+		return a.Detail < b.Detail
+	})
+
+	report.Isolated = len(report.Overlaps) == 0
+
+	return report, nil
+}
+
+// isolationOverlaps returns the overlaps between the resources of the
+// experiment being checked and those of the other given experiment. The same
+// VLAN ID on different bridges is isolated.
+func isolationOverlaps(other string, ours, theirs namespaceResources) []IsolationOverlap {
+	var overlaps []IsolationOverlap
+
+	for bridge, vlans := range ours.vlans {
+		for id, alias := range vlans {
+			if otherAlias, ok := theirs.vlans[bridge][id]; ok {
+				overlaps = append(overlaps, IsolationOverlap{
+					Type:       IsolationOverlapVLAN,
+					Value:      strconv.Itoa(id),
+					Experiment: other,
+					Detail:     fmt.Sprintf("VLAN %d (%s) is also used as %s by experiment %s on bridge %s", id, alias, otherAlias, other, bridge),
+				})
+			}
+		}
+	}
+
+	for _, tap := range theirs.taps {
+		if alias, ok := ours.vlans[tap.bridge][tap.vlan]; ok {
+			overlaps = append(overlaps, IsolationOverlap{
+				Type:       IsolationOverlapTap,
+				Value:      tap.name,
+				Experiment: other,
+				Detail:     fmt.Sprintf("tap %s on host %s in experiment %s is on VLAN %d (%s) on bridge %s", tap.name, tap.host, other, tap.vlan, alias, tap.bridge),
+			})
+		}
+	}
+
+	for _, tap := range ours.taps {
+		if alias, ok := theirs.vlans[tap.bridge][tap.vlan]; ok {
+			overlaps = append(overlaps, IsolationOverlap{
+				Type:       IsolationOverlapTap,
+				Value:      tap.name,
+				Experiment: other,
+				Detail:     fmt.Sprintf("tap %s on host %s is on VLAN %d used as %s by experiment %s on bridge %s", tap.name, tap.host, tap.vlan, alias, other, tap.bridge),
+			})
+		}
+	}
+
+	return overlaps
+}
+
+func getNamespaceResources(exp *types.Experiment) (namespaceResources, error) {
+	var (
+		ns  = exp.Metadata.Name
+		res = namespaceResources{vlans: make(map[string]map[int]string)}
+	)
+
+	aliases, err := mm.GetVLANs(mm.NS(ns))
+	if err != nil {
+		return res, fmt.Errorf("getting VLANs for experiment %s: %w", ns, err)
+	}
+
+	launched := make(map[string]struct{})
+
+	for _, vm := range mm.GetVMInfo(mm.NS(ns)) {
+		launched[vm.Name] = struct{}{}
+	}
+
+	// minimega doesn't report which bridge each VM interface is on, so they're
+	// taken from the topology for the VMs that were launched.
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if _, ok := launched[node.General().Hostname()]; !ok {
+			continue
+		}
+
+		for _, iface := range node.Network().Interfaces() {
+			id, ok := aliases[iface.VLAN()]
+			if !ok {
+				// VLANs given to VMs directly by ID don't show up in the
+				// namespace's VLAN aliases.
+				if id, err = strconv.Atoi(iface.VLAN()); err != nil {
+					continue
+				}
+			}
+
+			bridge := iface.Bridge()
+			if bridge == "" {
+				bridge = exp.Spec.DefaultBridge()
+			}
+
+			if res.vlans[bridge] == nil {
+				res.vlans[bridge] = make(map[int]string)
+			}
+
+			res.vlans[bridge][id] = iface.VLAN()
+		}
+	}
+
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "tap"
+	cmd.Columns = []string{"host", "bridge", "tap", "vlan"}
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		match := vlanIDRegex.FindStringSubmatch(strings.TrimSpace(row["vlan"]))
+		if match == nil {
+			continue
+		}
+
+		id, _ := strconv.Atoi(match[1])
+
+		res.taps = append(res.taps, hostTap{host: row["host"], name: row["tap"], bridge: row["bridge"], vlan: id})
+	}
+
+	return res, nil
+}
@@ -0,0 +1,53 @@
+package experiment
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestIsolationOverlaps(t *testing.T) {
+	ours := namespaceResources{
+		vlans: map[string]map[int]string{
+			"phenix": {101: "EXP", 102: "MGMT"},
+			"other":  {200: "DMZ"},
+		},
+		taps: []hostTap{{host: "compute1", name: "ours-tap", bridge: "phenix", vlan: 300}},
+	}
+
+	theirs := namespaceResources{
+		vlans: map[string]map[int]string{
+			// The same VLAN ID on a different bridge is isolated.
+			"phenix": {101: "LAN", 300: "WAN"},
+			"third":  {200: "DMZ"},
+		},
+		taps: []hostTap{
+			{host: "compute2", name: "theirs-tap", bridge: "phenix", vlan: 102},
+			{host: "compute2", name: "isolated-tap", bridge: "other", vlan: 102},
+		},
+	}
+
+	overlaps := isolationOverlaps("exp2", ours, theirs)
+
+	var got []string
+
+	for _, o := range overlaps {
+		if o.Experiment != "exp2" {
+			t.Errorf("expected overlap with exp2, got %+v", o)
+		}
+
+		got = append(got, o.Type+"|"+o.Value)
+	}
+
+	sort.Strings(got)
+
+	expected := []string{"tap|ours-tap", "tap|theirs-tap", "vlan|101"}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected overlaps %v, got %v (%+v)", expected, got, overlaps)
+	}
+
+	if overlaps := isolationOverlaps("exp2", ours, namespaceResources{}); len(overlaps) != 0 {
+		t.Fatalf("expected no overlaps with an empty namespace, got %+v", overlaps)
+	}
+}
@@ -0,0 +1,267 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/notes"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// How often the state of a batch of VMs being started is checked.
+const launchBatchPollInterval = 1 * time.Second
+
+// BootGroup is a group of VMs with the same boot order, started together once
+// all the VMs in the previous group are running.
+type BootGroup struct {
+	// Position of the group in the experiment's boot groups, starting at 1.
+	Index int `json:"index"`
+
+	// Total number of boot groups in the experiment.
+	Total int `json:"total"`
+
+	// Boot order shared by the VMs in the group. Zero is the group of VMs
+	// without a boot order set.
+	Order int `json:"order"`
+
+	VMs []string `json:"vms"`
+}
+
+// bootGroups groups the given VM hostnames by the boot order set on them in the
+// experiment's topology, in ascending order. VMs without a boot order are put in
+// the last group. It returns nil if none of the VMs have a boot order set.
+func bootGroups(exp *types.Experiment, names []string) []BootGroup {
+	orders := make(map[string]int)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		orders[node.General().Hostname()] = node.General().BootOrder()
+	}
+
+	grouped := make(map[int][]string)
+
+	for _, name := range names {
+		grouped[orders[name]] = append(grouped[orders[name]], name)
+	}
+
+	if _, ok := grouped[0]; ok && len(grouped) == 1 {
+		return nil
+	}
+
+	keys := make([]int, 0, len(grouped))
+
+	for order := range grouped {
+		keys = append(keys, order)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		// Unset boot orders go last.
+		if keys[i] == 0 || keys[j] == 0 {
+			return keys[j] == 0 && keys[i] != 0
+		}
+
+		return keys[i] < keys[j]
+	})
+
+	groups := make([]BootGroup, len(keys))
+
+	for i, order := range keys {
+		groups[i] = BootGroup{Index: i + 1, Total: len(keys), Order: order, VMs: grouped[order]}
+	}
+
+	return groups
+}
+
+// launchVMsBatched launches the VMs queued in the given namespace and then
+// starts the given VMs at most n at a time, waiting for each batch of VMs to be
+// running before starting the next one.
+func launchVMsBatched(ctx context.Context, ns string, names []string, n int) error {
+	// An empty, non-nil slice launches the queued VMs without starting any.
+	if err := mm.LaunchVMs(ns, []string{}...); err != nil {
+		return err
+	}
+
+	return startVMsBatched(ctx, ns, names, n)
+}
+
+// launchVMsInBootOrder launches the VMs queued in the given namespace and then
+// starts each of the given boot groups in order, waiting at most timeout for
+// the VMs in a group to be running before starting the next group. VMs within
+// a group are started at most n at a time, or all at once if n is less than 1.
+func launchVMsInBootOrder(ctx context.Context, ns string, groups []BootGroup, n int, timeout time.Duration, progress func(BootGroup)) error {
+	if err := mm.LaunchVMs(ns, []string{}...); err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		progress(group)
+
+		notes.AddInfo(ctx, false, fmt.Sprintf("starting boot group %d of %d (%s)", group.Index, group.Total, strings.Join(group.VMs, ", ")))
+
+		size := n
+		if size < 1 {
+			size = len(group.VMs)
+		}
+
+		gctx, cancel := context.WithTimeout(ctx, timeout)
+		err := startVMsBatched(gctx, ns, group.VMs, size)
+		cancel()
+
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return fmt.Errorf("VMs in boot group %d of %d not running after %v", group.Index, group.Total, timeout)
+			}
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func startVMsBatched(ctx context.Context, ns string, names []string, n int) error {
+	for i := 0; i < len(names); i += n {
+		end := i + n
+
+		if end > len(names) {
+			end = len(names)
+		}
+
+		batch := names[i:end]
+
+		if n < len(names) {
+			notes.AddInfo(ctx, false, fmt.Sprintf("starting VMs %d-%d of %d", i+1, i+len(batch), len(names)))
+		}
+
+		for _, name := range batch {
+			if err := mm.StartVM(mm.NS(ns), mm.VMName(name)); err != nil {
+				return fmt.Errorf("starting VM %s: %w", name, err)
+			}
+		}
+
+		if err := waitForRunning(ctx, ns, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// waitForRunning blocks until all the given VMs in the given namespace are
+// running, one of them fails, or the context is canceled.
+func waitForRunning(ctx context.Context, ns string, names []string) error {
+	for {
+		states, err := mm.GetPerVMLaunchState(ns)
+		if err != nil {
+			return fmt.Errorf("getting VM launch states: %w", err)
+		}
+
+		running := true
+
+		for _, name := range names {
+			switch states[name] {
+			case mm.LaunchStateRunning:
+			case mm.LaunchStateError:
+				return fmt.Errorf("VM %s failed to start", name)
+			default:
+				running = false
+			}
+		}
+
+		if running {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(launchBatchPollInterval):
+		}
+	}
+}
+
+// teardownGroups returns the given boot groups in reverse, the order their VMs
+// should be killed in when stopping the experiment. Groups are renumbered so
+// Index is their position in the teardown.
+func teardownGroups(groups []BootGroup) []BootGroup {
+	teardown := make([]BootGroup, len(groups))
+
+	for i, group := range groups {
+		group.Index = len(groups) - i
+		teardown[len(groups)-1-i] = group
+	}
+
+	return teardown
+}
+
+// killVMsInTeardownOrder kills the VMs in each of the given teardown groups in
+// order, waiting at most timeout for the VMs in a group to exit before moving
+// on to the next group. VMs that don't exit in time are left for the caller to
+// clear along with the rest of the namespace.
+func killVMsInTeardownOrder(ctx context.Context, ns string, groups []BootGroup, timeout time.Duration, progress func(BootGroup)) error {
+	var errs error
+
+	for _, group := range groups {
+		progress(group)
+
+		notes.AddInfo(ctx, false, fmt.Sprintf("stopping teardown group %d of %d (%s)", group.Index, group.Total, strings.Join(group.VMs, ", ")))
+
+		for _, name := range group.VMs {
+			if err := mm.KillVM(mm.NS(ns), mm.VMName(name)); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("killing VM %s: %w", name, err))
+			}
+		}
+
+		gctx, cancel := context.WithTimeout(ctx, timeout)
+		err := waitForExited(gctx, ns, group.VMs)
+		cancel()
+
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				notes.AddWarnings(ctx, false, fmt.Errorf("VMs in teardown group %d of %d not stopped after %v", group.Index, group.Total, timeout))
+				continue
+			}
+
+			return multierror.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// waitForExited blocks until none of the given VMs in the given namespace are
+// still running or the context is canceled.
+func waitForExited(ctx context.Context, ns string, names []string) error {
+	for {
+		states, err := mm.GetPerVMLaunchState(ns)
+		if err != nil {
+			return fmt.Errorf("getting VM launch states: %w", err)
+		}
+
+		exited := true
+
+		for _, name := range names {
+			// VMs that have quit are reported as errored until flushed.
+			if state, ok := states[name]; ok && state != mm.LaunchStateError {
+				exited = false
+				break
+			}
+		}
+
+		if exited {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(launchBatchPollInterval):
+		}
+	}
+}
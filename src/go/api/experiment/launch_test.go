@@ -0,0 +1,42 @@
+package experiment
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBootGroups(t *testing.T) {
+	node := func(hostname string, order int) map[string]any {
+		return testNode(hostname, map[string]any{"general.boot_order": order})
+	}
+
+	exp := testExperiment(t, nil, node("host", 0), node("router", 1), node("fw", 2), node("core", 1))
+
+	groups := bootGroups(exp, []string{"host", "router", "fw", "core"})
+
+	expected := []BootGroup{
+		{Index: 1, Total: 3, Order: 1, VMs: []string{"router", "core"}},
+		{Index: 2, Total: 3, Order: 2, VMs: []string{"fw"}},
+		{Index: 3, Total: 3, Order: 0, VMs: []string{"host"}},
+	}
+
+	if !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected boot groups %v, got %v", expected, groups)
+	}
+
+	teardown := teardownGroups(groups)
+
+	expected = []BootGroup{
+		{Index: 1, Total: 3, Order: 0, VMs: []string{"host"}},
+		{Index: 2, Total: 3, Order: 2, VMs: []string{"fw"}},
+		{Index: 3, Total: 3, Order: 1, VMs: []string{"router", "core"}},
+	}
+
+	if !reflect.DeepEqual(teardown, expected) {
+		t.Fatalf("expected teardown groups %v, got %v", expected, teardown)
+	}
+
+	if groups := bootGroups(exp, []string{"host"}); groups != nil {
+		t.Fatalf("expected no boot groups without boot orders set, got %v", groups)
+	}
+}
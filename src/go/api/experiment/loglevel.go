@@ -0,0 +1,66 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+var ErrInvalidLogLevel = errors.New("invalid log level")
+
+// Annotation holding the log level used for the experiment instead of the
+// global log level.
+const logLevelAnnotation = "logLevel"
+
+// SetLogLevel sets the log level used when logging about the experiment with
+// the given name to one of debug, info, warn or error. An empty level unsets
+// it, so the global log level is used.
+func SetLogLevel(name, level string) error {
+	level = strings.ToLower(strings.TrimSpace(level))
+
+	if level != "" {
+		if _, err := parseLogLevel(level); err != nil {
+			return err
+		}
+	}
+
+	return Annotate(name, map[string]string{logLevelAnnotation: level})
+}
+
+// LogLevel returns the log level set for the experiment with the given name,
+// and false if one isn't set (or the experiment doesn't exist).
+func LogLevel(name string) (slog.Level, bool) {
+	exp, err := Get(name)
+	if err != nil {
+		return 0, false
+	}
+
+	level, ok := exp.Metadata.Annotations[logLevelAnnotation]
+	if !ok {
+		return 0, false
+	}
+
+	l, err := parseLogLevel(level)
+	if err != nil {
+		return 0, false
+	}
+
+	return l, true
+}
+
+func parseLogLevel(level string) (slog.Level, error) {
+	switch level {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s (must be one of debug, info, warn or error)", ErrInvalidLogLevel, level)
+}
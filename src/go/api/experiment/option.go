@@ -1,6 +1,10 @@
 package experiment
 
 import (
+	"context"
+	"fmt"
+	"time"
+
 	ifaces "phenix/types/interfaces"
 	"phenix/util/common"
 )
@@ -10,6 +14,7 @@ type CreateOption func(*createOptions)
 type createOptions struct {
 	name          string
 	annotations   map[string]string
+	owner         string
 	topology      string
 	scenario      string
 	disabledApps  []string
@@ -56,6 +61,14 @@ func CreateWithAnnotations(a map[string]string) CreateOption {
 	}
 }
 
+// CreateWithOwner makes the given user the owner of the new experiment,
+// restricting starting and stopping it to the users it's shared with.
+func CreateWithOwner(u string) CreateOption {
+	return func(o *createOptions) {
+		o.owner = u
+	}
+}
+
 func CreateWithTopology(t string) CreateOption {
 	return func(o *createOptions) {
 		o.topology = t
@@ -204,6 +217,21 @@ func ScheduleWithAlgorithm(a string) ScheduleOption {
 	}
 }
 
+const (
+	// Bounds and default for how often the launch progress of an experiment is
+	// checked while it's starting.
+	MinProgressInterval     = 100 * time.Millisecond
+	MaxProgressInterval     = 30 * time.Second
+	DefaultProgressInterval = 2 * time.Second
+
+	// Default amount of time callers should wait for an experiment to start.
+	DefaultStartTimeout = 30 * time.Minute
+
+	// Default amount of time to wait for the VMs in a boot group to be running
+	// before giving up on starting the experiment.
+	DefaultBootGroupTimeout = 5 * time.Minute
+)
+
 type StartOption func(*startOptions)
 
 type startOptions struct {
@@ -216,10 +244,86 @@ type startOptions struct {
 	// Option to treat all errors generated by minimega as warnings when launching
 	// an experiment.
 	mmErrAsWarn bool
+
+	// How often callers monitoring the start of an experiment should check on
+	// its launch progress.
+	progressInterval time.Duration
+
+	// How long callers should wait for the experiment to start before giving
+	// up on it.
+	timeout time.Duration
+
+	// VM to host schedule to use instead of the one in the experiment spec.
+	schedule map[string]string
+
+	// Maximum number of VMs to start at once. Zero means no limit.
+	maxConcurrentLaunch int
+
+	// Treat starting an already running experiment as a successful no-op.
+	idempotent bool
+
+	// Scheduling algorithm used to place VMs not explicitly scheduled.
+	scheduler string
+
+	// How long to wait for each boot group to be running, and a function called
+	// as each boot group is started.
+	bootGroupTimeout  time.Duration
+	bootGroupProgress func(BootGroup)
+
+	// Start-time variables made available to apps via their context.
+	vars map[string]string
+
+	// How many times to retry starting each delayed VM that fails to start, and
+	// a function called before each retry.
+	delayedRetries       int
+	delayedRetryProgress func(DelayedRetry)
+
+	// Priority of the start when queued behind other experiment starts.
+	priority int
+
+	// VLAN IDs allocated for the experiment's VLAN aliases, used instead of the
+	// IDs (if any) in the experiment spec for this start only.
+	vlanAllocation map[string]int
+
+	// Record the minimega commands run and state transitions made while
+	// starting the experiment to a trace file.
+	trace bool
+
+	// Readiness probe to run against VMs without their own, how long to wait for
+	// each VM to be ready, and a function called as VMs become ready.
+	readinessProbe    *ReadinessProbe
+	readinessTimeout  time.Duration
+	readinessProgress func(map[string]string)
+
+	// Fail the start if the experiment's VLANs or taps overlap with those of
+	// other running experiments once its VMs are launched.
+	verifyIsolation bool
+
+	// How many times to retry starting the experiment after it fails to start,
+	// and how long to wait before each retry.
+	autoRetries      int
+	autoRetryBackoff time.Duration
+}
+
+// NewStartOptions returns the start options initialized with the given option
+// list. It's exported so callers monitoring a start can access the options
+// relevant to them.
+func NewStartOptions(opts ...StartOption) startOptions {
+	return newStartOptions(opts...)
 }
 
 func newStartOptions(opts ...StartOption) startOptions {
-	var o startOptions
+	o := startOptions{
+		progressInterval:  DefaultProgressInterval,
+		timeout:           DefaultStartTimeout,
+		bootGroupTimeout:  DefaultBootGroupTimeout,
+		bootGroupProgress: func(BootGroup) {},
+
+		delayedRetryProgress: func(DelayedRetry) {},
+
+		readinessTimeout:  DefaultReadinessTimeout,
+		readinessProgress: func(map[string]string) {},
+	}
 
 	for _, opt := range opts {
 		opt(&o)
@@ -263,3 +367,518 @@ func StartWithMMErrorsAsWarnings(w bool) StartOption {
 		o.mmErrAsWarn = w
 	}
 }
+
+// StartWithProgressInterval sets how often the launch progress of the
+// experiment should be checked. Zero values are ignored.
+func StartWithProgressInterval(d time.Duration) StartOption {
+	return func(o *startOptions) {
+		if d > 0 {
+			o.progressInterval = d
+		}
+	}
+}
+
+// StartWithSchedule sets the hosts the given VMs should be scheduled on when
+// starting the experiment, overriding the schedule in the experiment spec.
+func StartWithSchedule(s map[string]string) StartOption {
+	return func(o *startOptions) {
+		o.schedule = s
+	}
+}
+
+// StartWithTimeout sets the overall deadline for starting the experiment.
+// Zero values are ignored.
+func StartWithTimeout(d time.Duration) StartOption {
+	return func(o *startOptions) {
+		if d > 0 {
+			o.timeout = d
+		}
+	}
+}
+
+// StartWithScheduler sets the scheduling algorithm (e.g. `round-robin`,
+// `binpack`, or `spread`) used to place VMs that haven't been explicitly
+// scheduled on a cluster host when starting the experiment. By default, such
+// VMs are placed by minimega.
+func StartWithScheduler(s string) StartOption {
+	return func(o *startOptions) {
+		o.scheduler = s
+	}
+}
+
+// StartWithVars sets variables (e.g. a git ref or seed value) made available
+// to apps run while starting the experiment via `app.GetContextVars`. They're
+// start-time-only, so they're never persisted to the experiment config.
+func StartWithVars(v map[string]string) StartOption {
+	return func(o *startOptions) {
+		o.vars = v
+	}
+}
+
+// StartWithIdempotent makes starting an experiment that's already running a
+// no-op instead of an error. Experiments started as a dry run are still
+// restarted.
+func StartWithIdempotent(i bool) StartOption {
+	return func(o *startOptions) {
+		o.idempotent = i
+	}
+}
+
+// StartWithMaxConcurrentLaunch limits the number of VMs started at once to n,
+// waiting for each batch of VMs to be running before starting the next. Values
+// less than 1 are ignored, starting all VMs at once.
+//
+// A reasonable limit depends on how much memory the cluster hosts have free for
+// VMs to boot with. As a rule of thumb, allow one VM per host for every 4 GB of
+// uncommitted host memory (e.g. 16 for a single host with 64 GB free), and
+// lower it for VMs with large disk images since they're slower to boot.
+func StartWithMaxConcurrentLaunch(n int) StartOption {
+	return func(o *startOptions) {
+		if n > 0 {
+			o.maxConcurrentLaunch = n
+		}
+	}
+}
+
+// StartWithBootGroupTimeout sets how long to wait for the VMs in each boot
+// group to be running before failing the start. Values less than 1 are
+// ignored.
+func StartWithBootGroupTimeout(t time.Duration) StartOption {
+	return func(o *startOptions) {
+		if t > 0 {
+			o.bootGroupTimeout = t
+		}
+	}
+}
+
+// StartWithBootGroupProgress sets a function to be called each time a boot
+// group starts being launched. It's only called for experiments with VMs that
+// have a boot order set.
+func StartWithBootGroupProgress(p func(BootGroup)) StartOption {
+	return func(o *startOptions) {
+		o.bootGroupProgress = p
+	}
+}
+
+// StartWithDelayedRetries sets how many times to retry starting each delayed VM
+// that fails to start before giving up on it. Values less than 1 are ignored.
+func StartWithDelayedRetries(n int) StartOption {
+	return func(o *startOptions) {
+		if n > 0 {
+			o.delayedRetries = n
+		}
+	}
+}
+
+// StartWithDelayedRetryProgress sets a function to be called before each retry
+// of a delayed VM that failed to start.
+func StartWithDelayedRetryProgress(p func(DelayedRetry)) StartOption {
+	return func(o *startOptions) {
+		o.delayedRetryProgress = p
+	}
+}
+
+// StartWithPriority sets the priority of the start when it's queued behind
+// other experiment starts. Higher priority starts are launched first, and
+// starts with the same priority are launched in the order they were queued.
+func StartWithPriority(p int) StartOption {
+	return func(o *startOptions) {
+		o.priority = p
+	}
+}
+
+// StartWithVLANAllocation sets VLAN IDs to use for the given VLAN aliases when
+// launching the experiment, such as those allocated from a shared VLAN pool.
+// They aren't saved to the experiment spec, so aliases without an ID in the
+// spec can be allocated a different ID the next time it's started.
+func StartWithVLANAllocation(a map[string]int) StartOption {
+	return func(o *startOptions) {
+		o.vlanAllocation = a
+	}
+}
+
+// StartWithTrace sets whether every minimega command run (and its responses)
+// and every state transition made while starting the experiment is recorded to
+// a trace file, which can be retrieved with LatestTrace.
+func StartWithTrace(t bool) StartOption {
+	return func(o *startOptions) {
+		o.trace = t
+	}
+}
+
+// StartWithReadinessProbe sets the readiness probe run against each launched
+// VM that doesn't set its own in its topology annotations. The experiment isn't
+// considered started until every probed VM is ready, since minimega reports VMs
+// as running as soon as QEMU starts, well before their guest OS has booted.
+func StartWithReadinessProbe(p ReadinessProbe) StartOption {
+	return func(o *startOptions) {
+		o.readinessProbe = &p
+	}
+}
+
+// StartWithReadinessTimeout sets how long to wait for launched VMs to pass
+// their readiness probes before the start fails. It defaults to
+// DefaultReadinessTimeout.
+func StartWithReadinessTimeout(t time.Duration) StartOption {
+	return func(o *startOptions) {
+		if t > 0 {
+			o.readinessTimeout = t
+		}
+	}
+}
+
+// StartWithReadinessProgress sets a function to be called with the readiness
+// state (ReadinessBooting or ReadinessReady) of every probed VM, keyed by VM
+// name, once VMs are launched and each time one of them becomes ready.
+func StartWithReadinessProgress(p func(map[string]string)) StartOption {
+	return func(o *startOptions) {
+		if p != nil {
+			o.readinessProgress = p
+		}
+	}
+}
+
+// StartWithIsolationCheck sets whether the experiment's VLANs and taps are
+// checked against those of other running experiments once its VMs are
+// launched (see VerifyIsolation), failing the start if any overlap.
+func StartWithIsolationCheck(c bool) StartOption {
+	return func(o *startOptions) {
+		o.verifyIsolation = c
+	}
+}
+
+// Limits on retrying failed starts with StartWithAutoRetry, so a start that
+// keeps failing doesn't tie up the experiment (and cluster) indefinitely or
+// retry faster than the cluster can recover.
+const (
+	MaxAutoRetries      = 5
+	MinAutoRetryBackoff = 5 * time.Second
+)
+
+// StartWithAutoRetry sets how many times to retry starting the experiment from
+// scratch after it fails to start (e.g. due to a transient cluster problem),
+// waiting for the given backoff before each retry. Callers monitoring the start
+// are responsible for cleaning up after each failed attempt and retrying it.
+// Attempts less than 1 are ignored, attempts over MaxAutoRetries are capped at
+// it, and backoffs are at least MinAutoRetryBackoff.
+func StartWithAutoRetry(attempts int, backoff time.Duration) StartOption {
+	return func(o *startOptions) {
+		if attempts < 1 {
+			return
+		}
+
+		if attempts > MaxAutoRetries {
+			attempts = MaxAutoRetries
+		}
+
+		if backoff < MinAutoRetryBackoff {
+			backoff = MinAutoRetryBackoff
+		}
+
+		o.autoRetries = attempts
+		o.autoRetryBackoff = backoff
+	}
+}
+
+func (this startOptions) DryRun() bool {
+	return this.dryrun
+}
+
+func (this startOptions) ProgressInterval() time.Duration {
+	return this.progressInterval
+}
+
+func (this startOptions) Timeout() time.Duration {
+	return this.timeout
+}
+
+// Default amount of time to spend forcibly killing an experiment's VMs after
+// minimega fails to clear them before giving up.
+const DefaultForceKillTimeout = 30 * time.Second
+
+// Default amount of time to wait for the VMs in a boot group to exit when
+// stopping an experiment before moving on to the next group.
+const DefaultTeardownGroupTimeout = 1 * time.Minute
+
+type StopOption func(*stopOptions)
+
+type stopOptions struct {
+	force        bool
+	forceTimeout time.Duration
+
+	// Kill all the VMs at once instead of in reverse boot order, along with how
+	// long to wait for each boot group to exit and a function called as each
+	// group is torn down.
+	parallel         bool
+	teardownTimeout  time.Duration
+	teardownProgress func(BootGroup)
+
+	// How long to wait for VMs to shut down after asking them to power off
+	// before killing them, if at all, and a function called as each VM's
+	// shutdown progresses.
+	graceful         time.Duration
+	shutdownProgress func(ShutdownProgress)
+
+	// Called with the names of the VMs still remaining when forcibly stopping
+	// an experiment.
+	progress func([]string)
+
+	// Label for the disk snapshots taken of each VM before stopping, if any, and
+	// a function called as each VM is snapshotted.
+	snapshot         string
+	snapshotProgress func(SnapshotProgress)
+
+	// Set if the snapshot label given isn't valid, in which case the experiment
+	// isn't stopped.
+	snapshotErr error
+
+	// Context passed to the apps run while stopping the experiment. Notes added
+	// to it by the apps can be consumed by the caller.
+	ctx context.Context
+}
+
+func newStopOptions(opts ...StopOption) stopOptions {
+	o := stopOptions{
+		ctx:              context.TODO(),
+		forceTimeout:     DefaultForceKillTimeout,
+		teardownTimeout:  DefaultTeardownGroupTimeout,
+		teardownProgress: func(BootGroup) {},
+		shutdownProgress: func(ShutdownProgress) {},
+		progress:         func([]string) {},
+		snapshotProgress: func(SnapshotProgress) {},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// StopWithForce causes the processes backing the experiment's VMs to be killed
+// directly if minimega is unable to kill them itself.
+func StopWithForce(f bool) StopOption {
+	return func(o *stopOptions) {
+		o.force = f
+	}
+}
+
+// StopWithParallel causes all the experiment's VMs to be killed at once instead
+// of in reverse boot order, for the fastest teardown.
+func StopWithParallel(p bool) StopOption {
+	return func(o *stopOptions) {
+		o.parallel = p
+	}
+}
+
+// MaxGracefulTimeout is the longest StopWithGraceful waits for VMs to shut down,
+// which keeps graceful stops within how long the web server's stop lock lasts.
+const MaxGracefulTimeout = 45 * time.Second
+
+// StopWithGraceful causes each of the experiment's VMs to be asked to power off
+// via ACPI before the experiment is stopped, waiting up to the given timeout
+// (capped at MaxGracefulTimeout) for them to shut down cleanly. VMs still
+// running after the timeout are killed.
+func StopWithGraceful(timeout time.Duration) StopOption {
+	return func(o *stopOptions) {
+		if timeout > MaxGracefulTimeout {
+			timeout = MaxGracefulTimeout
+		}
+
+		o.graceful = timeout
+	}
+}
+
+// StopWithShutdownProgress sets a function to be called as each VM is asked to
+// power off, shuts down, or has to be killed when stopping the experiment
+// gracefully.
+func StopWithShutdownProgress(f func(ShutdownProgress)) StopOption {
+	return func(o *stopOptions) {
+		if f != nil {
+			o.shutdownProgress = f
+		}
+	}
+}
+
+// StopWithTeardownProgress sets a function to be called each time a boot group
+// starts being torn down when stopping an experiment in reverse boot order.
+func StopWithTeardownProgress(f func(BootGroup)) StopOption {
+	return func(o *stopOptions) {
+		if f != nil {
+			o.teardownProgress = f
+		}
+	}
+}
+
+// StopWithProgress sets a function to be called with the names of the VMs that
+// are still being killed when forcibly stopping the experiment.
+func StopWithProgress(f func([]string)) StopOption {
+	return func(o *stopOptions) {
+		if f != nil {
+			o.progress = f
+		}
+	}
+}
+
+// StopWithSnapshot causes the disk of each running VM in the experiment to be
+// snapshotted, using the given label in the snapshot file names, before the
+// experiment is stopped. The label must be valid (see ValidSnapshotLabel), or
+// stopping the experiment fails with ErrInvalidSnapshotLabel (wrapped).
+func StopWithSnapshot(label string) StopOption {
+	return func(o *stopOptions) {
+		if label != "" && !ValidSnapshotLabel(label) {
+			o.snapshotErr = fmt.Errorf("%w: %q (must only contain letters, numbers, '.', '_' and '-')", ErrInvalidSnapshotLabel, label)
+			return
+		}
+
+		o.snapshot = label
+	}
+}
+
+// StopWithSnapshotProgress sets a function to be called as each VM is
+// snapshotted when stopping the experiment with a snapshot.
+func StopWithSnapshotProgress(f func(SnapshotProgress)) StopOption {
+	return func(o *stopOptions) {
+		if f != nil {
+			o.snapshotProgress = f
+		}
+	}
+}
+
+// StopWithContext sets the context passed to the apps run while stopping the
+// experiment, such as a notes context used to collect their logs.
+func StopWithContext(ctx context.Context) StopOption {
+	return func(o *stopOptions) {
+		if ctx != nil {
+			o.ctx = ctx
+		}
+	}
+}
+
+func (this startOptions) MaxConcurrentLaunch() int {
+	return this.maxConcurrentLaunch
+}
+
+func (this startOptions) Idempotent() bool {
+	return this.idempotent
+}
+
+func (this startOptions) Scheduler() string {
+	return this.scheduler
+}
+
+func (this startOptions) Vars() map[string]string {
+	return this.vars
+}
+
+func (this startOptions) Priority() int {
+	return this.priority
+}
+
+// AutoRetry returns how many times a failed start should be retried, and how
+// long to wait before each retry.
+func (this startOptions) AutoRetry() (int, time.Duration) {
+	return this.autoRetries, this.autoRetryBackoff
+}
+
+type CloneOption func(*cloneOptions)
+
+type cloneOptions struct {
+	subnetOffset int
+	owner        string
+}
+
+func newCloneOptions(opts ...CloneOption) cloneOptions {
+	var o cloneOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// CloneWithSubnetOffset shifts each IPv4 interface address (and gateway) in the
+// cloned topology by the given number of subnets, based on the interface's
+// mask, so the clone doesn't collide with the original experiment. For example,
+// an offset of 1 moves 10.0.1.5/24 to 10.0.2.5/24.
+func CloneWithSubnetOffset(n int) CloneOption {
+	return func(o *cloneOptions) {
+		o.subnetOffset = n
+	}
+}
+
+// CloneWithOwner makes the given user the owner of the clone. Users with access
+// to the source experiment don't get access to the clone.
+func CloneWithOwner(u string) CloneOption {
+	return func(o *cloneOptions) {
+		o.owner = u
+	}
+}
+
+type ExportOption func(*exportOptions)
+
+type exportOptions struct {
+	images bool
+}
+
+func newExportOptions(opts ...ExportOption) exportOptions {
+	o := exportOptions{images: true}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ExportWithImages sets whether the disk images referenced by the experiment's
+// VMs are included in the bundle. They're included by default; leaving them out
+// produces a lightweight, config-only bundle for installations that already
+// have the images.
+func ExportWithImages(i bool) ExportOption {
+	return func(o *exportOptions) {
+		o.images = i
+	}
+}
+
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	name   string
+	rename bool
+	owner  string
+}
+
+func newImportOptions(opts ...ImportOption) importOptions {
+	var o importOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ImportWithName imports the experiment with the given name instead of the name
+// it was exported with.
+func ImportWithName(n string) ImportOption {
+	return func(o *importOptions) {
+		o.name = n
+	}
+}
+
+// ImportWithRename adds a numeric suffix to the imported experiment's name if
+// an experiment with the name already exists, instead of failing the import.
+func ImportWithRename(r bool) ImportOption {
+	return func(o *importOptions) {
+		o.rename = r
+	}
+}
+
+// ImportWithOwner makes the given user the owner of the imported experiment.
+func ImportWithOwner(u string) ImportOption {
+	return func(o *importOptions) {
+		o.owner = u
+	}
+}
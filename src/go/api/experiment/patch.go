@@ -0,0 +1,114 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/jsonpatch"
+)
+
+var ErrImmutableField = errors.New("field cannot be changed while experiment is running")
+
+// Top-level experiment spec fields that can't be changed while the experiment
+// is running, since they've already been deployed to minimega.
+var runningImmutableFields = map[string]struct{}{
+	"experimentName": {},
+	"baseDir":        {},
+	"defaultBridge":  {},
+	"topology":       {},
+	"scenario":       {},
+	"vlans":          {},
+	"schedules":      {},
+	"deployMode":     {},
+	"useGREMesh":     {},
+}
+
+// Patch applies the given JSON Patch (RFC 6902) document to the spec of the
+// experiment with the given name. Paths in the patch are relative to the spec.
+// All operations are applied or, if any of them fail, none are. If the
+// experiment is running, operations that modify fields already deployed (like
+// the topology) are rejected with ErrImmutableField. The patched spec is
+// validated before being saved, and the updated config is returned.
+func Patch(name string, patch []byte) (*store.Config, error) {
+	defer InvalidateCached(name)
+
+	ops, err := jsonpatch.Decode(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment %s: %w", name, err)
+	}
+
+	if exp.Running() {
+		for _, op := range ops {
+			if err := checkMutable(op); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	patched, err := jsonpatch.Apply(c.Spec, ops)
+	if err != nil {
+		return nil, err
+	}
+
+	spec, ok := patched.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: patched experiment spec is not an object", jsonpatch.ErrInvalidPatch)
+	}
+
+	c.Spec = spec
+
+	if err := types.ValidateConfigSpec(*c); err != nil {
+		return nil, fmt.Errorf("validating patched experiment %s: %w", name, err)
+	}
+
+	if _, err := types.DecodeExperimentFromConfig(*c); err != nil {
+		return nil, fmt.Errorf("decoding patched experiment %s: %w", name, err)
+	}
+
+	if err := store.Update(c); err != nil {
+		return nil, fmt.Errorf("saving patched experiment %s: %w", name, err)
+	}
+
+	return c, nil
+}
+
+func checkMutable(op jsonpatch.Operation) error {
+	// Tests don't modify anything.
+	if op.Op == "test" {
+		return nil
+	}
+
+	paths := []string{op.Path}
+
+	// A move removes the value it's moving, but a copy leaves it in place.
+	if op.Op == "move" {
+		paths = append(paths, op.From)
+	}
+
+	for _, path := range paths {
+		tokens := jsonpatch.Path(path)
+
+		if len(tokens) == 0 {
+			return fmt.Errorf("%w: cannot %s entire spec", ErrImmutableField, op.Op)
+		}
+
+		if _, ok := runningImmutableFields[tokens[0]]; ok {
+			return fmt.Errorf("%w: %s", ErrImmutableField, path)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"phenix/scheduler"
+	"phenix/store"
+
+	"github.com/activeshadow/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	ErrPresetNotFound = errors.New("start preset not found")
+	ErrPresetExists   = errors.New("start preset already exists")
+	ErrInvalidPreset  = errors.New("invalid start preset")
+)
+
+// StartPreset is a named, saved set of options to start experiments with, so
+// they don't have to be provided each time an experiment is started. Durations
+// are strings parsable by `time.ParseDuration`. Zero values leave the
+// corresponding start option at its default.
+type StartPreset struct {
+	Name             string `json:"name" structs:"name" mapstructure:"name"`
+	Scheduler        string `json:"scheduler,omitempty" structs:"scheduler" mapstructure:"scheduler"`
+	MaxConcurrent    int    `json:"maxConcurrent,omitempty" structs:"maxConcurrent" mapstructure:"maxConcurrent"`
+	Timeout          string `json:"timeout,omitempty" structs:"timeout" mapstructure:"timeout"`
+	BootGroupTimeout string `json:"bootGroupTimeout,omitempty" structs:"bootGroupTimeout" mapstructure:"bootGroupTimeout"`
+	ProgressInterval string `json:"progressInterval,omitempty" structs:"progressInterval" mapstructure:"progressInterval"`
+	Idempotent       bool   `json:"idempotent,omitempty" structs:"idempotent" mapstructure:"idempotent"`
+}
+
+// Validate returns an error wrapping ErrInvalidPreset if any of the preset's
+// options are invalid.
+func (this StartPreset) Validate() error {
+	if this.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidPreset)
+	}
+
+	if this.Scheduler != "" {
+		var known bool
+
+		for _, name := range scheduler.List() {
+			if name == this.Scheduler {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			return fmt.Errorf("%w: unknown scheduler %s", ErrInvalidPreset, this.Scheduler)
+		}
+	}
+
+	if this.MaxConcurrent < 0 {
+		return fmt.Errorf("%w: max concurrent launch must not be negative", ErrInvalidPreset)
+	}
+
+	if _, err := parsePresetDuration("timeout", this.Timeout); err != nil {
+		return err
+	}
+
+	if _, err := parsePresetDuration("boot group timeout", this.BootGroupTimeout); err != nil {
+		return err
+	}
+
+	interval, err := parsePresetDuration("progress interval", this.ProgressInterval)
+	if err != nil {
+		return err
+	}
+
+	if interval != 0 && (interval < MinProgressInterval || interval > MaxProgressInterval) {
+		return fmt.Errorf("%w: progress interval must be between %v and %v", ErrInvalidPreset, MinProgressInterval, MaxProgressInterval)
+	}
+
+	return nil
+}
+
+// Options returns the start options configured by the preset. Any options given
+// after them when starting an experiment take precedence.
+func (this StartPreset) Options() []StartOption {
+	// Already validated when the preset was saved.
+	var (
+		timeout, _   = parsePresetDuration("timeout", this.Timeout)
+		bgTimeout, _ = parsePresetDuration("boot group timeout", this.BootGroupTimeout)
+		interval, _  = parsePresetDuration("progress interval", this.ProgressInterval)
+	)
+
+	opts := []StartOption{
+		StartWithTimeout(timeout),
+		StartWithBootGroupTimeout(bgTimeout),
+		StartWithProgressInterval(interval),
+		StartWithMaxConcurrentLaunch(this.MaxConcurrent),
+	}
+
+	if this.Scheduler != "" {
+		opts = append(opts, StartWithScheduler(this.Scheduler))
+	}
+
+	if this.Idempotent {
+		opts = append(opts, StartWithIdempotent(true))
+	}
+
+	return opts
+}
+
+// ListPresets returns all the saved start presets.
+func ListPresets() ([]StartPreset, error) {
+	configs, err := store.List("StartPreset")
+	if err != nil {
+		return nil, fmt.Errorf("getting start presets from store: %w", err)
+	}
+
+	presets := make([]StartPreset, len(configs))
+
+	for i, c := range configs {
+		if err := mapstructure.Decode(c.Spec, &presets[i]); err != nil {
+			return nil, fmt.Errorf("decoding start preset %s: %w", c.Metadata.Name, err)
+		}
+	}
+
+	return presets, nil
+}
+
+// GetPreset returns the saved start preset with the given name.
+func GetPreset(name string) (*StartPreset, error) {
+	c := presetConfig(name)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+		}
+
+		return nil, fmt.Errorf("getting start preset %s from store: %w", name, err)
+	}
+
+	var preset StartPreset
+
+	if err := mapstructure.Decode(c.Spec, &preset); err != nil {
+		return nil, fmt.Errorf("decoding start preset %s: %w", name, err)
+	}
+
+	return &preset, nil
+}
+
+// CreatePreset validates and saves the given start preset, returning an error
+// wrapping ErrPresetExists if one with the same name already exists.
+func CreatePreset(preset StartPreset) error {
+	if err := preset.Validate(); err != nil {
+		return err
+	}
+
+	c := presetConfig(preset.Name)
+	c.Spec = structs.MapDefaultCase(preset, structs.CASESNAKE)
+
+	if err := store.Create(c); err != nil {
+		if errors.Is(err, store.ErrExist) {
+			return fmt.Errorf("%w: %s", ErrPresetExists, preset.Name)
+		}
+
+		return fmt.Errorf("saving start preset %s: %w", preset.Name, err)
+	}
+
+	return nil
+}
+
+// UpdatePreset validates and saves the given start preset, returning an error
+// wrapping ErrPresetNotFound if it doesn't already exist.
+func UpdatePreset(preset StartPreset) error {
+	if err := preset.Validate(); err != nil {
+		return err
+	}
+
+	c := presetConfig(preset.Name)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrPresetNotFound, preset.Name)
+		}
+
+		return fmt.Errorf("getting start preset %s from store: %w", preset.Name, err)
+	}
+
+	c.Spec = structs.MapDefaultCase(preset, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("saving start preset %s: %w", preset.Name, err)
+	}
+
+	return nil
+}
+
+// DeletePreset deletes the saved start preset with the given name.
+func DeletePreset(name string) error {
+	if err := store.Delete(presetConfig(name)); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+		}
+
+		return fmt.Errorf("deleting start preset %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func presetConfig(name string) *store.Config {
+	return &store.Config{
+		Version:  store.API_GROUP + "/v1",
+		Kind:     "StartPreset",
+		Metadata: store.ConfigMetadata{Name: name},
+	}
+}
+
+func parsePresetDuration(field, v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid %s %s", ErrInvalidPreset, field, v)
+	}
+
+	if d < 0 {
+		return 0, fmt.Errorf("%w: %s must not be negative", ErrInvalidPreset, field)
+	}
+
+	return d, nil
+}
@@ -0,0 +1,52 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStartPresetValidate(t *testing.T) {
+	valid := StartPreset{
+		Name:             "nightly",
+		Scheduler:        "round-robin",
+		MaxConcurrent:    8,
+		Timeout:          "1h",
+		BootGroupTimeout: "10m",
+		ProgressInterval: "5s",
+	}
+
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid preset, got %v", err)
+	}
+
+	invalid := map[string]StartPreset{
+		"missing name":      {Scheduler: "round-robin"},
+		"unknown scheduler": {Name: "foo", Scheduler: "bogus"},
+		"bad timeout":       {Name: "foo", Timeout: "soon"},
+		"negative timeout":  {Name: "foo", BootGroupTimeout: "-1m"},
+		"fast progress":     {Name: "foo", ProgressInterval: "1ms"},
+		"negative batch":    {Name: "foo", MaxConcurrent: -1},
+	}
+
+	for desc, preset := range invalid {
+		if err := preset.Validate(); !errors.Is(err, ErrInvalidPreset) {
+			t.Errorf("%s: expected invalid preset error, got %v", desc, err)
+		}
+	}
+
+	o := newStartOptions(append(valid.Options(), StartWithMaxConcurrentLaunch(2))...)
+
+	if o.timeout != time.Hour || o.bootGroupTimeout != 10*time.Minute || o.progressInterval != 5*time.Second {
+		t.Errorf("unexpected durations from preset: %v, %v, %v", o.timeout, o.bootGroupTimeout, o.progressInterval)
+	}
+
+	if o.scheduler != "round-robin" {
+		t.Errorf("expected round-robin scheduler, got %q", o.scheduler)
+	}
+
+	// Options given after the preset's take precedence.
+	if o.maxConcurrentLaunch != 2 {
+		t.Errorf("expected max concurrent launch override of 2, got %d", o.maxConcurrentLaunch)
+	}
+}
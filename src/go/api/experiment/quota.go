@@ -0,0 +1,111 @@
+package experiment
+
+import (
+	"fmt"
+	"strings"
+
+	"phenix/types"
+	"phenix/util/mm"
+)
+
+// ResourceTotals is the total amount of resources requested by, allowed for,
+// or available to an experiment's VMs.
+type ResourceTotals struct {
+	VCPUs    int `json:"vcpus"`
+	MemoryMB int `json:"memoryMB"`
+	VMs      int `json:"vms"`
+}
+
+// QuotaError is returned (wrapped) when starting an experiment would exceed its
+// quota or the capacity of the cluster. It wraps ErrScheduleInfeasible. Zero
+// values in Limit mean no limit, and Available is only set when the experiment
+// was checked against the cluster's capacity.
+type QuotaError struct {
+	Requested ResourceTotals  `json:"requested"`
+	Limit     ResourceTotals  `json:"limit"`
+	Available *ResourceTotals `json:"available,omitempty"`
+
+	// Descriptions of each limit exceeded.
+	Exceeded []string `json:"exceeded"`
+}
+
+func (this QuotaError) Error() string {
+	return fmt.Sprintf("experiment resources exceed limits: %s", strings.Join(this.Exceeded, "; "))
+}
+
+func (QuotaError) Unwrap() error {
+	return ErrScheduleInfeasible
+}
+
+// requestedResources sums the resources of the VMs that would be booted when
+// starting the given experiment.
+func requestedResources(exp *types.Experiment) ResourceTotals {
+	var totals ResourceTotals
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		totals.VCPUs += node.Hardware().VCPU()
+		totals.MemoryMB += node.Hardware().Memory()
+		totals.VMs++
+	}
+
+	return totals
+}
+
+// checkQuota returns a QuotaError if the resources requested by the given
+// experiment exceed its quota, or the uncommitted memory of the given cluster
+// hosts (if any are provided). vCPUs are commonly overcommitted, so they're only
+// checked against the experiment's quota.
+func checkQuota(exp *types.Experiment, cluster mm.Hosts) error {
+	var (
+		quota = exp.Spec.Quota()
+		qerr  = QuotaError{Requested: requestedResources(exp), Exceeded: []string{}}
+	)
+
+	if quota != nil {
+		qerr.Limit = ResourceTotals{VCPUs: quota.MaxVCPUs(), MemoryMB: quota.MaxMemory(), VMs: quota.MaxVMs()}
+	}
+
+	if limit := qerr.Limit.VCPUs; limit > 0 && qerr.Requested.VCPUs > limit {
+		qerr.Exceeded = append(qerr.Exceeded, fmt.Sprintf("%d vCPUs requested, over quota of %d by %d", qerr.Requested.VCPUs, limit, qerr.Requested.VCPUs-limit))
+	}
+
+	if limit := qerr.Limit.MemoryMB; limit > 0 && qerr.Requested.MemoryMB > limit {
+		qerr.Exceeded = append(qerr.Exceeded, fmt.Sprintf("%d MB of memory requested, over quota of %d MB by %d MB", qerr.Requested.MemoryMB, limit, qerr.Requested.MemoryMB-limit))
+	}
+
+	if limit := qerr.Limit.VMs; limit > 0 && qerr.Requested.VMs > limit {
+		qerr.Exceeded = append(qerr.Exceeded, fmt.Sprintf("%d VMs requested, over quota of %d by %d", qerr.Requested.VMs, limit, qerr.Requested.VMs-limit))
+	}
+
+	if len(cluster) > 0 {
+		qerr.Available = new(ResourceTotals)
+
+		for _, host := range cluster {
+			if free := host.CPUs - host.CPUCommit; free > 0 {
+				qerr.Available.VCPUs += free
+			}
+
+			if free := host.MemTotal - host.MemCommit; free > 0 {
+				qerr.Available.MemoryMB += free
+			}
+		}
+
+		if free := qerr.Available.MemoryMB; qerr.Requested.MemoryMB > free {
+			qerr.Exceeded = append(qerr.Exceeded, fmt.Sprintf("%d MB of memory requested, over uncommitted cluster memory of %d MB by %d MB", qerr.Requested.MemoryMB, free, qerr.Requested.MemoryMB-free))
+		}
+	}
+
+	if len(qerr.Exceeded) > 0 {
+		return qerr
+	}
+
+	return nil
+}
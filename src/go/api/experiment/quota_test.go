@@ -0,0 +1,47 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+
+	"phenix/util/mm"
+)
+
+func TestCheckQuota(t *testing.T) {
+	node := func(hostname string, vcpus, memory int) map[string]any {
+		return testNode(hostname, map[string]any{"hardware.vcpus": vcpus, "hardware.memory": memory})
+	}
+
+	exp := testExperiment(t,
+		map[string]any{"quota": map[string]any{"maxVCPUs": 4, "maxMemory": 8192}},
+		node("router", 2, 2048), node("host", 4, 4096),
+	)
+
+	err := checkQuota(exp, nil)
+
+	var qerr QuotaError
+
+	if !errors.As(err, &qerr) || !errors.Is(err, ErrScheduleInfeasible) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+
+	if qerr.Requested.VCPUs != 6 || qerr.Requested.MemoryMB != 6144 || qerr.Requested.VMs != 2 {
+		t.Fatalf("unexpected requested resources %+v", qerr.Requested)
+	}
+
+	if len(qerr.Exceeded) != 1 {
+		t.Fatalf("expected only the vCPU quota to be exceeded, got %v", qerr.Exceeded)
+	}
+
+	exp.Spec.Topology().Nodes()[1].Hardware().SetVCPU(2)
+
+	if err := checkQuota(exp, nil); err != nil {
+		t.Fatalf("expected resources within quota, got %v", err)
+	}
+
+	cluster := mm.Hosts{{Name: "compute1", MemTotal: 8192, MemCommit: 4096}}
+
+	if err := checkQuota(exp, cluster); !errors.As(err, &qerr) || qerr.Available.MemoryMB != 4096 {
+		t.Fatalf("expected cluster capacity to be exceeded, got %v", err)
+	}
+}
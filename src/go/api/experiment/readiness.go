@@ -0,0 +1,306 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+)
+
+var (
+	ErrInvalidReadinessProbe = errors.New("invalid readiness probe")
+	ErrVMNotReady            = errors.New("VM not ready")
+)
+
+// Readiness states of VMs with a readiness probe, reported to the function set
+// with StartWithReadinessProgress. VMs are booting from when they're launched
+// until their probe succeeds.
+const (
+	ReadinessBooting = "booting"
+	ReadinessReady   = "ready"
+)
+
+// Types of readiness probes.
+const (
+	// The VM's miniccc agent has connected, with the VM's hostname.
+	ReadinessProbeAgent = "agent"
+
+	// A TCP port on the VM accepts connections. The connection is made from the
+	// host phenix is running on, not from within the experiment, so the VM's
+	// address has to be reachable from that host (e.g. through a tap).
+	ReadinessProbeTCP = "tcp"
+
+	// A marker file exists in the VM, as reported by its miniccc agent.
+	ReadinessProbeFile = "file"
+)
+
+// ReadinessProbeAnnotation is the topology node annotation overriding the
+// readiness probe for a single VM, in the same form as ParseReadinessProbe
+// accepts. It can be set to `none` to not wait for the VM.
+const ReadinessProbeAnnotation = "phenix/readiness-probe"
+
+// DefaultReadinessTimeout is how long to wait for each VM to be ready by
+// default.
+const DefaultReadinessTimeout = 10 * time.Minute
+
+// How often each VM's readiness probe is run while it's booting, and how long
+// each probe has to succeed. They're variables so tests can shorten them.
+var (
+	readinessInterval     = 5 * time.Second
+	readinessProbeTimeout = 5 * time.Second
+)
+
+// ReadinessProbe is a check that a VM's guest OS has finished booting, beyond
+// minimega reporting it as running.
+type ReadinessProbe struct {
+	Type string `json:"type"`
+
+	// Port to connect to for TCP probes, and optionally the address to connect
+	// to (defaults to the VM's first static IP address).
+	Port    int    `json:"port,omitempty"`
+	Address string `json:"address,omitempty"`
+
+	// Path of the marker file for file probes.
+	Path string `json:"path,omitempty"`
+}
+
+// ParseReadinessProbe parses a readiness probe of the form `agent`,
+// `tcp:<port>`, `tcp:<address>:<port>`, or `file:<path>`.
+func ParseReadinessProbe(probe string) (ReadinessProbe, error) {
+	typ, arg, _ := strings.Cut(strings.TrimSpace(probe), ":")
+
+	switch typ {
+	case ReadinessProbeAgent:
+		if arg != "" {
+			return ReadinessProbe{}, fmt.Errorf("%w: agent probes don't take an argument", ErrInvalidReadinessProbe)
+		}
+
+		return ReadinessProbe{Type: typ}, nil
+	case ReadinessProbeTCP:
+		var addr string
+
+		if i := strings.LastIndex(arg, ":"); i >= 0 {
+			addr, arg = arg[:i], arg[i+1:]
+		}
+
+		port, err := strconv.Atoi(arg)
+		if err != nil || port < 1 || port > 65535 {
+			return ReadinessProbe{}, fmt.Errorf("%w: invalid TCP port %q", ErrInvalidReadinessProbe, arg)
+		}
+
+		return ReadinessProbe{Type: typ, Port: port, Address: addr}, nil
+	case ReadinessProbeFile:
+		if arg == "" {
+			return ReadinessProbe{}, fmt.Errorf("%w: file probes need a path", ErrInvalidReadinessProbe)
+		}
+
+		return ReadinessProbe{Type: typ, Path: arg}, nil
+	}
+
+	return ReadinessProbe{}, fmt.Errorf("%w: unknown probe type %q", ErrInvalidReadinessProbe, typ)
+}
+
+// readinessProbes returns the readiness probe for each of the given VMs in the
+// given experiment, using the given default probe for VMs without one set in
+// their topology annotations. VMs without a probe aren't included.
+func readinessProbes(exp *types.Experiment, vms []string, def *ReadinessProbe) (map[string]ReadinessProbe, error) {
+	probes := make(map[string]ReadinessProbe)
+
+	for _, name := range vms {
+		node := exp.Spec.Topology().FindNodeByName(name)
+		if node == nil {
+			continue
+		}
+
+		if v, ok := node.GetAnnotation(ReadinessProbeAnnotation); ok {
+			s := fmt.Sprintf("%v", v)
+
+			if s == "none" {
+				continue
+			}
+
+			probe, err := ParseReadinessProbe(s)
+			if err != nil {
+				return nil, fmt.Errorf("parsing readiness probe for VM %s: %w", name, err)
+			}
+
+			probes[name] = probe
+
+			continue
+		}
+
+		if def != nil {
+			probes[name] = *def
+		}
+	}
+
+	return probes, nil
+}
+
+// waitForReadiness runs the readiness probe for each of the given VMs in the
+// given experiment until it succeeds, calling progress with the readiness state
+// of every VM being probed each time one becomes ready. It returns an error
+// wrapping ErrVMNotReady listing the VMs that still weren't ready once the
+// timeout passed.
+func waitForReadiness(ctx context.Context, exp *types.Experiment, probes map[string]ReadinessProbe, timeout time.Duration, progress func(map[string]string)) error {
+	if len(probes) == 0 {
+		return nil
+	}
+
+	var (
+		ns     = exp.Spec.ExperimentName()
+		states = make(map[string]string)
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+	)
+
+	report := func() {
+		copied := make(map[string]string)
+
+		for k, v := range states {
+			copied[k] = v
+		}
+
+		progress(copied)
+	}
+
+	for name := range probes {
+		states[name] = ReadinessBooting
+	}
+
+	report()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for name, probe := range probes {
+		wg.Add(1)
+
+		go func(name string, probe ReadinessProbe) {
+			defer wg.Done()
+
+			for {
+				if err := runReadinessProbe(ctx, exp, ns, name, probe); err == nil {
+					mu.Lock()
+					defer mu.Unlock()
+
+					states[name] = ReadinessReady
+					report()
+
+					return
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(readinessInterval):
+				}
+			}
+		}(name, probe)
+	}
+
+	wg.Wait()
+
+	var booting []string
+
+	for name, state := range states {
+		if state != ReadinessReady {
+			booting = append(booting, name)
+		}
+	}
+
+	if len(booting) == 0 {
+		return nil
+	}
+
+	sort.Strings(booting)
+
+	// The parent context being canceled takes precedence over the timeout.
+	if err := context.Cause(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+
+	return fmt.Errorf("%w after %v: %s", ErrVMNotReady, timeout, strings.Join(booting, ", "))
+}
+
+func runReadinessProbe(ctx context.Context, exp *types.Experiment, ns, vm string, probe ReadinessProbe) error {
+	switch probe.Type {
+	case ReadinessProbeAgent:
+		return mm.IsC2ClientActive(mm.C2Context(ctx), mm.C2NS(ns), mm.C2VM(vm), mm.C2Timeout(readinessProbeTimeout))
+	case ReadinessProbeTCP:
+		addr := probe.Address
+
+		if addr == "" {
+			node := exp.Spec.Topology().FindNodeByName(vm)
+
+			for _, iface := range node.Network().Interfaces() {
+				if ip := iface.Address(); ip != "" {
+					addr = ip
+					break
+				}
+			}
+		}
+
+		if addr == "" {
+			return fmt.Errorf("no address to probe for VM %s", vm)
+		}
+
+		dialer := net.Dialer{Timeout: readinessProbeTimeout}
+
+		conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(addr, strconv.Itoa(probe.Port)))
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	case ReadinessProbeFile:
+		opts := []mm.C2Option{mm.C2Context(ctx), mm.C2NS(ns), mm.C2VM(vm), mm.C2Timeout(readinessProbeTimeout)}
+
+		id, err := mm.ExecC2Command(append(opts, mm.C2Command("ls "+probe.Path), mm.C2Wait())...)
+		if err != nil {
+			return err
+		}
+
+		// The probe is run every interval until the VM is ready, so its commands
+		// would otherwise pile up in the namespace.
+		defer deleteC2Command(ns, id)
+
+		// `ls` only writes the path to stdout if it exists.
+		resp, err := mm.GetC2Response(append(opts, mm.C2CommandID(id), mm.C2ResponseTypeStdout())...)
+		if err != nil {
+			return err
+		}
+
+		if strings.TrimSpace(resp) == "" {
+			return fmt.Errorf("marker file %s not found in VM %s", probe.Path, vm)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: unknown probe type %q", ErrInvalidReadinessProbe, probe.Type)
+}
+
+// deleteC2Command deletes the cc command with the given ID, and its responses,
+// from the given namespace.
+func deleteC2Command(ns, id string) {
+	cmd := mmcli.NewNamespacedCommand(ns)
+
+	for _, kind := range []string{"response", "command"} {
+		cmd.Command = fmt.Sprintf("cc delete %s %s", kind, id)
+
+		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+			plog.Warn("deleting readiness probe cc command", "ns", ns, "id", id, "kind", kind, "err", err)
+		}
+	}
+}
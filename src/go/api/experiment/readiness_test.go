@@ -0,0 +1,112 @@
+package experiment
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseReadinessProbe(t *testing.T) {
+	cases := map[string]ReadinessProbe{
+		"agent":           {Type: ReadinessProbeAgent},
+		"tcp:22":          {Type: ReadinessProbeTCP, Port: 22},
+		"tcp:10.0.0.1:80": {Type: ReadinessProbeTCP, Port: 80, Address: "10.0.0.1"},
+		"file:/tmp/ready": {Type: ReadinessProbeFile, Path: "/tmp/ready"},
+	}
+
+	for in, expected := range cases {
+		probe, err := ParseReadinessProbe(in)
+		if err != nil {
+			t.Errorf("parsing %s: %v", in, err)
+			continue
+		}
+
+		if probe != expected {
+			t.Errorf("parsing %s: expected %+v, got %+v", in, expected, probe)
+		}
+	}
+
+	for _, in := range []string{"", "ping", "agent:foo", "tcp", "tcp:ssh", "tcp:70000", "file:"} {
+		if _, err := ParseReadinessProbe(in); !errors.Is(err, ErrInvalidReadinessProbe) {
+			t.Errorf("parsing %q: expected ErrInvalidReadinessProbe, got %v", in, err)
+		}
+	}
+}
+
+func TestWaitForReadiness(t *testing.T) {
+	interval, timeout := readinessInterval, readinessProbeTimeout
+	readinessInterval, readinessProbeTimeout = 10*time.Millisecond, 100*time.Millisecond
+
+	defer func() { readinessInterval, readinessProbeTimeout = interval, timeout }()
+
+	exp := testExperiment(t, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer listener.Close()
+
+	// Listening and closing right away leaves a port nothing is listening on.
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	closed.Close()
+
+	var (
+		up   = listener.Addr().(*net.TCPAddr)
+		down = closed.Addr().(*net.TCPAddr)
+
+		states []map[string]string
+		mu     sync.Mutex
+	)
+
+	probes := map[string]ReadinessProbe{
+		"up":   {Type: ReadinessProbeTCP, Address: "127.0.0.1", Port: up.Port},
+		"down": {Type: ReadinessProbeTCP, Address: "127.0.0.1", Port: down.Port},
+	}
+
+	err = waitForReadiness(context.Background(), exp, probes, 200*time.Millisecond, func(s map[string]string) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		states = append(states, s)
+	})
+
+	if !errors.Is(err, ErrVMNotReady) {
+		t.Fatalf("expected ErrVMNotReady, got %v", err)
+	}
+
+	if !strings.HasSuffix(err.Error(), ": down") {
+		t.Fatalf("expected only the down VM to not be ready, got %v", err)
+	}
+
+	if len(states) != 2 {
+		t.Fatalf("expected initial and ready progress, got %v", states)
+	}
+
+	if states[0]["up"] != ReadinessBooting || states[0]["down"] != ReadinessBooting {
+		t.Fatalf("expected VMs to start out booting, got %v", states[0])
+	}
+
+	if states[1]["up"] != ReadinessReady || states[1]["down"] != ReadinessBooting {
+		t.Fatalf("expected only the up VM to be ready, got %v", states[1])
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	canceled := errors.New("canceled")
+
+	cancel(canceled)
+
+	err = waitForReadiness(ctx, exp, map[string]ReadinessProbe{"down": probes["down"]}, time.Minute, func(map[string]string) {})
+	if !errors.Is(err, canceled) {
+		t.Fatalf("expected canceled wait to return its cause, got %v", err)
+	}
+}
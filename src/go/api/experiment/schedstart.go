@@ -0,0 +1,340 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"phenix/store"
+	"phenix/util/cron"
+
+	"github.com/activeshadow/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+// Number of times a scheduled start is attempted for each time it's due, and
+// how long to wait between attempts.
+const (
+	MaxScheduledStartAttempts = 3
+	ScheduledStartRetryDelay  = time.Minute
+)
+
+var (
+	ErrScheduledStartNotFound = errors.New("scheduled start not found")
+	ErrScheduledStartExists   = errors.New("scheduled start already exists")
+	ErrInvalidScheduledStart  = errors.New("invalid scheduled start")
+)
+
+// ScheduledStart is a window in which an experiment should be started
+// automatically. Times are RFC 3339 strings. Without a cron expression, the
+// experiment is started once, as soon as possible after Earliest and no later
+// than Latest (if set). With one, the experiment is started each time the cron
+// expression occurs, bounded by Earliest and Latest (if set).
+type ScheduledStart struct {
+	Experiment string `json:"experiment" structs:"experiment" mapstructure:"experiment"`
+	Earliest   string `json:"earliest,omitempty" structs:"earliest" mapstructure:"earliest"`
+	Latest     string `json:"latest,omitempty" structs:"latest" mapstructure:"latest"`
+	Cron       string `json:"cron,omitempty" structs:"cron" mapstructure:"cron"`
+
+	// Only updated by the scheduler.
+	Status ScheduledStartStatus `json:"status" structs:"-" mapstructure:"-"`
+}
+
+// ScheduledStartStatus tracks a scheduled start's progress. Times are RFC 3339
+// strings.
+type ScheduledStartStatus struct {
+	// When the schedule was created or last updated. Occurrences before then are
+	// ignored.
+	Since string `json:"since" structs:"since" mapstructure:"since"`
+
+	// When the last occurrence was handled, whether the experiment was started
+	// or not.
+	LastRun string `json:"lastRun,omitempty" structs:"lastRun" mapstructure:"lastRun"`
+
+	// Failed attempts to start the experiment for the current occurrence.
+	Attempts    int    `json:"attempts,omitempty" structs:"attempts" mapstructure:"attempts"`
+	LastAttempt string `json:"lastAttempt,omitempty" structs:"lastAttempt" mapstructure:"lastAttempt"`
+	LastError   string `json:"lastError,omitempty" structs:"lastError" mapstructure:"lastError"`
+}
+
+// Validate returns an error wrapping ErrInvalidScheduledStart if the scheduled
+// start's window is invalid.
+func (this ScheduledStart) Validate() error {
+	if this.Experiment == "" {
+		return fmt.Errorf("%w: missing experiment", ErrInvalidScheduledStart)
+	}
+
+	if this.Earliest == "" && this.Cron == "" {
+		return fmt.Errorf("%w: earliest start time or cron expression required", ErrInvalidScheduledStart)
+	}
+
+	earliest, err := parseScheduleTime("earliest start time", this.Earliest)
+	if err != nil {
+		return err
+	}
+
+	latest, err := parseScheduleTime("latest start time", this.Latest)
+	if err != nil {
+		return err
+	}
+
+	if !earliest.IsZero() && !latest.IsZero() && !latest.After(earliest) {
+		return fmt.Errorf("%w: latest start time must be after earliest start time", ErrInvalidScheduledStart)
+	}
+
+	if this.Cron != "" {
+		if _, err := cron.Parse(this.Cron); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidScheduledStart, err)
+		}
+	}
+
+	return nil
+}
+
+// Next returns the next time the experiment should be started, ignoring any
+// failed attempts for it. It returns the zero time if there are no occurrences
+// left.
+func (this ScheduledStart) Next() time.Time {
+	// Already validated when the scheduled start was saved.
+	var (
+		earliest, _ = parseScheduleTime("", this.Earliest)
+		latest, _   = parseScheduleTime("", this.Latest)
+		since, _    = parseScheduleTime("", this.Status.Since)
+		last, _     = parseScheduleTime("", this.Status.LastRun)
+	)
+
+	if this.Cron == "" {
+		if !last.IsZero() {
+			return time.Time{}
+		}
+
+		return earliest
+	}
+
+	sched, err := cron.Parse(this.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+
+	base := since
+
+	if last.After(base) {
+		base = last
+	}
+
+	// Allow the earliest start time itself to be an occurrence.
+	if e := earliest.Add(-time.Second); e.After(base) {
+		base = e
+	}
+
+	next := sched.Next(base)
+
+	if !latest.IsZero() && next.After(latest) {
+		return time.Time{}
+	}
+
+	return next
+}
+
+// Due returns the occurrence the experiment should be started for at the given
+// time, and whether it should be started now. The occurrence is returned even
+// if it's not due yet so the window can be checked. If the previous attempt for
+// the occurrence failed, it's not due again until the retry delay has passed.
+func (this ScheduledStart) Due(now time.Time) (time.Time, bool) {
+	next := this.Next()
+
+	if next.IsZero() || now.Before(next) {
+		return next, false
+	}
+
+	if last, _ := parseScheduleTime("", this.Status.LastAttempt); !last.IsZero() && this.Status.Attempts > 0 {
+		if now.Before(last.Add(ScheduledStartRetryDelay)) {
+			return next, false
+		}
+	}
+
+	return next, true
+}
+
+// Expired returns true if the latest start time has passed as of the given
+// time.
+func (this ScheduledStart) Expired(now time.Time) bool {
+	latest, _ := parseScheduleTime("", this.Latest)
+	return !latest.IsZero() && now.After(latest)
+}
+
+// ListScheduledStarts returns all the saved scheduled starts.
+func ListScheduledStarts() ([]ScheduledStart, error) {
+	configs, err := store.List("ScheduledStart")
+	if err != nil {
+		return nil, fmt.Errorf("getting scheduled starts from store: %w", err)
+	}
+
+	starts := make([]ScheduledStart, len(configs))
+
+	for i, c := range configs {
+		if err := decodeScheduledStart(c, &starts[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return starts, nil
+}
+
+// GetScheduledStart returns the saved scheduled start for the experiment with
+// the given name.
+func GetScheduledStart(name string) (*ScheduledStart, error) {
+	c := scheduledStartConfig(name)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrScheduledStartNotFound, name)
+		}
+
+		return nil, fmt.Errorf("getting scheduled start %s from store: %w", name, err)
+	}
+
+	var start ScheduledStart
+
+	if err := decodeScheduledStart(*c, &start); err != nil {
+		return nil, err
+	}
+
+	return &start, nil
+}
+
+// CreateScheduledStart validates and saves the given scheduled start, returning
+// an error wrapping ErrScheduledStartExists if the experiment already has one.
+// The experiment must exist.
+func CreateScheduledStart(start ScheduledStart) error {
+	if err := start.Validate(); err != nil {
+		return err
+	}
+
+	exp, _ := store.NewConfig("experiment/" + start.Experiment)
+
+	if err := store.Get(exp); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: experiment %s does not exist", ErrInvalidScheduledStart, start.Experiment)
+		}
+
+		return fmt.Errorf("getting experiment %s from store: %w", start.Experiment, err)
+	}
+
+	start.Status = ScheduledStartStatus{Since: time.Now().Format(time.RFC3339)}
+
+	c := scheduledStartConfig(start.Experiment)
+	encodeScheduledStart(start, c)
+
+	if err := store.Create(c); err != nil {
+		if errors.Is(err, store.ErrExist) {
+			return fmt.Errorf("%w: %s", ErrScheduledStartExists, start.Experiment)
+		}
+
+		return fmt.Errorf("saving scheduled start %s: %w", start.Experiment, err)
+	}
+
+	return nil
+}
+
+// UpdateScheduledStart validates and saves the given scheduled start, returning
+// an error wrapping ErrScheduledStartNotFound if it doesn't already exist. Its
+// status is reset, so the new window starts fresh.
+func UpdateScheduledStart(start ScheduledStart) error {
+	if err := start.Validate(); err != nil {
+		return err
+	}
+
+	c := scheduledStartConfig(start.Experiment)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrScheduledStartNotFound, start.Experiment)
+		}
+
+		return fmt.Errorf("getting scheduled start %s from store: %w", start.Experiment, err)
+	}
+
+	start.Status = ScheduledStartStatus{Since: time.Now().Format(time.RFC3339)}
+	encodeScheduledStart(start, c)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("saving scheduled start %s: %w", start.Experiment, err)
+	}
+
+	return nil
+}
+
+// SetScheduledStartStatus saves the given status for the scheduled start for the
+// experiment with the given name.
+func SetScheduledStartStatus(name string, status ScheduledStartStatus) error {
+	c := scheduledStartConfig(name)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrScheduledStartNotFound, name)
+		}
+
+		return fmt.Errorf("getting scheduled start %s from store: %w", name, err)
+	}
+
+	c.Status = structs.MapDefaultCase(status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("saving scheduled start %s status: %w", name, err)
+	}
+
+	return nil
+}
+
+// DeleteScheduledStart deletes the saved scheduled start for the experiment with
+// the given name.
+func DeleteScheduledStart(name string) error {
+	if err := store.Delete(scheduledStartConfig(name)); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrScheduledStartNotFound, name)
+		}
+
+		return fmt.Errorf("deleting scheduled start %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func scheduledStartConfig(name string) *store.Config {
+	return &store.Config{
+		Version:  store.API_GROUP + "/v1",
+		Kind:     "ScheduledStart",
+		Metadata: store.ConfigMetadata{Name: name},
+	}
+}
+
+func encodeScheduledStart(start ScheduledStart, c *store.Config) {
+	c.Spec = structs.MapDefaultCase(start, structs.CASESNAKE)
+	c.Status = structs.MapDefaultCase(start.Status, structs.CASESNAKE)
+}
+
+func decodeScheduledStart(c store.Config, start *ScheduledStart) error {
+	if err := mapstructure.Decode(c.Spec, start); err != nil {
+		return fmt.Errorf("decoding scheduled start %s: %w", c.Metadata.Name, err)
+	}
+
+	if err := mapstructure.Decode(c.Status, &start.Status); err != nil {
+		return fmt.Errorf("decoding scheduled start %s status: %w", c.Metadata.Name, err)
+	}
+
+	return nil
+}
+
+func parseScheduleTime(field, v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid %s %s", ErrInvalidScheduledStart, field, v)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,82 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestScheduledStartValidate(t *testing.T) {
+	invalid := map[string]ScheduledStart{
+		"missing experiment": {Earliest: "2024-01-10T08:00:00Z"},
+		"missing window":     {Experiment: "foo"},
+		"bad earliest":       {Experiment: "foo", Earliest: "tomorrow"},
+		"latest too soon":    {Experiment: "foo", Earliest: "2024-01-10T08:00:00Z", Latest: "2024-01-10T07:00:00Z"},
+		"bad cron":           {Experiment: "foo", Cron: "every day"},
+	}
+
+	for desc, start := range invalid {
+		if err := start.Validate(); !errors.Is(err, ErrInvalidScheduledStart) {
+			t.Errorf("%s: expected invalid scheduled start error, got %v", desc, err)
+		}
+	}
+}
+
+func TestScheduledStartDue(t *testing.T) {
+	start := ScheduledStart{
+		Experiment: "foo",
+		Earliest:   "2024-01-10T08:00:00Z",
+		Latest:     "2024-01-10T09:00:00Z",
+		Status:     ScheduledStartStatus{Since: "2024-01-09T00:00:00Z"},
+	}
+
+	if _, due := start.Due(time.Date(2024, time.January, 10, 7, 59, 0, 0, time.UTC)); due {
+		t.Errorf("expected one-time start not to be due before its earliest time")
+	}
+
+	now := time.Date(2024, time.January, 10, 8, 1, 0, 0, time.UTC)
+
+	if _, due := start.Due(now); !due {
+		t.Errorf("expected one-time start to be due after its earliest time")
+	}
+
+	// Failed attempts aren't retried until the retry delay has passed.
+	start.Status.Attempts = 1
+	start.Status.LastAttempt = now.Format(time.RFC3339)
+
+	if _, due := start.Due(now.Add(ScheduledStartRetryDelay / 2)); due {
+		t.Errorf("expected failed start not to be retried before the retry delay")
+	}
+
+	if _, due := start.Due(now.Add(ScheduledStartRetryDelay)); !due {
+		t.Errorf("expected failed start to be retried after the retry delay")
+	}
+
+	if !start.Expired(time.Date(2024, time.January, 10, 9, 1, 0, 0, time.UTC)) {
+		t.Errorf("expected start to be expired after its latest time")
+	}
+
+	start.Status.LastRun = now.Format(time.RFC3339)
+
+	if next := start.Next(); !next.IsZero() {
+		t.Errorf("expected no more occurrences for one-time start after it ran, got %v", next)
+	}
+
+	cron := ScheduledStart{
+		Experiment: "foo",
+		Cron:       "0 6 * * *",
+		Latest:     "2024-01-12T00:00:00Z",
+		Status:     ScheduledStartStatus{Since: "2024-01-10T08:00:00Z", LastRun: "2024-01-11T06:00:00Z"},
+	}
+
+	// The next occurrence is after the latest start time.
+	if next := cron.Next(); !next.IsZero() {
+		t.Errorf("expected no more occurrences for cron start past its latest time, got %v", next)
+	}
+
+	cron.Latest = "2024-01-12T07:00:00Z"
+
+	if next := cron.Next(); !next.Equal(time.Date(2024, time.January, 12, 6, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected cron start to next occur on 2024-01-12 at 06:00, got %v", next)
+	}
+}
@@ -0,0 +1,116 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/notes"
+	"phenix/util/plog"
+)
+
+// How often the states of VMs being shut down are checked.
+const shutdownPollInterval = 1 * time.Second
+
+// Shutdown statuses reported for each VM when gracefully stopping an
+// experiment.
+const (
+	ShutdownStatusShuttingDown = "shuttingDown"
+	ShutdownStatusStopped      = "stopped"
+	ShutdownStatusKilled       = "killed"
+)
+
+// ShutdownProgress is the status of a VM being shut down when gracefully
+// stopping an experiment. A status of `killed` means the VM didn't shut down
+// before the timeout (or couldn't be asked to) and will be killed instead.
+type ShutdownProgress struct {
+	VM     string
+	Status string
+}
+
+// shutdownVMs asks each of the experiment's running VMs to power off, in
+// reverse boot order unless stopping in parallel, and waits up to the graceful
+// timeout for them to shut down. The timeout covers all the VMs, not each boot
+// group. VMs still running afterwards, or once the stop's context is done, are
+// reported as killed and left for the caller to kill.
+func shutdownVMs(exp *types.Experiment, o stopOptions) {
+	ns := exp.Spec.ExperimentName()
+
+	var names []string
+
+	for _, vm := range mm.GetVMInfo(mm.NS(ns)) {
+		if vm.Running {
+			names = append(names, vm.Name)
+		}
+	}
+
+	groups := []BootGroup{{Index: 1, Total: 1, VMs: names}}
+
+	if !o.parallel {
+		if g := bootGroups(exp, names); g != nil {
+			groups = teardownGroups(g)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(o.ctx, o.graceful)
+	defer cancel()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	var killed []string
+
+	for _, group := range groups {
+		if len(groups) > 1 {
+			notes.AddInfo(o.ctx, false, fmt.Sprintf("shutting down teardown group %d of %d (%s)", group.Index, group.Total, strings.Join(group.VMs, ", ")))
+		}
+
+		pending := make(map[string]struct{})
+
+		for _, name := range group.VMs {
+			if err := mm.ShutdownVM(mm.NS(ns), mm.VMName(name)); err != nil {
+				plog.Warn("unable to shut down VM gracefully", "exp", ns, "vm", name, "err", err)
+
+				killed = append(killed, name)
+				o.shutdownProgress(ShutdownProgress{VM: name, Status: ShutdownStatusKilled})
+
+				continue
+			}
+
+			pending[name] = struct{}{}
+			o.shutdownProgress(ShutdownProgress{VM: name, Status: ShutdownStatusShuttingDown})
+		}
+
+	wait:
+		for len(pending) > 0 {
+			select {
+			case <-ctx.Done():
+				break wait
+			case <-ticker.C:
+			}
+
+			states := mm.GetVMStates(mm.NS(ns))
+
+			for name := range pending {
+				if state, ok := states[name]; !ok || state == "QUIT" {
+					delete(pending, name)
+					o.shutdownProgress(ShutdownProgress{VM: name, Status: ShutdownStatusStopped})
+				}
+			}
+		}
+
+		for _, name := range group.VMs {
+			if _, ok := pending[name]; ok {
+				killed = append(killed, name)
+				o.shutdownProgress(ShutdownProgress{VM: name, Status: ShutdownStatusKilled})
+			}
+		}
+	}
+
+	if len(killed) > 0 {
+		plog.Warn("VMs did not shut down gracefully before timeout", "exp", ns, "timeout", o.graceful, "vms", killed)
+	}
+}
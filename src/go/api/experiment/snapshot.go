@@ -0,0 +1,72 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"phenix/util/common"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+var ErrInvalidSnapshotLabel = errors.New("invalid snapshot label")
+
+// Snapshot labels end up in snapshot file names and in the QMP commands used to
+// take the snapshots, so they're limited to characters safe in both.
+var snapshotLabelRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidSnapshotLabel returns true if the given label can be used to label the
+// disk snapshots taken when stopping an experiment.
+func ValidSnapshotLabel(label string) bool {
+	return snapshotLabelRegex.MatchString(label) && label != "." && label != ".."
+}
+
+// SnapshotProgress describes the result of snapshotting a single VM's disk
+// when stopping an experiment with a snapshot.
+type SnapshotProgress struct {
+	VM    string
+	Path  string // empty if the snapshot failed
+	Error error
+
+	// Number of VMs processed so far (including this one) and in total.
+	Done  int
+	Total int
+}
+
+// snapshotVMs snapshots the disk of each running VM in the given namespace
+// using the label in the stop options, returning the snapshot file path for
+// each VM successfully snapshotted. Failures are reported via the snapshot
+// progress function and logged, but otherwise don't stop the remaining VMs from
+// being snapshotted.
+func snapshotVMs(ns string, o stopOptions) map[string]string {
+	var (
+		vms       = mm.GetVMInfo(mm.NS(ns))
+		snapshots = make(map[string]string)
+	)
+
+	for i, vm := range vms {
+		progress := SnapshotProgress{VM: vm.Name, Done: i + 1, Total: len(vms)}
+
+		if !vm.Running {
+			progress.Error = fmt.Errorf("VM %s is not running", vm.Name)
+		} else {
+			path := fmt.Sprintf("%s/images/%s_%s__%s.qc2", common.PhenixBase, ns, vm.Name, o.snapshot)
+
+			if err := mm.SnapshotVMDisk(mm.NS(ns), mm.VMName(vm.Name), mm.SnapshotFile(path)); err != nil {
+				progress.Error = err
+			} else {
+				progress.Path = path
+				snapshots[vm.Name] = path
+			}
+		}
+
+		if progress.Error != nil {
+			plog.Warn("unable to snapshot VM before stopping experiment", "exp", ns, "vm", vm.Name, "err", progress.Error)
+		}
+
+		o.snapshotProgress(progress)
+	}
+
+	return snapshots
+}
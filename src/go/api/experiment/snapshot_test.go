@@ -0,0 +1,29 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidSnapshotLabel(t *testing.T) {
+	cases := map[string]bool{
+		"before-upgrade": true,
+		"run_2.1":        true,
+		"":               false,
+		"..":             false,
+		"../../x":        false,
+		"it's":           false,
+		`a"b`:            false,
+		"a b":            false,
+	}
+
+	for label, expected := range cases {
+		if valid := ValidSnapshotLabel(label); valid != expected {
+			t.Errorf("%q: expected valid to be %t, got %t", label, expected, valid)
+		}
+	}
+
+	if o := newStopOptions(StopWithSnapshot("../../x")); !errors.Is(o.snapshotErr, ErrInvalidSnapshotLabel) || o.snapshot != "" {
+		t.Errorf("expected invalid snapshot label to be rejected, got %v", o.snapshotErr)
+	}
+}
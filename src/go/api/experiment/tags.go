@@ -0,0 +1,128 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"phenix/types"
+)
+
+var ErrInvalidTagSelector = errors.New("invalid tag selector")
+
+// ListByTag returns the experiments with tags matching the given selector. The
+// selector is a comma-separated list of `key=value` terms, or `key` terms to
+// match any value, all of which must match (e.g. `project=foo,owner`).
+func ListByTag(selector string) ([]types.Experiment, error) {
+	terms, err := parseTagSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	experiments, err := List()
+
+	var matched []types.Experiment
+
+	for _, exp := range experiments {
+		if tagsMatch(exp.Spec.Tags(), terms) {
+			matched = append(matched, exp)
+		}
+	}
+
+	return matched, err
+}
+
+// UpdateTags merges the given tags into the tags of the experiment with the
+// given name, removing any tags given with an empty value. It returns the
+// experiment's updated tags.
+func UpdateTags(name string, tags map[string]string) (map[string]string, error) {
+	defer InvalidateCached(name)
+
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	updated := make(map[string]string)
+
+	for k, v := range exp.Spec.Tags() {
+		updated[k] = v
+	}
+
+	for k, v := range tags {
+		if k == "" || strings.ContainsAny(k, "=,") {
+			return nil, fmt.Errorf("invalid tag key %q", k)
+		}
+
+		if v == "" {
+			delete(updated, k)
+			continue
+		}
+
+		updated[k] = v
+	}
+
+	exp.Spec.SetTags(updated)
+
+	if err := exp.WriteToStore(false); err != nil {
+		return nil, fmt.Errorf("saving tags for experiment %s: %w", name, err)
+	}
+
+	return updated, nil
+}
+
+func parseTagSelector(selector string) (map[string]*string, error) {
+	terms := make(map[string]*string)
+
+	for _, term := range strings.Split(selector, ",") {
+		term = strings.TrimSpace(term)
+
+		if term == "" {
+			return nil, fmt.Errorf("%w: empty term in %q", ErrInvalidTagSelector, selector)
+		}
+
+		k, v, ok := strings.Cut(term, "=")
+
+		if k == "" {
+			return nil, fmt.Errorf("%w: missing key in %q", ErrInvalidTagSelector, term)
+		}
+
+		if ok {
+			terms[k] = &v
+		} else {
+			terms[k] = nil
+		}
+	}
+
+	return terms, nil
+}
+
+func tagsMatch(tags map[string]string, terms map[string]*string) bool {
+	for k, v := range terms {
+		tag, ok := tags[k]
+		if !ok {
+			return false
+		}
+
+		if v != nil && tag != *v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// TagSelector is a parsed tag selector, as accepted by ListByTag, that can be
+// used to match tags or labels on things other than experiments.
+type TagSelector map[string]*string
+
+// ParseTagSelector parses the given selector, returning an error wrapping
+// ErrInvalidTagSelector if it's malformed.
+func ParseTagSelector(selector string) (TagSelector, error) {
+	return parseTagSelector(selector)
+}
+
+// Matches returns true if all the selector's terms match the given tags.
+func (this TagSelector) Matches(tags map[string]string) bool {
+	return tagsMatch(tags, this)
+}
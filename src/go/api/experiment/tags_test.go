@@ -0,0 +1,35 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTagSelector(t *testing.T) {
+	tags := map[string]string{"project": "foo", "owner": "alice"}
+
+	cases := map[string]bool{
+		"project=foo":       true,
+		"project=bar":       false,
+		"owner":             true,
+		"project=foo,owner": true,
+		"project=foo,team":  false,
+	}
+
+	for selector, expected := range cases {
+		terms, err := parseTagSelector(selector)
+		if err != nil {
+			t.Fatalf("parsing selector %q: %v", selector, err)
+		}
+
+		if matched := tagsMatch(tags, terms); matched != expected {
+			t.Errorf("selector %q: expected match %t, got %t", selector, expected, matched)
+		}
+	}
+
+	for _, selector := range []string{"", "=foo", "project=foo,"} {
+		if _, err := parseTagSelector(selector); !errors.Is(err, ErrInvalidTagSelector) {
+			t.Errorf("selector %q: expected invalid selector error, got %v", selector, err)
+		}
+	}
+}
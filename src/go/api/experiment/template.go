@@ -0,0 +1,339 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	"phenix/types/version"
+	"phenix/util/common"
+
+	"github.com/activeshadow/structs"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	ErrTemplateNotFound      = errors.New("experiment template not found")
+	ErrTemplateExists        = errors.New("experiment template already exists")
+	ErrInvalidTemplate       = errors.New("invalid experiment template")
+	ErrInvalidTemplateParams = errors.New("invalid experiment template parameters")
+)
+
+// Template parameter names have to be usable as `{{ .name }}` in the spec.
+var templateParamName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Template is a named, saved experiment spec containing `{{ .param }}` tokens
+// that are substituted with parameter values to create concrete experiments.
+// The spec is kept as YAML (or JSON) text since it usually won't pass schema
+// validation until its parameters have been substituted.
+type Template struct {
+	Name        string          `json:"name" structs:"name" mapstructure:"name"`
+	Description string          `json:"description,omitempty" structs:"description" mapstructure:"description"`
+	Params      []TemplateParam `json:"params" structs:"params" mapstructure:"params"`
+	Spec        string          `json:"spec" structs:"spec" mapstructure:"spec"`
+}
+
+// TemplateParam is a parameter that can be substituted into a template's spec.
+// Parameters that aren't required and aren't given when instantiating the
+// template use their default value.
+type TemplateParam struct {
+	Name        string `json:"name" structs:"name" mapstructure:"name"`
+	Description string `json:"description,omitempty" structs:"description" mapstructure:"description"`
+	Default     string `json:"default,omitempty" structs:"default" mapstructure:"default"`
+	Required    bool   `json:"required,omitempty" structs:"required" mapstructure:"required"`
+}
+
+// Validate returns an error wrapping ErrInvalidTemplate if the template is
+// missing a name or spec, has invalid or duplicate parameters, or its spec
+// can't be parsed as a template.
+func (this Template) Validate() error {
+	if this.Name == "" {
+		return fmt.Errorf("%w: missing name", ErrInvalidTemplate)
+	}
+
+	if strings.TrimSpace(this.Spec) == "" {
+		return fmt.Errorf("%w: missing spec", ErrInvalidTemplate)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, param := range this.Params {
+		if !templateParamName.MatchString(param.Name) {
+			return fmt.Errorf("%w: invalid parameter name %q", ErrInvalidTemplate, param.Name)
+		}
+
+		if seen[param.Name] {
+			return fmt.Errorf("%w: duplicate parameter %s", ErrInvalidTemplate, param.Name)
+		}
+
+		seen[param.Name] = true
+	}
+
+	if _, err := this.parse(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Render returns the template's spec with the given parameters substituted,
+// after applying defaults for those not given. It returns an error wrapping
+// ErrInvalidTemplateParams if any required parameters are missing or any
+// unknown ones are given.
+func (this Template) Render(params map[string]string) (string, error) {
+	values := make(map[string]string)
+
+	var missing []string
+
+	for _, param := range this.Params {
+		v, ok := params[param.Name]
+		if !ok {
+			if param.Required {
+				missing = append(missing, param.Name)
+				continue
+			}
+
+			v = param.Default
+		}
+
+		values[param.Name] = v
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: missing required parameters %s", ErrInvalidTemplateParams, strings.Join(missing, ", "))
+	}
+
+	var unknown []string
+
+	for name := range params {
+		if _, ok := values[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return "", fmt.Errorf("%w: unknown parameters %s", ErrInvalidTemplateParams, strings.Join(unknown, ", "))
+	}
+
+	tmpl, err := this.parse()
+	if err != nil {
+		return "", err
+	}
+
+	var spec strings.Builder
+
+	if err := tmpl.Execute(&spec, values); err != nil {
+		// Most likely the spec references a parameter the template doesn't define.
+		return "", fmt.Errorf("%w: rendering spec: %v", ErrInvalidTemplate, err)
+	}
+
+	return spec.String(), nil
+}
+
+func (this Template) parse() (*template.Template, error) {
+	tmpl, err := template.New(this.Name).Option("missingkey=error").Parse(this.Spec)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing spec: %v", ErrInvalidTemplate, err)
+	}
+
+	return tmpl, nil
+}
+
+// Instantiate creates a new, stopped experiment with the given name from the
+// saved template with the given template name, substituting the given
+// parameters into its spec. The resulting config goes through the same schema
+// validation and create hooks as any other experiment. If an owner is given,
+// the new experiment is restricted to them. It returns the config of the new
+// experiment.
+func Instantiate(templateName string, params map[string]string, newName, owner string) (*store.Config, error) {
+	defer InvalidateCached(newName)
+
+	if newName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	if strings.ToLower(newName) == "all" {
+		return nil, fmt.Errorf("cannot use 'all' for experiment name")
+	}
+
+	if existing, _ := store.NewConfig("experiment/" + newName); store.Get(existing) == nil {
+		return nil, fmt.Errorf("instantiating template %s as %s: %w", templateName, newName, ErrExperimentExists)
+	}
+
+	tmpl, err := GetTemplate(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := tmpl.Render(params)
+	if err != nil {
+		return nil, fmt.Errorf("rendering template %s: %w", templateName, err)
+	}
+
+	spec := make(map[string]any)
+
+	if err := yaml.Unmarshal([]byte(rendered), &spec); err != nil {
+		return nil, fmt.Errorf("%w: parsing rendered spec for template %s: %v", types.ErrValidationFailed, templateName, err)
+	}
+
+	// The experiment name always comes from the new experiment, not the template.
+	spec["experimentName"] = newName
+
+	if dir, _ := spec["baseDir"].(string); dir == "" {
+		spec["baseDir"] = common.PhenixBase + "/experiments/" + newName
+	}
+
+	c := &store.Config{
+		Version: store.API_GROUP + "/" + version.StoredVersion["Experiment"],
+		Kind:    "Experiment",
+		Metadata: store.ConfigMetadata{
+			Name:        newName,
+			Annotations: map[string]string{"template": templateName},
+		},
+		Spec: spec,
+	}
+
+	if owner != "" {
+		c.Metadata.Annotations[accessAnnotationPrefix+owner] = string(PermissionOwner)
+	}
+
+	// Validate the rendered spec as given before it's decoded, since decoding
+	// drops anything the schema would reject.
+	if err := types.ValidateConfigSpec(*c); err != nil {
+		return nil, fmt.Errorf("validating experiment from template %s: %w", templateName, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding experiment from template %s: %v", types.ErrValidationFailed, templateName, err)
+	}
+
+	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
+
+	created, err := config.Create(config.CreateFromConfig(c), config.CreateWithValidation())
+	if err != nil {
+		return nil, fmt.Errorf("creating experiment config: %w", err)
+	}
+
+	for _, hook := range hooks["create"] {
+		hook("create", newName)
+	}
+
+	return created, nil
+}
+
+// ListTemplates returns all the saved experiment templates.
+func ListTemplates() ([]Template, error) {
+	configs, err := store.List("ExperimentTemplate")
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment templates from store: %w", err)
+	}
+
+	templates := make([]Template, len(configs))
+
+	for i, c := range configs {
+		if err := mapstructure.Decode(c.Spec, &templates[i]); err != nil {
+			return nil, fmt.Errorf("decoding experiment template %s: %w", c.Metadata.Name, err)
+		}
+	}
+
+	return templates, nil
+}
+
+// GetTemplate returns the saved experiment template with the given name.
+func GetTemplate(name string) (*Template, error) {
+	c := templateConfig(name)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+		}
+
+		return nil, fmt.Errorf("getting experiment template %s from store: %w", name, err)
+	}
+
+	var tmpl Template
+
+	if err := mapstructure.Decode(c.Spec, &tmpl); err != nil {
+		return nil, fmt.Errorf("decoding experiment template %s: %w", name, err)
+	}
+
+	return &tmpl, nil
+}
+
+// CreateTemplate validates and saves the given experiment template, returning
+// an error wrapping ErrTemplateExists if one with the same name already exists.
+func CreateTemplate(tmpl Template) error {
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+
+	c := templateConfig(tmpl.Name)
+	c.Spec = structs.MapDefaultCase(tmpl, structs.CASESNAKE)
+
+	if err := store.Create(c); err != nil {
+		if errors.Is(err, store.ErrExist) {
+			return fmt.Errorf("%w: %s", ErrTemplateExists, tmpl.Name)
+		}
+
+		return fmt.Errorf("saving experiment template %s: %w", tmpl.Name, err)
+	}
+
+	return nil
+}
+
+// UpdateTemplate validates and saves the given experiment template, returning
+// an error wrapping ErrTemplateNotFound if it doesn't already exist.
+// Experiments already instantiated from the template are left as they are.
+func UpdateTemplate(tmpl Template) error {
+	if err := tmpl.Validate(); err != nil {
+		return err
+	}
+
+	c := templateConfig(tmpl.Name)
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrTemplateNotFound, tmpl.Name)
+		}
+
+		return fmt.Errorf("getting experiment template %s from store: %w", tmpl.Name, err)
+	}
+
+	c.Spec = structs.MapDefaultCase(tmpl, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("saving experiment template %s: %w", tmpl.Name, err)
+	}
+
+	return nil
+}
+
+// DeleteTemplate deletes the saved experiment template with the given name.
+func DeleteTemplate(name string) error {
+	if err := store.Delete(templateConfig(name)); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+		}
+
+		return fmt.Errorf("deleting experiment template %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func templateConfig(name string) *store.Config {
+	return &store.Config{
+		Version:  store.API_GROUP + "/v1",
+		Kind:     "ExperimentTemplate",
+		Metadata: store.ConfigMetadata{Name: name},
+	}
+}
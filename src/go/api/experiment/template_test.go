@@ -0,0 +1,61 @@
+package experiment
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTemplateRender(t *testing.T) {
+	tmpl := Template{
+		Name: "lab",
+		Params: []TemplateParam{
+			{Name: "subnet", Required: true},
+			{Name: "memory", Default: "512"},
+		},
+		Spec: "topology:\n  nodes:\n  - network:\n      address: {{ .subnet }}.10\n    hardware:\n      memory: {{ .memory }}\n",
+	}
+
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("expected valid template, got %v", err)
+	}
+
+	spec, err := tmpl.Render(map[string]string{"subnet": "10.0.1"})
+	if err != nil {
+		t.Fatalf("rendering template: %v", err)
+	}
+
+	expected := "topology:\n  nodes:\n  - network:\n      address: 10.0.1.10\n    hardware:\n      memory: 512\n"
+
+	if spec != expected {
+		t.Errorf("expected rendered spec %q, got %q", expected, spec)
+	}
+
+	if _, err := tmpl.Render(nil); !errors.Is(err, ErrInvalidTemplateParams) {
+		t.Errorf("expected missing parameter error, got %v", err)
+	}
+
+	if _, err := tmpl.Render(map[string]string{"subnet": "10.0.1", "bogus": "1"}); !errors.Is(err, ErrInvalidTemplateParams) {
+		t.Errorf("expected unknown parameter error, got %v", err)
+	}
+
+	// The spec references a parameter the template doesn't define.
+	tmpl.Spec += "vlans:\n  min: {{ .vlan }}\n"
+
+	if _, err := tmpl.Render(map[string]string{"subnet": "10.0.1"}); !errors.Is(err, ErrInvalidTemplate) {
+		t.Errorf("expected undefined parameter error, got %v", err)
+	}
+
+	invalid := map[string]Template{
+		"missing name":    {Spec: "foo: bar"},
+		"missing spec":    {Name: "lab"},
+		"bad param name":  {Name: "lab", Spec: "foo: bar", Params: []TemplateParam{{Name: "my-param"}}},
+		"duplicate param": {Name: "lab", Spec: "foo: bar", Params: []TemplateParam{{Name: "a"}, {Name: "a"}}},
+		"unparsable spec": {Name: "lab", Spec: "foo: {{ .bar"},
+	}
+
+	for desc, tmpl := range invalid {
+		if err := tmpl.Validate(); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("%s: expected invalid template error, got %v", desc, err)
+		}
+	}
+}
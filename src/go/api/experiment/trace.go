@@ -0,0 +1,293 @@
+package experiment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"phenix/util/common"
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+
+	"github.com/activeshadow/libminimega/minicli"
+)
+
+var ErrNoTrace = errors.New("no start trace")
+
+// MaxTraceSize is the approximate maximum size (in bytes) of the minimega
+// commands and responses recorded in a start trace. Commands run once a trace
+// is full are counted but not recorded. State transitions are always recorded.
+const MaxTraceSize = 8 << 20
+
+// MaxTraces is the number of start traces kept for each experiment. The oldest
+// traces are removed as new ones are written.
+const MaxTraces = 5
+
+// StartTrace is a record of the minimega commands run and the state
+// transitions made while starting an experiment, for debugging failed starts.
+type StartTrace struct {
+	Experiment string       `json:"experiment"`
+	Started    time.Time    `json:"started"`
+	Finished   time.Time    `json:"finished"`
+	Error      string       `json:"error,omitempty"`
+	Events     []TraceEvent `json:"events"`
+
+	// Set if commands were left out of the trace once it reached MaxTraceSize,
+	// along with how many.
+	Truncated bool `json:"truncated,omitempty"`
+	Dropped   int  `json:"dropped,omitempty"`
+}
+
+// TraceEvent is a minimega command run, or a state transition made, while
+// starting an experiment.
+type TraceEvent struct {
+	Time time.Time `json:"time"`
+
+	// State transitions.
+	State  string `json:"state,omitempty"`
+	Detail string `json:"detail,omitempty"`
+
+	// Minimega commands.
+	Command    string          `json:"command,omitempty"`
+	DurationMs int64           `json:"durationMs,omitempty"`
+	Responses  []TraceResponse `json:"responses,omitempty"`
+}
+
+// TraceResponse is the response from a cluster host to a traced minimega
+// command.
+type TraceResponse struct {
+	Host     string `json:"host"`
+	Response string `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+type startTrace struct {
+	sync.Mutex
+
+	trace StartTrace
+	size  int
+
+	stop func()
+	once sync.Once
+}
+
+// newStartTrace starts recording the minimega commands run for the experiment
+// with the given name. Since minimega commands aren't tied to the start that
+// ran them, commands in the experiment's namespace or with its name as one of
+// their arguments are recorded.
+func newStartTrace(name string) *startTrace {
+	t := &startTrace{trace: StartTrace{Experiment: name, Started: time.Now().UTC(), Events: []TraceEvent{}}}
+
+	t.stop = mmcli.Observe(func(c mmcli.Command, responses []*minicli.Response, took time.Duration) {
+		if c.Namespace != name && !commandMentions(c.Command, name) {
+			return
+		}
+
+		t.command(c.String(), responses, took)
+	})
+
+	return t
+}
+
+// commandMentions returns true if the given experiment name is one of the
+// arguments of the given minimega command (or a value in one, like a filter),
+// so experiments whose names contain the name aren't matched.
+func commandMentions(cmd, name string) bool {
+	fields := strings.FieldsFunc(cmd, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`"',=`, r)
+	})
+
+	for _, field := range fields {
+		if field == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// state records a state transition. It's a no-op if the trace is nil, so
+// starts that aren't traced don't need to check.
+func (this *startTrace) state(state, format string, args ...any) {
+	if this == nil {
+		return
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.trace.Events = append(this.trace.Events, TraceEvent{
+		Time:   time.Now().UTC(),
+		State:  state,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+func (this *startTrace) command(cmd string, responses []*minicli.Response, took time.Duration) {
+	event := TraceEvent{
+		Time:       time.Now().UTC(),
+		Command:    cmd,
+		DurationMs: took.Milliseconds(),
+	}
+
+	size := len(cmd)
+
+	for _, resp := range responses {
+		event.Responses = append(event.Responses, TraceResponse{Host: resp.Host, Response: resp.Response, Error: resp.Error})
+		size += len(resp.Host) + len(resp.Response) + len(resp.Error)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	if this.size+size > MaxTraceSize {
+		this.trace.Truncated = true
+		this.trace.Dropped++
+
+		return
+	}
+
+	this.size += size
+	this.trace.Events = append(this.trace.Events, event)
+}
+
+// finish stops recording and writes the trace, rotating out the experiment's
+// oldest traces. Only the first call has any effect, and it's a no-op if the
+// trace is nil.
+func (this *startTrace) finish(err error) {
+	if this == nil {
+		return
+	}
+
+	this.once.Do(func() {
+		this.stop()
+
+		if err != nil {
+			this.state("failed", "%v", err)
+		} else {
+			this.state("finished", "")
+		}
+
+		this.Lock()
+		defer this.Unlock()
+
+		this.trace.Finished = time.Now().UTC()
+
+		if err != nil {
+			this.trace.Error = err.Error()
+		}
+
+		if err := writeTrace(this.trace); err != nil {
+			plog.Error("writing experiment start trace", "exp", this.trace.Experiment, "err", err)
+		}
+	})
+}
+
+// proxy returns a channel that records the errors sent to it in the trace
+// before forwarding them to the given channel, finishing the trace once it's
+// closed. It's used for starts that continue after Start returns.
+func (this *startTrace) proxy(errs chan error) chan error {
+	ch := make(chan error)
+
+	go func() {
+		defer this.finish(nil)
+		defer close(errs)
+
+		for err := range ch {
+			this.state("error", "%v", err)
+			errs <- err
+		}
+	}()
+
+	return ch
+}
+
+func traceDir(name string) string {
+	return filepath.Join(common.PhenixBase, "traces", name)
+}
+
+func writeTrace(trace StartTrace) error {
+	dir := traceDir(trace.Experiment)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("creating trace directory: %w", err)
+	}
+
+	body, err := json.MarshalIndent(trace, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling trace: %w", err)
+	}
+
+	// Named so they sort in the order they were started.
+	path := filepath.Join(dir, trace.Started.Format("20060102T150405.000000000Z")+".json")
+
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return fmt.Errorf("writing trace: %w", err)
+	}
+
+	traces, err := listTraces(trace.Experiment)
+	if err != nil {
+		return err
+	}
+
+	for len(traces) > MaxTraces {
+		if err := os.Remove(traces[0]); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("removing old trace: %w", err)
+		}
+
+		traces = traces[1:]
+	}
+
+	return nil
+}
+
+// listTraces returns the paths to the start traces for the experiment with the
+// given name, oldest first.
+func listTraces(name string) ([]string, error) {
+	traces, err := filepath.Glob(filepath.Join(traceDir(name), "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("listing traces: %w", err)
+	}
+
+	sort.Strings(traces)
+
+	return traces, nil
+}
+
+// LatestTrace returns the most recent start trace for the experiment with the
+// given name, as written. It returns ErrNoTrace if the experiment hasn't been
+// started with tracing enabled.
+func LatestTrace(name string) ([]byte, error) {
+	traces, err := listTraces(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(traces) == 0 {
+		return nil, fmt.Errorf("%w for experiment %s", ErrNoTrace, name)
+	}
+
+	body, err := os.ReadFile(traces[len(traces)-1])
+	if err != nil {
+		return nil, fmt.Errorf("reading trace: %w", err)
+	}
+
+	return body, nil
+}
+
+// RemoveTraces removes all the start traces for the experiment with the given
+// name.
+func RemoveTraces(name string) error {
+	if err := os.RemoveAll(traceDir(name)); err != nil {
+		return fmt.Errorf("removing traces for experiment %s: %w", name, err)
+	}
+
+	return nil
+}
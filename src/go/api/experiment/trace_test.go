@@ -0,0 +1,95 @@
+package experiment
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"phenix/util/common"
+
+	"github.com/activeshadow/libminimega/minicli"
+)
+
+func TestStartTraceRotation(t *testing.T) {
+	defer func(base string) { common.PhenixBase = base }(common.PhenixBase)
+	common.PhenixBase = t.TempDir()
+
+	if _, err := LatestTrace("test"); !errors.Is(err, ErrNoTrace) {
+		t.Fatalf("expected no trace error, got %v", err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < MaxTraces+2; i++ {
+		trace := StartTrace{Experiment: "test", Started: start.Add(time.Duration(i) * time.Minute)}
+
+		if err := writeTrace(trace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	traces, err := listTraces("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(traces) != MaxTraces {
+		t.Fatalf("expected %d traces, got %d", MaxTraces, len(traces))
+	}
+
+	body, err := LatestTrace("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var latest StartTrace
+
+	if err := json.Unmarshal(body, &latest); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := start.Add(time.Duration(MaxTraces+1) * time.Minute); !latest.Started.Equal(expected) {
+		t.Fatalf("expected latest trace started at %v, got %v", expected, latest.Started)
+	}
+}
+
+func TestStartTraceTruncated(t *testing.T) {
+	trace := &startTrace{trace: StartTrace{Experiment: "test"}}
+
+	big := []*minicli.Response{{Host: "compute1", Response: strings.Repeat("x", MaxTraceSize/2)}}
+
+	for i := 0; i < 3; i++ {
+		trace.command("vm info", big, time.Millisecond)
+	}
+
+	trace.state("launched", "")
+
+	if !trace.trace.Truncated || trace.trace.Dropped != 2 {
+		t.Fatalf("expected 2 dropped commands, got truncated=%t dropped=%d", trace.trace.Truncated, trace.trace.Dropped)
+	}
+
+	// State transitions are recorded even once the trace is full.
+	if n := len(trace.trace.Events); n != 2 {
+		t.Fatalf("expected 2 events, got %d", n)
+	}
+}
+
+func TestCommandMentions(t *testing.T) {
+	cases := map[string]bool{
+		"clear namespace test":    true,
+		"ns del test":             true,
+		"vm info name=test":       true,
+		`cc exec "ping test"`:     true,
+		"clear namespace test-2":  false,
+		"vm kill test_vm":         false,
+		"ns add-host test2 local": false,
+	}
+
+	for cmd, expected := range cases {
+		if got := commandMentions(cmd, "test"); got != expected {
+			t.Errorf("%q: expected %t, got %t", cmd, expected, got)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package experiment
+
+import (
+	"fmt"
+	"sort"
+
+	"phenix/util/mm"
+)
+
+// Usage is the current resource usage of a running experiment's VMs, along
+// with the experiment's quota. Zero values in Limit mean no limit.
+type Usage struct {
+	Total ResourceTotals `json:"total"`
+	Limit ResourceTotals `json:"limit"`
+	Hosts []HostUsage    `json:"hosts"`
+
+	// Descriptions of each quota limit exceeded, if any.
+	Exceeded []string `json:"exceeded"`
+}
+
+// HostUsage is the resource usage of a running experiment's VMs on a single
+// cluster host, along with the host's own capacity and current utilization.
+// The host's utilization includes VMs from other experiments.
+type HostUsage struct {
+	Host string `json:"host"`
+
+	ResourceTotals
+
+	CPUs      int      `json:"cpus"`
+	Load      []string `json:"load"`
+	MemTotal  int      `json:"memTotal"`
+	MemUsed   int      `json:"memUsed"`
+	MemCommit int      `json:"memCommit"`
+}
+
+// GetUsage returns the current resource usage of the VMs in the running
+// experiment with the given name, totaled across the experiment and broken
+// down by cluster host. Resources are those allocated to each VM by minimega,
+// so paused VMs are still counted.
+func GetUsage(name string) (*Usage, error) {
+	exp, err := Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("getting usage for experiment %s: %w", name, ErrExperimentNotRunning)
+	}
+
+	cluster, err := mm.GetClusterHosts(false)
+	if err != nil {
+		return nil, fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	var (
+		usage = &Usage{Hosts: []HostUsage{}, Exceeded: []string{}}
+		hosts = make(map[string]*HostUsage)
+	)
+
+	for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+		host, ok := hosts[vm.Host]
+		if !ok {
+			host = &HostUsage{Host: vm.Host}
+
+			if h := cluster.FindHostByName(vm.Host); h != nil {
+				host.CPUs = h.CPUs
+				host.Load = h.Load
+				host.MemTotal = h.MemTotal
+				host.MemUsed = h.MemUsed
+				host.MemCommit = h.MemCommit
+			}
+
+			hosts[vm.Host] = host
+		}
+
+		host.VCPUs += vm.CPUs
+		host.MemoryMB += vm.RAM
+		host.VMs++
+
+		usage.Total.VCPUs += vm.CPUs
+		usage.Total.MemoryMB += vm.RAM
+		usage.Total.VMs++
+	}
+
+	for _, host := range hosts {
+		usage.Hosts = append(usage.Hosts, *host)
+	}
+
+	sort.Slice(usage.Hosts, func(i, j int) bool { return usage.Hosts[i].Host < usage.Hosts[j].Host })
+
+	if quota := exp.Spec.Quota(); quota != nil {
+		usage.Limit = ResourceTotals{VCPUs: quota.MaxVCPUs(), MemoryMB: quota.MaxMemory(), VMs: quota.MaxVMs()}
+	}
+
+	if limit := usage.Limit.VCPUs; limit > 0 && usage.Total.VCPUs > limit {
+		usage.Exceeded = append(usage.Exceeded, fmt.Sprintf("%d vCPUs in use, over quota of %d by %d", usage.Total.VCPUs, limit, usage.Total.VCPUs-limit))
+	}
+
+	if limit := usage.Limit.MemoryMB; limit > 0 && usage.Total.MemoryMB > limit {
+		usage.Exceeded = append(usage.Exceeded, fmt.Sprintf("%d MB of memory in use, over quota of %d MB by %d MB", usage.Total.MemoryMB, limit, usage.Total.MemoryMB-limit))
+	}
+
+	if limit := usage.Limit.VMs; limit > 0 && usage.Total.VMs > limit {
+		usage.Exceeded = append(usage.Exceeded, fmt.Sprintf("%d VMs in use, over quota of %d by %d", usage.Total.VMs, limit, usage.Total.VMs-limit))
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,233 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"phenix/scheduler"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/mm"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// DefaultDryRunScheduler is the scheduling algorithm used to place VMs that
+// have not been explicitly scheduled when validating an experiment start, if
+// one wasn't provided via StartWithScheduler.
+const DefaultDryRunScheduler = "round-robin"
+
+// ValidateStart runs the pre-launch checks for the experiment configured in the
+// given start options without launching anything in minimega. It verifies that
+// all VM disk images exist, that the experiment's VLANs are available, that VMs
+// requesting GPUs can get them, and that the cluster hosts have capacity for
+// the VMs scheduled on them. Every problem found is returned, not just the
+// first, and each can be checked for with errors.Is and errors.As. The returned
+// map is the schedule (VM hostname to cluster host) computed by the scheduling
+// algorithm the experiment would be started with.
+func ValidateStart(ctx context.Context, opts ...StartOption) (map[string]string, error) {
+	o := newStartOptions(opts...)
+
+	c, _ := store.NewConfig("experiment/" + o.name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", o.name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if exp.Running() && !exp.DryRun() {
+		return nil, fmt.Errorf("experiment already running (started at: %s)", exp.Status.StartTime())
+	}
+
+	if o.vlanMin != 0 {
+		exp.Spec.VLANs().SetMin(o.vlanMin)
+	}
+
+	if o.vlanMax != 0 {
+		exp.Spec.VLANs().SetMax(o.vlanMax)
+	}
+
+	for vm, host := range o.schedule {
+		exp.Spec.Schedules()[vm] = host
+	}
+
+	var errs *multierror.Error
+
+	// Missing images are returned as is so callers can get at them.
+	if err := checkImages(exp, os.Stat); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if err := validateVLANs(exp); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	if len(requestedGPUs(exp)) > 0 {
+		cluster, _, err := mm.GetCachedClusterHosts(false)
+		if err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("%w: getting cluster hosts: %w", ErrGPUUnavailable, err))
+		} else if _, err := scheduleGPUs(exp, cluster, false); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("scheduling GPUs: %w", err))
+		}
+	}
+
+	algorithm := o.scheduler
+	if algorithm == "" {
+		algorithm = DefaultDryRunScheduler
+	}
+
+	// The experiment spec was decoded fresh from the store and is never written
+	// back, so it's safe to let the scheduler modify it here. The rest of the
+	// checks need the VMs to be scheduled.
+	if err := scheduler.Schedule(algorithm, exp.Spec); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("%w: running %s scheduler algorithm: %w", ErrScheduleInfeasible, algorithm, err))
+		return nil, validationErrors{errs}
+	}
+
+	if err := checkScheduledHosts(exp); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("checking scheduled hosts: %w", err))
+	}
+
+	if err := validateCapacity(exp); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("%w: %w", ErrScheduleInfeasible, err))
+	}
+
+	cluster, _, _ := mm.GetCachedClusterHosts(false)
+
+	if err := checkQuota(exp, cluster); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("checking experiment resources: %w", err))
+	}
+
+	if errs != nil {
+		return nil, validationErrors{errs}
+	}
+
+	return exp.Spec.Schedules(), nil
+}
+
+// validationErrors are the problems found by ValidateStart. The version of
+// multierror in use doesn't support errors.Is and errors.As, so this unwraps
+// to each of the problems for them.
+type validationErrors struct {
+	errs *multierror.Error
+}
+
+func (this validationErrors) Error() string {
+	return this.errs.Error()
+}
+
+func (this validationErrors) Unwrap() []error {
+	return this.errs.Errors
+}
+
+func validateVLANs(exp *types.Experiment) error {
+	running, err := types.Experiments(true)
+	if err != nil {
+		return fmt.Errorf("getting running experiments: %w", err)
+	}
+
+	// VLAN IDs currently in use by other running experiments.
+	used := make(map[int]string)
+
+	for _, other := range running {
+		if other.Metadata.Name == exp.Metadata.Name || other.DryRun() {
+			continue
+		}
+
+		for _, id := range other.Status.VLANs() {
+			used[id] = other.Metadata.Name
+		}
+	}
+
+	var (
+		vlans = exp.Spec.VLANs()
+		auto  int
+		errs  error
+	)
+
+	for alias, id := range vlans.Aliases() {
+		if id == 0 {
+			auto++
+			continue
+		}
+
+		if vlans.Min() != 0 && vlans.Max() != 0 && (id < vlans.Min() || id > vlans.Max()) {
+			errs = multierror.Append(errs, fmt.Errorf("VLAN %s (%d) is outside of range %d-%d", alias, id, vlans.Min(), vlans.Max()))
+		}
+
+		if other, ok := used[id]; ok {
+			errs = multierror.Append(errs, fmt.Errorf("VLAN %s (%d) already in use by experiment %s", alias, id, other))
+		}
+	}
+
+	if auto > 0 && vlans.Min() != 0 && vlans.Max() != 0 {
+		var available int
+
+		for id := vlans.Min(); id <= vlans.Max(); id++ {
+			if _, ok := used[id]; !ok {
+				available++
+			}
+		}
+
+		// Explicitly set VLAN IDs within the range are also unavailable for aliases
+		// with automatically assigned IDs.
+		for _, id := range vlans.Aliases() {
+			if id >= vlans.Min() && id <= vlans.Max() {
+				if _, ok := used[id]; !ok {
+					available--
+				}
+			}
+		}
+
+		if auto > available {
+			errs = multierror.Append(errs, fmt.Errorf("%d VLANs needed but only %d available in range %d-%d", auto, available, vlans.Min(), vlans.Max()))
+		}
+	}
+
+	return errs
+}
+
+func validateCapacity(exp *types.Experiment) error {
+	cluster, _, err := mm.GetCachedClusterHosts(false)
+	if err != nil {
+		return fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	// Memory (in MB) that would be committed to each host by this experiment.
+	mem := make(map[string]int)
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		if host, ok := exp.Spec.Schedules()[node.General().Hostname()]; ok {
+			mem[host] += node.Hardware().Memory()
+		}
+	}
+
+	var errs error
+
+	for name, needed := range mem {
+		host := cluster.FindHostByName(name)
+		if host == nil {
+			errs = multierror.Append(errs, fmt.Errorf("cluster host %s not found", name))
+			continue
+		}
+
+		if free := host.MemTotal - host.MemCommit; needed > free {
+			errs = multierror.Append(errs, fmt.Errorf("cluster host %s needs %d MB of memory but only %d MB is uncommitted", name, needed, free))
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,334 @@
+package vlan
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"phenix/api/experiment"
+	"phenix/types"
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+)
+
+var ErrVLANExhausted = errors.New("VLAN pool exhausted")
+
+// PoolAllocation is a VLAN ID allocated from the shared VLAN pool to a VLAN
+// alias in an experiment.
+type PoolAllocation struct {
+	Experiment string `json:"experiment"`
+	Alias      string `json:"alias"`
+	ID         int    `json:"id"`
+}
+
+// PoolStatus describes the shared VLAN pool and the VLAN IDs allocated from it.
+type PoolStatus struct {
+	Enabled     bool             `json:"enabled"`
+	Min         int              `json:"min,omitempty"`
+	Max         int              `json:"max,omitempty"`
+	Available   int              `json:"available"`
+	Allocations []PoolAllocation `json:"allocations"`
+}
+
+// The shared VLAN pool experiments started concurrently are allocated VLAN IDs
+// from so they don't collide.
+var pool = &vlanPool{allocated: make(map[string]map[string]int)}
+
+type vlanPool struct {
+	sync.Mutex
+
+	min int
+	max int
+
+	// VLAN IDs allocated to each experiment, keyed by alias.
+	allocated map[string]map[string]int
+
+	// Set once allocations for experiments already running when the pool was
+	// first used (e.g. before phenix was restarted) have been loaded.
+	seeded bool
+}
+
+func init() {
+	// Covers every way an experiment can be stopped, not just from the UI.
+	experiment.RegisterHook("stop", func(stage, name string) {
+		ReleasePool(name)
+	})
+}
+
+// ParsePoolRange parses a VLAN pool range of the form `<min>-<max>`.
+func ParsePoolRange(r string) (int, int, error) {
+	lo, hi, ok := strings.Cut(r, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid VLAN pool range %q (expected <min>-<max>)", r)
+	}
+
+	min, err := strconv.Atoi(strings.TrimSpace(lo))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid VLAN pool min %q: %w", lo, err)
+	}
+
+	max, err := strconv.Atoi(strings.TrimSpace(hi))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid VLAN pool max %q: %w", hi, err)
+	}
+
+	return min, max, nil
+}
+
+// ConfigurePool sets the range of VLAN IDs in the shared VLAN pool. A range of
+// 0-0 disables the pool, in which case experiments are left to use the VLAN
+// IDs in their own specs.
+//
+// So minimega doesn't hand out pool IDs to VLANs it allocates itself (e.g. for
+// experiments started from the CLI), minimega's default VLAN range is set to the
+// larger of the ranges below and above the pool. Experiments that set their own
+// VLAN range in their spec still use it, so it shouldn't overlap the pool.
+func ConfigurePool(min, max int) error {
+	if min != 0 || max != 0 {
+		if min < 1 || max > 4094 || min > max {
+			return fmt.Errorf("invalid VLAN pool range %d-%d", min, max)
+		}
+	}
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	pool.min, pool.max = min, max
+
+	if min == 0 && max == 0 {
+		return nil
+	}
+
+	lo, hi := rangeOutsidePool(min, max)
+	if lo == 0 {
+		plog.Warn("VLAN pool covers every VLAN ID, so minimega can't allocate VLANs outside it", "min", min, "max", max)
+		return nil
+	}
+
+	cmd := mmcli.NewCommand()
+	cmd.Command = fmt.Sprintf("vlans range %d %d", lo, hi)
+
+	// Minimega not being up yet shouldn't keep the pool from being used.
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		plog.Warn("restricting minimega VLAN range to exclude VLAN pool", "min", lo, "max", hi, "err", err)
+	}
+
+	return nil
+}
+
+// rangeOutsidePool returns the larger of the VLAN ID ranges below and above the
+// given pool range, or zeros if the pool covers every VLAN ID.
+func rangeOutsidePool(min, max int) (int, int) {
+	below, above := min-1, 4094-max
+
+	switch {
+	case below <= 0 && above <= 0:
+		return 0, 0
+	case below >= above:
+		return 1, min - 1
+	default:
+		return max + 1, 4094
+	}
+}
+
+// AllocatePool allocates VLAN IDs from the shared VLAN pool for each VLAN alias
+// in the experiment with the given name that isn't already set to a specific
+// ID in its spec, returning the allocated IDs keyed by alias. IDs in use by
+// other running experiments are never allocated. It returns an error wrapping
+// ErrVLANExhausted if there aren't enough IDs left in the pool, and nothing if
+// the pool isn't enabled. Allocations are released by ReleasePool, which is
+// called automatically when the experiment is stopped.
+func AllocatePool(name string) (map[string]int, error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	if pool.min == 0 && pool.max == 0 {
+		return nil, nil
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	running, err := types.Experiments(true)
+	if err != nil {
+		return nil, fmt.Errorf("getting running experiments: %w", err)
+	}
+
+	pool.seed(running)
+
+	// Any allocation left over from a previous start of the experiment is
+	// replaced.
+	delete(pool.allocated, name)
+
+	used := make(map[int]struct{})
+
+	for _, aliases := range pool.allocated {
+		for _, id := range aliases {
+			used[id] = struct{}{}
+		}
+	}
+
+	for _, other := range running {
+		if other.Metadata.Name == name || other.DryRun() {
+			continue
+		}
+
+		for _, id := range other.Status.VLANs() {
+			used[id] = struct{}{}
+		}
+	}
+
+	var needed []string
+
+	for alias, id := range experimentAliases(exp) {
+		if id == 0 {
+			needed = append(needed, alias)
+		} else {
+			used[id] = struct{}{}
+		}
+	}
+
+	if len(needed) == 0 {
+		return nil, nil
+	}
+
+	sort.Strings(needed)
+
+	var (
+		allocated = make(map[string]int)
+		next      = pool.min
+	)
+
+	for _, alias := range needed {
+		for ; next <= pool.max; next++ {
+			if _, ok := used[next]; !ok {
+				break
+			}
+		}
+
+		if next > pool.max {
+			return nil, fmt.Errorf("%w: experiment %s needs %d VLANs, only %d available in pool %d-%d", ErrVLANExhausted, name, len(needed), len(allocated), pool.min, pool.max)
+		}
+
+		allocated[alias] = next
+		next++
+	}
+
+	pool.allocated[name] = allocated
+
+	result := make(map[string]int)
+
+	for alias, id := range allocated {
+		result[alias] = id
+	}
+
+	return result, nil
+}
+
+// ReleasePool releases the VLAN IDs allocated from the shared VLAN pool to the
+// experiment with the given name, if any.
+func ReleasePool(name string) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	delete(pool.allocated, name)
+}
+
+// Pool returns the status of the shared VLAN pool, including the VLAN IDs
+// currently allocated from it.
+func Pool() (PoolStatus, error) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	status := PoolStatus{Min: pool.min, Max: pool.max, Allocations: []PoolAllocation{}}
+
+	if pool.min == 0 && pool.max == 0 {
+		return status, nil
+	}
+
+	status.Enabled = true
+
+	if !pool.seeded {
+		running, err := types.Experiments(true)
+		if err != nil {
+			return status, fmt.Errorf("getting running experiments: %w", err)
+		}
+
+		pool.seed(running)
+	}
+
+	for exp, aliases := range pool.allocated {
+		for alias, id := range aliases {
+			status.Allocations = append(status.Allocations, PoolAllocation{Experiment: exp, Alias: alias, ID: id})
+		}
+	}
+
+	sort.Slice(status.Allocations, func(i, j int) bool {
+		return status.Allocations[i].ID < status.Allocations[j].ID
+	})
+
+	status.Available = pool.max - pool.min + 1 - len(status.Allocations)
+
+	return status, nil
+}
+
+// seed loads the VLAN IDs within the pool range used by the given running
+// experiments the first time the pool is used, so experiments started before
+// phenix was restarted keep their allocations. The caller must hold the lock.
+func (this *vlanPool) seed(running []*types.Experiment) {
+	if this.seeded {
+		return
+	}
+
+	this.seeded = true
+
+	for _, exp := range running {
+		if exp.DryRun() {
+			continue
+		}
+
+		for alias, id := range exp.Status.VLANs() {
+			if id < this.min || id > this.max {
+				continue
+			}
+
+			if this.allocated[exp.Metadata.Name] == nil {
+				this.allocated[exp.Metadata.Name] = make(map[string]int)
+			}
+
+			this.allocated[exp.Metadata.Name][alias] = id
+		}
+	}
+}
+
+// experimentAliases returns the VLAN aliases used by the given experiment, both
+// those in its spec and those only referenced by its VMs' interfaces, mapped to
+// the ID set in its spec (or zero if not set).
+func experimentAliases(exp *types.Experiment) map[string]int {
+	aliases := make(map[string]int)
+
+	for alias, id := range exp.Spec.VLANs().Aliases() {
+		aliases[alias] = id
+	}
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		for _, iface := range node.Network().Interfaces() {
+			if vlan := iface.VLAN(); vlan != "" {
+				if _, ok := aliases[vlan]; !ok {
+					aliases[vlan] = 0
+				}
+			}
+		}
+	}
+
+	return aliases
+}
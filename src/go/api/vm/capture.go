@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"phenix/api/experiment"
 	"phenix/util/mm"
@@ -98,3 +99,46 @@ func StopCaptures(expName, vmName string) error {
 
 	return nil
 }
+
+// ListCaptures returns all the packet captures currently running in the given
+// experiment, including the current size of each capture file. The size is
+// left as 0 if it cannot be determined, for example when the host the VM is
+// running on is unreachable.
+func ListCaptures(expName string) ([]mm.Capture, error) {
+	if expName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	captures := mm.GetExperimentCaptures(mm.NS(expName))
+
+	if len(captures) == 0 {
+		return nil, nil
+	}
+
+	hosts := make(map[string]string)
+
+	for _, vm := range mm.GetVMInfo(mm.NS(expName)) {
+		hosts[vm.Name] = vm.Host
+	}
+
+	for i, capture := range captures {
+		host := hosts[capture.VM]
+
+		if host == "" || mm.IsHeadnode(host) {
+			if info, err := os.Stat(capture.Filepath); err == nil {
+				captures[i].Size = info.Size()
+			}
+
+			continue
+		}
+
+		resp, err := mm.MeshShellResponse(host, "stat -c %s "+capture.Filepath)
+		if err != nil {
+			continue
+		}
+
+		captures[i].Size, _ = strconv.ParseInt(resp, 10, 64)
+	}
+
+	return captures, nil
+}
@@ -0,0 +1,207 @@
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+)
+
+var ErrCommandNotAllowed = errors.New("minimega command not allowed")
+
+// DefaultExecVerbs are the minimega commands allowed to be run against a VM
+// with Exec by default. They're all scoped to a single VM. `vm qmp` is limited
+// to ExecQMPCommands, since arbitrary QMP (e.g. `human-monitor-command` or
+// `migrate` to an `exec:` URI) can run commands on the VM's host.
+var DefaultExecVerbs = []string{"vm info", "vm top", "vm qmp", "vm tag"}
+
+// ExecQMPCommands are the QMP commands allowed to be run with `vm qmp` by Exec.
+// They only report the VM's state.
+var ExecQMPCommands = []string{
+	"query-balloon",
+	"query-block",
+	"query-block-jobs",
+	"query-blockstats",
+	"query-chardev",
+	"query-cpus-fast",
+	"query-dump",
+	"query-kvm",
+	"query-memory-size-summary",
+	"query-migrate",
+	"query-name",
+	"query-pci",
+	"query-status",
+	"query-uuid",
+	"query-version",
+	"query-vnc",
+}
+
+// Verbs that don't take a VM target, so their output is filtered to the VM
+// instead.
+var filteredExecVerbs = map[string]bool{"vm info": true, "vm top": true}
+
+var execCommandRegex = regexp.MustCompile(`^(vm\s+[a-z]+)(?:\s+(.*))?$`)
+
+// ExecResponse is the output of a minimega command run against a VM with Exec.
+type ExecResponse struct {
+	Host     string     `json:"host"`
+	Response string     `json:"response,omitempty"`
+	Header   []string   `json:"header,omitempty"`
+	Tabular  [][]string `json:"tabular,omitempty"`
+	Error    string     `json:"error,omitempty"`
+}
+
+// Exec runs the given minimega command against the VM with the given name in
+// the given running experiment, on the cluster host the VM is running on, and
+// returns the full command run along with its output. The command's verb (e.g.
+// `vm qmp`) must be one of the given allowed verbs, and the VM is added as the
+// command's target, so `vm qmp '{"execute": "query-status"}'` is run as `vm qmp
+// <vm> '{"execute": "query-status"}'`. Commands that don't take a target, like
+// `vm info`, have their output filtered to the VM instead. `vm qmp` commands
+// must be one of ExecQMPCommands. It returns ErrCommandNotAllowed (wrapped) if
+// the verb or QMP command isn't allowed.
+func Exec(expName, vmName, command string, allowed []string) (string, []ExecResponse, error) {
+	if expName == "" {
+		return "", nil, fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return "", nil, fmt.Errorf("no VM name provided")
+	}
+
+	command = strings.TrimSpace(command)
+
+	if strings.ContainsAny(command, "\r\n") {
+		return "", nil, fmt.Errorf("%w: commands must be a single line", ErrCommandNotAllowed)
+	}
+
+	match := execCommandRegex.FindStringSubmatch(command)
+	if match == nil {
+		return "", nil, fmt.Errorf("%w: %s (allowed commands are %s)", ErrCommandNotAllowed, command, strings.Join(allowed, ", "))
+	}
+
+	var (
+		verb = strings.Join(strings.Fields(match[1]), " ")
+		args = match[2]
+		err  error
+	)
+
+	if !allowedExecVerb(verb, allowed) {
+		return "", nil, fmt.Errorf("%w: %s (allowed commands are %s)", ErrCommandNotAllowed, verb, strings.Join(allowed, ", "))
+	}
+
+	if verb == "vm qmp" {
+		if args, err = execQMPArgs(args); err != nil {
+			return "", nil, err
+		}
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return "", nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if exp.Spec.Topology().FindNodeByName(vmName) == nil {
+		return "", nil, fmt.Errorf("%w: %s in experiment %s", ErrVMNotFound, vmName, expName)
+	}
+
+	if !exp.Running() {
+		return "", nil, fmt.Errorf("running command against VM %s in experiment %s: %w", vmName, expName, experiment.ErrExperimentNotRunning)
+	}
+
+	host, err := mm.GetVMHost(mm.NS(expName), mm.VMName(vmName))
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to determine what host the VM is scheduled on: %w", err)
+	}
+
+	cmd := mmcli.NewNamespacedCommand(expName)
+
+	if filteredExecVerbs[verb] {
+		cmd.Command = strings.TrimSpace(verb + " " + args)
+		cmd.Filters = []string{"name=" + vmName}
+	} else {
+		cmd.Command = strings.TrimSpace(fmt.Sprintf("%s %s %s", verb, vmName, args))
+	}
+
+	run := cmd.Command
+
+	if !mm.IsHeadnode(host) {
+		cmd.Command = fmt.Sprintf("mesh send %s namespace %s %s", host, expName, cmd.Command)
+	}
+
+	var responses []ExecResponse
+
+	for response := range mmcli.Run(cmd) {
+		for _, resp := range response.Resp {
+			responses = append(responses, ExecResponse{
+				Host:     resp.Host,
+				Response: resp.Response,
+				Header:   resp.Header,
+				Tabular:  resp.Tabular,
+				Error:    resp.Error,
+			})
+		}
+	}
+
+	return run, responses, nil
+}
+
+func allowedExecVerb(verb string, allowed []string) bool {
+	for _, a := range allowed {
+		if strings.Join(strings.Fields(a), " ") == verb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// execQMPArgs checks the given `vm qmp` arguments are a single QMP command in
+// ExecQMPCommands, returning them re-encoded so nothing but the parsed command
+// is passed to minimega.
+func execQMPArgs(args string) (string, error) {
+	args = strings.TrimSpace(args)
+
+	if len(args) >= 2 && args[0] == '\'' && args[len(args)-1] == '\'' {
+		args = args[1 : len(args)-1]
+	}
+
+	var qmp struct {
+		Execute   string          `json:"execute"`
+		Arguments json.RawMessage `json:"arguments,omitempty"`
+	}
+
+	dec := json.NewDecoder(strings.NewReader(args))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&qmp); err != nil || dec.More() {
+		return "", fmt.Errorf("%w: vm qmp takes a single QMP command, e.g. '{\"execute\": \"query-status\"}'", ErrCommandNotAllowed)
+	}
+
+	var allowed bool
+
+	for _, c := range ExecQMPCommands {
+		if qmp.Execute == c {
+			allowed = true
+			break
+		}
+	}
+
+	if !allowed {
+		return "", fmt.Errorf("%w: QMP command %q (allowed QMP commands are %s)", ErrCommandNotAllowed, qmp.Execute, strings.Join(ExecQMPCommands, ", "))
+	}
+
+	body, _ := json.Marshal(qmp)
+
+	// The command is single quoted for minimega.
+	if strings.Contains(string(body), "'") {
+		return "", fmt.Errorf("%w: QMP arguments can't contain single quotes", ErrCommandNotAllowed)
+	}
+
+	return "'" + string(body) + "'", nil
+}
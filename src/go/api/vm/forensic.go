@@ -0,0 +1,461 @@
+package vm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/common"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+)
+
+// Stages of a VM's forensic capture, reported in ForensicProgress.
+const (
+	ForensicStageMemory    = "memory"
+	ForensicStageDisk      = "disk"
+	ForensicStageCompleted = "completed"
+	ForensicStageFailed    = "failed"
+)
+
+// How long a single VM's disk backup has to finish, and how often its progress
+// is checked. They're variables so tests can shorten them.
+var (
+	forensicBackupTimeout      = 1 * time.Hour
+	forensicBackupPollInterval = 1 * time.Second
+)
+
+// ForensicBundle describes the memory dumps and disk images captured from an
+// experiment's running VMs with ForensicCapture.
+type ForensicBundle struct {
+	Experiment string `json:"experiment"`
+
+	// Path to the bundle directory on the headnode, and the same path relative
+	// to the experiment's files directory.
+	Path     string `json:"path"`
+	FilesDir string `json:"filesDir"`
+
+	Started  string `json:"started"`
+	Finished string `json:"finished"`
+
+	VMs map[string]ForensicVMCapture `json:"vms"`
+
+	// VMs that couldn't be captured, along with why.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ForensicVMCapture describes the files captured for a single VM in a forensic
+// bundle. File names are relative to the bundle directory.
+type ForensicVMCapture struct {
+	// Cluster host the VM was running on when it was captured.
+	Host string `json:"host"`
+
+	Memory       string `json:"memory"`
+	MemorySHA256 string `json:"memorySha256,omitempty"`
+	Disk         string `json:"disk"`
+	DiskSHA256   string `json:"diskSha256,omitempty"`
+
+	// Total size (in bytes) of the VM's memory dump and disk image.
+	Size int64 `json:"size"`
+}
+
+// ForensicProgress is the progress of capturing a single VM, passed to the
+// callback given to ForensicCapture. Progress is between 0 and 1 across all
+// the VM's stages.
+type ForensicProgress struct {
+	VM       string  `json:"vm"`
+	Stage    string  `json:"stage"`
+	Progress float64 `json:"progress"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// ForensicCapture captures the memory and full disk state of the given VMs (or
+// every running VM if none are given) in the given running experiment into a
+// new timestamped bundle in the experiment's files directory, along with a
+// manifest.json describing it. Unlike SnapshotExperiment, VMs keep running:
+// memory is dumped with QMP's dump-guest-memory and disks are copied with a
+// full drive backup, neither of which stop the VM. Files captured on other
+// cluster hosts are pulled to the headnode. VMs that fail to be captured are
+// recorded in the bundle's errors, but an error is only returned if none could
+// be captured. The given callback, if not nil, is called as each VM progresses.
+// VMs still being captured when the given context is done fail.
+func ForensicCapture(ctx context.Context, expName string, vms []string, cb func(ForensicProgress)) (*ForensicBundle, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("capturing experiment %s: %w", expName, experiment.ErrExperimentNotRunning)
+	}
+
+	if exp.DryRun() {
+		return nil, fmt.Errorf("cannot capture dry-run experiment %s", expName)
+	}
+
+	all, err := List(expName)
+	if err != nil {
+		return nil, fmt.Errorf("listing VMs in experiment %s: %w", expName, err)
+	}
+
+	var (
+		now     = time.Now().UTC()
+		rel     = filepath.Join("forensics", now.Format("20060102T150405Z"))
+		running = make(map[string]mm.VM)
+		bundle  = &ForensicBundle{
+			Experiment: expName,
+			Path:       filepath.Join(common.PhenixBase, "images", expName, "files", rel),
+			FilesDir:   rel,
+			Started:    now.Format(time.RFC3339),
+			VMs:        make(map[string]ForensicVMCapture),
+			Errors:     make(map[string]string),
+		}
+	)
+
+	for _, vm := range all {
+		if vm.Running {
+			running[vm.Name] = vm
+		}
+	}
+
+	if len(vms) == 0 {
+		for name := range running {
+			vms = append(vms, name)
+		}
+	}
+
+	sort.Strings(vms)
+
+	if err := os.MkdirAll(bundle.Path, 0755); err != nil {
+		return nil, fmt.Errorf("creating forensic bundle directory: %w", err)
+	}
+
+	report := func(p ForensicProgress) {
+		if cb != nil {
+			cb(p)
+		}
+	}
+
+	for _, name := range vms {
+		vm, ok := running[name]
+		if !ok {
+			bundle.Errors[name] = "VM is not running"
+			report(ForensicProgress{VM: name, Stage: ForensicStageFailed, Error: bundle.Errors[name]})
+
+			continue
+		}
+
+		capture, err := captureVM(ctx, expName, vm, bundle.Path, func(stage string, progress float64) {
+			report(ForensicProgress{VM: name, Stage: stage, Progress: progress})
+		})
+
+		if err != nil {
+			plog.Warn("unable to capture VM for forensic bundle", "exp", expName, "vm", name, "bundle", bundle.Path, "err", err)
+
+			bundle.Errors[name] = err.Error()
+			report(ForensicProgress{VM: name, Stage: ForensicStageFailed, Error: err.Error()})
+
+			continue
+		}
+
+		bundle.VMs[name] = capture
+		report(ForensicProgress{VM: name, Stage: ForensicStageCompleted, Progress: 1})
+	}
+
+	bundle.Finished = time.Now().UTC().Format(time.RFC3339)
+
+	if len(bundle.VMs) == 0 {
+		os.RemoveAll(bundle.Path)
+
+		if len(bundle.Errors) == 0 {
+			return nil, fmt.Errorf("no running VMs to capture in experiment %s", expName)
+		}
+
+		return nil, fmt.Errorf("unable to capture any VMs in experiment %s", expName)
+	}
+
+	body, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding forensic bundle manifest: %w", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(bundle.Path, "manifest.json"), body, 0644); err != nil {
+		return nil, fmt.Errorf("writing forensic bundle manifest: %w", err)
+	}
+
+	return bundle, nil
+}
+
+// captureVM dumps the memory and copies the disk of the given running VM into
+// the given bundle directory, calling progress with the current stage and the
+// VM's overall progress.
+func captureVM(ctx context.Context, expName string, vm mm.VM, dir string, progress func(string, float64)) (ForensicVMCapture, error) {
+	capture := ForensicVMCapture{
+		Host:   vm.Host,
+		Memory: vm.Name + ".elf",
+		Disk:   vm.Name + ".qc2",
+	}
+
+	progress(ForensicStageMemory, 0)
+
+	// Memory is captured first so it's as close as possible to the time of the
+	// request. Cut progress in half since the memory dump is 1 of 2 steps.
+	_, err := MemorySnapshot(expName, vm.Name, filepath.Join(dir, capture.Memory), func(status string) {
+		if p, err := strconv.ParseFloat(status, 64); err == nil {
+			progress(ForensicStageMemory, p*0.5)
+		}
+	})
+
+	if err != nil {
+		return capture, fmt.Errorf("capturing memory: %w", err)
+	}
+
+	progress(ForensicStageDisk, 0.5)
+
+	err = backupDisk(ctx, expName, vm.Name, filepath.Join(dir, capture.Disk), func(p float64) {
+		progress(ForensicStageDisk, 0.5+(p*0.5))
+	})
+
+	if err != nil {
+		return capture, fmt.Errorf("capturing disk: %w", err)
+	}
+
+	for _, f := range []struct {
+		name string
+		hash *string
+	}{{capture.Memory, &capture.MemorySHA256}, {capture.Disk, &capture.DiskSHA256}} {
+		// The files were captured, so failing to hash them isn't fatal.
+		size, sum, err := hashFile(filepath.Join(dir, f.name))
+		if err != nil {
+			plog.Warn("hashing forensic capture file", "exp", expName, "vm", vm.Name, "file", f.name, "err", err)
+			continue
+		}
+
+		capture.Size += size
+		*f.hash = sum
+	}
+
+	return capture, nil
+}
+
+// backupDisk copies the full current state of the given running VM's disk,
+// including any backing images, to the given path using a QMP drive backup,
+// which doesn't pause the VM. The copy is pulled to the headnode if the VM is
+// running on another cluster host. progress is called with the backup's
+// progress, between 0 and 1. The backup is canceled if it doesn't finish
+// before forensicBackupTimeout passes or the given context is done.
+func backupDisk(ctx context.Context, expName, vmName, target string, progress func(float64)) error {
+	cmd := mmcli.NewNamespacedCommand(expName)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "id"}
+	cmd.Filters = []string{"name=" + vmName}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found", vmName)
+	}
+
+	cmd.Columns = nil
+	cmd.Filters = nil
+
+	var (
+		host = status[0]["host"]
+		fp   = fmt.Sprintf("%s/%s", common.MinimegaBase, status[0]["id"])
+	)
+
+	qmp := `{ "execute": "query-block" }`
+	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vmName, qmp)
+
+	res, err := mmcli.SingleResponse(mmcli.Run(cmd))
+	if err != nil {
+		return fmt.Errorf("querying for block device details for VM %s: %w", vmName, err)
+	}
+
+	var v map[string][]mm.BlockDevice
+
+	if err := json.Unmarshal([]byte(res), &v); err != nil {
+		return fmt.Errorf("parsing block device details for VM %s: %w", vmName, err)
+	}
+
+	var device, fallback string
+
+	for _, dev := range v["return"] {
+		if dev.Inserted == nil {
+			continue
+		}
+
+		// VMs launched with snapshot=false write directly to their disk image,
+		// so there's no overlay in minimega's VM directory to match on.
+		if fallback == "" && !strings.HasSuffix(dev.Inserted.File, ".iso") {
+			fallback = dev.Device
+		}
+
+		if strings.HasPrefix(dev.Inserted.File, fp) {
+			device = dev.Device
+			break
+		}
+	}
+
+	if device == "" {
+		device = fallback
+	}
+
+	if device == "" {
+		return fmt.Errorf("no disk found for VM %s", vmName)
+	}
+
+	// The job isn't dismissed automatically once it's done, so whether it
+	// succeeded can be checked before it's dismissed.
+	job := "forensic-" + vmName
+
+	qmp = fmt.Sprintf(`{ "execute": "drive-backup", "arguments": { "job-id": "%s", "device": "%s", "sync": "full", "format": "qcow2", "target": "%s", "auto-dismiss": false } }`, job, device, target)
+	cmd.Command = fmt.Sprintf(`vm qmp %s '%s'`, vmName, qmp)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("starting disk backup for VM %s: %w", vmName, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, forensicBackupTimeout)
+	defer cancel()
+
+	query := func() (string, error) {
+		cmd.Command = fmt.Sprintf(`vm qmp %s '{ "execute": "query-jobs" }'`, vmName)
+		return mmcli.SingleResponse(mmcli.Run(cmd))
+	}
+
+	err = waitForBackupJob(ctx, job, query, progress)
+
+	execute := "job-dismiss"
+
+	// A canceled job concludes on its own, and is dismissed by QEMU when the VM
+	// is killed if it isn't before.
+	if err != nil {
+		execute = "job-cancel"
+	}
+
+	cmd.Command = fmt.Sprintf(`vm qmp %s '{ "execute": "%s", "arguments": { "id": "%s" } }'`, vmName, execute, job)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		plog.Warn("cleaning up disk backup job", "exp", expName, "vm", vmName, "job", job, "command", execute, "err", err)
+	}
+
+	if err != nil {
+		return fmt.Errorf("backing up disk for VM %s: %w", vmName, err)
+	}
+
+	if !mm.IsHeadnode(host) {
+		if err := pullFile(target); err != nil {
+			return fmt.Errorf("pulling disk backup to headnode: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// qmpJob is a job as returned by QMP's query-jobs.
+type qmpJob struct {
+	ID      string `json:"id"`
+	Status  string `json:"status"`
+	Current int64  `json:"current-progress"`
+	Total   int64  `json:"total-progress"`
+	Error   string `json:"error"`
+}
+
+// waitForBackupJob polls the QMP job with the given ID, using query to get the
+// response to query-jobs, until the job concludes, calling progress with its
+// progress. It returns an error if the job failed, is gone before concluding,
+// or the given context is done first.
+func waitForBackupJob(ctx context.Context, id string, query func() (string, error), progress func(float64)) error {
+	ticker := time.NewTicker(forensicBackupPollInterval)
+	defer ticker.Stop()
+
+	for {
+		res, err := query()
+		if err != nil {
+			return fmt.Errorf("querying jobs: %w", err)
+		}
+
+		var v struct {
+			Return []qmpJob `json:"return"`
+		}
+
+		if err := json.Unmarshal([]byte(res), &v); err != nil {
+			return fmt.Errorf("parsing jobs: %w", err)
+		}
+
+		var job *qmpJob
+
+		for i := range v.Return {
+			if v.Return[i].ID == id {
+				job = &v.Return[i]
+				break
+			}
+		}
+
+		if job == nil {
+			return fmt.Errorf("job %s not found", id)
+		}
+
+		if job.Total > 0 {
+			progress(float64(job.Current) / float64(job.Total))
+		}
+
+		if job.Status == "concluded" {
+			if job.Error != "" {
+				return fmt.Errorf("job %s failed: %s", id, job.Error)
+			}
+
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for job %s: %w", id, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// pullFile copies the file at the given path under minimega's files directory
+// from the cluster host it was written on to the headnode.
+func pullFile(path string) error {
+	rel, err := filepath.Rel(filepath.Join(common.PhenixBase, "images"), path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is not in the minimega files directory", path)
+	}
+
+	cmd := mmcli.NewCommand()
+	cmd.Command = "file get " + rel
+
+	return mmcli.ErrorResponse(mmcli.Run(cmd))
+}
+
+func hashFile(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+
+	defer f.Close()
+
+	h := sha256.New()
+
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
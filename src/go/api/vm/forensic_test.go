@@ -0,0 +1,79 @@
+package vm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForBackupJob(t *testing.T) {
+	defer func(interval time.Duration) { forensicBackupPollInterval = interval }(forensicBackupPollInterval)
+	forensicBackupPollInterval = time.Millisecond
+
+	job := func(status string, current int64, errMsg string) string {
+		return fmt.Sprintf(`{"return": [{"id": "other", "status": "running"}, {"id": "forensic-vm", "status": "%s", "current-progress": %d, "total-progress": 4, "error": "%s"}]}`, status, current, errMsg)
+	}
+
+	cases := map[string]struct {
+		responses []string
+		err       string
+		progress  []float64
+	}{
+		"concluded": {
+			responses: []string{job("running", 1, ""), job("running", 2, ""), job("concluded", 4, "")},
+			progress:  []float64{0.25, 0.5, 1},
+		},
+		"failed": {
+			responses: []string{job("running", 1, ""), job("concluded", 2, "No space left on device")},
+			err:       "No space left on device",
+			progress:  []float64{0.25, 0.5},
+		},
+		"missing": {
+			responses: []string{`{"return": []}`},
+			err:       "not found",
+		},
+		"invalid": {
+			responses: []string{`not json`},
+			err:       "parsing jobs",
+		},
+	}
+
+	for name, tc := range cases {
+		var (
+			calls    int
+			progress []float64
+		)
+
+		query := func() (string, error) {
+			res := tc.responses[calls]
+			calls++
+
+			return res, nil
+		}
+
+		err := waitForBackupJob(context.Background(), "forensic-vm", query, func(p float64) { progress = append(progress, p) })
+
+		if tc.err == "" && err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+		}
+
+		if tc.err != "" && (err == nil || !strings.Contains(err.Error(), tc.err)) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tc.err, err)
+		}
+
+		if fmt.Sprint(progress) != fmt.Sprint(tc.progress) {
+			t.Errorf("%s: expected progress %v, got %v", name, tc.progress, progress)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := waitForBackupJob(ctx, "forensic-vm", func() (string, error) { return job("running", 1, ""), nil }, func(float64) {})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected wait to time out, got %v", err)
+	}
+}
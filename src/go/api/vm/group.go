@@ -0,0 +1,181 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"phenix/api/experiment"
+)
+
+var (
+	ErrInvalidGroupAction = errors.New("invalid VM group action")
+	ErrNoVMsMatched       = errors.New("no VMs match selector")
+)
+
+// Maximum number of VMs acted on at the same time by GroupAction.
+const DefaultGroupConcurrency = 16
+
+// Actions that can be taken on a group of VMs using GroupAction.
+var groupActions = map[string]func(string, string) error{
+	"start":   Resume,
+	"stop":    Shutdown,
+	"restart": Restart,
+	"pause":   Pause,
+}
+
+// GroupResult is the result of a group action for a single VM.
+type GroupResult struct {
+	VM  string
+	Err error
+}
+
+type GroupOption func(*groupOptions)
+
+type groupOptions struct {
+	concurrency int
+	before      func(string) error
+	after       func(GroupResult)
+}
+
+func newGroupOptions(opts ...GroupOption) groupOptions {
+	o := groupOptions{concurrency: DefaultGroupConcurrency}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// GroupWithConcurrency sets the maximum number of VMs acted on at the same
+// time. Values less than 1 are ignored.
+func GroupWithConcurrency(c int) GroupOption {
+	return func(o *groupOptions) {
+		if c > 0 {
+			o.concurrency = c
+		}
+	}
+}
+
+// GroupWithBefore sets a function to be called with the name of each VM right
+// before the action is taken on it. If it returns an error, the action is
+// skipped for the VM and the error is included in its result.
+func GroupWithBefore(f func(string) error) GroupOption {
+	return func(o *groupOptions) {
+		o.before = f
+	}
+}
+
+// GroupWithAfter sets a function to be called with the result for each VM as
+// soon as the action taken on it completes.
+func GroupWithAfter(f func(GroupResult)) GroupOption {
+	return func(o *groupOptions) {
+		o.after = f
+	}
+}
+
+// GroupMembers returns the names of the VMs in the experiment with the given
+// name whose labels match the given selector. The selector uses the same syntax
+// as experiment tag selectors (e.g. `role=server,zone`). External nodes are
+// never matched since phenix doesn't control them.
+func GroupMembers(expName, selector string) ([]string, error) {
+	if expName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	sel, err := experiment.ParseTagSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := experiment.GetCached(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	var members []string
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if sel.Matches(node.Labels()) {
+			members = append(members, node.General().Hostname())
+		}
+	}
+
+	if len(members) == 0 {
+		return nil, fmt.Errorf("%w %q in experiment %s", ErrNoVMsMatched, selector, expName)
+	}
+
+	return members, nil
+}
+
+// GroupAction takes the given action on all the VMs in the experiment with the
+// given name whose labels match the given selector. Valid actions are `start`
+// (resume), `stop` (power off), `restart`, and `pause`. The action is taken on
+// the matched VMs concurrently, and a result is returned for each of them in
+// the order they appear in the topology. An error is only returned if the
+// action or selector is invalid, no VMs match, or the experiment isn't running.
+func GroupAction(expName, selector, action string, opts ...GroupOption) ([]GroupResult, error) {
+	fn, ok := groupActions[action]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidGroupAction, action)
+	}
+
+	members, err := GroupMembers(expName, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	exp, err := experiment.GetCached(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("%w: %s", experiment.ErrExperimentNotRunning, expName)
+	}
+
+	var (
+		o       = newGroupOptions(opts...)
+		results = make([]GroupResult, len(members))
+		sem     = make(chan struct{}, o.concurrency)
+		wg      sync.WaitGroup
+	)
+
+	for i, name := range members {
+		wg.Add(1)
+
+		go func(i int, name string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			results[i] = GroupResult{VM: name}
+
+			if o.before != nil {
+				if err := o.before(name); err != nil {
+					results[i].Err = err
+				}
+			}
+
+			if results[i].Err == nil {
+				if err := fn(expName, name); err != nil {
+					results[i].Err = fmt.Errorf("%s VM %s: %w", action, name, err)
+				}
+			}
+
+			if o.after != nil {
+				o.after(results[i])
+			}
+		}(i, name)
+	}
+
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,150 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+	"phenix/util/plog"
+)
+
+var (
+	ErrMigrateSameHost    = errors.New("VM already running on target host")
+	ErrInsufficientMemory = errors.New("target host has insufficient memory")
+)
+
+// Migrate moves the running VM with the given name in the experiment with the
+// given name to the given cluster host, updating the experiment's schedule to
+// reflect its new placement. minimega can't migrate a VM directly between
+// hosts, so the VM's memory and disk state are snapshotted (the same way
+// Snapshot does) and it's relaunched from the snapshot on the target host. As
+// with Restore, the snapshot files must be reachable from the target host.
+//
+// The target host must have enough uncommitted memory for the VM. If migrating
+// fails, the schedule change is rolled back and, if it was already killed, the
+// VM is relaunched on its original host.
+func Migrate(expName, vmName, target string) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	if target == "" {
+		return fmt.Errorf("no target host provided")
+	}
+
+	if !experiment.Running(expName) {
+		return fmt.Errorf("migrating VM %s in experiment %s: %w", vmName, expName, experiment.ErrExperimentNotRunning)
+	}
+
+	vm, err := Get(expName, vmName)
+	if err != nil {
+		return fmt.Errorf("getting VM details: %w", err)
+	}
+
+	if !vm.Running {
+		return errors.New("VM is not running")
+	}
+
+	if vm.Host == target {
+		return fmt.Errorf("%w: %s", ErrMigrateSameHost, target)
+	}
+
+	cluster, err := mm.GetClusterHosts(true)
+	if err != nil {
+		return fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	host := cluster.FindHostByName(target)
+	if host == nil {
+		return fmt.Errorf("cluster host %s: %w", target, mm.ErrHostNotFound)
+	}
+
+	if free := host.MemTotal - host.MemCommit; vm.RAM > free {
+		return fmt.Errorf("%w: VM %s needs %d MB of memory but only %d MB is uncommitted on %s", ErrInsufficientMemory, vmName, vm.RAM, free, target)
+	}
+
+	source := vm.Host
+
+	// The VM is killed and relaunched on the target, which changes what's
+	// committed on both hosts.
+	defer mm.InvalidateClusterHosts()
+
+	if err := setSchedule(expName, vmName, target); err != nil {
+		return fmt.Errorf("updating schedule for VM %s: %w", vmName, err)
+	}
+
+	snap := fmt.Sprintf("migrate-%d", time.Now().Unix())
+
+	err = Snapshot(expName, vmName, snap, nil)
+	if err == nil {
+		err = relaunchFromSnapshot(expName, vmName, fmt.Sprintf("%s__%s", vmName, snap), target)
+	}
+
+	if err != nil {
+		if rerr := setSchedule(expName, vmName, source); rerr != nil {
+			plog.Error("rolling back schedule for VM after failed migration", "exp", expName, "vm", vmName, "host", source, "err", rerr)
+		}
+
+		recoverMigration(expName, vmName, source)
+
+		return fmt.Errorf("migrating VM %s to %s: %w", vmName, target, err)
+	}
+
+	return nil
+}
+
+// setSchedule sets the cluster host the given VM is scheduled on in both the
+// given experiment's spec and status.
+func setSchedule(expName, vmName, host string) error {
+	defer experiment.InvalidateCached(expName)
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	schedule := exp.Spec.Schedules()
+	schedule[vmName] = host
+
+	exp.Spec.SetSchedule(schedule)
+
+	// Only running experiments have their actual placement in their status.
+	if placed := exp.Status.Schedules(); len(placed) > 0 {
+		placed[vmName] = host
+		exp.Status.SetSchedule(placed)
+	}
+
+	return exp.WriteToStore(false)
+}
+
+// recoverMigration relaunches the given VM on the given host if a failed
+// migration left it killed. The VM's config cloned while relaunching it is
+// still in place, so it only needs to be rescheduled.
+func recoverMigration(expName, vmName, host string) {
+	if _, err := mm.GetVMHost(mm.NS(expName), mm.VMName(vmName)); err == nil {
+		return
+	}
+
+	cmd := mmcli.NewNamespacedCommand(expName)
+
+	for _, command := range []string{
+		fmt.Sprintf("vm config schedule %s", host),
+		fmt.Sprintf("vm launch kvm %s", vmName),
+		"vm launch",
+		fmt.Sprintf("vm start %s", vmName),
+	} {
+		cmd.Command = command
+
+		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+			plog.Error("relaunching VM on original host after failed migration", "exp", expName, "vm", vmName, "host", host, "err", err)
+			return
+		}
+	}
+}
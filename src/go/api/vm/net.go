@@ -2,46 +2,105 @@ package vm
 
 import (
 	"fmt"
+	"strings"
 
+	"phenix/api/experiment"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
 	"phenix/util/mm"
 )
 
-// Connect moves or reconnects the given interface for the given VM in the given
-// experiment to the given VLAN. The given interface must already exist in the
-// VM. It returns any errors encountered while connecting the interface.
-func Connect(expName, vmName string, iface int, vlan string) error {
-	if expName == "" {
-		return fmt.Errorf("no experiment name provided")
+// ConnectInterface moves or reconnects the given interface for the given VM in
+// the given running experiment to the given VLAN, which must be one of the
+// experiment's VLANs. The given interface must already exist in the VM. The
+// experiment's topology is updated to match, so the interface stays connected
+// to the VLAN if the experiment is restarted.
+//
+// It returns an error wrapping experiment.ErrVLANNotFound if the VLAN isn't
+// part of the experiment, and ErrVMNotFound or ErrInterfaceNotFound if the VM
+// or interface don't exist.
+func ConnectInterface(expName, vmName string, iface int, vlan string) error {
+	exp, node, err := runningInterface(expName, vmName, iface)
+	if err != nil {
+		return err
 	}
 
-	if vmName == "" {
-		return fmt.Errorf("no VM name provided")
+	var alias string
+
+	for a := range exp.Spec.VLANs().Aliases() {
+		if strings.EqualFold(a, vlan) {
+			alias = a
+			break
+		}
 	}
 
-	err := mm.ConnectVMInterface(mm.NS(expName), mm.VMName(vmName), mm.ConnectInterface(iface), mm.ConnectVLAN(vlan))
+	if alias == "" {
+		return fmt.Errorf("%w: %s in experiment %s", experiment.ErrVLANNotFound, vlan, expName)
+	}
+
+	err = mm.ConnectVMInterface(mm.NS(expName), mm.VMName(vmName), mm.ConnectInterface(iface), mm.ConnectVLAN(alias))
 	if err != nil {
 		return fmt.Errorf("connecting VM interface to VLAN: %w", err)
 	}
 
+	node.Network().Interfaces()[iface].SetVLAN(alias)
+
+	if err := experiment.Save(experiment.SaveWithName(expName), experiment.SaveWithSpec(exp.Spec)); err != nil {
+		return fmt.Errorf("saving experiment with updated VM interface: %w", err)
+	}
+
+	return nil
+}
+
+// DisconnectInterface disconnects the given interface for the given VM in the
+// given running experiment from the VLAN it's currently connected to (if any).
+// The experiment's topology is left as is, since the interface has to be
+// connected to a VLAN when the experiment is restarted.
+func DisconnectInterface(expName, vmName string, iface int) error {
+	if _, _, err := runningInterface(expName, vmName, iface); err != nil {
+		return err
+	}
+
+	err := mm.DisconnectVMInterface(mm.NS(expName), mm.VMName(vmName), mm.ConnectInterface(iface))
+	if err != nil {
+		return fmt.Errorf("disconnecting VM interface: %w", err)
+	}
+
 	return nil
 }
 
-// Disconnect disconnects the given interface for the given VM in the given
-// experiment from the VLAN it's currently connected to (if any). It returns any
-// errors encountered while disconnecting the interface.
-func Disonnect(expName, vmName string, iface int) error {
+// runningInterface returns the given running experiment and the node for the
+// given VM in it, checking the node has the given interface.
+func runningInterface(expName, vmName string, iface int) (*types.Experiment, ifaces.NodeSpec, error) {
 	if expName == "" {
-		return fmt.Errorf("no experiment name provided")
+		return nil, nil, fmt.Errorf("no experiment name provided")
 	}
 
 	if vmName == "" {
-		return fmt.Errorf("no VM name provided")
+		return nil, nil, fmt.Errorf("no VM name provided")
 	}
 
-	err := mm.DisconnectVMInterface(mm.NS(expName), mm.VMName(vmName), mm.ConnectInterface(iface))
+	exp, err := experiment.Get(expName)
 	if err != nil {
-		return fmt.Errorf("disconnecting VM interface: %w", err)
+		return nil, nil, fmt.Errorf("getting experiment %s: %w", expName, err)
 	}
 
-	return nil
+	if !exp.Running() {
+		return nil, nil, fmt.Errorf("changing VM interface: %w", experiment.ErrExperimentNotRunning)
+	}
+
+	if exp.DryRun() {
+		return nil, nil, fmt.Errorf("cannot change VM interfaces in a dry-run experiment")
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node == nil {
+		return nil, nil, fmt.Errorf("%w: %s in experiment %s", ErrVMNotFound, vmName, expName)
+	}
+
+	if node.Network() == nil || iface < 0 || iface >= len(node.Network().Interfaces()) {
+		return nil, nil, fmt.Errorf("%w: %d on VM %s", ErrInterfaceNotFound, iface, vmName)
+	}
+
+	return exp, node, nil
 }
@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"phenix/api/experiment"
+)
+
+// MaxNoteLength is the maximum number of characters in a VM note.
+const MaxNoteLength = 4096
+
+var ErrInvalidNote = errors.New("invalid VM note")
+
+// SetNote sets the free-text note for the VM with the given name in the
+// experiment with the given name, removing it if the note is empty. Notes are
+// saved as annotations on the experiment config, so they're kept across
+// starting and stopping the experiment and included whenever its config is
+// exported or cloned.
+func SetNote(expName, vmName, note string) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	if n := utf8.RuneCountInString(note); n > MaxNoteLength {
+		return fmt.Errorf("%w: note is %d characters, the maximum is %d", ErrInvalidNote, n, MaxNoteLength)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if exp.Spec.Topology().FindNodeByName(vmName) == nil {
+		return fmt.Errorf("%w: %s in experiment %s", ErrVMNotFound, vmName, expName)
+	}
+
+	if err := experiment.Annotate(expName, map[string]string{noteAnnotation(vmName): note}); err != nil {
+		return fmt.Errorf("saving note for VM %s: %w", vmName, err)
+	}
+
+	return nil
+}
+
+func noteAnnotation(vmName string) string {
+	return "note/" + vmName
+}
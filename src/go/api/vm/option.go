@@ -30,6 +30,14 @@ func newUpdateOptions(opts ...UpdateOption) updateOptions {
 	return o
 }
 
+// offlineChanges returns whether any settings other than an interface
+// connection are being updated, which can only be done while the experiment
+// isn't running.
+func (this updateOptions) offlineChanges() bool {
+	return this.cpu != 0 || this.mem != 0 || this.disk != "" || this.partition != 0 ||
+		this.dnb != nil || this.host != nil || this.snapshot != nil
+}
+
 func UpdateExperiment(e string) UpdateOption {
 	return func(o *updateOptions) {
 		o.exp = e
@@ -100,6 +108,7 @@ type redeployOptions struct {
 	disk   string
 	inject bool
 	part   int
+	clean  bool
 }
 
 func newRedeployOptions(opts ...RedeployOption) redeployOptions {
@@ -155,3 +164,13 @@ func InjectPartition(p int) RedeployOption {
 		o.part = p
 	}
 }
+
+// Clean sets whether the redeployed VM will get a clean disk snapshot of the
+// image configured for it in the experiment topology, with the topology's file
+// injections replicated, discarding any changes made to its current disk. If
+// true, `Disk`, `Inject`, and `InjectPartition` are ignored.
+func Clean(c bool) RedeployOption {
+	return func(o *redeployOptions) {
+		o.clean = c
+	}
+}
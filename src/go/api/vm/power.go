@@ -0,0 +1,69 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+)
+
+var ErrInvalidPowerAction = errors.New("invalid VM power action")
+
+// Power actions supported by Power.
+const (
+	PowerOn    = "on"
+	PowerOff   = "off"
+	PowerReset = "reset"
+)
+
+// Power changes the power state of the VM with the given name in the given
+// running experiment. The "on" action starts (or resumes) the VM, "off" stops it
+// the same way Pause does, and "reset" kills the VM and relaunches it with the
+// same config, the same way Redeploy does when no options are passed. It
+// returns ErrVMNotFound, `experiment.ErrExperimentNotRunning` or
+// ErrInvalidPowerAction (wrapped) if the VM or experiment is in no state to
+// change, or the action isn't supported.
+func Power(expName, vmName, action string) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	switch action {
+	case PowerOn, PowerOff, PowerReset:
+	default:
+		return fmt.Errorf("%w: %s (must be one of %s, %s or %s)", ErrInvalidPowerAction, action, PowerOn, PowerOff, PowerReset)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if exp.Spec.Topology().FindNodeByName(vmName) == nil {
+		return fmt.Errorf("%w: %s in experiment %s", ErrVMNotFound, vmName, expName)
+	}
+
+	if !exp.Running() {
+		return fmt.Errorf("powering %s VM %s in experiment %s: %w", action, vmName, expName, experiment.ErrExperimentNotRunning)
+	}
+
+	defer mm.InvalidateClusterHosts()
+
+	switch action {
+	case PowerOn:
+		if err := mm.StartVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
+			return fmt.Errorf("starting VM %s: %w", vmName, err)
+		}
+	case PowerOff:
+		return Pause(expName, vmName)
+	case PowerReset:
+		return Redeploy(expName, vmName)
+	}
+
+	return nil
+}
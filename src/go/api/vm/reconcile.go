@@ -0,0 +1,147 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// ReconcileReport describes how the VMs phenix tracks for an experiment differ
+// from the VMs minimega actually has in the experiment's namespace, which can
+// happen if phenix's view of the experiment diverges from minimega's (e.g.
+// after a crash).
+type ReconcileReport struct {
+	Experiment string `json:"experiment"`
+	Running    bool   `json:"running"`
+
+	// VMs minimega has that phenix isn't tracking, either because they're not in
+	// the experiment's topology or because the experiment isn't running.
+	Orphaned []string `json:"orphaned"`
+
+	// VMs phenix expects to be launched in the running experiment that minimega
+	// doesn't have.
+	Missing []string `json:"missing"`
+
+	// Set if the report was fixed, with the orphaned VMs that were killed and
+	// the missing VMs that were marked as errored.
+	Fixed  bool     `json:"fixed"`
+	Killed []string `json:"killed,omitempty"`
+	Marked []string `json:"marked,omitempty"`
+}
+
+// Reconcile compares the VMs in the topology and state of the experiment with
+// the given name against the VMs minimega has in the experiment's namespace,
+// reporting orphaned and missing VMs. If fix is true, orphaned VMs are killed
+// and missing VMs are marked as errored, so they're listed with an `ERROR`
+// state until they're launched again.
+func Reconcile(expName string, fix bool) (*ReconcileReport, error) {
+	if expName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	var (
+		running = exp.Running()
+		tracked = make(map[string]struct{})
+		actual  = make(map[string]struct{})
+	)
+
+	// Only VMs that are actually launched are tracked, and only while the
+	// experiment is running.
+	if running {
+		for _, node := range exp.Spec.Topology().Nodes() {
+			if node.External() {
+				continue
+			}
+
+			if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+				continue
+			}
+
+			tracked[node.General().Hostname()] = struct{}{}
+		}
+	}
+
+	for _, vm := range mm.GetVMInfo(mm.NS(expName)) {
+		actual[vm.Name] = struct{}{}
+	}
+
+	report := &ReconcileReport{
+		Experiment: expName,
+		Running:    running,
+		Orphaned:   []string{},
+		Missing:    []string{},
+	}
+
+	for name := range actual {
+		if _, ok := tracked[name]; !ok {
+			report.Orphaned = append(report.Orphaned, name)
+		}
+	}
+
+	for name := range tracked {
+		if _, ok := actual[name]; !ok {
+			report.Missing = append(report.Missing, name)
+		}
+	}
+
+	sort.Strings(report.Orphaned)
+	sort.Strings(report.Missing)
+
+	if !fix {
+		return report, nil
+	}
+
+	report.Fixed = true
+
+	// Orphaned VMs are killed, which changes what's committed on their hosts.
+	defer mm.InvalidateClusterHosts()
+
+	var errs error
+
+	for _, name := range report.Orphaned {
+		if err := mm.KillVM(mm.NS(expName), mm.VMName(name)); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("killing orphaned VM %s: %w", name, err))
+			continue
+		}
+
+		report.Killed = append(report.Killed, name)
+	}
+
+	annotations := make(map[string]string)
+
+	for _, name := range report.Missing {
+		annotations[errorAnnotation(name)] = "VM not found in minimega"
+		report.Marked = append(report.Marked, name)
+	}
+
+	// VMs marked as errored by an earlier fix that are back are unmarked.
+	for name := range actual {
+		if _, ok := exp.Metadata.Annotations[errorAnnotation(name)]; ok {
+			annotations[errorAnnotation(name)] = ""
+		}
+	}
+
+	if len(annotations) > 0 {
+		if err := experiment.Annotate(expName, annotations); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("marking missing VMs as errored: %w", err))
+			report.Marked = nil
+		}
+	}
+
+	return report, errs
+}
+
+// errorAnnotation is the experiment annotation a VM is marked as errored with
+// when it's missing from minimega.
+func errorAnnotation(vmName string) string {
+	return experiment.VMErrorAnnotationPrefix + vmName
+}
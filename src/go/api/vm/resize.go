@@ -0,0 +1,213 @@
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"phenix/util/mm"
+	"phenix/util/mm/mmcli"
+)
+
+// ErrHotplugUnsupported is returned (wrapped) by Resize when the VM cannot have
+// vCPUs or memory hotplugged, either because QEMU wasn't launched with
+// hotpluggable slots or because the guest doesn't support it.
+var ErrHotplugUnsupported = errors.New("hotplug not supported")
+
+type qmpResponse struct {
+	Return json.RawMessage `json:"return"`
+	Error  *struct {
+		Class string `json:"class"`
+		Desc  string `json:"desc"`
+	} `json:"error"`
+}
+
+type hotpluggableCPU struct {
+	Type       string         `json:"type"`
+	VCPUsCount int            `json:"vcpus-count"`
+	Props      map[string]any `json:"props"`
+	QOMPath    string         `json:"qom-path"`
+}
+
+// Resize hotplugs vCPUs and/or memory into the running VM with the given name
+// in the experiment with the given name. A value of 0 for cpus or memMB leaves
+// that setting as-is. Only growing a VM is supported. It returns an error
+// naming the VM if it isn't running or doesn't support the requested hotplug.
+func Resize(expName, vmName string, cpus, memMB int) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	state, err := mm.GetVMState(mm.NS(expName), mm.VMName(vmName))
+	if err != nil {
+		return fmt.Errorf("retrieving state for VM %s in experiment %s: %w", vmName, expName, err)
+	}
+
+	if state != "RUNNING" {
+		return fmt.Errorf("VM %s in experiment %s is not running", vmName, expName)
+	}
+
+	// minimega keeps reporting the vCPUs and memory the VM was launched with, so
+	// what's already been hotplugged has to come from QEMU itself.
+	currentCPUs, currentMem, err := liveResources(expName, vmName)
+	if err != nil {
+		return err
+	}
+
+	if cpus != 0 && cpus < currentCPUs {
+		return fmt.Errorf("cannot remove vCPUs from running VM %s (has %d, requested %d)", vmName, currentCPUs, cpus)
+	}
+
+	if memMB != 0 && memMB < currentMem {
+		return fmt.Errorf("cannot remove memory from running VM %s (has %d MB, requested %d MB)", vmName, currentMem, memMB)
+	}
+
+	if cpus > currentCPUs {
+		if err := hotplugCPUs(expName, vmName, cpus-currentCPUs); err != nil {
+			return err
+		}
+	}
+
+	if memMB > currentMem {
+		if err := hotplugMemory(expName, vmName, memMB-currentMem); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// liveResources returns the number of vCPUs and the memory (in MB) the running
+// VM with the given name currently has according to QEMU, including any that
+// have been hotplugged.
+func liveResources(expName, vmName string) (int, int, error) {
+	res, err := qmp(expName, vmName, `{ "execute": "query-cpus-fast" }`)
+	if err != nil {
+		return 0, 0, fmt.Errorf("querying vCPUs for VM %s: %w", vmName, err)
+	}
+
+	var cpus []json.RawMessage
+
+	if err := json.Unmarshal(res, &cpus); err != nil {
+		return 0, 0, fmt.Errorf("parsing vCPUs for VM %s: %w", vmName, err)
+	}
+
+	if res, err = qmp(expName, vmName, `{ "execute": "query-memory-size-summary" }`); err != nil {
+		return 0, 0, fmt.Errorf("querying memory for VM %s: %w", vmName, err)
+	}
+
+	var mem struct {
+		Base    int64 `json:"base-memory"`
+		Plugged int64 `json:"plugged-memory"`
+	}
+
+	if err := json.Unmarshal(res, &mem); err != nil {
+		return 0, 0, fmt.Errorf("parsing memory for VM %s: %w", vmName, err)
+	}
+
+	return len(cpus), int((mem.Base + mem.Plugged) / (1024 * 1024)), nil
+}
+
+func hotplugCPUs(expName, vmName string, count int) error {
+	res, err := qmp(expName, vmName, `{ "execute": "query-hotpluggable-cpus" }`)
+	if err != nil {
+		return fmt.Errorf("VM %s: %w: querying hotpluggable vCPUs: %v", vmName, ErrHotplugUnsupported, err)
+	}
+
+	var slots []hotpluggableCPU
+
+	if err := json.Unmarshal(res, &slots); err != nil {
+		return fmt.Errorf("parsing hotpluggable vCPUs for VM %s: %w", vmName, err)
+	}
+
+	var free []hotpluggableCPU
+
+	// Slots already in use have a QOM path; QEMU lists them in reverse order, so
+	// walk backwards to fill the lowest slots first.
+	for i := len(slots) - 1; i >= 0; i-- {
+		if slots[i].QOMPath == "" {
+			free = append(free, slots[i])
+		}
+	}
+
+	if len(free) < count {
+		return fmt.Errorf("VM %s: %w: %d vCPU slots available, %d requested", vmName, ErrHotplugUnsupported, len(free), count)
+	}
+
+	for i, slot := range free[:count] {
+		args := map[string]any{"driver": slot.Type, "id": fmt.Sprintf("phenix-cpu-%d-%d", time.Now().UnixNano(), i)}
+
+		for k, v := range slot.Props {
+			args[k] = v
+		}
+
+		body, _ := json.Marshal(map[string]any{"execute": "device_add", "arguments": args})
+
+		if _, err := qmp(expName, vmName, string(body)); err != nil {
+			return fmt.Errorf("VM %s: %w: adding vCPU: %v", vmName, ErrHotplugUnsupported, err)
+		}
+	}
+
+	return nil
+}
+
+func hotplugMemory(expName, vmName string, memMB int) error {
+	var (
+		suffix = time.Now().UnixNano()
+		memdev = fmt.Sprintf("phenix-mem-%d", suffix)
+		dimm   = fmt.Sprintf("phenix-dimm-%d", suffix)
+	)
+
+	body, _ := json.Marshal(map[string]any{
+		"execute":   "object_add",
+		"arguments": map[string]any{"qom-type": "memory-backend-ram", "id": memdev, "size": memMB * 1024 * 1024},
+	})
+
+	if _, err := qmp(expName, vmName, string(body)); err != nil {
+		return fmt.Errorf("VM %s: %w: adding memory backend: %v", vmName, ErrHotplugUnsupported, err)
+	}
+
+	body, _ = json.Marshal(map[string]any{
+		"execute":   "device_add",
+		"arguments": map[string]any{"driver": "pc-dimm", "id": dimm, "memdev": memdev},
+	})
+
+	if _, err := qmp(expName, vmName, string(body)); err != nil {
+		// Don't leave the unused memory backend lying around.
+		cleanup, _ := json.Marshal(map[string]any{"execute": "object-del", "arguments": map[string]any{"id": memdev}})
+		qmp(expName, vmName, string(cleanup))
+
+		return fmt.Errorf("VM %s: %w: adding memory: %v", vmName, ErrHotplugUnsupported, err)
+	}
+
+	return nil
+}
+
+// qmp sends the given QMP command to the VM via minimega, returning the
+// command's `return` value or an error if minimega or QEMU reported one.
+func qmp(expName, vmName, command string) (json.RawMessage, error) {
+	cmd := mmcli.NewNamespacedCommand(expName)
+	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vmName, command)
+
+	res, err := mmcli.SingleResponse(mmcli.Run(cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	var resp qmpResponse
+
+	if err := json.Unmarshal([]byte(res), &resp); err != nil {
+		return nil, fmt.Errorf("parsing QMP response: %w", err)
+	}
+
+	if resp.Error != nil {
+		return nil, fmt.Errorf("%s: %s", resp.Error.Class, resp.Error.Desc)
+	}
+
+	return resp.Return, nil
+}
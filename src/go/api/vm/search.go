@@ -1,6 +1,14 @@
 package vm
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"phenix/util/mm"
+)
 
 type TopologySearch struct {
 	Hostname   map[string]int   `json:"hostname"`
@@ -78,3 +86,242 @@ func (this *TopologySearch) AddIP(k string, n int) {
 
 	this.IP[k] = append(this.IP[k], n)
 }
+
+var ErrInvalidSearch = errors.New("invalid VM search")
+
+// Search fields supported in field-scoped search terms, like `ip:10.0.0.0/24`.
+var searchFields = []string{"name", "ip", "tag", "host", "note"}
+
+// How much each kind of match adds to a VM's search score. Exact matches rank
+// above partial ones, and matches on names rank above matches on anything else.
+const (
+	scoreNameExact  = 100
+	scoreNamePrefix = 50
+	scoreName       = 20
+	scoreIPExact    = 40
+	scoreIPNetwork  = 30
+	scoreIP         = 10
+	scoreTagExact   = 30
+	scoreTag        = 10
+	scoreHostExact  = 20
+	scoreHost       = 5
+	scoreNote       = 5
+)
+
+// SearchResult is a VM matching a search, along with how well it matched and
+// which of its fields matched.
+type SearchResult struct {
+	VM      mm.VM    `json:"vm"`
+	Score   int      `json:"score"`
+	Matched []string `json:"matched"`
+}
+
+type searchTerm struct {
+	field string // empty if the term isn't field-scoped
+	value string
+}
+
+// Search returns the VMs in the experiment with the given name matching the
+// given query, best matches first. The query is a whitespace-separated list of
+// terms, all of which must match for a VM to be included. Terms are matched
+// (case-insensitively) against VM names, IPs, tags, the cluster hosts VMs are
+// running on, and notes, unless scoped to one of those fields using one of
+// `name:<text>`, `ip:<ip|cidr|text>`, `tag:<key>[=<value>]`, `host:<text>` or
+// `note:<text>`. An empty query matches all VMs.
+func Search(expName, query string) ([]SearchResult, error) {
+	terms, err := parseSearch(query)
+	if err != nil {
+		return nil, err
+	}
+
+	vms, err := List(expName)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []SearchResult{}
+
+	for _, vm := range vms {
+		if result, ok := searchVM(vm, terms); ok {
+			results = append(results, result)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Score == results[j].Score {
+			return results[i].VM.Name < results[j].VM.Name
+		}
+
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+func parseSearch(query string) ([]searchTerm, error) {
+	var terms []searchTerm
+
+	for _, term := range strings.Fields(query) {
+		field, value, scoped := strings.Cut(term, ":")
+
+		if !scoped {
+			terms = append(terms, searchTerm{value: strings.ToLower(term)})
+			continue
+		}
+
+		if !validSearchField(field) {
+			return nil, fmt.Errorf("%w: unknown field %s in term %s (must be one of %s)", ErrInvalidSearch, field, term, strings.Join(searchFields, ", "))
+		}
+
+		if value == "" {
+			return nil, fmt.Errorf("%w: missing value in term %s", ErrInvalidSearch, term)
+		}
+
+		if field == "ip" && strings.Contains(value, "/") {
+			if _, _, err := net.ParseCIDR(value); err != nil {
+				return nil, fmt.Errorf("%w: invalid network in term %s: %v", ErrInvalidSearch, term, err)
+			}
+		}
+
+		terms = append(terms, searchTerm{field: field, value: strings.ToLower(value)})
+	}
+
+	return terms, nil
+}
+
+func validSearchField(field string) bool {
+	for _, f := range searchFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+// searchVM scores the given VM against each of the given terms, returning false
+// if any of them don't match.
+func searchVM(vm mm.VM, terms []searchTerm) (SearchResult, bool) {
+	var (
+		result  = SearchResult{VM: vm, Matched: []string{}}
+		matched = make(map[string]bool)
+	)
+
+	for _, term := range terms {
+		var termScore int
+
+		for _, field := range searchFields {
+			if term.field != "" && term.field != field {
+				continue
+			}
+
+			if score := searchField(vm, field, term.value); score > 0 {
+				termScore += score
+				matched[field] = true
+			}
+		}
+
+		if termScore == 0 {
+			return result, false
+		}
+
+		result.Score += termScore
+	}
+
+	for _, field := range searchFields {
+		if matched[field] {
+			result.Matched = append(result.Matched, field)
+		}
+	}
+
+	return result, true
+}
+
+// searchField returns how well the given (lowercase) value matches the given
+// field of the given VM, or zero if it doesn't match.
+func searchField(vm mm.VM, field, value string) int {
+	switch field {
+	case "name":
+		name := strings.ToLower(vm.Name)
+
+		switch {
+		case name == value:
+			return scoreNameExact
+		case strings.HasPrefix(name, value):
+			return scoreNamePrefix
+		case strings.Contains(name, value):
+			return scoreName
+		}
+	case "ip":
+		_, network, _ := net.ParseCIDR(value)
+
+		var best int
+
+		for _, addr := range vm.IPv4 {
+			// Addresses may include a netmask.
+			addr, _, _ = strings.Cut(addr, "/")
+
+			var score int
+
+			switch {
+			case addr == value:
+				score = scoreIPExact
+			case network != nil:
+				if ip := net.ParseIP(addr); ip != nil && network.Contains(ip) {
+					score = scoreIPNetwork
+				}
+			case strings.Contains(addr, value):
+				score = scoreIP
+			}
+
+			if score > best {
+				best = score
+			}
+		}
+
+		return best
+	case "tag":
+		key, val, hasValue := strings.Cut(value, "=")
+
+		var best int
+
+		for k, v := range vmTags(vm) {
+			k, v = strings.ToLower(k), strings.ToLower(v)
+
+			var score int
+
+			switch {
+			case hasValue && k == key && v == val:
+				score = scoreTagExact
+			case hasValue:
+				// Both the key and value have to match when searching for a value.
+			case k == key || v == key:
+				score = scoreTagExact
+			case strings.Contains(k, key) || strings.Contains(v, key):
+				score = scoreTag
+			}
+
+			if score > best {
+				best = score
+			}
+		}
+
+		return best
+	case "host":
+		host := strings.ToLower(vm.Host)
+
+		switch {
+		case host == "":
+		case host == value:
+			return scoreHostExact
+		case strings.Contains(host, value):
+			return scoreHost
+		}
+	case "note":
+		if strings.Contains(strings.ToLower(vm.Note), value) {
+			return scoreNote
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,90 @@
+package vm
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"phenix/util/mm"
+)
+
+func TestSearchVM(t *testing.T) {
+	vm := mm.VM{
+		Name: "web-server",
+		Host: "compute1",
+		IPv4: []string{"10.0.0.5/24", "192.168.1.10"},
+		Tags: []string{"role:frontend", "site:east"},
+		Note: "Patched last week",
+	}
+
+	cases := map[string]struct {
+		query   string
+		score   int
+		matched []string
+		err     error
+		miss    bool
+	}{
+		"empty":        {query: "", score: 0, matched: []string{}},
+		"name exact":   {query: "web-server", score: scoreNameExact, matched: []string{"name"}},
+		"name prefix":  {query: "name:WEB", score: scoreNamePrefix, matched: []string{"name"}},
+		"name":         {query: "name:server", score: scoreName, matched: []string{"name"}},
+		"ip exact":     {query: "ip:10.0.0.5", score: scoreIPExact, matched: []string{"ip"}},
+		"ip network":   {query: "ip:192.168.0.0/16", score: scoreIPNetwork, matched: []string{"ip"}},
+		"ip partial":   {query: "ip:168.1", score: scoreIP, matched: []string{"ip"}},
+		"ip best":      {query: "ip:10.0.0.0/8", score: scoreIPNetwork, matched: []string{"ip"}},
+		"tag key":      {query: "tag:role", score: scoreTagExact, matched: []string{"tag"}},
+		"tag value":    {query: "tag:role=frontend", score: scoreTagExact, matched: []string{"tag"}},
+		"tag partial":  {query: "tag:front", score: scoreTag, matched: []string{"tag"}},
+		"tag mismatch": {query: "tag:role=backend", miss: true},
+		"host exact":   {query: "host:compute1", score: scoreHostExact, matched: []string{"host"}},
+		"host":         {query: "host:compute", score: scoreHost, matched: []string{"host"}},
+		"note":         {query: "note:patched", score: scoreNote, matched: []string{"note"}},
+		"unscoped":     {query: "east", score: scoreTagExact, matched: []string{"tag"}},
+		// "compute" partially matches the host, but nothing else.
+		"all terms":     {query: "web compute", score: scoreNamePrefix + scoreHost, matched: []string{"name", "host"}},
+		"one term miss": {query: "web database", miss: true},
+		"unknown field": {query: "os:linux", err: ErrInvalidSearch},
+		"missing value": {query: "name:", err: ErrInvalidSearch},
+		"bad network":   {query: "ip:10.0.0.0/33", err: ErrInvalidSearch},
+	}
+
+	for name, tc := range cases {
+		terms, err := parseSearch(tc.query)
+
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("%s: expected error %v, got %v", name, tc.err, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", name, err)
+			continue
+		}
+
+		result, ok := searchVM(vm, terms)
+
+		if tc.miss {
+			if ok {
+				t.Errorf("%s: expected no match, got score %d", name, result.Score)
+			}
+
+			continue
+		}
+
+		if !ok {
+			t.Errorf("%s: expected match", name)
+			continue
+		}
+
+		if result.Score != tc.score {
+			t.Errorf("%s: expected score %d, got %d", name, tc.score, result.Score)
+		}
+
+		if !reflect.DeepEqual(result.Matched, tc.matched) {
+			t.Errorf("%s: expected matched fields %v, got %v", name, tc.matched, result.Matched)
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package vm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/file"
+	"phenix/util/plog"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+var (
+	ErrStateSnapshotExists   = errors.New("experiment snapshot already exists")
+	ErrStateSnapshotNotFound = errors.New("experiment snapshot not found")
+	ErrInvalidSnapshotLabel  = errors.New("invalid experiment snapshot label")
+)
+
+// Prefix of the experiment annotations holding the metadata for each of its
+// state snapshots, keyed by the snapshot's label.
+const stateSnapshotAnnotationPrefix = "stateSnapshot/"
+
+var snapshotLabelRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// StateSnapshot describes a snapshot of the memory and disk state of every
+// running VM in an experiment, taken with SnapshotExperiment.
+type StateSnapshot struct {
+	Label   string `json:"label"`
+	Created string `json:"created"`
+
+	// Total size (in bytes) of the snapshot files for all the VMs.
+	Size int64 `json:"size"`
+
+	VMs map[string]VMStateSnapshot `json:"vms"`
+
+	// VMs that couldn't be snapshotted, along with why.
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// VMStateSnapshot describes the snapshot of a single VM that's part of an
+// experiment snapshot.
+type VMStateSnapshot struct {
+	// Name of the VM snapshot in the experiment's files directory (see
+	// Snapshots).
+	Snapshot string `json:"snapshot"`
+
+	// Cluster host the VM was running on when it was snapshotted. The VM is
+	// relaunched on the same host when the snapshot is restored.
+	Host string `json:"host"`
+
+	// Size (in bytes) of the VM's memory and disk snapshot files.
+	Size int64 `json:"size"`
+}
+
+// SnapshotExperiment snapshots the memory and disk state of every running VM in
+// the given running experiment, the same way Snapshot does for individual VMs,
+// and records them as an experiment snapshot with the given label. VMs are
+// snapshotted one at a time, each being paused while its memory is saved. VMs
+// that fail to be snapshotted are recorded in the snapshot's errors, but an
+// error is only returned if none could be snapshotted.
+func SnapshotExperiment(expName, label string) (*StateSnapshot, error) {
+	if !snapshotLabelRegex.MatchString(label) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidSnapshotLabel, label)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("snapshotting experiment %s: %w", expName, experiment.ErrExperimentNotRunning)
+	}
+
+	if exp.DryRun() {
+		return nil, fmt.Errorf("cannot snapshot dry-run experiment %s", expName)
+	}
+
+	if _, ok := exp.Metadata.Annotations[stateSnapshotAnnotationPrefix+label]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrStateSnapshotExists, label)
+	}
+
+	vms, err := List(expName)
+	if err != nil {
+		return nil, fmt.Errorf("listing VMs in experiment %s: %w", expName, err)
+	}
+
+	snap := &StateSnapshot{
+		Label:   label,
+		Created: time.Now().UTC().Format(time.RFC3339),
+		VMs:     make(map[string]VMStateSnapshot),
+		Errors:  make(map[string]string),
+	}
+
+	for _, vm := range vms {
+		if !vm.Running {
+			continue
+		}
+
+		if err := Snapshot(expName, vm.Name, label, nil); err != nil {
+			plog.Warn("unable to snapshot VM for experiment snapshot", "exp", expName, "vm", vm.Name, "snapshot", label, "err", err)
+
+			snap.Errors[vm.Name] = err.Error()
+			continue
+		}
+
+		snap.VMs[vm.Name] = VMStateSnapshot{Snapshot: fmt.Sprintf("%s__%s", vm.Name, label), Host: vm.Host}
+	}
+
+	if len(snap.VMs) == 0 {
+		if len(snap.Errors) == 0 {
+			return nil, fmt.Errorf("no running VMs to snapshot in experiment %s", expName)
+		}
+
+		return nil, fmt.Errorf("unable to snapshot any VMs in experiment %s", expName)
+	}
+
+	if files, err := file.GetExperimentFiles(expName, ""); err == nil {
+		for name, vs := range snap.VMs {
+			for _, f := range files {
+				if f.Name == vs.Snapshot+".SNAP" || f.Name == vs.Snapshot+".qc2" {
+					vs.Size += f.Size
+				}
+			}
+
+			snap.VMs[name] = vs
+			snap.Size += vs.Size
+		}
+	} else {
+		plog.Warn("getting experiment snapshot file sizes", "exp", expName, "snapshot", label, "err", err)
+	}
+
+	body, err := json.Marshal(snap)
+	if err != nil {
+		return nil, fmt.Errorf("encoding experiment snapshot: %w", err)
+	}
+
+	if err := experiment.Annotate(expName, map[string]string{stateSnapshotAnnotationPrefix + label: string(body)}); err != nil {
+		return nil, fmt.Errorf("saving experiment snapshot: %w", err)
+	}
+
+	return snap, nil
+}
+
+// ExperimentSnapshots returns the snapshots taken for the given experiment,
+// newest first.
+func ExperimentSnapshots(expName string) ([]StateSnapshot, error) {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	snaps := []StateSnapshot{}
+
+	for k, v := range exp.Metadata.Annotations {
+		if !strings.HasPrefix(k, stateSnapshotAnnotationPrefix) {
+			continue
+		}
+
+		var snap StateSnapshot
+
+		if err := json.Unmarshal([]byte(v), &snap); err != nil {
+			plog.Warn("decoding experiment snapshot", "exp", expName, "snapshot", k, "err", err)
+			continue
+		}
+
+		snaps = append(snaps, snap)
+	}
+
+	sort.Slice(snaps, func(i, j int) bool {
+		if snaps[i].Created == snaps[j].Created {
+			return snaps[i].Label < snaps[j].Label
+		}
+
+		return snaps[i].Created > snaps[j].Created
+	})
+
+	return snaps, nil
+}
+
+// RestoreExperiment relaunches each VM recorded in the given experiment's
+// snapshot with the given label from its memory and disk snapshot, the same
+// way Restore does for individual VMs. The experiment must be running, and not
+// as a dry run. Each VM
+// is relaunched on the cluster host it was running on when the snapshot was
+// taken, and the experiment's schedule is updated to match, so VMs land on
+// hosts compatible with their saved state. VMs that fail to be restored don't
+// stop the remaining VMs from being restored.
+func RestoreExperiment(expName, label string) error {
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	if !exp.Running() {
+		return fmt.Errorf("restoring experiment %s: %w", expName, experiment.ErrExperimentNotRunning)
+	}
+
+	// Dry runs don't have any VMs in minimega to relaunch.
+	if exp.DryRun() {
+		return fmt.Errorf("cannot restore dry-run experiment %s", expName)
+	}
+
+	body, ok := exp.Metadata.Annotations[stateSnapshotAnnotationPrefix+label]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrStateSnapshotNotFound, label)
+	}
+
+	var snap StateSnapshot
+
+	if err := json.Unmarshal([]byte(body), &snap); err != nil {
+		return fmt.Errorf("decoding experiment snapshot %s: %w", label, err)
+	}
+
+	available, err := file.GetExperimentSnapshots(expName)
+	if err != nil {
+		return fmt.Errorf("getting list of experiment snapshots: %w", err)
+	}
+
+	found := make(map[string]bool)
+
+	for _, ss := range available {
+		found[ss] = true
+	}
+
+	var errs error
+
+	for name, vs := range snap.VMs {
+		if !found[vs.Snapshot] {
+			errs = multierror.Append(errs, fmt.Errorf("snapshot for VM %s does not exist on cluster", name))
+			continue
+		}
+
+		if err := relaunchFromSnapshot(expName, name, vs.Snapshot, vs.Host); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("restoring VM %s: %w", name, err))
+			continue
+		}
+
+		if err := setSchedule(expName, name, vs.Host); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("updating schedule for VM %s: %w", name, err))
+		}
+	}
+
+	return errs
+}
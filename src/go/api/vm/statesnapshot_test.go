@@ -0,0 +1,60 @@
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"phenix/api/experiment"
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestRestoreExperimentChecks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(orig store.Store) { store.DefaultStore = orig }(store.DefaultStore)
+
+	m := store.NewMockStore(ctrl)
+	store.DefaultStore = m
+
+	expect := func(startTime string) {
+		m.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+			c.Version = "phenix.sandia.gov/v1"
+			c.Kind = "Experiment"
+			c.Metadata.Annotations = map[string]string{stateSnapshotAnnotationPrefix + "snap": `{"label": "snap"}`}
+			c.Spec = map[string]any{"experimentName": "test-experiment", "topology": map[string]any{"nodes": []any{}}}
+			c.Status = map[string]any{"startTime": startTime}
+
+			return nil
+		})
+	}
+
+	expect("")
+
+	if err := RestoreExperiment("test-experiment", "snap"); !errors.Is(err, experiment.ErrExperimentNotRunning) {
+		t.Fatalf("expected stopped experiment to not be restored, got %v", err)
+	}
+
+	expect("2024-01-01T00:00:00Z-DRYRUN")
+
+	if err := RestoreExperiment("test-experiment", "snap"); err == nil || !strings.Contains(err.Error(), "dry-run") {
+		t.Fatalf("expected dry-run experiment to not be restored, got %v", err)
+	}
+
+	expect("2024-01-01T00:00:00Z")
+
+	if err := RestoreExperiment("test-experiment", "missing"); !errors.Is(err, ErrStateSnapshotNotFound) {
+		t.Fatalf("expected missing snapshot to not be restored, got %v", err)
+	}
+}
+
+func TestSnapshotExperimentLabel(t *testing.T) {
+	for _, label := range []string{"", "-snap", "snap shot", "../snap"} {
+		if _, err := SnapshotExperiment("test-experiment", label); !errors.Is(err, ErrInvalidSnapshotLabel) {
+			t.Errorf("label %q: expected ErrInvalidSnapshotLabel, got %v", label, err)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+)
+
+var (
+	ErrVMNotFound        = errors.New("VM not found")
+	ErrTagNotFound       = errors.New("tag not found")
+	ErrInvalidTag        = errors.New("invalid tag")
+	ErrInterfaceNotFound = errors.New("interface not found")
+)
+
+// SetTags sets the given tags on the VM with the given name in the experiment
+// with the given name, replacing the values of any tags already set. Tags are
+// saved as labels on the VM's node in the experiment's topology, which become
+// minimega tags when the VM is launched. If the VM is already running, the tags
+// are set in minimega too.
+func SetTags(expName, vmName string, tags map[string]string) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	for k, v := range tags {
+		if err := validateTag(k, v); err != nil {
+			return err
+		}
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node == nil {
+		return fmt.Errorf("%w: %s in experiment %s", ErrVMNotFound, vmName, expName)
+	}
+
+	if launched(expName, vmName) {
+		for _, k := range sortedTagKeys(tags) {
+			if err := mm.SetVMTag(expName, vmName, k, tags[k]); err != nil {
+				return fmt.Errorf("setting tag %s on VM %s: %w", k, vmName, err)
+			}
+		}
+	}
+
+	for k, v := range tags {
+		node.AddLabel(k, v)
+	}
+
+	if err := experiment.Save(experiment.SaveWithName(expName), experiment.SaveWithSpec(exp.Spec)); err != nil {
+		return fmt.Errorf("saving tags for VM %s: %w", vmName, err)
+	}
+
+	return nil
+}
+
+// DeleteTag removes the tag with the given key from the VM with the given name in
+// the experiment with the given name, both from the VM's node in the
+// experiment's topology and, if the VM is running, from minimega.
+func DeleteTag(expName, vmName, key string) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node == nil {
+		return fmt.Errorf("%w: %s in experiment %s", ErrVMNotFound, vmName, expName)
+	}
+
+	_, labeled := node.Labels()[key]
+
+	var tagged bool
+
+	if launched(expName, vmName) {
+		for _, details := range mm.GetVMInfo(mm.NS(expName), mm.VMName(vmName)) {
+			_, tagged = vmTags(details)[key]
+		}
+
+		if tagged {
+			if err := mm.ClearVMTag(expName, vmName, key); err != nil {
+				return fmt.Errorf("clearing tag %s on VM %s: %w", key, vmName, err)
+			}
+		}
+	}
+
+	if !labeled && !tagged {
+		return fmt.Errorf("%w: %s on VM %s", ErrTagNotFound, key, vmName)
+	}
+
+	if !labeled {
+		return nil
+	}
+
+	node.RemoveLabel(key)
+
+	if err := experiment.Save(experiment.SaveWithName(expName), experiment.SaveWithSpec(exp.Spec)); err != nil {
+		return fmt.Errorf("saving tags for VM %s: %w", vmName, err)
+	}
+
+	return nil
+}
+
+// reconcileTags adds any of the given topology labels missing from the given
+// tags reported by minimega for a running VM, so tags saved while the VM was
+// running aren't lost if minimega doesn't report them (e.g. if setting them in
+// minimega failed or the VM was relaunched before they were).
+func reconcileTags(labels map[string]string, tags []string) []string {
+	current := vmTags(mm.VM{Tags: tags})
+
+	for _, k := range sortedTagKeys(labels) {
+		if _, ok := current[k]; !ok {
+			tags = append(tags, fmt.Sprintf("%q:%q", k, labels[k]))
+		}
+	}
+
+	return tags
+}
+
+// launched returns true if the VM with the given name has been launched in
+// minimega for the experiment with the given name.
+func launched(expName, vmName string) bool {
+	if !experiment.Running(expName) {
+		return false
+	}
+
+	return len(mm.GetVMInfo(mm.NS(expName), mm.VMName(vmName))) > 0
+}
+
+func validateTag(k, v string) error {
+	if k == "" {
+		return fmt.Errorf("%w: missing tag key", ErrInvalidTag)
+	}
+
+	if strings.ContainsAny(k, " \t\n\"") || strings.ContainsAny(v, " \t\n\"") {
+		return fmt.Errorf("%w: tag %s cannot contain whitespace or quotes", ErrInvalidTag, k)
+	}
+
+	return nil
+}
+
+func sortedTagKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -255,3 +255,71 @@ func getTimestamp() string {
 
 	return time.Now().Format("20060102_1500")
 }
+
+// parseVMFilter parses a VM filter expression (see ListWithFilter) into a
+// function that reports whether a VM matches all of the filter's clauses.
+func parseVMFilter(filter string) (func(mm.VM) bool, error) {
+	var clauses []func(mm.VM) bool
+
+	for _, clause := range strings.Split(filter, ",") {
+		clause = strings.TrimSpace(clause)
+
+		if clause == "" {
+			continue
+		}
+
+		typ, expr, ok := strings.Cut(clause, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid filter clause %s", clause)
+		}
+
+		switch typ {
+		case "tag":
+			key, value, hasValue := strings.Cut(expr, "=")
+			if key == "" {
+				return nil, fmt.Errorf("missing tag key in filter clause %s", clause)
+			}
+
+			clauses = append(clauses, func(vm mm.VM) bool {
+				v, ok := vmTags(vm)[key]
+				return ok && (!hasValue || v == value)
+			})
+		case "name":
+			if _, err := filepath.Match(expr, ""); err != nil {
+				return nil, fmt.Errorf("invalid name glob in filter clause %s: %w", clause, err)
+			}
+
+			clauses = append(clauses, func(vm mm.VM) bool {
+				matched, _ := filepath.Match(expr, vm.Name)
+				return matched
+			})
+		default:
+			return nil, fmt.Errorf("unknown filter type %s in clause %s", typ, clause)
+		}
+	}
+
+	match := func(vm mm.VM) bool {
+		for _, clause := range clauses {
+			if !clause(vm) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	return match, nil
+}
+
+// vmTags converts the tags reported by minimega for a VM, which look like
+// `"key":"value"`, into a map.
+func vmTags(vm mm.VM) map[string]string {
+	tags := make(map[string]string)
+
+	for _, tag := range vm.Tags {
+		k, v, _ := strings.Cut(tag, ":")
+		tags[strings.Trim(k, `" `)] = strings.Trim(v, `" `)
+	}
+
+	return tags
+}
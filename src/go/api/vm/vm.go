@@ -24,7 +24,16 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-var vlanAliasRegex = regexp.MustCompile(`(.*) \(\d*\)`)
+var (
+	vlanAliasRegex = regexp.MustCompile(`(.*) \(\d*\)`)
+
+	ErrInvalidFilter = errors.New("invalid VM filter")
+	ErrVMRunning     = errors.New("VM settings can't be updated while experiment is running")
+)
+
+// How long to wait for the disk backup taken when snapshotting a VM to complete
+// before giving up.
+var snapshotBackupTimeout = 30 * time.Minute
 
 func Count(expName string) (int, error) {
 	if expName == "" {
@@ -93,6 +102,7 @@ func List(expName string) ([]mm.VM, error) {
 			InjectPartition: injectPartition,
 			Interfaces:      make(map[string]string),
 			DoNotBoot:       dnb,
+			Note:            exp.Metadata.Annotations[noteAnnotation(node.General().Hostname())],
 			Type:            node.Type(),
 			OSType:          node.Hardware().OSType(),
 			Snapshot:        snapshot,
@@ -118,7 +128,7 @@ func List(expName string) ([]mm.VM, error) {
 			vm.IPv4 = details.IPv4
 			vm.Captures = details.Captures
 			vm.CdRom = details.CdRom
-			vm.Tags = details.Tags
+			vm.Tags = reconcileTags(node.Labels(), details.Tags)
 			vm.Uptime = details.Uptime
 			vm.CPUs = details.CPUs
 			vm.RAM = details.RAM
@@ -153,6 +163,11 @@ func List(expName string) ([]mm.VM, error) {
 			}
 		} else {
 			vm.Host = exp.Spec.Schedules()[vm.Name]
+
+			// Missing from minimega and marked as errored when reconciled.
+			if _, ok := exp.Metadata.Annotations[errorAnnotation(vm.Name)]; ok && exp.Running() {
+				vm.State = "ERROR"
+			}
 		}
 
 		vms = append(vms, vm)
@@ -161,6 +176,33 @@ func List(expName string) ([]mm.VM, error) {
 	return vms, nil
 }
 
+// ListWithFilter collects VMs the same way as List, but only returns the VMs
+// matching the given filter. The filter is a comma-separated list of clauses,
+// all of which must match for a VM to be included. Supported clauses are
+// `tag:<key>=<value>` (or `tag:<key>` to only check for the tag's existence)
+// and `name:<glob>`. An empty filter matches all VMs.
+func ListWithFilter(expName, filter string) ([]mm.VM, error) {
+	match, err := parseVMFilter(filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+	}
+
+	vms, err := List(expName)
+	if err != nil {
+		return nil, err
+	}
+
+	var filtered []mm.VM
+
+	for _, vm := range vms {
+		if match(vm) {
+			filtered = append(filtered, vm)
+		}
+	}
+
+	return filtered, nil
+}
+
 // Get retrieves the VM with the given name from the experiment with the given
 // name. If the experiment is running, topology VM settings are combined with
 // running VM details. It returns a pointer to a VM struct, and any errors
@@ -196,6 +238,7 @@ func Get(expName, vmName string) (*mm.VM, error) {
 			InjectPartition: *node.Hardware().Drives()[0].InjectPartition(),
 			Interfaces:      make(map[string]string),
 			DoNotBoot:       *node.General().DoNotBoot(),
+			Note:            exp.Metadata.Annotations[noteAnnotation(vmName)],
 			OSType:          string(node.Hardware().OSType()),
 			Metadata:        make(map[string]interface{}),
 			Labels:          node.Labels(),
@@ -230,6 +273,11 @@ func Get(expName, vmName string) (*mm.VM, error) {
 	details := mm.GetVMInfo(mm.NS(expName), mm.VMName(vmName))
 
 	if len(details) != 1 {
+		// Missing from minimega and marked as errored when reconciled.
+		if _, ok := exp.Metadata.Annotations[errorAnnotation(vmName)]; ok {
+			vm.State = "ERROR"
+		}
+
 		return vm, nil
 	}
 
@@ -241,7 +289,7 @@ func Get(expName, vmName string) (*mm.VM, error) {
 	vm.IPv4 = details[0].IPv4
 	vm.Captures = details[0].Captures
 	vm.CdRom = details[0].CdRom
-	vm.Tags = details[0].Tags
+	vm.Tags = reconcileTags(vm.Labels, details[0].Tags)
 	vm.Uptime = details[0].Uptime
 	vm.CPUs = details[0].CPUs
 	vm.RAM = details[0].RAM
@@ -286,17 +334,19 @@ func Update(opts ...UpdateOption) error {
 
 	running := experiment.Running(o.exp)
 
-	if running && o.iface == nil {
-		return fmt.Errorf("only interface connections can be updated while experiment is running")
+	// Other settings are rejected rather than dropped so callers don't think
+	// they were applied.
+	if running && (o.iface == nil || o.offlineChanges()) {
+		return fmt.Errorf("%w: only interface connections can be updated", ErrVMRunning)
 	}
 
 	// The only setting that can be updated while an experiment is running is the
 	// VLAN an interface is connected to.
 	if running {
 		if o.iface.vlan == "" {
-			return Disonnect(o.exp, o.vm, o.iface.index)
+			return DisconnectInterface(o.exp, o.vm, o.iface.index)
 		} else {
-			return Connect(o.exp, o.vm, o.iface.index, o.iface.vlan)
+			return ConnectInterface(o.exp, o.vm, o.iface.index, o.iface.vlan)
 		}
 	}
 
@@ -401,6 +451,8 @@ func Restart(expName, vmName string) error {
 
 	//Using "system_reset" on a VM that is in the "QUIT" state fails
 	if state == "QUIT" {
+		defer mm.InvalidateClusterHosts()
+
 		return mm.StartVM(mm.NS(expName), mm.VMName(vmName))
 
 	}
@@ -444,6 +496,8 @@ func Shutdown(expName, vmName string) error {
 		return fmt.Errorf("stopping captures for VM %s in experiment %s: %w", vmName, expName, err)
 	}
 
+	defer mm.InvalidateClusterHosts()
+
 	// Send a powerdown signal to the VM using QEMU QMP.
 	cmd := mmcli.NewNamespacedCommand(expName)
 	qmp := `{ "execute": "system_powerdown" }`
@@ -593,9 +647,13 @@ func Resume(expName, vmName string) error {
 }
 
 // Redeploy redeploys a VM with the given name in the experiment with the given
-// name. Multiple redeploy options can be passed to alter the resulting
-// redeployed VM, such as CPU, memory, and disk options. It returns any errors
-// encountered while redeploying the VM.
+// name, killing and relaunching just that VM. The VM's minimega config is
+// cloned before it's killed, so its VLAN attachments and the cluster host it's
+// scheduled on are preserved. Multiple redeploy options can be passed to alter
+// the resulting redeployed VM, such as CPU, memory, and disk options. It returns
+// any errors encountered while redeploying the VM, including
+// `experiment.ErrExperimentNotRunning` (wrapped) if the experiment isn't
+// running.
 func Redeploy(expName, vmName string, opts ...RedeployOption) error {
 	if expName == "" {
 		return fmt.Errorf("no experiment name provided")
@@ -605,8 +663,21 @@ func Redeploy(expName, vmName string, opts ...RedeployOption) error {
 		return fmt.Errorf("no VM name provided")
 	}
 
+	if !experiment.Running(expName) {
+		return fmt.Errorf("redeploying VM %s in experiment %s: %w", vmName, expName, experiment.ErrExperimentNotRunning)
+	}
+
+	defer mm.InvalidateClusterHosts()
+
 	o := newRedeployOptions(opts...)
 
+	// A clean redeploy recreates the disk snapshot from the topology's image,
+	// which is what injecting does when no disk is provided.
+	if o.clean {
+		o.disk = ""
+		o.inject = true
+	}
+
 	var injects []string
 
 	if o.inject {
@@ -622,7 +693,10 @@ func Redeploy(expName, vmName string, opts ...RedeployOption) error {
 
 			if o.disk == "" {
 				o.disk = n.Hardware().Drives()[0].Image()
-				o.part = *n.Hardware().Drives()[0].InjectPartition()
+
+				if part := n.Hardware().Drives()[0].InjectPartition(); part != nil {
+					o.part = *part
+				}
 			}
 
 			for _, i := range n.Injections() {
@@ -661,6 +735,8 @@ func Kill(expName, vmName string) error {
 		return fmt.Errorf("no VM name provided")
 	}
 
+	defer mm.InvalidateClusterHosts()
+
 	if err := mm.KillVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
 		return fmt.Errorf("killing VM: %w", err)
 	}
@@ -758,6 +834,8 @@ func Snapshot(expName, vmName, out string, cb func(string)) error {
 	qmp = fmt.Sprintf(`{ "execute": "query-block-jobs" }`)
 	cmd.Command = fmt.Sprintf(`vm qmp %s '%s'`, vmName, qmp)
 
+	deadline := time.Now().Add(snapshotBackupTimeout)
+
 	for {
 		res, err := mmcli.SingleResponse(mmcli.Run(cmd))
 		if err != nil {
@@ -765,12 +843,19 @@ func Snapshot(expName, vmName, out string, cb func(string)) error {
 		}
 
 		var v map[string][]mm.BlockDeviceJobs
-		json.Unmarshal([]byte(res), &v)
+
+		if err := json.Unmarshal([]byte(res), &v); err != nil {
+			return fmt.Errorf("parsing block device jobs for VM %s: %w", vmName, err)
+		}
 
 		if len(v["return"]) == 0 {
 			break
 		}
 
+		if time.Now().After(deadline) {
+			return fmt.Errorf("disk snapshot for VM %s not done after %v", vmName, snapshotBackupTimeout)
+		}
+
 		for _, job := range v["return"] {
 			if job.Device != device {
 				continue
@@ -890,6 +975,13 @@ func Restore(expName, vmName, snap string) error {
 		return fmt.Errorf("snapshot does not exist on cluster")
 	}
 
+	return relaunchFromSnapshot(expName, vmName, snap, "")
+}
+
+// relaunchFromSnapshot kills the VM with the given name and relaunches it from
+// the given memory and disk snapshot in the experiment's files directory. If
+// host isn't empty, the relaunched VM is scheduled on it.
+func relaunchFromSnapshot(expName, vmName, snap, host string) error {
 	snap = fmt.Sprintf("%s/files/%s", expName, snap)
 
 	cmd := mmcli.NewNamespacedCommand(expName)
@@ -899,6 +991,14 @@ func Restore(expName, vmName, snap string) error {
 		return fmt.Errorf("cloning config for VM %s: %w", vmName, err)
 	}
 
+	if host != "" {
+		cmd.Command = fmt.Sprintf("vm config schedule %s", host)
+
+		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+			return fmt.Errorf("configuring schedule for VM %s: %w", vmName, err)
+		}
+	}
+
 	cmd.Command = fmt.Sprintf("vm config migrate %s.SNAP", snap)
 
 	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
@@ -1274,7 +1374,7 @@ func MemorySnapshot(expName, vmName, out string, cb func(string)) (string, error
 			if cb != nil {
 				cb("failed")
 			}
-			return "", fmt.Errorf("no status available for %s: %s", vmName, v)
+			return "", fmt.Errorf("no status available for %s: %v", vmName, v)
 
 		}
 
@@ -1282,16 +1382,21 @@ func MemorySnapshot(expName, vmName, out string, cb func(string)) (string, error
 			if cb != nil {
 				cb("failed")
 			}
-			return "failed", fmt.Errorf("failed to create memory snapshot for %s: %s", vmName, v)
+			return "failed", fmt.Errorf("failed to create memory snapshot for %s: %v", vmName, v)
 
 		}
 
 		progress = fmt.Sprintf("%v", float64(v.Return.Completed)/float64(v.Return.Total))
 
-		cb(progress)
+		if cb != nil {
+			cb(progress)
+		}
 
 		if v.Return.Status == "completed" {
-			cb("completed")
+			if cb != nil {
+				cb("completed")
+			}
+
 			break
 		}
 
@@ -1301,12 +1406,7 @@ func MemorySnapshot(expName, vmName, out string, cb func(string)) (string, error
 	// hosted on the headnode
 	if !mm.IsHeadnode(status[0]["host"]) {
 
-		// File path should be relative to the minimega files directory
-		memoryDumpPath := fmt.Sprintf("%s/files/%s", expName, filepath.Base(out))
-
-		cmd.Command = fmt.Sprintf(`file get %s`, memoryDumpPath)
-
-		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		if err := pullFile(out); err != nil {
 			return "", fmt.Errorf("pulling ELF memory snapshot to headnode: %w", err)
 		}
 
@@ -234,7 +234,12 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 	}
 
 	if exp.Spec.Scenario() != nil {
-		for _, app := range exp.Spec.Scenario().Apps() {
+		apps, err := OrderApps(exp.Spec.Scenario().Apps())
+		if err != nil {
+			return fmt.Errorf("ordering user apps for action %s: %w", options.Stage, err)
+		}
+
+		for _, app := range apps {
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
@@ -304,6 +309,7 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 
 				exp.Reload() // reload experiment from store in case status was updated during run
 				exp.Status.SetAppRunning(app.Name(), false)
+				exp.Status.SetAppLastRun(app.Name(), time.Now().Format(time.RFC3339))
 
 				if err := exp.WriteToStore(true); err != nil {
 					notes.AddErrors(ctx, false, fmt.Errorf("error updating store with experiment (%s): %v", exp.Spec.ExperimentName(), err))
@@ -346,10 +352,22 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 
 // PeriodicallyRunApps checks the configuration for each app in the scenario to
 // see if it's configured to have its "running" stage run periodically. A
-// Goroutine is scheduled for each applicable app.
+// Goroutine is scheduled for each applicable app. The first run of each app
+// waits for the first runs of any periodic apps it depends on, so the initial
+// runs happen in the same order as the apps' other stages.
 func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Experiment) error {
 	if exp.Spec.Scenario() != nil {
-		for _, app := range exp.Spec.Scenario().Apps() {
+		apps, err := OrderApps(exp.Spec.Scenario().Apps())
+		if err != nil {
+			return fmt.Errorf("ordering apps to run periodically: %w", err)
+		}
+
+		var (
+			durations = make(map[string]time.Duration)
+			initial   = make(map[string]chan struct{})
+		)
+
+		for _, app := range apps {
 			// Don't consider default apps as candidates for running periodically.
 			if _, ok := defaultApps[app.Name()]; ok {
 				continue
@@ -362,90 +380,169 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 					continue
 				}
 
-				plog.Info("[✓] scheduling 'running' stage for app", "app", app.Name(), "duration", app.RunPeriodically())
+				durations[app.Name()] = duration
+				initial[app.Name()] = make(chan struct{})
+			}
+		}
+
+		for _, app := range apps {
+			duration, ok := durations[app.Name()]
+			if !ok {
+				continue
+			}
+
+			var deps []chan struct{}
+
+			for _, dep := range app.DependsOn() {
+				if done, ok := initial[dep]; ok {
+					deps = append(deps, done)
+				}
+			}
 
-				wg.Add(1)
+			plog.Info("[✓] scheduling 'running' stage for app", "app", app.Name(), "duration", app.RunPeriodically())
 
-				go func(exp *types.Experiment, app ifaces.ScenarioApp, duration time.Duration) {
-					defer wg.Done()
+			wg.Add(1)
 
-					exp.Status.SetAppFrequency(app.Name(), app.RunPeriodically())
+			go func(exp *types.Experiment, app ifaces.ScenarioApp, duration time.Duration, done chan struct{}, deps []chan struct{}) {
+				defer wg.Done()
+
+				// Whether this app's initial run has happened (or been skipped), which
+				// apps depending on it are waiting for.
+				var ran bool
+
+				initialRunDone := func() {
+					if !ran {
+						ran = true
+						close(done)
+					}
+				}
+
+				defer initialRunDone()
+
+				exp.Status.SetAppFrequency(app.Name(), app.RunPeriodically())
+				exp.Status.SetAppRunning(app.Name(), false)
+
+				if err := exp.WriteToStore(true); err != nil {
+					plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
+				}
+
+				stop := func() {
+					exp.Status.SetAppFrequency(app.Name(), "")
 					exp.Status.SetAppRunning(app.Name(), false)
 
 					if err := exp.WriteToStore(true); err != nil {
 						plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
 					}
+				}
 
-					timer := time.NewTimer(duration)
+				timer := time.NewTimer(duration)
 
-					for {
-						select {
-						case <-ctx.Done():
-							if !timer.Stop() {
-								<-timer.C
+				for {
+					select {
+					case <-ctx.Done():
+						if !timer.Stop() {
+							<-timer.C
+						}
+
+						stop()
+
+						return
+					case <-timer.C:
+						if !ran {
+							for _, dep := range deps {
+								select {
+								case <-dep:
+								case <-ctx.Done():
+									stop()
+									return
+								}
 							}
+						}
 
-							exp.Status.SetAppFrequency(app.Name(), "")
-							exp.Status.SetAppRunning(app.Name(), false)
+						// Check to make sure this app wasn't triggered manually between
+						// periodic runs.
+						if running := exp.Status.AppRunning()[app.Name()]; running {
+							plog.Info("[✓] app is currently already executing its running stage -- skipping", "app", app.Name())
+							initialRunDone()
+							timer.Reset(duration)
+							continue
+						}
 
-							if err := exp.WriteToStore(true); err != nil {
-								plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
-							}
+						// The app's running status above is read from the experiment
+						// config pulled from the store when the app was scheduled, so a
+						// manual trigger could have set it since. The app locker, if
+						// one is set in the context, closes that gap.
+						unlock, err := lockApp(ctx, exp.Metadata.Name, app.Name())
+						if err != nil {
+							plog.Info("[✓] app is currently already executing its running stage -- skipping", "app", app.Name(), "err", err)
+							initialRunDone()
+							timer.Reset(duration)
+							continue
+						}
 
-							return
-						case <-timer.C:
-							// Check to make sure this app wasn't triggered manually between
-							// periodic runs.
-							if running := exp.Status.AppRunning()[app.Name()]; running {
-								plog.Info("[✓] app is currently already executing its running stage -- skipping", "app", app.Name())
-								continue
-							}
+						runPeriodicApp(ctx, exp, app, unlock)
+						initialRunDone()
 
-							// NOTE: there is a chance for a race condition here. Between the
-							// time we pull the experiment config from the store and read its
-							// app running status above, a manual trigger could have set
-							// running too. As of now, if this happens, multiple instances of
-							// the running stage will be executing at the same time. This
-							// might be a good place for optimistic locking.
+						timer.Reset(duration)
+					}
+				}
+			}(exp, app, duration, initial[app.Name()], deps)
+		}
+	}
 
-							a := GetApp(app.Name())
-							a.Init(Name(app.Name()))
+	return nil
+}
 
-							exp.Status.SetAppRunning(app.Name(), true)
+// runPeriodicApp runs the running stage of the given app against the given
+// experiment, calling unlock once it's done, even if the app panics.
+func runPeriodicApp(ctx context.Context, exp *types.Experiment, app ifaces.ScenarioApp, unlock func()) {
+	defer unlock()
 
-							if err := exp.WriteToStore(true); err != nil {
-								plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
-							}
+	defer func() {
+		if r := recover(); r != nil {
+			plog.Error("[✗] app panicked while periodically running", "app", app.Name(), "panic", r)
 
-							pubsub.Publish("trigger-app", TriggerPublication{
-								Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "start",
-							})
+			pubsub.Publish("trigger-app", TriggerPublication{
+				Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "error", Error: fmt.Errorf("app panicked: %v", r),
+			})
 
-							if err := a.Running(ctx, exp); err != nil {
-								pubsub.Publish("trigger-app", TriggerPublication{
-									Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "error", Error: err,
-								})
+			exp.Status.SetAppRunning(app.Name(), false)
 
-								plog.Error("[✗] error periodically running app", "app", app.Name(), "err", err)
-							}
+			if err := exp.WriteToStore(true); err != nil {
+				plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
+			}
+		}
+	}()
 
-							pubsub.Publish("trigger-app", TriggerPublication{
-								Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "success",
-							})
+	a := GetApp(app.Name())
+	a.Init(Name(app.Name()))
 
-							exp.Status.SetAppRunning(app.Name(), false)
+	exp.Status.SetAppRunning(app.Name(), true)
 
-							if err := exp.WriteToStore(true); err != nil {
-								plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
-							}
+	if err := exp.WriteToStore(true); err != nil {
+		plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
+	}
 
-							timer.Reset(duration)
-						}
-					}
-				}(exp, app, duration)
-			}
-		}
+	pubsub.Publish("trigger-app", TriggerPublication{
+		Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "start",
+	})
+
+	if err := a.Running(ctx, exp); err != nil {
+		pubsub.Publish("trigger-app", TriggerPublication{
+			Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "error", Error: err,
+		})
+
+		plog.Error("[✗] error periodically running app", "app", app.Name(), "err", err)
 	}
 
-	return nil
+	pubsub.Publish("trigger-app", TriggerPublication{
+		Experiment: exp.Spec.ExperimentName(), App: app.Name(), State: "success",
+	})
+
+	exp.Status.SetAppRunning(app.Name(), false)
+	exp.Status.SetAppLastRun(app.Name(), time.Now().Format(time.RFC3339))
+
+	if err := exp.WriteToStore(true); err != nil {
+		plog.Error("[✗] error updating store with experiment", "exp", exp.Metadata.Name, "err", err)
+	}
 }
@@ -6,8 +6,14 @@ type (
 	metadata   struct{}
 	triggerUI  struct{}
 	triggerCLI struct{}
+	vars       struct{}
+	appLocker  struct{}
 )
 
+// AppLocker locks the given app for the given experiment, returning a function
+// that releases the lock. It returns an error if the app is already running.
+type AppLocker func(exp, app string) (func(), error)
+
 func AddContextMetadata(ctx context.Context, key string, val any) context.Context {
 	var (
 		v  = ctx.Value(metadata{})
@@ -37,6 +43,19 @@ func SetContextTriggerCLI(ctx context.Context) context.Context {
 	return context.WithValue(ctx, triggerCLI{}, struct{}{})
 }
 
+// SetContextVars sets the start-time variables available to apps. They're
+// provided when starting an experiment and aren't persisted anywhere.
+func SetContextVars(ctx context.Context, v map[string]string) context.Context {
+	return context.WithValue(ctx, vars{}, v)
+}
+
+// SetContextAppLocker sets the function used to lock an app for an experiment
+// before it's run periodically, so periodic runs can't overlap with runs
+// triggered elsewhere.
+func SetContextAppLocker(ctx context.Context, l AppLocker) context.Context {
+	return context.WithValue(ctx, appLocker{}, l)
+}
+
 func GetContextMetadata(ctx context.Context) map[string]any {
 	md := ctx.Value(metadata{})
 	if md != nil {
@@ -55,3 +74,24 @@ func IsContextTriggerCLI(ctx context.Context) bool {
 	ok := ctx.Value(triggerCLI{})
 	return ok != nil
 }
+
+// GetContextVars returns the start-time variables set in the given context, if
+// any. The returned map should not be modified.
+func GetContextVars(ctx context.Context) map[string]string {
+	v := ctx.Value(vars{})
+	if v != nil {
+		return v.(map[string]string)
+	}
+
+	return make(map[string]string)
+}
+
+// lockApp locks the given app for the given experiment using the app locker set
+// in the given context. It's a no-op if no app locker is set.
+func lockApp(ctx context.Context, exp, app string) (func(), error) {
+	if l, ok := ctx.Value(appLocker{}).(AppLocker); ok && l != nil {
+		return l(exp, app)
+	}
+
+	return func() {}, nil
+}
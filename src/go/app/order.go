@@ -0,0 +1,91 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	ifaces "phenix/types/interfaces"
+)
+
+var (
+	ErrAppDependencyCycle   = errors.New("app dependency cycle")
+	ErrUnknownAppDependency = errors.New("unknown app dependency")
+)
+
+// OrderApps returns the given scenario apps sorted so each app comes after the
+// apps it depends on, keeping the apps' configured order otherwise. Default
+// apps always run before scenario apps, so dependencies on them are ignored.
+// It returns an error if an app depends on an app not in the scenario or the
+// dependencies form a cycle.
+func OrderApps(apps []ifaces.ScenarioApp) ([]ifaces.ScenarioApp, error) {
+	names := make(map[string]struct{})
+
+	for _, app := range apps {
+		names[app.Name()] = struct{}{}
+	}
+
+	for _, app := range apps {
+		for _, dep := range app.DependsOn() {
+			if _, ok := defaultApps[dep]; ok {
+				continue
+			}
+
+			if _, ok := names[dep]; !ok {
+				return nil, fmt.Errorf("%w: app %s depends on %s", ErrUnknownAppDependency, app.Name(), dep)
+			}
+		}
+	}
+
+	var (
+		ordered   = make([]ifaces.ScenarioApp, 0, len(apps))
+		placed    = make(map[string]struct{})
+		remaining = apps
+	)
+
+	// Repeatedly place the first remaining app whose dependencies have all been
+	// placed, so apps without dependencies between them stay in config order.
+	for len(remaining) > 0 {
+		next := -1
+
+		for i, app := range remaining {
+			if dependenciesPlaced(app, placed) {
+				next = i
+				break
+			}
+		}
+
+		if next < 0 {
+			cycle := make([]string, len(remaining))
+
+			for i, app := range remaining {
+				cycle[i] = app.Name()
+			}
+
+			return nil, fmt.Errorf("%w: unable to order apps %s", ErrAppDependencyCycle, strings.Join(cycle, ", "))
+		}
+
+		app := remaining[next]
+
+		ordered = append(ordered, app)
+		placed[app.Name()] = struct{}{}
+
+		remaining = append(remaining[:next:next], remaining[next+1:]...)
+	}
+
+	return ordered, nil
+}
+
+func dependenciesPlaced(app ifaces.ScenarioApp, placed map[string]struct{}) bool {
+	for _, dep := range app.DependsOn() {
+		if _, ok := defaultApps[dep]; ok {
+			continue
+		}
+
+		if _, ok := placed[dep]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,61 @@
+package app
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	ifaces "phenix/types/interfaces"
+	v2 "phenix/types/version/v2"
+)
+
+func TestOrderApps(t *testing.T) {
+	apps := func(deps map[string][]string, names ...string) []ifaces.ScenarioApp {
+		var list []ifaces.ScenarioApp
+
+		for _, name := range names {
+			list = append(list, &v2.ScenarioApp{NameF: name, DependsOnF: deps[name]})
+		}
+
+		return list
+	}
+
+	names := func(apps []ifaces.ScenarioApp) []string {
+		var list []string
+
+		for _, app := range apps {
+			list = append(list, app.Name())
+		}
+
+		return list
+	}
+
+	deps := map[string][]string{
+		"soh":       {"scorch", "ntp"},
+		"scorch":    {"protonuke"},
+		"wireshark": nil,
+	}
+
+	ordered, err := OrderApps(apps(deps, "soh", "wireshark", "scorch", "protonuke"))
+	if err != nil {
+		t.Fatalf("ordering apps: %v", err)
+	}
+
+	// Dependencies on default apps (ntp) are ignored, and apps without
+	// dependencies between them keep their config order.
+	expected := []string{"wireshark", "protonuke", "scorch", "soh"}
+
+	if got := names(ordered); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected order %v, got %v", expected, got)
+	}
+
+	deps["protonuke"] = []string{"soh"}
+
+	if _, err := OrderApps(apps(deps, "soh", "scorch", "protonuke")); !errors.Is(err, ErrAppDependencyCycle) {
+		t.Errorf("expected dependency cycle error, got %v", err)
+	}
+
+	if _, err := OrderApps(apps(deps, "soh", "ntp")); !errors.Is(err, ErrUnknownAppDependency) {
+		t.Errorf("expected unknown dependency error, got %v", err)
+	}
+}
@@ -112,6 +112,7 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 			"PHENIX_DRYRUN="+strconv.FormatBool(this.options.DryRun),
 			"PHENIX_STORE_ENDPOINT="+common.StoreEndpoint,
 		),
+		shell.Env(varsEnv(GetContextVars(ctx))...),
 	}
 
 	stdOut, stdErr, err := shell.ExecCommand(ctx, opts...)
@@ -170,3 +171,27 @@ func (this UserApp) shellOut(ctx context.Context, action Action, exp *types.Expe
 
 	return nil
 }
+
+// varsEnv converts the given start-time variables into environment variables
+// for user apps, prefixed with `PHENIX_VAR_` (e.g. `git-ref` becomes
+// `PHENIX_VAR_GIT_REF`).
+func varsEnv(vars map[string]string) []string {
+	env := make([]string, 0, len(vars))
+
+	for k, v := range vars {
+		key := strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+				return r
+			case r >= 'a' && r <= 'z':
+				return r - 'a' + 'A'
+			}
+
+			return '_'
+		}, k)
+
+		env = append(env, "PHENIX_VAR_"+key+"="+v)
+	}
+
+	return env
+}
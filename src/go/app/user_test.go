@@ -68,3 +68,19 @@ func TestUserAppFound(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestVarsEnv(t *testing.T) {
+	env := varsEnv(map[string]string{"git-ref": "main", "seed": "42"})
+
+	expected := map[string]bool{"PHENIX_VAR_GIT_REF=main": true, "PHENIX_VAR_SEED=42": true}
+
+	if len(env) != len(expected) {
+		t.Fatalf("expected %d environment variables, got %v", len(expected), env)
+	}
+
+	for _, e := range env {
+		if !expected[e] {
+			t.Errorf("unexpected environment variable %s", e)
+		}
+	}
+}
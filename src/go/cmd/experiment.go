@@ -391,6 +391,18 @@ func newExperimentStartCmd() *cobra.Command {
 					experiment.StartWithVLANMin(MustGetInt(cmd.Flags(), "vlan-min")),
 					experiment.StartWithVLANMax(MustGetInt(cmd.Flags(), "vlan-max")),
 					experiment.StartWithMMErrorsAsWarnings(MustGetBool(cmd.Flags(), "treat-mm-errors-as-warnings")),
+					experiment.StartWithTrace(MustGetBool(cmd.Flags(), "trace")),
+					experiment.StartWithIsolationCheck(MustGetBool(cmd.Flags(), "verify-isolation")),
+				}
+
+				if v := MustGetString(cmd.Flags(), "readiness-probe"); v != "" {
+					probe, err := experiment.ParseReadinessProbe(v)
+					if err != nil {
+						err := util.HumanizeError(err, "Unable to start the "+exp.Metadata.Name+" experiment")
+						return err.Humanized()
+					}
+
+					opts = append(opts, experiment.StartWithReadinessProbe(probe))
 				}
 
 				if err := experiment.Start(ctx, opts...); err != nil {
@@ -426,6 +438,9 @@ func newExperimentStartCmd() *cobra.Command {
 	cmd.Flags().Bool("treat-mm-errors-as-warnings", false, "Treat errors from minimega as warnings instead of failing")
 	cmd.Flags().Int("vlan-min", 0, "VLAN pool minimum")
 	cmd.Flags().Int("vlan-max", 0, "VLAN pool maximum")
+	cmd.Flags().Bool("trace", false, "Record minimega commands and state transitions during the start to a trace file")
+	cmd.Flags().Bool("verify-isolation", false, "Fail the start if the experiment's VLANs or taps overlap with other running experiments")
+	cmd.Flags().String("readiness-probe", "", "Wait for VMs to pass a readiness probe (agent, tcp:<port>, or file:<path>) before the experiment is started")
 
 	return cmd
 }
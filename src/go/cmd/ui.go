@@ -5,10 +5,12 @@ import (
 	"os"
 	"time"
 
+	"phenix/api/vm"
 	"phenix/util"
 	"phenix/util/common"
 	"phenix/util/plog"
 	"phenix/web"
+	"phenix/web/broker"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -38,6 +40,18 @@ func newUICmd() *cobra.Command {
 				web.ServeWithFeatures(viper.GetStringSlice("ui.features")),
 				web.ServeWithProxyAuthHeader(viper.GetString("ui.proxy-auth-header")),
 				web.ServeWithUnixSocketGid(viper.GetInt("unix-socket-gid")),
+				web.ServeWithShutdownTimeout(viper.GetDuration("ui.shutdown-timeout")),
+				web.ServeWithStartCooldown(viper.GetDuration("ui.start-cooldown")),
+				web.ServeWithStartQueueWorkers(viper.GetInt("ui.start-queue-workers")),
+				web.ServeWithHookSecret(viper.GetString("ui.hook-secret")),
+				web.ServeWithWebhooks(viper.GetStringSlice("ui.webhook-urls")),
+				web.ServeWithWebhookSecret(viper.GetString("ui.webhook-secret")),
+				web.ServeWithWebhookAllowedHosts(viper.GetStringSlice("ui.webhook-allowed-hosts")),
+				web.ServeWithDiskWarnThreshold(viper.GetFloat64("ui.disk-warn-threshold")),
+				web.ServeWithBroadcastCoalesceWindow(viper.GetDuration("ui.broadcast-coalesce-window")),
+				web.ServeWithBroadcastCompressionThreshold(viper.GetInt("ui.broadcast-compression-threshold")),
+				web.ServeWithVMExecVerbs(viper.GetStringSlice("ui.vm-exec-verbs")),
+				web.ServeWithVLANPool(viper.GetString("ui.vlan-pool")),
 			}
 
 			if endpoint := viper.GetString("ui.unix-socket-endpoint"); endpoint != "" {
@@ -112,6 +126,18 @@ func newUICmd() *cobra.Command {
 	cmd.Flags().StringSlice("features", nil, "list of features to enable (options: vm-mount)")
 	cmd.Flags().String("minimega-path", "", "path to minimega executable (for console access) - DEPRECATED (use --minimega-console instead)")
 	cmd.Flags().Bool("minimega-console", false, "enable minimega console access in UI")
+	cmd.Flags().Duration("shutdown-timeout", web.DefaultShutdownTimeout, "how long to wait for starting experiments to exit on shutdown")
+	cmd.Flags().Duration("start-cooldown", 0, "how long after an experiment is stopped before it can be started again (0 to disable)")
+	cmd.Flags().Int("start-queue-workers", 0, "number of queued experiment starts launched at once (0 for one per cluster host)")
+	cmd.Flags().String("hook-secret", "", "secret used to verify signed requests to the experiment start hook (hook disabled if not set)")
+	cmd.Flags().StringSlice("webhook-urls", nil, "URLs to notify when experiments start or stop")
+	cmd.Flags().String("webhook-secret", "", "secret used to sign webhook notifications (webhooks disabled if not set)")
+	cmd.Flags().StringSlice("webhook-allowed-hosts", nil, "hosts webhooks set for individual experiments can notify, on top of the webhook-urls hosts")
+	cmd.Flags().Float64("disk-warn-threshold", web.DefaultDiskWarnThreshold, "percent of a host's disk used before warning experiments running on it (0 to disable)")
+	cmd.Flags().Duration("broadcast-coalesce-window", broker.DefaultCoalesceWindow, "how long updates broadcast to clients for a resource are held so later updates replace them (0 to disable)")
+	cmd.Flags().Int("broadcast-compression-threshold", broker.DefaultCompressionThreshold, "size (in bytes) of results broadcast to clients at or above which they're compressed for clients that ask for it (0 to disable)")
+	cmd.Flags().StringSlice("vm-exec-verbs", vm.DefaultExecVerbs, "minimega commands allowed to be run against VMs by experiment owners (empty to disable)")
+	cmd.Flags().String("vlan-pool", "", "range of VLAN IDs (e.g. 2000-2999) allocated to experiments as they're started, excluded from minimega's own VLAN range (disabled if not set)")
 
 	viper.BindPFlag("ui.listen-endpoint", cmd.Flags().Lookup("listen-endpoint"))
 	viper.BindPFlag("ui.unix-socket-endpoint", cmd.Flags().Lookup("unix-socket-endpoint"))
@@ -130,6 +156,18 @@ func newUICmd() *cobra.Command {
 	viper.BindPFlag("ui.features", cmd.Flags().Lookup("features"))
 	viper.BindPFlag("ui.minimega-path", cmd.Flags().Lookup("minimega-path"))
 	viper.BindPFlag("ui.minimega-console", cmd.Flags().Lookup("minimega-console"))
+	viper.BindPFlag("ui.shutdown-timeout", cmd.Flags().Lookup("shutdown-timeout"))
+	viper.BindPFlag("ui.start-cooldown", cmd.Flags().Lookup("start-cooldown"))
+	viper.BindPFlag("ui.start-queue-workers", cmd.Flags().Lookup("start-queue-workers"))
+	viper.BindPFlag("ui.hook-secret", cmd.Flags().Lookup("hook-secret"))
+	viper.BindPFlag("ui.webhook-urls", cmd.Flags().Lookup("webhook-urls"))
+	viper.BindPFlag("ui.webhook-secret", cmd.Flags().Lookup("webhook-secret"))
+	viper.BindPFlag("ui.webhook-allowed-hosts", cmd.Flags().Lookup("webhook-allowed-hosts"))
+	viper.BindPFlag("ui.disk-warn-threshold", cmd.Flags().Lookup("disk-warn-threshold"))
+	viper.BindPFlag("ui.broadcast-coalesce-window", cmd.Flags().Lookup("broadcast-coalesce-window"))
+	viper.BindPFlag("ui.broadcast-compression-threshold", cmd.Flags().Lookup("broadcast-compression-threshold"))
+	viper.BindPFlag("ui.vm-exec-verbs", cmd.Flags().Lookup("vm-exec-verbs"))
+	viper.BindPFlag("ui.vlan-pool", cmd.Flags().Lookup("vlan-pool"))
 
 	viper.BindEnv("ui.listen-endpoint")
 	viper.BindEnv("ui.unix-socket-endpoint")
@@ -148,6 +186,18 @@ func newUICmd() *cobra.Command {
 	viper.BindEnv("ui.features")
 	viper.BindEnv("ui.minimega-path")
 	viper.BindEnv("ui.minimega-console")
+	viper.BindEnv("ui.shutdown-timeout")
+	viper.BindEnv("ui.start-cooldown")
+	viper.BindEnv("ui.start-queue-workers")
+	viper.BindEnv("ui.hook-secret")
+	viper.BindEnv("ui.webhook-urls")
+	viper.BindEnv("ui.webhook-secret")
+	viper.BindEnv("ui.webhook-allowed-hosts")
+	viper.BindEnv("ui.disk-warn-threshold")
+	viper.BindEnv("ui.broadcast-coalesce-window")
+	viper.BindEnv("ui.broadcast-compression-threshold")
+	viper.BindEnv("ui.vm-exec-verbs")
+	viper.BindEnv("ui.vlan-pool")
 
 	cmd.Flags().Bool("log-requests", false, "Log API requests")
 	cmd.Flags().Bool("log-full", false, "Log API requests and responses")
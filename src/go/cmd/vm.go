@@ -374,7 +374,7 @@ func newVMNetCmd() *cobra.Command {
 				return fmt.Errorf("The network interface index must be an integer")
 			}
 
-			if err := vm.Connect(expName, vmName, iface, vlan); err != nil {
+			if err := vm.ConnectInterface(expName, vmName, iface, vlan); err != nil {
 				err := util.HumanizeError(err, "Unable to modify the connectivity for the "+vmName+" VM")
 				return err.Humanized()
 			}
@@ -403,7 +403,7 @@ func newVMNetCmd() *cobra.Command {
 				return fmt.Errorf("The network interface index must be an integer")
 			}
 
-			if err := vm.Disonnect(expName, vmName, iface); err != nil {
+			if err := vm.DisconnectInterface(expName, vmName, iface); err != nil {
 				err := util.HumanizeError(err, "Unable to disconnect the interface on the "+vmName+" VM")
 				return err.Humanized()
 			}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+)
+
+func init() {
+	schedulers["binpack"] = new(binpack)
+}
+
+// binpack fills up cluster hosts with VMs, based on memory, before moving on to
+// the next host, minimizing the number of hosts used by an experiment.
+type binpack struct{}
+
+func (binpack) Init(...Option) error {
+	return nil
+}
+
+func (binpack) Name() string {
+	return "binpack"
+}
+
+func (binpack) Schedule(spec ifaces.ExperimentSpec) error {
+	if len(spec.Topology().Nodes()) == 0 {
+		return fmt.Errorf("no VMs defined for experiment")
+	}
+
+	cluster, _, err := mm.GetCachedClusterHosts(false)
+	if err != nil {
+		return fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	nodes := unscheduledNodes(spec, cluster)
+
+	// Place the largest VMs first so smaller ones can fill in the gaps.
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Hardware().Memory() > nodes[j].Hardware().Memory()
+	})
+
+	for _, node := range nodes {
+		var (
+			hostname = node.General().Hostname()
+			memory   = node.Hardware().Memory()
+			best     = -1
+		)
+
+		// Use the host with the least amount of memory available that still fits
+		// the VM, so hosts get filled up before moving on to others.
+		for idx, host := range cluster {
+			avail := host.MemTotal - host.MemCommit
+
+			if avail < memory {
+				continue
+			}
+
+			if best == -1 || avail < cluster[best].MemTotal-cluster[best].MemCommit {
+				best = idx
+			}
+		}
+
+		if best == -1 {
+			return fmt.Errorf("no cluster host has %d MB of memory available for VM %s", memory, hostname)
+		}
+
+		spec.Schedules()[hostname] = cluster[best].Name
+
+		cluster[best].MemCommit += memory
+		cluster[best].VMs += 1
+	}
+
+	return nil
+}
+
+// unscheduledNodes returns the experiment VMs not yet scheduled on a cluster
+// host. The memory committed by, and count of, VMs already scheduled are added
+// to the cluster hosts they're scheduled on.
+func unscheduledNodes(spec ifaces.ExperimentSpec, cluster mm.Hosts) []ifaces.NodeSpec {
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range spec.Topology().Nodes() {
+		if node.External() {
+			continue
+		}
+
+		if name, ok := spec.Schedules()[node.General().Hostname()]; ok {
+			cluster.IncrHostVMs(name, 1)
+			cluster.IncrHostMemCommit(name, node.Hardware().Memory())
+
+			continue
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
@@ -4,9 +4,13 @@ requirements, network configurations, etc. and cluster node capacity.
 
 Default Schedulers
 
+  * binpack.go:            fills up cluster nodes with experiment VMs, based on
+                           memory, before moving on to the next node
   * isolate-experiment.go: isolates all experiment VMs on a single cluster node
   * round-robin.go:        assigns experiment VMs to cluster nodes in a
                            round-robin fashion
+  * spread.go:             assigns each experiment VM to the cluster node with
+                           the most memory available
   * subnet-compute.go:     assigns experiment VMs to cluster nodes based on
                            interface VLAN assignments
 
@@ -26,7 +26,7 @@ func (isolateExperiment) Schedule(spec ifaces.ExperimentSpec) error {
 		return fmt.Errorf("no VMs defined for experiment")
 	}
 
-	cluster, err := mm.GetClusterHosts(true)
+	cluster, _, err := mm.GetCachedClusterHosts(false)
 	if err != nil {
 		return fmt.Errorf("getting cluster hosts: %w", err)
 	}
@@ -0,0 +1,56 @@
+package scheduler
+
+import (
+	"fmt"
+
+	ifaces "phenix/types/interfaces"
+	"phenix/util/mm"
+)
+
+func init() {
+	schedulers["spread"] = new(spread)
+}
+
+// spread assigns each VM to the cluster host with the most memory available,
+// spreading the memory load of an experiment evenly across the cluster.
+type spread struct{}
+
+func (spread) Init(...Option) error {
+	return nil
+}
+
+func (spread) Name() string {
+	return "spread"
+}
+
+func (spread) Schedule(spec ifaces.ExperimentSpec) error {
+	if len(spec.Topology().Nodes()) == 0 {
+		return fmt.Errorf("no VMs defined for experiment")
+	}
+
+	cluster, _, err := mm.GetCachedClusterHosts(false)
+	if err != nil {
+		return fmt.Errorf("getting cluster hosts: %w", err)
+	}
+
+	if len(cluster) == 0 {
+		return fmt.Errorf("no cluster hosts available")
+	}
+
+	for _, node := range unscheduledNodes(spec, cluster) {
+		best := 0
+
+		for idx, host := range cluster {
+			if host.MemTotal-host.MemCommit > cluster[best].MemTotal-cluster[best].MemCommit {
+				best = idx
+			}
+		}
+
+		spec.Schedules()[node.General().Hostname()] = cluster[best].Name
+
+		cluster[best].MemCommit += node.Hardware().Memory()
+		cluster[best].VMs += 1
+	}
+
+	return nil
+}
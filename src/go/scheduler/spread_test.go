@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"testing"
+
+	v1 "phenix/types/version/v1"
+	"phenix/util/mm"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestSpreadScheduler(t *testing.T) {
+	spec := &v1.ExperimentSpec{
+		TopologyF: &v1.TopologySpec{
+			NodesF: nodes,
+		},
+		SchedulesF: make(map[string]string),
+	}
+
+	hosts := mm.Hosts(
+		[]mm.Host{
+			{
+				Name:     "compute0",
+				MemTotal: 16384,
+			},
+			{
+				Name:     "compute1",
+				MemTotal: 16384,
+			},
+		},
+	)
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm.NewMockMM(ctrl)
+	m.EXPECT().GetClusterHosts(true).Return(hosts, nil)
+
+	mm.DefaultMM = m
+
+	if err := Schedule("spread", spec); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[string]string{
+		"foo":   "compute0",
+		"bar":   "compute1",
+		"sucka": "compute0",
+		"fish":  "compute1",
+	}
+
+	for vm, host := range expected {
+		if spec.SchedulesF[vm] != host {
+			t.Logf("expected %s -> %s, got %s -> %s", vm, host, vm, spec.SchedulesF[vm])
+			t.FailNow()
+		}
+	}
+}
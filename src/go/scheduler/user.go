@@ -43,7 +43,7 @@ func (this userScheduler) shellOut(spec ifaces.ExperimentSpec) error {
 		return fmt.Errorf("external user scheduler %s does not exist in your path: %w", cmdName, ErrUserSchedulerNotFound)
 	}
 
-	cluster, err := mm.GetClusterHosts(true)
+	cluster, _, err := mm.GetCachedClusterHosts(false)
 	if err != nil {
 		return fmt.Errorf("getting cluster hosts: %w", err)
 	}
@@ -200,11 +200,12 @@ func ConfigFullName(name ...string) string {
 type EventType string
 
 const (
-	EventTypeNotSet  EventType = ""
-	EventTypeInfo    EventType = "info"
-	EventTypeError   EventType = "error"
-	EventTypeUnknown EventType = "unknown"
-	EventTypeHistory EventType = "history"
+	EventTypeNotSet    EventType = ""
+	EventTypeInfo      EventType = "info"
+	EventTypeError     EventType = "error"
+	EventTypeUnknown   EventType = "unknown"
+	EventTypeHistory   EventType = "history"
+	EventTypeLifecycle EventType = "lifecycle"
 )
 
 type Event struct {
@@ -248,6 +249,13 @@ func NewHistoryEvent(history string) *Event {
 	return event
 }
 
+func NewLifecycleEvent(format string, args ...any) *Event {
+	event := NewEvent(format, args...)
+	event.Type = EventTypeLifecycle
+
+	return event
+}
+
 func (this *Event) WithMetadata(k, v string) *Event {
 	if this.Metadata == nil {
 		this.Metadata = make(map[string]string)
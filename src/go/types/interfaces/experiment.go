@@ -1,6 +1,9 @@
 package ifaces
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type VLANSpec interface {
 	Init() error
@@ -26,6 +29,10 @@ type ExperimentSpec interface {
 	Schedules() map[string]string
 	DeployMode() string
 	UseGREMesh() bool
+	Tags() map[string]string
+	Annotations() map[string]string
+	Quota() ExperimentQuota
+	AutoStop() ExperimentAutoStop
 
 	SetExperimentName(string)
 	SetBaseDir(string)
@@ -37,9 +44,27 @@ type ExperimentSpec interface {
 	SetScenario(ScenarioSpec)
 	SetDeployMode(string)
 	SetUseGREMesh(bool)
+	SetTags(map[string]string)
+	SetAnnotations(map[string]string)
 
 	VerifyScenario(context.Context) error
 	ScheduleNode(string, string) error
+	SnapshotName(string) string
+}
+
+// ExperimentQuota limits the resources used by an experiment's VMs. A limit of
+// zero means no limit.
+type ExperimentQuota interface {
+	MaxVCPUs() int
+	MaxMemory() int
+	MaxVMs() int
+}
+
+// ExperimentAutoStop configures stopping an experiment automatically once its
+// VMs have been idle for a while. An idle timeout of zero disables it.
+type ExperimentAutoStop interface {
+	IdleTimeout() time.Duration
+	MinBytesPerSecond() float64
 }
 
 type ExperimentStatus interface {
@@ -49,15 +74,25 @@ type ExperimentStatus interface {
 	AppStatus() map[string]any
 	AppFrequency() map[string]string
 	AppRunning() map[string]bool
+	AppLastRun() map[string]string
 	VLANs() map[string]int
 	Schedules() map[string]string
+	Paused() bool
+	LastStartDurationSeconds() float64
+	LinkImpairments() map[string]map[string]float64
+	GPUs() map[string][]string
 
 	SetStartTime(string)
 	SetAppStatus(string, any)
 	SetAppFrequency(string, string)
 	SetAppRunning(string, bool)
+	SetAppLastRun(string, string)
 	SetVLANs(map[string]int)
 	SetSchedule(map[string]string)
+	SetPaused(bool)
+	SetLastStartDurationSeconds(float64)
+	SetLinkImpairment(string, map[string]float64)
+	SetGPUs(map[string][]string)
 
 	ParseAppStatus(string, any) error
 	ResetAppStatus()
@@ -13,12 +13,14 @@ type ScenarioApp interface {
 	Hosts() []ScenarioAppHost
 	RunPeriodically() string
 	Disabled() bool
+	DependsOn() []string
 
 	SetAssetDir(string)
 	SetMetadata(map[string]any)
 	SetHosts([]ScenarioAppHost)
 	SetRunPeriodically(string)
 	SetDisabled(bool)
+	SetDependsOn([]string)
 
 	ParseMetadata(any) error
 	ParseHostMetadata(string, any) error
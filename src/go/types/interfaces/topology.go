@@ -37,6 +37,7 @@ type NodeSpec interface {
 	SetInjections([]NodeInjection)
 
 	AddLabel(string, string)
+	RemoveLabel(string)
 	AddHardware(string, int, int) NodeHardware
 	AddNetworkInterface(string, string, string) NodeNetworkInterface
 	AddNetworkRoute(string, string, int)
@@ -58,6 +59,7 @@ type NodeGeneral interface {
 	Snapshot() *bool
 	SetSnapshot(bool)
 	DoNotBoot() *bool
+	BootOrder() int
 
 	SetDoNotBoot(bool)
 }
@@ -67,6 +69,7 @@ type NodeHardware interface {
 	VCPU() int
 	Memory() int
 	OSType() string
+	GPUs() int
 	Drives() []NodeDrive
 
 	SetVCPU(int)
@@ -204,6 +207,7 @@ type NodeInjection interface {
 	Dst() string
 	Description() string
 	Permissions() string
+	Required() bool
 }
 
 type NodeDelay interface {
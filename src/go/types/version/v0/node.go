@@ -89,6 +89,10 @@ func (this *Node) AddLabel(k, v string) {
 	this.LabelsF[k] = v
 }
 
+func (this *Node) RemoveLabel(k string) {
+	delete(this.LabelsF, k)
+}
+
 func (this *Node) AddHardware(os string, vcpu, memory int) ifaces.NodeHardware {
 	h := &Hardware{
 		OSTypeF: os,
@@ -199,6 +203,10 @@ func (this *General) SetDoNotBoot(b bool) {
 	this.DoNotBootF = &b
 }
 
+func (General) BootOrder() int {
+	return 0
+}
+
 type Hardware struct {
 	CPUF    string   `json:"cpu" yaml:"cpu" structs:"cpu" mapstructure:"cpu"`
 	VCPUF   int      `json:"vcpus,string" yaml:"vcpus" structs:"vcpus" mapstructure:"vcpus"`
@@ -223,6 +231,10 @@ func (this Hardware) OSType() string {
 	return this.OSTypeF
 }
 
+func (this Hardware) GPUs() int {
+	return 0
+}
+
 func (this Hardware) Drives() []ifaces.NodeDrive {
 	drives := make([]ifaces.NodeDrive, len(this.DrivesF))
 
@@ -311,6 +323,10 @@ func (this Injection) Permissions() string {
 	return this.PermissionsF
 }
 
+func (Injection) Required() bool {
+	return false
+}
+
 type Delay struct{}
 
 func (this Delay) Timer() time.Duration {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"path/filepath"
 	"reflect"
+	"time"
 
 	ifaces "phenix/types/interfaces"
 	v2 "phenix/types/version/v2"
@@ -82,6 +83,69 @@ type ExperimentSpec struct {
 	SchedulesF      map[string]string `json:"schedules" yaml:"schedules" structs:"schedules" mapstructure:"schedules"`
 	DeployModeF     string            `json:"deployMode" yaml:"deployMode" structs:"deployMode" mapstructure:"deployMode"`
 	UseGREMeshF     bool              `json:"useGREMesh" yaml:"useGREMesh" structs:"useGREMesh" mapstructure:"useGREMesh"`
+	TagsF           map[string]string `json:"tags,omitempty" yaml:"tags,omitempty" structs:"tags" mapstructure:"tags"`
+	AnnotationsF    map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty" structs:"annotations" mapstructure:"annotations"`
+	QuotaF          *QuotaSpec        `json:"quota,omitempty" yaml:"quota,omitempty" structs:"quota" mapstructure:"quota"`
+	AutoStopF       *AutoStopSpec     `json:"autoStop,omitempty" yaml:"autoStop,omitempty" structs:"autoStop" mapstructure:"autoStop"`
+}
+
+type QuotaSpec struct {
+	MaxVCPUsF  int `json:"maxVCPUs,omitempty" yaml:"maxVCPUs,omitempty" structs:"maxVCPUs" mapstructure:"maxVCPUs"`
+	MaxMemoryF int `json:"maxMemory,omitempty" yaml:"maxMemory,omitempty" structs:"maxMemory" mapstructure:"maxMemory"`
+	MaxVMsF    int `json:"maxVMs,omitempty" yaml:"maxVMs,omitempty" structs:"maxVMs" mapstructure:"maxVMs"`
+}
+
+func (this *QuotaSpec) MaxVCPUs() int {
+	if this == nil {
+		return 0
+	}
+
+	return this.MaxVCPUsF
+}
+
+// MaxMemory returns the maximum total memory, in MB, of the experiment's VMs.
+func (this *QuotaSpec) MaxMemory() int {
+	if this == nil {
+		return 0
+	}
+
+	return this.MaxMemoryF
+}
+
+func (this *QuotaSpec) MaxVMs() int {
+	if this == nil {
+		return 0
+	}
+
+	return this.MaxVMsF
+}
+
+type AutoStopSpec struct {
+	IdleTimeoutF       string  `json:"idleTimeout,omitempty" yaml:"idleTimeout,omitempty" structs:"idleTimeout" mapstructure:"idleTimeout"`
+	MinBytesPerSecondF float64 `json:"minBytesPerSecond,omitempty" yaml:"minBytesPerSecond,omitempty" structs:"minBytesPerSecond" mapstructure:"minBytesPerSecond"`
+}
+
+// IdleTimeout returns how long the experiment's VMs can go without network
+// activity before the experiment is stopped. The duration format is enforced by
+// the schema, so an unparsable value disables it.
+func (this *AutoStopSpec) IdleTimeout() time.Duration {
+	if this == nil {
+		return 0
+	}
+
+	d, _ := time.ParseDuration(this.IdleTimeoutF)
+	return d
+}
+
+// MinBytesPerSecond returns the total throughput across all the experiment's VM
+// interfaces, in bytes per second, at or below which the VMs are considered
+// idle.
+func (this *AutoStopSpec) MinBytesPerSecond() float64 {
+	if this == nil {
+		return 0
+	}
+
+	return this.MinBytesPerSecondF
 }
 
 func (this *ExperimentSpec) Init() error {
@@ -265,6 +329,30 @@ func (this *ExperimentSpec) SetUseGREMesh(g bool) {
 	this.UseGREMeshF = g
 }
 
+func (this ExperimentSpec) Tags() map[string]string {
+	return this.TagsF
+}
+
+func (this *ExperimentSpec) SetTags(tags map[string]string) {
+	this.TagsF = tags
+}
+
+func (this ExperimentSpec) Annotations() map[string]string {
+	return this.AnnotationsF
+}
+
+func (this *ExperimentSpec) SetAnnotations(annotations map[string]string) {
+	this.AnnotationsF = annotations
+}
+
+func (this ExperimentSpec) Quota() ifaces.ExperimentQuota {
+	return this.QuotaF
+}
+
+func (this ExperimentSpec) AutoStop() ifaces.ExperimentAutoStop {
+	return this.AutoStopF
+}
+
 func (this ExperimentSpec) VerifyScenario(ctx context.Context) error {
 	if this.ScenarioF == nil {
 		return nil
@@ -301,12 +389,23 @@ type ExperimentStatus struct {
 	SchedulesF map[string]string `json:"schedules" yaml:"schedules" structs:"schedules" mapstructure:"schedules"`
 	AppsF      map[string]any    `json:"apps" yaml:"apps" structs:"apps" mapstructure:"apps"`
 	VLANsF     map[string]int    `json:"vlans" yaml:"vlans" structs:"vlans" mapstructure:"vlans"`
+	PausedF    bool              `json:"paused,omitempty" yaml:"paused,omitempty" structs:"paused" mapstructure:"paused"`
+
+	// Wall-clock time, in seconds, the most recent start of the experiment took.
+	LastStartDurationSecondsF float64 `json:"lastStartDurationSeconds,omitempty" yaml:"lastStartDurationSeconds,omitempty" structs:"lastStartDurationSeconds" mapstructure:"lastStartDurationSeconds"`
+
+	// Link impairments (latency, loss, rate) currently applied to each VLAN.
+	ImpairmentsF map[string]map[string]float64 `json:"impairments,omitempty" yaml:"impairments,omitempty" structs:"impairments" mapstructure:"impairments"`
+
+	// PCI addresses of the host GPUs passed through to each VM.
+	GPUsF map[string][]string `json:"gpus,omitempty" yaml:"gpus,omitempty" structs:"gpus" mapstructure:"gpus"`
 
 	// Used to track details of an app's running stage. Requires special attention
 	// since it can be run periodically in the background and/or triggered
 	// manually via the CLI or UI.
 	FrequencyF map[string]string `json:"appRunningStageFrequency,omitempty" yaml:"appRunningStageFrequency,omitempty" structs:"appRunningStageFrequency" mapstructure:"appRunningStageFrequency"`
 	RunningF   map[string]bool   `json:"appRunningStageStatus,omitempty" yaml:"appRunningStageStatus,omitempty" structs:"appRunningStageStatus" mapstructure:"appRunningStageStatus"`
+	LastRunF   map[string]string `json:"appRunningStageLastRun,omitempty" yaml:"appRunningStageLastRun,omitempty" structs:"appRunningStageLastRun" mapstructure:"appRunningStageLastRun"`
 }
 
 func (this *ExperimentStatus) Init() error {
@@ -353,6 +452,30 @@ func (this ExperimentStatus) AppRunning() map[string]bool {
 	return this.RunningF
 }
 
+func (this ExperimentStatus) AppLastRun() map[string]string {
+	if this.LastRunF == nil {
+		return make(map[string]string)
+	}
+
+	return this.LastRunF
+}
+
+func (this ExperimentStatus) LinkImpairments() map[string]map[string]float64 {
+	if this.ImpairmentsF == nil {
+		return make(map[string]map[string]float64)
+	}
+
+	return this.ImpairmentsF
+}
+
+func (this ExperimentStatus) GPUs() map[string][]string {
+	if this.GPUsF == nil {
+		return make(map[string][]string)
+	}
+
+	return this.GPUsF
+}
+
 func (this ExperimentStatus) VLANs() map[string]int {
 	if this.VLANsF == nil {
 		return make(map[string]int)
@@ -369,10 +492,26 @@ func (this ExperimentStatus) Schedules() map[string]string {
 	return this.SchedulesF
 }
 
+func (this ExperimentStatus) Paused() bool {
+	return this.PausedF
+}
+
+func (this ExperimentStatus) LastStartDurationSeconds() float64 {
+	return this.LastStartDurationSecondsF
+}
+
 func (this *ExperimentStatus) SetStartTime(t string) {
 	this.StartTimeF = t
 }
 
+func (this *ExperimentStatus) SetPaused(p bool) {
+	this.PausedF = p
+}
+
+func (this *ExperimentStatus) SetLastStartDurationSeconds(d float64) {
+	this.LastStartDurationSecondsF = d
+}
+
 func (this *ExperimentStatus) SetAppStatus(a string, s any) {
 	if this.AppsF == nil {
 		this.AppsF = make(map[string]any)
@@ -412,6 +551,33 @@ func (this *ExperimentStatus) SetAppRunning(a string, r bool) {
 	this.RunningF[a] = r
 }
 
+func (this *ExperimentStatus) SetAppLastRun(a, t string) {
+	if this.LastRunF == nil {
+		this.LastRunF = make(map[string]string)
+	}
+
+	this.LastRunF[a] = t
+}
+
+// SetLinkImpairment sets the impairment currently applied to the given VLAN. A
+// nil impairment removes the VLAN's impairment.
+func (this *ExperimentStatus) SetLinkImpairment(v string, i map[string]float64) {
+	if i == nil {
+		delete(this.ImpairmentsF, v)
+		return
+	}
+
+	if this.ImpairmentsF == nil {
+		this.ImpairmentsF = make(map[string]map[string]float64)
+	}
+
+	this.ImpairmentsF[v] = i
+}
+
+func (this *ExperimentStatus) SetGPUs(g map[string][]string) {
+	this.GPUsF = g
+}
+
 func (this *ExperimentStatus) SetVLANs(v map[string]int) {
 	if this.VLANsF == nil {
 		this.VLANsF = make(map[string]int)
@@ -450,4 +616,5 @@ func (this *ExperimentStatus) ResetAppStatus() {
 
 	this.FrequencyF = nil
 	this.RunningF = nil
+	this.LastRunF = nil
 }
@@ -110,6 +110,10 @@ func (this *Node) AddLabel(k, v string) {
 	this.LabelsF[k] = v
 }
 
+func (this *Node) RemoveLabel(k string) {
+	delete(this.LabelsF, k)
+}
+
 func (this *Node) AddHardware(os string, vcpu, memory int) ifaces.NodeHardware {
 	h := &Hardware{
 		OSTypeF: os,
@@ -257,6 +261,7 @@ type General struct {
 	VMTypeF      string `json:"vm_type" yaml:"vm_type" structs:"vm_type" mapstructure:"vm_type"`
 	SnapshotF    *bool  `json:"snapshot" yaml:"snapshot" structs:"snapshot" mapstructure:"snapshot"`
 	DoNotBootF   *bool  `json:"do_not_boot" yaml:"do_not_boot" structs:"do_not_boot" mapstructure:"do_not_boot"`
+	BootOrderF   int    `json:"boot_order,omitempty" yaml:"boot_order,omitempty" structs:"boot_order" mapstructure:"boot_order"`
 }
 
 func (this *General) Hostname() string {
@@ -317,12 +322,26 @@ func (this *General) SetDoNotBoot(b bool) {
 	this.DoNotBootF = &b
 }
 
+// BootOrder returns the group the VM is started in relative to the rest of the
+// experiment's VMs. Lower values are started first, and zero (unset) means the
+// VM is started in the last group.
+func (this *General) BootOrder() int {
+	if this == nil {
+		return 0
+	}
+
+	return this.BootOrderF
+}
+
 type Hardware struct {
 	CPUF    string   `json:"cpu" yaml:"cpu" structs:"cpu" mapstructure:"cpu"`
 	VCPUF   int      `json:"vcpus" yaml:"vcpus" structs:"vcpus" mapstructure:"vcpus"`
 	MemoryF int      `json:"memory" yaml:"memory" structs:"memory" mapstructure:"memory"`
 	OSTypeF string   `json:"os_type" yaml:"os_type" structs:"os_type" mapstructure:"os_type"`
 	DrivesF []*Drive `json:"drives" yaml:"drives" structs:"drives" mapstructure:"drives"`
+
+	// Number of host GPUs passed through to the VM.
+	GPUsF int `json:"gpus,omitempty" yaml:"gpus,omitempty" structs:"gpus" mapstructure:"gpus"`
 }
 
 func (this *Hardware) CPU() string {
@@ -357,6 +376,14 @@ func (this *Hardware) OSType() string {
 	return this.OSTypeF
 }
 
+func (this *Hardware) GPUs() int {
+	if this == nil {
+		return 0
+	}
+
+	return this.GPUsF
+}
+
 func (this *Hardware) Drives() []ifaces.NodeDrive {
 	if this == nil {
 		return nil
@@ -431,6 +458,7 @@ type Injection struct {
 	DstF         string `json:"dst" yaml:"dst" structs:"dst" mapstructure:"dst"`
 	DescriptionF string `json:"description" yaml:"description" structs:"description" mapstructure:"description"`
 	PermissionsF string `json:"permissions" yaml:"permissions" structs:"permissions" mapstructure:"permissions"`
+	RequiredF    *bool  `json:"required,omitempty" yaml:"required,omitempty" structs:"required" mapstructure:"required"`
 }
 
 func (this Injection) Src() string {
@@ -449,6 +477,14 @@ func (this Injection) Permissions() string {
 	return this.PermissionsF
 }
 
+func (this Injection) Required() bool {
+	if this.RequiredF == nil {
+		return false
+	}
+
+	return *this.RequiredF
+}
+
 func (this Node) validate() error {
 	if this.ExternalF == nil {
 		return nil
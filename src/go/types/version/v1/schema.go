@@ -195,6 +195,41 @@ components:
             type: string
           example:
             ADServer: compute1
+        tags:
+          type: object
+          additionalProperties:
+            type: string
+          example:
+            project: foo
+        annotations:
+          type: object
+          additionalProperties:
+            type: string
+          example:
+            owner: jdoe
+            ticket: OPS-123
+        quota:
+          type: object
+          properties:
+            maxVCPUs:
+              type: integer
+              minimum: 0
+            maxMemory:
+              type: integer
+              minimum: 0
+            maxVMs:
+              type: integer
+              minimum: 0
+        autoStop:
+          type: object
+          properties:
+            idleTimeout:
+              type: string
+              pattern: '^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$'
+              example: 2h
+            minBytesPerSecond:
+              type: number
+              minimum: 0
     minimega_node:
       type: object
       required:
@@ -236,6 +271,11 @@ components:
               default: false
               example: false
               nullable: true
+            boot_order:
+              type: integer
+              minimum: 0
+              default: 0
+              example: 1
         hardware:
           type: object
           required:
@@ -258,6 +298,10 @@ components:
               - type: string
               default: 1024
               example: 8192
+            gpus:
+              type: integer
+              minimum: 0
+              example: 1
             os_type:
               type: string
               enum:
@@ -487,6 +531,9 @@ components:
               permissions:
                 type: string
                 example: '0664'
+              required:
+                type: boolean
+                example: false
         delay:
           type: object
           nullable: true
@@ -569,6 +616,10 @@ components:
               - type: string
               default: 1024
               example: 8192
+            gpus:
+              type: integer
+              minimum: 0
+              example: 1
             os_type:
               type: string
               default: linux
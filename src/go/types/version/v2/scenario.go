@@ -47,6 +47,7 @@ type ScenarioApp struct {
 	HostsF           []*ScenarioAppHost `json:"hosts,omitempty" yaml:"hosts,omitempty" structs:"hosts" mapstructure:"hosts"`
 	RunPeriodicallyF string             `json:"runPeriodically,omitempty" yaml:"runPeriodically,omitempty" structs:"runPeriodically" mapstructure:"runPeriodically"`
 	DisabledF        bool               `json:"disabled,omitempty" yaml:"disabled,omitempty" structs:"disabled" mapstructure:"disabled"`
+	DependsOnF       []string           `json:"dependsOn,omitempty" yaml:"dependsOn,omitempty" structs:"dependsOn" mapstructure:"dependsOn"`
 }
 
 func (this ScenarioApp) Name() string {
@@ -83,6 +84,10 @@ func (this ScenarioApp) Disabled() bool {
 	return this.DisabledF
 }
 
+func (this ScenarioApp) DependsOn() []string {
+	return this.DependsOnF
+}
+
 func (this *ScenarioApp) SetAssetDir(dir string) {
 	this.AssetDirF = dir
 }
@@ -109,6 +114,10 @@ func (this *ScenarioApp) SetDisabled(d bool) {
 	this.DisabledF = d
 }
 
+func (this *ScenarioApp) SetDependsOn(deps []string) {
+	this.DependsOnF = deps
+}
+
 func (this ScenarioApp) ParseMetadata(md any) error {
 	if this.MetadataF == nil {
 		return fmt.Errorf("missing metadata for app %s", this.NameF)
@@ -163,6 +163,13 @@ components:
               assetDir:
                 type: string
                 example: /phenix/topologies/example-topo/assets
+              dependsOn:
+                type: array
+                nullable: true
+                items:
+                  type: string
+                example:
+                - other-app
               metadata:
                 type: object
                 nullable: true
@@ -267,6 +274,11 @@ components:
               default: false
               example: false
               nullable: true
+            boot_order:
+              type: integer
+              minimum: 0
+              default: 0
+              example: 1
         hardware:
           type: object
           required:
@@ -512,6 +524,9 @@ components:
               permissions:
                 type: string
                 example: '0664'
+              required:
+                type: boolean
+                example: false
         delay:
           type: object
           nullable: true
@@ -0,0 +1,182 @@
+// Package cron parses standard five-field cron expressions (minute, hour, day
+// of month, month, day of week) and computes when they next occur.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// Whether the day of month or day of week fields were restricted (not `*`).
+	// If both are, a day matches if either does, as with standard cron.
+	domRestricted, dowRestricted bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// Parse parses the given cron expression. Each field can be `*`, a value, a
+// range (`a-b`), or a comma-separated list of them, optionally followed by a
+// step (`/n`). A day of week of 7 is Sunday, the same as 0.
+func Parse(expr string) (Schedule, error) {
+	tokens := strings.Fields(expr)
+
+	if len(tokens) != len(fields) {
+		return Schedule{}, fmt.Errorf("expected %d fields in cron expression, got %d", len(fields), len(tokens))
+	}
+
+	var bits [5]uint64
+
+	for i, token := range tokens {
+		b, err := parseField(token, fields[i])
+		if err != nil {
+			return Schedule{}, fmt.Errorf("parsing %s field: %w", fields[i].name, err)
+		}
+
+		bits[i] = b
+	}
+
+	// Treat Sunday as 0 only.
+	if bits[4]&(1<<7) != 0 {
+		bits[4] = (bits[4] | 1) &^ (1 << 7)
+	}
+
+	return Schedule{
+		minute:        bits[0],
+		hour:          bits[1],
+		dom:           bits[2],
+		month:         bits[3],
+		dow:           bits[4],
+		domRestricted: tokens[2] != "*",
+		dowRestricted: tokens[4] != "*",
+	}, nil
+}
+
+func parseField(token string, f field) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(token, ",") {
+		var (
+			rng  = part
+			step = 1
+		)
+
+		if r, s, ok := strings.Cut(part, "/"); ok {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %s", s)
+			}
+
+			rng, step = r, n
+		}
+
+		lo, hi := f.min, f.max
+
+		if rng != "*" {
+			var err error
+
+			if a, b, ok := strings.Cut(rng, "-"); ok {
+				if lo, err = parseValue(a, f); err != nil {
+					return 0, err
+				}
+
+				if hi, err = parseValue(b, f); err != nil {
+					return 0, err
+				}
+
+				if lo > hi {
+					return 0, fmt.Errorf("invalid range %s", rng)
+				}
+			} else {
+				if lo, err = parseValue(rng, f); err != nil {
+					return 0, err
+				}
+
+				// A single value with a step (e.g. `5/15`) runs through the max.
+				if step == 1 {
+					hi = lo
+				}
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+
+	return bits, nil
+}
+
+func parseValue(s string, f field) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %s (must be %d-%d)", s, f.min, f.max)
+	}
+
+	return v, nil
+}
+
+// Next returns the first time after the given time the schedule occurs, in the
+// given time's location. It returns the zero time if the schedule never occurs
+// (e.g. February 30th).
+func (this Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every valid schedule occurs at least once within a leap year cycle.
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if this.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if !this.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if this.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+
+		if this.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (this Schedule) dayMatches(t time.Time) bool {
+	var (
+		dom = this.dom&(1<<uint(t.Day())) != 0
+		dow = this.dow&(1<<uint(t.Weekday())) != 0
+	)
+
+	if this.domRestricted && this.dowRestricted {
+		return dom || dow
+	}
+
+	return dom && dow
+}
@@ -0,0 +1,41 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2024, time.January, 10, 8, 30, 0, 0, time.UTC)
+
+	tests := map[string]time.Time{
+		"*/15 * * * *":  time.Date(2024, time.January, 10, 8, 45, 0, 0, time.UTC),
+		"0 9 * * *":     time.Date(2024, time.January, 10, 9, 0, 0, 0, time.UTC),
+		"0 6 * * *":     time.Date(2024, time.January, 11, 6, 0, 0, 0, time.UTC),
+		"0 0 1 * *":     time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		"30 22 * * 1-5": time.Date(2024, time.January, 10, 22, 30, 0, 0, time.UTC),
+		"0 12 * * 7":    time.Date(2024, time.January, 14, 12, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":    time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"0 0 15 * 1":    time.Date(2024, time.January, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	for expr, expected := range tests {
+		sched, err := Parse(expr)
+		if err != nil {
+			t.Fatalf("parsing %q: %v", expr, err)
+		}
+
+		if next := sched.Next(from); !next.Equal(expected) {
+			t.Errorf("expected %q to next occur at %v, got %v", expr, expected, next)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("expected error parsing %q", expr)
+		}
+	}
+}
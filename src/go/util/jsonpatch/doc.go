@@ -0,0 +1,3 @@
+// Package jsonpatch applies JSON Patch (RFC 6902) documents to decoded JSON
+// values.
+package jsonpatch
@@ -0,0 +1,364 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidPatch = errors.New("invalid JSON patch")
+	ErrTestFailed   = errors.New("JSON patch test failed")
+)
+
+// Operation is a single operation in a JSON Patch document.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Decode parses the given JSON Patch document, returning an error if it isn't
+// an array of valid operations.
+func Decode(patch []byte) ([]Operation, error) {
+	var ops []Operation
+
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	for i, op := range ops {
+		switch op.Op {
+		case "add", "replace", "test":
+			if op.Value == nil {
+				return nil, fmt.Errorf("%w: operation %d (%s) missing value", ErrInvalidPatch, i, op.Op)
+			}
+		case "move", "copy":
+			if _, err := parsePointer(op.From); err != nil {
+				return nil, fmt.Errorf("%w: operation %d (%s): %v", ErrInvalidPatch, i, op.Op, err)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d has unknown op %q", ErrInvalidPatch, i, op.Op)
+		}
+
+		if _, err := parsePointer(op.Path); err != nil {
+			return nil, fmt.Errorf("%w: operation %d (%s): %v", ErrInvalidPatch, i, op.Op, err)
+		}
+	}
+
+	return ops, nil
+}
+
+// Apply applies the given operations, in order, to a copy of the given
+// document, which must only contain types produced by `json.Unmarshal` into an
+// `any`. The given document is never modified, so if any operation fails none
+// of them are applied.
+func Apply(doc any, ops []Operation) (any, error) {
+	doc, err := clone(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		if doc, err = apply(doc, op); err != nil {
+			return nil, fmt.Errorf("applying operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return doc, nil
+}
+
+func apply(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+	}
+
+	switch op.Op {
+	case "add":
+		var value any
+
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: parsing value: %v", ErrInvalidPatch, err)
+		}
+
+		return add(doc, path, value)
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		var value any
+
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: parsing value: %v", ErrInvalidPatch, err)
+		}
+
+		if doc, _, err = remove(doc, path); err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	case "move":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		if op.From != op.Path && strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move %s into one of its children", ErrInvalidPatch, op.From)
+		}
+
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	case "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPatch, err)
+		}
+
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+
+		if value, err = clone(value); err != nil {
+			return nil, err
+		}
+
+		return add(doc, path, value)
+	case "test":
+		var expected any
+
+		if err := json.Unmarshal(op.Value, &expected); err != nil {
+			return nil, fmt.Errorf("%w: parsing value: %v", ErrInvalidPatch, err)
+		}
+
+		actual, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+
+		if !reflect.DeepEqual(actual, expected) {
+			return nil, fmt.Errorf("%w: value at %s does not match", ErrTestFailed, op.Path)
+		}
+
+		return doc, nil
+	}
+
+	return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidPatch, op.Op)
+}
+
+// add sets the value at the given path, returning the (possibly new) document.
+func add(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = value
+	case []any:
+		idx := len(p)
+
+		if key != "-" {
+			if idx, err = index(key, len(p)); err != nil {
+				return nil, err
+			}
+		}
+
+		p = append(p, nil)
+		copy(p[idx+1:], p[idx:])
+		p[idx] = value
+
+		// Slices grow by reallocating, so the new slice has to replace the old one
+		// in the document.
+		return set(doc, path[:len(path)-1], p)
+	default:
+		return nil, fmt.Errorf("%w: parent of %s is not an object or array", ErrInvalidPatch, format(path))
+	}
+
+	return doc, nil
+}
+
+// remove deletes the value at the given path, returning the (possibly new)
+// document and the removed value.
+func remove(doc any, path []string) (any, any, error) {
+	if len(path) == 0 {
+		return nil, doc, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		value, ok := p[key]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: %s does not exist", ErrInvalidPatch, format(path))
+		}
+
+		delete(p, key)
+
+		return doc, value, nil
+	case []any:
+		idx, err := index(key, len(p)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		value := p[idx]
+		p = append(p[:idx:idx], p[idx+1:]...)
+
+		doc, err = set(doc, path[:len(path)-1], p)
+		return doc, value, err
+	default:
+		return nil, nil, fmt.Errorf("%w: parent of %s is not an object or array", ErrInvalidPatch, format(path))
+	}
+}
+
+// set replaces the existing value at the given path.
+func set(doc any, path []string, value any) (any, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	parent, err := get(doc, path[:len(path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := path[len(path)-1]
+
+	switch p := parent.(type) {
+	case map[string]any:
+		p[key] = value
+	case []any:
+		idx, err := index(key, len(p)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		p[idx] = value
+	}
+
+	return doc, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	for i, key := range path {
+		switch d := doc.(type) {
+		case map[string]any:
+			value, ok := d[key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s does not exist", ErrInvalidPatch, format(path[:i+1]))
+			}
+
+			doc = value
+		case []any:
+			idx, err := index(key, len(d)-1)
+			if err != nil {
+				return nil, err
+			}
+
+			doc = d[idx]
+		default:
+			return nil, fmt.Errorf("%w: %s does not exist", ErrInvalidPatch, format(path[:i+1]))
+		}
+	}
+
+	return doc, nil
+}
+
+// index parses the given array index, which must be between 0 and max.
+func index(key string, max int) (int, error) {
+	// RFC 6901 doesn't allow leading zeros.
+	if key == "" || (len(key) > 1 && key[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, key)
+	}
+
+	idx, err := strconv.Atoi(key)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("%w: invalid array index %q", ErrInvalidPatch, key)
+	}
+
+	if idx > max {
+		return 0, fmt.Errorf("%w: array index %d out of range", ErrInvalidPatch, idx)
+	}
+
+	return idx, nil
+}
+
+// parsePointer splits the given JSON Pointer (RFC 6901) into its unescaped
+// reference tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// Path returns the unescaped reference tokens of the given JSON Pointer, or nil
+// if the pointer is invalid or refers to the whole document.
+func Path(pointer string) []string {
+	tokens, _ := parsePointer(pointer)
+	return tokens
+}
+
+func format(path []string) string {
+	var sb strings.Builder
+
+	for _, token := range path {
+		sb.WriteString("/")
+		sb.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(token))
+	}
+
+	return sb.String()
+}
+
+// clone deep copies the given value by round-tripping it through JSON, which
+// also normalizes any Go types (like ints) to their JSON equivalents.
+func clone(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("copying JSON document: %w", err)
+	}
+
+	var copied any
+
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("copying JSON document: %w", err)
+	}
+
+	return copied, nil
+}
@@ -0,0 +1,102 @@
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+		err   error
+	}{
+		{
+			name:  "add to object and array",
+			doc:   `{"a": {"b": 1}, "c": [1, 3]}`,
+			patch: `[{"op": "add", "path": "/a/d", "value": 2}, {"op": "add", "path": "/c/1", "value": 2}, {"op": "add", "path": "/c/-", "value": 4}]`,
+			want:  `{"a": {"b": 1, "d": 2}, "c": [1, 2, 3, 4]}`,
+		},
+		{
+			name:  "remove and replace",
+			doc:   `{"a": 1, "b": [1, 2, 3]}`,
+			patch: `[{"op": "remove", "path": "/b/1"}, {"op": "replace", "path": "/a", "value": "x"}]`,
+			want:  `{"a": "x", "b": [1, 3]}`,
+		},
+		{
+			name:  "move and copy",
+			doc:   `{"a": {"b": 1}, "c": {}}`,
+			patch: `[{"op": "copy", "from": "/a/b", "path": "/c/b"}, {"op": "move", "from": "/a", "path": "/d"}]`,
+			want:  `{"c": {"b": 1}, "d": {"b": 1}}`,
+		},
+		{
+			name:  "escaped pointer",
+			doc:   `{"a/b": 1, "m~n": 2}`,
+			patch: `[{"op": "replace", "path": "/a~1b", "value": 3}, {"op": "remove", "path": "/m~0n"}]`,
+			want:  `{"a/b": 3}`,
+		},
+		{
+			name:  "failed test",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "replace", "path": "/a", "value": 2}, {"op": "test", "path": "/a", "value": 1}]`,
+			err:   ErrTestFailed,
+		},
+		{
+			name:  "missing path",
+			doc:   `{"a": 1}`,
+			patch: `[{"op": "remove", "path": "/b"}]`,
+			err:   ErrInvalidPatch,
+		},
+		{
+			name:  "index out of range",
+			doc:   `{"a": [1]}`,
+			patch: `[{"op": "add", "path": "/a/2", "value": 1}]`,
+			err:   ErrInvalidPatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			json.Unmarshal([]byte(tt.doc), &doc)
+
+			ops, err := Decode([]byte(tt.patch))
+			if err != nil {
+				t.Fatalf("decoding patch: %v", err)
+			}
+
+			got, err := Apply(doc, ops)
+
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("expected error %v, got %v", tt.err, err)
+				}
+
+				// The original document must be left untouched.
+				var orig any
+				json.Unmarshal([]byte(tt.doc), &orig)
+
+				if !reflect.DeepEqual(doc, orig) {
+					t.Fatalf("original document modified: %v", doc)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var want any
+			json.Unmarshal([]byte(tt.want), &want)
+
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("expected %v, got %v", want, got)
+			}
+		})
+	}
+}
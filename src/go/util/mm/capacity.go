@@ -0,0 +1,162 @@
+package mm
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ClusterHostsTTL is how long the schedulable cluster hosts returned by
+// GetCachedClusterHosts are reused before being queried from minimega again.
+var ClusterHostsTTL = 10 * time.Second
+
+// DefaultVMSlotMemory is the memory (in MB) of a single VM slot when computing
+// cluster capacity, if a VM size isn't given.
+const DefaultVMSlotMemory = 2048
+
+var clusterHosts struct {
+	sync.Mutex
+
+	// The MM the hosts were queried from, so replacing DefaultMM (e.g. in tests)
+	// doesn't return hosts from a different cluster.
+	mm      MM
+	hosts   Hosts
+	updated time.Time
+}
+
+// GetCachedClusterHosts returns the schedulable cluster hosts, the same as
+// GetClusterHosts(true), along with when they were queried from minimega. The
+// hosts from the last query are reused if made within ClusterHostsTTL, unless
+// refresh is true. Each call returns its own copy of the hosts, so callers
+// (e.g. schedulers) can update them as they place VMs.
+func GetCachedClusterHosts(refresh bool) (Hosts, time.Time, error) {
+	clusterHosts.Lock()
+	defer clusterHosts.Unlock()
+
+	stale := clusterHosts.mm != DefaultMM || time.Since(clusterHosts.updated) > ClusterHostsTTL
+
+	if refresh || stale || clusterHosts.hosts == nil {
+		hosts, err := DefaultMM.GetClusterHosts(true)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		clusterHosts.mm = DefaultMM
+		clusterHosts.hosts = hosts
+		clusterHosts.updated = time.Now()
+	}
+
+	hosts := make(Hosts, len(clusterHosts.hosts))
+	copy(hosts, clusterHosts.hosts)
+
+	return hosts, clusterHosts.updated, nil
+}
+
+// InvalidateClusterHosts discards the cached cluster hosts, so the next call to
+// GetCachedClusterHosts queries minimega. It should be called whenever VMs are
+// launched or killed, since that changes what's committed on each host.
+func InvalidateClusterHosts() {
+	clusterHosts.Lock()
+	defer clusterHosts.Unlock()
+
+	clusterHosts.hosts = nil
+}
+
+// CapacityTotals is the total and uncommitted resources of one or more cluster
+// hosts. VM slots are how many VMs of a given size fit in a host's memory.
+// Minimega doesn't track GPUs passed through to VMs, so it's up to callers to
+// subtract the GPUs in use from those available.
+type CapacityTotals struct {
+	CPUs              int `json:"cpus"`
+	CPUsAvailable     int `json:"cpusAvailable"`
+	MemoryMB          int `json:"memoryMB"`
+	MemoryAvailableMB int `json:"memoryAvailableMB"`
+	VMs               int `json:"vms"`
+	VMSlots           int `json:"vmSlots"`
+	VMSlotsAvailable  int `json:"vmSlotsAvailable"`
+	GPUs              int `json:"gpus"`
+	GPUsAvailable     int `json:"gpusAvailable"`
+}
+
+// Add adds the given totals to these totals.
+func (this *CapacityTotals) Add(other CapacityTotals) {
+	this.CPUs += other.CPUs
+	this.CPUsAvailable += other.CPUsAvailable
+	this.MemoryMB += other.MemoryMB
+	this.MemoryAvailableMB += other.MemoryAvailableMB
+	this.VMs += other.VMs
+	this.VMSlots += other.VMSlots
+	this.VMSlotsAvailable += other.VMSlotsAvailable
+	this.GPUs += other.GPUs
+	this.GPUsAvailable += other.GPUsAvailable
+}
+
+// HostCapacity is the capacity of a single schedulable cluster host.
+type HostCapacity struct {
+	Name     string `json:"name"`
+	Headnode bool   `json:"headnode"`
+
+	CapacityTotals
+}
+
+// ClusterCapacity is the capacity of each schedulable cluster host, along with
+// the capacity of the cluster as a whole.
+type ClusterCapacity struct {
+	Hosts   []HostCapacity `json:"hosts"`
+	Total   CapacityTotals `json:"total"`
+	Updated time.Time      `json:"updated"`
+
+	// Memory (in MB) of each VM slot.
+	VMSlotMemory int `json:"vmSlotMemory"`
+}
+
+// GetClusterCapacity returns the capacity of the schedulable cluster hosts,
+// computed from the same cached hosts used when scheduling and starting
+// experiments (see GetCachedClusterHosts). VM slots are computed for VMs with
+// the given memory (in MB), or DefaultVMSlotMemory if it's less than 1.
+func GetClusterCapacity(refresh bool, vmMemory int) (*ClusterCapacity, error) {
+	hosts, updated, err := GetCachedClusterHosts(refresh)
+	if err != nil {
+		return nil, err
+	}
+
+	if vmMemory < 1 {
+		vmMemory = DefaultVMSlotMemory
+	}
+
+	capacity := &ClusterCapacity{Hosts: []HostCapacity{}, Updated: updated, VMSlotMemory: vmMemory}
+
+	for _, host := range hosts {
+		hc := HostCapacity{
+			Name:     host.Name,
+			Headnode: host.Headnode,
+			CapacityTotals: CapacityTotals{
+				CPUs:     host.CPUs,
+				MemoryMB: host.MemTotal,
+				VMs:      host.VMs,
+			},
+		}
+
+		if free := host.CPUs - host.CPUCommit; free > 0 {
+			hc.CPUsAvailable = free
+		}
+
+		if free := host.MemTotal - host.MemCommit; free > 0 {
+			hc.MemoryAvailableMB = free
+		}
+
+		hc.GPUs = len(host.GPUs)
+		hc.GPUsAvailable = len(host.GPUs)
+
+		hc.VMSlots = hc.MemoryMB / vmMemory
+		hc.VMSlotsAvailable = hc.MemoryAvailableMB / vmMemory
+
+		capacity.Total.Add(hc.CapacityTotals)
+
+		capacity.Hosts = append(capacity.Hosts, hc)
+	}
+
+	sort.Slice(capacity.Hosts, func(i, j int) bool { return capacity.Hosts[i].Name < capacity.Hosts[j].Name })
+
+	return capacity, nil
+}
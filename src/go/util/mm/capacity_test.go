@@ -0,0 +1,67 @@
+package mm
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestGetClusterCapacity(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(orig MM) {
+		DefaultMM = orig
+		InvalidateClusterHosts()
+	}(DefaultMM)
+
+	hosts := Hosts{
+		{Name: "compute2", CPUs: 8, CPUCommit: 10, MemTotal: 16384, MemCommit: 16384, VMs: 4},
+		{Name: "compute1", CPUs: 16, CPUCommit: 4, MemTotal: 32768, MemCommit: 8192, VMs: 2},
+	}
+
+	m := NewMockMM(ctrl)
+	m.EXPECT().GetClusterHosts(true).Return(hosts, nil).Times(2)
+
+	DefaultMM = m
+
+	capacity, err := GetClusterCapacity(false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(capacity.Hosts) != 2 || capacity.Hosts[0].Name != "compute1" {
+		t.Fatalf("expected hosts sorted by name, got %+v", capacity.Hosts)
+	}
+
+	expected := CapacityTotals{CPUs: 16, CPUsAvailable: 12, MemoryMB: 32768, MemoryAvailableMB: 24576, VMs: 2, VMSlots: 16, VMSlotsAvailable: 12}
+
+	if got := capacity.Hosts[0].CapacityTotals; got != expected {
+		t.Errorf("expected %+v for compute1, got %+v", expected, got)
+	}
+
+	// Overcommitted hosts have nothing available rather than negative amounts.
+	if got := capacity.Hosts[1]; got.CPUsAvailable != 0 || got.VMSlotsAvailable != 0 {
+		t.Errorf("expected nothing available on compute2, got %+v", got.CapacityTotals)
+	}
+
+	expected = CapacityTotals{CPUs: 24, CPUsAvailable: 12, MemoryMB: 49152, MemoryAvailableMB: 24576, VMs: 6, VMSlots: 24, VMSlotsAvailable: 12}
+
+	if capacity.Total != expected {
+		t.Errorf("expected total %+v, got %+v", expected, capacity.Total)
+	}
+
+	// Served from the cache, so the hosts aren't queried again.
+	cached, err := GetClusterCapacity(false, 4096)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !cached.Updated.Equal(capacity.Updated) || cached.Total.VMSlots != 12 {
+		t.Errorf("expected cached capacity with 12 VM slots, got %+v", cached)
+	}
+
+	if _, err := GetClusterCapacity(true, 0); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -35,7 +37,14 @@ var (
 var ccMu sync.Mutex
 
 // Regular express to use for matching C2 response headers.
-var responseRegex = regexp.MustCompile(`(\d*)\/(.*)\/(stdout|stderr):`)
+var (
+	responseRegex   = regexp.MustCompile(`(\d*)\/(.*)\/(stdout|stderr):`)
+	vlanAliasRegex  = regexp.MustCompile(`(.*) \(\d*\)`)
+	pciAddressRegex = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
+)
+
+// How long to wait for a VM's disk snapshot to complete before giving up.
+var diskSnapshotTimeout = 30 * time.Minute
 
 type Minimega struct{}
 
@@ -131,6 +140,131 @@ func (Minimega) GetLaunchProgress(ns string, expected int) (float64, error) {
 
 }
 
+// GetPerVMLaunchState returns the launch state of each VM in the given
+// namespace, keyed by VM name. Queued VMs are determined using a single `ns
+// queue` call, and launched VMs using a single `vm info` call, to avoid
+// querying minimega for each VM individually.
+func (Minimega) GetPerVMLaunchState(ns string) (map[string]string, error) {
+	states := make(map[string]string)
+
+	for _, name := range queuedVMs(ns) {
+		states[name] = LaunchStatePending
+	}
+
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"name", "state"}
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		states[row["name"]] = launchState(row["state"])
+	}
+
+	return states, nil
+}
+
+// GetExperimentSnapshot returns the VM states, launch states, and captures for
+// the given namespace together, using one `ns queue`, one `vm info`, and one
+// `capture` call. If interfaces is true, interface stats are included too,
+// which takes one more call per cluster host with launched VMs to read their
+// tap counters. It's meant for callers that need more than one of these at a
+// time, so they don't each query minimega for them.
+func (this Minimega) GetExperimentSnapshot(ns string, interfaces bool) (*ExperimentSnapshot, error) {
+	snap := &ExperimentSnapshot{
+		VMStates:     make(map[string]string),
+		LaunchStates: make(map[string]string),
+		Captures:     []Capture{},
+	}
+
+	queued := queuedVMs(ns)
+
+	snap.Queued = len(queued)
+
+	for _, name := range queued {
+		snap.LaunchStates[name] = LaunchStatePending
+	}
+
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "name", "state", "vlan", "tap"}
+
+	rows := mmcli.RunTabular(cmd)
+
+	for _, row := range rows {
+		snap.VMStates[row["name"]] = row["state"]
+		snap.LaunchStates[row["name"]] = launchState(row["state"])
+	}
+
+	if interfaces {
+		stats, errs := this.interfaceStats(rows)
+
+		snap.Interfaces = stats
+
+		for host, err := range errs {
+			if snap.InterfaceErrors == nil {
+				snap.InterfaceErrors = make(map[string]string)
+			}
+
+			snap.InterfaceErrors[host] = err.Error()
+		}
+	}
+
+	cmd = mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "capture"
+	cmd.Columns = []string{"interface", "path"}
+
+	if captures := parseCaptures(mmcli.RunTabular(cmd)); captures != nil {
+		snap.Captures = captures
+	}
+
+	return snap, nil
+}
+
+// queuedVMs returns the names of the VMs in the given namespace's launch
+// queue.
+func queuedVMs(ns string) []string {
+	var queued []string
+
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "ns queue"
+
+	re := regexp.MustCompile(`Names: (.*)`)
+
+	for resps := range mmcli.Run(cmd) {
+		for _, resp := range resps.Resp {
+			// minimega errors when there's nothing queued for the namespace (e.g.
+			// before any VMs have been queued or after they've all launched).
+			if resp.Error != "" {
+				plog.Debug("getting queued VMs in namespace", "ns", ns, "err", resp.Error)
+				return nil
+			}
+
+			for _, m := range re.FindAllStringSubmatch(resp.Response, -1) {
+				for _, name := range strings.Split(m[1], ",") {
+					queued = append(queued, strings.TrimSpace(name))
+				}
+			}
+		}
+	}
+
+	return queued
+}
+
+// launchState converts the given minimega VM state to a launch state.
+func launchState(state string) string {
+	switch state {
+	case "BUILDING":
+		return LaunchStateLaunching
+	case "PAUSED":
+		// VMs are paused after being launched until they're started, which may be
+		// delayed.
+		return LaunchStatePending
+	case "RUNNING":
+		return LaunchStateRunning
+	default:
+		return LaunchStateError
+	}
+}
+
 func (this Minimega) GetVMInfo(opts ...Option) VMs {
 	o := NewOptions(opts...)
 
@@ -301,6 +435,45 @@ func (Minimega) GetVNCEndpoint(opts ...Option) (string, error) {
 	return endpoint, nil
 }
 
+// GetVMConsolePort returns the endpoint for the console of the given type (VNC
+// by default) for the given VM. VNC consoles are served over TCP by the host
+// the VM is running on. Serial consoles are unix sockets in the VM's minimega
+// instance directory, so they can only be reached for VMs on the headnode.
+func (this Minimega) GetVMConsolePort(opts ...Option) (ConsoleEndpoint, error) {
+	o := NewOptions(opts...)
+
+	switch o.consoleType {
+	case "", ConsoleTypeVNC:
+		endpoint, err := this.GetVNCEndpoint(opts...)
+		if err != nil {
+			return ConsoleEndpoint{}, fmt.Errorf("getting VNC endpoint for VM %s: %w", o.vm, err)
+		}
+
+		return ConsoleEndpoint{Network: "tcp", Address: endpoint}, nil
+	case ConsoleTypeSerial:
+		cmd := mmcli.NewNamespacedCommand(o.ns)
+		cmd.Command = "vm info"
+		cmd.Columns = []string{"host", "id"}
+		cmd.Filters = []string{"type=kvm", "name=" + o.vm}
+
+		rows := mmcli.RunTabular(cmd)
+
+		if len(rows) == 0 {
+			return ConsoleEndpoint{}, fmt.Errorf("VM %s not found in namespace %s", o.vm, o.ns)
+		}
+
+		if host := rows[0]["host"]; !this.IsHeadnode(host) {
+			return ConsoleEndpoint{}, fmt.Errorf("serial console for VM %s is only available on the headnode (VM is on %s)", o.vm, host)
+		}
+
+		path := fmt.Sprintf("%s/%s/serial0", common.MinimegaBase, rows[0]["id"])
+
+		return ConsoleEndpoint{Network: "unix", Address: path}, nil
+	default:
+		return ConsoleEndpoint{}, fmt.Errorf("unknown console type %s", o.consoleType)
+	}
+}
+
 func (Minimega) StartVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
@@ -327,6 +500,21 @@ func (Minimega) StopVM(opts ...Option) error {
 	return nil
 }
 
+// ShutdownVM sends an ACPI power down request to the given VM, leaving it up to
+// the guest to shut itself down. It doesn't wait for the VM to exit.
+func (Minimega) ShutdownVM(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf(`vm qmp %s '{ "execute": "system_powerdown" }'`, o.vm)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("shutting down VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
 func (Minimega) RedeployVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
@@ -452,6 +640,414 @@ func (Minimega) KillVM(opts ...Option) error {
 	return flush(o.ns)
 }
 
+// ForceKillVM kills the QEMU process backing the given VM directly on the host
+// the VM is running on. It's meant to be used when minimega is unable to kill
+// the VM itself (e.g. the process is zombied).
+func (Minimega) ForceKillVM(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "pid"}
+	cmd.Filters = []string{"name=" + o.vm}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found in namespace %s", o.vm, o.ns)
+	}
+
+	pid := status[0]["pid"]
+
+	if pid == "" || pid == "0" {
+		return fmt.Errorf("no process found for VM %s in namespace %s", o.vm, o.ns)
+	}
+
+	if err := MeshShell(status[0]["host"], "kill -9 "+pid); err != nil {
+		return fmt.Errorf("killing process for VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+// SetLinkImpairment applies the given latency (in milliseconds), packet loss
+// (percentage) and rate limit (in kbit/s) to every VM tap in the given
+// namespace connected to the given VLAN alias, replacing any impairment already
+// applied to the taps. A value of 0 disables that type of impairment.
+func (Minimega) SetLinkImpairment(ns, vlan string, latencyMs int, lossPct float64, rateKbit int) error {
+	var qos []string
+
+	if latencyMs > 0 {
+		qos = append(qos, fmt.Sprintf("delay %dms", latencyMs))
+	}
+
+	if lossPct > 0 {
+		qos = append(qos, fmt.Sprintf("loss %s", strconv.FormatFloat(lossPct, 'f', -1, 64)))
+	}
+
+	if rateKbit > 0 {
+		qos = append(qos, fmt.Sprintf("rate %d kbit", rateKbit))
+	}
+
+	return vlanQoS(ns, vlan, qos)
+}
+
+// ClearLinkImpairment removes any impairment applied to the VM taps in the
+// given namespace connected to the given VLAN alias.
+func (Minimega) ClearLinkImpairment(ns, vlan string) error {
+	return vlanQoS(ns, vlan, nil)
+}
+
+// GetInterfaceStats returns the RX/TX byte and packet counters for each
+// interface of each VM in the given namespace. The counters are read from the
+// kernel's counters for each VM's tap on the cluster host the VM is running on.
+// They're reported from the VM's point of view, so traffic the host receives on
+// a tap is counted as TX for the VM.
+func (this Minimega) GetInterfaceStats(ns string) ([]InterfaceStats, error) {
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "name", "vlan", "tap"}
+
+	stats, errs := this.interfaceStats(mmcli.RunTabular(cmd))
+
+	if len(errs) > 0 {
+		host := sortedHosts(errs)[0]
+		return nil, fmt.Errorf("getting interface counters from host %s: %w", host, errs[host])
+	}
+
+	return stats, nil
+}
+
+// interfaceStats returns the stats for the interfaces of the VMs in the given
+// `vm info` rows, which must include the host, name, vlan, and tap columns.
+// The tap counters are read once per host. Hosts the counters couldn't be read
+// from are returned with their errors, and their VMs are left out.
+func (this Minimega) interfaceStats(rows []map[string]string) ([]InterfaceStats, map[string]error) {
+	var (
+		stats []InterfaceStats
+		hosts = make(map[string]map[string]netDevCounters)
+		errs  = make(map[string]error)
+	)
+
+	for _, row := range rows {
+		var (
+			host     = row["host"]
+			networks = splitList(row["vlan"])
+			taps     = splitList(row["tap"])
+		)
+
+		if len(taps) == 0 {
+			continue
+		}
+
+		if _, failed := errs[host]; failed {
+			continue
+		}
+
+		counters, ok := hosts[host]
+		if !ok {
+			out, err := this.MeshShellResponse(host, "cat /proc/net/dev")
+			if err != nil {
+				errs[host] = err
+				continue
+			}
+
+			counters = parseNetDev(out)
+			hosts[host] = counters
+		}
+
+		for i, tap := range taps {
+			c, ok := counters[tap]
+			if !ok {
+				continue
+			}
+
+			s := InterfaceStats{
+				VM:        row["name"],
+				Interface: i,
+				Tap:       tap,
+				RxBytes:   c.txBytes,
+				TxBytes:   c.rxBytes,
+				RxPackets: c.txPackets,
+				TxPackets: c.rxPackets,
+			}
+
+			if i < len(networks) {
+				s.Network = networks[i]
+			}
+
+			stats = append(stats, s)
+		}
+	}
+
+	return stats, errs
+}
+
+func sortedHosts(errs map[string]error) []string {
+	hosts := make([]string, 0, len(errs))
+
+	for host := range errs {
+		hosts = append(hosts, host)
+	}
+
+	sort.Strings(hosts)
+
+	return hosts
+}
+
+// SetVMTag sets the tag with the given key on the given VM in the given
+// namespace.
+func (Minimega) SetVMTag(ns, vm, key, value string) error {
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = fmt.Sprintf("vm tag %s %s %s", vm, key, value)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("setting tag %s on VM %s in namespace %s: %w", key, vm, ns, err)
+	}
+
+	return nil
+}
+
+// ClearVMTag removes the tag with the given key from the given VM in the given
+// namespace.
+func (Minimega) ClearVMTag(ns, vm, key string) error {
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = fmt.Sprintf("clear vm tag %s %s", vm, key)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("clearing tag %s on VM %s in namespace %s: %w", key, vm, ns, err)
+	}
+
+	return nil
+}
+
+// GetVMDiskUsage returns the size of each VM's disk in the given namespace. For
+// VMs using a snapshot, it also returns how much the VM's overlay has grown on
+// top of its backing image. Sizes that can't be determined are left at zero,
+// with the reason in the VM's Error field.
+func (this Minimega) GetVMDiskUsage(ns string) ([]VMDiskUsage, error) {
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "name", "disks", "snapshot"}
+
+	var usage []VMDiskUsage
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		disks := strings.Fields(row["disks"])
+		if len(disks) == 0 {
+			continue
+		}
+
+		var (
+			host = row["host"]
+			// diskspec can include multiple settings separated by comma. Path to disk
+			// will always be first setting.
+			disk = strings.Split(disks[0], ",")[0]
+			u    = VMDiskUsage{VM: row["name"], Host: host, Image: disk}
+		)
+
+		if snapshot, _ := strconv.ParseBool(row["snapshot"]); snapshot {
+			info := mmcli.NewCommand()
+			info.Command = "disk info " + disk
+
+			if !IsHeadnode(host) {
+				info.Command = fmt.Sprintf("mesh send %s %s", host, info.Command)
+			}
+
+			if resp := mmcli.RunTabular(info); len(resp) > 0 && resp[0]["backingfile"] != "" {
+				u.Image = resp[0]["backingfile"]
+				u.Overlay = disk
+			}
+		}
+
+		paths := []string{u.Image}
+
+		if u.Overlay != "" {
+			paths = append(paths, u.Overlay)
+		}
+
+		sizes, err := this.fileSizes(host, paths...)
+		if err != nil {
+			u.Error = err.Error()
+		} else {
+			u.ImageBytes = sizes[0]
+
+			if u.Overlay != "" {
+				u.OverlayBytes = sizes[1]
+			}
+		}
+
+		usage = append(usage, u)
+	}
+
+	return usage, nil
+}
+
+// GetHostDiskSpace returns the size and free space of the filesystem the given
+// path is on for the given cluster host.
+func (this Minimega) GetHostDiskSpace(host, path string) (HostDiskSpace, error) {
+	space := HostDiskSpace{Host: host, Path: path}
+
+	resp, err := this.MeshShellResponse(host, "df -B1 --output=size,avail "+path)
+	if err != nil {
+		return space, fmt.Errorf("getting disk space for %s on host %s: %w", path, host, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(resp), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+
+	if len(fields) != 2 {
+		return space, fmt.Errorf("unexpected disk space output for %s on host %s: %s", path, host, resp)
+	}
+
+	if space.TotalBytes, err = strconv.ParseInt(fields[0], 10, 64); err != nil {
+		return space, fmt.Errorf("parsing disk size for %s on host %s: %w", path, host, err)
+	}
+
+	if space.FreeBytes, err = strconv.ParseInt(fields[1], 10, 64); err != nil {
+		return space, fmt.Errorf("parsing free disk space for %s on host %s: %w", path, host, err)
+	}
+
+	if space.TotalBytes > 0 {
+		space.UsedPercent = 100 * float64(space.TotalBytes-space.FreeBytes) / float64(space.TotalBytes)
+	}
+
+	return space, nil
+}
+
+// fileSizes returns the size in bytes of each of the given files on the given
+// cluster host.
+func (this Minimega) fileSizes(host string, paths ...string) ([]int64, error) {
+	resp, err := this.MeshShellResponse(host, "stat -L -c %s "+strings.Join(paths, " "))
+	if err != nil {
+		return nil, fmt.Errorf("getting size of %s on host %s: %w", strings.Join(paths, ", "), host, err)
+	}
+
+	lines := strings.Fields(resp)
+
+	if len(lines) != len(paths) {
+		return nil, fmt.Errorf("unexpected sizes for %s on host %s: %s", strings.Join(paths, ", "), host, resp)
+	}
+
+	sizes := make([]int64, len(paths))
+
+	for i, line := range lines {
+		if sizes[i], err = strconv.ParseInt(line, 10, 64); err != nil {
+			return nil, fmt.Errorf("parsing size of %s on host %s: %w", paths[i], host, err)
+		}
+	}
+
+	return sizes, nil
+}
+
+// Ping checks that minimega is reachable by running a command that has no side
+// effects.
+func (Minimega) Ping() error {
+	cmd := mmcli.NewCommand()
+	cmd.Command = "version"
+
+	if _, err := mmcli.SingleResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("pinging minimega: %w", err)
+	}
+
+	return nil
+}
+
+// SnapshotVMDisk backs up the current disk state of the given running VM to the
+// given snapshot file using QEMU's drive-backup, blocking until the backup
+// completes.
+func (Minimega) SnapshotVMDisk(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	if o.snapshotFile == "" {
+		return fmt.Errorf("no snapshot file provided for VM %s", o.vm)
+	}
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"id"}
+	cmd.Filters = []string{"name=" + o.vm}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found in namespace %s", o.vm, o.ns)
+	}
+
+	cmd.Columns = nil
+	cmd.Filters = nil
+
+	// minimega's disk for the VM lives in its instance directory.
+	prefix := fmt.Sprintf("%s/%s", common.MinimegaBase, status[0]["id"])
+
+	cmd.Command = fmt.Sprintf(`vm qmp %s '{ "execute": "query-block" }'`, o.vm)
+
+	res, err := mmcli.SingleResponse(mmcli.Run(cmd))
+	if err != nil {
+		return fmt.Errorf("querying for block device details for VM %s: %w", o.vm, err)
+	}
+
+	var blocks map[string][]BlockDevice
+
+	if err := json.Unmarshal([]byte(res), &blocks); err != nil {
+		return fmt.Errorf("parsing block device details for VM %s: %w", o.vm, err)
+	}
+
+	var device string
+
+	for _, dev := range blocks["return"] {
+		if dev.Inserted != nil && strings.HasPrefix(dev.Inserted.File, prefix) {
+			device = dev.Device
+			break
+		}
+	}
+
+	if device == "" {
+		return fmt.Errorf("no disk found for VM %s", o.vm)
+	}
+
+	cmd.Command = fmt.Sprintf(`vm qmp %s '{ "execute": "drive-backup", "arguments": { "device": "%s", "sync": "top", "target": "%s" } }'`, o.vm, device, o.snapshotFile)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("starting disk snapshot for VM %s: %w", o.vm, err)
+	}
+
+	cmd.Command = fmt.Sprintf(`vm qmp %s '{ "execute": "query-block-jobs" }'`, o.vm)
+
+	deadline := time.Now().Add(diskSnapshotTimeout)
+
+	for {
+		res, err := mmcli.SingleResponse(mmcli.Run(cmd))
+		if err != nil {
+			return fmt.Errorf("querying for block device jobs for VM %s: %w", o.vm, err)
+		}
+
+		var jobs map[string][]BlockDeviceJobs
+
+		if err := json.Unmarshal([]byte(res), &jobs); err != nil {
+			return fmt.Errorf("parsing block device jobs for VM %s: %w", o.vm, err)
+		}
+
+		var running bool
+
+		for _, job := range jobs["return"] {
+			if job.Device == device {
+				running = true
+			}
+		}
+
+		if !running {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("disk snapshot for VM %s not done after %v", o.vm, diskSnapshotTimeout)
+		}
+
+		time.Sleep(1 * time.Second)
+	}
+}
+
 func (Minimega) GetVMHost(opts ...Option) (string, error) {
 	o := NewOptions(opts...)
 
@@ -486,6 +1082,24 @@ func (Minimega) GetVMState(opts ...Option) (string, error) {
 	return status[0]["state"], nil
 }
 
+// GetVMStates returns the minimega state (e.g. RUNNING, PAUSED) of each VM in
+// the namespace, keyed by VM name, using a single `vm info summary` call.
+func (Minimega) GetVMStates(opts ...Option) map[string]string {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info summary"
+	cmd.Columns = []string{"name", "state"}
+
+	states := make(map[string]string)
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		states[row["name"]] = row["state"]
+	}
+
+	return states
+}
+
 func (Minimega) ConnectVMInterface(opts ...Option) error {
 	o := NewOptions(opts...)
 
@@ -671,9 +1285,15 @@ func (Minimega) GetExperimentCaptures(opts ...Option) []Capture {
 	cmd.Command = "capture"
 	cmd.Columns = []string{"interface", "path"}
 
+	return parseCaptures(mmcli.RunTabular(cmd))
+}
+
+// parseCaptures converts the given `capture` rows, which must include the
+// interface and path columns, to VM captures.
+func parseCaptures(rows []map[string]string) []Capture {
 	var captures []Capture
 
-	for _, row := range mmcli.RunTabular(cmd) {
+	for _, row := range rows {
 		// `interface` column will be empty if the capture is bridge-wide
 		if row["interface"] == "" {
 			// currently phenix doesn't provide the option to create bridge-wide
@@ -758,6 +1378,8 @@ func (this Minimega) GetClusterHosts(schedOnly bool) (Hosts, error) {
 		host.DiskUsage.Phenix = this.getDiskUsage(host.Name, common.PhenixBase)
 		host.DiskUsage.Minimega = this.getDiskUsage(host.Name, common.MinimegaBase)
 
+		host.GPUs = this.getGPUs(host.Name)
+
 		cluster = append(cluster, host)
 	}
 
@@ -771,6 +1393,8 @@ func (this Minimega) GetClusterHosts(schedOnly bool) (Hosts, error) {
 	head.DiskUsage.Phenix = this.getDiskUsage(head.Name, common.PhenixBase)
 	head.DiskUsage.Minimega = this.getDiskUsage(head.Name, common.MinimegaBase)
 
+	head.GPUs = this.getGPUs(head.Name)
+
 	cluster = append(cluster, head)
 
 	return cluster, nil
@@ -821,6 +1445,23 @@ func (this Minimega) IsHeadnode(node string) bool {
 	return node == this.Headnode()
 }
 
+// PingHost checks the given cluster host is reachable over minimega's mesh and
+// that minimega is running on it by having it report its hostname.
+func (this Minimega) PingHost(host string) error {
+	cmd := mmcli.NewCommand()
+	cmd.Command = "host name"
+
+	if !this.IsHeadnode(host) {
+		cmd.Command = fmt.Sprintf("mesh send %s host name", host)
+	}
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("pinging host %s: %w", host, err)
+	}
+
+	return nil
+}
+
 func (Minimega) GetVLANs(opts ...Option) (map[string]int, error) {
 	o := NewOptions(opts...)
 
@@ -1248,6 +1889,48 @@ func (Minimega) MeshSend(ns, host, command string) error {
 	return nil
 }
 
+// vlanQoS clears the QoS settings for each VM interface in the given namespace
+// connected to the given VLAN alias and then applies the given QoS settings
+// (e.g. `delay 100ms`) to them.
+func vlanQoS(ns, vlan string, qos []string) error {
+	var found bool
+
+	for _, vm := range GetVMInfo(NS(ns)) {
+		for idx, nw := range vm.Networks {
+			if match := vlanAliasRegex.FindStringSubmatch(nw); match != nil {
+				nw = match[1]
+			}
+
+			if !strings.EqualFold(nw, vlan) {
+				continue
+			}
+
+			found = true
+
+			cmd := mmcli.NewNamespacedCommand(ns)
+			cmd.Command = fmt.Sprintf("clear qos %s %d", vm.Name, idx)
+
+			if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+				return fmt.Errorf("clearing qos for VM %s interface %d: %w", vm.Name, idx, err)
+			}
+
+			for _, q := range qos {
+				cmd.Command = fmt.Sprintf("qos add %s %d %s", vm.Name, idx, q)
+
+				if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+					return fmt.Errorf("adding qos (%s) for VM %s interface %d: %w", q, vm.Name, idx, err)
+				}
+			}
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no VM interfaces connected to VLAN %s", vlan)
+	}
+
+	return nil
+}
+
 // GetLocalMountPath returns where the mount path should be on this filesystem
 // for the given namespace and VM.
 func GetLocalMountPath(ns string, vm string) string {
@@ -1331,6 +2014,13 @@ func flush(ns string) error {
 	return nil
 }
 
+// InjectDiskFiles injects files into the given partition of the given disk,
+// which must not be in use by a running VM. Each inject is given as
+// `"<src>":"<dst>"`, where src is a path on the headnode.
+func (Minimega) InjectDiskFiles(disk string, part int, injects ...string) error {
+	return inject(disk, part, injects...)
+}
+
 func inject(disk string, part int, injects ...string) error {
 	files := strings.Join(injects, " ")
 
@@ -1411,3 +2101,88 @@ func (this Minimega) getDiskUsage(host string, path string) float64 {
 
 	return diskUsage
 }
+
+// getGPUs returns the PCI addresses of the GPUs on the given host that can be
+// passed through to VMs, which are the display controllers bound to the
+// vfio-pci driver.
+func (this Minimega) getGPUs(host string) []string {
+	cmd := `bash -c "for d in /sys/bus/pci/drivers/vfio-pci/*:*; do grep -qs ^0x03 $d/class && basename $d; done"`
+
+	resp, err := this.MeshShellResponse(host, cmd)
+	if err != nil {
+		return nil
+	}
+
+	return parseGPUs(resp)
+}
+
+// parseGPUs parses the PCI addresses, one per line, output when listing the
+// GPUs on a host.
+func parseGPUs(out string) []string {
+	var gpus []string
+
+	for _, addr := range strings.Fields(out) {
+		if pciAddressRegex.MatchString(addr) {
+			gpus = append(gpus, addr)
+		}
+	}
+
+	sort.Strings(gpus)
+
+	return gpus
+}
+
+type netDevCounters struct {
+	rxBytes, rxPackets uint64
+	txBytes, txPackets uint64
+}
+
+// parseNetDev parses the contents of /proc/net/dev into the RX/TX counters for
+// each interface, keyed by interface name.
+func parseNetDev(out string) map[string]netDevCounters {
+	counters := make(map[string]netDevCounters)
+
+lines:
+	for _, line := range strings.Split(out, "\n") {
+		iface, fields, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		// The first 8 fields are receive counters, followed by 8 transmit
+		// counters, each starting with bytes and packets.
+		f := strings.Fields(fields)
+		if len(f) < 10 {
+			continue
+		}
+
+		var vals [4]uint64
+
+		for i, idx := range []int{0, 1, 8, 9} {
+			v, err := strconv.ParseUint(f[idx], 10, 64)
+			if err != nil {
+				continue lines
+			}
+
+			vals[i] = v
+		}
+
+		counters[strings.TrimSpace(iface)] = netDevCounters{
+			rxBytes: vals[0], rxPackets: vals[1], txBytes: vals[2], txPackets: vals[3],
+		}
+	}
+
+	return counters
+}
+
+// splitList splits a list column from a minimega table (e.g. `[a, b]`).
+func splitList(s string) []string {
+	s = strings.TrimPrefix(s, "[")
+	s = strings.TrimSuffix(s, "]")
+
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, ", ")
+}
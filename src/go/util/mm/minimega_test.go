@@ -0,0 +1,22 @@
+package mm
+
+import "testing"
+
+func TestParseNetDev(t *testing.T) {
+	out := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:    1200      12    0    0    0     0          0         0     1200      12    0    0    0     0       0          0
+mega_tap0: 5000 40 0 0 0 0 0 0 7000 55 0 0 0 0 0 0`
+
+	counters := parseNetDev(out)
+
+	if len(counters) != 2 {
+		t.Fatalf("expected 2 interfaces, got %d", len(counters))
+	}
+
+	expected := netDevCounters{rxBytes: 5000, rxPackets: 40, txBytes: 7000, txPackets: 55}
+
+	if c := counters["mega_tap0"]; c != expected {
+		t.Errorf("expected %+v for mega_tap0, got %+v", expected, c)
+	}
+}
@@ -3,21 +3,30 @@ package mm
 var DefaultMM MM = new(Minimega)
 
 type MM interface {
+	Ping() error
+
 	ReadScriptFromFile(string) error
 	ClearNamespace(string) error
 
 	LaunchVMs(string, ...string) error
 	GetLaunchProgress(string, int) (float64, error)
+	GetPerVMLaunchState(string) (map[string]string, error)
+	GetExperimentSnapshot(string, bool) (*ExperimentSnapshot, error)
 
 	GetVMInfo(...Option) VMs
 	GetVMScreenshot(...Option) ([]byte, error)
 	GetVNCEndpoint(...Option) (string, error)
+	GetVMConsolePort(...Option) (ConsoleEndpoint, error)
 	StartVM(...Option) error
 	StopVM(...Option) error
+	ShutdownVM(...Option) error
 	RedeployVM(...Option) error
 	KillVM(...Option) error
+	ForceKillVM(...Option) error
+	SnapshotVMDisk(...Option) error
 	GetVMHost(...Option) (string, error)
 	GetVMState(...Option) (string, error)
+	GetVMStates(...Option) map[string]string
 
 	ConnectVMInterface(...Option) error
 	DisconnectVMInterface(...Option) error
@@ -37,7 +46,16 @@ type MM interface {
 	GetNamespaceHosts(string) (Hosts, error)
 	Headnode() string
 	IsHeadnode(string) bool
+	PingHost(string) error
 	GetVLANs(...Option) (map[string]int, error)
+	SetLinkImpairment(string, string, int, float64, int) error
+	ClearLinkImpairment(string, string) error
+	GetInterfaceStats(string) ([]InterfaceStats, error)
+	GetVMDiskUsage(string) ([]VMDiskUsage, error)
+	SetVMTag(string, string, string, string) error
+	ClearVMTag(string, string, string) error
+	GetHostDiskSpace(string, string) (HostDiskSpace, error)
+	InjectDiskFiles(string, int, ...string) error
 
 	IsC2ClientActive(...C2Option) error
 	ExecC2Command(...C2Option) (string, error)
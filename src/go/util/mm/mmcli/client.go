@@ -146,6 +146,10 @@ func SingleDataResponse(responses chan *miniclient.Response) (interface{}, error
 // redialing if disconnected. Any errors encountered will be returned as part of
 // the response channel.
 func Run(c *Command) chan *miniclient.Response {
+	return observe(c, time.Now(), run(c))
+}
+
+func run(c *Command) chan *miniclient.Response {
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -0,0 +1,83 @@
+package mmcli
+
+import (
+	"sync"
+	"time"
+
+	"github.com/activeshadow/libminimega/minicli"
+	"github.com/activeshadow/libminimega/miniclient"
+)
+
+// Observer is called with each command run and all the responses to it, once
+// they've been received by the caller, along with how long they took.
+type Observer func(c Command, responses []*minicli.Response, took time.Duration)
+
+var (
+	observersMu  sync.RWMutex
+	observers    = make(map[uint64]Observer)
+	nextObserver uint64
+)
+
+// Observe registers the given observer to be called for every command run
+// until the returned function is called. Observers are called concurrently, so
+// they must be safe to call from multiple Goroutines.
+func Observe(o Observer) func() {
+	observersMu.Lock()
+	defer observersMu.Unlock()
+
+	nextObserver++
+	id := nextObserver
+
+	observers[id] = o
+
+	return func() {
+		observersMu.Lock()
+		defer observersMu.Unlock()
+
+		delete(observers, id)
+	}
+}
+
+// observe passes the given responses through to the returned channel,
+// collecting them for any registered observers. The responses are returned as
+// is if there are no observers.
+func observe(c *Command, started time.Time, in chan *miniclient.Response) chan *miniclient.Response {
+	observersMu.RLock()
+
+	if len(observers) == 0 {
+		observersMu.RUnlock()
+		return in
+	}
+
+	current := make([]Observer, 0, len(observers))
+
+	for _, o := range observers {
+		current = append(current, o)
+	}
+
+	observersMu.RUnlock()
+
+	var (
+		cmd = *c
+		out = make(chan *miniclient.Response)
+	)
+
+	go func() {
+		defer close(out)
+
+		var responses []*minicli.Response
+
+		for resp := range in {
+			responses = append(responses, resp.Resp...)
+			out <- resp
+		}
+
+		took := time.Since(started)
+
+		for _, o := range current {
+			o(cmd, responses, took)
+		}
+	}()
+
+	return out
+}
@@ -28,6 +28,10 @@ type options struct {
 
 	screenshotSize string
 
+	consoleType string
+
+	snapshotFile string
+
 	// tunnels
 	srcPort int
 	dstPort int
@@ -122,12 +126,24 @@ func CaptureFile(f string) Option {
 	}
 }
 
+func SnapshotFile(f string) Option {
+	return func(o *options) {
+		o.snapshotFile = f
+	}
+}
+
 func ScreenshotSize(s string) Option {
 	return func(o *options) {
 		o.screenshotSize = s
 	}
 }
 
+func ConsoleType(t string) Option {
+	return func(o *options) {
+		o.consoleType = t
+	}
+}
+
 func TunnelSourcePort(p int) Option {
 	return func(o *options) {
 		o.srcPort = p
@@ -1,5 +1,9 @@
 package mm
 
+func Ping() error {
+	return DefaultMM.Ping()
+}
+
 func ReadScriptFromFile(filename string) error {
 	return DefaultMM.ReadScriptFromFile(filename)
 }
@@ -16,6 +20,14 @@ func GetLaunchProgress(ns string, expected int) (float64, error) {
 	return DefaultMM.GetLaunchProgress(ns, expected)
 }
 
+func GetPerVMLaunchState(ns string) (map[string]string, error) {
+	return DefaultMM.GetPerVMLaunchState(ns)
+}
+
+func GetExperimentSnapshot(ns string, interfaces bool) (*ExperimentSnapshot, error) {
+	return DefaultMM.GetExperimentSnapshot(ns, interfaces)
+}
+
 func GetVMInfo(opts ...Option) VMs {
 	return DefaultMM.GetVMInfo(opts...)
 }
@@ -28,10 +40,18 @@ func GetVNCEndpoint(opts ...Option) (string, error) {
 	return DefaultMM.GetVNCEndpoint(opts...)
 }
 
+func GetVMConsolePort(opts ...Option) (ConsoleEndpoint, error) {
+	return DefaultMM.GetVMConsolePort(opts...)
+}
+
 func StartVM(opts ...Option) error {
 	return DefaultMM.StartVM(opts...)
 }
 
+func ShutdownVM(opts ...Option) error {
+	return DefaultMM.ShutdownVM(opts...)
+}
+
 func StopVM(opts ...Option) error {
 	return DefaultMM.StopVM(opts...)
 }
@@ -44,6 +64,14 @@ func KillVM(opts ...Option) error {
 	return DefaultMM.KillVM(opts...)
 }
 
+func ForceKillVM(opts ...Option) error {
+	return DefaultMM.ForceKillVM(opts...)
+}
+
+func SnapshotVMDisk(opts ...Option) error {
+	return DefaultMM.SnapshotVMDisk(opts...)
+}
+
 func GetVMHost(opts ...Option) (string, error) {
 	return DefaultMM.GetVMHost(opts...)
 }
@@ -52,6 +80,10 @@ func GetVMState(opts ...Option) (string, error) {
 	return DefaultMM.GetVMState(opts...)
 }
 
+func GetVMStates(opts ...Option) map[string]string {
+	return DefaultMM.GetVMStates(opts...)
+}
+
 func ConnectVMInterface(opts ...Option) error {
 	return DefaultMM.ConnectVMInterface(opts...)
 }
@@ -108,10 +140,46 @@ func IsHeadnode(node string) bool {
 	return DefaultMM.IsHeadnode(node)
 }
 
+func PingHost(host string) error {
+	return DefaultMM.PingHost(host)
+}
+
 func GetVLANs(opts ...Option) (map[string]int, error) {
 	return DefaultMM.GetVLANs(opts...)
 }
 
+func SetLinkImpairment(ns, vlan string, latencyMs int, lossPct float64, rateKbit int) error {
+	return DefaultMM.SetLinkImpairment(ns, vlan, latencyMs, lossPct, rateKbit)
+}
+
+func ClearLinkImpairment(ns, vlan string) error {
+	return DefaultMM.ClearLinkImpairment(ns, vlan)
+}
+
+func GetInterfaceStats(ns string) ([]InterfaceStats, error) {
+	return DefaultMM.GetInterfaceStats(ns)
+}
+
+func SetVMTag(ns, vm, key, value string) error {
+	return DefaultMM.SetVMTag(ns, vm, key, value)
+}
+
+func ClearVMTag(ns, vm, key string) error {
+	return DefaultMM.ClearVMTag(ns, vm, key)
+}
+
+func GetVMDiskUsage(ns string) ([]VMDiskUsage, error) {
+	return DefaultMM.GetVMDiskUsage(ns)
+}
+
+func GetHostDiskSpace(host, path string) (HostDiskSpace, error) {
+	return DefaultMM.GetHostDiskSpace(host, path)
+}
+
+func InjectDiskFiles(disk string, part int, injects ...string) error {
+	return DefaultMM.InjectDiskFiles(disk, part, injects...)
+}
+
 func IsC2ClientActive(opts ...C2Option) error {
 	return DefaultMM.IsC2ClientActive(opts...)
 }
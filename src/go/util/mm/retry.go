@@ -0,0 +1,89 @@
+package mm
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"phenix/util/mm/mmcli"
+)
+
+// Backoff configures how Retry retries a failing minimega call.
+type Backoff struct {
+	// Maximum number of times the call is attempted, including the first.
+	Attempts int
+
+	// Delay before the first retry, doubled after each retry up to Max.
+	Initial time.Duration
+	Max     time.Duration
+}
+
+// DefaultBackoff is suitable for calls made while monitoring an experiment,
+// giving minimega a few seconds to recover before giving up.
+var DefaultBackoff = Backoff{Attempts: 5, Initial: 250 * time.Millisecond, Max: 4 * time.Second}
+
+// Substrings of errors returned when minimega can't be reached or the
+// connection to it is interrupted. These tend to resolve themselves once
+// minimega is restarted or catches up.
+var transientErrors = []string{
+	"unable to dial",
+	"unable to redial",
+	"broken pipe",
+	"connection refused",
+	"connection reset",
+	"EOF",
+}
+
+// IsTransient reports whether the given error is likely a temporary failure to
+// communicate with minimega, and thus worth retrying.
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, mmcli.ErrTimeout) {
+		return true
+	}
+
+	msg := err.Error()
+
+	// Errors from minimega responses are returned as strings, so the original
+	// error types are lost.
+	if strings.Contains(msg, mmcli.ErrTimeout.Error()) {
+		return true
+	}
+
+	for _, s := range transientErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Retry calls the given function until it succeeds, returns an error that
+// isn't transient, the attempts configured in the given backoff are used up, or
+// the context is canceled, waiting exponentially longer between attempts. It
+// returns the last error returned by the function.
+func Retry(ctx context.Context, b Backoff, fn func() error) error {
+	delay := b.Initial
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsTransient(err) || attempt >= b.Attempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > b.Max {
+			delay = b.Max
+		}
+	}
+}
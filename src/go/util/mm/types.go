@@ -138,6 +138,9 @@ type Host struct {
 	Uptime      float64   `json:"uptime"`
 	Schedulable bool      `json:"schedulable"`
 	Headnode    bool      `json:"headnode"`
+
+	// PCI addresses of the GPUs that can be passed through to VMs.
+	GPUs []string `json:"gpus,omitempty"`
 }
 
 type DiskUsage struct {
@@ -205,6 +208,94 @@ func (this VMs) Paginate(page, size int) VMs {
 	return this[start:end]
 }
 
+// InterfaceStats holds the traffic counters for a single VM interface, as
+// returned by `GetInterfaceStats`. Counters are from the VM's point of view.
+type InterfaceStats struct {
+	VM        string `json:"vm"`
+	Interface int    `json:"interface"`
+	Network   string `json:"network"`
+	Tap       string `json:"tap"`
+	RxBytes   uint64 `json:"rxBytes"`
+	TxBytes   uint64 `json:"txBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	TxPackets uint64 `json:"txPackets"`
+}
+
+// VMDiskUsage is the size of a VM's disk, as returned by `GetVMDiskUsage`. For
+// VMs using a snapshot, the disk is a qcow2 overlay on top of the backing
+// image, and OverlayBytes is how much it has grown.
+type VMDiskUsage struct {
+	VM           string `json:"vm"`
+	Host         string `json:"host"`
+	Image        string `json:"image"`
+	ImageBytes   int64  `json:"imageBytes"`
+	Overlay      string `json:"overlay,omitempty"`
+	OverlayBytes int64  `json:"overlayBytes,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// HostDiskSpace is the size and free space of the filesystem a path is on for
+// a cluster host, as returned by `GetHostDiskSpace`.
+type HostDiskSpace struct {
+	Host        string  `json:"host"`
+	Path        string  `json:"path"`
+	TotalBytes  int64   `json:"totalBytes"`
+	FreeBytes   int64   `json:"freeBytes"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+// Launch states returned by `GetPerVMLaunchState`.
+const (
+	LaunchStatePending   = "pending"
+	LaunchStateLaunching = "launching"
+	LaunchStateRunning   = "running"
+	LaunchStateError     = "error"
+)
+
+// ExperimentSnapshot is the state of an experiment's VMs in minimega at a
+// point in time, as returned by `GetExperimentSnapshot`.
+type ExperimentSnapshot struct {
+	// Number of VMs still in the namespace's launch queue.
+	Queued int `json:"queued"`
+
+	// minimega state (e.g. RUNNING, PAUSED) of each launched VM, keyed by name.
+	VMStates map[string]string `json:"vmStates"`
+
+	// Launch state of each queued or launched VM, keyed by name, the same as
+	// returned by `GetPerVMLaunchState`.
+	LaunchStates map[string]string `json:"launchStates"`
+
+	// Only included if asked for, since reading them takes a call to each
+	// cluster host.
+	Interfaces []InterfaceStats `json:"interfaces"`
+	Captures   []Capture        `json:"captures"`
+
+	// Errors getting interface stats from cluster hosts, keyed by host. Stats
+	// are left out for VMs on those hosts.
+	InterfaceErrors map[string]string `json:"interfaceErrors,omitempty"`
+}
+
+// LaunchProgress returns the launch progress for the given number of expected
+// VMs, the same as `GetLaunchProgress` would have at the time of the snapshot.
+func (this ExperimentSnapshot) LaunchProgress(expected int) float64 {
+	if expected == 0 {
+		return 0
+	}
+
+	queued := this.Queued
+
+	// The launch queue will be empty once queued VMs have been launched.
+	if queued == 0 {
+		for _, state := range this.VMStates {
+			if state == "BUILDING" {
+				queued++
+			}
+		}
+	}
+
+	return float64(queued) / float64(expected)
+}
+
 type VM struct {
 	ID              int       `json:"id"`
 	Name            string    `json:"name"`
@@ -230,6 +321,7 @@ type VM struct {
 	CdRom           string    `json:"cdRom"`
 	Tags            []string  `json:"tags"`
 	Snapshot        bool      `json:"snapshot"`
+	Note            string    `json:"note,omitempty"`
 
 	// Used internally to track network <--> IP relationship, since
 	// network ordering from minimega may not be the same as network
@@ -269,6 +361,19 @@ func (this VM) Copy() VM {
 	return vm
 }
 
+// Console types supported by `GetVMConsolePort`.
+const (
+	ConsoleTypeVNC    = "vnc"
+	ConsoleTypeSerial = "serial"
+)
+
+// ConsoleEndpoint is the address to dial to access a VM's console, in the
+// form expected by `net.Dial`.
+type ConsoleEndpoint struct {
+	Network string
+	Address string
+}
+
 type Captures struct {
 	Captures []Capture `json:"captures"`
 }
@@ -277,6 +382,9 @@ type Capture struct {
 	VM        string `json:"vm"`
 	Interface int    `json:"interface"`
 	Filepath  string `json:"filepath"`
+
+	// Size of the capture file in bytes. Only populated by `vm.ListCaptures`.
+	Size int64 `json:"size,omitempty"`
 }
 
 type BlockDevice struct {
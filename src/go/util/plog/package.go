@@ -38,6 +38,17 @@ func With(args ...any) *slog.Logger {
 	return logger.With(args...)
 }
 
+// WithLevel returns a logger that logs to the "phenix-default" slog.Handler at
+// the given log level instead of the level set with SetLevel. Other handlers
+// keep their own levels.
+func WithLevel(l slog.Level) *slog.Logger {
+	if handler == nil {
+		return nil
+	}
+
+	return slog.New(handler.withLevel(l))
+}
+
 func Debug(msg string, args ...any) {
 	if logger == nil {
 		return
@@ -22,10 +22,16 @@ var (
 	ignore = map[string]struct{}{ScorchSohKey: {}}
 )
 
+// name of the slog.Handler logging to STDERR
+const defaultHandler = "phenix-default"
+
 // main phenix slog.Handler
 type phenixHandler struct {
 	handlers map[string]slog.Handler
 
+	// overrides the log level of the "phenix-default" handler if set
+	level slog.Leveler
+
 	mu sync.RWMutex
 }
 
@@ -47,7 +53,7 @@ func NewPhenixHandler() {
 	}
 
 	// handler.AddHandler("phenix-default", slog.NewTextHandler(os.Stderr, options))
-	handler.AddHandler("phenix-default", tint.NewHandler(os.Stderr, options))
+	handler.AddHandler(defaultHandler, tint.NewHandler(os.Stderr, options))
 }
 
 // AddHandler adds a new slog.Handler by name to the main phenix slog.Handler.
@@ -71,8 +77,8 @@ func (this *phenixHandler) Enabled(ctx context.Context, l slog.Level) bool {
 	this.mu.RLock()
 	defer this.mu.RUnlock()
 
-	for _, h := range this.handlers {
-		if h.Enabled(ctx, l) {
+	for n, h := range this.handlers {
+		if this.enabled(ctx, n, h, l) {
 			return true
 		}
 	}
@@ -87,8 +93,8 @@ func (this *phenixHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	var errs error
 
-	for _, h := range this.handlers {
-		if h.Enabled(ctx, r.Level) {
+	for n, h := range this.handlers {
+		if this.enabled(ctx, n, h, r.Level) {
 			errs = errors.Join(errs, h.Handle(ctx, r.Clone()))
 		}
 	}
@@ -96,6 +102,16 @@ func (this *phenixHandler) Handle(ctx context.Context, r slog.Record) error {
 	return errs
 }
 
+// enabled returns true if the named slog.Handler handles records with the given
+// level, taking into account any level override.
+func (this *phenixHandler) enabled(ctx context.Context, name string, h slog.Handler, l slog.Level) bool {
+	if name == defaultHandler && this.level != nil {
+		return l >= this.level.Level()
+	}
+
+	return h.Enabled(ctx, l)
+}
+
 // WithAttrs implements the slog.Handler interface for the phenix handler.
 func (this *phenixHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	this.mu.RLock()
@@ -107,7 +123,7 @@ func (this *phenixHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		with[n] = h.WithAttrs(attrs)
 	}
 
-	return &phenixHandler{handlers: with}
+	return &phenixHandler{handlers: with, level: this.level}
 }
 
 // WithGroup implements the slog.Handler interface for the phenix handler.
@@ -121,5 +137,20 @@ func (this *phenixHandler) WithGroup(name string) slog.Handler {
 		with[n] = h.WithGroup(name)
 	}
 
-	return &phenixHandler{handlers: with}
+	return &phenixHandler{handlers: with, level: this.level}
+}
+
+// withLevel returns a copy of the phenix handler that overrides the log level
+// of the "phenix-default" handler with the given level.
+func (this *phenixHandler) withLevel(l slog.Leveler) slog.Handler {
+	this.mu.RLock()
+	defer this.mu.RUnlock()
+
+	with := make(map[string]slog.Handler)
+
+	for n, h := range this.handlers {
+		with[n] = h
+	}
+
+	return &phenixHandler{handlers: with, level: l}
 }
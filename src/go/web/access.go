@@ -0,0 +1,233 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/middleware"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// systemCallerKey is the context key marking calls made by phenix itself (e.g.
+// scheduled starts and idle experiments being stopped) rather than on behalf of
+// a request. It's unexported so only this package can set it; users are never
+// trusted based on their username alone.
+type systemCallerKey struct{}
+
+// systemContext returns a context for calls phenix makes on its own, which
+// aren't subject to per-experiment permissions.
+func systemContext() context.Context {
+	return context.WithValue(context.Background(), systemCallerKey{}, true)
+}
+
+func isSystemCaller(ctx context.Context) bool {
+	v, _ := ctx.Value(systemCallerKey{}).(bool)
+	return v
+}
+
+// experimentOwner returns the user to make the owner of experiments created
+// with the given context. Experiments created by phenix itself, or when
+// requests aren't authenticated, are left unrestricted.
+func experimentOwner(ctx context.Context) string {
+	if isSystemCaller(ctx) || middleware.Unauthenticated(ctx) {
+		return ""
+	}
+
+	user, _ := ctx.Value("user").(string)
+	return user
+}
+
+// authorizeExperiment is called before an experiment is started or stopped to
+// check the user doing so has at least the given permission for it, on top of
+// the role check already done by the handler for the request. Calls made by
+// phenix itself, and users whose role allows overriding experiment permissions
+// (e.g. global admins), are always allowed. It's a variable so tests can
+// replace it.
+var authorizeExperiment = func(ctx context.Context, name, user string, required experiment.Permission) error {
+	if isSystemCaller(ctx) {
+		return nil
+	}
+
+	err := experiment.CheckPermission(name, user, required)
+	if err == nil || !errors.Is(err, experiment.ErrPermissionDenied) {
+		return err
+	}
+
+	// The role is in the context for requests, including unauthenticated ones.
+	if role, ok := ctx.Value("role").(rbac.Role); ok {
+		if role.Allowed("experiments/access", "override", name) {
+			return nil
+		}
+
+		return err
+	}
+
+	if u, uerr := rbac.GetUser(user); uerr == nil {
+		if role, rerr := u.Role(); rerr == nil && role.Allowed("experiments/access", "override", name) {
+			return nil
+		}
+	}
+
+	return err
+}
+
+// authorizeExperimentAction wraps authorizeExperiment, returning a web error
+// describing the given action (e.g. "starting") if the user isn't authorized.
+func authorizeExperimentAction(ctx context.Context, name, user, action string, required experiment.Permission) error {
+	err := authorizeExperiment(ctx, name, user, required)
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, experiment.ErrPermissionDenied):
+		werr := weberror.NewWebError(err, "%s experiment %s not allowed for %s", action, name, user)
+		return werr.SetStatus(http.StatusForbidden).SetCode(weberror.PermissionDenied)
+	case errors.Is(err, store.ErrNotExist):
+		werr := weberror.NewWebError(err, "experiment %s not found", name)
+		return werr.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	werr := weberror.NewWebError(err, "unable to check permissions for experiment %s", name)
+	return werr.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
+}
+
+// GET /experiments/{name}/access
+func GetExperimentAccess(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentAccess")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/access", "get", name) {
+		err := weberror.NewWebError(nil, "getting access for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, user, "getting access for", experiment.PermissionViewer); err != nil {
+		return err
+	}
+
+	body, err := experimentAccess(name)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PATCH /experiments/{name}/access
+//
+// Grants the permissions in the request body, of the form `{"users":
+// {"<user>": "viewer|editor|owner"}}`, to the given users for the experiment.
+// An empty permission revokes the user's access. Only owners can change who has
+// access to an experiment.
+func UpdateExperimentAccess(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateExperimentAccess")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/access", "patch", name) {
+		err := weberror.NewWebError(nil, "updating access for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Users map[string]experiment.Permission `json:"users"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse access request for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if len(req.Users) == 0 {
+		err := weberror.NewWebError(nil, "no users provided for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, user, "updating access for", experiment.PermissionOwner); err != nil {
+		return err
+	}
+
+	if err := cache.LockExperimentForUpdate(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", name)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	err := experiment.SetPermissions(name, req.Users)
+	cache.UnlockExperiment(name)
+
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to update access for experiment %s", name)
+
+		if errors.Is(err, experiment.ErrInvalidPermission) {
+			return werr.SetStatus(http.StatusBadRequest)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := experimentAccess(name)
+	if err != nil {
+		return err
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/access", "get", name),
+		bt.NewResource("experiment/access", name, "update"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+func experimentAccess(name string) ([]byte, error) {
+	perms, err := experiment.Permissions(name)
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to get access for experiment %s", name)
+
+		if errors.Is(err, store.ErrNotExist) {
+			return nil, werr.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+		}
+
+		return nil, werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(map[string]any{"users": perms})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process access for experiment %s", name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	return body, nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"phenix/api/vm"
 	"phenix/app"
@@ -23,6 +24,21 @@ var (
 	unregister = make(chan *Client, 1024)
 )
 
+// Clients currently connected, for checking whether anyone is listening from
+// outside the goroutine managing the clients.
+var (
+	subscribers   = make(map[*Client]struct{})
+	subscribersMu sync.RWMutex
+)
+
+// Last sequence number published for each experiment. Never reset, so
+// sequence numbers stay monotonic even if an experiment is deleted and
+// recreated.
+var (
+	sequences   = make(map[string]uint64)
+	sequencesMu sync.Mutex
+)
+
 func Start() {
 	triggerSub := pubsub.Subscribe("trigger-app")
 	delayedSub := pubsub.Subscribe("delayed-start")
@@ -87,13 +103,39 @@ func Start() {
 			broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: body}
 		case cli := <-register:
 			clients[cli] = true
+
+			subscribersMu.Lock()
+			subscribers[cli] = struct{}{}
+			subscribersMu.Unlock()
 		case cli := <-unregister:
 			if _, ok := clients[cli]; ok {
 				cli.Stop()
 				delete(clients, cli)
+
+				subscribersMu.Lock()
+				delete(subscribers, cli)
+				subscribersMu.Unlock()
 			}
 		case pub := <-broadcast:
+			if sequenced(pub.Resource) {
+				// Copy the resource so callers reusing it don't see the sequence number.
+				resource := *pub.Resource
+				resource.Seq = nextSequence(resource.Name)
+				pub.Resource = &resource
+			}
+
+			compressed := newCompressedResults(pub)
+
 			for cli := range clients {
+				// Experiment logs are only sent to clients subscribed to them.
+				if pub.Resource != nil && pub.Resource.Type == "experiment/logs" && !cli.subscribedToLogs(pub.Resource.Name) {
+					continue
+				}
+
+				if !cli.wants(pub.Resource) {
+					continue
+				}
+
 				var (
 					policy = pub.RequestPolicy
 					allow  bool
@@ -108,18 +150,63 @@ func Start() {
 				}
 
 				if allow {
-					select {
-					case cli.publish <- pub:
-					default:
-						cli.Stop()
-						delete(clients, cli)
-					}
+					cli.enqueue(compressed.forEncoding(cli.encoding))
 				}
 			}
 		}
 	}
 }
 
-func Broadcast(policy *bt.RequestPolicy, resource *bt.Resource, msg json.RawMessage) {
-	broadcast <- bt.Publish{RequestPolicy: policy, Resource: resource, Result: msg}
+// HasSubscribers returns true if any connected client wants resources of the
+// given type with the given name (e.g. "experiment" and the experiment's name)
+// published to it. Clients that aren't allowed to see the resource still count.
+// It can be used to skip building updates nobody is listening for, but only for
+// updates clients can catch up on later (e.g. progress), since clients may
+// connect right after it's called.
+func HasSubscribers(typ, name string) bool {
+	subscribersMu.RLock()
+	defer subscribersMu.RUnlock()
+
+	resource := bt.NewResource(typ, name, "")
+
+	for cli := range subscribers {
+		if cli.wants(resource) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Sequence returns the sequence number of the last experiment or VM state
+// change published for the given experiment, or zero if none have been.
+func Sequence(exp string) uint64 {
+	sequencesMu.Lock()
+	defer sequencesMu.Unlock()
+
+	return sequences[exp]
+}
+
+// sequenced returns true if the given resource should have a sequence number,
+// which is the case for experiment and VM state changes. Progress updates are
+// left out since they're coalesced for slow clients anyway.
+func sequenced(resource *bt.Resource) bool {
+	if resource == nil || resource.Action == "progress" {
+		return false
+	}
+
+	return resource.Type == "experiment" || resource.Type == "experiment/vm"
+}
+
+// nextSequence increments and returns the sequence number for the experiment
+// the given resource name belongs to.
+func nextSequence(name string) uint64 {
+	exp, _, _ := strings.Cut(name, "/")
+
+	sequencesMu.Lock()
+	defer sequencesMu.Unlock()
+
+	sequences[exp]++
+
+	return sequences[exp]
 }
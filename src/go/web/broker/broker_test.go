@@ -0,0 +1,114 @@
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+func TestSequenced(t *testing.T) {
+	for _, r := range []*bt.Resource{
+		bt.NewResource("experiment", "seq", "start"),
+		bt.NewResource("experiment/vm", "seq/host", "update"),
+	} {
+		if !sequenced(r) {
+			t.Errorf("expected %s resource %s to be sequenced", r.Type, r.Name)
+		}
+	}
+
+	for _, r := range []*bt.Resource{
+		nil,
+		bt.NewResource("experiment", "seq", "progress"),
+		bt.NewResource("experiment/vm/screenshot", "seq/host", "update"),
+		bt.NewResource("experiment/logs", "seq", "log"),
+	} {
+		if sequenced(r) {
+			t.Errorf("expected resource %v to not be sequenced", r)
+		}
+	}
+
+	// VMs share their experiment's sequence.
+	nextSequence("seq")
+	nextSequence("seq/host")
+
+	if seq := Sequence("seq"); seq != 2 {
+		t.Errorf("expected sequence 2, got %d", seq)
+	}
+
+	if seq := Sequence("other"); seq != 0 {
+		t.Errorf("expected sequence 0 for unknown experiment, got %d", seq)
+	}
+}
+
+func TestBroadcastCoalesce(t *testing.T) {
+	SetCoalesceWindow(50 * time.Millisecond)
+	defer SetCoalesceWindow(DefaultCoalesceWindow)
+
+	for i := 0; i < 3; i++ {
+		Broadcast(nil, bt.NewResource("experiment/vm", "coalesce/host", "update"), json.RawMessage(fmt.Sprintf("%d", i)))
+	}
+
+	select {
+	case pub := <-broadcast:
+		if string(pub.Result) != "2" {
+			t.Fatalf("expected latest update to be published, got %s", pub.Result)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected coalesced update to be published")
+	}
+
+	select {
+	case pub := <-broadcast:
+		t.Fatalf("expected a single update to be published, got %s", pub.Result)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// State changes are published right away, after any pending updates.
+	Broadcast(nil, bt.NewResource("experiment", "coalesce", "update"), json.RawMessage("1"))
+	Broadcast(nil, bt.NewResource("experiment", "coalesce", "start"), json.RawMessage("2"))
+
+	for _, expected := range []string{"update", "start"} {
+		select {
+		case pub := <-broadcast:
+			if pub.Resource.Action != expected {
+				t.Fatalf("expected %s to be published, got %s", expected, pub.Resource.Action)
+			}
+		default:
+			t.Fatalf("expected %s to be published right away", expected)
+		}
+	}
+}
+
+func TestHasSubscribers(t *testing.T) {
+	if HasSubscribers("experiment", "subs") {
+		t.Fatal("expected no subscribers without any clients")
+	}
+
+	cli := new(Client)
+	cli.updateFilter("subs", "subscribe", []byte(`{"types": ["experiment/vm"]}`))
+
+	subscribersMu.Lock()
+	subscribers[cli] = struct{}{}
+	subscribersMu.Unlock()
+
+	defer func() {
+		subscribersMu.Lock()
+		delete(subscribers, cli)
+		subscribersMu.Unlock()
+	}()
+
+	if HasSubscribers("experiment", "subs") {
+		t.Error("expected no subscribers for filtered out resource type")
+	}
+
+	if !HasSubscribers("experiment/vm", "subs/host") {
+		t.Error("expected subscriber for resource type in filter")
+	}
+
+	if !HasSubscribers("experiment", "other") {
+		t.Error("expected subscriber for experiment without a filter")
+	}
+}
@@ -18,6 +18,10 @@ type Resource struct {
 	Type   string `json:"type"`
 	Name   string `json:"name"`
 	Action string `json:"action"`
+
+	// Per-experiment sequence number set by the broker on published experiment
+	// and VM state changes. Zero for everything else.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 func NewResource(t, n, a string) *Resource {
@@ -28,6 +32,11 @@ type Publish struct {
 	RequestPolicy *RequestPolicy  `json:"-"`
 	Resource      *Resource       `json:"resource"`
 	Result        json.RawMessage `json:"result"`
+
+	// Set to gzip or deflate when the result has been compressed for the
+	// client, in which case the result is a base64 encoded string of the
+	// compressed JSON.
+	Encoding string `json:"encoding,omitempty"`
 }
 
 type Request struct {
@@ -79,4 +88,95 @@ Screenshot Updates:
 		"screenshot": "data:image/png;base64,..."
 	}
 }
+
+Experiment Logs (subscribe/unsubscribe request):
+
+{
+	"resource": {
+		"type": "experiment/logs",
+		"name": "<exp name>",
+		"action": "subscribe"
+	}
+}
+
+Experiment Resource Filter (subscribe/unsubscribe request):
+
+Limits the resources published to the client for the given experiment to the
+given types. Unsubscribing, or subscribing without any
+types, goes back to publishing all resources for the experiment.
+
+{
+	"resource": {
+		"type": "experiment/filter",
+		"name": "<exp name>",
+		"action": "subscribe"
+	},
+	"request": {
+		"types": ["experiment", "experiment/vm"]
+	}
+}
+
+Sequence Numbers:
+
+Published "experiment" and "experiment/vm" resources, other than progress
+updates, include a sequence number that increases by one with each one
+published for the experiment (resources named `<exp name>` or
+`<exp name>/<vm name>`). Messages can be dropped for clients that don't keep up,
+so a client that sees a sequence number other than one more than the last one
+it saw for an experiment may have missed a state change. It should then call
+`GET /api/v1/experiments/{name}/resync?since=<last seen>`, replace its state
+for the experiment with the one returned, and continue from the returned
+sequence number, ignoring any messages at or below it. A sequence number lower
+than the last one seen means the server was restarted, and should be handled
+the same way.
+
+Clients filtering out either resource type, or without access to all of an
+experiment's VMs, will see gaps for messages they weren't sent and should
+not rely on sequence numbers.
+
+{
+	"resource": {
+		"type": "experiment/vm",
+		"name": "<exp name>/<vm name>",
+		"action": "start",
+		"seq": 42
+	},
+	"result": {
+		...
+	}
+}
+
+Experiment Log Updates:
+
+{
+	"resource": {
+		"type": "experiment/logs",
+		"name": "<exp name>",
+		"action": "log"
+	},
+	"result": {
+		"severity": "info",
+		"timestamp": "2006-01-02T15:04:05.999999999Z",
+		"message": "..."
+	}
+}
+
+Compressed Results:
+
+Clients can ask for large results (e.g. experiment and VM lists) to be
+compressed by requesting the "phenix.gzip" or "phenix.deflate" WebSocket
+subprotocol when connecting. Results at or above the server's compression
+threshold are then published as a base64 encoded string of the compressed
+JSON, with the encoding set. Smaller results, and results for clients that
+don't request a subprotocol, are published uncompressed.
+
+{
+	"resource": {
+		"type": "experiment",
+		"name": "<exp name>",
+		"action": "start"
+	},
+	"result": "H4sIAAAAAAAA/...",
+	"encoding": "gzip"
+}
 */
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"phenix/api/experiment"
@@ -38,6 +39,10 @@ const (
 	pongWait   = 60 * time.Second
 	pingPeriod = (pongWait * 9) / 10
 	maxMsgSize = 2048
+
+	// Number of messages buffered for each client before the oldest ones start
+	// getting dropped.
+	publishBufferSize = 256
 )
 
 var (
@@ -45,6 +50,7 @@ var (
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  4096,
 		WriteBufferSize: 4096,
+		Subprotocols:    []string{SubprotocolGzip, SubprotocolDeflate},
 	}
 )
 
@@ -53,24 +59,132 @@ type Client struct {
 	conn   *websocket.Conn
 	connMu sync.Mutex
 
+	// Encoding large published results are compressed with, as negotiated in
+	// the WebSocket handshake. Empty if the client didn't ask for compression.
+	encoding string
+
 	publish chan interface{}
 	done    chan struct{}
 	once    sync.Once
 
+	// Progress messages are coalesced per resource rather than queued in
+	// `publish` so slow clients only ever see the latest progress. The
+	// `progressC` channel signals the writer that progress is pending.
+	progress   map[string]bt.Publish
+	progressMu sync.Mutex
+	progressC  chan struct{}
+
+	// Count of messages dropped because this client couldn't keep up.
+	dropped atomic.Uint64
+
 	// Track the VMs this client currently has in view, if any, so we know
 	// what screenshots need to periodically be pushed to the client over
 	// the WebSocket connection.
 	vms  []vmScope
 	vmMu sync.RWMutex
+
+	// Experiments this client has subscribed to logs for.
+	logs   map[string]struct{}
+	logsMu sync.RWMutex
+
+	// Resource types this client wants published to it, per experiment. All
+	// resource types are published for experiments without a filter.
+	filters   map[string]map[string]struct{}
+	filtersMu sync.RWMutex
 }
 
 func NewClient(role rbac.Role, conn *websocket.Conn) *Client {
-	return &Client{
+	cli := &Client{
 		role:    role,
 		conn:    conn,
-		publish: make(chan interface{}, 256),
+		publish: make(chan interface{}, publishBufferSize),
 		done:    make(chan struct{}),
+
+		progress:  make(map[string]bt.Publish),
+		progressC: make(chan struct{}, 1),
+	}
+
+	if conn != nil {
+		cli.encoding = compressionEncoding(conn.Subprotocol())
 	}
+
+	return cli
+}
+
+// Dropped returns the number of messages dropped for this client because it
+// wasn't reading them fast enough.
+func (this *Client) Dropped() uint64 {
+	return this.dropped.Load()
+}
+
+// enqueue queues the given message to be published to the client without ever
+// blocking. Progress messages replace any pending progress message for the
+// same resource, and if the client's buffer is full the oldest message is
+// dropped to make room. Any other message for a resource drops its pending
+// progress message, since the pending one would otherwise be published after,
+// for example, the final `start` message and leave the client showing stale
+// progress.
+func (this *Client) enqueue(pub bt.Publish) {
+	if pub.Resource != nil && pub.Resource.Action == "progress" {
+		key := progressKey(pub.Resource)
+
+		this.progressMu.Lock()
+
+		if _, ok := this.progress[key]; ok {
+			this.dropped.Add(1)
+		}
+
+		this.progress[key] = pub
+
+		this.progressMu.Unlock()
+
+		select {
+		case this.progressC <- struct{}{}:
+		default:
+		}
+
+		return
+	}
+
+	if pub.Resource != nil {
+		this.progressMu.Lock()
+		delete(this.progress, progressKey(pub.Resource))
+		this.progressMu.Unlock()
+	}
+
+	for {
+		select {
+		case this.publish <- pub:
+			return
+		default:
+		}
+
+		select {
+		case <-this.publish:
+			this.dropped.Add(1)
+		default:
+		}
+	}
+}
+
+func progressKey(r *bt.Resource) string {
+	return r.Type + "|" + r.Name
+}
+
+// pendingProgress returns and clears the progress messages not yet published
+// to the client.
+func (this *Client) pendingProgress() []bt.Publish {
+	this.progressMu.Lock()
+	defer this.progressMu.Unlock()
+
+	pending := make([]bt.Publish, 0, len(this.progress))
+
+	for key, pub := range this.progress {
+		pending = append(pending, pub)
+		delete(this.progress, key)
+	}
+
+	return pending
 }
 
 func (this *Client) Go() {
@@ -99,6 +213,114 @@ func (this *Client) stop() {
 	this.conn.Close()
 }
 
+// subscribedToLogs returns true if the client has subscribed to the logs for
+// the given experiment.
+func (this *Client) subscribedToLogs(exp string) bool {
+	this.logsMu.RLock()
+	defer this.logsMu.RUnlock()
+
+	_, ok := this.logs[exp]
+	return ok
+}
+
+func (this *Client) updateLogSubscription(exp, action string) {
+	switch action {
+	case "subscribe":
+		if !this.role.Allowed("experiments/logs", "get", exp) {
+			plog.Warn("client access to experiment logs forbidden", "exp", exp)
+			return
+		}
+
+		this.logsMu.Lock()
+		defer this.logsMu.Unlock()
+
+		if this.logs == nil {
+			this.logs = make(map[string]struct{})
+		}
+
+		this.logs[exp] = struct{}{}
+	case "unsubscribe":
+		this.logsMu.Lock()
+		defer this.logsMu.Unlock()
+
+		delete(this.logs, exp)
+	default:
+		plog.Error("unexpected WebSocket request resource action for experiment/logs resource type", "action", action)
+	}
+}
+
+// wants returns true if the given resource should be published to the client
+// based on the resource filters it has subscribed with. A resource belongs to
+// an experiment if it's named after it, either exactly or as a prefix (e.g.
+// `<exp name>/<vm name>`). Types must match exactly, so `experiment/vm` doesn't
+// also match `experiment/vm/screenshot`.
+func (this *Client) wants(resource *bt.Resource) bool {
+	if resource == nil {
+		return true
+	}
+
+	this.filtersMu.RLock()
+	defer this.filtersMu.RUnlock()
+
+	if len(this.filters) == 0 {
+		return true
+	}
+
+	exp, _, _ := strings.Cut(resource.Name, "/")
+
+	types, ok := this.filters[exp]
+	if !ok {
+		return true
+	}
+
+	_, ok = types[resource.Type]
+	return ok
+}
+
+func (this *Client) updateFilter(exp, action string, payload json.RawMessage) {
+	switch action {
+	case "subscribe":
+		var req struct {
+			Types []string `json:"types"`
+		}
+
+		if len(payload) > 0 {
+			if err := json.Unmarshal(payload, &req); err != nil {
+				plog.Error("cannot unmarshal experiment filter request payload", "err", err)
+				return
+			}
+		}
+
+		this.filtersMu.Lock()
+		defer this.filtersMu.Unlock()
+
+		// No types means all of them, same as having no filter at all.
+		if len(req.Types) == 0 {
+			delete(this.filters, exp)
+			return
+		}
+
+		if this.filters == nil {
+			this.filters = make(map[string]map[string]struct{})
+		}
+
+		types := make(map[string]struct{})
+
+		for _, typ := range req.Types {
+			types[typ] = struct{}{}
+		}
+
+		this.filters[exp] = types
+	case "unsubscribe":
+		this.filtersMu.Lock()
+		defer this.filtersMu.Unlock()
+
+		delete(this.filters, exp)
+	default:
+		plog.Error("unexpected WebSocket request resource action for experiment/filter resource type", "action", action)
+	}
+}
+
 func (this *Client) read() {
 	defer this.Stop()
 
@@ -141,6 +363,12 @@ func (this *Client) read() {
 			}
 
 			switch req.Resource.Type {
+			case "experiment/logs":
+				this.updateLogSubscription(req.Resource.Name, req.Resource.Action)
+				continue
+			case "experiment/filter":
+				this.updateFilter(req.Resource.Name, req.Resource.Action, req.Payload)
+				continue
 			case "experiment/vms":
 			case "experiment/topology":
 				// TODO: check RBAC permissions?
@@ -380,6 +608,8 @@ func (this *Client) write() {
 	defer ticker.Stop()
 	defer this.Stop()
 
+	var reported uint64
+
 	for {
 		select {
 		case <-this.done:
@@ -388,7 +618,18 @@ func (this *Client) write() {
 			if err := this.publisher(msg); err != nil {
 				plog.Error("publishing message to client", "err", err)
 			}
+		case <-this.progressC:
+			for _, msg := range this.pendingProgress() {
+				if err := this.publisher(msg); err != nil {
+					plog.Error("publishing progress message to client", "err", err)
+				}
+			}
 		case <-ticker.C:
+			if dropped := this.Dropped(); dropped > reported {
+				plog.Warn("client not keeping up with published messages", "client", this.conn.RemoteAddr().String(), "dropped", dropped)
+				reported = dropped
+			}
+
 			if err := this.conn.SetWriteDeadline(time.Now().Add(writeWait)); err != nil {
 				plog.Error("setting write deadline for client connection", "err", err)
 				return
@@ -417,7 +658,7 @@ func (this *Client) publisher(msg interface{}) error {
 
 	defer w.Close()
 
-	b, err := json.Marshal(msg)
+	b, err := this.marshal(msg)
 	if err != nil {
 		plog.Error("marshaling message to be published", "err", err)
 		return nil
@@ -434,7 +675,7 @@ func (this *Client) publisher(msg interface{}) error {
 
 		msg := <-this.publish
 
-		b, err := json.Marshal(msg)
+		b, err := this.marshal(msg)
 		if err != nil {
 			plog.Error("marshaling message to be published", "err", err)
 			continue
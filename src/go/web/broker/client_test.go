@@ -0,0 +1,59 @@
+package broker
+
+import (
+	"testing"
+
+	"phenix/web/rbac"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+func TestClientWants(t *testing.T) {
+	cli := new(Client)
+
+	var (
+		progress   = bt.NewResource("experiment", "foo", "progress")
+		vmState    = bt.NewResource("experiment/vm", "foo/host", "start")
+		screenshot = bt.NewResource("experiment/vm/screenshot", "foo/host", "update")
+		logs       = bt.NewResource("experiment/logs", "foo", "log")
+		other      = bt.NewResource("experiment/logs", "bar", "log")
+	)
+
+	if !cli.wants(logs) {
+		t.Fatal("expected all resources to be wanted without a filter")
+	}
+
+	cli.updateFilter("foo", "subscribe", []byte(`{"types": ["experiment", "experiment/vm"]}`))
+
+	for _, r := range []*bt.Resource{progress, vmState, other} {
+		if !cli.wants(r) {
+			t.Errorf("expected %s resource %s to be wanted", r.Type, r.Name)
+		}
+	}
+
+	for _, r := range []*bt.Resource{screenshot, logs} {
+		if cli.wants(r) {
+			t.Errorf("expected filtered %s resource %s to not be wanted", r.Type, r.Name)
+		}
+	}
+
+	cli.updateFilter("foo", "unsubscribe", nil)
+
+	if !cli.wants(logs) {
+		t.Error("expected all resources to be wanted after unsubscribing")
+	}
+}
+
+func TestClientEnqueueDropsStaleProgress(t *testing.T) {
+	cli := NewClient(rbac.Role{}, nil)
+
+	cli.enqueue(bt.Publish{Resource: bt.NewResource("experiment", "foo", "progress")})
+	cli.enqueue(bt.Publish{Resource: bt.NewResource("experiment", "bar", "progress")})
+	cli.enqueue(bt.Publish{Resource: bt.NewResource("experiment", "foo", "start")})
+
+	pending := cli.pendingProgress()
+
+	if len(pending) != 1 || pending[0].Resource.Name != "bar" {
+		t.Fatalf("expected only progress for bar to be pending, got %v", pending)
+	}
+}
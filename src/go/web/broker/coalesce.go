@@ -0,0 +1,106 @@
+package broker
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// DefaultCoalesceWindow is how long updates to a resource are held by default
+// so any later updates to it within the window replace them.
+const DefaultCoalesceWindow = 200 * time.Millisecond
+
+// Resource actions that are coalesced. Any other action (e.g. start, stop or an
+// error) is a state change clients need to see, so it's always published, after
+// publishing any pending updates to the same resource so clients see them in
+// order.
+var coalescedActions = []string{"update", "progress"}
+
+var (
+	coalesceWindow = DefaultCoalesceWindow
+
+	// Updates waiting to be published, keyed by resource type, name and action.
+	pending   = make(map[string]*pendingPublish)
+	pendingMu sync.Mutex
+)
+
+type pendingPublish struct {
+	pub bt.Publish
+}
+
+// SetCoalesceWindow sets how long updates to a resource are held so any later
+// updates to it within the window replace them, reducing how many messages are
+// published to clients when a resource is updated repeatedly (e.g. VMs while an
+// experiment is starting). Zero disables coalescing.
+func SetCoalesceWindow(d time.Duration) {
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	coalesceWindow = d
+}
+
+func Broadcast(policy *bt.RequestPolicy, resource *bt.Resource, msg json.RawMessage) {
+	pub := bt.Publish{RequestPolicy: policy, Resource: resource, Result: msg}
+
+	// Publishing while holding the lock keeps updates in order with any state
+	// changes published for the same resource.
+	pendingMu.Lock()
+	defer pendingMu.Unlock()
+
+	if resource == nil {
+		broadcast <- pub
+		return
+	}
+
+	if coalesceWindow > 0 && coalesced(resource.Action) {
+		key := coalesceKey(resource.Type, resource.Name, resource.Action)
+
+		if p, ok := pending[key]; ok {
+			p.pub = pub
+			return
+		}
+
+		p := &pendingPublish{pub: pub}
+		pending[key] = p
+
+		time.AfterFunc(coalesceWindow, func() {
+			pendingMu.Lock()
+			defer pendingMu.Unlock()
+
+			// The update may have already been published ahead of a state change.
+			if pending[key] == p {
+				delete(pending, key)
+				broadcast <- p.pub
+			}
+		})
+
+		return
+	}
+
+	for _, action := range coalescedActions {
+		key := coalesceKey(resource.Type, resource.Name, action)
+
+		if p, ok := pending[key]; ok {
+			delete(pending, key)
+			broadcast <- p.pub
+		}
+	}
+
+	broadcast <- pub
+}
+
+func coalesced(action string) bool {
+	for _, a := range coalescedActions {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
+
+func coalesceKey(typ, name, action string) string {
+	return typ + "|" + name + "|" + action
+}
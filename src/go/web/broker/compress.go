@@ -0,0 +1,159 @@
+package broker
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"phenix/util/plog"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// DefaultCompressionThreshold is the default size (in bytes) of a published
+// result at or above which it's compressed for clients that asked for it.
+const DefaultCompressionThreshold = 16 * 1024
+
+// WebSocket subprotocols clients can request in the handshake to have large
+// published results compressed. Clients that don't request either get
+// uncompressed results.
+const (
+	SubprotocolGzip    = "phenix.gzip"
+	SubprotocolDeflate = "phenix.deflate"
+)
+
+var compressionThreshold atomic.Int64
+
+func init() {
+	compressionThreshold.Store(DefaultCompressionThreshold)
+}
+
+// SetCompressionThreshold sets the size (in bytes) of a published result at or
+// above which it's compressed for clients that asked for compression. Smaller
+// results, like progress updates, aren't worth the overhead. Zero disables
+// compression.
+func SetCompressionThreshold(n int) {
+	compressionThreshold.Store(int64(n))
+}
+
+// compressionEncoding returns the encoding to compress results with for the
+// given negotiated WebSocket subprotocol, or an empty string for none.
+func compressionEncoding(subprotocol string) string {
+	switch subprotocol {
+	case SubprotocolGzip:
+		return "gzip"
+	case SubprotocolDeflate:
+		return "deflate"
+	}
+
+	return ""
+}
+
+// compressedResults caches a broadcast result compressed with each encoding
+// clients asked for, so large results are compressed once per broadcast rather
+// than once per connected client.
+type compressedResults struct {
+	pub     bt.Publish
+	results map[string]bt.Publish
+}
+
+func newCompressedResults(pub bt.Publish) *compressedResults {
+	return &compressedResults{pub: pub, results: make(map[string]bt.Publish)}
+}
+
+// forEncoding returns the message to publish to clients using the given
+// encoding, compressing its result the first time the encoding is asked for.
+func (this *compressedResults) forEncoding(encoding string) bt.Publish {
+	if encoding == "" || !compressible(this.pub) {
+		return this.pub
+	}
+
+	if pub, ok := this.results[encoding]; ok {
+		return pub
+	}
+
+	pub, err := compressPublish(this.pub, encoding)
+	if err != nil {
+		plog.Error("compressing broadcast result", "encoding", encoding, "err", err)
+		pub = this.pub
+	}
+
+	this.results[encoding] = pub
+
+	return pub
+}
+
+// marshal encodes the given message to be written to the client, compressing
+// its result using the client's encoding if it's large enough and hasn't
+// already been compressed.
+func (this *Client) marshal(msg interface{}) ([]byte, error) {
+	pub, ok := msg.(bt.Publish)
+	if !ok || this.encoding == "" || pub.Encoding != "" || !compressible(pub) {
+		return json.Marshal(msg)
+	}
+
+	pub, err := compressPublish(pub, this.encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(pub)
+}
+
+// compressible returns true if the given message's result is large enough to
+// be compressed.
+func compressible(pub bt.Publish) bool {
+	threshold := compressionThreshold.Load()
+
+	return threshold > 0 && int64(len(pub.Result)) >= threshold
+}
+
+// compressPublish returns a copy of the given message with its result
+// compressed using the given encoding.
+func compressPublish(pub bt.Publish, encoding string) (bt.Publish, error) {
+	compressed, err := compress(encoding, pub.Result)
+	if err != nil {
+		return pub, fmt.Errorf("compressing published result: %w", err)
+	}
+
+	// Compressed results are sent as a base64 encoded JSON string, with the
+	// encoding set so clients know to decode and decompress them.
+	pub.Result, _ = json.Marshal(base64.StdEncoding.EncodeToString(compressed))
+	pub.Encoding = encoding
+
+	return pub, nil
+}
+
+func compress(encoding string, data []byte) ([]byte, error) {
+	var (
+		buf bytes.Buffer
+		w   io.WriteCloser
+		err error
+	)
+
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(&buf)
+	case "deflate":
+		if w, err = flate.NewWriter(&buf, flate.DefaultCompression); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown encoding %s", encoding)
+	}
+
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
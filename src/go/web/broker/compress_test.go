@@ -0,0 +1,120 @@
+package broker
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"testing"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+func TestClientMarshalCompressed(t *testing.T) {
+	defer SetCompressionThreshold(DefaultCompressionThreshold)
+
+	SetCompressionThreshold(64)
+
+	var (
+		small = bt.Publish{Resource: bt.NewResource("experiment", "foo", "progress"), Result: []byte(`{"percent":0.5}`)}
+		large = bt.Publish{Resource: bt.NewResource("experiment", "foo", "start"), Result: []byte(`{"vms":"` + string(bytes.Repeat([]byte("a"), 128)) + `"}`)}
+	)
+
+	cli := &Client{encoding: "gzip"}
+
+	for _, pub := range []bt.Publish{small, large} {
+		body, err := new(Client).marshal(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if bytes.Contains(body, []byte(`"encoding"`)) {
+			t.Fatalf("expected uncompressed result for client without an encoding, got %s", body)
+		}
+	}
+
+	body, err := cli.marshal(small)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(body, []byte(`"encoding"`)) {
+		t.Fatalf("expected small result to be left uncompressed, got %s", body)
+	}
+
+	body, err = cli.marshal(large)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg struct {
+		Result   string `json:"result"`
+		Encoding string `json:"encoding"`
+	}
+
+	if err := json.Unmarshal(body, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	if msg.Encoding != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", msg.Encoding)
+	}
+
+	compressed, err := base64.StdEncoding.DecodeString(msg.Result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(result, large.Result) {
+		t.Fatalf("expected decompressed result %s, got %s", large.Result, result)
+	}
+}
+
+func TestCompressedResults(t *testing.T) {
+	defer SetCompressionThreshold(DefaultCompressionThreshold)
+
+	SetCompressionThreshold(64)
+
+	large := bt.Publish{Resource: bt.NewResource("experiment", "foo", "start"), Result: []byte(`{"vms":"` + string(bytes.Repeat([]byte("a"), 128)) + `"}`)}
+
+	compressed := newCompressedResults(large)
+
+	if pub := compressed.forEncoding(""); !bytes.Equal(pub.Result, large.Result) {
+		t.Fatalf("expected uncompressed result for clients without an encoding, got %s", pub.Result)
+	}
+
+	first := compressed.forEncoding("gzip")
+
+	if first.Encoding != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", first.Encoding)
+	}
+
+	// The result is compressed once and reused for every client with the
+	// same encoding.
+	if second := compressed.forEncoding("gzip"); &second.Result[0] != &first.Result[0] {
+		t.Fatal("expected compressed result to be reused")
+	}
+
+	// Clients don't compress results that have already been compressed.
+	body, err := (&Client{encoding: "gzip"}).marshal(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, _ := json.Marshal(first)
+
+	if !bytes.Equal(body, expected) {
+		t.Fatalf("expected already compressed result to be published as is, got %s", body)
+	}
+}
@@ -158,6 +158,7 @@ func CreateExperimentFromBuilder(w http.ResponseWriter, r *http.Request) error {
 		experiment.CreateWithTopology(req.Name),
 		experiment.CreateWithScenario(req.Scenario),
 		experiment.CreateWithVLANAliases(req.VLANs),
+		experiment.CreateWithOwner(experimentOwner(ctx)),
 	}
 
 	if err := experiment.Create(ctx, opts...); err != nil {
@@ -375,6 +376,7 @@ func UpdateExperimentFromBuilder(w http.ResponseWriter, r *http.Request) error {
 			experiment.CreateWithTopology(req.Name),
 			experiment.CreateWithScenario(req.Scenario),
 			experiment.CreateWithVLANAliases(req.VLANs),
+			experiment.CreateWithOwner(experimentOwner(ctx)),
 		}
 
 		if err := experiment.Create(ctx, opts...); err != nil {
@@ -0,0 +1,171 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/types"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/export[?includeImages=<bool>]
+//
+// Downloads the experiment as a gzipped tarball that can be imported into
+// another phenix installation. Disk images are included unless includeImages
+// is false.
+func ExportExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ExportExperiment")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		user  = ctx.Value("user").(string)
+		vars  = mux.Vars(r)
+		name  = vars["name"]
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("experiments/export", "get", name) {
+		err := weberror.NewWebError(nil, "exporting experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var opts []experiment.ExportOption
+
+	if v := query.Get("includeImages"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid includeImages value %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.ExportWithImages(include))
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, user, "exporting", experiment.PermissionViewer); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.tar.gz", name))
+
+	// The bundle is streamed since it can include large disk images, so the
+	// response has already started if exporting fails part way through.
+	if err := experiment.Export(name, w, opts...); err != nil {
+		plog.Error("exporting experiment", "exp", name, "err", err)
+	}
+
+	return nil
+}
+
+// POST /experiments/import[?name=<name>][&rename=<bool>]
+//
+// Registers the experiment from a bundle downloaded from an export, given as
+// the request body. The experiment keeps the name it was exported with unless
+// a name is given. If an experiment with the name already exists, the import
+// fails unless rename is true, in which case a numeric suffix is added to it.
+func ImportExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ImportExperiment")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		user  = ctx.Value("user").(string)
+		query = r.URL.Query()
+		name  = query.Get("name")
+	)
+
+	allowed := role.Allowed("experiments", "create")
+
+	if name != "" {
+		allowed = role.Allowed("experiments", "create", name)
+	}
+
+	if !allowed {
+		err := weberror.NewWebError(nil, "creating experiments not allowed for %s", user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	opts := []experiment.ImportOption{experiment.ImportWithOwner(experimentOwner(ctx))}
+
+	if name != "" {
+		if err := cache.LockExperimentForCreation(name); err != nil {
+			err := weberror.NewWebError(err, "experiment %s is locked", name)
+			return err.SetStatus(http.StatusConflict)
+		}
+
+		defer cache.UnlockExperiment(name)
+
+		opts = append(opts, experiment.ImportWithName(name))
+	}
+
+	if v := query.Get("rename"); v != "" {
+		rename, err := strconv.ParseBool(v)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid rename value %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.ImportWithRename(rename))
+	}
+
+	result, err := experiment.Import(r.Body, opts...)
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to import experiment")
+
+		switch {
+		case errors.Is(err, experiment.ErrExperimentExists):
+			return werr.SetStatus(http.StatusConflict)
+		case errors.Is(err, experiment.ErrInvalidBundle), errors.Is(err, types.ErrValidationFailed):
+			return werr.SetStatus(http.StatusBadRequest)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	if exp, err := experiment.Get(result.Name); err == nil {
+		vms, _ := vm.List(result.Name)
+
+		if body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms)); err == nil {
+			broker.Broadcast(
+				bt.NewRequestPolicy("experiments", "get", result.Name),
+				bt.NewResource("experiment", result.Name, "create"),
+				body,
+			)
+		}
+	}
+
+	// Clear experiment name... not applicable to end users.
+	delete(result.Config.Spec, "experimentName")
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process imported experiment %s", result.Name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
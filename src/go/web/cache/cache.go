@@ -20,6 +20,12 @@ const (
 	StatusSnapshotting Status = "snapshotting"
 	StatusRestoring    Status = "restoring"
 	StatusCommitting   Status = "committing"
+	StatusPausing      Status = "pausing"
+	StatusPaused       Status = "paused"
+	StatusResuming     Status = "resuming"
+	StatusRestarting   Status = "restarting"
+	StatusMigrating    Status = "migrating"
+	StatusRunning      Status = "running"
 )
 
 type WebCache interface {
@@ -29,6 +35,7 @@ type WebCache interface {
 
 	Lock(string, Status, time.Duration) Status
 	Locked(string) Status
+	Refresh(string, Status, time.Duration) bool
 	Unlock(string)
 }
 
@@ -88,6 +95,18 @@ func (this *GoWebCache) Locked(key string) Status {
 	return v.(Status)
 }
 
+func (this *GoWebCache) Refresh(key string, status Status, exp time.Duration) bool {
+	key = "LOCK|" + key
+
+	v, ok := this.c.Get(key)
+	if !ok || v.(Status) != status {
+		return false
+	}
+
+	this.c.Set(key, status, exp)
+	return true
+}
+
 func (this *GoWebCache) Unlock(key string) {
 	this.c.Delete("LOCK|" + key)
 }
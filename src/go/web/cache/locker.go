@@ -2,9 +2,23 @@ package cache
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
+// How long each experiment lock lasts before it expires on its own, by the
+// status it's locked with.
+var experimentLockTTLs = map[Status]time.Duration{
+	StatusCreating:   5 * time.Minute,
+	StatusUpdating:   5 * time.Minute,
+	StatusDeleting:   1 * time.Minute,
+	StatusStarting:   5 * time.Minute,
+	StatusStopping:   1 * time.Minute,
+	StatusPausing:    1 * time.Minute,
+	StatusResuming:   1 * time.Minute,
+	StatusRestarting: 10 * time.Minute,
+}
+
 func IsExperimentLocked(name string) Status {
 	key := "experiment|" + name
 
@@ -17,6 +31,41 @@ func UnlockExperiment(name string) {
 	Unlock(key)
 }
 
+// KeepExperimentLocked refreshes the lock on the experiment with the given name
+// before it expires for as long as it's still locked with the given status, so
+// operations that take longer than the lock lasts keep it. The returned
+// function stops refreshing the lock, but doesn't unlock the experiment.
+func KeepExperimentLocked(name string, status Status) func() {
+	var (
+		key  = "experiment|" + name
+		ttl  = experimentLockTTLs[status]
+		done = make(chan struct{})
+		once sync.Once
+	)
+
+	if ttl == 0 {
+		return func() {}
+	}
+
+	go func() {
+		ticker := time.NewTicker(ttl / 2)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if !Refresh(key, status, ttl) {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() { once.Do(func() { close(done) }) }
+}
+
 func IsVMLocked(exp, name string) Status {
 	key := fmt.Sprintf("vm|%s/%s", exp, name)
 
@@ -32,7 +81,7 @@ func UnlockVM(exp, name string) {
 func LockExperimentForCreation(name string) error {
 	key := "experiment|" + name
 
-	if status := Lock(key, StatusCreating, 5*time.Minute); status != "" {
+	if status := Lock(key, StatusCreating, experimentLockTTLs[StatusCreating]); status != "" {
 		return fmt.Errorf("experiment %s is locked with status %s", name, status)
 	}
 
@@ -42,7 +91,7 @@ func LockExperimentForCreation(name string) error {
 func LockExperimentForUpdate(name string) error {
 	key := "experiment|" + name
 
-	if status := Lock(key, StatusUpdating, 5*time.Minute); status != "" {
+	if status := Lock(key, StatusUpdating, experimentLockTTLs[StatusUpdating]); status != "" {
 		return fmt.Errorf("experiment %s is locked with status %s", name, status)
 	}
 
@@ -52,7 +101,7 @@ func LockExperimentForUpdate(name string) error {
 func LockExperimentForDeletion(name string) error {
 	key := "experiment|" + name
 
-	if status := Lock(key, StatusDeleting, 1*time.Minute); status != "" {
+	if status := Lock(key, StatusDeleting, experimentLockTTLs[StatusDeleting]); status != "" {
 		return fmt.Errorf("experiment %s is locked with status %s", name, status)
 	}
 
@@ -62,7 +111,7 @@ func LockExperimentForDeletion(name string) error {
 func LockExperimentForStarting(name string) error {
 	key := "experiment|" + name
 
-	if status := Lock(key, StatusStarting, 5*time.Minute); status != "" {
+	if status := Lock(key, StatusStarting, experimentLockTTLs[StatusStarting]); status != "" {
 		return fmt.Errorf("experiment %s is locked with status %s", name, status)
 	}
 
@@ -72,7 +121,37 @@ func LockExperimentForStarting(name string) error {
 func LockExperimentForStopping(name string) error {
 	key := "experiment|" + name
 
-	if status := Lock(key, StatusStopping, 1*time.Minute); status != "" {
+	if status := Lock(key, StatusStopping, experimentLockTTLs[StatusStopping]); status != "" {
+		return fmt.Errorf("experiment %s is locked with status %s", name, status)
+	}
+
+	return nil
+}
+
+func LockExperimentForPausing(name string) error {
+	key := "experiment|" + name
+
+	if status := Lock(key, StatusPausing, experimentLockTTLs[StatusPausing]); status != "" {
+		return fmt.Errorf("experiment %s is locked with status %s", name, status)
+	}
+
+	return nil
+}
+
+func LockExperimentForResuming(name string) error {
+	key := "experiment|" + name
+
+	if status := Lock(key, StatusResuming, experimentLockTTLs[StatusResuming]); status != "" {
+		return fmt.Errorf("experiment %s is locked with status %s", name, status)
+	}
+
+	return nil
+}
+
+func LockExperimentForRestarting(name string) error {
+	key := "experiment|" + name
+
+	if status := Lock(key, StatusRestarting, experimentLockTTLs[StatusRestarting]); status != "" {
 		return fmt.Errorf("experiment %s is locked with status %s", name, status)
 	}
 
@@ -109,6 +188,16 @@ func LockVMForRedeploying(exp, name string) error {
 	return nil
 }
 
+func LockVMForMigrating(exp, name string) error {
+	key := fmt.Sprintf("vm|%s/%s", exp, name)
+
+	if status := Lock(key, StatusMigrating, 10*time.Minute); status != "" {
+		return fmt.Errorf("VM %s is locked with status %s", name, status)
+	}
+
+	return nil
+}
+
 func LockVMForSnapshotting(exp, name string) error {
 	key := fmt.Sprintf("vm|%s/%s", exp, name)
 
@@ -148,3 +237,24 @@ func LockVMForMemorySnapshotting(exp, name string) error {
 
 	return nil
 }
+
+// LockExperimentApp locks the given app for the given experiment while its
+// running stage is executing, so the same app can't be run against the same
+// experiment more than once at a time. The lock doesn't expire since apps can
+// run for any amount of time, so callers must always release it with
+// `UnlockExperimentApp` once the app is done.
+func LockExperimentApp(name, app string) error {
+	key := fmt.Sprintf("app|%s/%s", name, app)
+
+	if status := Lock(key, StatusRunning, 0); status != "" {
+		return fmt.Errorf("app %s for experiment %s is locked with status %s", app, name, status)
+	}
+
+	return nil
+}
+
+func UnlockExperimentApp(name, app string) {
+	key := fmt.Sprintf("app|%s/%s", name, app)
+
+	Unlock(key)
+}
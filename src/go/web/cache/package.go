@@ -24,6 +24,13 @@ func Locked(key string) Status {
 	return DefaultWebCache.Locked(key)
 }
 
+// Refresh resets the expiration of the lock on the given key to the given
+// duration if it's still locked with the given status. It returns false if the
+// key isn't locked with the given status.
+func Refresh(key string, status Status, exp time.Duration) bool {
+	return DefaultWebCache.Refresh(key, status, exp)
+}
+
 func Unlock(key string) {
 	DefaultWebCache.Unlock(key)
 }
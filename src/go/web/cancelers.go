@@ -0,0 +1,65 @@
+package web
+
+import (
+	"context"
+	"sync"
+)
+
+var (
+	// Track context cancelers and wait groups for starting experiments and
+	// periodically running apps.
+	cancelers = make(map[string][]context.CancelFunc)
+	waiters   = make(map[string]*sync.WaitGroup)
+
+	// Guards access to both the cancelers and waiters maps.
+	cancelersMu sync.Mutex
+)
+
+func addCanceler(key string, cancel context.CancelFunc) {
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	cancelers[key] = append(cancelers[key], cancel)
+}
+
+// takeCancelers removes and returns all the cancelers registered for the
+// given key.
+func takeCancelers(key string) []context.CancelFunc {
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	cancels := cancelers[key]
+	delete(cancelers, key)
+
+	return cancels
+}
+
+func setWaiter(key string, wg *sync.WaitGroup) {
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	waiters[key] = wg
+}
+
+// hasWaiter returns whether a wait group is registered for the given key.
+func hasWaiter(key string) bool {
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	return waiters[key] != nil
+}
+
+// takeCancelersAndWaiter removes and returns all the cancelers and the wait
+// group registered for the given key.
+func takeCancelersAndWaiter(key string) ([]context.CancelFunc, *sync.WaitGroup) {
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	cancels := cancelers[key]
+	wg := waiters[key]
+
+	delete(cancelers, key)
+	delete(waiters, key)
+
+	return cancels, wg
+}
@@ -0,0 +1,69 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// Mimics concurrent starts and stops of several experiments, along with app
+// triggers, to make sure access to the cancelers and waiters is synchronized.
+// Meant to be run with `go test -race`.
+func TestCancelersConcurrentStartStop(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("test-experiment-%d", i)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 50; j++ {
+				// start
+				ctx, cancel := context.WithCancel(context.Background())
+
+				var running sync.WaitGroup
+				running.Add(1)
+
+				addCanceler(name, cancel)
+				setWaiter(name, &running)
+
+				go func() {
+					defer running.Done()
+					<-ctx.Done()
+				}()
+
+				// trigger
+				app := fmt.Sprintf("%s/test-app", name)
+				addCanceler(app, cancel)
+
+				// stop
+				cancelPeriodicApps(name)
+
+				for _, cancel := range takeCancelers(app) {
+					cancel()
+				}
+
+				if ctx.Err() == nil {
+					t.Errorf("expected context for experiment %s to be canceled", name)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	if len(cancelers) != 0 {
+		t.Errorf("expected no cancelers left, got %d", len(cancelers))
+	}
+
+	if len(waiters) != 0 {
+		t.Errorf("expected no waiters left, got %d", len(waiters))
+	}
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+)
+
+// GET /cluster/capacity[?refresh=<bool>][&vmMemory=<MB>]
+//
+// Returns the total and uncommitted CPUs, memory, VM slots, and passthrough
+// GPUs of each schedulable cluster host, along with the totals across them. VM
+// slots are how many VMs with the given memory fit on each host. The host
+// details are the same ones experiments are scheduled and started against,
+// which are cached briefly unless refresh is true.
+func GetClusterCapacity(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetClusterCapacity")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		query = r.URL.Query()
+
+		refresh  bool
+		vmMemory int
+	)
+
+	if !role.Allowed("hosts", "list") {
+		err := weberror.NewWebError(nil, "getting cluster capacity not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if v := query.Get("refresh"); v != "" {
+		var err error
+
+		if refresh, err = strconv.ParseBool(v); err != nil {
+			err := weberror.NewWebError(err, "invalid refresh value %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	if v := query.Get("vmMemory"); v != "" {
+		var err error
+
+		if vmMemory, err = strconv.Atoi(v); err != nil || vmMemory < 1 {
+			err := weberror.NewWebError(err, "invalid vmMemory value %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	capacity, err := mm.GetClusterCapacity(refresh, vmMemory)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get cluster capacity")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	// Minimega doesn't know which GPUs are passed through to VMs.
+	gpus, err := experiment.GPUsInUse("")
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get GPUs in use")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var (
+		hosts = []mm.HostCapacity{}
+		total mm.CapacityTotals
+	)
+
+	// The totals only include the hosts the user is allowed to see.
+	for _, host := range capacity.Hosts {
+		host.GPUsAvailable -= len(gpus[host.Name])
+
+		if host.GPUsAvailable < 0 {
+			host.GPUsAvailable = 0
+		}
+
+		if role.Allowed("hosts", "list", host.Name) {
+			hosts = append(hosts, host)
+			total.Add(host.CapacityTotals)
+		}
+	}
+
+	capacity.Hosts = hosts
+	capacity.Total = total
+
+	body, err := json.Marshal(capacity)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process cluster capacity")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
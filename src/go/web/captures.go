@@ -0,0 +1,192 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"phenix/api/vm"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/proto"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	// VMs with captures started via the experiment captures endpoint, keyed by
+	// experiment name.
+	trackedCaptures   = make(map[string]map[string]struct{})
+	trackedCapturesMu sync.Mutex
+)
+
+func trackCapture(exp, name string) {
+	trackedCapturesMu.Lock()
+	defer trackedCapturesMu.Unlock()
+
+	if _, ok := trackedCaptures[exp]; !ok {
+		trackedCaptures[exp] = make(map[string]struct{})
+	}
+
+	trackedCaptures[exp][name] = struct{}{}
+}
+
+func untrackCapture(exp, name string) {
+	trackedCapturesMu.Lock()
+	defer trackedCapturesMu.Unlock()
+
+	delete(trackedCaptures[exp], name)
+
+	if len(trackedCaptures[exp]) == 0 {
+		delete(trackedCaptures, exp)
+	}
+}
+
+// stopTrackedCaptures stops the captures started via the experiment captures
+// endpoint for the given experiment. It's called when the experiment is being
+// stopped so the capture files are closed out before the VMs are killed.
+func stopTrackedCaptures(exp string) {
+	trackedCapturesMu.Lock()
+	tracked := trackedCaptures[exp]
+	delete(trackedCaptures, exp)
+	trackedCapturesMu.Unlock()
+
+	for name := range tracked {
+		if err := vm.StopCaptures(exp, name); err != nil {
+			if !errors.Is(err, vm.ErrNoCaptures) {
+				plog.Error("stopping tracked captures for VM", "exp", exp, "vm", name, "err", err)
+			}
+
+			continue
+		}
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/captures", "delete", fmt.Sprintf("%s/%s", exp, name)),
+			bt.NewResource("experiment/capture", fmt.Sprintf("%s/%s", exp, name), "stop"),
+			nil,
+		)
+	}
+}
+
+// POST /experiments/{exp}/captures
+func StartExperimentCapture(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "StartExperimentCapture")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+	)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to read capture request for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req proto.StartCaptureRequest
+
+	if err := unmarshaler.Unmarshal(body, &req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse capture request for experiment %s", exp)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if req.Vm == "" {
+		err := weberror.NewWebError(nil, "no VM provided for capture in experiment %s", exp)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if !role.Allowed("vms/captures", "create", fmt.Sprintf("%s/%s", exp, req.Vm)) {
+		err := weberror.NewWebError(nil, "starting capture for VM %s/%s not allowed for %s", exp, req.Vm, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := vm.StartCapture(exp, req.Vm, int(req.Interface), req.Filename); err != nil {
+		status := http.StatusBadRequest
+
+		if errors.Is(err, mm.ErrCaptureExists) {
+			status = http.StatusConflict
+		}
+
+		err := weberror.NewWebError(err, "unable to start capture for VM %s/%s", exp, req.Vm)
+		return err.SetStatus(status)
+	}
+
+	trackCapture(exp, req.Vm)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/captures", "create", fmt.Sprintf("%s/%s", exp, req.Vm)),
+		bt.NewResource("experiment/capture", fmt.Sprintf("%s/%s", exp, req.Vm), "start"),
+		body,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// DELETE /experiments/{exp}/captures[?vm=<name>]
+func StopExperimentCaptures(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "StopExperimentCaptures")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = r.URL.Query().Get("vm")
+	)
+
+	var names []string
+
+	if name != "" {
+		names = []string{name}
+	} else {
+		seen := make(map[string]struct{})
+
+		for _, capture := range mm.GetExperimentCaptures(mm.NS(exp)) {
+			if _, ok := seen[capture.VM]; !ok {
+				seen[capture.VM] = struct{}{}
+				names = append(names, capture.VM)
+			}
+		}
+	}
+
+	for _, name := range names {
+		if !role.Allowed("vms/captures", "delete", fmt.Sprintf("%s/%s", exp, name)) {
+			err := weberror.NewWebError(nil, "stopping captures for VM %s/%s not allowed for %s", exp, name, ctx.Value("user").(string))
+			return err.SetStatus(http.StatusForbidden)
+		}
+	}
+
+	for _, name := range names {
+		if err := vm.StopCaptures(exp, name); err != nil {
+			status := http.StatusInternalServerError
+
+			if errors.Is(err, vm.ErrNoCaptures) {
+				status = http.StatusNotFound
+			}
+
+			err := weberror.NewWebError(err, "unable to stop captures for VM %s/%s", exp, name)
+			return err.SetStatus(status)
+		}
+
+		untrackCapture(exp, name)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/captures", "delete", fmt.Sprintf("%s/%s", exp, name)),
+			bt.NewResource("experiment/capture", fmt.Sprintf("%s/%s", exp, name), "stop"),
+			nil,
+		)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
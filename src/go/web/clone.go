@@ -0,0 +1,110 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+type cloneRequest struct {
+	Name         string `json:"name"`
+	SubnetOffset int    `json:"subnetOffset"`
+}
+
+// POST /experiments/{name}/clone
+func CloneExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CloneExperiment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		src  = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "get", src) {
+		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", src, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req cloneRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse clone request for experiment %s", src)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if !role.Allowed("experiments", "create", req.Name) {
+		err := weberror.NewWebError(nil, "creating experiment %s not allowed for %s", req.Name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := experiment.Get(src); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", src)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	if err := cache.LockExperimentForCreation(req.Name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", req.Name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(req.Name)
+
+	opts := []experiment.CloneOption{
+		experiment.CloneWithSubnetOffset(req.SubnetOffset),
+		experiment.CloneWithOwner(experimentOwner(ctx)),
+	}
+
+	cfg, err := experiment.Clone(src, req.Name, opts...)
+	if err != nil {
+		status := http.StatusBadRequest
+
+		if errors.Is(err, experiment.ErrExperimentExists) {
+			status = http.StatusConflict
+		}
+
+		err := weberror.NewWebError(err, "unable to clone experiment %s to %s", src, req.Name)
+		return err.SetStatus(status)
+	}
+
+	if exp, err := experiment.Get(req.Name); err == nil {
+		vms, _ := vm.List(req.Name)
+
+		if body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms)); err == nil {
+			broker.Broadcast(
+				bt.NewRequestPolicy("experiments", "get", req.Name),
+				bt.NewResource("experiment", req.Name, "create"),
+				body,
+			)
+		}
+	}
+
+	// Clear experiment name... not applicable to end users.
+	delete(cfg.Spec, "experimentName")
+
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process config for experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
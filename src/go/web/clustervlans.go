@@ -0,0 +1,53 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/vlan"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+)
+
+// GET /cluster/vlans
+//
+// Returns the shared VLAN pool experiments are allocated VLAN IDs from as
+// they're started, along with the IDs currently allocated to each experiment.
+func GetClusterVLANs(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetClusterVLANs")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("vlans", "list") {
+		err := weberror.NewWebError(nil, "listing cluster VLANs not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	status, err := vlan.Pool()
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VLAN pool")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	// Allocations to experiments the user can't see are only counted.
+	allowed := []vlan.PoolAllocation{}
+
+	for _, alloc := range status.Allocations {
+		if role.Allowed("experiments", "get", alloc.Experiment) {
+			allowed = append(allowed, alloc)
+		}
+	}
+
+	status.Allocations = allowed
+
+	body, _ := json.Marshal(status)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -6,83 +6,541 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"runtime/debug"
 	"sync"
 	"time"
 
 	"phenix/api/experiment"
+	"phenix/api/vlan"
 	"phenix/api/vm"
 	"phenix/app"
 	"phenix/types"
 	"phenix/util/mm"
 	"phenix/util/notes"
 	"phenix/util/plog"
+	"phenix/util/pubsub"
 	"phenix/web/broker"
 	"phenix/web/cache"
+	"phenix/web/proto"
 	"phenix/web/util"
 	"phenix/web/weberror"
 
+	putil "phenix/util"
 	bt "phenix/web/broker/brokertypes"
 )
 
+// Used as the cause when an experiment start is canceled by a user.
+var errStartCanceled = errors.New("experiment start canceled")
+
+// Wrapped by the errors for starts that time out, or whose launch can no longer
+// be monitored, respectively.
 var (
-	// Track context cancelers and wait groups for periodically running apps.
-	cancelers = make(map[string][]context.CancelFunc)
-	waiters   = make(map[string]*sync.WaitGroup)
+	errStartTimedOut       = errors.New("start timed out")
+	errLaunchMonitorFailed = errors.New("monitoring launch")
 )
 
-func startExperiment(name string) ([]byte, error) {
+// How long to wait before retrying to list an experiment's VMs after it has
+// started.
+const vmListRetryBackoff = 1 * time.Second
+
+// How long to wait for an abandoned experiment start to exit after canceling it
+// before cleaning up after it anyway.
+const abandonStartTimeout = 1 * time.Minute
+
+func startExperiment(ctx context.Context, name, user string, opts ...experiment.StartOption) (body []byte, err error) {
+	if err := authorizeExperimentAction(ctx, name, user, "starting", experiment.PermissionEditor); err != nil {
+		return nil, err
+	}
+
+	if err := checkMaintenance(name); err != nil {
+		return nil, err
+	}
+
 	if err := cache.LockExperimentForStarting(name); err != nil {
 		err := weberror.NewWebError(err, "unable to lock experiment %s for starting", name)
-		return nil, err.SetStatus(http.StatusConflict)
+		return nil, err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
 	}
 
 	defer cache.UnlockExperiment(name)
 
+	// Starts can wait in the start queue, and launch (including waiting for
+	// readiness probes), for longer than the lock lasts, so it's kept until the
+	// start returns.
+	defer cache.KeepExperimentLocked(name, cache.StatusStarting)()
+	defer recoverLockedExperiment(name, user, "starting", &err)
+
+	// The experiment can't be in the middle of starting at this point since it
+	// was successfully locked for starting.
+	if experiment.NewStartOptions(opts...).Idempotent() {
+		if exp, err := experiment.Get(name); err == nil && exp.Running() && !exp.DryRun() {
+			return currentExperiment(name, exp)
+		}
+	}
+
+	return queueExperimentStart(name, user, opts...)
+}
+
+// queueExperimentStart checks the given experiment's disk images, waits for its
+// turn in the start queue, and allocates its VLANs before starting it on behalf
+// of the given user, assuming the caller has already locked the experiment in
+// the cache and keeps it locked until it returns. It's used for restarts too,
+// so they don't jump the start queue or reuse VLANs released when stopping.
+func queueExperimentStart(name, user string, opts ...experiment.StartOption) (body []byte, err error) {
+	// Catch missing disk images before anything is launched, since minimega's
+	// errors for them are less than helpful.
+	if err := experiment.CheckImages(name); err != nil {
+		werr := weberror.NewWebError(err, "unable to start experiment %s", name)
+
+		if errors.Is(err, experiment.ErrImageMissing) {
+			return nil, werr.SetStatus(http.StatusBadRequest).SetCode(weberror.VMImageMissing).SetData(missingImagesData(err))
+		}
+
+		return nil, werr.SetStatus(http.StatusInternalServerError).SetCode(weberror.StartFailed)
+	}
+
+	// Dry runs don't use any cluster resources, so they don't wait in the
+	// start queue.
+	if o := experiment.NewStartOptions(opts...); !o.DryRun() {
+		// Canceling the start while it's queued removes it from the queue.
+		queueCtx, cancelQueued := context.WithCancelCause(context.Background())
+		addCanceler(name, func() { cancelQueued(errStartCanceled) })
+
+		// Errors are scoped to this block so they don't shadow the named result
+		// that releaseVLANsOnError checks.
+		release, qerr := starts.wait(queueCtx, name, user, o.Priority())
+		cancelQueued(nil)
+
+		if qerr != nil {
+			err := weberror.NewWebError(qerr, "start of experiment %s was canceled while queued", name)
+			return nil, err.SetStatus(http.StatusConflict).SetCode(weberror.StartCanceled)
+		}
+
+		defer release()
+
+		// Allocated after waiting in the queue so queued starts don't hold VLANs
+		// they aren't using yet.
+		vlans, verr := vlan.AllocatePool(name)
+		if verr != nil {
+			werr := weberror.NewWebError(verr, "unable to allocate VLANs for experiment %s", name)
+
+			if errors.Is(verr, vlan.ErrVLANExhausted) {
+				return nil, werr.SetStatus(http.StatusConflict).SetCode(weberror.VLANExhausted)
+			}
+
+			return nil, werr.SetStatus(http.StatusInternalServerError).SetCode(weberror.StartFailed)
+		}
+
+		if vlans != nil {
+			opts = append(opts, experiment.StartWithVLANAllocation(vlans))
+
+			// Stopping the experiment releases its VLANs too, but a start that fails
+			// part way through doesn't necessarily get that far.
+			defer releaseVLANsOnError(name, &err)
+		}
+	}
+
+	return startExperimentLocked(name, user, opts...)
+}
+
+// releaseVLANsOnError releases the VLANs allocated to the given experiment from
+// the shared VLAN pool if the given error is set once its start returns.
+func releaseVLANsOnError(name string, err *error) {
+	if *err != nil {
+		vlan.ReleasePool(name)
+	}
+}
+
+// missingImagesData returns the missing images and the VMs referencing them
+// from the given error if it's (or wraps) a missing images error. It returns
+// nil otherwise.
+func missingImagesData(err error) json.RawMessage {
+	var merr experiment.MissingImagesError
+
+	if !errors.As(err, &merr) {
+		return nil
+	}
+
+	body, _ := json.Marshal(merr)
+	return body
+}
+
+// quotaErrorData returns the requested resources and limits from the given
+// error if it's (or wraps) a quota error, so users know by how much they're
+// over. It returns nil otherwise.
+func quotaErrorData(err error) json.RawMessage {
+	var qerr experiment.QuotaError
+
+	if !errors.As(err, &qerr) {
+		return nil
+	}
+
+	body, _ := json.Marshal(qerr)
+	return body
+}
+
+// hostUnreachableData returns the unreachable hosts, and why each couldn't be
+// reached, from the given error if it's (or wraps) a host unreachable error. It
+// returns nil otherwise.
+func hostUnreachableData(err error) json.RawMessage {
+	var herr experiment.HostUnreachableError
+
+	if !errors.As(err, &herr) {
+		return nil
+	}
+
+	body, _ := json.Marshal(herr)
+	return body
+}
+
+// currentExperiment returns the details of the given experiment in the same
+// form as a successful start or stop. It's used when starting or stopping the
+// experiment is a no-op since it's already running or stopped.
+func currentExperiment(name string, exp *types.Experiment) ([]byte, error) {
+	vms, err := vm.List(name)
+
+	pb := util.ExperimentToProtobuf(*exp, "", vms)
+
+	if err != nil {
+		plog.Error("listing VMs in experiment", "exp", name, "err", err)
+
+		pb.VmListError = err.Error()
+	}
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", name)
+		return nil, err.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
+	}
+
+	return body, nil
+}
+
+// validateExperimentStart runs the pre-launch validation for the given
+// experiment and returns the schedule it would be started with. The experiment
+// is only locked while validating, and no status is broadcast since nothing is
+// actually started.
+func validateExperimentStart(name string, opts ...experiment.StartOption) ([]byte, error) {
+	if err := cache.LockExperimentForStarting(name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for starting", name)
+		return nil, err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	opts = append(opts, experiment.StartWithName(name))
+
+	schedule, err := experiment.ValidateStart(context.Background(), opts...)
+
+	cache.UnlockExperiment(name)
+
+	if err != nil {
+		werr := weberror.NewWebError(err, "validating start of experiment %s", name).SetStatus(http.StatusBadRequest)
+
+		switch {
+		case errors.Is(err, experiment.ErrImageMissing):
+			return nil, werr.SetCode(weberror.VMImageMissing).SetData(missingImagesData(err))
+		case errors.Is(err, experiment.ErrGPUUnavailable):
+			return nil, werr.SetCode(weberror.GPUUnavailable)
+		case errors.Is(err, experiment.ErrScheduleInfeasible):
+			return nil, werr.SetCode(weberror.ScheduleInfeasible).SetData(quotaErrorData(err))
+		case errors.Is(err, experiment.ErrHostUnreachable):
+			return nil, werr.SetCode(weberror.HostUnreachable).SetData(hostUnreachableData(err))
+		}
+
+		return nil, werr.SetCode(weberror.StartFailed)
+	}
+
+	algorithm := experiment.NewStartOptions(opts...).Scheduler()
+	if algorithm == "" {
+		algorithm = experiment.DefaultDryRunScheduler
+	}
+
+	body, err := json.Marshal(map[string]any{"scheduler": algorithm, "schedule": schedule})
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling schedule for experiment %s", name)
+		return nil, err.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
+	}
+
+	return body, nil
+}
+
+// startExperimentLocked starts the given experiment on behalf of the given
+// user, assuming the caller has already locked the experiment in the cache.
+// Starts that fail are retried as many times as the start options allow (see
+// experiment.StartWithAutoRetry), so all the attempts together can take longer
+// than the lock lasts; callers must keep it with cache.KeepExperimentLocked.
+func startExperimentLocked(name, user string, opts ...experiment.StartOption) ([]byte, error) {
+	// Buffer logs generated while starting so clients connecting mid-start can
+	// catch up. They're no longer needed once the start has finished.
+	newStartLog(name)
+	defer clearStartLog(name)
+
+	// Progress is only persisted for servers restarted while the experiment is
+	// still starting.
+	defer clearStartProgress(name)
+
+	resetStartStatus(name)
+
+	started := time.Now()
+
 	broker.Broadcast(
 		bt.NewRequestPolicy("experiments/start", "update", name),
 		bt.NewResource("experiment", name, "starting"),
 		nil,
 	)
 
+	recordExperimentEvent(name, user, "starting", nil)
+
+	retries, backoff := experiment.NewStartOptions(opts...).AutoRetry()
+
+	for attempt := 1; ; attempt++ {
+		body, err := startExperimentAttempt(name, user, started, attempt <= retries, opts...)
+
+		var aerr startAttemptError
+
+		if !errors.As(err, &aerr) {
+			return body, err
+		}
+
+		if err := retryExperimentStart(name, user, attempt, retries, backoff, aerr.err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// startAttemptError is returned by startExperimentAttempt in place of
+// broadcasting that the start failed when the failed attempt can be retried.
+type startAttemptError struct {
+	err error
+}
+
+func (this startAttemptError) Error() string {
+	return this.err.Error()
+}
+
+// retryableStartError returns true if retrying a start that failed with the
+// given error could succeed. Only transient failures, due to the state of the
+// cluster rather than the experiment itself, are retried: unreachable hosts,
+// minimega failing to launch the VMs, being unable to monitor the launch, and
+// the start timing out.
+func retryableStartError(err error) bool {
+	switch {
+	case errors.Is(err, experiment.ErrHostUnreachable),
+		errors.Is(err, experiment.ErrLaunchFailed),
+		errors.Is(err, errLaunchMonitorFailed),
+		errors.Is(err, errStartTimedOut):
+		return true
+	}
+
+	return false
+}
+
+// retryExperimentStart cleans up after the given failed attempt to start the
+// given experiment (see cleanUpExperimentStart) and waits for the given backoff
+// before it's retried. It returns the error to respond with if
+// the start shouldn't be retried after all, since the cleanup failed or the
+// start was canceled in the meantime.
+func retryExperimentStart(name, user string, attempt, retries int, backoff time.Duration, cause error) error {
+	logger := experimentLogger(name)
+
+	logger.Warn("unable to start experiment, retrying", "exp", name, "attempt", attempt, "retries", retries, "backoff", backoff, "err", cause)
+
+	if err := cleanUpExperimentStart(name); err != nil {
+		return startError(name, user, fmt.Errorf("%w (cleaning up for retry: %v)", cause, err))
+	}
+
+	resetStartStatus(name)
+
+	body, _ := json.Marshal(map[string]any{
+		"attempt":  attempt + 1,
+		"attempts": retries + 1,
+		"error":    cause.Error(),
+		"backoff":  backoff.Seconds(),
+	})
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/start", "update", name),
+		bt.NewResource("experiment", name, "retrying"),
+		body,
+	)
+
+	recordExperimentEvent(name, user, "retrying", cause)
+
+	// Canceling the start while waiting to retry it cancels the retry.
+	ctx, cancel := context.WithCancelCause(context.Background())
+	addCanceler(name, func() { cancel(errStartCanceled) })
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(backoff):
+	}
+
+	takeCancelers(name)
+	cancel(nil)
+
+	if errors.Is(context.Cause(ctx), errStartCanceled) {
+		err := weberror.NewWebError(errStartCanceled, "start of experiment %s was canceled", name)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.StartCanceled)
+	}
+
+	return nil
+}
+
+// startError records and broadcasts that starting the given experiment failed
+// with the given error, returning the error to respond with.
+func startError(name, user string, err error) *weberror.WebError {
+	updateStartStatus(name, func(st *startStatus) { st.err = err.Error() })
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/start", "update", name),
+		bt.NewResource("experiment", name, "errorStarting"),
+		nil,
+	)
+
+	recordExperimentEvent(name, user, "errorStarting", err)
+
+	var (
+		code = weberror.StartFailed
+		data = quotaErrorData(err)
+	)
+
+	switch {
+	case errors.Is(err, experiment.ErrExperimentRunning):
+		code = weberror.ExperimentRunning
+	case errors.Is(err, experiment.ErrScheduleInfeasible):
+		code = weberror.ScheduleInfeasible
+	case errors.Is(err, experiment.ErrHostUnreachable):
+		code = weberror.HostUnreachable
+		data = hostUnreachableData(err)
+	case errors.Is(err, experiment.ErrIsolationViolated):
+		code = weberror.IsolationViolated
+	case errors.Is(err, experiment.ErrGPUUnavailable):
+		code = weberror.GPUUnavailable
+	}
+
+	werr := weberror.NewWebError(err, "unable to start experiment %s", name)
+	return werr.SetStatus(http.StatusBadRequest).SetCode(code).SetData(data)
+}
+
+// startExperimentAttempt makes a single attempt at starting the given
+// experiment, started by the given user at the given time. If retry is true
+// and the start itself fails with an error that can be retried, a
+// startAttemptError is returned instead of the failure being broadcast.
+func startExperimentAttempt(name, user string, started time.Time, retry bool, opts ...experiment.StartOption) ([]byte, error) {
+	// Uses the experiment's log level, if set, so noisy experiments can be
+	// quieted and others traced in more detail.
+	logger := experimentLogger(name)
+
 	type result struct {
 		exp *types.Experiment
 		err error
 	}
 
-	status := make(chan result)
+	var (
+		o        = experiment.NewStartOptions(opts...)
+		timeout  = o.Timeout()
+		deadline = time.After(timeout)
+	)
+
+	logger.Debug("starting experiment", "exp", name, "user", user, "timeout", timeout)
+
+	// Buffered so the Goroutine below doesn't block forever if we've already
+	// given up on the start due to a timeout.
+	status := make(chan result, 1)
+
+	// Errors encountered starting delayed VMs, included in the response.
+	var (
+		delayedErrs   []*proto.DelayedError
+		delayedErrsMu sync.Mutex
+	)
+
+	// Boot group currently being started, included in progress broadcasts.
+	var (
+		bootGroup   experiment.BootGroup
+		bootGroupMu sync.Mutex
+	)
+
+	opts = append(opts, experiment.StartWithBootGroupProgress(func(g experiment.BootGroup) {
+		bootGroupMu.Lock()
+		defer bootGroupMu.Unlock()
+
+		bootGroup = g
+	}))
+
+	// Readiness of VMs with readiness probes once they're launched, included in
+	// progress broadcasts in place of their launch state.
+	var (
+		readiness   map[string]string
+		readinessMu sync.Mutex
+	)
+
+	opts = append(opts, experiment.StartWithReadinessProgress(func(r map[string]string) {
+		readinessMu.Lock()
+		defer readinessMu.Unlock()
+
+		readiness = r
+	}))
+
+	opts = append(opts, experiment.StartWithDelayedRetryProgress(func(r experiment.DelayedRetry) {
+		broadcastDelayedRetry(name, r)
+	}))
+
+	// We don't want to use the HTTP request's context here.
+	startCtx, cancelStart := context.WithCancelCause(context.Background())
+	addCanceler(name, func() { cancelStart(errStartCanceled) })
+
+	// Track the Goroutine starting the experiment so `cancelExperimentStart` can
+	// wait for it to exit.
+	var startWG sync.WaitGroup
+	startWG.Add(1)
+	setWaiter(name, &startWG)
 
 	go func() {
-		// We don't want to use the HTTP request's context here.
-		ctx, cancel := context.WithCancel(context.Background())
-		cancelers[name] = append(cancelers[name], cancel)
+		defer startWG.Done()
+
+		cancel := func() { cancelStart(nil) }
+		ctx := notes.Context(startCtx, false)
 
-		ctx = notes.Context(ctx, false)
+		// A panic in here can't be recovered by the caller since it's in its own
+		// Goroutine, so it's turned into a start error instead of taking the whole
+		// server down.
+		defer func() {
+			if r := recover(); r != nil {
+				plog.Error("panic while starting experiment", "exp", name, "panic", r, "stack", string(debug.Stack()))
+
+				cancel()
+				takeCancelers(name)
+
+				status <- result{nil, fmt.Errorf("panic while starting experiment: %v", r)}
+			}
+		}()
 
 		ch := make(chan error)
 
-		if err := experiment.Start(ctx, experiment.StartWithName(name), experiment.StartWithErrorChannel(ch)); err != nil {
+		opts := append(opts, experiment.StartWithName(name), experiment.StartWithErrorChannel(ch))
+
+		if err := experiment.Start(ctx, opts...); err != nil {
 			cancel() // avoid leakage
-			delete(cancelers, name)
+			takeCancelers(name)
 
 			status <- result{nil, err}
+			return
 		} else {
-			for _, note := range notes.Info(ctx, false) {
-				plog.Info(note)
-			}
+			flushExperimentNotes(ctx, name)
 
 			done := make(chan struct{})
 
 			// Goroutine to periodically print out logs generated by experiment while
 			// starting.
 			go func() {
+				defer logStartPanic(name)
+
 				for {
-					for _, note := range notes.Info(ctx, false) {
-						plog.Info(note)
-					}
+					flushExperimentNotes(ctx, name)
 
 					select {
 					case <-done:
 						return
+					case <-ctx.Done():
+						return
 					default:
 						time.Sleep(1 * time.Second)
 					}
@@ -90,22 +548,47 @@ func startExperiment(name string) ([]byte, error) {
 			}()
 
 			go func() {
-				for err := range ch {
-					plog.Warn("delayed error starting experiment", "exp", name, "err", err)
+				defer logStartPanic(name)
+
+				// Stop periodically printing out logs via previous Goroutine.
+				defer close(done)
+
+				for {
+					var err error
+
+					select {
+					case <-ctx.Done():
+						// Keep draining the error channel so the experiment start
+						// Goroutine doesn't block forever trying to send to it. It will
+						// be closed when that Goroutine returns.
+						go func() {
+							for range ch {
+							}
+						}()
+
+						return
+					case e, ok := <-ch:
+						if !ok {
+							return
+						}
+
+						err = e
+					}
+
+					logger.Warn("delayed error starting experiment", "exp", name, "err", err)
 
 					var delayErr experiment.DelayedVMError
 
+					// Delayed VMs are only reported here once they've used up their
+					// retries, at which point they can be retried individually.
 					if errors.As(err, &delayErr) {
-						broker.Broadcast(
-							bt.NewRequestPolicy("experiments/start", "update", name),
-							bt.NewResource("experiment/vm", fmt.Sprintf("%s/%s", name, delayErr.VM), "error"),
-							json.RawMessage(fmt.Sprintf(`{"error": "unable to start delayed VM %s"}`, delayErr.VM)),
-						)
+						delayed := recordDelayedError(name, delayErr)
+
+						delayedErrsMu.Lock()
+						delayedErrs = append(delayedErrs, delayed)
+						delayedErrsMu.Unlock()
 					}
 				}
-
-				// Stop periodically printing out logs via previous Goroutine.
-				close(done)
 			}()
 		}
 
@@ -114,48 +597,74 @@ func startExperiment(name string) ([]byte, error) {
 		status <- result{exp, err}
 	}()
 
-	var progress float64
+	var (
+		progress float64
+		interval = o.ProgressInterval()
+	)
+
 	count, _ := vm.Count(name)
 
 	for {
 		select {
 		case s := <-status:
+			if errors.Is(context.Cause(startCtx), errStartCanceled) {
+				// `cancelExperimentStart` is responsible for cleaning up after the
+				// experiment and broadcasting the cancellation.
+				err := weberror.NewWebError(errStartCanceled, "start of experiment %s was canceled", name)
+				return nil, err.SetStatus(http.StatusConflict).SetCode(weberror.StartCanceled)
+			}
+
 			if s.err != nil {
-				broker.Broadcast(
-					bt.NewRequestPolicy("experiments/start", "update", name),
-					bt.NewResource("experiment", name, "errorStarting"),
-					nil,
-				)
+				if retry && retryableStartError(s.err) {
+					return nil, startAttemptError{s.err}
+				}
 
-				err := weberror.NewWebError(s.err, "unable to start experiment %s", name)
-				return nil, err.SetStatus(http.StatusBadRequest)
+				return nil, startError(name, user, s.err)
 			}
 
-			// We don't want to use the HTTP request's context here.
-			ctx, cancel := context.WithCancel(context.Background())
-			cancelers[name] = append(cancelers[name], cancel)
+			// Record how long the start took for capacity planning. This is done
+			// before scheduling periodic apps so it doesn't race with their status
+			// updates.
+			s.exp.Status.SetLastStartDurationSeconds(time.Since(started).Seconds())
 
-			var wg sync.WaitGroup
-			waiters[name] = &wg
+			if err := s.exp.WriteToStore(true); err != nil {
+				logger.Error("saving experiment start duration", "exp", name, "err", err)
+			}
 
-			if err := app.PeriodicallyRunApps(ctx, &wg, s.exp); err != nil {
-				cancel() // avoid leakage
-				delete(cancelers, name)
-				delete(waiters, name)
+			experiment.InvalidateCached(name)
 
-				fmt.Printf("Error scheduling experiment apps to run periodically: %v\n", err)
-			}
+			setStartVars(name, o.Vars())
+			schedulePeriodicApps(name, s.exp)
+			startIdleMonitor(name, s.exp)
 
 			vms, err := vm.List(name)
 			if err != nil {
-				// TODO
-				plog.Error("listing VMs in experiment", "exp", name, "err", err)
+				logger.Warn("listing VMs in experiment, retrying", "exp", name, "err", err)
+
+				time.Sleep(vmListRetryBackoff)
+
+				vms, err = vm.List(name)
+			}
+
+			pb := util.ExperimentToProtobuf(*s.exp, "", vms)
+
+			if err != nil {
+				logger.Error("listing VMs in experiment", "exp", name, "err", err)
+
+				pb.VmListError = err.Error()
 			}
 
-			body, err := marshaler.Marshal(util.ExperimentToProtobuf(*s.exp, "", vms))
+			// Only delayed VM errors encountered so far can be included, since
+			// delayed VMs may still be starting after the experiment itself has.
+			delayedErrsMu.Lock()
+			pb.DelayedErrors = append(pb.DelayedErrors, delayedErrs...)
+			pb.PartialFailure = len(delayedErrs) > 0
+			delayedErrsMu.Unlock()
+
+			body, err := marshaler.Marshal(pb)
 			if err != nil {
 				err := weberror.NewWebError(err, "unable to start experiment %s", name)
-				return nil, err.SetStatus(http.StatusInternalServerError)
+				return nil, err.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
 			}
 
 			broker.Broadcast(
@@ -164,89 +673,529 @@ func startExperiment(name string) ([]byte, error) {
 				body,
 			)
 
+			recordExperimentEvent(name, user, "start", nil)
+
 			return body, nil
+		case <-deadline:
+			err := fmt.Errorf("%w after %v", errStartTimedOut, timeout)
+
+			if retry {
+				return nil, startAttemptError{err}
+			}
+
+			werr := abandonExperimentStart(name, user, err)
+			return nil, werr.SetStatus(http.StatusGatewayTimeout).SetCode(weberror.StartTimeout)
 		default:
-			p, err := mm.GetLaunchProgress(name, count)
+			p, states, err := launchProgress(startCtx, name, count, progress)
 			if err != nil {
-				plog.Error("getting progress for experiment", "exp", name, "err", err)
-				continue
-			}
+				// The start itself was canceled or finished; let the status case above
+				// handle it.
+				if startCtx.Err() != nil {
+					time.Sleep(interval)
+					continue
+				}
+
+				logger.Error("monitoring launch of experiment", "exp", name, "err", err)
+
+				err := fmt.Errorf("%w: %w", errLaunchMonitorFailed, err)
 
-			if p > progress {
-				progress = p
+				if retry {
+					return nil, startAttemptError{err}
+				}
+
+				werr := abandonExperimentStart(name, user, err)
+				return nil, werr.SetStatus(http.StatusBadGateway).SetCode(weberror.StartFailed)
 			}
 
-			plog.Info("percent deployed", "percent", progress*100.0)
+			bootGroupMu.Lock()
+			group := bootGroup
+			bootGroupMu.Unlock()
+
+			progress = p
+
+			updateStartStatus(name, func(s *startStatus) { s.percent = progress })
+
+			logger.Info("percent deployed", "exp", name, "percent", progress*100.0)
+			logger.Debug("VM launch states", "exp", name, "states", states, "bootGroup", group.Index, "bootGroups", group.Total)
+
+			readinessMu.Lock()
+
+			for vm, state := range readiness {
+				if states == nil {
+					states = make(map[string]string)
+				}
 
-			status := map[string]interface{}{
-				"percent": progress,
+				states[vm] = state
 			}
 
-			marshalled, _ := json.Marshal(status)
+			readinessMu.Unlock()
 
-			broker.Broadcast(
-				bt.NewRequestPolicy("experiments/start", "update", name),
-				bt.NewResource("experiment", name, "progress"),
-				marshalled,
-			)
+			sp := util.NewStartProgress(progress, count, started)
+			sp.VMs = states
+			sp.BootGroup = group.Index
+			sp.BootGroups = group.Total
+
+			// Progress is only broadcast if someone's listening, since clients that
+			// connect later can catch up via the experiment's status.
+			if broker.HasSubscribers("experiment", name) {
+				marshalled, _ := json.Marshal(sp)
+
+				broker.Broadcast(
+					bt.NewRequestPolicy("experiments/start", "update", name),
+					bt.NewResource("experiment", name, "progress"),
+					marshalled,
+				)
+			}
+
+			saveStartProgress(name, sp)
 
-			time.Sleep(2 * time.Second)
+			time.Sleep(interval)
 		}
 	}
 }
 
-func stopExperiment(name string) ([]byte, error) {
-	if err := cache.LockExperimentForStopping(name); err != nil {
-		err := weberror.NewWebError(err, "unable to lock experiment %s for stopping", name)
-		return nil, err.SetStatus(http.StatusConflict)
-	}
-
-	defer cache.UnlockExperiment(name)
+// monitorLaunch gets the launch progress of the given experiment and the
+// per-VM launch state from a single minimega snapshot of the experiment,
+// retrying transient minimega errors. An error is returned if getting the
+// snapshot fails permanently or keeps failing.
+func monitorLaunch(ctx context.Context, name string, count int, prev float64) (float64, map[string]string, error) {
+	var snap *mm.ExperimentSnapshot
 
-	broker.Broadcast(
-		bt.NewRequestPolicy("experiments/stop", "update", name),
-		bt.NewResource("experiment", name, "stopping"),
-		nil,
-	)
+	err := mm.Retry(ctx, mm.DefaultBackoff, func() error {
+		var err error
 
-	if cancels, ok := cancelers[name]; ok {
-		for _, cancel := range cancels {
-			cancel()
+		snap, err = mm.GetExperimentSnapshot(name, false)
+		if err != nil {
+			plog.Warn("getting launch snapshot for experiment", "exp", name, "err", err)
 		}
 
-		if wg, ok := waiters[name]; ok {
-			wg.Wait()
-		}
-	}
+		return err
+	})
 
-	delete(cancelers, name)
-	delete(waiters, name)
+	if err != nil {
+		return prev, nil, fmt.Errorf("getting launch progress: %w", err)
+	}
 
-	if err := experiment.Stop(name); err != nil {
-		broker.Broadcast(
-			bt.NewRequestPolicy("experiments/stop", "update", name),
-			bt.NewResource("experiment", name, "errorStopping"),
-			nil,
-		)
+	progress := prev
 
-		err := weberror.NewWebError(err, "unable to stop experiment %s", name)
-		return nil, err.SetStatus(http.StatusBadRequest)
+	if count > 0 {
+		if p := snap.LaunchProgress(count); p > prev {
+			progress = p
+		}
 	}
 
-	exp, err := experiment.Get(name)
-	if err != nil {
-		// TODO
-	}
+	return progress, snap.LaunchStates, nil
+}
 
-	vms, err := vm.List(name)
-	if err != nil {
-		// TODO
+// abandonExperimentStart gives up on the start of the given experiment due to
+// the given error, such as it timing out or its launch no longer being able to
+// be monitored. The start is canceled and waited on (for up to
+// abandonStartTimeout, in case it's hung), and anything it already launched,
+// including the side effects of its pre-start apps, is cleaned up before the
+// failure is broadcast, so the start can't keep launching VMs once the caller
+// unlocks the experiment.
+func abandonExperimentStart(name, user string, err error) *weberror.WebError {
+	if cerr := cleanUpExperimentStart(name); cerr != nil {
+		err = fmt.Errorf("%w (cleaning up: %v)", err, cerr)
 	}
 
-	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	updateStartStatus(name, func(s *startStatus) { s.err = err.Error() })
+
+	body, _ := json.Marshal(map[string]any{"error": err.Error()})
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/start", "update", name),
+		bt.NewResource("experiment", name, "errorStarting"),
+		body,
+	)
+
+	recordExperimentEvent(name, user, "errorStarting", err)
+
+	return weberror.NewWebError(err, "unable to start experiment %s", name)
+}
+
+// cleanUpExperimentStart cancels the start of the given experiment, waits for it
+// to exit (for up to abandonStartTimeout, in case it's hung), and cleans up
+// after it. Experiments that finished starting are stopped, and otherwise any
+// VMs already launched and the side effects of the pre-start apps are cleaned
+// up (see experiment.CleanupStart).
+func cleanUpExperimentStart(name string) error {
+	cancels, wg := takeCancelersAndWaiter(name)
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	if wg != nil {
+		done := make(chan struct{})
+
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(abandonStartTimeout):
+			experimentLogger(name).Warn("start of experiment still running after being canceled", "exp", name, "timeout", abandonStartTimeout)
+		}
+	}
+
+	if experiment.Running(name) {
+		return experiment.Stop(name)
+	}
+
+	return experiment.CleanupStart(context.Background(), name)
+}
+
+// cancelExperimentStart cancels the start of the given experiment on behalf of
+// the given user, waits for the start to exit, and stops the experiment to clean
+// up any VMs that were already launched.
+func cancelExperimentStart(name, user string) error {
+	if status := cache.IsExperimentLocked(name); status != cache.StatusStarting {
+		if experiment.Running(name) {
+			err := weberror.NewWebError(nil, "experiment %s is already running", name)
+			return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentRunning)
+		}
+
+		err := weberror.NewWebError(nil, "experiment %s is not starting", name)
+		return err.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotStarting)
+	}
+
+	// Starts still waiting in the start queue haven't touched the experiment yet.
+	launching := hasWaiter(name)
+
+	cancelPeriodicApps(name)
+
+	// The experiment will only be marked as running if it finished launching
+	// before the start was canceled. Otherwise, some of its VMs may have been
+	// launched (and its apps configured) before the start gave up.
+	var err error
+
+	if experiment.Running(name) {
+		err = experiment.Stop(name)
+	} else if launching {
+		err = experiment.CleanupStart(context.Background(), name)
+	}
+
+	if err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/start", "delete", name),
+			bt.NewResource("experiment", name, "errorStopping"),
+			nil,
+		)
+
+		recordExperimentEvent(name, user, "errorStopping", err)
+
+		err := weberror.NewWebError(err, "unable to clean up canceled experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError).SetCode(weberror.StopFailed)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/start", "delete", name),
+		bt.NewResource("experiment", name, "cancelled"),
+		nil,
+	)
+
+	recordExperimentEvent(name, user, "cancelled", nil)
+
+	return nil
+}
+
+// Default number of experiments started concurrently by `startExperiments`.
+const defaultStartConcurrency = 4
+
+type startResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// startExperiments starts the given experiments in parallel, with at most
+// `concurrency` experiments starting at the same time. Each experiment is
+// locked and broadcasted individually via `startExperiment`, and a failure to
+// start one experiment doesn't prevent the others from being started.
+func startExperiments(ctx context.Context, names []string, user string, concurrency int, opts ...experiment.StartOption) []startResult {
+	if concurrency < 1 {
+		concurrency = defaultStartConcurrency
+	}
+
+	var (
+		results = make([]startResult, len(names))
+		jobs    = make(chan int)
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < concurrency && i < len(names); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for idx := range jobs {
+				name := names[idx]
+
+				if _, err := startExperiment(ctx, name, user, opts...); err != nil {
+					results[idx] = startResult{Name: name, Status: "error", Error: err.Error()}
+					continue
+				}
+
+				results[idx] = startResult{Name: name, Status: string(cache.StatusStarted)}
+			}
+		}()
+	}
+
+	for i := range names {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+func stopExperiment(ctx context.Context, name, user string, opts ...experiment.StopOption) (body []byte, err error) {
+	if err := authorizeExperimentAction(ctx, name, user, "stopping", experiment.PermissionEditor); err != nil {
+		return nil, err
+	}
+
+	if err := cache.LockExperimentForStopping(name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for stopping", name)
+		return nil, err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	// Tearing down VMs in reverse boot order waits on each group in turn, which
+	// can take longer than the lock lasts, so it's kept until the stop returns.
+	defer cache.KeepExperimentLocked(name, cache.StatusStopping)()
+	defer recoverLockedExperiment(name, user, "stopping", &err)
+
+	// The experiment can't be in the middle of stopping at this point since it
+	// was successfully locked for stopping, so if it's already stopped there's
+	// nothing left to do (and nothing to broadcast).
+	if exp, err := experiment.Get(name); err == nil && !exp.Running() {
+		return currentExperiment(name, exp)
+	}
+
+	return stopExperimentLocked(name, user, opts...)
+}
+
+// recoverLockedExperiment recovers from a panic while starting or stopping (as
+// given by action) the given experiment, setting the given error so callers
+// (e.g. the scheduler) don't take the whole server down with them. It must be
+// deferred after the experiment's deferred unlock, which then releases the
+// lock once the panic is recovered.
+func recoverLockedExperiment(name, user, action string, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	plog.Error("panic while "+action+" experiment", "exp", name, "panic", r, "stack", string(debug.Stack()))
+
+	perr := fmt.Errorf("panic while %s experiment: %v", action, r)
+
+	policy, event := "experiments/start", "errorStarting"
+
+	if action == "stopping" {
+		policy, event = "experiments/stop", "errorStopping"
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy(policy, "update", name),
+		bt.NewResource("experiment", name, event),
+		nil,
+	)
+
+	recordExperimentEvent(name, user, event, perr)
+
+	werr := weberror.NewWebError(perr, "unable to finish %s experiment %s", action, name)
+	*err = werr.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
+}
+
+// logStartPanic logs a panic in one of the Goroutines helping start the given
+// experiment instead of letting it take the whole server down. The start itself
+// carries on without the helper.
+func logStartPanic(name string) {
+	if r := recover(); r != nil {
+		plog.Error("panic while starting experiment", "exp", name, "panic", r, "stack", string(debug.Stack()))
+	}
+}
+
+// stopExperimentLocked stops the given experiment on behalf of the given user,
+// assuming the caller has already locked the experiment in the cache.
+func stopExperimentLocked(name, user string, opts ...experiment.StopOption) ([]byte, error) {
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/stop", "update", name),
+		bt.NewResource("experiment", name, "stopping"),
+		nil,
+	)
+
+	recordExperimentEvent(name, user, "stopping", nil)
+
+	cancelPeriodicApps(name)
+	stopTrackedCaptures(name)
+	closeConsoles(name)
+	resetStartStatus(name)
+
+	// Only called when forcibly stopping the experiment.
+	progress := func(remaining []string) {
+		body, _ := json.Marshal(map[string]any{"remaining": remaining})
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stop", "update", name),
+			bt.NewResource("experiment", name, "stopping"),
+			body,
+		)
+	}
+
+	// Only called when stopping an experiment with boot groups in reverse boot
+	// order.
+	teardownProgress := func(g experiment.BootGroup) {
+		body, _ := json.Marshal(map[string]any{"teardown_group": g.Index, "teardown_groups": g.Total, "vms": g.VMs})
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stop", "update", name),
+			bt.NewResource("experiment", name, "stopping"),
+			body,
+		)
+	}
+
+	var hardKilled []string
+
+	// Only called when stopping the experiment gracefully.
+	shutdownProgress := func(p experiment.ShutdownProgress) {
+		if p.Status == experiment.ShutdownStatusKilled {
+			hardKilled = append(hardKilled, p.VM)
+		}
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stop", "update", name),
+			bt.NewResource("experiment/vm", name+"/"+p.VM, p.Status),
+			nil,
+		)
+	}
+
+	var snapshotFailures []*proto.SnapshotFailure
+
+	// Only called when stopping the experiment with a snapshot.
+	snapshotProgress := func(p experiment.SnapshotProgress) {
+		status := map[string]any{"vm": p.VM, "done": p.Done, "total": p.Total}
+
+		if p.Error != nil {
+			status["error"] = p.Error.Error()
+			snapshotFailures = append(snapshotFailures, &proto.SnapshotFailure{Vm: p.VM, Error: p.Error.Error()})
+		} else {
+			status["path"] = p.Path
+		}
+
+		body, _ := json.Marshal(status)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stop", "update", name),
+			bt.NewResource("experiment", name, "snapshotting"),
+			body,
+		)
+	}
+
+	// Publish logs generated by the cleanup apps run while stopping.
+	ctx := notes.Context(context.Background(), false)
+	tail := tailExperimentNotes(ctx, name)
+
+	opts = append(opts,
+		experiment.StopWithContext(ctx),
+		experiment.StopWithProgress(progress),
+		experiment.StopWithTeardownProgress(teardownProgress),
+		experiment.StopWithShutdownProgress(shutdownProgress),
+		experiment.StopWithSnapshotProgress(snapshotProgress),
+	)
+
+	err := experiment.Stop(name, opts...)
+	tail()
+
+	if err != nil {
+		// Let the operator know which VMs are still around after the failed stop.
+		remaining := remainingVMs(name)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stop", "update", name),
+			bt.NewResource("experiment", name, "errorStopping"),
+			remaining,
+		)
+
+		recordExperimentEvent(name, user, "errorStopping", err)
+
+		code := weberror.StopFailed
+
+		if errors.Is(err, experiment.ErrExperimentNotRunning) {
+			code = weberror.ExperimentNotRunning
+		}
+
+		err := weberror.NewWebError(err, "unable to stop experiment %s", name)
+		return nil, err.SetStatus(http.StatusBadRequest).SetCode(code).SetData(remaining)
+	}
+
+	return stoppedExperiment(name, user, snapshotFailures, hardKilled)
+}
+
+// stoppedExperiment broadcasts and returns the details of the given experiment
+// after it has been successfully stopped. If the experiment's details can't be
+// read, only its name is broadcast, and the returned body notes the experiment
+// was stopped but its details are missing.
+func stoppedExperiment(name, user string, snapshotFailures []*proto.SnapshotFailure, hardKilled []string) ([]byte, error) {
+	recordExperimentEvent(name, user, "stop", nil)
+
+	// Start-time variables only apply until the experiment is stopped.
+	clearStartVars(name)
+
+	recordStopped(name)
+
+	exp, err := experiment.GetCached(name)
+	if err != nil {
+		plog.Error("getting experiment after stopping", "exp", name, "err", err)
+
+		body, _ := marshaler.Marshal(&proto.Experiment{Name: name})
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stop", "update", name),
+			bt.NewResource("experiment", name, "stop"),
+			body,
+		)
+
+		pb := &proto.Experiment{
+			Name:             name,
+			SnapshotFailures: snapshotFailures,
+			HardKilledVms:    hardKilled,
+			MetadataError:    fmt.Sprintf("experiment stopped, but unable to get its details: %v", err),
+		}
+
+		body, err := marshaler.Marshal(pb)
+		if err != nil {
+			err := weberror.NewWebError(err, "unable to stop experiment %s", name)
+			return nil, err.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
+		}
+
+		return body, nil
+	}
+
+	vms, err := vm.List(name)
+
+	pb := util.ExperimentToProtobuf(*exp, "", vms)
+	pb.SnapshotFailures = snapshotFailures
+	pb.HardKilledVms = hardKilled
+
+	if err != nil {
+		plog.Error("listing VMs in experiment after stopping", "exp", name, "err", err)
+
+		pb.VmListError = err.Error()
+	}
+
+	body, err := marshaler.Marshal(pb)
 	if err != nil {
 		err := weberror.NewWebError(err, "unable to stop experiment %s", name)
-		return nil, err.SetStatus(http.StatusInternalServerError)
+		return nil, err.SetStatus(http.StatusInternalServerError).SetCode(weberror.Internal)
 	}
 
 	broker.Broadcast(
@@ -257,3 +1206,443 @@ func stopExperiment(name string) ([]byte, error) {
 
 	return body, nil
 }
+
+// remainingVMs returns the marshaled list of VMs (and their states) left in
+// the given experiment, or nil if they can't be determined.
+func remainingVMs(name string) []byte {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		plog.Error("getting experiment to list remaining VMs", "exp", name, "err", err)
+		return nil
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		plog.Error("listing remaining VMs in experiment", "exp", name, "err", err)
+		return nil
+	}
+
+	pb := &proto.VMList{Total: uint32(len(vms))}
+
+	for _, v := range vms {
+		pb.Vms = append(pb.Vms, util.VMToProtobuf(name, v, exp.Spec.Topology()))
+	}
+
+	body, err := marshaler.Marshal(pb)
+	if err != nil {
+		plog.Error("marshaling remaining VMs in experiment", "exp", name, "err", err)
+		return nil
+	}
+
+	return body
+}
+
+// restartExperiment stops and then starts the given experiment, keeping its
+// VMs scheduled on the same hosts they were running on. The experiment stays
+// locked for restarting throughout so no other start or stop can happen in
+// between.
+func restartExperiment(ctx context.Context, name, user string) ([]byte, error) {
+	if err := authorizeExperimentAction(ctx, name, user, "restarting", experiment.PermissionEditor); err != nil {
+		return nil, err
+	}
+
+	// Restarting would leave the experiment stopped since it can't be started
+	// back up in maintenance mode.
+	if err := checkMaintenance(name); err != nil {
+		return nil, err
+	}
+
+	if err := cache.LockExperimentForRestarting(name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for restarting", name)
+		return nil, err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(name)
+	defer cache.KeepExperimentLocked(name, cache.StatusRestarting)()
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", name)
+		return nil, err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if !exp.Running() {
+		err := weberror.NewWebError(experiment.ErrExperimentNotRunning, "unable to restart experiment %s", name)
+		return nil, err.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/restart", "update", name),
+		bt.NewResource("experiment", name, "restarting"),
+		nil,
+	)
+
+	recordExperimentEvent(name, user, "restarting", nil)
+
+	// Capture where each VM is actually running so the schedule can be reused
+	// when starting the experiment back up.
+	schedule := make(map[string]string)
+
+	for vm, host := range exp.Status.Schedules() {
+		schedule[vm] = host
+	}
+
+	// Stopping clears the start-time variables, but a restart should reuse them.
+	vars := getStartVars(name)
+
+	if _, err := stopExperimentLocked(name, user); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/restart", "update", name),
+			bt.NewResource("experiment", name, "errorRestarting"),
+			nil,
+		)
+
+		recordExperimentEvent(name, user, "errorRestarting", err)
+
+		return nil, err
+	}
+
+	body, err := queueExperimentStart(name, user, experiment.StartWithSchedule(schedule), experiment.StartWithVars(vars))
+	if err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/restart", "update", name),
+			bt.NewResource("experiment", name, "errorRestarting"),
+			nil,
+		)
+
+		recordExperimentEvent(name, user, "errorRestarting", err)
+
+		return nil, err
+	}
+
+	return body, nil
+}
+
+func pauseExperiment(name string) ([]byte, error) {
+	if err := cache.LockExperimentForPausing(name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for pausing", name)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/pause", "update", name),
+		bt.NewResource("experiment", name, "pausing"),
+		nil,
+	)
+
+	// Periodically running apps are canceled while the experiment is paused
+	// and get rescheduled when it's resumed.
+	cancelPeriodicApps(name)
+
+	if err := experiment.Pause(name); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/pause", "update", name),
+			bt.NewResource("experiment", name, "errorPausing"),
+			nil,
+		)
+
+		if exp, err := experiment.Get(name); err == nil && exp.Running() && !exp.Status.Paused() {
+			schedulePeriodicApps(name, exp)
+			startIdleMonitor(name, exp)
+		}
+
+		err := weberror.NewWebError(err, "unable to pause experiment %s", name)
+		return nil, err.SetStatus(http.StatusBadRequest)
+	}
+
+	exp, err := experiment.GetCached(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s after pausing", name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		plog.Error("listing VMs in experiment", "exp", name, "err", err)
+	}
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, cache.StatusPaused, vms))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to pause experiment %s", name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/pause", "update", name),
+		bt.NewResource("experiment", name, "paused"),
+		body,
+	)
+
+	return body, nil
+}
+
+func resumeExperiment(name string) ([]byte, error) {
+	if err := cache.LockExperimentForResuming(name); err != nil {
+		err := weberror.NewWebError(err, "unable to lock experiment %s for resuming", name)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/resume", "update", name),
+		bt.NewResource("experiment", name, "resuming"),
+		nil,
+	)
+
+	if err := experiment.Resume(name); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/resume", "update", name),
+			bt.NewResource("experiment", name, "errorResuming"),
+			nil,
+		)
+
+		err := weberror.NewWebError(err, "unable to resume experiment %s", name)
+		return nil, err.SetStatus(http.StatusBadRequest)
+	}
+
+	// Periodic apps and the idle monitor keep (and modify) the experiment they're
+	// given, so they can't be given the shared cached copy.
+	exp, err := experiment.Get(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s after resuming", name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	schedulePeriodicApps(name, exp)
+	startIdleMonitor(name, exp)
+
+	vms, err := vm.List(name)
+	if err != nil {
+		plog.Error("listing VMs in experiment", "exp", name, "err", err)
+	}
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, cache.StatusStarted, vms))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to resume experiment %s", name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/resume", "update", name),
+		bt.NewResource("experiment", name, "resumed"),
+		body,
+	)
+
+	return body, nil
+}
+
+// launchProgress gets the launch progress of the given experiment, and the
+// per-VM launch state, the same way for every client following the start (see
+// monitorLaunch). When VMs are started in batches or boot groups, minimega's
+// launch queue only reflects the launch itself, so progress is based on how
+// many VMs are running if that's further along. It never returns a value less
+// than the previous one provided.
+func launchProgress(ctx context.Context, name string, count int, prev float64) (float64, map[string]string, error) {
+	p, states, err := monitorLaunch(ctx, name, count, prev)
+	if err != nil {
+		return p, nil, err
+	}
+
+	if count > 0 {
+		var running int
+
+		for _, state := range states {
+			if state == mm.LaunchStateRunning {
+				running++
+			}
+		}
+
+		if r := float64(running) / float64(count); r > p {
+			p = r
+		}
+	}
+
+	return p, states, nil
+}
+
+// schedulePeriodicApps starts running the experiment's apps periodically in
+// the background, tracking the canceler and wait group so they can be torn
+// down later.
+func schedulePeriodicApps(name string, exp *types.Experiment) {
+	// We don't want to use the HTTP request's context here.
+	ctx, cancel := context.WithCancel(context.Background())
+	addCanceler(name, cancel)
+
+	var wg sync.WaitGroup
+	setWaiter(name, &wg)
+
+	// Keep periodic runs from overlapping with manually triggered runs.
+	ctx = app.SetContextAppLocker(withStartVars(ctx, name), lockExperimentApp)
+
+	if err := app.PeriodicallyRunApps(ctx, &wg, exp); err != nil {
+		cancel() // avoid leakage
+		takeCancelersAndWaiter(name)
+
+		plog.Error("scheduling experiment apps to run periodically", "exp", name, "err", err)
+	}
+}
+
+// reattachPeriodicApps reschedules periodically running apps for experiments
+// that are still running in minimega. The cancelers used to stop periodic apps
+// are only tracked in memory, so without this, periodic apps for running
+// experiments silently stop when the web server restarts. It's meant to be
+// called once when the web server starts.
+func reattachPeriodicApps() {
+	exps, err := types.Experiments(true)
+	if err != nil {
+		plog.Error("getting running experiments to reattach periodic apps", "err", err)
+		return
+	}
+
+	for _, exp := range exps {
+		name := exp.Metadata.Name
+
+		if exp.DryRun() {
+			continue
+		}
+
+		// Only reattach if minimega agrees the experiment is running.
+		if vms := mm.GetVMInfo(mm.NS(name)); len(vms) == 0 {
+			plog.Warn("experiment running in store but not in minimega, not reattaching periodic apps", "exp", name)
+			continue
+		}
+
+		plog.Info("reattaching periodic apps for running experiment", "exp", name)
+
+		schedulePeriodicApps(name, exp)
+		startIdleMonitor(name, exp)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments", "get", name),
+			bt.NewResource("experiment", name, "reattached"),
+			nil,
+		)
+	}
+}
+
+// cancelPeriodicApps cancels any periodically running apps for the given
+// experiment and waits for them to exit.
+func cancelPeriodicApps(name string) {
+	cancels, wg := takeCancelersAndWaiter(name)
+
+	if len(cancels) == 0 {
+		return
+	}
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	if wg != nil {
+		wg.Wait()
+	}
+}
+
+// lockExperimentApp is the app locker used when periodically running apps.
+func lockExperimentApp(name, a string) (func(), error) {
+	if err := cache.LockExperimentApp(name, a); err != nil {
+		return nil, err
+	}
+
+	return func() { cache.UnlockExperimentApp(name, a) }, nil
+}
+
+// triggerExperimentApp runs the running stage of a single app against the given
+// running experiment, returning the app's status and any notes it generated.
+func triggerExperimentApp(name, a string) ([]byte, error) {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", name)
+		return nil, err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if !exp.Running() {
+		err := weberror.NewWebError(nil, "experiment %s is not running", name)
+		return nil, err.SetStatus(http.StatusBadRequest)
+	}
+
+	if exp.App(a) == nil {
+		err := weberror.NewWebError(nil, "app %s not configured for experiment %s", a, name)
+		return nil, err.SetStatus(http.StatusNotFound)
+	}
+
+	if err := cache.LockExperimentApp(name, a); err != nil {
+		err := weberror.NewWebError(err, "app %s is already running for experiment %s", a, name)
+		return nil, err.SetStatus(http.StatusConflict)
+	}
+
+	// Deferred so the lock is released even if the app panics.
+	defer cache.UnlockExperimentApp(name, a)
+
+	pubsub.Publish("trigger-app", app.TriggerPublication{
+		Experiment: name, App: a, State: "start",
+	})
+
+	k := fmt.Sprintf("%s/%s", name, a)
+
+	// We don't want to use the HTTP request's context here.
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx = app.SetContextTriggerUI(ctx)
+	ctx = withStartVars(ctx, name)
+	ctx = notes.Context(ctx, false)
+	addCanceler(k, cancel)
+
+	defer func() {
+		cancel() // avoid leakage
+		takeCancelers(k)
+	}()
+
+	tail := tailExperimentNotes(ctx, name)
+	err = experiment.TriggerRunning(ctx, name, a)
+
+	result := map[string]any{"app": a, "notes": tail()}
+
+	if err != nil {
+		plog.Error("triggering experiment app", "exp", name, "app", a, "err", err)
+
+		humanized := putil.HumanizeError(err, "Unable to trigger running stage for %s app in %s experiment", a, name)
+		pubsub.Publish("trigger-app", app.TriggerPublication{
+			Experiment: name, App: a, State: "error", Error: humanized,
+		})
+
+		result["error"] = err.Error()
+		body, _ := json.Marshal(result)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/trigger", "create", name),
+			bt.NewResource("experiment/app", k, "appTriggered"),
+			body,
+		)
+
+		err := weberror.NewWebError(err, "unable to trigger running stage for %s app in %s experiment", a, name)
+		return nil, err.SetStatus(http.StatusBadRequest)
+	}
+
+	pubsub.Publish("trigger-app", app.TriggerPublication{
+		Experiment: name, App: a, State: "success",
+	})
+
+	// The running stage updates the app's status in the store.
+	if updated, err := experiment.Get(name); err == nil {
+		exp = updated
+	}
+
+	result["status"] = exp.Status.AppStatus()[a]
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling %s app results for experiment %s", a, name)
+		return nil, err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/trigger", "create", name),
+		bt.NewResource("experiment/app", k, "appTriggered"),
+		body,
+	)
+
+	return body, nil
+}
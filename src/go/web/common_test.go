@@ -0,0 +1,256 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/util/mm"
+	"phenix/web/cache"
+	"phenix/web/proto"
+	"phenix/web/weberror"
+
+	"github.com/golang/mock/gomock"
+)
+
+// Make sure a failure to re-read the experiment after it has been stopped
+// doesn't cause a panic and still results in a successful response.
+func TestStoppedExperimentGetFailure(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := store.NewMockStore(ctrl)
+	m.EXPECT().Get(gomock.Any()).Return(fmt.Errorf("store unavailable")).AnyTimes()
+	m.EXPECT().AddEvent(gomock.Any()).Return(nil).AnyTimes()
+
+	store.DefaultStore = m
+
+	failures := []*proto.SnapshotFailure{{Vm: "foo", Error: "bar"}}
+
+	body, err := stoppedExperiment("test-stopped-experiment", "", failures, nil)
+	if err != nil {
+		t.Logf("expected no error, got %v", err)
+		t.FailNow()
+	}
+
+	var pb proto.Experiment
+
+	if err := unmarshaler.Unmarshal(body, &pb); err != nil {
+		t.Logf("unmarshaling response body: %v", err)
+		t.FailNow()
+	}
+
+	if pb.Name != "test-stopped-experiment" {
+		t.Logf("expected experiment name in response, got %q", pb.Name)
+		t.FailNow()
+	}
+
+	if pb.MetadataError == "" {
+		t.Log("expected metadata error in response")
+		t.FailNow()
+	}
+
+	if len(pb.SnapshotFailures) != 1 {
+		t.Log("expected snapshot failures in response")
+		t.FailNow()
+	}
+}
+
+// Make sure intermittent minimega failures while monitoring an experiment's
+// launch are retried, and permanent ones are not.
+func TestMonitorLaunchIntermittentFailures(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm.NewMockMM(ctrl)
+
+	defer func(orig mm.MM, backoff mm.Backoff) {
+		mm.DefaultMM = orig
+		mm.DefaultBackoff = backoff
+	}(mm.DefaultMM, mm.DefaultBackoff)
+
+	mm.DefaultMM = m
+	mm.DefaultBackoff = mm.Backoff{Attempts: 3, Initial: time.Millisecond, Max: time.Millisecond}
+
+	transient := fmt.Errorf("unable to redial: broken pipe")
+
+	snap := &mm.ExperimentSnapshot{
+		Queued:       2,
+		LaunchStates: map[string]string{"foo": mm.LaunchStateRunning},
+	}
+
+	gomock.InOrder(
+		m.EXPECT().GetExperimentSnapshot("test-experiment", false).Return(nil, transient),
+		m.EXPECT().GetExperimentSnapshot("test-experiment", false).Return(nil, transient),
+		m.EXPECT().GetExperimentSnapshot("test-experiment", false).Return(snap, nil),
+	)
+
+	progress, states, err := monitorLaunch(context.Background(), "test-experiment", 4, 0)
+	if err != nil {
+		t.Fatalf("expected transient errors to be retried, got %v", err)
+	}
+
+	if progress != 0.5 || states["foo"] != mm.LaunchStateRunning {
+		t.Fatalf("unexpected launch progress %v and states %v", progress, states)
+	}
+
+	// Permanent errors are only attempted once.
+	m.EXPECT().GetExperimentSnapshot("test-experiment", false).Return(nil, fmt.Errorf("invalid namespace")).Times(1)
+
+	if _, _, err := monitorLaunch(context.Background(), "test-experiment", 4, 0.25); err == nil {
+		t.Fatal("expected permanent error to be returned")
+	}
+
+	// Giving up after too many transient errors.
+	m.EXPECT().GetExperimentSnapshot("test-experiment", false).Return(nil, transient).Times(3)
+
+	if _, _, err := monitorLaunch(context.Background(), "test-experiment", 4, 0.25); err == nil {
+		t.Fatal("expected error after exhausting retries")
+	}
+}
+
+// Make sure a panic while an experiment is locked is turned into an error and
+// doesn't leave the experiment locked.
+func TestRecoverLockedExperiment(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(orig store.Store) {
+		store.DefaultStore = orig
+	}(store.DefaultStore)
+
+	m := store.NewMockStore(ctrl)
+	m.EXPECT().AddEvent(gomock.Any()).Return(nil).Times(1)
+	m.EXPECT().Get(gomock.Any()).Return(store.ErrNotExist).AnyTimes()
+
+	store.DefaultStore = m
+
+	name := "test-panicked-experiment"
+
+	start := func() (err error) {
+		if err := cache.LockExperimentForStarting(name); err != nil {
+			return err
+		}
+
+		defer cache.UnlockExperiment(name)
+		defer recoverLockedExperiment(name, "", "starting", &err)
+
+		panic("boom")
+	}
+
+	err := start()
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected error from panic, got %v", err)
+	}
+
+	if status := cache.IsExperimentLocked(name); status != "" {
+		t.Fatalf("expected experiment to be unlocked, got status %s", status)
+	}
+}
+
+// Make sure a failed start attempt is torn down before it's retried, and that
+// canceling the start while waiting to retry it stops the retry.
+func TestRetryExperimentStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(origStore store.Store, origMM mm.MM) {
+		store.DefaultStore = origStore
+		mm.DefaultMM = origMM
+	}(store.DefaultStore, mm.DefaultMM)
+
+	name := "test-retried-experiment"
+
+	s := store.NewMockStore(ctrl)
+	s.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		c.Version = "phenix.sandia.gov/v1"
+		c.Spec = map[string]any{"experimentName": name, "topology": map[string]any{"nodes": []any{}}}
+
+		return nil
+	}).AnyTimes()
+	s.EXPECT().AddEvent(gomock.Any()).Return(nil).AnyTimes()
+	s.EXPECT().Delete(gomock.Any()).Return(nil).AnyTimes()
+
+	// VMs launched by the failed attempts are cleared before each retry.
+	m := mm.NewMockMM(ctrl)
+	m.EXPECT().GetVMInfo(gomock.Any()).Return(mm.VMs{{Name: "vm"}}).AnyTimes()
+	m.EXPECT().ClearNamespace(name).Return(nil).Times(2)
+
+	store.DefaultStore = s
+	mm.DefaultMM = m
+
+	var (
+		cause   = fmt.Errorf("unable to redial: broken pipe")
+		ctx, cf = context.WithCancel(context.Background())
+		running sync.WaitGroup
+	)
+
+	// Mimic the Goroutine of a failed attempt that's still running.
+	running.Add(1)
+
+	addCanceler(name, cf)
+	setWaiter(name, &running)
+
+	go func() {
+		defer running.Done()
+		<-ctx.Done()
+	}()
+
+	if err := retryExperimentStart(name, "", 1, 2, time.Millisecond, cause); err != nil {
+		t.Fatalf("expected start to be retried, got %v", err)
+	}
+
+	if ctx.Err() == nil {
+		t.Fatal("expected failed attempt to be canceled")
+	}
+
+	if cancels, wg := takeCancelersAndWaiter(name); len(cancels) != 0 || wg != nil {
+		t.Fatalf("expected cancelers to be torn down, got %d cancelers", len(cancels))
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+
+		for _, cancel := range takeCancelers(name) {
+			cancel()
+		}
+	}()
+
+	err := retryExperimentStart(name, "", 2, 2, time.Minute, cause)
+
+	var werr *weberror.WebError
+
+	if !errors.As(err, &werr) || werr.Code != weberror.StartCanceled {
+		t.Fatalf("expected retry to be canceled, got %v", err)
+	}
+}
+
+// Make sure only errors that could be due to the state of the cluster are
+// retried.
+func TestRetryableStartError(t *testing.T) {
+	if !retryableStartError(fmt.Errorf("%w: launching VMs: unable to redial: broken pipe", experiment.ErrLaunchFailed)) {
+		t.Fatal("expected minimega error to be retryable")
+	}
+
+	if !retryableStartError(fmt.Errorf("%w after 1m0s", errStartTimedOut)) {
+		t.Fatal("expected timeout to be retryable")
+	}
+
+	if retryableStartError(fmt.Errorf("starting: %w", experiment.ErrExperimentRunning)) {
+		t.Fatal("expected running experiment error not to be retryable")
+	}
+
+	if retryableStartError(fmt.Errorf("checking experiment resources: %w", experiment.ErrScheduleInfeasible)) {
+		t.Fatal("expected quota error not to be retryable")
+	}
+
+	if retryableStartError(fmt.Errorf("%w: overlapping VLANs", experiment.ErrIsolationViolated)) {
+		t.Fatal("expected isolation error not to be retryable")
+	}
+}
@@ -387,6 +387,14 @@ func UpdateConfig(w http.ResponseWriter, r *http.Request) error {
 		return err.SetStatus(http.StatusForbidden)
 	}
 
+	// Experiment configs are subject to the experiment's own permissions too.
+	// Permissions themselves are only changed via the experiment's access.
+	if strings.EqualFold(vars["kind"], "experiment") {
+		if err := authorizeExperimentAction(ctx, vars["name"], ctx.Value("user").(string), "updating config for", experiment.PermissionEditor); err != nil {
+			return err
+		}
+	}
+
 	var (
 		typ = r.Header.Get("Content-Type")
 		c   *store.Config
@@ -528,6 +536,13 @@ func DeleteConfig(w http.ResponseWriter, r *http.Request) error {
 		return err.SetStatus(http.StatusForbidden)
 	}
 
+	// Experiment configs are subject to the experiment's own permissions too.
+	if strings.EqualFold(vars["kind"], "experiment") {
+		if err := authorizeExperimentAction(ctx, vars["name"], ctx.Value("user").(string), "deleting config for", experiment.PermissionEditor); err != nil {
+			return err
+		}
+	}
+
 	if err := config.Delete(name); err != nil {
 		return weberror.NewWebError(err, "unable to update config %s", name)
 	}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"phenix/util/plog"
+	"phenix/web/cache"
+	"phenix/web/weberror"
+)
+
+func cooldownKey(name string) string {
+	return "experiment|" + name + "|stopped"
+}
+
+// recordStopped records when the given experiment was stopped so it can't be
+// started again until the start cooldown has elapsed. Nothing is recorded if
+// the cooldown is disabled.
+func recordStopped(name string) {
+	if o.startCooldown <= 0 {
+		return
+	}
+
+	stopped := []byte(time.Now().Format(time.RFC3339Nano))
+
+	// The entry expiring with the cooldown means there's nothing to clean up.
+	if err := cache.SetWithExpire(cooldownKey(name), stopped, o.startCooldown); err != nil {
+		plog.Error("recording experiment stop time", "exp", name, "err", err)
+	}
+}
+
+// checkStartCooldown returns an error if the given experiment was stopped less
+// than the start cooldown ago. The error includes a `Retry-After` header with
+// the number of seconds left in the cooldown.
+func checkStartCooldown(name string) error {
+	if o.startCooldown <= 0 {
+		return nil
+	}
+
+	v, ok := cache.Get(cooldownKey(name))
+	if !ok {
+		return nil
+	}
+
+	stopped, err := time.Parse(time.RFC3339Nano, string(v))
+	if err != nil {
+		return nil
+	}
+
+	remaining := o.startCooldown - time.Since(stopped)
+	if remaining <= 0 {
+		return nil
+	}
+
+	retry := strconv.Itoa(int(math.Ceil(remaining.Seconds())))
+
+	werr := weberror.NewWebError(nil, "experiment %s was stopped recently; it can be started again in %s seconds", name, retry)
+	return werr.SetStatus(http.StatusTooManyRequests).SetCode(weberror.StartCooldown).SetHeader("Retry-After", retry)
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"phenix/web/weberror"
+)
+
+func TestCheckStartCooldown(t *testing.T) {
+	defer func() { o.startCooldown = 0 }()
+
+	recordStopped("foo")
+
+	if err := checkStartCooldown("foo"); err != nil {
+		t.Fatalf("expected no error with cooldown disabled, got %v", err)
+	}
+
+	o.startCooldown = time.Minute
+
+	recordStopped("foo")
+
+	err := checkStartCooldown("foo")
+
+	var werr *weberror.WebError
+
+	if !errors.As(err, &werr) {
+		t.Fatalf("expected web error, got %v", err)
+	}
+
+	if werr.Status != http.StatusTooManyRequests || werr.Code != weberror.StartCooldown {
+		t.Errorf("expected 429 with code %s, got %d with code %s", weberror.StartCooldown, werr.Status, werr.Code)
+	}
+
+	if retry := werr.Headers.Get("Retry-After"); retry != "60" {
+		t.Errorf("expected Retry-After of 60 seconds, got %q", retry)
+	}
+
+	if err := checkStartCooldown("bar"); err != nil {
+		t.Errorf("expected no error for experiment that wasn't stopped, got %v", err)
+	}
+}
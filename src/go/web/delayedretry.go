@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/proto"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// recordDelayedError records the given delayed VM that failed to start in the
+// given experiment's start status, so it can be retried, and queues the failure
+// to be broadcast along with any others with the same cause. It returns the
+// error as included in start responses.
+func recordDelayedError(name string, err experiment.DelayedVMError) *proto.DelayedError {
+	delayed := &proto.DelayedError{Vm: err.VM, Error: err.Error()}
+
+	setDelayedError(name, delayed)
+	broadcastVMError(name, err.VM, errors.Unwrap(err))
+
+	return delayed
+}
+
+// broadcastDelayedRetry broadcasts an attempt at starting a delayed VM again
+// after it failed to start.
+func broadcastDelayedRetry(name string, r experiment.DelayedRetry) {
+	status := map[string]any{"attempt": r.Attempt, "retries": r.Retries}
+
+	if r.Err != nil {
+		status["error"] = r.Err.Error()
+	}
+
+	body, _ := json.Marshal(status)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/start", "update", name),
+		bt.NewResource("experiment/vm", fmt.Sprintf("%s/%s", name, r.VM), "retrying"),
+		body,
+	)
+}
+
+// POST /experiments/{exp}/vms/{name}/retry
+//
+// Makes another attempt at starting a delayed VM that failed to start when the
+// experiment was started. Only VMs whose failure has been reported (i.e. after
+// any automatic retries were used up) can be retried, so this never races with
+// the retries made while starting the experiment.
+func RetryDelayedVM(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RetryDelayedVM")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/retry", "update", fullName) {
+		err := weberror.NewWebError(nil, "retrying VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cache.LockVMForStarting(expName, name); err != nil {
+		err := weberror.NewWebError(err, "VM %s is locked", fullName)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	// Taking the recorded failure keeps concurrent retries of the same VM from
+	// both starting it.
+	prev, ok := takeDelayedError(expName, name)
+	if !ok {
+		err := weberror.NewWebError(nil, "no failed start recorded for delayed VM %s", fullName)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	broadcastDelayedRetry(expName, experiment.DelayedRetry{VM: name, Attempt: 1, Retries: 1})
+
+	if err := experiment.RetryDelayedVM(expName, name); err != nil {
+		werr := weberror.NewWebError(err, "unable to retry delayed VM %s", fullName)
+
+		var delayErr experiment.DelayedVMError
+
+		switch {
+		case errors.As(err, &delayErr):
+			recordDelayedError(expName, delayErr)
+			return werr.SetStatus(http.StatusInternalServerError).SetCode(weberror.StartFailed)
+		case errors.Is(err, experiment.ErrDelayedVMStarted):
+			// Started some other way in the meantime, so there's nothing to retry.
+			return werr.SetStatus(http.StatusConflict)
+		}
+
+		setDelayedError(expName, prev)
+
+		switch {
+		case errors.Is(err, experiment.ErrExperimentNotRunning):
+			return werr.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+		case errors.Is(err, experiment.ErrNotDelayedVM):
+			return werr.SetStatus(http.StatusBadRequest)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	v, err := vm.Get(expName, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
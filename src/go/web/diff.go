@@ -0,0 +1,82 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// POST /experiments/{name}/diff
+//
+// Returns the difference between the experiment's stored config and the
+// candidate experiment config in the request body, given as JSON or, with a
+// `Content-Type` of `application/x-yaml`, YAML. Nothing is saved.
+func DiffExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DiffExperiment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "get", name) {
+		err := weberror.NewWebError(nil, "diffing experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to read candidate config for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	var c *store.Config
+
+	if r.Header.Get("Content-Type") == "application/x-yaml" {
+		c, err = store.NewConfigFromYAML(body)
+	} else {
+		c, err = store.NewConfigFromJSON(body)
+	}
+
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse candidate config for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	diff, err := experiment.Diff(name, *c)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to diff experiment %s", name)
+
+		switch {
+		case errors.Is(err, store.ErrNotExist):
+			return err.SetStatus(http.StatusNotFound)
+		case errors.Is(err, types.ErrValidationFailed):
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err = json.Marshal(diff)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process diff for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
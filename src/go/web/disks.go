@@ -0,0 +1,231 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/common"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// DefaultDiskWarnThreshold is the percent of a host's disk that can be used
+// before a warning is broadcast for experiments running on it.
+const DefaultDiskWarnThreshold = 90.0
+
+// How often the hosts running experiments are checked against the disk warning
+// threshold.
+const diskWarningsInterval = 5 * time.Minute
+
+// Hosts and paths currently over the disk warning threshold for each
+// experiment, keyed by experiment and then `host|path`, so a warning is only
+// broadcast for an experiment when one of its hosts first crosses it.
+var (
+	diskWarningsMu sync.Mutex
+	diskWarnings   = make(map[string]map[string]bool)
+)
+
+// GET /experiments/{exp}/disks
+//
+// Returns the disk usage of each VM in the experiment, along with the free
+// space on each host the experiment's VMs are running on. A warning is
+// broadcast for the experiment when any of the hosts crosses the configured
+// disk warning threshold, which is also checked in the background.
+func GetExperimentDisks(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentDisks")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/disks", "get", exp) {
+		err := weberror.NewWebError(nil, "getting disk usage for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if !experiment.Running(exp) {
+		err := weberror.NewWebError(nil, "experiment %s is not running", exp)
+		return err.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+	}
+
+	vms, err := mm.GetVMDiskUsage(exp)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get disk usage for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if vms == nil {
+		vms = []mm.VMDiskUsage{}
+	}
+
+	var vmHosts []string
+
+	for _, vm := range vms {
+		vmHosts = append(vmHosts, vm.Host)
+	}
+
+	hosts := experimentHostDiskSpace(vmHosts)
+	checkDiskWarnings(exp, hosts)
+
+	body, err := json.Marshal(map[string]any{"vms": vms, "hosts": hosts})
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process disk usage for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// experimentHostDiskSpace returns the free space for the minimega and phenix
+// directories on each of the given hosts an experiment's VMs are running on.
+// Hosts that can't be checked are logged and left out.
+func experimentHostDiskSpace(vmHosts []string) []mm.HostDiskSpace {
+	var hosts []string
+
+	seen := make(map[string]bool)
+
+	for _, host := range vmHosts {
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+
+	sort.Strings(hosts)
+
+	space := []mm.HostDiskSpace{}
+
+	for _, host := range hosts {
+		for _, path := range []string{common.MinimegaBase, common.PhenixBase} {
+			s, err := mm.GetHostDiskSpace(host, path)
+			if err != nil {
+				plog.Error("getting host disk space", "host", host, "path", path, "err", err)
+				continue
+			}
+
+			space = append(space, s)
+		}
+	}
+
+	return space
+}
+
+// runDiskWarnings periodically checks the hosts running each experiment
+// against the disk warning threshold, so warnings are broadcast without anyone
+// having to look at the experiment's disks.
+func runDiskWarnings() {
+	ticker := time.NewTicker(diskWarningsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		checkRunningDiskWarnings()
+	}
+}
+
+// checkRunningDiskWarnings checks the hosts running each running experiment
+// against the disk warning threshold, forgetting the warnings for experiments
+// that are no longer running.
+func checkRunningDiskWarnings() {
+	if o.diskWarnThreshold <= 0 {
+		return
+	}
+
+	exps, err := experiment.List()
+	if err != nil {
+		plog.Error("getting experiments for disk warnings", "err", err)
+		return
+	}
+
+	running := make(map[string]bool)
+
+	for _, exp := range exps {
+		if !exp.Running() || exp.DryRun() {
+			continue
+		}
+
+		name := exp.Metadata.Name
+		running[name] = true
+
+		var hosts []string
+
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			hosts = append(hosts, vm.Host)
+		}
+
+		checkDiskWarnings(name, experimentHostDiskSpace(hosts))
+	}
+
+	diskWarningsMu.Lock()
+	defer diskWarningsMu.Unlock()
+
+	for name := range diskWarnings {
+		if !running[name] {
+			delete(diskWarnings, name)
+		}
+	}
+}
+
+// checkDiskWarnings broadcasts a warning for the given experiment for each host
+// that has crossed the disk warning threshold since it was last checked for the
+// experiment. It's a no-op if the threshold is disabled.
+func checkDiskWarnings(exp string, hosts []mm.HostDiskSpace) {
+	if o.diskWarnThreshold <= 0 {
+		return
+	}
+
+	diskWarningsMu.Lock()
+	defer diskWarningsMu.Unlock()
+
+	warned := diskWarnings[exp]
+	if warned == nil {
+		warned = make(map[string]bool)
+	}
+
+	for _, host := range hosts {
+		var (
+			key  = host.Host + "|" + host.Path
+			over = host.UsedPercent >= o.diskWarnThreshold
+		)
+
+		if over && !warned[key] {
+			plog.Warn("host disk usage over threshold", "exp", exp, "host", host.Host, "path", host.Path, "used", host.UsedPercent, "threshold", o.diskWarnThreshold)
+
+			body, _ := json.Marshal(map[string]any{"host": host, "threshold": o.diskWarnThreshold})
+
+			broker.Broadcast(
+				bt.NewRequestPolicy("experiments/disks", "get", exp),
+				bt.NewResource("experiment/disks", exp, "warning"),
+				body,
+			)
+		}
+
+		if over {
+			warned[key] = true
+		} else {
+			delete(warned, key)
+		}
+	}
+
+	if len(warned) > 0 {
+		diskWarnings[exp] = warned
+	} else {
+		delete(diskWarnings, exp)
+	}
+}
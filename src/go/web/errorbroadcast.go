@@ -0,0 +1,174 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/web/broker"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// How long errors starting delayed VMs are collected before being broadcast,
+// so identical errors from VMs failing at about the same time (e.g. because of
+// a bad image) are broadcast once, and the minimum time between error
+// broadcasts. They're variables so tests can shorten them.
+var (
+	vmErrorWindow   = 500 * time.Millisecond
+	vmErrorInterval = 250 * time.Millisecond
+)
+
+// vmErrorGroup is a set of VMs in an experiment that failed to start with the
+// same error.
+type vmErrorGroup struct {
+	Experiment string   `json:"-"`
+	Error      string   `json:"error"`
+	Cause      string   `json:"cause"`
+	VMs        []string `json:"vms"`
+}
+
+var vmErrors = &vmErrorBroadcaster{pending: make(map[string]map[string][]string)}
+
+// Broadcasts each group of VM errors as it's flushed. It's a variable so tests
+// can see what's broadcast.
+var broadcastVMErrorGroup = vmErrorGroup.broadcast
+
+type vmErrorBroadcaster struct {
+	sync.Mutex
+
+	// VMs waiting to have their errors broadcast, keyed by experiment and then by
+	// the underlying error.
+	pending map[string]map[string][]string
+
+	flushing bool
+	last     time.Time
+}
+
+// broadcastVMError queues the error starting the given delayed VM in the given
+// experiment to be broadcast. Errors with the same cause queued around the
+// same time are broadcast together. Per-VM details are still available from
+// the experiment's start status.
+func broadcastVMError(exp, vm string, cause error) {
+	vmErrors.Lock()
+	defer vmErrors.Unlock()
+
+	var msg string
+
+	if cause != nil {
+		msg = vmErrorCause(vm, cause)
+	}
+
+	if vmErrors.pending[exp] == nil {
+		vmErrors.pending[exp] = make(map[string][]string)
+	}
+
+	vmErrors.pending[exp][msg] = append(vmErrors.pending[exp][msg], vm)
+
+	if !vmErrors.flushing {
+		vmErrors.flushing = true
+		go vmErrors.flush()
+	}
+}
+
+func (this *vmErrorBroadcaster) flush() {
+	for {
+		time.Sleep(vmErrorWindow)
+
+		this.Lock()
+
+		if len(this.pending) == 0 {
+			this.flushing = false
+			this.Unlock()
+
+			return
+		}
+
+		groups := groupVMErrors(this.pending)
+		this.pending = make(map[string]map[string][]string)
+
+		this.Unlock()
+
+		for _, group := range groups {
+			// Only this Goroutine updates last while flushing.
+			if wait := time.Until(this.last.Add(vmErrorInterval)); wait > 0 {
+				time.Sleep(wait)
+			}
+
+			this.last = time.Now()
+
+			broadcastVMErrorGroup(group)
+		}
+	}
+}
+
+// vmErrorCause returns the message of the given error starting the given VM
+// with the VM's name replaced, so the same error from different VMs is grouped
+// together. Only the whole name is replaced, so names that are part of others
+// (e.g. in image names) are left alone.
+func vmErrorCause(vm string, cause error) string {
+	re := regexp.MustCompile(`(^|[^\w-])` + regexp.QuoteMeta(vm) + `([^\w-]|$)`)
+	return re.ReplaceAllString(cause.Error(), "${1}<vm>${2}")
+}
+
+func (this vmErrorGroup) broadcast() {
+	policy := bt.NewRequestPolicy("experiments/start", "update", this.Experiment)
+
+	// A single VM is broadcast the same way it was before errors were grouped,
+	// for clients that only handle per-VM errors.
+	if len(this.VMs) == 1 {
+		body, _ := json.Marshal(map[string]string{"error": this.Error, "cause": this.Cause})
+
+		broker.Broadcast(
+			policy,
+			bt.NewResource("experiment/vm", fmt.Sprintf("%s/%s", this.Experiment, this.VMs[0]), "error"),
+			body,
+		)
+
+		return
+	}
+
+	body, _ := json.Marshal(this)
+
+	broker.Broadcast(
+		policy,
+		bt.NewResource("experiment", this.Experiment, "vmErrors"),
+		body,
+	)
+}
+
+// groupVMErrors returns the given pending VM errors as groups of VMs with the
+// same error, ordered by experiment and then by the first VM in each group.
+func groupVMErrors(pending map[string]map[string][]string) []vmErrorGroup {
+	var groups []vmErrorGroup
+
+	for exp, causes := range pending {
+		for cause, vms := range causes {
+			vms = append([]string(nil), vms...)
+			sort.Strings(vms)
+
+			group := vmErrorGroup{Experiment: exp, Cause: cause, VMs: vms}
+
+			if len(vms) == 1 {
+				group.Error = "unable to start delayed VM " + vms[0]
+			} else {
+				group.Error = fmt.Sprintf("%d VMs failed with: unable to start delayed VM", len(vms))
+			}
+
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Experiment != groups[j].Experiment {
+			return groups[i].Experiment < groups[j].Experiment
+		}
+
+		return groups[i].VMs[0] < groups[j].VMs[0]
+	})
+
+	return groups
+}
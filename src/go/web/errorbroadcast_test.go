@@ -0,0 +1,89 @@
+package web
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Make sure identical errors from different VMs are collapsed into one group,
+// while single VMs keep their own error.
+func TestGroupVMErrors(t *testing.T) {
+	pending := map[string]map[string][]string{
+		"exp1": {
+			"bad image": {"vm3", "vm1", "vm2"},
+			"no memory": {"vm4"},
+		},
+		"exp2": {
+			"bad image": {"vm1"},
+		},
+	}
+
+	expected := []vmErrorGroup{
+		{Experiment: "exp1", Error: "3 VMs failed with: unable to start delayed VM", Cause: "bad image", VMs: []string{"vm1", "vm2", "vm3"}},
+		{Experiment: "exp1", Error: "unable to start delayed VM vm4", Cause: "no memory", VMs: []string{"vm4"}},
+		{Experiment: "exp2", Error: "unable to start delayed VM vm1", Cause: "bad image", VMs: []string{"vm1"}},
+	}
+
+	if groups := groupVMErrors(pending); !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, groups)
+	}
+}
+
+// Make sure errors queued around the same time are flushed together, with VM
+// names left out of their causes, and flushed groups are rate limited.
+func TestBroadcastVMErrors(t *testing.T) {
+	defer func(window, interval time.Duration, broadcast func(vmErrorGroup)) {
+		vmErrorWindow, vmErrorInterval, broadcastVMErrorGroup = window, interval, broadcast
+	}(vmErrorWindow, vmErrorInterval, broadcastVMErrorGroup)
+
+	vmErrorWindow, vmErrorInterval = 20*time.Millisecond, 50*time.Millisecond
+
+	var (
+		groups []vmErrorGroup
+		times  []time.Time
+		mu     sync.Mutex
+	)
+
+	broadcastVMErrorGroup = func(group vmErrorGroup) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		groups = append(groups, group)
+		times = append(times, time.Now())
+	}
+
+	broadcastVMError("exp", "vm2", errors.New("launching vm2: image vm2-disk.qc2 missing"))
+	broadcastVMError("exp", "vm1", errors.New("launching vm1: image vm2-disk.qc2 missing"))
+	broadcastVMError("exp", "vm10", errors.New("out of memory"))
+
+	for i := 0; i < 100; i++ {
+		vmErrors.Lock()
+		flushing := vmErrors.flushing
+		vmErrors.Unlock()
+
+		if !flushing {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	expected := []vmErrorGroup{
+		{Experiment: "exp", Error: "2 VMs failed with: unable to start delayed VM", Cause: "launching <vm>: image vm2-disk.qc2 missing", VMs: []string{"vm1", "vm2"}},
+		{Experiment: "exp", Error: "unable to start delayed VM vm10", Cause: "out of memory", VMs: []string{"vm10"}},
+	}
+
+	if !reflect.DeepEqual(groups, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, groups)
+	}
+
+	if gap := times[1].Sub(times[0]); gap < vmErrorInterval {
+		t.Fatalf("expected broadcasts to be at least %v apart, got %v", vmErrorInterval, gap)
+	}
+}
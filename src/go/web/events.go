@@ -0,0 +1,64 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// recordExperimentEvent adds the given lifecycle transition to the experiment's
+// event log, and notifies webhooks if it's a terminal state. Failing to record
+// an event shouldn't fail the transition itself, so errors are only logged.
+func recordExperimentEvent(name, user, action string, err error) {
+	if err := experiment.RecordEvent(name, action, user, err); err != nil {
+		plog.Error("recording experiment event", "exp", name, "action", action, "err", err)
+	}
+
+	notifyWebhooks(name, action, err)
+}
+
+// GET /experiments/{name}/events
+func GetExperimentEvents(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentEvents")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/events", "get", name) {
+		err := weberror.NewWebError(nil, "getting events for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	events, err := experiment.Events(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get events for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if events == nil {
+		events = store.Events{}
+	}
+
+	body, err := json.Marshal(util.WithRoot("events", events))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process events for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"phenix/api/experiment"
 	"phenix/api/vm"
 	"phenix/util/cache"
 	"phenix/util/mm"
@@ -62,6 +63,46 @@ func GetExperimentTopology(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// GET /experiments/{name}/topology.{dot,svg}
+func GetExperimentTopologyDiagram(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentTopologyDiagram")
+
+	var (
+		ctx    = r.Context()
+		role   = ctx.Value("role").(rbac.Role)
+		vars   = mux.Vars(r)
+		name   = vars["name"]
+		format = vars["format"]
+	)
+
+	if !role.Allowed("experiments/topology", "get", name) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var (
+		body []byte
+		err  error
+	)
+
+	switch format {
+	case "dot":
+		body, err = experiment.TopologyDOT(name)
+		w.Header().Set("Content-Type", "text/vnd.graphviz")
+	case "svg":
+		body, err = experiment.TopologySVG(ctx, name)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	}
+
+	if err != nil {
+		plog.Error("generating experiment topology diagram", "exp", name, "format", format, "err", err)
+		http.Error(w, "unable to generate experiment topology diagram", http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
 // GET /experiments/{name}/topology/search?hostname=xyz&vlan=abc
 func SearchExperimentTopology(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "SearchExperimentTopology")
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"phenix/util/notes"
+	"phenix/web/broker"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// How often notes are flushed to the experiment log topic while tailing them.
+const experimentLogInterval = 1 * time.Second
+
+// experimentLogEntry is published to the `experiment/logs` broker topic for
+// each note generated while an experiment is starting, stopping, or running
+// triggered apps. Clients must subscribe to an experiment's logs to receive
+// them.
+type experimentLogEntry struct {
+	Severity  string `json:"severity"`
+	Timestamp string `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+func publishExperimentLog(name, severity, msg string) {
+	body, _ := json.Marshal(experimentLogEntry{
+		Severity:  severity,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Message:   msg,
+	})
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/logs", "get", name),
+		bt.NewResource("experiment/logs", name, "log"),
+		body,
+	)
+}
+
+// flushExperimentNotes logs any new info and warning notes in the given notes
+// context to the server log and publishes them to the experiment's log topic.
+// Info notes are also added to the experiment's start log, if it's starting.
+// The new info notes are returned.
+func flushExperimentNotes(ctx context.Context, name string) []string {
+	var (
+		infos = notes.Info(ctx, false)
+		warns = notes.Warnings(ctx, false)
+	)
+
+	if len(infos) == 0 && len(warns) == 0 {
+		return infos
+	}
+
+	logger := experimentLogger(name)
+
+	for _, note := range infos {
+		logger.Info(note, "exp", name)
+		appendStartLog(name, note)
+		publishExperimentLog(name, "info", note)
+	}
+
+	for _, warn := range warns {
+		logger.Warn(warn.Error(), "exp", name)
+		publishExperimentLog(name, "warn", warn.Error())
+	}
+
+	return infos
+}
+
+// tailExperimentNotes periodically flushes the notes in the given notes context
+// (see flushExperimentNotes) until the returned function is called. The
+// returned function does a final flush and returns all the info notes flushed.
+func tailExperimentNotes(ctx context.Context, name string) func() []string {
+	var (
+		infos []string
+		mu    sync.Mutex
+		done  = make(chan struct{})
+		wg    sync.WaitGroup
+	)
+
+	flush := func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		infos = append(infos, flushExperimentNotes(ctx, name)...)
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(experimentLogInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				flush()
+			}
+		}
+	}()
+
+	return func() []string {
+		close(done)
+		wg.Wait()
+
+		flush()
+
+		return infos
+	}
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// POST /experiments/{exp}/forensic-capture
+//
+// Captures the memory and disk state of the experiment's running VMs into a
+// timestamped bundle in the experiment's files directory without stopping
+// them. The request body is optional, and can limit the capture to some VMs
+// with `{"vms": ["<vm>", ...]}`. Capturing can take a long time, so it's done
+// in the background after responding with 202 Accepted. Progress for each VM
+// is broadcast as it's captured, followed by the bundle (including its path)
+// once it's done, or the error if it failed.
+func ForensicCaptureExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ForensicCaptureExperiment")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		user    = ctx.Value("user").(string)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/forensic-capture", "create", expName) {
+		err := weberror.NewWebError(nil, "capturing experiment %s not allowed for %s", expName, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		VMs []string `json:"vms"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		err := weberror.NewWebError(err, "unable to parse forensic capture request for experiment %s", expName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", expName)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if !exp.Running() {
+		err := weberror.NewWebError(experiment.ErrExperimentNotRunning, "unable to capture experiment %s", expName)
+		return err.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+	}
+
+	if err := cache.LockExperimentForUpdate(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", expName)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	policy := bt.NewRequestPolicy("experiments/forensic-capture", "create", expName)
+
+	broker.Broadcast(policy, bt.NewResource("experiment/forensic-capture", expName, "capturing"), nil)
+
+	go func() {
+		defer cache.UnlockExperiment(expName)
+
+		// Capturing large VMs can take longer than the lock lasts.
+		defer cache.KeepExperimentLocked(expName, cache.StatusUpdating)()
+
+		bundle, err := vm.ForensicCapture(context.Background(), expName, req.VMs, func(p vm.ForensicProgress) {
+			body, _ := json.Marshal(p)
+			broker.Broadcast(policy, bt.NewResource("experiment/forensic-capture", expName, "progress"), body)
+		})
+
+		recordExperimentEvent(expName, user, "forensic-capture", err)
+
+		if err != nil {
+			plog.Error("capturing experiment for forensic bundle", "exp", expName, "err", err)
+
+			body, _ := json.Marshal(map[string]string{"error": err.Error()})
+			broker.Broadcast(policy, bt.NewResource("experiment/forensic-capture", expName, "errorCapturing"), body)
+
+			return
+		}
+
+		body, _ := json.Marshal(bundle)
+		broker.Broadcast(policy, bt.NewResource("experiment/forensic-capture", expName, "capture"), body)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
@@ -25,7 +25,9 @@ import (
 	"phenix/api/scenario"
 	"phenix/api/vm"
 	"phenix/app"
+	"phenix/scheduler"
 	"phenix/store"
+	"phenix/types"
 	"phenix/util/common"
 	"phenix/util/mm"
 	"phenix/util/notes"
@@ -56,7 +58,7 @@ var (
 	ptyMu sync.Mutex
 )
 
-// GET /experiments
+// GET /experiments[?screenshot=<size>][&tag=<selector>]
 func GetExperiments(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetExperiments")
 
@@ -65,6 +67,7 @@ func GetExperiments(w http.ResponseWriter, r *http.Request) {
 		role  = ctx.Value("role").(rbac.Role)
 		query = r.URL.Query()
 		size  = query.Get("screenshot")
+		tag   = query.Get("tag")
 	)
 
 	if !role.Allowed("experiments", "list") {
@@ -73,7 +76,21 @@ func GetExperiments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	experiments, err := experiment.List()
+	var (
+		experiments []types.Experiment
+		err         error
+	)
+
+	if tag != "" {
+		experiments, err = experiment.ListByTag(tag)
+		if errors.Is(err, experiment.ErrInvalidTagSelector) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		experiments, err = experiment.List()
+	}
+
 	if err != nil {
 		plog.Error("getting experiments", "err", err)
 	}
@@ -90,7 +107,9 @@ func GetExperiments(w http.ResponseWriter, r *http.Request) {
 		status := cache.IsExperimentLocked(exp.Metadata.Name)
 
 		if status == "" {
-			if exp.Running() {
+			if exp.Status.Paused() {
+				status = cache.StatusPaused
+			} else if exp.Running() {
 				status = cache.StatusStarted
 			} else {
 				status = cache.StatusStopped
@@ -188,6 +207,7 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 		experiment.CreateWithDeployMode(deployMode),
 		experiment.CreateWithDefaultBridge(req.DefaultBridge),
 		experiment.CreateWithGREMesh(req.UseGreMesh),
+		experiment.CreateWithOwner(experimentOwner(ctx)),
 	}
 
 	if req.WorkflowBranch != "" {
@@ -259,17 +279,21 @@ func UpdateExperiment(w http.ResponseWriter, r *http.Request) error {
 		return err.SetStatus(http.StatusInternalServerError)
 	}
 
-	if exp.Running() {
-		err := weberror.NewWebError(err, "cannot update running experiment %s", name)
-		return err.SetStatus(http.StatusBadRequest)
-	}
-
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		err := weberror.NewWebError(err, "unable to parse update request for experiment %s", name)
 		return err.SetStatus(http.StatusInternalServerError)
 	}
 
+	if isJSONPatch(r, body) {
+		return patchExperiment(w, name, body)
+	}
+
+	if exp.Running() {
+		err := weberror.NewWebError(err, "cannot update running experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
 	var vlans map[string]int
 
 	if err := json.Unmarshal(body, &vlans); err != nil {
@@ -336,6 +360,10 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 	status := cache.IsExperimentLocked(name)
 	allowed := mm.VMs{}
 
+	if status == "" && exp.Status.Paused() {
+		status = cache.StatusPaused
+	}
+
 	// Build a Boolean expression tree and determine
 	// the fields that should be searched
 	filterTree := mm.BuildTree(clientFilter)
@@ -386,22 +414,565 @@ func GetExperiment(w http.ResponseWriter, r *http.Request) error {
 		allowed = allowed.Paginate(n, s)
 	}
 
-	experiment := util.ExperimentToProtobuf(*exp, status, allowed)
-	experiment.VmCount = uint32(totalBeforePaging)
-	body, err := marshaler.Marshal(experiment)
+	experiment := util.ExperimentToProtobuf(*exp, status, allowed)
+	experiment.VmCount = uint32(totalBeforePaging)
+	body, err := marshaler.Marshal(experiment)
+
+	if err != nil {
+		err := weberror.NewWebError(err, "marshaling experiment %s - %v", name, err)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Write(body)
+	return nil
+}
+
+// DELETE /experiments/{name}
+func DeleteExperiment(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "DeleteExperiment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "delete", name) {
+		plog.Warn("deleting experiment not allowed", "user", ctx.Value("user").(string), "exp", name)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := cache.LockExperimentForDeletion(name); err != nil {
+		plog.Error("locking experiment", "exp", name, "action", "deletion", "err", err)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	if err := experiment.Delete(name); err != nil {
+		plog.Error("deleting experiment", "exp", name, "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resetStartStatus(name)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments", "delete", name),
+		bt.NewResource("experiment", name, "delete"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /experiments/{name}/start[?preset=<name>][&progressInterval=<duration>][&dryRun=<bool>][&maxConcurrent=<int>][&idempotent=<bool>][&scheduler=<name>][&bootGroupTimeout=<duration>][&delayedRetries=<int>][&ignoreCooldown=<bool>][&priority=<int>][&trace=<bool>][&readinessProbe=<probe>][&readinessTimeout=<duration>][&verifyIsolation=<bool>][&autoRetry=<int>][&autoRetryBackoff=<duration>]
+// Body (optional): {"vars": {"<key>": "<value>"}}
+func StartExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "StartExperiment")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		name  = vars["name"]
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("experiments/start", "update", name) {
+		err := weberror.NewWebError(nil, "starting experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if ignore, _ := strconv.ParseBool(query.Get("ignoreCooldown")); !ignore {
+		if err := checkStartCooldown(name); err != nil {
+			return err
+		}
+	}
+
+	var opts []experiment.StartOption
+
+	// Options given as query parameters are applied after (and so override)
+	// those from the preset.
+	if v := query.Get("preset"); v != "" {
+		if !role.Allowed("start-presets", "get", v) {
+			err := weberror.NewWebError(nil, "getting start preset %s not allowed for %s", v, ctx.Value("user").(string))
+			return err.SetStatus(http.StatusForbidden)
+		}
+
+		preset, err := experiment.GetPreset(v)
+		if err != nil {
+			return presetError(err, "unable to get start preset %s", v)
+		}
+
+		opts = append(opts, preset.Options()...)
+	}
+
+	if v := query.Get("progressInterval"); v != "" {
+		var interval time.Duration
+
+		if err := parseDuration(v, &interval); err != nil {
+			err := weberror.NewWebError(err, "invalid progress interval %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		if interval < experiment.MinProgressInterval || interval > experiment.MaxProgressInterval {
+			err := weberror.NewWebError(nil, "progress interval must be between %v and %v", experiment.MinProgressInterval, experiment.MaxProgressInterval)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithProgressInterval(interval))
+	}
+
+	// See `experiment.StartWithMaxConcurrentLaunch` for guidance on limits.
+	if v := query.Get("maxConcurrent"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			err := weberror.NewWebError(err, "invalid max concurrent launch %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithMaxConcurrentLaunch(n))
+	}
+
+	if v := query.Get("bootGroupTimeout"); v != "" {
+		var timeout time.Duration
+
+		if err := parseDuration(v, &timeout); err != nil || timeout <= 0 {
+			err := weberror.NewWebError(err, "invalid boot group timeout %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithBootGroupTimeout(timeout))
+	}
+
+	if v := query.Get("delayedRetries"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			err := weberror.NewWebError(err, "invalid delayed VM retries %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithDelayedRetries(n))
+	}
+
+	if v := query.Get("autoRetry"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > experiment.MaxAutoRetries {
+			err := weberror.NewWebError(err, "invalid start retries %s (must be between 0 and %d)", v, experiment.MaxAutoRetries)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		var backoff time.Duration
+
+		if b := query.Get("autoRetryBackoff"); b != "" {
+			if err := parseDuration(b, &backoff); err != nil || backoff < 0 {
+				err := weberror.NewWebError(err, "invalid start retry backoff %s", b)
+				return err.SetStatus(http.StatusBadRequest)
+			}
+		}
+
+		opts = append(opts, experiment.StartWithAutoRetry(n, backoff))
+	}
+
+	if v := query.Get("scheduler"); v != "" {
+		var known bool
+
+		for _, name := range scheduler.List() {
+			if name == v {
+				known = true
+				break
+			}
+		}
+
+		if !known {
+			err := weberror.NewWebError(nil, "unknown scheduler %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithScheduler(v))
+	}
+
+	if idempotent, _ := strconv.ParseBool(query.Get("idempotent")); idempotent {
+		opts = append(opts, experiment.StartWithIdempotent(true))
+	}
+
+	// Traces are retrieved afterwards from `GET /experiments/{name}/trace`.
+	if trace, _ := strconv.ParseBool(query.Get("trace")); trace {
+		opts = append(opts, experiment.StartWithTrace(true))
+	}
+
+	if verify, _ := strconv.ParseBool(query.Get("verifyIsolation")); verify {
+		opts = append(opts, experiment.StartWithIsolationCheck(true))
+	}
+
+	// Probes are of the form `agent`, `tcp:<port>`, or `file:<path>`.
+	if v := query.Get("readinessProbe"); v != "" {
+		probe, err := experiment.ParseReadinessProbe(v)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid readiness probe %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithReadinessProbe(probe))
+	}
+
+	if v := query.Get("readinessTimeout"); v != "" {
+		var timeout time.Duration
+
+		if err := parseDuration(v, &timeout); err != nil || timeout <= 0 {
+			err := weberror.NewWebError(err, "invalid readiness timeout %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithReadinessTimeout(timeout))
+	}
+
+	if v := query.Get("priority"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid start priority %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StartWithPriority(p))
+	}
+
+	// Start-time variables for apps are optionally provided in the body. An
+	// empty body is allowed for backwards compatibility.
+	if r.Body != nil {
+		var req struct {
+			Vars map[string]string `json:"vars"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			err := weberror.NewWebError(err, "unable to parse start request for experiment %s", name)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		if len(req.Vars) > 0 {
+			opts = append(opts, experiment.StartWithVars(req.Vars))
+		}
+	}
+
+	if dryRun, _ := strconv.ParseBool(query.Get("dryRun")); dryRun {
+		body, err := validateExperimentStart(name, opts...)
+		if err != nil {
+			return err
+		}
+
+		w.Write(body)
+		return nil
+	}
+
+	body, err := startExperiment(ctx, name, ctx.Value("user").(string), opts...)
+	if err != nil {
+		return err
+	}
+
+	w.Write(body)
+	return nil
+}
+
+// DELETE /experiments/{name}/start
+func CancelExperimentStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CancelExperimentStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/start", "delete", name) {
+		err := weberror.NewWebError(nil, "canceling start of experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cancelExperimentStart(name, ctx.Value("user").(string)); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// POST /experiments/start[?concurrency=<n>][&ignoreCooldown=<bool>]
+func StartExperiments(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "StartExperiments")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		query = r.URL.Query()
+	)
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to parse start request for experiments")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	var req struct {
+		Names []string `json:"names"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse start request for experiments")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if len(req.Names) == 0 {
+		err := weberror.NewWebError(nil, "no experiment names provided")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	var concurrency int
+
+	if v := query.Get("concurrency"); v != "" {
+		if err := parseInt(v, &concurrency); err != nil || concurrency < 1 {
+			err := weberror.NewWebError(err, "invalid concurrency %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	var (
+		allowed []string
+		denied  []startResult
+	)
+
+	ignoreCooldown, _ := strconv.ParseBool(query.Get("ignoreCooldown"))
+
+	for _, name := range req.Names {
+		if !role.Allowed("experiments/start", "update", name) {
+			plog.Warn("starting experiment not allowed", "user", ctx.Value("user").(string), "exp", name)
+			denied = append(denied, startResult{Name: name, Status: "error", Error: "forbidden"})
+
+			continue
+		}
+
+		if !ignoreCooldown {
+			if err := checkStartCooldown(name); err != nil {
+				denied = append(denied, startResult{Name: name, Status: "error", Error: err.Error()})
+				continue
+			}
+		}
+
+		allowed = append(allowed, name)
+	}
+
+	results := append(startExperiments(ctx, allowed, ctx.Value("user").(string), concurrency), denied...)
+
+	body, err = json.Marshal(results)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to marshal experiment start results")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{name}/stop[?force=<bool>][&snapshot=<label>][&parallel=<bool>][&graceful=<duration>]
+func StopExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "StopExperiment")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		name  = vars["name"]
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("experiments/stop", "update", name) {
+		err := weberror.NewWebError(nil, "stopping experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var opts []experiment.StopOption
+
+	if v := query.Get("force"); v != "" {
+		force, err := strconv.ParseBool(v)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid force value %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StopWithForce(force))
+	}
+
+	if label := query.Get("snapshot"); label != "" {
+		if !experiment.ValidSnapshotLabel(label) {
+			err := weberror.NewWebError(experiment.ErrInvalidSnapshotLabel, "invalid snapshot label %s (must only contain letters, numbers, '.', '_' and '-')", label)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StopWithSnapshot(label))
+	}
+
+	if v := query.Get("parallel"); v != "" {
+		parallel, err := strconv.ParseBool(v)
+		if err != nil {
+			err := weberror.NewWebError(err, "invalid parallel value %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StopWithParallel(parallel))
+	}
+
+	if v := query.Get("graceful"); v != "" {
+		var timeout time.Duration
+
+		if err := parseDuration(v, &timeout); err != nil || timeout > experiment.MaxGracefulTimeout {
+			err := weberror.NewWebError(err, "invalid graceful stop timeout %s (must be at most %v)", v, experiment.MaxGracefulTimeout)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		opts = append(opts, experiment.StopWithGraceful(timeout))
+	}
+
+	body, err := stopExperiment(ctx, name, ctx.Value("user").(string), opts...)
+	if err != nil {
+		return err
+	}
+
+	w.Write(body)
+	return nil
+}
+
+// GET /experiments/{name}/progress[?progressInterval=<duration>]
+//
+// Streams the start progress of the experiment as server-sent events every
+// progress interval, ending with a `done` event once it's running or a
+// `failed` event if it stops starting without running (the start failed or
+// was canceled).
+func GetExperimentProgress(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentProgress")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		query = r.URL.Query()
+		name  = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "get", name) {
+		plog.Warn("getting experiment progress not allowed", "user", ctx.Value("user").(string), "exp", name)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	interval := experiment.DefaultProgressInterval
+
+	if v := query.Get("progressInterval"); v != "" {
+		if err := parseDuration(v, &interval); err != nil {
+			http.Error(w, fmt.Sprintf("invalid progress interval %s", v), http.StatusBadRequest)
+			return
+		}
+
+		if interval < experiment.MinProgressInterval || interval > experiment.MaxProgressInterval {
+			http.Error(w, fmt.Sprintf("progress interval must be between %v and %v", experiment.MinProgressInterval, experiment.MaxProgressInterval), http.StatusBadRequest)
+			return
+		}
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	count, err := vm.Count(name)
+	if err != nil {
+		plog.Error("counting VMs in experiment", "exp", name, "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	var (
+		progress float64
+		states   map[string]string
+		ticker   = time.NewTicker(interval)
+
+		// Clients usually connect right after asking for the experiment to be
+		// started, so it isn't considered failed for not starting until it has
+		// been seen starting or an interval has passed.
+		waited bool
+	)
+
+	defer ticker.Stop()
+
+	for {
+		// The experiment remains locked for starting (or restarting) until
+		// `startExperiment` has broadcasted the final `start` status, even though
+		// it's already marked as running in the store.
+		status := cache.IsExperimentLocked(name)
+		starting := status == cache.StatusStarting || status == cache.StatusRestarting
+
+		if !starting {
+			if experiment.Running(name) {
+				done := util.NewStartProgress(1.0, count, time.Time{})
+				done.Stage = util.StartStageDone
+
+				writeServerSentEvent(w, "done", done)
+				flusher.Flush()
+
+				return
+			}
+
+			if waited {
+				failed := util.NewStartProgress(progress, count, time.Time{})
+				failed.Stage = util.StartStageFailed
+
+				writeServerSentEvent(w, "failed", failed)
+				flusher.Flush()
+
+				return
+			}
+		}
+
+		if starting {
+			waited = true
+
+			// Based on the same minimega snapshot as the progress broadcast while
+			// starting, including its per-VM launch states.
+			if p, s, err := launchProgress(ctx, name, count, progress); err != nil {
+				plog.Error("getting progress for experiment", "exp", name, "err", err)
+			} else {
+				progress, states = p, s
+			}
+		}
+
+		sp := util.NewStartProgress(progress, count, time.Time{})
+		sp.VMs = states
+
+		writeServerSentEvent(w, "progress", sp)
+		flusher.Flush()
 
-	if err != nil {
-		err := weberror.NewWebError(err, "marshaling experiment %s - %v", name, err)
-		return err.SetStatus(http.StatusInternalServerError)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			waited = true
+		}
 	}
-
-	w.Write(body)
-	return nil
 }
 
-// DELETE /experiments/{name}
-func DeleteExperiment(w http.ResponseWriter, r *http.Request) {
-	plog.Debug("HTTP handler called", "handler", "DeleteExperiment")
+// GET /experiments/{name}/startlog
+func GetExperimentStartLog(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentStartLog")
 
 	var (
 		ctx  = r.Context()
@@ -410,38 +981,51 @@ func DeleteExperiment(w http.ResponseWriter, r *http.Request) {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments", "delete", name) {
-		plog.Warn("deleting experiment not allowed", "user", ctx.Value("user").(string), "exp", name)
+	if !role.Allowed("experiments", "get", name) {
+		plog.Warn("getting experiment start log not allowed", "user", ctx.Value("user").(string), "exp", name)
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := cache.LockExperimentForDeletion(name); err != nil {
-		plog.Error("locking experiment", "exp", name, "action", "deletion", "err", err)
-		http.Error(w, err.Error(), http.StatusConflict)
+	body, err := json.Marshal(util.WithRoot("lines", getStartLog(name)))
+	if err != nil {
+		plog.Error("marshaling experiment start log", "exp", name, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	defer cache.UnlockExperiment(name)
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
 
-	if err := experiment.Delete(name); err != nil {
-		plog.Error("deleting experiment", "exp", name, "err", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
+// POST /experiments/{name}/restart
+func RestartExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RestartExperiment")
 
-	broker.Broadcast(
-		bt.NewRequestPolicy("experiments", "delete", name),
-		bt.NewResource("experiment", name, "delete"),
-		nil,
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
 	)
 
-	w.WriteHeader(http.StatusNoContent)
+	if !role.Allowed("experiments/restart", "update", name) {
+		err := weberror.NewWebError(nil, "restarting experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := restartExperiment(ctx, name, ctx.Value("user").(string))
+	if err != nil {
+		return err
+	}
+
+	w.Write(body)
+	return nil
 }
 
-// POST /experiments/{name}/start
-func StartExperiment(w http.ResponseWriter, r *http.Request) error {
-	plog.Debug("HTTP handler called", "handler", "StartExperiment")
+// POST /experiments/{name}/pause
+func PauseExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "PauseExperiment")
 
 	var (
 		ctx  = r.Context()
@@ -450,12 +1034,12 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) error {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/start", "update", name) {
-		err := weberror.NewWebError(nil, "starting experiment %s not allowed for %s", name, ctx.Value("user").(string))
+	if !role.Allowed("experiments/pause", "update", name) {
+		err := weberror.NewWebError(nil, "pausing experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	body, err := startExperiment(name)
+	body, err := pauseExperiment(name)
 	if err != nil {
 		return err
 	}
@@ -464,9 +1048,9 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-// POST /experiments/{name}/stop
-func StopExperiment(w http.ResponseWriter, r *http.Request) error {
-	plog.Debug("HTTP handler called", "handler", "StopExperiment")
+// POST /experiments/{name}/resume
+func ResumeExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ResumeExperiment")
 
 	var (
 		ctx  = r.Context()
@@ -475,12 +1059,12 @@ func StopExperiment(w http.ResponseWriter, r *http.Request) error {
 		name = vars["name"]
 	)
 
-	if !role.Allowed("experiments/stop", "update", name) {
-		err := weberror.NewWebError(nil, "stopping experiment %s not allowed for %s", name, ctx.Value("user").(string))
+	if !role.Allowed("experiments/resume", "update", name) {
+		err := weberror.NewWebError(nil, "resuming experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		return err.SetStatus(http.StatusForbidden)
 	}
 
-	body, err := stopExperiment(name)
+	body, err := resumeExperiment(name)
 	if err != nil {
 		return err
 	}
@@ -509,17 +1093,34 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	apps := strings.Split(appsFilter, ",")
+
+	// Lock all the apps up front so we can let the caller know if any of them
+	// are already running.
+	for i, a := range apps {
+		if err := cache.LockExperimentApp(name, a); err != nil {
+			for _, locked := range apps[:i] {
+				cache.UnlockExperimentApp(name, locked)
+			}
+
+			plog.Warn("experiment app already running", "exp", name, "app", a, "err", err)
+			http.Error(w, fmt.Sprintf("app %s is already running for experiment %s", a, name), http.StatusConflict)
+
+			return
+		}
+	}
+
 	go func() {
-		var (
-			md   = make(map[string]any)
-			apps = strings.Split(appsFilter, ",")
-		)
+		md := make(map[string]any)
 
 		for k, v := range query {
 			md[k] = v
 		}
 
-		for _, a := range apps {
+		trigger := func(a string) error {
+			// Deferred so the lock is released even if the app panics.
+			defer cache.UnlockExperimentApp(name, a)
+
 			pubsub.Publish("trigger-app", app.TriggerPublication{
 				Experiment: name, App: a, State: "start",
 			})
@@ -530,11 +1131,17 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 			ctx, cancel := context.WithCancel(context.Background())
 			ctx = app.SetContextTriggerUI(ctx)
 			ctx = app.SetContextMetadata(ctx, md)
-			cancelers[k] = append(cancelers[k], cancel)
+			ctx = withStartVars(ctx, name)
+			ctx = notes.Context(ctx, false)
+			addCanceler(k, cancel)
 
-			if err := experiment.TriggerRunning(ctx, name, a); err != nil {
+			tail := tailExperimentNotes(ctx, name)
+			err := experiment.TriggerRunning(ctx, name, a)
+			tail()
+
+			if err != nil {
 				cancel() // avoid leakage
-				delete(cancelers, k)
+				takeCancelers(k)
 
 				humanized := putil.HumanizeError(err, "Unable to trigger running stage for %s app in %s experiment", a, name)
 				pubsub.Publish("trigger-app", app.TriggerPublication{
@@ -542,18 +1149,59 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 				})
 
 				plog.Error("triggering experiment app", "exp", name, "app", a, "err", err)
-				return
+				return err
 			}
 
 			pubsub.Publish("trigger-app", app.TriggerPublication{
 				Experiment: name, App: a, State: "success",
 			})
+
+			return nil
+		}
+
+		for i, a := range apps {
+			if err := trigger(a); err != nil {
+				// The remaining apps won't be run, so release their locks.
+				for _, rest := range apps[i+1:] {
+					cache.UnlockExperimentApp(name, rest)
+				}
+
+				return
+			}
 		}
 	}()
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// POST /experiments/{name}/apps/{app}/trigger
+func TriggerExperimentApp(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "TriggerExperimentApp")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+		a    = vars["app"]
+	)
+
+	if !role.Allowed("experiments/trigger", "create", name) {
+		err := weberror.NewWebError(nil, "triggering experiment app %s for %s not allowed for %s", a, name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	body, err := triggerExperimentApp(name, a)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
 // DELETE /experiments/{name}/trigger[?apps=<foo,bar,baz>]
 func CancelTriggeredExperimentApps(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "CancelTriggeredExperimentApps")
@@ -580,14 +1228,10 @@ func CancelTriggeredExperimentApps(w http.ResponseWriter, r *http.Request) {
 		for _, a := range apps {
 			k := fmt.Sprintf("%s/%s", name, a)
 
-			cancels := cancelers[k]
-
-			for _, cancel := range cancels {
+			for _, cancel := range takeCancelers(k) {
 				cancel()
 			}
 
-			delete(cancelers, k)
-
 			pubsub.Publish("trigger-app", app.TriggerPublication{
 				Experiment: name, Verb: "delete", App: a, State: "success",
 			})
@@ -724,10 +1368,14 @@ func GetExperimentCaptures(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var (
-		captures = mm.GetExperimentCaptures(mm.NS(name))
-		allowed  []mm.Capture
-	)
+	captures, err := vm.ListCaptures(name)
+	if err != nil {
+		plog.Error("listing captures for experiment", "exp", name, "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var allowed []mm.Capture
 
 	for _, capture := range captures {
 		if role.Allowed("experiments/captures", "list", capture.VM) {
@@ -838,14 +1486,15 @@ func GetExperimentFile(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, "", time.Now(), bytes.NewReader(contents))
 }
 
-// GET /experiments/{name}/apps
+// GET /experiments/{name}/apps[?details=<bool>]
 func GetExperimentApps(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "GetExperimentApps")
 
 	var (
-		ctx  = r.Context()
-		role = ctx.Value("role").(rbac.Role)
-		name = mux.Vars(r)["name"]
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		name    = mux.Vars(r)["name"]
+		details = r.URL.Query().Get("details")
 	)
 
 	if !role.Allowed("experiments/apps", "get", name) {
@@ -858,6 +1507,37 @@ func GetExperimentApps(w http.ResponseWriter, r *http.Request) error {
 		return weberror.NewWebError(err, "unable to get experiment %s from store", name)
 	}
 
+	if ok, _ := strconv.ParseBool(details); ok {
+		type appDetails struct {
+			Name            string   `json:"name"`
+			Disabled        bool     `json:"disabled"`
+			RunPeriodically string   `json:"runPeriodically,omitempty"`
+			DependsOn       []string `json:"dependsOn,omitempty"`
+			Running         bool     `json:"running"`
+			LastRun         string   `json:"lastRun,omitempty"`
+		}
+
+		var apps []appDetails
+
+		for _, app := range exp.Apps() {
+			apps = append(apps, appDetails{
+				Name:            app.Name(),
+				Disabled:        app.Disabled(),
+				RunPeriodically: app.RunPeriodically(),
+				DependsOn:       app.DependsOn(),
+				Running:         exp.Status.AppRunning()[app.Name()],
+				LastRun:         exp.Status.AppLastRun()[app.Name()],
+			})
+		}
+
+		body, _ := json.Marshal(map[string]any{"apps": apps})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+
+		return nil
+	}
+
 	apps := make(map[string]bool)
 
 	for _, app := range exp.Apps() {
@@ -876,7 +1556,7 @@ func GetExperimentApps(w http.ResponseWriter, r *http.Request) error {
 	return nil
 }
 
-// GET /experiments/{exp}/vms
+// GET /experiments/{exp}/vms[?filter=<tag:key=value,name:glob>]
 func GetVMs(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetVMs")
 
@@ -891,6 +1571,7 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		sortDir = query.Get("sortDir")
 		pageNum = query.Get("pageNum")
 		perPage = query.Get("perPage")
+		filter  = query.Get("filter")
 	)
 
 	if !role.Allowed("vms", "list") {
@@ -904,8 +1585,13 @@ func GetVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vms, err := vm.List(expName)
+	vms, err := vm.ListWithFilter(expName, filter)
 	if err != nil {
+		if errors.Is(err, vm.ErrInvalidFilter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1032,11 +1718,40 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mem := req.Ram
+	if req.Memory != 0 {
+		mem = req.Memory
+	}
+
+	// CPU and memory changes to running VMs are hotplugged live, but nothing
+	// else can be changed along with them.
+	if experiment.Running(expName) && req.Interface == nil && (req.Cpus != 0 || mem != 0) {
+		if req.Disk != "" || req.InjectPartition != 0 || req.Boot != nil || req.ClusterHost != nil || req.SnapshotOption != nil {
+			http.Error(w, "only CPUs and memory can be hotplugged while experiment is running", http.StatusBadRequest)
+			return
+		}
+
+		resizeVM(w, expName, name, int(req.Cpus), int(mem))
+		return
+	}
+
+	// Interface changes to running VMs save the experiment's topology, so the
+	// experiment is locked while they're made.
+	if req.Interface != nil && experiment.Running(expName) {
+		if err := cache.LockExperimentForUpdate(expName); err != nil {
+			plog.Error("locking experiment", "exp", expName, "action", "update", "err", err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		defer cache.UnlockExperiment(expName)
+	}
+
 	opts := []vm.UpdateOption{
 		vm.UpdateExperiment(expName),
 		vm.UpdateVM(name),
 		vm.UpdateWithCPU(int(req.Cpus)),
-		vm.UpdateWithMem(int(req.Ram)),
+		vm.UpdateWithMem(int(mem)),
 		vm.UpdateWithDisk(req.Disk),
 		vm.UpdateWithPartition(int(req.InjectPartition)),
 	}
@@ -1062,6 +1777,12 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 
 	if err := vm.Update(opts...); err != nil {
 		plog.Error("updating VM", "err", err)
+
+		if errors.Is(err, vm.ErrVMRunning) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
 		http.Error(w, "unable to update VM", http.StatusInternalServerError)
 		return
 	}
@@ -1102,6 +1823,57 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// resizeVM hotplugs CPUs and/or memory into a running VM as part of UpdateVM.
+func resizeVM(w http.ResponseWriter, expName, name string, cpus, mem int) {
+	if err := vm.Resize(expName, name, cpus, mem); err != nil {
+		plog.Error("resizing VM", "exp", expName, "vm", name, "err", err)
+
+		if errors.Is(err, vm.ErrHotplugUnsupported) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		http.Error(w, "unable to get experiment", http.StatusBadRequest)
+		return
+	}
+
+	v, err := vm.Get(expName, name)
+	if err != nil {
+		http.Error(w, "unable to get VM", http.StatusInternalServerError)
+		return
+	}
+
+	// minimega keeps reporting the resources the VM was launched with, so
+	// reflect the hotplugged allocation explicitly.
+	if cpus != 0 {
+		v.CPUs = cpus
+	}
+
+	if mem != 0 {
+		v.RAM = mem
+	}
+
+	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms", "patch", fmt.Sprintf("%s/%s", expName, name)),
+		bt.NewResource("experiment/vm", fmt.Sprintf("%s/%s", expName, name), "update"),
+		body,
+	)
+
+	w.Write(body)
+}
+
 // PATCH /experiments/{exp}/vms
 func UpdateVMs(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "UpdateVMs")
@@ -1125,6 +1897,27 @@ func UpdateVMs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Interface changes to running VMs save the experiment's topology, so the
+	// experiment is locked while they're made.
+	hasIface := false
+
+	for _, vmRequest := range req.Vms {
+		if vmRequest.Interface != nil {
+			hasIface = true
+			break
+		}
+	}
+
+	if hasIface && experiment.Running(expName) {
+		if err := cache.LockExperimentForUpdate(expName); err != nil {
+			plog.Error("locking experiment", "exp", expName, "action", "update", "err", err)
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		defer cache.UnlockExperiment(expName)
+	}
+
 	resp := &proto.VMList{Total: req.Total}
 	resp.Vms = make([]*proto.VM, int(req.Total))
 
@@ -1229,7 +2022,7 @@ func DeleteVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := mm.KillVM(mm.NS(expName), mm.VMName(name)); err != nil {
+	if err := vm.Kill(expName, name); err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1603,7 +2396,7 @@ func ResetVM(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
-// POST /experiments/{exp}/vms/{name}/redeploy
+// POST /experiments/{exp}/vms/{name}/redeploy[?replicate-injects=true][&clean=true]
 func RedeployVM(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "RedeployVM")
 
@@ -1616,6 +2409,7 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 		fullName = expName + "/" + name
 		query    = r.URL.Query()
 		inject   = query.Get("replicate-injects") != ""
+		clean, _ = strconv.ParseBool(query.Get("clean"))
 	)
 
 	if !role.Allowed("vms/redeploy", "update", fullName) {
@@ -1637,6 +2431,11 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !exp.Running() {
+		http.Error(w, fmt.Sprintf("experiment %s is not running", expName), http.StatusBadRequest)
+		return
+	}
+
 	v, err := vm.Get(expName, name)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1673,6 +2472,7 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 			vm.Memory(v.RAM),
 			vm.Disk(v.Disk),
 			vm.Inject(inject),
+			vm.Clean(clean),
 		}
 
 		// `body` will be nil if err above was EOF.
@@ -1690,6 +2490,7 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 				vm.Memory(int(req.Ram)),
 				vm.Disk(req.Disk),
 				vm.Inject(req.Injects),
+				vm.Clean(req.Clean || clean),
 			}
 		}
 
@@ -1744,6 +2545,80 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// POST /experiments/{exp}/vms/{name}/screenshot/subscribe[?interval=<duration>][&size=<size>]
+func SubscribeScreenshots(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SubscribeScreenshots")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		exp   = vars["exp"]
+		name  = vars["name"]
+		query = r.URL.Query()
+		size  = query.Get("size")
+
+		interval = defaultScreenshotStreamInterval
+	)
+
+	if !role.Allowed("vms/screenshot", "get", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "streaming screenshots for VM %s/%s not allowed for %s", exp, name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if size == "" {
+		size = "215"
+	}
+
+	if v := query.Get("interval"); v != "" {
+		if err := parseDuration(v, &interval); err != nil {
+			err := weberror.NewWebError(err, "invalid screenshot interval %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		if interval < minScreenshotStreamInterval {
+			err := weberror.NewWebError(nil, "screenshot interval must be at least %v", minScreenshotStreamInterval)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	if !experiment.Running(exp) {
+		err := weberror.NewWebError(nil, "experiment %s is not running", exp)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if err := startScreenshotStream(exp, name, size, interval); err != nil {
+		err := weberror.NewWebError(err, "unable to stream screenshots for VM %s/%s", exp, name)
+		return err.SetStatus(http.StatusTooManyRequests)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// DELETE /experiments/{exp}/vms/{name}/screenshot/subscribe
+func UnsubscribeScreenshots(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UnsubscribeScreenshots")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/screenshot", "get", exp+"/"+name) {
+		err := weberror.NewWebError(nil, "streaming screenshots for VM %s/%s not allowed for %s", exp, name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	stopScreenshotStream(exp, name)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
 // GET /experiments/{exp}/vms/{name}/screenshot.png
 func GetScreenshot(w http.ResponseWriter, r *http.Request) {
 	plog.Debug("HTTP handler called", "handler", "GetScreenshot")
@@ -3007,6 +3882,16 @@ func EjectOpticalDisc(w http.ResponseWriter, r *http.Request) {
 
 }
 
+func writeServerSentEvent(w http.ResponseWriter, event string, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		plog.Error("marshaling server-sent event data", "event", event, "err", err)
+		return
+	}
+
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, body)
+}
+
 func parseDuration(v string, d *time.Duration) error {
 	var err error
 	*d, err = time.ParseDuration(v)
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/store"
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/plog"
+)
+
+type componentHealth struct {
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+type Health struct {
+	Healthy            bool                       `json:"healthy"`
+	Components         map[string]componentHealth `json:"components"`
+	RunningExperiments int                        `json:"runningExperiments"`
+}
+
+// GET /health
+func GetHealth(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "GetHealth")
+
+	health := Health{Healthy: true, Components: make(map[string]componentHealth)}
+
+	if err := mm.Ping(); err != nil {
+		health.Healthy = false
+		health.Components["minimega"] = componentHealth{Error: err.Error()}
+	} else {
+		health.Components["minimega"] = componentHealth{Healthy: true}
+	}
+
+	if _, err := store.List("Experiment"); err != nil {
+		health.Healthy = false
+		health.Components["store"] = componentHealth{Error: err.Error()}
+	} else {
+		health.Components["store"] = componentHealth{Healthy: true}
+
+		if exps, err := types.Experiments(true); err == nil {
+			health.RunningExperiments = len(exps)
+		}
+	}
+
+	body, _ := json.Marshal(health)
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !health.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	w.Write(body)
+}
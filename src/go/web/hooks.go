@@ -0,0 +1,240 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"phenix/util/plog"
+	"phenix/web/cache"
+	"phenix/web/weberror"
+)
+
+const (
+	// User recorded in experiment events for starts triggered by hooks.
+	hookUser = "hook"
+
+	// How far a hook request's timestamp can be from the server's clock.
+	hookMaxSkew = 5 * time.Minute
+
+	// Maximum number of hook requests accepted per window from each caller.
+	hookRateLimit  = 10
+	hookRateWindow = time.Minute
+
+	hookMaxBodySize = 64 * 1024
+)
+
+var hookLimiter = &sourceRateLimiter{limit: hookRateLimit, window: hookRateWindow}
+
+// rateLimiter allows up to limit events within any sliding window.
+type rateLimiter struct {
+	sync.Mutex
+
+	limit  int
+	window time.Duration
+	events []time.Time
+}
+
+// allow records an event at the given time if it's within the limit. If it's
+// not, it returns false along with how long until the next event is allowed.
+func (this *rateLimiter) allow(now time.Time) (bool, time.Duration) {
+	this.Lock()
+	defer this.Unlock()
+
+	cutoff := now.Add(-this.window)
+
+	for len(this.events) > 0 && !this.events[0].After(cutoff) {
+		this.events = this.events[1:]
+	}
+
+	if len(this.events) >= this.limit {
+		return false, this.events[0].Sub(cutoff)
+	}
+
+	this.events = append(this.events, now)
+
+	return true, 0
+}
+
+// sourceRateLimiter allows up to limit events from each source within any
+// sliding window.
+type sourceRateLimiter struct {
+	sync.Mutex
+
+	limit   int
+	window  time.Duration
+	sources map[string]*rateLimiter
+}
+
+// allow records an event from the given source at the given time if it's within
+// the source's limit. If it's not, it returns false along with how long until
+// the source's next event is allowed.
+func (this *sourceRateLimiter) allow(source string, now time.Time) (bool, time.Duration) {
+	this.Lock()
+	defer this.Unlock()
+
+	if this.sources == nil {
+		this.sources = make(map[string]*rateLimiter)
+	}
+
+	// Sources without any events left in the window are forgotten so the
+	// sources don't build up over time.
+	cutoff := now.Add(-this.window)
+
+	for src, limiter := range this.sources {
+		if n := len(limiter.events); n == 0 || !limiter.events[n-1].After(cutoff) {
+			delete(this.sources, src)
+		}
+	}
+
+	limiter, ok := this.sources[source]
+	if !ok {
+		limiter = &rateLimiter{limit: this.limit, window: this.window}
+		this.sources[source] = limiter
+	}
+
+	return limiter.allow(now)
+}
+
+// hookSource returns the address hook requests are rate limited by.
+func hookSource(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+
+	return r.RemoteAddr
+}
+
+// hookSignature returns the hex-encoded HMAC-SHA256 of the given timestamp and
+// body, joined with a period, using the given secret.
+func hookSignature(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyHookRequest checks the given timestamp (Unix seconds) is within the
+// allowed clock skew of now and the given signature is valid for the timestamp
+// and body.
+func verifyHookRequest(secret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		err := weberror.NewWebError(err, "invalid hook timestamp %s", timestamp)
+		return err.SetStatus(http.StatusUnauthorized)
+	}
+
+	if skew := now.Sub(time.Unix(ts, 0)); skew > hookMaxSkew || skew < -hookMaxSkew {
+		err := weberror.NewWebError(nil, "hook timestamp is more than %v from server time", hookMaxSkew)
+		return err.SetStatus(http.StatusUnauthorized)
+	}
+
+	expected := hookSignature(secret, timestamp, body)
+
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		err := weberror.NewWebError(nil, "invalid hook signature")
+		return err.SetStatus(http.StatusUnauthorized)
+	}
+
+	return nil
+}
+
+// POST /hooks/start
+//
+// Starts an experiment on behalf of an external system (e.g. a CI/CD
+// pipeline) without user credentials. The request body is of the form
+// `{"experiment": "<name>", "token": "<nonce>"}`, where the token must be
+// unique for each request. The request must include an `X-Phenix-Timestamp`
+// header with the current Unix time in seconds and an `X-Phenix-Signature`
+// header with the hex-encoded HMAC-SHA256 of `<timestamp>.<body>` using the
+// configured hook secret. Requests are rejected if the timestamp is more than 5
+// minutes off or the token has already been used, and rate limited for each
+// caller after being verified.
+func StartExperimentHook(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "StartExperimentHook")
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, hookMaxBodySize))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to read hook request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	var (
+		timestamp = r.Header.Get("X-Phenix-Timestamp")
+		signature = r.Header.Get("X-Phenix-Signature")
+	)
+
+	if err := verifyHookRequest(o.hookSecret, timestamp, signature, body, time.Now()); err != nil {
+		plog.Warn("rejecting experiment start hook", "remote", r.RemoteAddr, "err", err)
+		return err
+	}
+
+	// Only verified requests count against the rate limit, so unsigned requests
+	// can't use up the limit of legitimate callers.
+	if ok, retry := hookLimiter.allow(hookSource(r), time.Now()); !ok {
+		plog.Warn("rate limiting experiment start hook", "remote", r.RemoteAddr)
+
+		seconds := strconv.Itoa(int(retry.Seconds()) + 1)
+
+		err := weberror.NewWebError(nil, "too many hook requests; try again in %s seconds", seconds)
+		return err.SetStatus(http.StatusTooManyRequests).SetHeader("Retry-After", seconds)
+	}
+
+	var req struct {
+		Experiment string `json:"experiment"`
+		Token      string `json:"token"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse hook request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if req.Experiment == "" || req.Token == "" {
+		err := weberror.NewWebError(nil, "hook request must include an experiment and token")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	// Locking is used since it atomically checks and sets the token. Tokens only
+	// need to be remembered while their timestamp is still valid.
+	if status := cache.Lock("hook|"+req.Token, cache.StatusStarting, 2*hookMaxSkew); status != "" {
+		plog.Warn("rejecting replayed experiment start hook", "remote", r.RemoteAddr, "exp", req.Experiment)
+
+		err := weberror.NewWebError(nil, "hook token has already been used")
+		return err.SetStatus(http.StatusUnauthorized)
+	}
+
+	plog.Info("experiment start hook invoked", "remote", r.RemoteAddr, "exp", req.Experiment)
+
+	recordExperimentEvent(req.Experiment, hookUser, "hookInvoked", nil)
+
+	resp, err := startExperimentFromHook(req.Experiment)
+	if err != nil {
+		recordExperimentEvent(req.Experiment, hookUser, "hookFailed", err)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+
+	return nil
+}
+
+// startExperimentFromHook starts the given experiment on behalf of the start
+// hook, honoring the start cooldown.
+func startExperimentFromHook(name string) ([]byte, error) {
+	if err := checkStartCooldown(name); err != nil {
+		return nil, err
+	}
+
+	return startExperiment(systemContext(), name, hookUser)
+}
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerifyHookRequest(t *testing.T) {
+	var (
+		now  = time.Now()
+		ts   = strconv.FormatInt(now.Unix(), 10)
+		body = []byte(`{"experiment":"foo","token":"abc"}`)
+		sig  = hookSignature("secret", ts, body)
+	)
+
+	if err := verifyHookRequest("secret", ts, sig, body, now); err != nil {
+		t.Fatalf("expected valid signature, got %v", err)
+	}
+
+	if err := verifyHookRequest("other", ts, sig, body, now); err == nil {
+		t.Errorf("expected error for signature from a different secret")
+	}
+
+	if err := verifyHookRequest("secret", ts, sig, []byte(`{"experiment":"bar","token":"abc"}`), now); err == nil {
+		t.Errorf("expected error for tampered body")
+	}
+
+	if err := verifyHookRequest("secret", ts, sig, body, now.Add(hookMaxSkew+time.Second)); err == nil {
+		t.Errorf("expected error for clock-skewed timestamp")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	var (
+		limiter = &rateLimiter{limit: 2, window: time.Minute}
+		now     = time.Now()
+	)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := limiter.allow(now); !ok {
+			t.Fatalf("expected event %d to be allowed", i)
+		}
+	}
+
+	if ok, retry := limiter.allow(now.Add(30 * time.Second)); ok || retry != 30*time.Second {
+		t.Errorf("expected event to be limited for 30s, got allowed=%v retry=%v", ok, retry)
+	}
+
+	if ok, _ := limiter.allow(now.Add(time.Minute)); !ok {
+		t.Errorf("expected event to be allowed once the window passed")
+	}
+}
+
+func TestSourceRateLimiter(t *testing.T) {
+	var (
+		limiter = &sourceRateLimiter{limit: 1, window: time.Minute}
+		now     = time.Now()
+	)
+
+	if ok, _ := limiter.allow("10.0.0.1", now); !ok {
+		t.Fatal("expected first event from source to be allowed")
+	}
+
+	if ok, _ := limiter.allow("10.0.0.1", now); ok {
+		t.Error("expected second event from source to be limited")
+	}
+
+	if ok, _ := limiter.allow("10.0.0.2", now); !ok {
+		t.Error("expected event from another source to be allowed")
+	}
+
+	if ok, _ := limiter.allow("10.0.0.2", now.Add(2*time.Minute)); !ok {
+		t.Error("expected event to be allowed once the window passed")
+	}
+
+	if _, ok := limiter.sources["10.0.0.1"]; ok {
+		t.Error("expected idle source to be forgotten")
+	}
+}
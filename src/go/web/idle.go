@@ -0,0 +1,116 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/types"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+const (
+	// User recorded in experiment events for experiments stopped for being idle.
+	idleMonitorUser = "idle monitor"
+
+	// How often the network activity of experiments with an idle timeout is
+	// checked.
+	idleMonitorInterval = 30 * time.Second
+)
+
+var idleMonitors = newExperimentPollers("idle monitor")
+
+// startIdleMonitor starts watching the network activity of the given
+// experiment's VMs if it has an idle timeout configured, stopping the
+// experiment once the VMs have been idle for that long. The monitor is canceled
+// along with the experiment's periodic apps, so it's meant to be started
+// wherever they're scheduled. The auto-stop settings are re-read each time the
+// activity is checked, so they can be changed while the experiment is running.
+func startIdleMonitor(name string, exp *types.Experiment) {
+	if exp.Spec.AutoStop().IdleTimeout() <= 0 {
+		return
+	}
+
+	var (
+		last       []mm.InterfaceStats
+		polled     time.Time
+		lastActive = time.Now()
+	)
+
+	idleMonitors.start(name, idleMonitorInterval, func() {
+		exp, err := experiment.GetCached(name)
+		if err != nil {
+			plog.Error("getting experiment for idle monitor", "exp", name, "err", err)
+			return
+		}
+
+		var (
+			timeout = exp.Spec.AutoStop().IdleTimeout()
+			minRate = exp.Spec.AutoStop().MinBytesPerSecond()
+		)
+
+		stats, err := mm.GetInterfaceStats(name)
+		if err != nil {
+			plog.Error("getting network stats for idle monitor", "exp", name, "err", err)
+			return
+		}
+
+		now := time.Now()
+
+		// The first poll only sets the baseline counters.
+		if last != nil {
+			if networkStatsRate(networkStatsDeltas(last, stats, now.Sub(polled)), now.Sub(polled)) > minRate {
+				lastActive = now
+			}
+		}
+
+		last, polled = stats, now
+
+		if timeout <= 0 || now.Sub(lastActive) < timeout {
+			return
+		}
+
+		reason := fmt.Sprintf("no network activity above %g bytes/sec for %v", minRate, timeout)
+
+		plog.Info("stopping idle experiment", "exp", name, "reason", reason)
+
+		if _, err := stopExperiment(systemContext(), name, idleMonitorUser); err != nil {
+			plog.Error("stopping idle experiment", "exp", name, "err", err)
+
+			// Give the experiment another full timeout before trying again.
+			lastActive = now
+			return
+		}
+
+		recordExperimentEvent(name, idleMonitorUser, "autoStopped", nil)
+
+		body, _ := json.Marshal(map[string]any{"reason": reason})
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments", "get", name),
+			bt.NewResource("experiment", name, "autoStopped"),
+			body,
+		)
+	})
+}
+
+// networkStatsRate returns the total throughput, in bytes per second, across
+// all the given interface deltas.
+func networkStatsRate(deltas []networkStatsDelta, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+
+	var total uint64
+
+	for _, d := range deltas {
+		total += d.RxBytes + d.TxBytes
+	}
+
+	return float64(total) / elapsed.Seconds()
+}
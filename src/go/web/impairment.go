@@ -0,0 +1,127 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+type linkImpairment struct {
+	LatencyMs int     `json:"latencyMs"`
+	LossPct   float64 `json:"lossPct"`
+	RateKbit  int     `json:"rateKbit"`
+}
+
+// PUT /experiments/{exp}/vlans/{vlan}/impairment
+func SetVLANImpairment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SetVLANImpairment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		vlan = vars["vlan"]
+	)
+
+	if !role.Allowed("experiments/impairment", "update", exp) {
+		err := weberror.NewWebError(nil, "impairing VLANs for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req linkImpairment
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse impairment request for VLAN %s", vlan)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if err := cache.LockExperimentForUpdate(exp); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", exp)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	err := experiment.SetLinkImpairment(exp, vlan, req.LatencyMs, req.LossPct, req.RateKbit)
+	cache.UnlockExperiment(exp)
+
+	if err != nil {
+		return linkImpairmentError(err, "unable to impair VLAN %s in experiment %s", vlan, exp)
+	}
+
+	body, _ := json.Marshal(req)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments", "get", exp),
+		bt.NewResource("experiment/vlan", fmt.Sprintf("%s/%s", exp, vlan), "impaired"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// DELETE /experiments/{exp}/vlans/{vlan}/impairment
+func ClearVLANImpairment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ClearVLANImpairment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		vlan = vars["vlan"]
+	)
+
+	if !role.Allowed("experiments/impairment", "delete", exp) {
+		err := weberror.NewWebError(nil, "clearing VLAN impairments for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cache.LockExperimentForUpdate(exp); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", exp)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	err := experiment.ClearLinkImpairment(exp, vlan)
+	cache.UnlockExperiment(exp)
+
+	if err != nil {
+		return linkImpairmentError(err, "unable to clear impairment for VLAN %s in experiment %s", vlan, exp)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments", "get", exp),
+		bt.NewResource("experiment/vlan", fmt.Sprintf("%s/%s", exp, vlan), "unimpaired"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func linkImpairmentError(err error, format string, args ...any) error {
+	werr := weberror.NewWebError(err, format, args...)
+
+	switch {
+	case errors.Is(err, experiment.ErrVLANNotFound):
+		return werr.SetStatus(http.StatusNotFound)
+	case errors.Is(err, experiment.ErrInvalidImpairment), errors.Is(err, experiment.ErrExperimentNotRunning):
+		return werr.SetStatus(http.StatusBadRequest)
+	default:
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/isolation
+//
+// Checks that the VLANs and taps minimega has assigned to the running
+// experiment's VMs aren't also assigned in other running experiments'
+// namespaces, reporting any overlaps.
+func VerifyExperimentIsolation(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "VerifyExperimentIsolation")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/isolation", "get", name) {
+		err := weberror.NewWebError(nil, "verifying isolation of experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	report, err := experiment.VerifyIsolation(name)
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to verify isolation of experiment %s", name)
+
+		if errors.Is(err, experiment.ErrExperimentNotRunning) {
+			return werr.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentNotRunning)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	if !report.Isolated {
+		plog.Warn("experiment isolation violated", "exp", name, "overlaps", len(report.Overlaps))
+	}
+
+	body, _ := json.Marshal(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/exp/slog"
+)
+
+// PATCH /experiments/{name}/logLevel
+//
+// Sets the log level used when logging about the experiment (e.g. while it's
+// starting) to the level in the request body, of the form `{"level":
+// "debug|info|warn|error"}`. An empty level goes back to using the global log
+// level.
+func UpdateExperimentLogLevel(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateExperimentLogLevel")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/loglevel", "patch", name) {
+		err := weberror.NewWebError(nil, "updating log level for experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Level string `json:"level"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse log level request for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, user, "updating log level for", experiment.PermissionEditor); err != nil {
+		return err
+	}
+
+	if err := cache.LockExperimentForUpdate(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", name)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	err := experiment.SetLogLevel(name, req.Level)
+	cache.UnlockExperiment(name)
+
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to update log level for experiment %s", name)
+
+		if errors.Is(err, experiment.ErrInvalidLogLevel) {
+			return werr.SetStatus(http.StatusBadRequest)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	resp := map[string]any{"experiment": name, "level": ""}
+
+	if level, ok := experiment.LogLevel(name); ok {
+		resp["level"] = strings.ToLower(level.String())
+	}
+
+	body, _ := json.Marshal(resp)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// experimentLogger returns the logger to use when logging about the given
+// experiment, which uses the experiment's log level if one is set.
+func experimentLogger(name string) *slog.Logger {
+	if level, ok := experiment.LogLevel(name); ok {
+		return plog.WithLevel(level)
+	}
+
+	return plog.With()
+}
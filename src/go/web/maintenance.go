@@ -0,0 +1,197 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// Message returned when starting an experiment while in maintenance mode if the
+// operator didn't provide one.
+const defaultMaintenanceMessage = "phenix is in maintenance mode; new experiment starts are disabled"
+
+type maintenanceStatus struct {
+	Enabled bool   `json:"enabled" mapstructure:"enabled"`
+	Message string `json:"message,omitempty" mapstructure:"message"`
+	User    string `json:"user,omitempty" mapstructure:"user"`
+	Updated string `json:"updated,omitempty" mapstructure:"updated"`
+}
+
+var (
+	maintenance   maintenanceStatus
+	maintenanceMu sync.RWMutex
+)
+
+// loadMaintenance initializes the maintenance mode status from the store so it
+// survives a restart.
+func loadMaintenance() error {
+	c := maintenanceConfig()
+
+	if err := store.Get(c); err != nil {
+		if errors.Is(err, store.ErrNotExist) {
+			return nil
+		}
+
+		return fmt.Errorf("getting maintenance status from store: %w", err)
+	}
+
+	var status maintenanceStatus
+
+	if err := mapstructure.Decode(c.Spec, &status); err != nil {
+		return fmt.Errorf("decoding maintenance status: %w", err)
+	}
+
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+
+	maintenance = status
+
+	return nil
+}
+
+func getMaintenance() maintenanceStatus {
+	maintenanceMu.RLock()
+	defer maintenanceMu.RUnlock()
+
+	return maintenance
+}
+
+// setMaintenance persists the given maintenance mode status to the store before
+// making it active.
+func setMaintenance(status maintenanceStatus) error {
+	c := maintenanceConfig()
+
+	c.Spec = map[string]any{
+		"enabled": status.Enabled,
+		"message": status.Message,
+		"user":    status.User,
+		"updated": status.Updated,
+	}
+
+	if err := store.Create(c); err != nil {
+		if !errors.Is(err, store.ErrExist) {
+			return fmt.Errorf("saving maintenance status: %w", err)
+		}
+
+		if err := store.Update(c); err != nil {
+			return fmt.Errorf("saving maintenance status: %w", err)
+		}
+	}
+
+	maintenanceMu.Lock()
+	defer maintenanceMu.Unlock()
+
+	maintenance = status
+
+	return nil
+}
+
+func maintenanceConfig() *store.Config {
+	return &store.Config{
+		Version:  store.API_GROUP + "/v1",
+		Kind:     "Setting",
+		Metadata: store.ConfigMetadata{Name: "maintenance"},
+	}
+}
+
+// checkMaintenance returns an error if maintenance mode is enabled, meaning new
+// experiment starts aren't allowed.
+func checkMaintenance(name string) error {
+	status := getMaintenance()
+	if !status.Enabled {
+		return nil
+	}
+
+	msg := status.Message
+	if msg == "" {
+		msg = defaultMaintenanceMessage
+	}
+
+	err := weberror.NewWebError(nil, "unable to start experiment %s: %s", name, msg)
+	return err.SetStatus(http.StatusServiceUnavailable).SetCode(weberror.Maintenance)
+}
+
+// GET /admin/maintenance
+func GetMaintenance(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetMaintenance")
+
+	// Any authenticated user can see if maintenance mode is enabled, since it
+	// determines whether or not they can start experiments.
+	body, err := json.Marshal(getMaintenance())
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process maintenance status")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /admin/maintenance
+func SetMaintenance(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SetMaintenance")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+	)
+
+	if !role.Allowed("maintenance", "update") {
+		err := weberror.NewWebError(nil, "updating maintenance mode not allowed for %s", user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Enabled bool   `json:"enabled"`
+		Message string `json:"message"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse maintenance mode request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	status := maintenanceStatus{
+		Enabled: req.Enabled,
+		Message: req.Message,
+		User:    user,
+		Updated: time.Now().Format(time.RFC3339),
+	}
+
+	if err := setMaintenance(status); err != nil {
+		err := weberror.NewWebError(err, "unable to update maintenance mode")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	plog.Info("maintenance mode updated", "enabled", status.Enabled, "message", status.Message, "user", user)
+
+	body, _ := json.Marshal(status)
+
+	// Everyone needs to know when experiments can't be started.
+	broker.Broadcast(
+		nil,
+		bt.NewResource("maintenance", "", "update"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
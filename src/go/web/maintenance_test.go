@@ -0,0 +1,36 @@
+package web
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"phenix/web/weberror"
+)
+
+func TestCheckMaintenance(t *testing.T) {
+	defer func() { maintenance = maintenanceStatus{} }()
+
+	if err := checkMaintenance("foo"); err != nil {
+		t.Fatalf("expected no error when not in maintenance mode, got %v", err)
+	}
+
+	maintenance = maintenanceStatus{Enabled: true, Message: "upgrading hosts"}
+
+	err := checkMaintenance("foo")
+
+	var werr *weberror.WebError
+
+	if !errors.As(err, &werr) {
+		t.Fatalf("expected web error, got %v", err)
+	}
+
+	if werr.Status != http.StatusServiceUnavailable || werr.Code != weberror.Maintenance {
+		t.Errorf("expected 503 with code %s, got %d with code %s", weberror.Maintenance, werr.Status, werr.Code)
+	}
+
+	if !strings.Contains(werr.Error(), "upgrading hosts") {
+		t.Errorf("expected error to include maintenance message, got %q", werr.Error())
+	}
+}
@@ -28,14 +28,30 @@ func fromPhenixAuthTokenHeader(r *http.Request) (string, error) {
 	return authHeaderParts[1], nil
 }
 
+// NoAuthUser is the user requests are treated as coming from when they aren't
+// authenticated.
+const NoAuthUser = "global-admin"
+
+// noAuthKey is the context key marking requests that aren't authenticated, so
+// they can be told apart from a user that happens to be named NoAuthUser.
+type noAuthKey struct{}
+
+// Unauthenticated returns true if the request with the given context was let
+// through without authentication (i.e. authentication is disabled).
+func Unauthenticated(ctx context.Context) bool {
+	v, _ := ctx.Value(noAuthKey{}).(bool)
+	return v
+}
+
 func NoAuth(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		role, _ := rbac.RoleFromConfig("global-admin")
 
 		ctx := r.Context()
 
-		ctx = context.WithValue(ctx, "user", "global-admin")
+		ctx = context.WithValue(ctx, "user", NoAuthUser)
 		ctx = context.WithValue(ctx, "role", *role)
+		ctx = context.WithValue(ctx, noAuthKey{}, true)
 
 		h.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -108,6 +124,19 @@ func Auth(jwtKey, proxyAuthHeader string) mux.MiddlewareFunc {
 				return
 			}
 
+			// Allow load balancers and liveness probes to check health without
+			// credentials.
+			if strings.HasSuffix(r.URL.Path, "/api/v1/health") {
+				h.ServeHTTP(w, r)
+				return
+			}
+
+			// The experiment start hook verifies signed requests itself.
+			if strings.HasSuffix(r.URL.Path, "/api/v1/hooks/start") {
+				h.ServeHTTP(w, r)
+				return
+			}
+
 			ctx := r.Context()
 
 			userToken := ctx.Value("user")
@@ -0,0 +1,133 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// POST /experiments/{exp}/vms/{name}/migrate
+func MigrateVM(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "MigrateVM")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/migrate", "update", fullName) {
+		err := weberror.NewWebError(nil, "migrating VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Target string `json:"target"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse VM migration request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if req.Target == "" {
+		err := weberror.NewWebError(nil, "no target host provided for migrating VM %s", fullName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if err := cache.LockVMForMigrating(expName, name); err != nil {
+		plog.Error("locking VM", "exp", expName, "vm", name, "action", "migrating", "err", err)
+
+		err := weberror.NewWebError(err, "unable to lock VM %s for migrating", fullName)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	// Migrating saves the VM's new host to the experiment's config, so the
+	// experiment is locked to keep that from racing with other updates to it.
+	if err := cache.LockExperimentForUpdate(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", expName)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(expName)
+
+	// Migrating large VMs can take longer than the lock lasts.
+	defer cache.KeepExperimentLocked(expName, cache.StatusUpdating)()
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/migrate", "update", fullName),
+		bt.NewResource("experiment/vm", fullName, "migrating"),
+		nil,
+	)
+
+	if err := vm.Migrate(expName, name, req.Target); err != nil {
+		plog.Error("migrating VM", "exp", expName, "vm", name, "target", req.Target, "err", err)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/migrate", "update", fullName),
+			bt.NewResource("experiment/vm", fullName, "errorMigrating"),
+			nil,
+		)
+
+		werr := weberror.NewWebError(err, "unable to migrate VM %s to %s", fullName, req.Target)
+
+		switch {
+		case errors.Is(err, experiment.ErrExperimentNotRunning), errors.Is(err, vm.ErrMigrateSameHost):
+			return werr.SetStatus(http.StatusBadRequest)
+		case errors.Is(err, mm.ErrHostNotFound):
+			return werr.SetStatus(http.StatusNotFound)
+		case errors.Is(err, vm.ErrInsufficientMemory):
+			return werr.SetStatus(http.StatusConflict).SetCode(weberror.ScheduleInfeasible)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	v, err := vm.Get(expName, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/migrate", "update", fullName),
+		bt.NewResource("experiment/vm", fullName, "running"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
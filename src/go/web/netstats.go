@@ -0,0 +1,157 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultNetworkStatsInterval = 5 * time.Second
+	minNetworkStatsInterval     = 1 * time.Second
+)
+
+var networkStatsStreams = newExperimentPollers("network stats")
+
+// networkStatsDelta is the change in an interface's counters between two polls
+// of a network stats stream.
+type networkStatsDelta struct {
+	mm.InterfaceStats
+
+	// Number of seconds between the two polls, so throughput can be computed
+	// from the deltas.
+	Seconds float64 `json:"seconds"`
+}
+
+// GET /experiments/{exp}/stats/network[?stream=true][&interval=<duration>]
+func GetNetworkStats(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetNetworkStats")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		exp   = vars["exp"]
+		query = r.URL.Query()
+
+		interval = defaultNetworkStatsInterval
+	)
+
+	if !role.Allowed("experiments/stats", "get", exp) {
+		err := weberror.NewWebError(nil, "getting network stats for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if v := query.Get("interval"); v != "" {
+		if err := parseDuration(v, &interval); err != nil {
+			err := weberror.NewWebError(err, "invalid network stats interval %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		if interval < minNetworkStatsInterval {
+			err := weberror.NewWebError(nil, "network stats interval must be at least %v", minNetworkStatsInterval)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	if !experiment.Running(exp) {
+		err := weberror.NewWebError(nil, "experiment %s is not running", exp)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	stats, err := mm.GetInterfaceStats(exp)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get network stats for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if query.Get("stream") == "true" {
+		startNetworkStatsStream(exp, interval, stats)
+	}
+
+	if stats == nil {
+		stats = []mm.InterfaceStats{}
+	}
+
+	body, err := json.Marshal(util.WithRoot("stats", stats))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process network stats for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// startNetworkStatsStream starts periodically broadcasting the change in each
+// VM interface's counters for the given experiment, starting from the given
+// stats. It's a no-op if a stream for the experiment is already running.
+func startNetworkStatsStream(exp string, interval time.Duration, last []mm.InterfaceStats) {
+	polled := time.Now()
+
+	networkStatsStreams.start(exp, interval, func() {
+		stats, err := mm.GetInterfaceStats(exp)
+		if err != nil {
+			plog.Error("getting network stats for network stats stream", "exp", exp, "err", err)
+			return
+		}
+
+		now := time.Now()
+
+		body, _ := json.Marshal(util.WithRoot("deltas", networkStatsDeltas(last, stats, now.Sub(polled))))
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/stats", "get", exp),
+			bt.NewResource("experiment/stats/network", exp, "stream"),
+			body,
+		)
+
+		last, polled = stats, now
+	})
+}
+
+// networkStatsDeltas returns the change in counters for each interface in curr
+// since prev. Interfaces missing from prev (e.g. VMs launched since) and
+// interfaces whose counters went backwards (e.g. VMs relaunched since, getting
+// new taps) report their current counters as the delta.
+func networkStatsDeltas(prev, curr []mm.InterfaceStats, elapsed time.Duration) []networkStatsDelta {
+	previous := make(map[string]mm.InterfaceStats)
+
+	for _, s := range prev {
+		previous[fmt.Sprintf("%s/%d", s.VM, s.Interface)] = s
+	}
+
+	deltas := make([]networkStatsDelta, 0, len(curr))
+
+	for _, s := range curr {
+		d := networkStatsDelta{InterfaceStats: s, Seconds: elapsed.Seconds()}
+
+		if p, ok := previous[fmt.Sprintf("%s/%d", s.VM, s.Interface)]; ok && p.Tap == s.Tap {
+			if s.RxBytes >= p.RxBytes && s.TxBytes >= p.TxBytes && s.RxPackets >= p.RxPackets && s.TxPackets >= p.TxPackets {
+				d.RxBytes -= p.RxBytes
+				d.TxBytes -= p.TxBytes
+				d.RxPackets -= p.RxPackets
+				d.TxPackets -= p.TxPackets
+			}
+		}
+
+		deltas = append(deltas, d)
+	}
+
+	return deltas
+}
@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"os"
+	"phenix/api/vm"
 	"phenix/util/common"
 	"phenix/util/plog"
+	"phenix/web/broker"
 	"phenix/web/rbac"
 	"phenix/web/weberror"
 	"strings"
@@ -37,6 +39,31 @@ type serverOptions struct {
 	features map[string]bool
 
 	unixSocketGid int
+
+	shutdownTimeout time.Duration
+	startCooldown   time.Duration
+
+	// Number of experiment starts launched from the start queue at once. Zero
+	// sizes it to the number of cluster hosts.
+	startQueueWorkers int
+
+	hookSecret string
+
+	webhookURLs         []string
+	webhookSecret       string
+	webhookAllowedHosts []string
+
+	diskWarnThreshold float64
+
+	broadcastCoalesceWindow       time.Duration
+	broadcastCompressionThreshold int
+
+	// Minimega commands allowed to be run against VMs.
+	vmExecVerbs []string
+
+	// Range of VLAN IDs (e.g. `2000-2999`) allocated to experiments as they're
+	// started. Empty disables the shared VLAN pool.
+	vlanPool string
 }
 
 func newServerOptions(opts ...ServerOption) serverOptions {
@@ -46,6 +73,14 @@ func newServerOptions(opts ...ServerOption) serverOptions {
 		basePath:    "/",
 		jwtLifetime: 24 * time.Hour,
 		features:    make(map[string]bool),
+
+		shutdownTimeout:   DefaultShutdownTimeout,
+		diskWarnThreshold: DefaultDiskWarnThreshold,
+
+		broadcastCoalesceWindow:       broker.DefaultCoalesceWindow,
+		broadcastCompressionThreshold: broker.DefaultCompressionThreshold,
+
+		vmExecVerbs: vm.DefaultExecVerbs,
 	}
 
 	for _, opt := range opts {
@@ -181,6 +216,107 @@ func ServeWithUnixSocketGid(g int) ServerOption {
 	}
 }
 
+// ServeWithShutdownTimeout sets how long to wait for experiments that are
+// starting to exit when the server is shutting down.
+func ServeWithShutdownTimeout(t time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.shutdownTimeout = t
+	}
+}
+
+// ServeWithStartCooldown sets how long after an experiment is stopped before it
+// can be started again, unless the start overrides it. Zero disables it.
+func ServeWithStartCooldown(c time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.startCooldown = c
+	}
+}
+
+// ServeWithHookSecret sets the secret used to verify the signatures of requests
+// to the experiment start hook. The hook is disabled if no secret is set.
+func ServeWithHookSecret(s string) ServerOption {
+	return func(o *serverOptions) {
+		o.hookSecret = s
+	}
+}
+
+// ServeWithWebhooks sets the URLs notified of every experiment's state changes,
+// on top of any set for individual experiments.
+func ServeWithWebhooks(urls []string) ServerOption {
+	return func(o *serverOptions) {
+		o.webhookURLs = urls
+	}
+}
+
+// ServeWithWebhookSecret sets the secret used to sign webhook notifications.
+// Webhooks are disabled if no secret is set.
+func ServeWithWebhookSecret(s string) ServerOption {
+	return func(o *serverOptions) {
+		o.webhookSecret = s
+	}
+}
+
+// ServeWithWebhookAllowedHosts sets the hosts (with or without a port) that
+// webhooks set for individual experiments can notify, on top of the hosts of
+// the global webhooks.
+func ServeWithWebhookAllowedHosts(hosts []string) ServerOption {
+	return func(o *serverOptions) {
+		o.webhookAllowedHosts = hosts
+	}
+}
+
+// ServeWithDiskWarnThreshold sets the percent of a host's disk that can be used
+// before a warning is broadcast for experiments running on it. Zero disables
+// it.
+func ServeWithDiskWarnThreshold(t float64) ServerOption {
+	return func(o *serverOptions) {
+		o.diskWarnThreshold = t
+	}
+}
+
+// ServeWithBroadcastCoalesceWindow sets how long updates broadcast to clients
+// for a resource are held so any later updates to it replace them. Zero
+// disables it.
+func ServeWithBroadcastCoalesceWindow(w time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.broadcastCoalesceWindow = w
+	}
+}
+
+// ServeWithStartQueueWorkers sets how many queued experiment starts are
+// launched at once. Zero launches one per cluster host.
+func ServeWithStartQueueWorkers(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.startQueueWorkers = n
+	}
+}
+
+// ServeWithBroadcastCompressionThreshold sets the size (in bytes) of results
+// broadcast to clients at or above which they're compressed for clients that
+// ask for compression. Zero disables it.
+func ServeWithBroadcastCompressionThreshold(n int) ServerOption {
+	return func(o *serverOptions) {
+		o.broadcastCompressionThreshold = n
+	}
+}
+
+// ServeWithVMExecVerbs sets the minimega commands (e.g. `vm qmp`) allowed to be
+// run against VMs. None disables running commands against VMs.
+func ServeWithVMExecVerbs(v []string) ServerOption {
+	return func(o *serverOptions) {
+		o.vmExecVerbs = v
+	}
+}
+
+// ServeWithVLANPool sets the range of VLAN IDs, of the form `<min>-<max>`,
+// allocated to the VLAN aliases of experiments as they're started so
+// concurrently running experiments don't collide. Empty disables it.
+func ServeWithVLANPool(r string) ServerOption {
+	return func(o *serverOptions) {
+		o.vlanPool = r
+	}
+}
+
 // GET /options
 func GetOptions(w http.ResponseWriter, r *http.Request) error {
 	plog.Debug("HTTP handler called", "handler", "GetOptions")
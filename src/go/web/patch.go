@@ -0,0 +1,81 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/types"
+	"phenix/util/jsonpatch"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// isJSONPatch returns true if the given experiment update request is a JSON
+// Patch document rather than the legacy map of VLAN aliases to IDs, either
+// because of its content type or because the body is a JSON array.
+func isJSONPatch(r *http.Request, body []byte) bool {
+	if mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mt == "application/json-patch+json" {
+		return true
+	}
+
+	return bytes.HasPrefix(bytes.TrimSpace(body), []byte("["))
+}
+
+// patchExperiment applies the given JSON Patch document to the spec of the
+// experiment with the given name and writes the patched config to the response.
+func patchExperiment(w http.ResponseWriter, name string, patch []byte) error {
+	if err := cache.LockExperimentForUpdate(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", name)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	cfg, err := experiment.Patch(name, patch)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to patch experiment %s", name)
+
+		switch {
+		case errors.Is(err, experiment.ErrImmutableField):
+			return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentRunning)
+		case errors.Is(err, jsonpatch.ErrTestFailed):
+			return err.SetStatus(http.StatusConflict)
+		case errors.Is(err, jsonpatch.ErrInvalidPatch), errors.Is(err, types.ErrValidationFailed):
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if exp, err := experiment.Get(name); err == nil {
+		vms, _ := vm.List(name)
+
+		if body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms)); err == nil {
+			broker.Broadcast(
+				bt.NewRequestPolicy("experiments", "get", name),
+				bt.NewResource("experiment", name, "update"),
+				body,
+			)
+		}
+	}
+
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process config for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
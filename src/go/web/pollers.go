@@ -0,0 +1,75 @@
+package web
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+)
+
+// experimentPollers tracks goroutines periodically polling running experiments
+// for one kind of stream (e.g. network stats), keyed by experiment name.
+type experimentPollers struct {
+	// Name of the kind of stream, used when logging.
+	kind string
+
+	mu      sync.Mutex
+	pollers map[string]context.Context
+}
+
+func newExperimentPollers(kind string) *experimentPollers {
+	return &experimentPollers{kind: kind, pollers: make(map[string]context.Context)}
+}
+
+// start calls poll every interval for the given experiment until it's no
+// longer running. It's a no-op if the experiment is already being polled. The
+// poller is canceled along with the experiment's other cancelers when it's
+// stopped.
+func (this *experimentPollers) start(exp string, interval time.Duration, poll func()) {
+	this.mu.Lock()
+	defer this.mu.Unlock()
+
+	// A canceled poller may not have exited yet (e.g. when an experiment is
+	// paused and quickly resumed), but shouldn't keep a new one from starting.
+	if ctx, ok := this.pollers[exp]; ok && ctx.Err() == nil {
+		return
+	}
+
+	// We don't want to use the HTTP request's context here.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	this.pollers[exp] = ctx
+	addCanceler(exp, cancel)
+
+	go func() {
+		defer func() {
+			this.mu.Lock()
+			if this.pollers[exp] == ctx {
+				delete(this.pollers, exp)
+			}
+			this.mu.Unlock()
+
+			cancel()
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			if !experiment.Running(exp) {
+				plog.Info("experiment no longer running, ending stream", "exp", exp, "stream", this.kind)
+				return
+			}
+
+			poll()
+		}
+	}()
+}
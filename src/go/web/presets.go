@@ -0,0 +1,194 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// presetError converts the given start preset error into a web error with the
+// appropriate status.
+func presetError(err error, format string, args ...any) *weberror.WebError {
+	werr := weberror.NewWebError(err, format, args...)
+
+	switch {
+	case errors.Is(err, experiment.ErrPresetNotFound):
+		return werr.SetStatus(http.StatusNotFound)
+	case errors.Is(err, experiment.ErrPresetExists):
+		return werr.SetStatus(http.StatusConflict)
+	case errors.Is(err, experiment.ErrInvalidPreset):
+		return werr.SetStatus(http.StatusBadRequest)
+	}
+
+	return werr.SetStatus(http.StatusInternalServerError)
+}
+
+// GET /start-presets
+func GetStartPresets(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetStartPresets")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("start-presets", "list") {
+		err := weberror.NewWebError(nil, "listing start presets not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	presets, err := experiment.ListPresets()
+	if err != nil {
+		return presetError(err, "unable to list start presets")
+	}
+
+	allowed := []experiment.StartPreset{}
+
+	for _, preset := range presets {
+		if role.Allowed("start-presets", "list", preset.Name) {
+			allowed = append(allowed, preset)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("presets", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process start presets")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /start-presets
+func CreateStartPreset(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CreateStartPreset")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	var preset experiment.StartPreset
+
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		err := weberror.NewWebError(err, "unable to parse start preset")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if !role.Allowed("start-presets", "create", preset.Name) {
+		err := weberror.NewWebError(nil, "creating start preset %s not allowed for %s", preset.Name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := experiment.CreatePreset(preset); err != nil {
+		return presetError(err, "unable to create start preset %s", preset.Name)
+	}
+
+	body, _ := json.Marshal(preset)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
+
+// GET /start-presets/{name}
+func GetStartPreset(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetStartPreset")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("start-presets", "get", name) {
+		err := weberror.NewWebError(nil, "getting start preset %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	preset, err := experiment.GetPreset(name)
+	if err != nil {
+		return presetError(err, "unable to get start preset %s", name)
+	}
+
+	body, _ := json.Marshal(preset)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PUT /start-presets/{name}
+func UpdateStartPreset(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateStartPreset")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("start-presets", "update", name) {
+		err := weberror.NewWebError(nil, "updating start preset %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var preset experiment.StartPreset
+
+	if err := json.NewDecoder(r.Body).Decode(&preset); err != nil {
+		err := weberror.NewWebError(err, "unable to parse start preset")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	// The preset being updated is identified by the path, not the body.
+	preset.Name = name
+
+	if err := experiment.UpdatePreset(preset); err != nil {
+		return presetError(err, "unable to update start preset %s", name)
+	}
+
+	body, _ := json.Marshal(preset)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// DELETE /start-presets/{name}
+func DeleteStartPreset(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DeleteStartPreset")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("start-presets", "delete", name) {
+		err := weberror.NewWebError(nil, "deleting start preset %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := experiment.DeletePreset(name); err != nil {
+		return presetError(err, "unable to delete start preset %s", name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -24,28 +24,70 @@ var Permissions = []Permission{
 	{"experiments", "list"},
 	{"experiments", "patch"},
 	{"experiments", "update"},
+	{"experiments/access", "get"},
+	{"experiments/access", "override"},
+	{"experiments/access", "patch"},
 	{"experiments/apps", "get"},
 	{"experiments/captures", "list"},
+	{"experiments/disks", "get"},
+	{"experiments/events", "get"},
+	{"experiments/export", "get"},
 	{"experiments/files", "get"},
 	{"experiments/files", "list"},
+	{"experiments/forensic-capture", "create"},
+	{"experiments/impairment", "delete"},
+	{"experiments/impairment", "update"},
+	{"experiments/isolation", "get"},
+	{"experiments/loglevel", "patch"},
+	{"experiments/logs", "get"},
 	{"experiments/netflow", "create"},
 	{"experiments/netflow", "delete"},
 	{"experiments/netflow", "get"},
+	{"experiments/pause", "update"},
+	{"experiments/reconcile", "get"},
+	{"experiments/reconcile", "update"},
+	{"experiments/restart", "update"},
+	{"experiments/resume", "update"},
 	{"experiments/schedule", "create"},
 	{"experiments/schedule", "get"},
+	{"experiments/snapshots", "create"},
+	{"experiments/snapshots", "list"},
+	{"experiments/snapshots", "update"},
+	{"experiments/start", "delete"},
 	{"experiments/start", "update"},
+	{"experiments/stats", "get"},
 	{"experiments/stop", "update"},
 	{"experiments/topology", "get"},
+	{"experiments/trace", "get"},
 	{"experiments/trigger", "create"},
 	{"experiments/trigger", "delete"},
+	{"experiments/unlock", "update"},
+	{"experiments/usage", "get"},
 	{"history", "get"},
 	{"hosts", "list"},
+	{"maintenance", "update"},
 	{"miniconsole", "get"},
 	{"miniconsole", "post"},
 	{"options", "list"},
+	{"queue", "list"},
 	{"roles", "list"},
 	{"scenarios", "list"},
+	{"scheduled-starts", "create"},
+	{"scheduled-starts", "delete"},
+	{"scheduled-starts", "get"},
+	{"scheduled-starts", "list"},
+	{"scheduled-starts", "update"},
 	{"schemas", "get"},
+	{"start-presets", "create"},
+	{"start-presets", "delete"},
+	{"start-presets", "get"},
+	{"start-presets", "list"},
+	{"start-presets", "update"},
+	{"templates", "create"},
+	{"templates", "delete"},
+	{"templates", "get"},
+	{"templates", "list"},
+	{"templates", "update"},
 	{"topologies", "list"},
 	{"users", "create"},
 	{"users", "delete"},
@@ -53,6 +95,7 @@ var Permissions = []Permission{
 	{"users", "list"},
 	{"users", "patch"},
 	{"users/roles", "patch"},
+	{"vlans", "list"},
 	{"vms", "delete"},
 	{"vms", "get"},
 	{"vms", "list"},
@@ -63,19 +106,26 @@ var Permissions = []Permission{
 	{"vms/cdrom", "delete"},
 	{"vms/cdrom", "update"},
 	{"vms/commit", "create"},
+	{"vms/console", "get"},
+	{"vms/exec", "create"},
 	{"vms/forwards", "create"},
 	{"vms/forwards", "delete"},
 	{"vms/forwards", "get"},
 	{"vms/forwards", "list"},
+	{"vms/interfaces", "update"},
 	{"vms/memorySnapshot", "create"},
+	{"vms/migrate", "update"},
 	{"vms/mount", "delete"},
 	{"vms/mount", "get"},
 	{"vms/mount", "list"},
 	{"vms/mount", "patch"},
 	{"vms/mount", "post"},
+	{"vms/note", "patch"},
+	{"vms/power", "update"},
 	{"vms/redeploy", "update"},
 	{"vms/reset", "update"},
 	{"vms/restart", "update"},
+	{"vms/retry", "update"},
 	{"vms/screenshot", "get"},
 	{"vms/shutdown", "update"},
 	{"vms/snapshots", "create"},
@@ -83,6 +133,8 @@ var Permissions = []Permission{
 	{"vms/snapshots", "update"},
 	{"vms/start", "update"},
 	{"vms/stop", "update"},
+	{"vms/tags", "delete"},
+	{"vms/tags", "patch"},
 	{"vms/vnc", "get"},
 	{"workflow", "create"},
 }
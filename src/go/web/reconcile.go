@@ -0,0 +1,117 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/reconcile
+//
+// Compares the experiment's VMs with those minimega actually has, reporting
+// orphaned VMs (running but not tracked by phenix) and missing VMs (tracked but
+// gone). Nothing is changed; see FixExperimentReconcile.
+func ReconcileExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ReconcileExperiment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/reconcile", "get", name) {
+		err := weberror.NewWebError(nil, "reconciling experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	report, err := vm.Reconcile(name, false)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to reconcile experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, _ := json.Marshal(report)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{exp}/reconcile
+//
+// Reconciles the experiment like ReconcileExperiment, then kills the orphaned
+// VMs and marks the missing VMs as errored. The experiment is locked while
+// it's fixed, so it's refused while the experiment is starting, stopping or
+// otherwise being updated.
+func FixExperimentReconcile(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "FixExperimentReconcile")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		vars = mux.Vars(r)
+		name = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/reconcile", "update", name) {
+		err := weberror.NewWebError(nil, "fixing experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if err := cache.LockExperimentForUpdate(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", name)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(name)
+
+	report, err := vm.Reconcile(name, true)
+	if report == nil {
+		err := weberror.NewWebError(err, "unable to reconcile experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	// Some of the fixes may have failed, but the report is still returned.
+	plog.Info("reconciled experiment", "exp", name, "user", user, "killed", report.Killed, "marked", report.Marked, "err", err)
+
+	recordExperimentEvent(name, user, "reconcile", err)
+
+	body, _ := json.Marshal(report)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/reconcile", "get", name),
+		bt.NewResource("experiment", name, "reconciled"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
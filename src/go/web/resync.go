@@ -0,0 +1,115 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/resync?since=N
+//
+// Returns the current state of the experiment and all its VMs (including those
+// not being booted), along with the broker sequence number it's current as of,
+// for clients that detected a gap in the experiment's sequence numbers after
+// the given one. See the broker types for the client contract.
+func ResyncExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ResyncExperiment")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		name  = mux.Vars(r)["name"]
+		since uint64
+	)
+
+	if !role.Allowed("experiments", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+
+		if since, err = strconv.ParseUint(v, 10, 64); err != nil {
+			err := weberror.NewWebError(err, "invalid sequence number %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	// Get the sequence number before the state so any change published while
+	// getting the state has a higher one. Clients may then apply a change twice,
+	// but never miss one.
+	seq := broker.Sequence(name)
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s from store", name)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VMs for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	status := cache.IsExperimentLocked(name)
+
+	if status == "" && exp.Status.Paused() {
+		status = cache.StatusPaused
+	}
+
+	allowed := mm.VMs{}
+
+	for _, vm := range vms {
+		if role.Allowed("vms", "list", fmt.Sprintf("%s/%s", name, vm.Name)) {
+			allowed = append(allowed, vm)
+		}
+	}
+
+	pb := util.ExperimentToProtobuf(*exp, status, allowed)
+	pb.VmCount = uint32(len(allowed))
+
+	state, err := marshaler.Marshal(pb)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	resync := map[string]any{
+		"seq":        seq,
+		"experiment": json.RawMessage(state),
+	}
+
+	// A client ahead of the broker means the server was restarted since the
+	// client last saw a sequence number, so there's no telling what it missed.
+	if since <= seq {
+		resync["missed"] = seq - since
+	} else {
+		resync["reset"] = true
+	}
+
+	body, err := json.Marshal(resync)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,430 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	// User recorded in experiment events for scheduled starts.
+	schedulerUser = "scheduler"
+
+	// How often scheduled starts are checked to see if they're due.
+	scheduledStartsInterval = 30 * time.Second
+)
+
+var (
+	// Experiments currently being started by the scheduler.
+	scheduledStartsInProgress   = make(map[string]struct{})
+	scheduledStartsInProgressMu sync.Mutex
+)
+
+// scheduledStart is a scheduled start along with when it next occurs, if ever.
+type scheduledStart struct {
+	experiment.ScheduledStart
+
+	Next string `json:"next,omitempty"`
+}
+
+func newScheduledStart(start experiment.ScheduledStart) scheduledStart {
+	s := scheduledStart{ScheduledStart: start}
+
+	if next := start.Next(); !next.IsZero() {
+		s.Next = next.Format(time.RFC3339)
+	}
+
+	return s
+}
+
+// scheduledStartError converts the given scheduled start error into a web
+// error with the appropriate status.
+func scheduledStartError(err error, format string, args ...any) *weberror.WebError {
+	werr := weberror.NewWebError(err, format, args...)
+
+	switch {
+	case errors.Is(err, experiment.ErrScheduledStartNotFound):
+		return werr.SetStatus(http.StatusNotFound)
+	case errors.Is(err, experiment.ErrScheduledStartExists):
+		return werr.SetStatus(http.StatusConflict)
+	case errors.Is(err, experiment.ErrInvalidScheduledStart):
+		return werr.SetStatus(http.StatusBadRequest)
+	}
+
+	return werr.SetStatus(http.StatusInternalServerError)
+}
+
+// GET /scheduled-starts
+func GetScheduledStarts(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetScheduledStarts")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+	)
+
+	if !role.Allowed("scheduled-starts", "list") {
+		err := weberror.NewWebError(nil, "listing scheduled starts not allowed for %s", user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	starts, err := experiment.ListScheduledStarts()
+	if err != nil {
+		return scheduledStartError(err, "unable to list scheduled starts")
+	}
+
+	allowed := []scheduledStart{}
+
+	for _, start := range starts {
+		if !role.Allowed("scheduled-starts", "list", start.Experiment) {
+			continue
+		}
+
+		// Only list scheduled starts for experiments the user can view.
+		if authorizeExperiment(ctx, start.Experiment, user, experiment.PermissionViewer) != nil {
+			continue
+		}
+
+		allowed = append(allowed, newScheduledStart(start))
+	}
+
+	body, err := json.Marshal(util.WithRoot("starts", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process scheduled starts")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// GET /schedules
+//
+// Lists the upcoming scheduled starts, soonest first.
+func GetUpcomingSchedules(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetUpcomingSchedules")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+	)
+
+	if !role.Allowed("scheduled-starts", "list") {
+		err := weberror.NewWebError(nil, "listing scheduled starts not allowed for %s", user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	starts, err := experiment.ListScheduledStarts()
+	if err != nil {
+		return scheduledStartError(err, "unable to list scheduled starts")
+	}
+
+	upcoming := []scheduledStart{}
+
+	for _, start := range starts {
+		if !role.Allowed("scheduled-starts", "list", start.Experiment) {
+			continue
+		}
+
+		if authorizeExperiment(ctx, start.Experiment, user, experiment.PermissionViewer) != nil {
+			continue
+		}
+
+		if s := newScheduledStart(start); s.Next != "" {
+			upcoming = append(upcoming, s)
+		}
+	}
+
+	// RFC 3339 times in UTC sort lexically, but times may have any offset.
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].ScheduledStart.Next().Before(upcoming[j].ScheduledStart.Next())
+	})
+
+	body, err := json.Marshal(util.WithRoot("schedules", upcoming))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process scheduled starts")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /scheduled-starts
+func CreateScheduledStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CreateScheduledStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	var start experiment.ScheduledStart
+
+	if err := json.NewDecoder(r.Body).Decode(&start); err != nil {
+		err := weberror.NewWebError(err, "unable to parse scheduled start")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	// Scheduling a start is as good as starting the experiment.
+	if !role.Allowed("scheduled-starts", "create", start.Experiment) || !role.Allowed("experiments/start", "update", start.Experiment) {
+		err := weberror.NewWebError(nil, "scheduling start of experiment %s not allowed for %s", start.Experiment, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	// Scheduled starts run without the user's permissions, so they're checked
+	// now instead.
+	if err := authorizeExperimentAction(ctx, start.Experiment, ctx.Value("user").(string), "scheduling start of", experiment.PermissionEditor); err != nil {
+		return err
+	}
+
+	if err := experiment.CreateScheduledStart(start); err != nil {
+		return scheduledStartError(err, "unable to schedule start of experiment %s", start.Experiment)
+	}
+
+	saved, err := experiment.GetScheduledStart(start.Experiment)
+	if err != nil {
+		return scheduledStartError(err, "unable to get scheduled start for experiment %s", start.Experiment)
+	}
+
+	body, _ := json.Marshal(newScheduledStart(*saved))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
+
+// GET /scheduled-starts/{name}
+func GetScheduledStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetScheduledStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("scheduled-starts", "get", name) {
+		err := weberror.NewWebError(nil, "getting scheduled start for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, ctx.Value("user").(string), "getting scheduled start for", experiment.PermissionViewer); err != nil {
+		return err
+	}
+
+	start, err := experiment.GetScheduledStart(name)
+	if err != nil {
+		return scheduledStartError(err, "unable to get scheduled start for experiment %s", name)
+	}
+
+	body, _ := json.Marshal(newScheduledStart(*start))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PUT /scheduled-starts/{name}
+func UpdateScheduledStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateScheduledStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("scheduled-starts", "update", name) || !role.Allowed("experiments/start", "update", name) {
+		err := weberror.NewWebError(nil, "updating scheduled start for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, ctx.Value("user").(string), "updating scheduled start for", experiment.PermissionEditor); err != nil {
+		return err
+	}
+
+	var start experiment.ScheduledStart
+
+	if err := json.NewDecoder(r.Body).Decode(&start); err != nil {
+		err := weberror.NewWebError(err, "unable to parse scheduled start")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	// The scheduled start being updated is identified by the path, not the body.
+	start.Experiment = name
+
+	if err := experiment.UpdateScheduledStart(start); err != nil {
+		return scheduledStartError(err, "unable to update scheduled start for experiment %s", name)
+	}
+
+	saved, err := experiment.GetScheduledStart(name)
+	if err != nil {
+		return scheduledStartError(err, "unable to get scheduled start for experiment %s", name)
+	}
+
+	body, _ := json.Marshal(newScheduledStart(*saved))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// DELETE /scheduled-starts/{name}
+func DeleteScheduledStart(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DeleteScheduledStart")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("scheduled-starts", "delete", name) {
+		err := weberror.NewWebError(nil, "deleting scheduled start for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := authorizeExperimentAction(ctx, name, ctx.Value("user").(string), "deleting scheduled start for", experiment.PermissionEditor); err != nil {
+		return err
+	}
+
+	if err := experiment.DeleteScheduledStart(name); err != nil {
+		return scheduledStartError(err, "unable to delete scheduled start for experiment %s", name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+// runScheduledStarts periodically starts experiments whose scheduled starts are
+// due. It's meant to be run once, in its own goroutine, when the web server
+// starts.
+func runScheduledStarts() {
+	ticker := time.NewTicker(scheduledStartsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		checkScheduledStarts(time.Now())
+	}
+}
+
+// checkScheduledStarts starts, in the background, each experiment whose
+// scheduled start is due at the given time, and gives up on those whose window
+// has passed.
+func checkScheduledStarts(now time.Time) {
+	starts, err := experiment.ListScheduledStarts()
+	if err != nil {
+		plog.Error("listing scheduled starts", "err", err)
+		return
+	}
+
+	for _, start := range starts {
+		if _, due := start.Due(now); !due {
+			continue
+		}
+
+		name := start.Experiment
+
+		if start.Expired(now) {
+			plog.Warn("scheduled start window passed before experiment could be started", "exp", name)
+
+			recordExperimentEvent(name, schedulerUser, "scheduledStartExpired", nil)
+
+			status := start.Status
+			status.LastRun = now.Format(time.RFC3339)
+			status.Attempts = 0
+
+			if err := experiment.SetScheduledStartStatus(name, status); err != nil {
+				plog.Error("updating scheduled start status", "exp", name, "err", err)
+			}
+
+			continue
+		}
+
+		scheduledStartsInProgressMu.Lock()
+
+		if _, ok := scheduledStartsInProgress[name]; ok {
+			scheduledStartsInProgressMu.Unlock()
+			continue
+		}
+
+		scheduledStartsInProgress[name] = struct{}{}
+		scheduledStartsInProgressMu.Unlock()
+
+		go attemptScheduledStart(start, now)
+	}
+}
+
+// attemptScheduledStart starts the experiment for the given scheduled start,
+// recording each failure in the experiment's events. The occurrence is given up
+// on after MaxScheduledStartAttempts failures.
+func attemptScheduledStart(start experiment.ScheduledStart, now time.Time) {
+	name := start.Experiment
+
+	defer func() {
+		scheduledStartsInProgressMu.Lock()
+		delete(scheduledStartsInProgress, name)
+		scheduledStartsInProgressMu.Unlock()
+	}()
+
+	plog.Info("starting scheduled experiment", "exp", name, "attempt", start.Status.Attempts+1)
+
+	status := start.Status
+	status.LastAttempt = now.Format(time.RFC3339)
+
+	err := checkStartCooldown(name)
+	if err == nil {
+		// Experiments already running (e.g. from a previous occurrence) are left
+		// as is.
+		_, err = startExperiment(systemContext(), name, schedulerUser, experiment.StartWithIdempotent(true))
+	}
+
+	if err == nil {
+		status.LastRun = status.LastAttempt
+		status.Attempts = 0
+		status.LastError = ""
+	} else {
+		plog.Error("starting scheduled experiment", "exp", name, "err", err)
+
+		recordExperimentEvent(name, schedulerUser, "errorScheduledStart", err)
+
+		status.Attempts++
+		status.LastError = err.Error()
+
+		if status.Attempts >= experiment.MaxScheduledStartAttempts {
+			plog.Error("giving up on scheduled experiment start", "exp", name, "attempts", status.Attempts)
+
+			recordExperimentEvent(name, schedulerUser, "scheduledStartAbandoned", err)
+
+			status.LastRun = status.LastAttempt
+			status.Attempts = 0
+		}
+	}
+
+	if err := experiment.SetScheduledStartStatus(name, status); err != nil {
+		plog.Error("updating scheduled start status", "exp", name, "err", err)
+	}
+}
@@ -0,0 +1,116 @@
+package web
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/util"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+const (
+	// Maximum number of VM screenshot streams allowed at once across all
+	// experiments, to avoid overloading minimega.
+	maxScreenshotStreams = 16
+
+	defaultScreenshotStreamInterval = 5 * time.Second
+	minScreenshotStreamInterval     = 1 * time.Second
+)
+
+var (
+	errTooManyScreenshotStreams = errors.New("too many VM screenshot streams")
+
+	// Keyed by `<exp>/<vm>`.
+	screenshotStreams   = make(map[string]context.CancelFunc)
+	screenshotStreamsMu sync.Mutex
+)
+
+// startScreenshotStream starts periodically broadcasting screenshots of the
+// given VM. It's a no-op if a stream for the VM is already running. The stream
+// is canceled along with the experiment's other cancelers when it's stopped.
+func startScreenshotStream(exp, name, size string, interval time.Duration) error {
+	key := fmt.Sprintf("%s/%s", exp, name)
+
+	screenshotStreamsMu.Lock()
+	defer screenshotStreamsMu.Unlock()
+
+	if _, ok := screenshotStreams[key]; ok {
+		return nil
+	}
+
+	if len(screenshotStreams) >= maxScreenshotStreams {
+		return errTooManyScreenshotStreams
+	}
+
+	// We don't want to use the HTTP request's context here.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	screenshotStreams[key] = cancel
+	addCanceler(exp, cancel)
+
+	go func() {
+		defer func() {
+			screenshotStreamsMu.Lock()
+			delete(screenshotStreams, key)
+			screenshotStreamsMu.Unlock()
+
+			cancel()
+		}()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			screenshot, err := mm.GetVMScreenshot(mm.NS(exp), mm.VMName(name), mm.ScreenshotSize(size))
+			if err != nil {
+				if errors.Is(err, mm.ErrVMNotFound) {
+					plog.Info("VM no longer exists, ending screenshot stream", "exp", exp, "vm", name)
+					return
+				}
+
+				if !errors.Is(err, mm.ErrScreenshotNotFound) {
+					plog.Error("getting screenshot for screenshot stream", "exp", exp, "vm", name, "err", err)
+				}
+			} else {
+				encoded := "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+				body, _ := json.Marshal(util.WithRoot("screenshot", encoded))
+
+				broker.Broadcast(
+					bt.NewRequestPolicy("vms/screenshot", "get", key),
+					bt.NewResource("experiment/vm/screenshot", key, "stream"),
+					body,
+				)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return nil
+}
+
+// stopScreenshotStream stops the screenshot stream for the given VM, if any.
+func stopScreenshotStream(exp, name string) {
+	key := fmt.Sprintf("%s/%s", exp, name)
+
+	screenshotStreamsMu.Lock()
+	cancel, ok := screenshotStreams[key]
+	screenshotStreamsMu.Unlock()
+
+	if ok {
+		cancel()
+	}
+}
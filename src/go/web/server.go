@@ -2,13 +2,17 @@ package web
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"strings"
 
+	"phenix/api/vlan"
 	"phenix/util/common"
 	"phenix/util/plog"
+	"phenix/util/sigterm"
 	"phenix/web/broker"
 	"phenix/web/forward"
 	"phenix/web/middleware"
@@ -80,6 +84,23 @@ func Start(opts ...ServerOption) error {
 
 	ConfigureUsers(o.users)
 
+	if o.vlanPool != "" {
+		min, max, err := vlan.ParsePoolRange(o.vlanPool)
+		if err != nil {
+			return fmt.Errorf("parsing VLAN pool: %w", err)
+		}
+
+		if err := vlan.ConfigurePool(min, max); err != nil {
+			return fmt.Errorf("configuring VLAN pool: %w", err)
+		}
+
+		plog.Info("allocating experiment VLANs from shared pool", "min", min, "max", max)
+	}
+
+	if err := loadMaintenance(); err != nil {
+		plog.Error("loading maintenance mode status", "err", err)
+	}
+
 	var (
 		router = mux.NewRouter().StrictSlash(true)
 		assets http.FileSystem
@@ -140,8 +161,18 @@ func Start(opts ...ServerOption) error {
 	api := router.PathPrefix("/api/v1").Subrouter()
 
 	// OPTIONS method needed for CORS
+	api.HandleFunc("/health", GetHealth).Methods("GET", "OPTIONS")
 	api.Handle("/builder/topologies", weberror.ErrorHandler(GetBuilderTopologies)).Methods("GET", "OPTIONS")
 	api.Handle("/builder/topologies/{name}", weberror.ErrorHandler(GetBuilderTopology)).Methods("GET", "OPTIONS")
+	if o.hookSecret != "" {
+		plog.Info("experiment start hook is enabled")
+		api.Handle("/hooks/start", weberror.ErrorHandler(StartExperimentHook)).Methods("POST", "OPTIONS")
+	}
+
+	if len(o.webhookURLs) > 0 && o.webhookSecret == "" {
+		plog.Warn("webhooks are disabled since no webhook secret is set")
+	}
+
 	api.Handle("/configs", weberror.ErrorHandler(GetConfigs)).Methods("GET", "OPTIONS")
 	api.Handle("/configs", weberror.ErrorHandler(CreateConfig)).Methods("POST", "OPTIONS")
 	api.Handle("/configs/{kind}/{name}", weberror.ErrorHandler(GetConfig)).Methods("GET", "OPTIONS")
@@ -153,24 +184,54 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments", GetExperiments).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments", CreateExperiment).Methods("POST", "OPTIONS")
 	api.Handle("/experiments/builder", weberror.ErrorHandler(CreateExperimentFromBuilder)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/start", weberror.ErrorHandler(StartExperiments)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/import", weberror.ErrorHandler(ImportExperiment)).Methods("POST", "OPTIONS")
 	api.Handle("/experiments/builder", weberror.ErrorHandler(UpdateExperimentFromBuilder)).Methods("PUT", "OPTIONS")
 	api.Handle("/experiments/{name}", weberror.ErrorHandler(GetExperiment)).Methods("GET", "OPTIONS")
 	api.Handle("/experiments/{name}", weberror.ErrorHandler(UpdateExperiment)).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/experiments/{name}", DeleteExperiment).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{name}/tags", weberror.ErrorHandler(UpdateExperimentTags)).Methods("PATCH", "OPTIONS")
+	api.Handle("/experiments/{name}/annotations", weberror.ErrorHandler(UpdateExperimentAnnotations)).Methods("PATCH", "OPTIONS")
+	api.Handle("/experiments/{name}/clone", weberror.ErrorHandler(CloneExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/diff", weberror.ErrorHandler(DiffExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/resync", weberror.ErrorHandler(ResyncExperiment)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/export", weberror.ErrorHandler(ExportExperiment)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/access", weberror.ErrorHandler(GetExperimentAccess)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/access", weberror.ErrorHandler(UpdateExperimentAccess)).Methods("PATCH", "OPTIONS")
+	api.Handle("/experiments/{name}/logLevel", weberror.ErrorHandler(UpdateExperimentLogLevel)).Methods("PATCH", "OPTIONS")
 	api.Handle("/experiments/{name}/apps", weberror.ErrorHandler(GetExperimentApps)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/apps/{app}/trigger", weberror.ErrorHandler(TriggerExperimentApp)).Methods("POST", "OPTIONS")
 	api.Handle("/experiments/{name}/start", weberror.ErrorHandler(StartExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/start", weberror.ErrorHandler(CancelExperimentStart)).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{name}/trace", weberror.ErrorHandler(GetExperimentTrace)).Methods("GET", "OPTIONS")
 	api.Handle("/experiments/{name}/stop", weberror.ErrorHandler(StopExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/status", weberror.ErrorHandler(GetExperimentStatus)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/events", weberror.ErrorHandler(GetExperimentEvents)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/progress", GetExperimentProgress).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/startlog", GetExperimentStartLog).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{name}/restart", weberror.ErrorHandler(RestartExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/pause", weberror.ErrorHandler(PauseExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{name}/resume", weberror.ErrorHandler(ResumeExperiment)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow", GetNetflow).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow", StartNetflow).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow", StopNetflow).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{exp}/vlans/{vlan}/impairment", weberror.ErrorHandler(SetVLANImpairment)).Methods("PUT", "OPTIONS")
+	api.Handle("/experiments/{exp}/vlans/{vlan}/impairment", weberror.ErrorHandler(ClearVLANImpairment)).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{exp}/snapshots", weberror.ErrorHandler(GetExperimentSnapshots)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/snapshots", weberror.ErrorHandler(SnapshotExperiment)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/snapshots/{label}/restore", weberror.ErrorHandler(RestoreExperimentSnapshot)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/forensic-capture", weberror.ErrorHandler(ForensicCaptureExperiment)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/netflow/ws", GetNetflowWebSocket).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/topology", GetExperimentTopology).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/topology.{format:dot|svg}", GetExperimentTopologyDiagram).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/topology/search", SearchExperimentTopology).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/trigger", TriggerExperimentApps).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/trigger", CancelTriggeredExperimentApps).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/schedule", GetExperimentSchedule).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/schedule", ScheduleExperiment).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/captures", GetExperimentCaptures).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/captures", weberror.ErrorHandler(StartExperimentCapture)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/captures", weberror.ErrorHandler(StopExperimentCaptures)).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/captureSubnet", StartCaptureSubnet).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/stopCaptureSubnet", StopCaptureSubnet).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/files", GetExperimentFiles).Methods("GET", "OPTIONS")
@@ -186,22 +247,42 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}/exit/{id}", scorch.ExitTerminal).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/scorch/terminals/{pid}/ws/{id}", scorch.StreamTerminal).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/soh", GetExperimentSoH).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/stats/network", weberror.ErrorHandler(GetNetworkStats)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/usage", weberror.ErrorHandler(GetExperimentUsage)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/disks", weberror.ErrorHandler(GetExperimentDisks)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/reconcile", weberror.ErrorHandler(ReconcileExperiment)).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/reconcile", weberror.ErrorHandler(FixExperimentReconcile)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/isolation", weberror.ErrorHandler(VerifyExperimentIsolation)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms", GetVMs).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms", UpdateVMs).Methods("PATCH", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/group", weberror.ErrorHandler(VMGroupAction)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/search", weberror.ErrorHandler(SearchVMs)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", GetVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", UpdateVM).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", DeleteVM).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/reset", ResetVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/restart", RestartVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/start", StartVM).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/retry", weberror.ErrorHandler(RetryDelayedVM)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/stop", StopVM).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/power", weberror.ErrorHandler(PowerVM)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/exec", weberror.ErrorHandler(ExecVM)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/shutdown", ShutdownVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/redeploy", RedeployVM).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/migrate", weberror.ErrorHandler(MigrateVM)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/tags", weberror.ErrorHandler(UpdateVMTags)).Methods("PATCH", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/tags/{tag}", weberror.ErrorHandler(DeleteVMTag)).Methods("DELETE", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/interfaces/{iface}/connect", weberror.ErrorHandler(ConnectVMInterface)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/interfaces/{iface}/disconnect", weberror.ErrorHandler(DisconnectVMInterface)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/note", weberror.ErrorHandler(UpdateVMNote)).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/cdrom", ChangeOpticalDisc).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/cdrom", EjectOpticalDisc).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/screenshot.png", GetScreenshot).Methods("GET", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/screenshot/subscribe", weberror.ErrorHandler(SubscribeScreenshots)).Methods("POST", "OPTIONS")
+	api.Handle("/experiments/{exp}/vms/{name}/screenshot/subscribe", weberror.ErrorHandler(UnsubscribeScreenshots)).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/vnc", GetVNC).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/vnc/ws", GetVNCWebSocket).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/console", GetVMConsole).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", GetVMCaptures).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", StartVMCapture).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", StopVMCaptures).Methods("DELETE", "OPTIONS")
@@ -230,6 +311,9 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/topologies/{topo}/scenarios", GetScenarios).Methods("GET", "OPTIONS")
 	api.HandleFunc("/disks", GetDisks).Methods("GET", "OPTIONS")
 	api.HandleFunc("/hosts", GetClusterHosts).Methods("GET", "OPTIONS")
+	api.Handle("/cluster/capacity", weberror.ErrorHandler(GetClusterCapacity)).Methods("GET", "OPTIONS")
+	api.Handle("/cluster/vlans", weberror.ErrorHandler(GetClusterVLANs)).Methods("GET", "OPTIONS")
+	api.Handle("/queue", weberror.ErrorHandler(GetStartQueue)).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users", GetUsers).Methods("GET", "OPTIONS")
 	api.HandleFunc("/users", CreateUser).Methods("POST", "OPTIONS")
 	api.HandleFunc("/users/{username}", GetUser).Methods("GET", "OPTIONS")
@@ -241,6 +325,26 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/login", Login).Methods("GET", "POST", "OPTIONS")
 	api.HandleFunc("/logout", Logout).Methods("GET", "OPTIONS")
 	api.Handle("/history", weberror.ErrorHandler(GetHistory)).Methods("POST", "OPTIONS")
+	api.Handle("/start-presets", weberror.ErrorHandler(GetStartPresets)).Methods("GET", "OPTIONS")
+	api.Handle("/start-presets", weberror.ErrorHandler(CreateStartPreset)).Methods("POST", "OPTIONS")
+	api.Handle("/start-presets/{name}", weberror.ErrorHandler(GetStartPreset)).Methods("GET", "OPTIONS")
+	api.Handle("/start-presets/{name}", weberror.ErrorHandler(UpdateStartPreset)).Methods("PUT", "OPTIONS")
+	api.Handle("/start-presets/{name}", weberror.ErrorHandler(DeleteStartPreset)).Methods("DELETE", "OPTIONS")
+	api.Handle("/templates", weberror.ErrorHandler(GetTemplates)).Methods("GET", "OPTIONS")
+	api.Handle("/templates", weberror.ErrorHandler(CreateTemplate)).Methods("POST", "OPTIONS")
+	api.Handle("/templates/{name}", weberror.ErrorHandler(GetTemplate)).Methods("GET", "OPTIONS")
+	api.Handle("/templates/{name}", weberror.ErrorHandler(UpdateTemplate)).Methods("PUT", "OPTIONS")
+	api.Handle("/templates/{name}", weberror.ErrorHandler(DeleteTemplate)).Methods("DELETE", "OPTIONS")
+	api.Handle("/templates/{name}/instantiate", weberror.ErrorHandler(InstantiateTemplate)).Methods("POST", "OPTIONS")
+	api.Handle("/scheduled-starts", weberror.ErrorHandler(GetScheduledStarts)).Methods("GET", "OPTIONS")
+	api.Handle("/scheduled-starts", weberror.ErrorHandler(CreateScheduledStart)).Methods("POST", "OPTIONS")
+	api.Handle("/scheduled-starts/{name}", weberror.ErrorHandler(GetScheduledStart)).Methods("GET", "OPTIONS")
+	api.Handle("/scheduled-starts/{name}", weberror.ErrorHandler(UpdateScheduledStart)).Methods("PUT", "OPTIONS")
+	api.Handle("/scheduled-starts/{name}", weberror.ErrorHandler(DeleteScheduledStart)).Methods("DELETE", "OPTIONS")
+	api.Handle("/schedules", weberror.ErrorHandler(GetUpcomingSchedules)).Methods("GET", "OPTIONS")
+	api.Handle("/admin/maintenance", weberror.ErrorHandler(GetMaintenance)).Methods("GET", "OPTIONS")
+	api.Handle("/admin/maintenance", weberror.ErrorHandler(SetMaintenance)).Methods("POST", "OPTIONS")
+	api.Handle("/admin/experiments/{name}/unlock", weberror.ErrorHandler(ForceUnlockExperiment)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/ws", broker.ServeWS).Methods("GET")
 	api.HandleFunc("/console", CreateConsole).Methods("POST", "OPTIONS")
 	api.HandleFunc("/console/{pid}/ws", WsConsole).Methods("GET", "OPTIONS")
@@ -281,8 +385,23 @@ func Start(opts ...ServerOption) error {
 
 	plog.Info("starting websockets broker")
 
+	broker.SetCoalesceWindow(o.broadcastCoalesceWindow)
+	broker.SetCompressionThreshold(o.broadcastCompressionThreshold)
+
 	go broker.Start()
 
+	plog.Info("reattaching periodic apps for running experiments")
+
+	go reattachPeriodicApps()
+
+	plog.Info("starting scheduled start checker")
+
+	go runScheduledStarts()
+
+	plog.Info("starting host disk warning checker")
+
+	go runDiskWarnings()
+
 	plog.Info("starting scorch processors")
 
 	go scorch.Start(o.basePath)
@@ -294,6 +413,8 @@ func Start(opts ...ServerOption) error {
 	plog.Info("using base path", "path", o.basePath)
 	plog.Info("using JWT lifetime", "lifetime", o.jwtLifetime)
 
+	var unixServer *http.Server
+
 	if common.UnixSocket != "" {
 		var (
 			router = mux.NewRouter().StrictSlash(true)
@@ -310,7 +431,8 @@ func Start(opts ...ServerOption) error {
 
 		plog.Info("starting Unix socket server", "path", common.UnixSocket)
 
-		server := http.Server{Handler: router}
+		unixServer = &http.Server{Handler: router}
+
 		listener, err := net.Listen("unix", common.UnixSocket)
 		if err != nil {
 			return err
@@ -327,19 +449,57 @@ func Start(opts ...ServerOption) error {
 		}
 
 		go func() {
-			if err := server.Serve(listener); err != nil {
+			if err := unixServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 				plog.Error("serving Unix socket", "err", err)
 			}
 		}()
 	}
 
+	var (
+		server   = &http.Server{Addr: o.endpoint, Handler: router}
+		shutdown = make(chan struct{})
+	)
+
+	// Drain experiments that are starting and stop serving requests when the
+	// process is terminated, rather than letting in-flight starts get killed
+	// abruptly.
+	go func() {
+		defer close(shutdown)
+
+		<-sigterm.CancelContext(context.Background()).Done()
+
+		plog.Info("shutting down server", "timeout", o.shutdownTimeout)
+
+		drainExperiments(o.shutdownTimeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), o.shutdownTimeout)
+		defer cancel()
+
+		if unixServer != nil {
+			unixServer.Shutdown(ctx)
+		}
+
+		if err := server.Shutdown(ctx); err != nil {
+			plog.Error("shutting down server", "err", err)
+		}
+	}()
+
+	var err error
+
 	if o.tlsEnabled() {
 		plog.Info("starting HTTPS server", "endpoint", o.endpoint)
-		return http.ListenAndServeTLS(o.endpoint, o.tlsCrtPath, o.tlsKeyPath, router)
+		err = server.ListenAndServeTLS(o.tlsCrtPath, o.tlsKeyPath)
 	} else {
 		plog.Info("Starting HTTP server", "endpoint", o.endpoint)
-		return http.ListenAndServe(o.endpoint, router)
+		err = server.ListenAndServe()
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		<-shutdown
+		return nil
 	}
+
+	return err
 }
 
 func addRoutesToRouter(router *mux.Router, routes ...route) {
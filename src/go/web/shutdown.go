@@ -0,0 +1,88 @@
+package web
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// DefaultShutdownTimeout is how long to wait for experiments that are starting
+// to exit when the server is shutting down, if one wasn't provided via
+// ServeWithShutdownTimeout.
+const DefaultShutdownTimeout = 30 * time.Second
+
+// drainExperiments cancels everything tracked in the cancelers map (experiment
+// starts, periodic apps, etc.) and waits up to the given timeout for all the
+// tracked wait groups to finish. Experiments that were in the middle of
+// starting are broadcast as interrupted so clients know monitoring of the start
+// stopped, rather than it silently going stale. The names of the interrupted
+// experiments are returned.
+func drainExperiments(timeout time.Duration) []string {
+	cancelersMu.Lock()
+
+	cancels := cancelers
+	wgs := waiters
+
+	cancelers = make(map[string][]context.CancelFunc)
+	waiters = make(map[string]*sync.WaitGroup)
+
+	cancelersMu.Unlock()
+
+	var interrupted []string
+
+	// Waiters are keyed by experiment name, so check which of them are currently
+	// locked for starting before they're canceled and unlocked.
+	for name := range wgs {
+		switch cache.IsExperimentLocked(name) {
+		case cache.StatusStarting, cache.StatusRestarting:
+			interrupted = append(interrupted, name)
+		}
+	}
+
+	sort.Strings(interrupted)
+
+	for _, cancels := range cancels {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for _, wg := range wgs {
+			wg.Wait()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		plog.Warn("timed out waiting for experiments to exit during shutdown", "timeout", timeout)
+	}
+
+	for _, name := range interrupted {
+		plog.Warn("experiment start interrupted by shutdown", "exp", name)
+
+		updateStartStatus(name, func(s *startStatus) { s.err = "start interrupted by server shutdown" })
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/start", "update", name),
+			bt.NewResource("experiment", name, "interrupted"),
+			nil,
+		)
+
+		recordExperimentEvent(name, "", "interrupted", nil)
+	}
+
+	return interrupted
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Make sure draining cancels everything being tracked and doesn't wait forever
+// on a Goroutine that's hung.
+func TestDrainExperimentsTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var running, hung sync.WaitGroup
+
+	running.Add(1)
+	hung.Add(1)
+	defer hung.Done()
+
+	addCanceler("test-drain-running", cancel)
+	setWaiter("test-drain-running", &running)
+	setWaiter("test-drain-hung", &hung)
+
+	go func() {
+		defer running.Done()
+		<-ctx.Done()
+	}()
+
+	start := time.Now()
+
+	if interrupted := drainExperiments(100 * time.Millisecond); len(interrupted) != 0 {
+		t.Errorf("expected no interrupted experiments, got %v", interrupted)
+	}
+
+	if ctx.Err() == nil {
+		t.Errorf("expected context to be canceled")
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected drain to time out, took %v", elapsed)
+	}
+
+	cancelersMu.Lock()
+	defer cancelersMu.Unlock()
+
+	if len(cancelers) != 0 || len(waiters) != 0 {
+		t.Errorf("expected no cancelers or waiters left, got %d and %d", len(cancelers), len(waiters))
+	}
+}
@@ -0,0 +1,132 @@
+package web
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"phenix/util/mm"
+)
+
+// Simulated round trip time for each command sent to minimega.
+const simulatedMinimegaLatency = 100 * time.Microsecond
+
+// fakeMinimega simulates the minimega commands issued by each of the MM methods
+// used to monitor an experiment's VMs, so getting the state needed by the
+// start progress loop, the status endpoint, and the usage endpoint one call at
+// a time can be compared with getting it from a single snapshot. The number of
+// commands for each method matches the Minimega implementation.
+type fakeMinimega struct {
+	mm.MM
+
+	vms, hosts int
+	commands   int
+}
+
+func (this *fakeMinimega) run(commands int) {
+	this.commands += commands
+	time.Sleep(time.Duration(commands) * simulatedMinimegaLatency)
+}
+
+func (this *fakeMinimega) states() map[string]string {
+	states := make(map[string]string, this.vms)
+
+	for i := 0; i < this.vms; i++ {
+		states[fmt.Sprintf("vm%d", i)] = "RUNNING"
+	}
+
+	return states
+}
+
+// `ns queue` and, once the queue is empty, `vm info`.
+func (this *fakeMinimega) GetLaunchProgress(string, int) (float64, error) {
+	this.run(2)
+	return 0, nil
+}
+
+// `ns queue` and `vm info`.
+func (this *fakeMinimega) GetPerVMLaunchState(string) (map[string]string, error) {
+	this.run(2)
+
+	states := this.states()
+
+	for name := range states {
+		states[name] = mm.LaunchStateRunning
+	}
+
+	return states, nil
+}
+
+// `vm info summary`.
+func (this *fakeMinimega) GetVMStates(...mm.Option) map[string]string {
+	this.run(1)
+	return this.states()
+}
+
+// `capture`.
+func (this *fakeMinimega) GetExperimentCaptures(...mm.Option) []mm.Capture {
+	this.run(1)
+	return nil
+}
+
+// `vm info` and reading the tap counters on each host.
+func (this *fakeMinimega) GetInterfaceStats(string) ([]mm.InterfaceStats, error) {
+	this.run(1 + this.hosts)
+	return make([]mm.InterfaceStats, this.vms), nil
+}
+
+// `ns queue`, `vm info`, `capture`, and reading the tap counters on each host
+// if interfaces are included.
+func (this *fakeMinimega) GetExperimentSnapshot(_ string, interfaces bool) (*mm.ExperimentSnapshot, error) {
+	snap := &mm.ExperimentSnapshot{
+		VMStates:     this.states(),
+		LaunchStates: make(map[string]string, this.vms),
+	}
+
+	if interfaces {
+		this.run(3 + this.hosts)
+		snap.Interfaces = make([]mm.InterfaceStats, this.vms)
+	} else {
+		this.run(3)
+	}
+
+	for name := range snap.VMStates {
+		snap.LaunchStates[name] = mm.LaunchStateRunning
+	}
+
+	return snap, nil
+}
+
+func benchmarkExperimentState(b *testing.B, get func(name string)) {
+	defer func(orig mm.MM) { mm.DefaultMM = orig }(mm.DefaultMM)
+
+	fake := &fakeMinimega{vms: 500, hosts: 10}
+	mm.DefaultMM = fake
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		get("bench")
+	}
+
+	b.ReportMetric(float64(fake.commands)/float64(b.N), "mm-cmds/op")
+}
+
+// Gets the state of a 500 VM experiment running on 10 hosts the way each
+// consumer did before snapshots.
+func BenchmarkExperimentStatePerCall(b *testing.B) {
+	benchmarkExperimentState(b, func(name string) {
+		mm.GetLaunchProgress(name, 500)
+		mm.GetPerVMLaunchState(name)
+		mm.GetVMStates(mm.NS(name))
+		mm.GetExperimentCaptures(mm.NS(name))
+		mm.GetInterfaceStats(name)
+	})
+}
+
+// Gets the same state from a single snapshot.
+func BenchmarkExperimentStateSnapshot(b *testing.B) {
+	benchmarkExperimentState(b, func(name string) {
+		mm.GetExperimentSnapshot(name, true)
+	})
+}
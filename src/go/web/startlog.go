@@ -0,0 +1,82 @@
+package web
+
+import "sync"
+
+// Maximum number of log lines buffered per experiment while it's starting.
+const startLogSize = 500
+
+// startLog is a fixed-size ring buffer of log lines generated while an
+// experiment is starting.
+type startLog struct {
+	lines []string
+	next  int
+	full  bool
+}
+
+func (this *startLog) add(line string) {
+	if len(this.lines) < startLogSize {
+		this.lines = append(this.lines, line)
+		return
+	}
+
+	this.lines[this.next] = line
+	this.next = (this.next + 1) % startLogSize
+	this.full = true
+}
+
+// ordered returns a copy of the buffered lines, oldest first.
+func (this *startLog) ordered() []string {
+	lines := make([]string, 0, len(this.lines))
+
+	if this.full {
+		lines = append(lines, this.lines[this.next:]...)
+		lines = append(lines, this.lines[:this.next]...)
+	} else {
+		lines = append(lines, this.lines...)
+	}
+
+	return lines
+}
+
+var (
+	startLogs   = make(map[string]*startLog)
+	startLogsMu sync.Mutex
+)
+
+// newStartLog creates an empty start log for the given experiment, replacing
+// any existing one.
+func newStartLog(name string) {
+	startLogsMu.Lock()
+	defer startLogsMu.Unlock()
+
+	startLogs[name] = new(startLog)
+}
+
+// appendStartLog adds the given line to the start log for the given
+// experiment. It's a no-op if the experiment isn't currently starting.
+func appendStartLog(name, line string) {
+	startLogsMu.Lock()
+	defer startLogsMu.Unlock()
+
+	if log, ok := startLogs[name]; ok {
+		log.add(line)
+	}
+}
+
+func getStartLog(name string) []string {
+	startLogsMu.Lock()
+	defer startLogsMu.Unlock()
+
+	if log, ok := startLogs[name]; ok {
+		return log.ordered()
+	}
+
+	return []string{}
+}
+
+func clearStartLog(name string) {
+	startLogsMu.Lock()
+	defer startLogsMu.Unlock()
+
+	delete(startLogs, name)
+}
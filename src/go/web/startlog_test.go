@@ -0,0 +1,52 @@
+package web
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestStartLogWrapsAround(t *testing.T) {
+	var log startLog
+
+	for i := 0; i < startLogSize+10; i++ {
+		log.add(fmt.Sprintf("line %d", i))
+	}
+
+	lines := log.ordered()
+
+	if len(lines) != startLogSize {
+		t.Fatalf("expected %d lines, got %d", startLogSize, len(lines))
+	}
+
+	if lines[0] != "line 10" {
+		t.Errorf("expected oldest line to be 'line 10', got '%s'", lines[0])
+	}
+
+	if last := lines[len(lines)-1]; last != fmt.Sprintf("line %d", startLogSize+9) {
+		t.Errorf("expected newest line to be 'line %d', got '%s'", startLogSize+9, last)
+	}
+}
+
+func TestStartLogOnlyBuffersWhileStarting(t *testing.T) {
+	name := "test-experiment"
+
+	appendStartLog(name, "ignored")
+
+	if lines := getStartLog(name); len(lines) != 0 {
+		t.Fatalf("expected no lines before start, got %d", len(lines))
+	}
+
+	newStartLog(name)
+	appendStartLog(name, "starting")
+
+	if lines := getStartLog(name); len(lines) != 1 || lines[0] != "starting" {
+		t.Fatalf("expected single 'starting' line, got %v", lines)
+	}
+
+	clearStartLog(name)
+	appendStartLog(name, "ignored")
+
+	if lines := getStartLog(name); len(lines) != 0 {
+		t.Fatalf("expected no lines after start, got %d", len(lines))
+	}
+}
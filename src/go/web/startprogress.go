@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"phenix/store"
+	"phenix/util/plog"
+	"phenix/web/util"
+)
+
+// How long progress persisted for an experiment start is used for if it isn't
+// updated again, at which point the start is assumed to have died along with the
+// server that was monitoring it.
+var startProgressTTL = 5 * time.Minute
+
+// saveStartProgress persists the given progress of the start of the given
+// experiment to the store, so it can still be reported if the server restarts
+// before the start finishes. Errors are only logged since they shouldn't fail
+// the start.
+func saveStartProgress(name string, sp util.StartProgress) {
+	c := startProgressConfig(name)
+
+	body, _ := json.Marshal(sp)
+
+	if err := json.Unmarshal(body, &c.Spec); err != nil {
+		plog.Warn("encoding experiment start progress", "exp", name, "err", err)
+		return
+	}
+
+	err := store.Update(c)
+	if errors.Is(err, store.ErrNotExist) {
+		err = store.Create(c)
+	}
+
+	if err != nil {
+		plog.Warn("saving experiment start progress", "exp", name, "err", err)
+	}
+}
+
+// loadStartProgress returns the progress persisted for the start of the given
+// experiment, if any has been saved within startProgressTTL.
+func loadStartProgress(name string) (util.StartProgress, bool) {
+	var (
+		c  = startProgressConfig(name)
+		sp util.StartProgress
+	)
+
+	if err := store.Get(c); err != nil {
+		if !errors.Is(err, store.ErrNotExist) {
+			plog.Warn("getting experiment start progress", "exp", name, "err", err)
+		}
+
+		return sp, false
+	}
+
+	// The store sets the updated timestamp each time the progress is saved.
+	if ts, err := time.Parse(time.RFC3339, c.Metadata.Updated); err != nil || time.Since(ts) > startProgressTTL {
+		return sp, false
+	}
+
+	body, _ := json.Marshal(c.Spec)
+
+	if err := json.Unmarshal(body, &sp); err != nil {
+		plog.Warn("decoding experiment start progress", "exp", name, "err", err)
+		return sp, false
+	}
+
+	return sp, true
+}
+
+// clearStartProgress deletes the progress persisted for the start of the given
+// experiment, if any.
+func clearStartProgress(name string) {
+	if err := store.Delete(startProgressConfig(name)); err != nil && !errors.Is(err, store.ErrNotExist) {
+		plog.Warn("deleting experiment start progress", "exp", name, "err", err)
+	}
+}
+
+func startProgressConfig(name string) *store.Config {
+	return &store.Config{
+		Version:  store.API_GROUP + "/v1",
+		Kind:     "StartProgress",
+		Metadata: store.ConfigMetadata{Name: name},
+	}
+}
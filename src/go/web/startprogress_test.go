@@ -0,0 +1,57 @@
+package web
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"phenix/store"
+	"phenix/web/util"
+)
+
+// Make sure start progress persisted before a server restart is reported until
+// it's cleared or goes stale.
+func TestStartProgressPersistence(t *testing.T) {
+	b := store.NewBoltDB()
+
+	if err := b.Init(store.Endpoint("bolt://" + filepath.Join(t.TempDir(), "phenix.bdb"))); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(orig store.Store, ttl time.Duration) {
+		store.DefaultStore = orig
+		startProgressTTL = ttl
+	}(store.DefaultStore, startProgressTTL)
+
+	store.DefaultStore = b
+
+	if _, ok := loadStartProgress("test-experiment"); ok {
+		t.Fatal("expected no start progress before any is saved")
+	}
+
+	saveStartProgress("test-experiment", util.NewStartProgress(0.5, 10, time.Time{}))
+	saveStartProgress("test-experiment", util.NewStartProgress(0.9, 10, time.Time{}))
+
+	sp, ok := loadStartProgress("test-experiment")
+	if !ok {
+		t.Fatal("expected saved start progress")
+	}
+
+	if sp.Percent != 0.9 || sp.Launched != 9 || sp.Stage != util.StartStageLaunching {
+		t.Fatalf("expected latest start progress, got %+v", sp)
+	}
+
+	startProgressTTL = -time.Minute
+
+	if _, ok := loadStartProgress("test-experiment"); ok {
+		t.Fatal("expected stale start progress to be ignored")
+	}
+
+	startProgressTTL = time.Minute
+
+	clearStartProgress("test-experiment")
+
+	if _, ok := loadStartProgress("test-experiment"); ok {
+		t.Fatal("expected start progress to be cleared")
+	}
+}
@@ -0,0 +1,251 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+)
+
+// Experiment starts waiting to be launched, and being launched, so concurrent
+// large starts don't compete for cluster resources.
+var starts = newStartQueue(startQueueWorkers)
+
+// queuedStart is an experiment start waiting in (or launched from) the start
+// queue.
+type queuedStart struct {
+	Experiment string    `json:"experiment"`
+	User       string    `json:"user"`
+	Priority   int       `json:"priority"`
+	Queued     time.Time `json:"queued"`
+
+	// Position in the queue, starting at 1. Zero for starts being launched.
+	Position int `json:"position,omitempty"`
+
+	seq   uint64
+	ready chan struct{}
+}
+
+type startQueue struct {
+	sync.Mutex
+
+	// Number of starts to launch at once.
+	workers func() int
+
+	pending []*queuedStart
+
+	// Starts being launched, by their sequence number, since the same
+	// experiment can be queued again as soon as its previous start finishes.
+	running map[uint64]*queuedStart
+
+	// Increases with each start queued, so starts with the same priority are
+	// launched in the order they were queued.
+	seq uint64
+}
+
+func newStartQueue(workers func() int) *startQueue {
+	return &startQueue{workers: workers, running: make(map[uint64]*queuedStart)}
+}
+
+// startQueueWorkers returns the number of experiment starts to launch at once,
+// which is the number of cluster hosts unless set when serving.
+func startQueueWorkers() int {
+	if o.startQueueWorkers > 0 {
+		return o.startQueueWorkers
+	}
+
+	cluster, _, err := mm.GetCachedClusterHosts(false)
+	if err != nil || len(cluster) == 0 {
+		return defaultStartConcurrency
+	}
+
+	return len(cluster)
+}
+
+// wait queues a start of the given experiment with the given priority and
+// blocks until it's launched from the queue, broadcasting its position in the
+// queue as it changes. The returned function must be called once the start
+// finishes to launch the next start in the queue. It returns the context's
+// cause if the context is done before the start is launched.
+func (this *startQueue) wait(ctx context.Context, name, user string, priority int) (func(), error) {
+	// Getting the number of workers can mean asking minimega for the cluster's
+	// hosts, so it's done before taking the lock.
+	workers := this.workers()
+
+	this.Lock()
+
+	this.seq++
+
+	start := &queuedStart{
+		Experiment: name,
+		User:       user,
+		Priority:   priority,
+		Queued:     time.Now().UTC(),
+		seq:        this.seq,
+		ready:      make(chan struct{}),
+	}
+
+	this.pending = append(this.pending, start)
+	this.dispatch(workers)
+
+	this.Unlock()
+
+	release := func() {
+		workers := this.workers()
+
+		this.Lock()
+		defer this.Unlock()
+
+		delete(this.running, start.seq)
+		this.dispatch(workers)
+	}
+
+	select {
+	case <-start.ready:
+		return release, nil
+	case <-ctx.Done():
+	}
+
+	this.Lock()
+
+	// The start may have been launched at the same time the context was done.
+	if _, ok := this.running[start.seq]; ok {
+		this.Unlock()
+		release()
+
+		return nil, context.Cause(ctx)
+	}
+
+	for i, s := range this.pending {
+		if s == start {
+			this.pending = append(this.pending[:i], this.pending[i+1:]...)
+			break
+		}
+	}
+
+	this.broadcastPositions()
+	this.Unlock()
+
+	return nil, context.Cause(ctx)
+}
+
+// dispatch launches the highest priority pending starts while fewer than the
+// given number of workers are busy. The caller must hold the lock.
+func (this *startQueue) dispatch(workers int) {
+	sort.SliceStable(this.pending, func(i, j int) bool {
+		if this.pending[i].Priority == this.pending[j].Priority {
+			return this.pending[i].seq < this.pending[j].seq
+		}
+
+		return this.pending[i].Priority > this.pending[j].Priority
+	})
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	for len(this.pending) > 0 && len(this.running) < workers {
+		start := this.pending[0]
+		this.pending = this.pending[1:]
+
+		start.Position = 0
+		this.running[start.seq] = start
+
+		close(start.ready)
+	}
+
+	this.broadcastPositions()
+}
+
+// broadcastPositions broadcasts the position of each pending start whose
+// position changed. The caller must hold the lock.
+func (this *startQueue) broadcastPositions() {
+	for i, start := range this.pending {
+		if start.Position == i+1 {
+			continue
+		}
+
+		start.Position = i + 1
+
+		plog.Debug("experiment start queued", "exp", start.Experiment, "position", start.Position, "priority", start.Priority)
+
+		body, _ := json.Marshal(map[string]any{"position": start.Position, "priority": start.Priority, "pending": len(this.pending)})
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/start", "update", start.Experiment),
+			bt.NewResource("experiment", start.Experiment, "queued"),
+			body,
+		)
+	}
+}
+
+// list returns copies of the starts being launched, and those pending in the
+// order they'll be launched.
+func (this *startQueue) list() (running, pending []queuedStart) {
+	this.Lock()
+	defer this.Unlock()
+
+	running = []queuedStart{}
+	pending = []queuedStart{}
+
+	for _, start := range this.running {
+		running = append(running, *start)
+	}
+
+	sort.Slice(running, func(i, j int) bool { return running[i].seq < running[j].seq })
+
+	for _, start := range this.pending {
+		pending = append(pending, *start)
+	}
+
+	return running, pending
+}
+
+// GET /queue
+//
+// Lists the experiment starts waiting in the start queue, in the order they'll
+// be launched, along with those currently being launched from it.
+func GetStartQueue(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetStartQueue")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("queue", "list") {
+		err := weberror.NewWebError(nil, "listing start queue not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	running, pending := starts.list()
+
+	allowed := func(starts []queuedStart) []queuedStart {
+		filtered := []queuedStart{}
+
+		for _, start := range starts {
+			if role.Allowed("experiments", "list", start.Experiment) {
+				filtered = append(filtered, start)
+			}
+		}
+
+		return filtered
+	}
+
+	body, _ := json.Marshal(map[string]any{"running": allowed(running), "pending": allowed(pending), "workers": starts.workers()})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
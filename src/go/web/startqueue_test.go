@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"phenix/store"
+	"phenix/web/weberror"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestStartQueuePriority(t *testing.T) {
+	queue := newStartQueue(func() int { return 1 })
+
+	release, err := queue.wait(context.Background(), "first", "user", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	launched := make(chan string, 2)
+
+	queueStart := func(name string, priority int) {
+		go func() {
+			release, err := queue.wait(context.Background(), name, "user", priority)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			launched <- name
+			release()
+		}()
+	}
+
+	waitPending := func(n int) {
+		for i := 0; i < 100; i++ {
+			if _, pending := queue.list(); len(pending) == n {
+				return
+			}
+
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		t.Fatalf("expected %d pending starts", n)
+	}
+
+	queueStart("low", 0)
+	waitPending(1)
+
+	queueStart("high", 5)
+	waitPending(2)
+
+	if _, pending := queue.list(); pending[0].Experiment != "high" || pending[0].Position != 1 || pending[1].Position != 2 {
+		t.Fatalf("expected high priority start to be first in queue, got %+v", pending)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	canceled := errors.New("canceled")
+
+	done := make(chan error)
+
+	go func() {
+		_, err := queue.wait(ctx, "canceled", "user", 0)
+		done <- err
+	}()
+
+	waitPending(3)
+	cancel(canceled)
+
+	if err := <-done; !errors.Is(err, canceled) {
+		t.Fatalf("expected queued start to be canceled, got %v", err)
+	}
+
+	release()
+
+	if first, second := <-launched, <-launched; first != "high" || second != "low" {
+		t.Fatalf("expected starts to be launched by priority, got %s then %s", first, second)
+	}
+}
+
+func TestStartQueueRequeue(t *testing.T) {
+	queue := newStartQueue(func() int { return 1 })
+
+	release, err := queue.wait(context.Background(), "exp", "user", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release()
+
+	// The same experiment queued again right after its start finished holds a
+	// worker of its own, which the previous start's release can't free.
+	again, err := queue.wait(context.Background(), "exp", "user", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	release()
+
+	if running, _ := queue.list(); len(running) != 1 {
+		t.Fatalf("expected requeued start to still be running, got %+v", running)
+	}
+
+	again()
+
+	if running, _ := queue.list(); len(running) != 0 {
+		t.Fatalf("expected no running starts, got %+v", running)
+	}
+}
+
+// Make sure starts made on behalf of restarts, which go through
+// queueExperimentStart, wait their turn in the start queue.
+func TestQueueExperimentStartWaits(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defer func(orig store.Store, queue *startQueue) {
+		store.DefaultStore = orig
+		starts = queue
+	}(store.DefaultStore, starts)
+
+	name := "test-restarted-experiment"
+
+	m := store.NewMockStore(ctrl)
+	m.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		c.Version = "phenix.sandia.gov/v1"
+		c.Spec = map[string]any{"experimentName": name, "topology": map[string]any{"nodes": []any{}}}
+
+		return nil
+	}).AnyTimes()
+
+	store.DefaultStore = m
+
+	starts = newStartQueue(func() int { return 1 })
+
+	release, err := starts.wait(context.Background(), "other", "user", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer release()
+
+	done := make(chan error)
+
+	go func() {
+		_, err := queueExperimentStart(name, "user")
+		done <- err
+	}()
+
+	for i := 0; ; i++ {
+		if _, pending := starts.list(); len(pending) == 1 && pending[0].Experiment == name {
+			break
+		}
+
+		if i == 100 {
+			t.Fatal("expected restart to be waiting in the start queue")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	for _, cancel := range takeCancelers(name) {
+		cancel()
+	}
+
+	var werr *weberror.WebError
+
+	if err := <-done; !errors.As(err, &werr) || werr.Code != weberror.StartCanceled {
+		t.Fatalf("expected queued restart to be canceled, got %v", err)
+	}
+}
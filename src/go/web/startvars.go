@@ -0,0 +1,52 @@
+package web
+
+import (
+	"context"
+	"sync"
+
+	"phenix/app"
+)
+
+var (
+	// Start-time variables for running experiments, keyed by experiment name.
+	// They're only kept in memory since they're never persisted to the
+	// experiment config, so they're lost if the web server restarts.
+	startVars   = make(map[string]map[string]string)
+	startVarsMu sync.RWMutex
+)
+
+func setStartVars(name string, vars map[string]string) {
+	startVarsMu.Lock()
+	defer startVarsMu.Unlock()
+
+	if len(vars) == 0 {
+		delete(startVars, name)
+		return
+	}
+
+	startVars[name] = vars
+}
+
+func getStartVars(name string) map[string]string {
+	startVarsMu.RLock()
+	defer startVarsMu.RUnlock()
+
+	return startVars[name]
+}
+
+func clearStartVars(name string) {
+	startVarsMu.Lock()
+	defer startVarsMu.Unlock()
+
+	delete(startVars, name)
+}
+
+// withStartVars adds the start-time variables for the given experiment, if
+// any, to the given app context.
+func withStartVars(ctx context.Context, name string) context.Context {
+	if vars := getStartVars(name); len(vars) > 0 {
+		return app.SetContextVars(ctx, vars)
+	}
+
+	return ctx
+}
@@ -0,0 +1,206 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/snapshots
+//
+// Lists the snapshots of the memory and disk state of the experiment's VMs,
+// newest first.
+func GetExperimentSnapshots(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentSnapshots")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/snapshots", "list", expName) {
+		err := weberror.NewWebError(nil, "listing snapshots for experiment %s not allowed for %s", expName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	snaps, err := vm.ExperimentSnapshots(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", expName)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	body, _ := json.Marshal(map[string]any{"snapshots": snaps})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{exp}/snapshots
+//
+// Snapshots the memory and disk state of every running VM in the experiment,
+// recording them under the label in the request body, of the form `{"label":
+// "<label>"}`. Each VM is paused while its memory is saved.
+func SnapshotExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SnapshotExperiment")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		user    = ctx.Value("user").(string)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+	)
+
+	if !role.Allowed("experiments/snapshots", "create", expName) {
+		err := weberror.NewWebError(nil, "snapshotting experiment %s not allowed for %s", expName, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Label string `json:"label"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse snapshot request for experiment %s", expName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if err := cache.LockExperimentForUpdate(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", expName)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(expName)
+
+	// Snapshotting VMs one at a time can take longer than the lock lasts.
+	defer cache.KeepExperimentLocked(expName, cache.StatusUpdating)()
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/snapshots", "create", expName),
+		bt.NewResource("experiment/snapshot", expName, "creating"),
+		nil,
+	)
+
+	snap, err := vm.SnapshotExperiment(expName, req.Label)
+	if err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/snapshots", "create", expName),
+			bt.NewResource("experiment/snapshot", expName, "errorCreating"),
+			nil,
+		)
+
+		return stateSnapshotError(err, "unable to snapshot experiment %s", expName)
+	}
+
+	body, _ := json.Marshal(snap)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/snapshots", "create", expName),
+		bt.NewResource("experiment/snapshot", expName, "create"),
+		body,
+	)
+
+	recordExperimentEvent(expName, user, "snapshot", nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
+
+// POST /experiments/{exp}/snapshots/{label}/restore
+//
+// Relaunches each VM in the snapshot with the given label from its saved memory
+// and disk state, on the cluster host it was running on when the snapshot was
+// taken. The experiment must be running.
+func RestoreExperimentSnapshot(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "RestoreExperimentSnapshot")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		user    = ctx.Value("user").(string)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		label   = vars["label"]
+	)
+
+	if !role.Allowed("experiments/snapshots", "update", expName) {
+		err := weberror.NewWebError(nil, "restoring experiment %s not allowed for %s", expName, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cache.LockExperimentForUpdate(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", expName)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	defer cache.UnlockExperiment(expName)
+
+	// Relaunching VMs one at a time can take longer than the lock lasts.
+	defer cache.KeepExperimentLocked(expName, cache.StatusUpdating)()
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/snapshots", "update", expName),
+		bt.NewResource("experiment/snapshot", expName, "restoring"),
+		nil,
+	)
+
+	if err := vm.RestoreExperiment(expName, label); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/snapshots", "update", expName),
+			bt.NewResource("experiment/snapshot", expName, "errorRestoring"),
+			nil,
+		)
+
+		recordExperimentEvent(expName, user, "restore", err)
+
+		return stateSnapshotError(err, "unable to restore experiment %s from snapshot %s", expName, label)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments/snapshots", "update", expName),
+		bt.NewResource("experiment/snapshot", expName, "restore"),
+		nil,
+	)
+
+	recordExperimentEvent(expName, user, "restore", nil)
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func stateSnapshotError(err error, format string, args ...any) *weberror.WebError {
+	werr := weberror.NewWebError(err, format, args...)
+
+	switch {
+	case errors.Is(err, vm.ErrStateSnapshotNotFound):
+		return werr.SetStatus(http.StatusNotFound)
+	case errors.Is(err, vm.ErrStateSnapshotExists):
+		return werr.SetStatus(http.StatusConflict)
+	case errors.Is(err, vm.ErrInvalidSnapshotLabel):
+		return werr.SetStatus(http.StatusBadRequest)
+	case errors.Is(err, experiment.ErrExperimentNotRunning):
+		return werr.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+	}
+
+	return werr.SetStatus(http.StatusInternalServerError)
+}
@@ -0,0 +1,224 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"phenix/api/experiment"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/cache"
+	"phenix/web/proto"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// Overall states of an experiment reported in experimentStatus.
+const (
+	experimentStateConfigured = "configured"
+	experimentStateStarting   = "starting"
+	experimentStateRunning    = "running"
+	experimentStatePaused     = "paused"
+	experimentStateStopping   = "stopping"
+	experimentStateError      = "error"
+)
+
+// experimentStatus is a snapshot of everything a client needs to know about an
+// experiment's current state, so clients (re)connecting don't have to piece it
+// together from the individual broadcasts sent while it starts and stops.
+type experimentStatus struct {
+	Name          string                `json:"name"`
+	State         string                `json:"state"`
+	LaunchPercent float64               `json:"launchPercent,omitempty"`
+	Error         string                `json:"error,omitempty"`
+	VMStates      map[string]int        `json:"vmStates"`
+	Captures      []mm.Capture          `json:"captures"`
+	Interfaces    []mm.InterfaceStats   `json:"interfaces,omitempty"`
+	DelayedErrors []*proto.DelayedError `json:"delayedErrors,omitempty"`
+
+	// Last progress persisted for a start still underway when the server was
+	// restarted.
+	Progress *util.StartProgress `json:"progress,omitempty"`
+}
+
+// startStatus tracks details about the most recent start of an experiment that
+// aren't persisted in the experiment's status.
+type startStatus struct {
+	percent     float64
+	err         string
+	delayedErrs []*proto.DelayedError
+}
+
+var (
+	startStatuses   = make(map[string]*startStatus)
+	startStatusesMu sync.Mutex
+)
+
+// resetStartStatus clears the start status tracked for the given experiment,
+// along with any start progress persisted for it.
+func resetStartStatus(name string) {
+	clearStartProgress(name)
+
+	startStatusesMu.Lock()
+	defer startStatusesMu.Unlock()
+
+	delete(startStatuses, name)
+}
+
+// updateStartStatus calls the given function with the start status tracked for
+// the given experiment, creating it if needed.
+func updateStartStatus(name string, update func(*startStatus)) {
+	startStatusesMu.Lock()
+	defer startStatusesMu.Unlock()
+
+	s, ok := startStatuses[name]
+	if !ok {
+		s = new(startStatus)
+		startStatuses[name] = s
+	}
+
+	update(s)
+}
+
+// getStartStatus returns a copy of the start status tracked for the given
+// experiment.
+func getStartStatus(name string) startStatus {
+	startStatusesMu.Lock()
+	defer startStatusesMu.Unlock()
+
+	s, ok := startStatuses[name]
+	if !ok {
+		return startStatus{}
+	}
+
+	status := *s
+	status.delayedErrs = append([]*proto.DelayedError(nil), s.delayedErrs...)
+
+	return status
+}
+
+// setDelayedError records the given error starting a delayed VM in the start
+// status tracked for the given experiment, replacing any error already recorded
+// for the same VM.
+func setDelayedError(name string, delayed *proto.DelayedError) {
+	updateStartStatus(name, func(s *startStatus) {
+		for i, e := range s.delayedErrs {
+			if e.Vm == delayed.Vm {
+				s.delayedErrs[i] = delayed
+				return
+			}
+		}
+
+		s.delayedErrs = append(s.delayedErrs, delayed)
+	})
+}
+
+// takeDelayedError removes and returns the error recorded for the given delayed
+// VM in the start status tracked for the given experiment, if any.
+func takeDelayedError(name, vm string) (*proto.DelayedError, bool) {
+	startStatusesMu.Lock()
+	defer startStatusesMu.Unlock()
+
+	s, ok := startStatuses[name]
+	if !ok {
+		return nil, false
+	}
+
+	for i, e := range s.delayedErrs {
+		if e.Vm == vm {
+			s.delayedErrs = append(s.delayedErrs[:i:i], s.delayedErrs[i+1:]...)
+			return e, true
+		}
+	}
+
+	return nil, false
+}
+
+// GET /experiments/{name}/status
+func GetExperimentStatus(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentStatus")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	exp, err := experiment.GetCached(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", name)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	var (
+		start  = getStartStatus(name)
+		status = experimentStatus{
+			Name:          name,
+			State:         experimentStateConfigured,
+			VMStates:      make(map[string]int),
+			Captures:      []mm.Capture{},
+			DelayedErrors: start.delayedErrs,
+		}
+	)
+
+	switch cache.IsExperimentLocked(name) {
+	case cache.StatusStarting, cache.StatusRestarting:
+		status.State = experimentStateStarting
+		status.LaunchPercent = start.percent
+	case cache.StatusStopping:
+		status.State = experimentStateStopping
+	default:
+		if exp.Running() {
+			status.State = experimentStateRunning
+
+			if exp.Status.Paused() {
+				status.State = experimentStatePaused
+			}
+		} else if start.err != "" {
+			status.State = experimentStateError
+			status.Error = start.err
+		} else if sp, ok := loadStartProgress(name); ok {
+			// The experiment was still starting when this server was restarted, so
+			// report how far along it had gotten instead of starting over at 0%.
+			status.State = experimentStateStarting
+			status.LaunchPercent = sp.Percent
+			status.Progress = &sp
+		}
+	}
+
+	// Configured experiments have no VMs or captures in minimega.
+	if status.State != experimentStateConfigured && status.State != experimentStateError {
+		snap, err := mm.GetExperimentSnapshot(name, true)
+		if err != nil {
+			plog.Error("getting minimega snapshot for experiment status", "exp", name, "err", err)
+		} else {
+			for _, state := range snap.VMStates {
+				status.VMStates[state]++
+			}
+
+			status.Captures = snap.Captures
+			status.Interfaces = snap.Interfaces
+		}
+	}
+
+	body, err := json.Marshal(status)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to marshal status for experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
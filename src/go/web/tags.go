@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// PATCH /experiments/{name}/tags
+func UpdateExperimentTags(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateExperimentTags")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments", "patch", name) {
+		err := weberror.NewWebError(nil, "updating experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	// Tags with an empty value are removed.
+	var req map[string]string
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse tags update request for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound)
+	}
+
+	// Locked so the read-modify-write of the experiment's config doesn't race
+	// with it being saved while starting, stopping, or otherwise updating it.
+	if err := cache.LockExperimentForUpdate(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", name)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	tags, err := experiment.UpdateTags(name, req)
+	cache.UnlockExperiment(name)
+
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to update tags for experiment %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	body, _ := json.Marshal(map[string]any{"tags": tags})
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments", "get", name),
+		bt.NewResource("experiment", name, "tags"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
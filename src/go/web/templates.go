@@ -0,0 +1,286 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// templateError converts the given experiment template error into a web error
+// with the appropriate status.
+func templateError(err error, format string, args ...any) *weberror.WebError {
+	werr := weberror.NewWebError(err, format, args...)
+
+	switch {
+	case errors.Is(err, experiment.ErrTemplateNotFound):
+		return werr.SetStatus(http.StatusNotFound)
+	case errors.Is(err, experiment.ErrTemplateExists):
+		return werr.SetStatus(http.StatusConflict)
+	case errors.Is(err, experiment.ErrInvalidTemplate):
+		return werr.SetStatus(http.StatusBadRequest)
+	}
+
+	return werr.SetStatus(http.StatusInternalServerError)
+}
+
+// GET /templates
+func GetTemplates(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetTemplates")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	if !role.Allowed("templates", "list") {
+		err := weberror.NewWebError(nil, "listing experiment templates not allowed for %s", ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	templates, err := experiment.ListTemplates()
+	if err != nil {
+		return templateError(err, "unable to list experiment templates")
+	}
+
+	allowed := []experiment.Template{}
+
+	for _, tmpl := range templates {
+		if role.Allowed("templates", "list", tmpl.Name) {
+			allowed = append(allowed, tmpl)
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("templates", allowed))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process experiment templates")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// POST /templates
+func CreateTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "CreateTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+	)
+
+	var tmpl experiment.Template
+
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		err := weberror.NewWebError(err, "unable to parse experiment template")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if !role.Allowed("templates", "create", tmpl.Name) {
+		err := weberror.NewWebError(nil, "creating experiment template %s not allowed for %s", tmpl.Name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := experiment.CreateTemplate(tmpl); err != nil {
+		return templateError(err, "unable to create experiment template %s", tmpl.Name)
+	}
+
+	body, _ := json.Marshal(tmpl)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
+
+// GET /templates/{name}
+func GetTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("templates", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment template %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	tmpl, err := experiment.GetTemplate(name)
+	if err != nil {
+		return templateError(err, "unable to get experiment template %s", name)
+	}
+
+	body, _ := json.Marshal(tmpl)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// PUT /templates/{name}
+func UpdateTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("templates", "update", name) {
+		err := weberror.NewWebError(nil, "updating experiment template %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var tmpl experiment.Template
+
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		err := weberror.NewWebError(err, "unable to parse experiment template")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	// The template being updated is identified by the path, not the body.
+	tmpl.Name = name
+
+	if err := experiment.UpdateTemplate(tmpl); err != nil {
+		return templateError(err, "unable to update experiment template %s", name)
+	}
+
+	body, _ := json.Marshal(tmpl)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// DELETE /templates/{name}
+func DeleteTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DeleteTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("templates", "delete", name) {
+		err := weberror.NewWebError(nil, "deleting experiment template %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := experiment.DeleteTemplate(name); err != nil {
+		return templateError(err, "unable to delete experiment template %s", name)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+type instantiateRequest struct {
+	Name   string            `json:"name"`
+	Params map[string]string `json:"params"`
+}
+
+// POST /templates/{name}/instantiate
+//
+// Creates a new experiment from the template, substituting the parameters in
+// the request body, of the form `{"name": "<experiment>", "params":
+// {"<param>": "<value>"}}`, into the template's spec.
+func InstantiateTemplate(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "InstantiateTemplate")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("templates", "get", name) {
+		err := weberror.NewWebError(nil, "getting experiment template %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req instantiateRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse instantiate request for experiment template %s", name)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if !role.Allowed("experiments", "create", req.Name) {
+		err := weberror.NewWebError(nil, "creating experiment %s not allowed for %s", req.Name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if err := cache.LockExperimentForCreation(req.Name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", req.Name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockExperiment(req.Name)
+
+	cfg, err := experiment.Instantiate(name, req.Params, req.Name, experimentOwner(ctx))
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to instantiate experiment template %s as %s", name, req.Name)
+
+		switch {
+		case errors.Is(err, experiment.ErrTemplateNotFound):
+			return werr.SetStatus(http.StatusNotFound)
+		case errors.Is(err, experiment.ErrExperimentExists):
+			return werr.SetStatus(http.StatusConflict)
+		}
+
+		// Anything else is an invalid experiment, whether from missing parameters,
+		// schema validation, or the experiment config hooks.
+		return werr.SetStatus(http.StatusBadRequest)
+	}
+
+	if exp, err := experiment.Get(req.Name); err == nil {
+		vms, _ := vm.List(req.Name)
+
+		if body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms)); err == nil {
+			broker.Broadcast(
+				bt.NewRequestPolicy("experiments", "get", req.Name),
+				bt.NewResource("experiment", req.Name, "create"),
+				body,
+			)
+		}
+	}
+
+	// Clear experiment name... not applicable to end users.
+	delete(cfg.Spec, "experimentName")
+
+	body, err := json.Marshal(cfg)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process config for experiment %s", req.Name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(body)
+
+	return nil
+}
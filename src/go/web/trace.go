@@ -0,0 +1,56 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{name}/trace
+//
+// Downloads the trace of the most recent start of the experiment made with
+// tracing enabled (`POST /experiments/{name}/start?trace=true`).
+func GetExperimentTrace(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentTrace")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/trace", "get", name) {
+		err := weberror.NewWebError(nil, "getting start trace for experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if _, err := experiment.Get(name); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	body, err := experiment.LatestTrace(name)
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to get start trace for experiment %s", name)
+
+		if errors.Is(err, experiment.ErrNoTrace) {
+			return werr.SetStatus(http.StatusNotFound)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s-trace.json", name))
+	w.Write(body)
+
+	return nil
+}
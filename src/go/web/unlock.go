@@ -0,0 +1,83 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// POST /admin/experiments/{name}/unlock
+//
+// Force-releases the lock held on the experiment, for when whatever locked it
+// (e.g. a start or stop) died without releasing it, leaving every other
+// operation on the experiment failing with a conflict. Nothing is done to stop
+// whatever holds the lock, so it should only be used on experiments that are
+// actually stuck.
+func ForceUnlockExperiment(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ForceUnlockExperiment")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		user = ctx.Value("user").(string)
+		name = mux.Vars(r)["name"]
+	)
+
+	if !role.Allowed("experiments/unlock", "update", name) {
+		err := weberror.NewWebError(nil, "unlocking experiment %s not allowed for %s", name, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", name)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	status := cache.IsExperimentLocked(name)
+	if status == "" {
+		err := weberror.NewWebError(nil, "experiment %s is not locked", name)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	cache.UnlockExperiment(name)
+
+	plog.Warn("experiment lock force-released", "exp", name, "status", status, "user", user)
+
+	recordExperimentEvent(name, user, "forceUnlocked", nil)
+
+	vms, _ := vm.List(name)
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process experiment %s", name)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	// Clients may still think the experiment is starting or stopping, so send
+	// them its current state.
+	broker.Broadcast(
+		bt.NewRequestPolicy("experiments", "get", name),
+		bt.NewResource("experiment", name, "update"),
+		body,
+	)
+
+	resp, _ := json.Marshal(map[string]any{"experiment": name, "released": status})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(resp)
+
+	return nil
+}
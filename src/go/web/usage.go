@@ -0,0 +1,104 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultUsageStreamInterval = 10 * time.Second
+	minUsageStreamInterval     = 1 * time.Second
+)
+
+var usageStreams = newExperimentPollers("usage")
+
+// GET /experiments/{exp}/usage[?stream=true][&interval=<duration>]
+func GetExperimentUsage(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "GetExperimentUsage")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		exp   = vars["exp"]
+		query = r.URL.Query()
+
+		interval = defaultUsageStreamInterval
+	)
+
+	if !role.Allowed("experiments/usage", "get", exp) {
+		err := weberror.NewWebError(nil, "getting usage for experiment %s not allowed for %s", exp, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if v := query.Get("interval"); v != "" {
+		if err := parseDuration(v, &interval); err != nil {
+			err := weberror.NewWebError(err, "invalid usage interval %s", v)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+
+		if interval < minUsageStreamInterval {
+			err := weberror.NewWebError(nil, "usage interval must be at least %v", minUsageStreamInterval)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	usage, err := experiment.GetUsage(exp)
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to get usage for experiment %s", exp)
+
+		if errors.Is(err, experiment.ErrExperimentNotRunning) {
+			return werr.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := json.Marshal(usage)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process usage for experiment %s", exp)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if query.Get("stream") == "true" {
+		startUsageStream(exp, interval)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// startUsageStream starts periodically broadcasting the resource usage of the
+// given experiment. It's a no-op if a stream for the experiment is already
+// running.
+func startUsageStream(exp string, interval time.Duration) {
+	usageStreams.start(exp, interval, func() {
+		usage, err := experiment.GetUsage(exp)
+		if err != nil {
+			plog.Error("getting usage for usage stream", "exp", exp, "err", err)
+			return
+		}
+
+		body, _ := json.Marshal(usage)
+
+		broker.Broadcast(
+			bt.NewRequestPolicy("experiments/usage", "get", exp),
+			bt.NewResource("experiment/usage", exp, "stream"),
+			body,
+		)
+	})
+}
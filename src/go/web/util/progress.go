@@ -0,0 +1,53 @@
+package util
+
+import "time"
+
+// StartProgress represents the launch progress of an experiment that is
+// broadcasted to clients while the experiment is starting.
+type StartProgress struct {
+	Percent        float64 `json:"percent"`
+	Launched       int     `json:"launched"`
+	Total          int     `json:"total"`
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty"`
+	Stage          string  `json:"stage"`
+
+	// Launch state of each VM, keyed by VM name. VMs with a readiness probe are
+	// reported as booting or ready once they're running.
+	VMs map[string]string `json:"vms,omitempty"`
+
+	// Boot group currently being started (starting at 1) and the total number
+	// of boot groups, for experiments with VMs that have a boot order set.
+	BootGroup  int `json:"boot_group,omitempty"`
+	BootGroups int `json:"boot_groups,omitempty"`
+}
+
+// Stages of an experiment start reported in StartProgress.
+const (
+	StartStageLaunching  = "launching"
+	StartStageFinalizing = "finalizing"
+	StartStageDone       = "done"
+	StartStageFailed     = "failed"
+)
+
+// NewStartProgress creates a StartProgress for the given fraction of total VMs
+// launched. A zero start time results in no elapsed time being reported.
+func NewStartProgress(progress float64, total int, start time.Time) StartProgress {
+	sp := StartProgress{
+		Percent:  progress,
+		Launched: int(progress * float64(total)),
+		Total:    total,
+		Stage:    StartStageLaunching,
+	}
+
+	if progress >= 1.0 {
+		// All VMs have been launched, but post-start apps and delayed VMs may
+		// still be getting handled.
+		sp.Stage = StartStageFinalizing
+	}
+
+	if !start.IsZero() {
+		sp.ElapsedSeconds = time.Since(start).Seconds()
+	}
+
+	return sp
+}
@@ -20,6 +20,10 @@ func ExperimentToProtobuf(exp types.Experiment, status cache.Status, vms []mm.VM
 		Running:   exp.Running(),
 		Status:    string(status),
 		VmCount:   uint32(len(vms)),
+
+		LastStartDurationSeconds: exp.Status.LastStartDurationSeconds(),
+		Tags:                     exp.Spec.Tags(),
+		Annotations:              exp.Spec.Annotations(),
 	}
 
 	pb.Vms = make([]*proto.VM, len(vms))
@@ -40,6 +44,18 @@ func ExperimentToProtobuf(exp types.Experiment, status cache.Status, vms []mm.VM
 
 	pb.Apps = apps
 
+	if impairments := exp.Status.LinkImpairments(); len(impairments) > 0 {
+		pb.Impairments = make(map[string]*proto.LinkImpairment)
+
+		for vlan, i := range impairments {
+			pb.Impairments[vlan] = &proto.LinkImpairment{
+				LatencyMs: uint32(i["latencyMs"]),
+				LossPct:   i["lossPct"],
+				RateKbit:  uint32(i["rateKbit"]),
+			}
+		}
+	}
+
 	var aliases map[string]int
 
 	if exp.Running() {
@@ -111,6 +127,7 @@ func VMToProtobuf(exp string, vm mm.VM, topology ifaces.TopologySpec) *proto.VM
 		Tags:            vm.Tags,
 		CcActive:        vm.CCActive,
 		Snapshot:        vm.Snapshot,
+		Note:            vm.Note,
 	}
 
 	if topology == nil {
@@ -130,6 +147,7 @@ func CaptureToProtobuf(capture mm.Capture) *proto.Capture {
 		Vm:        capture.VM,
 		Interface: uint32(capture.Interface),
 		Filepath:  capture.Filepath,
+		Size:      uint64(capture.Size),
 	}
 }
 
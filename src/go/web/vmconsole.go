@@ -0,0 +1,169 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"phenix/api/vm"
+	"phenix/util/mm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+
+	"github.com/gorilla/mux"
+	"golang.org/x/net/websocket"
+)
+
+// How often the state of a VM is checked while its console is being proxied,
+// so the proxy can be closed if the VM stops.
+const consoleStatePollInterval = 5 * time.Second
+
+var (
+	// Cancelers for console proxies currently open, keyed by experiment name
+	// and then a per-proxy ID.
+	consoleCancelers   = make(map[string]map[uint64]context.CancelFunc)
+	consoleCancelersMu sync.Mutex
+	consoleID          uint64
+)
+
+func addConsoleCanceler(exp string, cancel context.CancelFunc) uint64 {
+	consoleCancelersMu.Lock()
+	defer consoleCancelersMu.Unlock()
+
+	if _, ok := consoleCancelers[exp]; !ok {
+		consoleCancelers[exp] = make(map[uint64]context.CancelFunc)
+	}
+
+	consoleID++
+	consoleCancelers[exp][consoleID] = cancel
+
+	return consoleID
+}
+
+func removeConsoleCanceler(exp string, id uint64) {
+	consoleCancelersMu.Lock()
+	defer consoleCancelersMu.Unlock()
+
+	delete(consoleCancelers[exp], id)
+
+	if len(consoleCancelers[exp]) == 0 {
+		delete(consoleCancelers, exp)
+	}
+}
+
+// closeConsoles closes all the console proxies open for VMs in the given
+// experiment. It's called when the experiment is being stopped.
+func closeConsoles(exp string) {
+	consoleCancelersMu.Lock()
+	cancels := consoleCancelers[exp]
+	delete(consoleCancelers, exp)
+	consoleCancelersMu.Unlock()
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+}
+
+// GET /experiments/{exp}/vms/{name}/console[?type=<vnc|serial>]
+func GetVMConsole(w http.ResponseWriter, r *http.Request) {
+	plog.Debug("HTTP handler called", "handler", "GetVMConsole")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+		typ  = r.URL.Query().Get("type")
+	)
+
+	if !role.Allowed("vms/console", "get", exp+"/"+name) {
+		plog.Warn("accessing VM console not allowed", "user", ctx.Value("user").(string), "exp", exp, "vm", name)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	v, err := vm.Get(exp, name)
+	if err != nil {
+		http.Error(w, "VM not found", http.StatusNotFound)
+		return
+	}
+
+	if !v.Running {
+		http.Error(w, "VM is not running", http.StatusBadRequest)
+		return
+	}
+
+	endpoint, err := mm.GetVMConsolePort(mm.NS(exp), mm.VMName(name), mm.ConsoleType(typ))
+	if err != nil {
+		plog.Error("getting VM console endpoint", "exp", exp, "vm", name, "err", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	websocket.Handler(consoleProxy(exp, name, endpoint)).ServeHTTP(w, r)
+}
+
+// consoleProxy returns a WebSocket handler that streams bytes in both
+// directions between the client and the given VM console endpoint. The proxy
+// is closed when either side disconnects, the VM stops running, or the
+// experiment is stopped.
+func consoleProxy(exp, name string, endpoint mm.ConsoleEndpoint) func(*websocket.Conn) {
+	return func(ws *websocket.Conn) {
+		// Needed for io.Copy to send binary frames instead of text frames.
+		ws.PayloadType = websocket.BinaryFrame
+
+		remote, err := net.Dial(endpoint.Network, endpoint.Address)
+		if err != nil {
+			plog.Error("dialing VM console", "exp", exp, "vm", name, "endpoint", endpoint.Address, "err", err)
+			ws.Close()
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		id := addConsoleCanceler(exp, cancel)
+		defer removeConsoleCanceler(exp, id)
+
+		// Closing both connections unblocks the copies below.
+		go func() {
+			<-ctx.Done()
+
+			ws.Close()
+			remote.Close()
+		}()
+
+		go func() {
+			ticker := time.NewTicker(consoleStatePollInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if state, err := mm.GetVMState(mm.NS(exp), mm.VMName(name)); err != nil || state != "RUNNING" {
+						plog.Info("VM no longer running, closing console", "exp", exp, "vm", name)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+
+		plog.Info("VM console client connected", "exp", exp, "vm", name, "endpoint", endpoint.Address)
+
+		go func() {
+			io.Copy(ws, remote)
+			cancel()
+		}()
+
+		io.Copy(remote, ws)
+		cancel()
+
+		plog.Info("VM console client disconnected", "exp", exp, "vm", name)
+	}
+}
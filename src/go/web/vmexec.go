@@ -0,0 +1,95 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// POST /experiments/{exp}/vms/{name}/exec
+//
+// Runs the minimega command in the request body, of the form `{"command": "vm
+// qmp '{\"execute\": \"query-status\"}'"}`, against the VM and returns its
+// output. Only the minimega commands allowed when serving can be run, and
+// running them requires owner access to the experiment. Every command run is
+// recorded in the experiment's events.
+func ExecVM(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ExecVM")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		user     = ctx.Value("user").(string)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/exec", "create", fullName) {
+		err := weberror.NewWebError(nil, "running commands against VM %s not allowed for %s", fullName, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if len(o.vmExecVerbs) == 0 {
+		err := weberror.NewWebError(nil, "running minimega commands against VMs is disabled")
+		return err.SetStatus(http.StatusMethodNotAllowed)
+	}
+
+	var req struct {
+		Command string `json:"command"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse command request for VM %s", fullName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if err := authorizeExperimentAction(ctx, expName, user, "running commands against VMs in", experiment.PermissionOwner); err != nil {
+		return err
+	}
+
+	run, responses, err := vm.Exec(expName, name, req.Command, o.vmExecVerbs)
+
+	if run == "" {
+		run = req.Command
+	}
+
+	plog.Info("running minimega command against VM", "exp", expName, "vm", name, "user", user, "command", run, "err", err)
+
+	md := map[string]string{"vm": name, "command": run}
+
+	if err := experiment.RecordEventWithMetadata(expName, "exec", user, md, err); err != nil {
+		plog.Error("recording experiment event", "exp", expName, "action", "exec", "err", err)
+	}
+
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to run command against VM %s", fullName)
+
+		switch {
+		case errors.Is(err, vm.ErrCommandNotAllowed):
+			return werr.SetStatus(http.StatusForbidden)
+		case errors.Is(err, vm.ErrVMNotFound):
+			return werr.SetStatus(http.StatusNotFound).SetCode(weberror.VMNotFound)
+		case errors.Is(err, experiment.ErrExperimentNotRunning):
+			return werr.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentNotRunning)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, _ := json.Marshal(map[string]any{"command": run, "responses": responses})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// vmGroupAction describes how a VM group action is authorized, locked, and
+// broadcast, matching the handlers for the equivalent single VM actions.
+type vmGroupAction struct {
+	policy  string
+	lock    func(string, string) error
+	pending string
+	done    string
+	failed  string
+}
+
+var vmGroupActions = map[string]vmGroupAction{
+	"start":   {"vms/start", cache.LockVMForStarting, "starting", "start", "errorStarting"},
+	"stop":    {"vms/shutdown", cache.LockVMForStopping, "stopping", "shutdown", "errorStopping"},
+	"restart": {"vms/restart", cache.LockVMForStarting, "restarting", "update", "errorRestarting"},
+	"pause":   {"vms/stop", cache.LockVMForStopping, "stopping", "stop", "errorStopping"},
+}
+
+type vmGroupResult struct {
+	VM    string `json:"vm"`
+	Error string `json:"error,omitempty"`
+}
+
+// POST /experiments/{exp}/vms/group
+func VMGroupAction(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "VMGroupAction")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		user    = ctx.Value("user").(string)
+		expName = mux.Vars(r)["exp"]
+	)
+
+	var req struct {
+		Selector string `json:"selector"`
+		Action   string `json:"action"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse VM group action request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	action, ok := vmGroupActions[req.Action]
+	if !ok {
+		err := weberror.NewWebError(vm.ErrInvalidGroupAction, "invalid VM group action %q", req.Action)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	members, err := vm.GroupMembers(expName, req.Selector)
+	if err != nil {
+		return vmGroupError(err, "unable to select VMs in experiment %s", expName)
+	}
+
+	// Acting on the group is all or nothing as far as authorization goes, so
+	// users don't end up with a partially applied action they can't undo.
+	for _, name := range members {
+		if !role.Allowed(action.policy, "update", expName+"/"+name) {
+			err := weberror.NewWebError(nil, "%s VM %s not allowed for %s", req.Action, name, user)
+			return err.SetStatus(http.StatusForbidden)
+		}
+	}
+
+	var (
+		locked   = make(map[string]bool)
+		lockedMu sync.Mutex
+	)
+
+	before := func(name string) error {
+		if err := action.lock(expName, name); err != nil {
+			plog.Error("locking VM", "exp", expName, "vm", name, "action", action.pending, "err", err)
+			return err
+		}
+
+		lockedMu.Lock()
+		locked[name] = true
+		lockedMu.Unlock()
+
+		broker.Broadcast(
+			bt.NewRequestPolicy(action.policy, "update", expName+"/"+name),
+			bt.NewResource("experiment/vm", name, action.pending),
+			nil,
+		)
+
+		return nil
+	}
+
+	after := func(result vm.GroupResult) {
+		lockedMu.Lock()
+		wasLocked := locked[result.VM]
+		lockedMu.Unlock()
+
+		// Don't touch VMs another request already had locked.
+		if !wasLocked {
+			return
+		}
+
+		defer cache.UnlockVM(expName, result.VM)
+
+		policy := bt.NewRequestPolicy(action.policy, "update", expName+"/"+result.VM)
+
+		if result.Err != nil {
+			broker.Broadcast(policy, bt.NewResource("experiment/vm", result.VM, action.failed), nil)
+			return
+		}
+
+		exp, err := experiment.Get(expName)
+		if err != nil {
+			broker.Broadcast(policy, bt.NewResource("experiment/vm", result.VM, action.failed), nil)
+			return
+		}
+
+		v, err := vm.Get(expName, result.VM)
+		if err != nil {
+			broker.Broadcast(policy, bt.NewResource("experiment/vm", result.VM, action.failed), nil)
+			return
+		}
+
+		body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+		if err != nil {
+			broker.Broadcast(policy, bt.NewResource("experiment/vm", result.VM, action.failed), nil)
+			return
+		}
+
+		broker.Broadcast(policy, bt.NewResource("experiment/vm", expName+"/"+result.VM, action.done), body)
+	}
+
+	results, err := vm.GroupAction(expName, req.Selector, req.Action, vm.GroupWithBefore(before), vm.GroupWithAfter(after))
+	if err != nil {
+		return vmGroupError(err, "unable to %s VMs in experiment %s", req.Action, expName)
+	}
+
+	resp := make([]vmGroupResult, len(results))
+
+	for i, result := range results {
+		resp[i] = vmGroupResult{VM: result.VM}
+
+		if result.Err != nil {
+			resp[i].Error = result.Err.Error()
+		}
+	}
+
+	body, err := json.Marshal(util.WithRoot("results", resp))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM group action results")
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
+
+// vmGroupError converts the given VM group error into a web error with the
+// appropriate status.
+func vmGroupError(err error, format string, args ...any) *weberror.WebError {
+	werr := weberror.NewWebError(err, format, args...)
+
+	switch {
+	case errors.Is(err, vm.ErrNoVMsMatched):
+		return werr.SetStatus(http.StatusNotFound)
+	case errors.Is(err, vm.ErrInvalidGroupAction), errors.Is(err, experiment.ErrInvalidTagSelector):
+		return werr.SetStatus(http.StatusBadRequest)
+	case errors.Is(err, experiment.ErrExperimentNotRunning):
+		return werr.SetStatus(http.StatusConflict)
+	}
+
+	return werr.SetStatus(http.StatusInternalServerError)
+}
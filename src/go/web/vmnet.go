@@ -0,0 +1,90 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// POST /experiments/{exp}/vms/{name}/interfaces/{iface}/connect
+//
+// Connects the VM's interface, given by its index, to the VLAN in the request
+// body, of the form `{"vlan": "<alias>"}`, while the experiment is running. The
+// VLAN must be one of the experiment's VLANs.
+func ConnectVMInterface(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "ConnectVMInterface")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/interfaces", "update", fullName) {
+		err := weberror.NewWebError(nil, "connecting interfaces for VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	iface, err := strconv.Atoi(vars["iface"])
+	if err != nil {
+		err := weberror.NewWebError(err, "invalid interface index %s", vars["iface"])
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	var req struct {
+		VLAN string `json:"vlan"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse VM interface request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if req.VLAN == "" {
+		err := weberror.NewWebError(nil, "no VLAN provided for VM %s interface %d", fullName, iface)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	return updateVM(w, expName, name, "interface", func() error {
+		return vm.ConnectInterface(expName, name, iface, req.VLAN)
+	})
+}
+
+// POST /experiments/{exp}/vms/{name}/interfaces/{iface}/disconnect
+func DisconnectVMInterface(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DisconnectVMInterface")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/interfaces", "update", fullName) {
+		err := weberror.NewWebError(nil, "disconnecting interfaces for VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	iface, err := strconv.Atoi(vars["iface"])
+	if err != nil {
+		err := weberror.NewWebError(err, "invalid interface index %s", vars["iface"])
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	return updateVM(w, expName, name, "interface", func() error {
+		return vm.DisconnectInterface(expName, name, iface)
+	})
+}
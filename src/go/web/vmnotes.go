@@ -0,0 +1,48 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// PATCH /experiments/{exp}/vms/{name}/note
+//
+// Sets the VM's note to the text in the request body, of the form `{"text":
+// "<note>"}`. Empty text removes the note.
+func UpdateVMNote(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateVMNote")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/note", "patch", fullName) {
+		err := weberror.NewWebError(nil, "updating note for VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Text string `json:"text"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse VM note request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	return updateVM(w, expName, name, "note", func() error {
+		return vm.SetNote(expName, name, req.Text)
+	})
+}
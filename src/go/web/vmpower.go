@@ -0,0 +1,143 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// How each VM power action locks the VM and is broadcast to clients, matching
+// the VM start, stop and redeploy handlers.
+var powerActions = map[string]struct {
+	lock                     func(string, string) error
+	pending, done, errorDone string
+}{
+	vm.PowerOn:    {cache.LockVMForStarting, "starting", "start", "errorStarting"},
+	vm.PowerOff:   {cache.LockVMForStopping, "stopping", "stop", "errorStopping"},
+	vm.PowerReset: {cache.LockVMForRedeploying, "redeploying", "redeployed", "errorRedeploying"},
+}
+
+// POST /experiments/{exp}/vms/{name}/power
+//
+// Powers the VM on, off, or resets it (kills and relaunches it) according to
+// the action in the request body, of the form `{"action": "on|off|reset"}`.
+func PowerVM(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "PowerVM")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		user     = ctx.Value("user").(string)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/power", "update", fullName) {
+		err := weberror.NewWebError(nil, "changing power state of VM %s not allowed for %s", fullName, user)
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Action string `json:"action"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse power request for VM %s", fullName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	action, ok := powerActions[req.Action]
+	if !ok {
+		err := weberror.NewWebError(vm.ErrInvalidPowerAction, "invalid power action %q for VM %s (must be one of on, off or reset)", req.Action, fullName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if _, err := experiment.Get(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", expName)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	if err := action.lock(expName, name); err != nil {
+		err := weberror.NewWebError(err, "VM %s is locked", fullName)
+		return err.SetStatus(http.StatusConflict)
+	}
+
+	defer cache.UnlockVM(expName, name)
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/power", "update", fullName),
+		bt.NewResource("experiment/vm", fullName, action.pending),
+		nil,
+	)
+
+	if err := vm.Power(expName, name, req.Action); err != nil {
+		broker.Broadcast(
+			bt.NewRequestPolicy("vms/power", "update", fullName),
+			bt.NewResource("experiment/vm", fullName, action.errorDone),
+			nil,
+		)
+
+		werr := weberror.NewWebError(err, "unable to power %s VM %s", req.Action, fullName)
+
+		switch {
+		case errors.Is(err, vm.ErrVMNotFound):
+			return werr.SetStatus(http.StatusNotFound).SetCode(weberror.VMNotFound)
+		case errors.Is(err, experiment.ErrExperimentNotRunning):
+			return werr.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentNotRunning)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	v, err := vm.Get(expName, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	if v.Running {
+		if screenshot, err := util.GetScreenshot(expName, name, "215"); err == nil {
+			v.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+		} else {
+			plog.Error("getting screenshot", "err", err)
+		}
+	}
+
+	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms/power", "update", fullName),
+		bt.NewResource("experiment/vm", fullName, action.done),
+		body,
+	)
+
+	w.Write(body)
+
+	return nil
+}
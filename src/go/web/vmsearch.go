@@ -0,0 +1,87 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/rbac"
+	"phenix/web/weberror"
+
+	"github.com/gorilla/mux"
+)
+
+// GET /experiments/{exp}/vms/search?q=<query>[&limit=<n>]
+//
+// Searches the experiment's VM names, IPs, tags, cluster hosts and notes for
+// the given query (see `vm.Search` for the query syntax), returning the
+// matching VMs best match first.
+func SearchVMs(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "SearchVMs")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		expName = vars["exp"]
+		query   = r.URL.Query()
+		limit   = -1
+	)
+
+	if !role.Allowed("vms", "list") {
+		err := weberror.NewWebError(nil, "searching VMs in experiment %s not allowed for %s", expName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	if l := query.Get("limit"); l != "" {
+		var err error
+
+		if limit, err = strconv.Atoi(l); err != nil || limit < 0 {
+			err := weberror.NewWebError(err, "invalid search limit %s", l)
+			return err.SetStatus(http.StatusBadRequest)
+		}
+	}
+
+	if _, err := experiment.Get(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s not found", expName)
+		return err.SetStatus(http.StatusNotFound).SetCode(weberror.ExperimentNotFound)
+	}
+
+	results, err := vm.Search(expName, query.Get("q"))
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to search VMs in experiment %s", expName)
+
+		if errors.Is(err, vm.ErrInvalidSearch) {
+			return werr.SetStatus(http.StatusBadRequest)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	allowed := []vm.SearchResult{}
+
+	for _, result := range results {
+		if role.Allowed("vms", "list", fmt.Sprintf("%s/%s", expName, result.VM.Name)) {
+			allowed = append(allowed, result)
+		}
+	}
+
+	// Total is the number of matching VMs before the limit is applied.
+	total := len(allowed)
+
+	if limit >= 0 && limit < total {
+		allowed = allowed[:limit]
+	}
+
+	body, _ := json.Marshal(map[string]any{"results": allowed, "total": total})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/util/plog"
+	"phenix/web/broker"
+	"phenix/web/cache"
+	"phenix/web/rbac"
+	"phenix/web/util"
+	"phenix/web/weberror"
+
+	bt "phenix/web/broker/brokertypes"
+
+	"github.com/gorilla/mux"
+)
+
+// PATCH /experiments/{exp}/vms/{name}/tags
+//
+// Sets the tags in the request body, of the form `{"tags": {"<key>":
+// "<value>"}}`, on the VM, leaving its other tags as they are.
+func UpdateVMTags(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "UpdateVMTags")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/tags", "patch", fullName) {
+		err := weberror.NewWebError(nil, "updating tags for VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	var req struct {
+		Tags map[string]string `json:"tags"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		err := weberror.NewWebError(err, "unable to parse VM tags request")
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	if len(req.Tags) == 0 {
+		err := weberror.NewWebError(nil, "no tags provided for VM %s", fullName)
+		return err.SetStatus(http.StatusBadRequest)
+	}
+
+	return updateVM(w, expName, name, "tags", func() error {
+		return vm.SetTags(expName, name, req.Tags)
+	})
+}
+
+// DELETE /experiments/{exp}/vms/{name}/tags/{tag}
+func DeleteVMTag(w http.ResponseWriter, r *http.Request) error {
+	plog.Debug("HTTP handler called", "handler", "DeleteVMTag")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		expName  = vars["exp"]
+		name     = vars["name"]
+		tag      = vars["tag"]
+		fullName = expName + "/" + name
+	)
+
+	if !role.Allowed("vms/tags", "delete", fullName) {
+		err := weberror.NewWebError(nil, "deleting tags for VM %s not allowed for %s", fullName, ctx.Value("user").(string))
+		return err.SetStatus(http.StatusForbidden)
+	}
+
+	return updateVM(w, expName, name, "tags", func() error {
+		return vm.DeleteTag(expName, name, tag)
+	})
+}
+
+// updateVM applies the given change to the given VM's tags, note, or interfaces
+// (named by what, for errors) while the experiment is locked for updating (so
+// it doesn't race with the experiment's config being saved while starting or
+// stopping it), then broadcasts and writes the updated VM.
+func updateVM(w http.ResponseWriter, expName, name, what string, update func() error) error {
+	fullName := expName + "/" + name
+
+	if err := cache.LockExperimentForUpdate(expName); err != nil {
+		err := weberror.NewWebError(err, "experiment %s is locked", expName)
+		return err.SetStatus(http.StatusConflict).SetCode(weberror.ExperimentLocked)
+	}
+
+	err := update()
+	cache.UnlockExperiment(expName)
+
+	if err != nil {
+		werr := weberror.NewWebError(err, "unable to update %s for VM %s", what, fullName)
+
+		switch {
+		case errors.Is(err, vm.ErrVMNotFound), errors.Is(err, vm.ErrTagNotFound), errors.Is(err, vm.ErrInterfaceNotFound):
+			return werr.SetStatus(http.StatusNotFound)
+		case errors.Is(err, vm.ErrInvalidTag), errors.Is(err, vm.ErrInvalidNote), errors.Is(err, experiment.ErrVLANNotFound):
+			return werr.SetStatus(http.StatusBadRequest)
+		case errors.Is(err, experiment.ErrExperimentNotRunning):
+			return werr.SetStatus(http.StatusBadRequest).SetCode(weberror.ExperimentNotRunning)
+		}
+
+		return werr.SetStatus(http.StatusInternalServerError)
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get experiment %s", expName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	v, err := vm.Get(expName, name)
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to get VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	body, err := marshaler.Marshal(util.VMToProtobuf(expName, *v, exp.Spec.Topology()))
+	if err != nil {
+		err := weberror.NewWebError(err, "unable to process VM %s", fullName)
+		return err.SetStatus(http.StatusInternalServerError)
+	}
+
+	broker.Broadcast(
+		bt.NewRequestPolicy("vms", "get", fullName),
+		bt.NewResource("experiment/vm", fullName, "update"),
+		body,
+	)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+
+	return nil
+}
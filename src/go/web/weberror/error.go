@@ -9,14 +9,49 @@ import (
 	"phenix/util/plog"
 )
 
+// ErrorCode is a machine-readable code included in the JSON body of a WebError
+// so clients can branch on (or localize) errors without matching on messages.
+type ErrorCode string
+
+const (
+	ExperimentLocked      ErrorCode = "ExperimentLocked"
+	ExperimentNotFound    ErrorCode = "ExperimentNotFound"
+	ExperimentRunning     ErrorCode = "ExperimentRunning"
+	ExperimentNotRunning  ErrorCode = "ExperimentNotRunning"
+	ExperimentNotStarting ErrorCode = "ExperimentNotStarting"
+	StartCanceled         ErrorCode = "StartCanceled"
+	StartFailed           ErrorCode = "StartFailed"
+	StartTimeout          ErrorCode = "StartTimeout"
+	StopFailed            ErrorCode = "StopFailed"
+	ScheduleInfeasible    ErrorCode = "ScheduleInfeasible"
+	HostUnreachable       ErrorCode = "HostUnreachable"
+	VLANExhausted         ErrorCode = "VLANExhausted"
+	IsolationViolated     ErrorCode = "IsolationViolated"
+	GPUUnavailable        ErrorCode = "GPUUnavailable"
+	VMNotFound            ErrorCode = "VMNotFound"
+	VMImageMissing        ErrorCode = "VMImageMissing"
+	Maintenance           ErrorCode = "Maintenance"
+	StartCooldown         ErrorCode = "StartCooldown"
+	PermissionDenied      ErrorCode = "PermissionDenied"
+	Internal              ErrorCode = "Internal"
+)
+
 type WebError struct {
 	*store.Event
 
-	Cause  error  `json:"-"`
-	Status int    `json:"-"`
-	URL    string `json:"url"`
+	Cause  error     `json:"-"`
+	Status int       `json:"-"`
+	URL    string    `json:"url"`
+	Code   ErrorCode `json:"code,omitempty"`
 
 	UserMetadata map[string]string `json:"metadata,omitempty"`
+
+	// Optional JSON body providing the client with additional details about the
+	// state of things when the error occurred.
+	Data json.RawMessage `json:"data,omitempty"`
+
+	// Optional headers to include in the response (e.g. `Retry-After`).
+	Headers http.Header `json:"-"`
 }
 
 func NewWebError(cause error, format string, args ...interface{}) *WebError {
@@ -50,6 +85,20 @@ func (this *WebError) WithMetadata(k, v string, user bool) *WebError {
 	return this
 }
 
+func (this *WebError) SetData(data json.RawMessage) *WebError {
+	this.Data = data
+	return this
+}
+
+func (this *WebError) SetHeader(k, v string) *WebError {
+	if this.Headers == nil {
+		this.Headers = make(http.Header)
+	}
+
+	this.Headers.Set(k, v)
+	return this
+}
+
 func (this *WebError) SetInformational() *WebError {
 	this.Event.Type = store.EventTypeInfo
 	return this
@@ -60,6 +109,11 @@ func (this *WebError) SetStatus(status int) *WebError {
 	return this
 }
 
+func (this *WebError) SetCode(code ErrorCode) *WebError {
+	this.Code = code
+	return this
+}
+
 func (this WebError) Error() string {
 	if this.Cause == nil {
 		return this.Event.Message
@@ -85,6 +139,10 @@ func (this ErrorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		body, _ := json.Marshal(web)
 		plog.Error(string(body))
 
+		for k, v := range web.Headers {
+			w.Header()[k] = v
+		}
+
 		w.Header().Set("Content-Type", "application/json")
 
 		w.WriteHeader(web.Status)
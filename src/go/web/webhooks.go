@@ -0,0 +1,218 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/util/plog"
+)
+
+// Annotation holding the URLs (separated by commas or whitespace) notified of
+// state changes for an individual experiment. Only URLs whose host is one of
+// the global webhook hosts or the allowed webhook hosts are notified, so users
+// able to annotate experiments can't have the server make requests to
+// arbitrary hosts.
+const webhooksAnnotation = "webhooks"
+
+// Experiment states webhooks are notified of.
+var webhookStates = map[string]bool{
+	"start":           true,
+	"stop":            true,
+	"errorStarting":   true,
+	"errorStopping":   true,
+	"errorRestarting": true,
+}
+
+// How webhook notifications are retried when they can't be delivered. The delay
+// before each retry is doubled up to the max. They're variables so tests can
+// shorten them.
+var (
+	webhookAttempts       = 5
+	webhookInitialBackoff = 1 * time.Second
+	webhookMaxBackoff     = 30 * time.Second
+	webhookTimeout        = 10 * time.Second
+)
+
+// webhookPayload is the body POSTed to webhooks when an experiment's state
+// changes.
+type webhookPayload struct {
+	Experiment string `json:"experiment"`
+	State      string `json:"state"`
+	Timestamp  string `json:"timestamp"`
+	Error      string `json:"error,omitempty"`
+}
+
+// notifyWebhooks notifies the global webhooks, and any set for the given
+// experiment, that it changed to the given state. Only the states in
+// webhookStates are notified. Notifications are delivered in the background.
+// Webhooks are disabled if no webhook secret is set, since receivers would
+// have no way of telling notifications apart from forged ones.
+func notifyWebhooks(name, state string, err error) {
+	if !webhookStates[state] || o.webhookSecret == "" {
+		return
+	}
+
+	urls := webhookURLs(name)
+	if len(urls) == 0 {
+		return
+	}
+
+	payload := webhookPayload{Experiment: name, State: state, Timestamp: time.Now().UTC().Format(time.RFC3339)}
+
+	if err != nil {
+		payload.Error = err.Error()
+	}
+
+	body, _ := json.Marshal(payload)
+
+	for _, url := range urls {
+		go func(url string) {
+			if err := deliverWebhook(context.Background(), url, o.webhookSecret, body); err != nil {
+				plog.Error("delivering webhook", "exp", name, "state", state, "url", url, "err", err)
+			}
+		}(url)
+	}
+}
+
+// webhookURLs returns the unique global webhook URLs along with any allowed
+// ones set for the given experiment.
+func webhookURLs(name string) []string {
+	var (
+		urls = append([]string(nil), o.webhookURLs...)
+		seen = make(map[string]bool)
+		uniq []string
+	)
+
+	if exp, err := experiment.Get(name); err == nil {
+		annotated := strings.FieldsFunc(exp.Metadata.Annotations[webhooksAnnotation], func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n'
+		})
+
+		for _, url := range annotated {
+			if !webhookAllowed(url) {
+				plog.Warn("ignoring webhook for host not allowed", "exp", name, "url", url)
+				continue
+			}
+
+			urls = append(urls, url)
+		}
+	}
+
+	for _, url := range urls {
+		if url != "" && !seen[url] {
+			seen[url] = true
+			uniq = append(uniq, url)
+		}
+	}
+
+	return uniq
+}
+
+// webhookAllowed returns whether the given webhook URL set for an experiment is
+// an HTTP(S) URL for one of the global webhook hosts or the allowed webhook
+// hosts.
+func webhookAllowed(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+
+	for _, global := range o.webhookURLs {
+		if g, err := url.Parse(global); err == nil && strings.EqualFold(g.Host, u.Host) {
+			return true
+		}
+	}
+
+	for _, host := range o.webhookAllowedHosts {
+		if strings.EqualFold(host, u.Host) || strings.EqualFold(host, u.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deliverWebhook POSTs the given body to the given URL, retrying with
+// exponential backoff if the request fails or the server responds with a 5xx or
+// 429 status. If a secret is given, the request includes an
+// `X-Phenix-Timestamp` header with the Unix time in seconds and an
+// `X-Phenix-Signature` header with the hex-encoded HMAC-SHA256 of
+// `<timestamp>.<body>`, the same as requests to the experiment start hook.
+// Redirects aren't followed, since they could point anywhere, including hosts
+// webhooks aren't allowed to be sent to; they're treated as failed deliveries.
+func deliverWebhook(ctx context.Context, url, secret string, body []byte) error {
+	var (
+		client = &http.Client{
+			Timeout: webhookTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+
+		delay = webhookInitialBackoff
+	)
+
+	for attempt := 1; ; attempt++ {
+		retry, err := postWebhook(ctx, client, url, secret, body)
+		if err == nil || !retry || attempt >= webhookAttempts {
+			if err != nil && retry {
+				return fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+
+			return err
+		}
+
+		plog.Warn("webhook delivery failed, retrying", "url", url, "attempt", attempt, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > webhookMaxBackoff {
+			delay = webhookMaxBackoff
+		}
+	}
+}
+
+// postWebhook makes a single attempt at delivering a webhook, returning whether
+// it's worth retrying if it fails.
+func postWebhook(ctx context.Context, client *http.Client, url, secret string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("creating webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+		req.Header.Set("X-Phenix-Timestamp", timestamp)
+		req.Header.Set("X-Phenix-Signature", hookSignature(secret, timestamp, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("sending webhook request: %w", err)
+	}
+
+	resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+
+	return retry, fmt.Errorf("webhook responded with status %s", resp.Status)
+}
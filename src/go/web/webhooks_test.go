@@ -0,0 +1,118 @@
+package web
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeliverWebhook(t *testing.T) {
+	defer func(initial time.Duration) {
+		webhookInitialBackoff = initial
+	}(webhookInitialBackoff)
+
+	webhookInitialBackoff = time.Millisecond
+
+	var (
+		body     = []byte(`{"experiment":"foo","state":"start"}`)
+		attempts int32
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+
+		var (
+			ts  = r.Header.Get("X-Phenix-Timestamp")
+			sig = r.Header.Get("X-Phenix-Signature")
+		)
+
+		if err := verifyHookRequest("secret", ts, sig, got, time.Now()); err != nil {
+			t.Errorf("expected valid webhook signature, got %v", err)
+		}
+
+		// Fail the first two attempts so they're retried.
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+
+	defer server.Close()
+
+	if err := deliverWebhook(context.Background(), server.URL, "secret", body); err != nil {
+		t.Fatalf("expected webhook to be delivered, got %v", err)
+	}
+
+	if attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", attempts)
+	}
+
+	// Client errors aren't retried.
+	atomic.StoreInt32(&attempts, 0)
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+
+	defer rejecting.Close()
+
+	if err := deliverWebhook(context.Background(), rejecting.URL, "", body); err == nil {
+		t.Fatal("expected error for rejected webhook")
+	}
+
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt for rejected webhook, got %d", attempts)
+	}
+
+	// Redirects aren't followed.
+	var redirected int32
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&redirected, 1)
+	}))
+
+	defer target.Close()
+
+	redirecting := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+
+	defer redirecting.Close()
+
+	if err := deliverWebhook(context.Background(), redirecting.URL, "", body); err == nil {
+		t.Fatal("expected error for redirected webhook")
+	}
+
+	if redirected != 0 {
+		t.Fatal("expected webhook redirect not to be followed")
+	}
+}
+
+func TestWebhookAllowed(t *testing.T) {
+	defer func(urls, hosts []string) {
+		o.webhookURLs, o.webhookAllowedHosts = urls, hosts
+	}(o.webhookURLs, o.webhookAllowedHosts)
+
+	o.webhookURLs = []string{"https://hooks.example.com/phenix"}
+	o.webhookAllowedHosts = []string{"ci.example.com", "bot.example.com:8443"}
+
+	tests := map[string]bool{
+		"https://hooks.example.com/other":     true,
+		"https://ci.example.com/notify":       true,
+		"http://ci.example.com:8080/notify":   true,
+		"https://bot.example.com:8443/notify": true,
+		"https://bot.example.com/notify":      false,
+		"http://169.254.169.254/latest":       false,
+		"https://localhost:3000/":             false,
+		"file:///etc/passwd":                  false,
+		"ci.example.com/notify":               false,
+	}
+
+	for raw, expected := range tests {
+		if got := webhookAllowed(raw); got != expected {
+			t.Errorf("webhookAllowed(%q) = %v, expected %v", raw, got, expected)
+		}
+	}
+}
@@ -141,6 +141,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 			experiment.CreateWithDeployMode(wf.ExperimentDeployMode()),
 			experiment.CreateWithDefaultBridge(wf.DefaultBridgeName()),
 			experiment.CreateWithGREMesh(wf.UseGREMesh),
+			experiment.CreateWithOwner(experimentOwner(ctx)),
 		}
 
 		if err := experiment.Create(ctx, opts...); err != nil {
@@ -151,7 +152,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 		if wf.AutoRestart() {
 			cache.UnlockExperiment(expName)
 
-			if _, err := startExperiment(expName); err != nil {
+			if _, err := startExperiment(ctx, expName, ctx.Value("user").(string)); err != nil {
 				return err
 			}
 		}
@@ -170,7 +171,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 
 			var err error
 
-			if _, err = stopExperiment(expName); err != nil {
+			if _, err = stopExperiment(ctx, expName, ctx.Value("user").(string)); err != nil {
 				return err
 			}
 
@@ -332,7 +333,7 @@ func ApplyWorkflow(w http.ResponseWriter, r *http.Request) error {
 		if wf.AutoRestart() {
 			cache.UnlockExperiment(expName)
 
-			if _, err := startExperiment(expName); err != nil {
+			if _, err := startExperiment(ctx, expName, ctx.Value("user").(string)); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,57 @@
+// Handles the compression the server can apply to large broker results (see
+// web/broker/compress.go). Compression is only asked for when the browser is
+// able to decompress the results.
+
+// Subprotocol used to ask the server to gzip large results, or an empty string
+// if the browser can't decompress them (in which case results are left
+// uncompressed).
+export function brokerProtocol () {
+  return typeof window.DecompressionStream === 'undefined' ? '' : 'phenix.gzip';
+}
+
+// Messages are decoded one websocket message at a time so they're handled in
+// the order they were received, even when some of them have to be
+// decompressed.
+let pending = Promise.resolve();
+
+// Returns a promise for the broker messages in the given (newline separated)
+// websocket message data, with any compressed results decompressed.
+export function brokerMessages ( data ) {
+  let decoded = pending.then( () => decodeMessages( data ) );
+
+  // Keep the chain going even if decoding this data fails.
+  pending = decoded.catch( () => {} );
+
+  return decoded;
+}
+
+async function decodeMessages ( data ) {
+  let msgs = [];
+
+  for ( let m of data.split( /\r?\n/ ) ) {
+    if ( !m ) {
+      continue;
+    }
+
+    let msg = JSON.parse( m );
+
+    if ( msg.encoding ) {
+      msg.result   = await decompress( msg.encoding, msg.result );
+      msg.encoding = undefined;
+    }
+
+    msgs.push( msg );
+  }
+
+  return msgs;
+}
+
+// Compressed results are base64 encoded strings of the compressed JSON. The
+// server's deflate encoding is raw deflate, without the zlib header.
+async function decompress ( encoding, result ) {
+  let bytes  = Uint8Array.from( window.atob( result ), c => c.charCodeAt( 0 ) );
+  let format = encoding === 'deflate' ? 'deflate-raw' : encoding;
+  let stream = new window.Blob( [ bytes ] ).stream().pipeThrough( new window.DecompressionStream( format ) );
+
+  return JSON.parse( await new window.Response( stream ).text() );
+}